@@ -50,6 +50,7 @@ var (
 	flagPrintProtocolOverview = fs.Bool("overview", false, "print a list of all available decoders and fields")
 
 	flagInterface    = fs.String("iface", "", "attach to network interface and capture in live mode")
+	flagFlows        = fs.String("flows", "", "listen for NetFlow, IPFIX and sFlow datagrams on the given UDP address, e.g. :2055")
 	flagCompress     = fs.Bool("comp", true, "compress output with gzip")
 	flagBuffer       = fs.Bool("buf", true, "buffer data in memory before writing to disk")
 	flagWorkers      = fs.Int("workers", 1, "number of workers") // runtime.NumCPU()
@@ -98,6 +99,7 @@ var (
 
 	flagFreeOSMemory          = fs.Int("free-os-mem", 0, "free OS memory every X minutes, disabled if set to 0")
 	flagReassembleConnections = fs.Bool("reassemble-connections", true, "reassemble TCP connections")
+	flagDecapsulate           = fs.String("decap", "", "strip the headers of the given tunnel protocols (vxlan,geneve,gre,mpls,erspan or all), so that the inner packets are passed to reassembly and the connection and profile decoders")
 
 	flagTCPDebug  = fs.Bool("tcp-debug", false, "add debug output for TCP connections to debug.log")
 	flagSaveConns = fs.Bool("conns", true, "save raw TCP connections")
@@ -139,6 +141,9 @@ var (
 	flagBannerSize          = fs.Int("bsize", 256, "size of the stored service banners in bytes")
 	flagHarvesterBannerSize = fs.Int("hbsize", 256, "size of the data passed to the credential harvesters in bytes")
 	flagCustomCredsRegex    = fs.String("reCustom", "", "possibility of passing a custom regex for harvesting credentials")
+	flagDBCFile             = fs.String("dbc", "", "path to a DBC file for decoding the signals of CAN frames")
+	flagUSBKeyboardLayout   = fs.String("usb-layout", "us", "keyboard layout for translating USB keystrokes, one of us, uk or de")
+	flagTACACSKey           = fs.String("tacacs-key", "", "shared key for decrypting TACACS+ packets")
 	flagStreamBufferSize    = fs.Int("stream-buffer", 1000, "stream buffer size for tcp stream processor")
 
 	flagCompressionBlockSize = fs.Int("compression-block-size", defaults.CompressionBlockSize, "block size used for parallel compression")
@@ -130,6 +130,8 @@ func Run() {
 		source = *flagInput
 	case *flagInterface != "":
 		source = *flagInterface
+	case *flagFlows != "":
+		source = *flagFlows
 	default:
 		source = "unknown"
 	}
@@ -150,9 +152,9 @@ func Run() {
 	}
 
 	// abort if there is no input or no live capture
-	if *flagInput == "" && !live {
+	if *flagInput == "" && !live && *flagFlows == "" {
 		printHeader()
-		fmt.Println(ansi.Red + "> nothing to do. need a pcap file with the read flag (-read) or live mode and an interface (-iface)" + ansi.Reset)
+		fmt.Println(ansi.Red + "> nothing to do. need a pcap file with the read flag (-read), live mode and an interface (-iface) or a flow listener address (-flows)" + ansi.Reset)
 		os.Exit(1)
 	}
 
@@ -181,6 +183,7 @@ func Run() {
 		DecodeOptions:         utils.GetDecodeOptions(*flagDecodeOptions),
 		DPI:                   *flagDPI,
 		ReassembleConnections: *flagReassembleConnections,
+		Decapsulate:           *flagDecapsulate,
 		FreeOSMem:             *flagFreeOSMemory,
 		LogErrors:             *flagLogErrors,
 		NoPrompt:              *flagNoPrompt,
@@ -242,6 +245,9 @@ func Run() {
 			StopAfterServiceProbeMatch:     *flagStopAfterServiceProbeMatch,
 			StopAfterServiceCategoryMiss:   *flagStopAfterServiceCategoryMiss,
 			CustomRegex:                    *flagCustomCredsRegex,
+			DBCFile:                        *flagDBCFile,
+			USBKeyboardLayout:              *flagUSBKeyboardLayout,
+			TACACSKey:                      *flagTACACSKey,
 			StreamBufferSize:               *flagStreamBufferSize,
 			IgnoreDecoderInitErrors:        *flagIgnoreInitErrs,
 			DisableGenericVersionHarvester: *flagDisableGenericVersionHarvester,
@@ -261,6 +267,16 @@ func Run() {
 
 	c.PrintConfiguration()
 
+	// collect flows exported by routers and switches
+	if *flagFlows != "" {
+		err = c.CollectFlows(*flagFlows)
+		if err != nil {
+			log.Fatal("failed to collect flow exports: ", err)
+		}
+
+		return
+	}
+
 	// collect traffic live from named interface
 	if live {
 		err = c.CollectLive(*flagInterface, *flagBPF)
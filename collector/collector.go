@@ -88,6 +88,12 @@ type Collector struct {
 	shutdown                 bool
 	isLive                   bool
 
+	// decoder for the base layer of the current capture
+	linkDecoder gopacket.Decoder
+
+	// tunnel layers that are stripped by the workers, mapped to the tunnel protocol names
+	tunnelLayers map[gopacket.LayerType]string
+
 	// logging
 	log           *zap.Logger // collector.log
 	netcapLog     *log.Logger // netcap.log
@@ -260,7 +266,7 @@ func (c *Collector) handlePacketTimeout(p gopacket.Packet) {
 	// send the packetInfo to the decoder routine
 	case c.workers[c.next] <- p:
 	case <-time.After(3 * time.Second):
-		pkt := gopacket.NewPacket(p.Data(), c.config.BaseLayer, gopacket.Default)
+		pkt := gopacket.NewPacket(p.Data(), c.baseDecoder(&p.Metadata().CaptureInfo), gopacket.Default)
 
 		var (
 			nf gopacket.Flow
@@ -101,6 +101,10 @@ type Config struct {
 	// Use TCP reassembly
 	ReassembleConnections bool
 
+	// Comma separated list of tunnel protocols whose headers are stripped before reassembly,
+	// the connection and the profile decoders see the inner packets
+	Decapsulate string
+
 	// LogErrors will log verbose packet decoding errors into the errors.log file
 	LogErrors bool
 
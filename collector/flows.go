@@ -0,0 +1,87 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/packet"
+)
+
+// maximum size of a flow export datagram.
+const maxFlowDatagramSize = 65535
+
+// CollectFlows listens for NetFlow, IPFIX and sFlow datagrams on the given UDP address.
+// The exported flows are converted into connections and update the IP and device profiles,
+// collection runs until the collector receives a signal.
+func (c *Collector) CollectFlows(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	// close listener on exit
+	defer conn.Close()
+
+	// initialize collector
+	if err = c.Init(); err != nil {
+		return err
+	}
+
+	c.printlnStdOut("listening for flow exports on", conn.LocalAddr().String())
+
+	stopProgress := c.printProgressInterval()
+
+	c.mu.Lock()
+	c.isLive = true
+	c.mu.Unlock()
+
+	var (
+		buf    = make([]byte, maxFlowDatagramSize)
+		n      int
+		remote net.Addr
+	)
+
+	for {
+		n, remote, err = conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+
+		// increment atomic packet counter
+		atomic.AddInt64(&c.current, 1)
+
+		exporter := remote.String()
+		if udpAddr, ok := remote.(*net.UDPAddr); ok {
+			exporter = udpAddr.IP.String()
+		}
+
+		if errExport := packet.HandleFlowExport(exporter, buf[:n], time.Now()); errExport != nil {
+			c.errorMap.Inc(errExport.Error())
+			c.log.Debug("failed to decode flow export", zap.String("exporter", exporter), zap.Error(errExport))
+		}
+	}
+
+	// Stop progress reporting
+	stopProgress <- struct{}{}
+
+	// run cleanup on listener exit
+	c.cleanup(false)
+
+	return errors.Wrap(err, "error reading flow export datagram")
+}
@@ -159,6 +159,11 @@ func (c *Collector) Init() (err error) {
 	c.abstractDecoders, err = stream.InitAbstractDecoders(c.config.DecoderConfig)
 	handleDecoderInitError(err, "abstract")
 
+	c.tunnelLayers, err = packet.TunnelLayers(c.config.Decapsulate)
+	if err != nil {
+		return err
+	}
+
 	c.log.Info("initialized packet decoders", zap.Int("total", len(c.streamDecoders)))
 
 	c.buildProgressString()
@@ -0,0 +1,99 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/decoder/packet"
+)
+
+// linkTypeRawDLT is DLT_RAW, which libpcap reports instead of LINKTYPE_RAW for live captures on most platforms.
+const linkTypeRawDLT layers.LinkType = 12
+
+// linkTypeDecoders maps the link types of capture files, pcapng interfaces and live captures
+// to the decoder for the base layer of the captured packets.
+// The layer type of a link type can not be used for this, since gopacket only sets it for few link types.
+var linkTypeDecoders = map[layers.LinkType]gopacket.Decoder{
+	layers.LinkTypeEthernet:       layers.LayerTypeEthernet,
+	layers.LinkTypeRaw:            layers.LinkTypeRaw, // IPv4 or IPv6, depending on the version field
+	linkTypeRawDLT:                layers.LinkTypeRaw,
+	layers.LinkTypeIPv4:           layers.LayerTypeIPv4,
+	layers.LinkTypeIPv6:           layers.LayerTypeIPv6,
+	layers.LinkTypeNull:           layers.LayerTypeLoopback,
+	layers.LinkTypeLoop:           layers.LayerTypeLoopback,
+	layers.LinkTypeFDDI:           layers.LayerTypeFDDI,
+	layers.LinkTypePPP:            layers.LayerTypePPP,
+	layers.LinkTypeIEEE802_11:     layers.LayerTypeDot11,
+	layers.LinkTypeIEEE80211Radio: layers.LayerTypeRadioTap,
+	layers.LinkTypePrismHeader:    layers.LayerTypePrismHeader,
+	layers.LinkTypeLinuxSLL:       layers.LayerTypeLinuxSLL,
+	layers.LinkTypePFLog:          layers.LayerTypePFLog,
+	layers.LinkTypeLinuxUSB:       layers.LayerTypeUSB,
+	packet.LinkTypeLinuxSLL2:      packet.LayerTypeLinuxSLL2,
+	packet.LinkTypePPI:            packet.LayerTypePPI,
+	packet.LinkTypeCANSocketCAN:   packet.LayerTypeCAN,
+}
+
+// captureHeaders are layers that carry information about the capture instead of a protocol,
+// they are not treated as unknown layers although no decoder produces audit records for them.
+var captureHeaders = map[gopacket.LayerType]struct{}{
+	layers.LayerTypeRadioTap:    {},
+	layers.LayerTypePrismHeader: {},
+	layers.LayerTypeLinuxSLL:    {},
+	packet.LayerTypeLinuxSLL2:   {},
+	packet.LayerTypePPI:         {},
+}
+
+// handleLinkType selects the base layer for packets of a capture file or interface.
+// Link types without a known base layer are decoded with the configured base layer.
+func (c *Collector) handleLinkType(lt layers.LinkType) {
+	d, ok := linkTypeDecoders[lt]
+	if !ok {
+		c.printlnStdOut("unknown link type:", int(lt), "using base layer", c.config.BaseLayer)
+		c.linkDecoder = c.config.BaseLayer
+
+		return
+	}
+
+	c.printlnStdOut("detected link type:", int(lt), "base layer:", d)
+	c.linkDecoder = d
+}
+
+// linkTypeDecoder returns the decoder for the base layer of packets with the given link type.
+func (c *Collector) linkTypeDecoder(lt layers.LinkType) gopacket.Decoder {
+	if d, ok := linkTypeDecoders[lt]; ok {
+		return d
+	}
+
+	return c.config.BaseLayer
+}
+
+// baseDecoder returns the decoder for the base layer of a packet.
+// A pcapng file can contain interfaces with different link types,
+// the reader then passes the link type of the interface the packet was captured on as ancillary data.
+func (c *Collector) baseDecoder(ci *gopacket.CaptureInfo) gopacket.Decoder {
+	if len(ci.AncillaryData) > 0 {
+		if lt, ok := ci.AncillaryData[0].(layers.LinkType); ok {
+			return c.linkTypeDecoder(lt)
+		}
+	}
+
+	if c.linkDecoder != nil {
+		return c.linkDecoder
+	}
+
+	return c.config.BaseLayer
+}
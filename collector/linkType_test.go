@@ -0,0 +1,71 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/decoder/packet"
+)
+
+func TestBaseDecoder(t *testing.T) {
+	c := New(Config{
+		BaseLayer: layers.LayerTypeEthernet,
+	})
+
+	// without a detected link type the configured base layer is used
+	if d := c.baseDecoder(&gopacket.CaptureInfo{}); d != layers.LayerTypeEthernet {
+		t.Fatal("expected configured base layer, got", d)
+	}
+
+	c.linkDecoder = c.linkTypeDecoder(packet.LinkTypeLinuxSLL2)
+	if d := c.baseDecoder(&gopacket.CaptureInfo{}); d != packet.LayerTypeLinuxSLL2 {
+		t.Fatal("expected SLL2 base layer, got", d)
+	}
+
+	// the link type of the pcapng interface takes precedence
+	ci := &gopacket.CaptureInfo{
+		AncillaryData: []interface{}{layers.LinkTypeIPv6},
+	}
+	if d := c.baseDecoder(ci); d != layers.LayerTypeIPv6 {
+		t.Fatal("expected IPv6 base layer, got", d)
+	}
+
+	// unknown link types fall back to the configured base layer
+	if d := c.linkTypeDecoder(layers.LinkTypeTokenRing); d != layers.LayerTypeEthernet {
+		t.Fatal("expected configured base layer for unknown link type, got", d)
+	}
+}
+
+func TestRawLinkType(t *testing.T) {
+	c := New(Config{
+		BaseLayer: layers.LayerTypeEthernet,
+	})
+
+	data := []byte{
+		0x60, 0x00, 0x00, 0x00, 0x00, 0x08, 0x11, 0x40,
+		0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01,
+		0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x02,
+		0x30, 0x39, 0x00, 0x35, 0x00, 0x08, 0x00, 0x00,
+	}
+
+	// raw captures contain IPv4 or IPv6 packets, depending on the version field
+	p := gopacket.NewPacket(data, c.linkTypeDecoder(layers.LinkTypeRaw), gopacket.Default)
+	if p.Layer(layers.LayerTypeIPv6) == nil || p.Layer(layers.LayerTypeUDP) == nil {
+		t.Fatal("expected IPv6 and UDP layers", p)
+	}
+}
@@ -22,7 +22,6 @@ import (
 	"time"
 
 	"github.com/dreadl0ck/gopacket"
-	"github.com/dreadl0ck/gopacket/layers"
 	"github.com/dreadl0ck/gopacket/pcapgo"
 	"github.com/dustin/go-humanize"
 	"github.com/pkg/errors"
@@ -189,33 +188,3 @@ func (c *Collector) CollectPcap(path string) error {
 
 	return nil
 }
-
-func (c *Collector) handleLinkType(lt layers.LinkType) {
-	c.printlnStdOut("detected link type:", lt)
-
-	// TODO: why does this not work?
-	//c.config.BaseLayer = lt.LayerType()
-
-	switch lt {
-	case layers.LinkTypeEthernet:
-		c.config.BaseLayer = layers.LayerTypeEthernet
-	case layers.LinkTypeRaw:
-		c.config.BaseLayer = layers.LayerTypeIPv4
-	case layers.LinkTypeIPv4:
-		c.config.BaseLayer = layers.LayerTypeIPv4
-	case layers.LinkTypeIPv6:
-		c.config.BaseLayer = layers.LayerTypeIPv6
-	case layers.LinkTypeNull:
-		c.config.BaseLayer = layers.LayerTypeLoopback
-	case layers.LinkTypeFDDI:
-		c.config.BaseLayer = layers.LayerTypeFDDI
-	case layers.LinkTypeIEEE802_11:
-		c.config.BaseLayer = layers.LayerTypeDot11
-	case layers.LinkTypeIEEE80211Radio:
-		c.config.BaseLayer = layers.LayerTypeRadioTap
-	case layers.LinkTypePPP:
-		c.config.BaseLayer = layers.LayerTypePPP
-	default:
-		log.Fatal("unhandled link type: ", lt)
-	}
-}
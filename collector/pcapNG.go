@@ -35,7 +35,10 @@ func openPcapNG(file string) (*pcapgo.NgReader, *os.File, error) {
 	}
 
 	// try to create pcap reader
-	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	// interfaces may use different link types, the link type is passed along with each packet
+	r, err := pcapgo.NewNgReader(f, pcapgo.NgReaderOptions{
+		WantMixedLinkType: true,
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -116,8 +119,6 @@ func (c *Collector) CollectPcapNG(path string) error {
 		}
 	}()
 
-	c.handleLinkType(r.LinkType())
-
 	// initialize collector
 	if err = c.Init(); err != nil {
 		return err
@@ -127,6 +128,9 @@ func (c *Collector) CollectPcapNG(path string) error {
 		data         []byte
 		ci           gopacket.CaptureInfo
 		stopProgress = c.printProgressInterval()
+
+		// interfaces whose link type has been reported
+		interfaces = make(map[int]struct{})
 	)
 
 	for {
@@ -140,6 +144,16 @@ func (c *Collector) CollectPcapNG(path string) error {
 			return errors.Wrap(err, "error reading packet data")
 		}
 
+		// the base layer is selected for each packet by the link type of its interface
+		if _, ok := interfaces[ci.InterfaceIndex]; !ok {
+			interfaces[ci.InterfaceIndex] = struct{}{}
+
+			if iface, errIface := r.Interface(ci.InterfaceIndex); errIface == nil {
+				c.printlnStdOut("interface", ci.InterfaceIndex, iface.Name)
+				c.handleLinkType(iface.LinkType)
+			}
+		}
+
 		// increment atomic packet counter
 		atomic.AddInt64(&c.current, 1)
 
@@ -29,7 +29,7 @@ import (
 
 func (c *Collector) handleRawPacketData(data []byte, ci *gopacket.CaptureInfo) {
 	// when not using lazy here, the packet will be decoded on the main thread!
-	p := gopacket.NewPacket(data, c.config.BaseLayer, c.config.DecodeOptions)
+	p := gopacket.NewPacket(data, c.baseDecoder(ci), c.config.DecodeOptions)
 	p.Metadata().CaptureInfo = *ci
 
 	// pass packet to a worker routine
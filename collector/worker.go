@@ -33,6 +33,9 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 		in  = make(chan gopacket.Packet, c.config.PacketBufferSize)
 		pkt gopacket.Packet
 
+		// tunneled packet of pkt, or pkt itself if no tunnel headers were stripped
+		inner gopacket.Packet
+
 		errLayer gopacket.ErrorLayer
 		err      error
 
@@ -58,10 +61,19 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 			pkt.Metadata().Length = pkt.Metadata().CaptureInfo.Length
 			pkt.Metadata().CaptureLength = pkt.Metadata().CaptureInfo.CaptureLength
 
+			// strip the configured tunnel headers,
+			// so that reassembly and the packet decoders process the tunneled traffic
+			inner = pkt
+			if len(c.tunnelLayers) > 0 {
+				if p := packet.Decapsulate(pkt, c.tunnelLayers, c.config.DecodeOptions); p != nil {
+					inner = p
+				}
+			}
+
 			// pass packet to reassembly
 			if c.config.ReassembleConnections {
 				t := time.Now()
-				tcp.ReassemblePacket(pkt, assembler)
+				tcp.ReassemblePacket(inner, assembler)
 				reassemblyTime.WithLabelValues().Set(float64(time.Since(t).Nanoseconds()))
 			}
 
@@ -69,8 +81,8 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 			ctx := &types.PacketContext{}
 
 			if c.config.DecoderConfig.AddContext {
-				netLayer = pkt.NetworkLayer()
-				transportLayer = pkt.TransportLayer()
+				netLayer = inner.NetworkLayer()
+				transportLayer = inner.TransportLayer()
 
 				if netLayer != nil {
 					ctx.SrcIP = netLayer.NetworkFlow().Src().String()
@@ -133,7 +145,7 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 							goto done
 						}
 					}
-				} else { // no netcap decoder implemented
+				} else if _, ok = captureHeaders[layer.LayerType()]; !ok { // no netcap decoder implemented
 
 					// increment unknown layer type counter
 					c.unknownProtosAtomic.Inc(layer.LayerType().String())
@@ -154,7 +166,7 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 			// call custom decoders
 			for _, customDec = range c.packetDecoders {
 				t := time.Now()
-				err = customDec.Decode(pkt)
+				err = customDec.Decode(inner)
 				customDecoderTime.WithLabelValues(customDec.GetName()).Set(float64(time.Since(t).Nanoseconds()))
 				if err != nil {
 					if c.config.DecoderConfig.ExportMetrics {
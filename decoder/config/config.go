@@ -86,6 +86,15 @@ type Config struct {
 	// CustomRegex to use for credentials harvester
 	CustomRegex string
 
+	// Path to a DBC file used to decode the signals of CAN frames
+	DBCFile string
+
+	// Keyboard layout for translating USB HID keystrokes, one of us, uk or de
+	USBKeyboardLayout string
+
+	// Shared key for decrypting the bodies of TACACS+ packets
+	TACACSKey string
+
 	// Will create a memory dump at the specified path for debugging and profiling
 	MemProfile string
 
@@ -27,4 +27,8 @@ type ConversationInfo struct {
 	ServerIP   string
 	ClientPort int32
 	ServerPort int32
+
+	// ServerHost is the address the server was requested by, for example the target of a SOCKS
+	// or HTTP CONNECT tunnel. It can be a hostname, ServerIP is only set if the IP is known.
+	ServerHost string
 }
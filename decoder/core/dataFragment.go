@@ -14,6 +14,8 @@
 package core
 
 import (
+	"time"
+
 	"github.com/dreadl0ck/gopacket"
 
 	"github.com/dreadl0ck/netcap/reassembly"
@@ -29,3 +31,13 @@ type dataFragment interface {
 	Network() gopacket.Flow
 	Transport() gopacket.Flow
 }
+
+// Timestamp returns the capture time of a data fragment.
+// TCP fragments carry the timestamp in their assembler context, UDP fragments in their capture info.
+func Timestamp(d dataFragment) time.Time {
+	if d.Context() != nil {
+		return d.Context().GetCaptureInfo().Timestamp
+	}
+
+	return d.CaptureInfo().Timestamp
+}
@@ -0,0 +1,67 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+// Direction holds the data sent in one direction of a connection,
+// along with the offsets and timestamps of the fragments it was assembled from.
+// It is used by decoders for protocols whose messages can be split across fragments.
+type Direction struct {
+	Client bool
+	Data   []byte
+
+	offsets []int
+	times   []time.Time
+}
+
+// TimeAt returns the timestamp of the fragment that contains the byte at offset.
+func (d *Direction) TimeAt(offset int) time.Time {
+	if len(d.times) == 0 {
+		return time.Time{}
+	}
+
+	i := sort.Search(len(d.offsets), func(i int) bool {
+		return d.offsets[i] > offset
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return d.times[i]
+}
+
+// SplitDirections splits the conversation into the data sent by client and server.
+func SplitDirections(data DataFragments) (client, server *Direction) {
+	client = &Direction{Client: true}
+	server = &Direction{}
+
+	for _, d := range data {
+		dir := server
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			dir = client
+		}
+
+		dir.offsets = append(dir.offsets, len(dir.Data))
+		dir.times = append(dir.times, Timestamp(d))
+		dir.Data = append(dir.Data, d.Raw()...)
+	}
+
+	return client, server
+}
@@ -0,0 +1,834 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// BACnet/IP uses the ports 0xBAC0 to 0xBACF, 0xBAC0 being the default
+	bacnetPortFirst = 47808
+	bacnetPortLast  = 47823
+
+	bvlcTypeBACnetIP = 0x81
+	bvlcHeaderSize   = 4
+
+	bvlcForwardedNPDU = 0x04
+
+	npduVersion = 0x01
+
+	// NPDU control flags
+	npduNetworkMessage = 0x80
+	npduDestination    = 0x20
+	npduSource         = 0x08
+	npduExpectingReply = 0x04
+	npduPriority       = 0x03
+
+	// APDU types
+	apduConfirmedRequest   = 0
+	apduUnconfirmedRequest = 1
+	apduSimpleACK          = 2
+	apduComplexACK         = 3
+	apduSegmentACK         = 4
+	apduError              = 5
+	apduReject             = 6
+	apduAbort              = 7
+
+	// segmented message flag of confirmed requests and complex acknowledgements
+	apduSegmented = 0x08
+
+	// services carrying device identities
+	bacnetServiceIAm           = 0
+	bacnetServiceWhoIs         = 8
+	bacnetServiceReadProperty  = 12
+	bacnetServiceWriteProperty = 15
+
+	// application tags
+	bacnetTagNull            = 0
+	bacnetTagBoolean         = 1
+	bacnetTagUnsigned        = 2
+	bacnetTagSigned          = 3
+	bacnetTagReal            = 4
+	bacnetTagDouble          = 5
+	bacnetTagOctetString     = 6
+	bacnetTagCharacterString = 7
+	bacnetTagBitString       = 8
+	bacnetTagEnumerated      = 9
+	bacnetTagDate            = 10
+	bacnetTagTime            = 11
+	bacnetTagObjectID        = 12
+
+	bacnetObjectTypeDevice = 8
+)
+
+var errBACnetTruncated = errors.New("truncated BACnet tag")
+
+var bvlcFunctions = map[byte]string{
+	0x00: "BVLC-Result",
+	0x01: "Write-Broadcast-Distribution-Table",
+	0x02: "Read-Broadcast-Distribution-Table",
+	0x03: "Read-Broadcast-Distribution-Table-Ack",
+	0x04: "Forwarded-NPDU",
+	0x05: "Register-Foreign-Device",
+	0x06: "Read-Foreign-Device-Table",
+	0x07: "Read-Foreign-Device-Table-Ack",
+	0x08: "Delete-Foreign-Device-Table-Entry",
+	0x09: "Distribute-Broadcast-To-Network",
+	0x0a: "Original-Unicast-NPDU",
+	0x0b: "Original-Broadcast-NPDU",
+	0x0c: "Secure-BVLL",
+}
+
+var bacnetNetworkMessages = map[byte]string{
+	0x00: "Who-Is-Router-To-Network",
+	0x01: "I-Am-Router-To-Network",
+	0x02: "I-Could-Be-Router-To-Network",
+	0x03: "Reject-Message-To-Network",
+	0x04: "Router-Busy-To-Network",
+	0x05: "Router-Available-To-Network",
+	0x06: "Initialize-Routing-Table",
+	0x07: "Initialize-Routing-Table-Ack",
+	0x08: "Establish-Connection-To-Network",
+	0x09: "Disconnect-Connection-To-Network",
+	0x12: "What-Is-Network-Number",
+	0x13: "Network-Number-Is",
+}
+
+var bacnetAPDUTypes = map[byte]string{
+	apduConfirmedRequest:   "Confirmed-Request",
+	apduUnconfirmedRequest: "Unconfirmed-Request",
+	apduSimpleACK:          "Simple-ACK",
+	apduComplexACK:         "Complex-ACK",
+	apduSegmentACK:         "Segment-ACK",
+	apduError:              "Error",
+	apduReject:             "Reject",
+	apduAbort:              "Abort",
+}
+
+var bacnetConfirmedServices = map[byte]string{
+	0:  "acknowledgeAlarm",
+	1:  "confirmedCOVNotification",
+	2:  "confirmedEventNotification",
+	3:  "getAlarmSummary",
+	4:  "getEnrollmentSummary",
+	5:  "subscribeCOV",
+	6:  "atomicReadFile",
+	7:  "atomicWriteFile",
+	8:  "addListElement",
+	9:  "removeListElement",
+	10: "createObject",
+	11: "deleteObject",
+	12: "readProperty",
+	13: "readPropertyConditional",
+	14: "readPropertyMultiple",
+	15: "writeProperty",
+	16: "writePropertyMultiple",
+	17: "deviceCommunicationControl",
+	18: "confirmedPrivateTransfer",
+	19: "confirmedTextMessage",
+	20: "reinitializeDevice",
+	21: "vtOpen",
+	22: "vtClose",
+	23: "vtData",
+	24: "authenticate",
+	25: "requestKey",
+	26: "readRange",
+	27: "lifeSafetyOperation",
+	28: "subscribeCOVProperty",
+	29: "getEventInformation",
+	30: "writeGroup",
+}
+
+var bacnetUnconfirmedServices = map[byte]string{
+	0:  "i-Am",
+	1:  "i-Have",
+	2:  "unconfirmedCOVNotification",
+	3:  "unconfirmedEventNotification",
+	4:  "unconfirmedPrivateTransfer",
+	5:  "unconfirmedTextMessage",
+	6:  "timeSynchronization",
+	7:  "who-Has",
+	8:  "who-Is",
+	9:  "utcTimeSynchronization",
+	10: "writeGroup",
+}
+
+var bacnetObjectTypes = map[uint32]string{
+	0:  "analog-input",
+	1:  "analog-output",
+	2:  "analog-value",
+	3:  "binary-input",
+	4:  "binary-output",
+	5:  "binary-value",
+	6:  "calendar",
+	7:  "command",
+	8:  "device",
+	9:  "event-enrollment",
+	10: "file",
+	11: "group",
+	12: "loop",
+	13: "multi-state-input",
+	14: "multi-state-output",
+	15: "notification-class",
+	16: "program",
+	17: "schedule",
+	18: "averaging",
+	19: "multi-state-value",
+	20: "trend-log",
+	21: "life-safety-point",
+	22: "life-safety-zone",
+	23: "accumulator",
+	24: "pulse-converter",
+	25: "event-log",
+	26: "global-group",
+	27: "trend-log-multiple",
+	28: "load-control",
+	29: "structured-view",
+	30: "access-door",
+}
+
+var bacnetProperties = map[uint32]string{
+	8:   "all",
+	12:  "application-software-version",
+	28:  "description",
+	36:  "event-state",
+	44:  "firmware-revision",
+	58:  "location",
+	62:  "max-apdu-length-accepted",
+	70:  "model-name",
+	75:  "object-identifier",
+	76:  "object-list",
+	77:  "object-name",
+	79:  "object-type",
+	80:  "optional",
+	81:  "out-of-service",
+	85:  "present-value",
+	87:  "priority-array",
+	96:  "protocol-object-types-supported",
+	97:  "protocol-services-supported",
+	98:  "protocol-version",
+	103: "reliability",
+	104: "relinquish-default",
+	105: "required",
+	107: "segmentation-supported",
+	111: "status-flags",
+	112: "system-status",
+	117: "units",
+	120: "vendor-identifier",
+	121: "vendor-name",
+	139: "protocol-revision",
+}
+
+var bacnetSegmentation = map[uint32]string{
+	0: "segmented-both",
+	1: "segmented-transmit",
+	2: "segmented-receive",
+	3: "no-segmentation",
+}
+
+var bacnetErrorClasses = map[uint32]string{
+	0: "device",
+	1: "object",
+	2: "property",
+	3: "resources",
+	4: "security",
+	5: "services",
+	6: "vt",
+	7: "communication",
+}
+
+// bacnetTag is a decoded BACnet tag with its content.
+type bacnetTag struct {
+	number  byte
+	context bool
+
+	// opening and closing tags of constructed context data
+	opening bool
+	closing bool
+
+	data []byte
+}
+
+var bacnetDecoder = newPacketDecoder(
+	types.Type_NC_BACnet,
+	"BACnet",
+	"The Building Automation and Control Networks protocol is used for building automation, such as heating, ventilation, lighting and access control",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udpLayer := p.Layer(layers.LayerTypeUDP)
+		if udpLayer == nil {
+			return nil
+		}
+
+		udp, ok := udpLayer.(*layers.UDP)
+		if !ok || !isBACnetPort(udp) {
+			return nil
+		}
+
+		b, err := decodeBACnet(udp.Payload)
+		if err != nil {
+			decoderLog.Debug("failed to decode BACnet packet", zap.Error(err))
+
+			return nil
+		}
+
+		b.Timestamp = p.Metadata().Timestamp.UnixNano()
+		b.SrcPort = int32(udp.SrcPort)
+		b.DstPort = int32(udp.DstPort)
+
+		if nl := p.NetworkLayer(); nl != nil {
+			b.SrcIP = nl.NetworkFlow().Src().String()
+			b.DstIP = nl.NetworkFlow().Dst().String()
+		}
+
+		if conf != nil && conf.IncludePayloads {
+			b.Payload = udp.Payload
+		}
+
+		if ip := bacnetDeviceAddress(b); ip != "" {
+			decoderutils.AddDeviceIdentifier(ip, bacnetDeviceID(b.DeviceInstance))
+		}
+
+		return b
+	},
+	nil,
+)
+
+func isBACnetPort(udp *layers.UDP) bool {
+	return (udp.SrcPort >= bacnetPortFirst && udp.SrcPort <= bacnetPortLast) ||
+		(udp.DstPort >= bacnetPortFirst && udp.DstPort <= bacnetPortLast)
+}
+
+// decodeBACnet parses the BVLC header, the NPDU and the APDU of a BACnet/IP message.
+func decodeBACnet(data []byte) (*types.BACnet, error) {
+	if len(data) < bvlcHeaderSize || data[0] != bvlcTypeBACnetIP {
+		return nil, errors.New("invalid BVLC header")
+	}
+
+	var (
+		function = data[1]
+		length   = int(binary.BigEndian.Uint16(data[2:4]))
+		b        = &types.BACnet{
+			BVLCFunction: bvlcFunctions[function],
+		}
+	)
+
+	if b.BVLCFunction == "" {
+		b.BVLCFunction = "BVLC 0x" + strconv.FormatInt(int64(function), 16)
+	}
+
+	if length < bvlcHeaderSize || length > len(data) {
+		return nil, errors.New("invalid BVLC length")
+	}
+
+	data = data[bvlcHeaderSize:length]
+
+	switch function {
+	case bvlcForwardedNPDU:
+		// address and port of the original sender
+		if len(data) < 6 {
+			return b, nil
+		}
+
+		b.ForwardedAddress = net.JoinHostPort(net.IP(data[:4]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(data[4:6]))))
+		data = data[6:]
+	case 0x09, 0x0a, 0x0b:
+	default:
+		// BVLL management messages do not carry an NPDU
+		return b, nil
+	}
+
+	apdu, err := decodeNPDU(data, b)
+	if err != nil || apdu == nil {
+		return b, err
+	}
+
+	decodeAPDU(apdu, b)
+
+	return b, nil
+}
+
+// decodeNPDU parses the network layer header and returns the APDU.
+func decodeNPDU(data []byte, b *types.BACnet) ([]byte, error) {
+	if len(data) < 2 || data[0] != npduVersion {
+		return nil, errors.New("invalid NPDU header")
+	}
+
+	control := data[1]
+	data = data[2:]
+
+	b.ExpectingReply = control&npduExpectingReply != 0
+	b.Priority = int32(control & npduPriority)
+
+	readAddress := func() (int32, string, bool) {
+		if len(data) < 3 || len(data) < 3+int(data[2]) {
+			return 0, "", false
+		}
+
+		var (
+			network = int32(binary.BigEndian.Uint16(data[:2]))
+			addr    = hex.EncodeToString(data[3 : 3+int(data[2])])
+		)
+
+		data = data[3+int(data[2]):]
+
+		return network, addr, true
+	}
+
+	var ok bool
+
+	if control&npduDestination != 0 {
+		if b.DestinationNetwork, b.DestinationAddress, ok = readAddress(); !ok {
+			return nil, errors.New("truncated NPDU destination")
+		}
+	}
+
+	if control&npduSource != 0 {
+		if b.SourceNetwork, b.SourceAddress, ok = readAddress(); !ok {
+			return nil, errors.New("truncated NPDU source")
+		}
+	}
+
+	if control&npduDestination != 0 {
+		if len(data) < 1 {
+			return nil, errors.New("missing NPDU hop count")
+		}
+
+		b.HopCount = int32(data[0])
+		data = data[1:]
+	}
+
+	if control&npduNetworkMessage != 0 {
+		if len(data) < 1 {
+			return nil, errors.New("missing network layer message type")
+		}
+
+		if name, exists := bacnetNetworkMessages[data[0]]; exists {
+			b.NetworkMessage = name
+		} else {
+			b.NetworkMessage = "Network Message 0x" + strconv.FormatInt(int64(data[0]), 16)
+		}
+
+		return nil, nil
+	}
+
+	return data, nil
+}
+
+// decodeAPDU parses the application layer header and the parameters of well known services.
+func decodeAPDU(data []byte, b *types.BACnet) {
+	if len(data) < 2 {
+		return
+	}
+
+	typ := data[0] >> 4
+	b.APDUType = bacnetAPDUTypes[typ]
+
+	var (
+		service  byte
+		names    = bacnetConfirmedServices
+		segments = data[0]&apduSegmented != 0
+	)
+
+	switch typ {
+	case apduConfirmedRequest:
+		// flags, max segments and max APDU, invoke id, optional sequence number and window size
+		offset := 3
+		if segments {
+			offset += 2
+		}
+
+		if len(data) <= offset {
+			return
+		}
+
+		b.InvokeID = int32(data[2])
+		service = data[offset]
+		data = data[offset+1:]
+	case apduUnconfirmedRequest:
+		names = bacnetUnconfirmedServices
+		service = data[1]
+		data = data[2:]
+	case apduSimpleACK:
+		if len(data) < 3 {
+			return
+		}
+
+		b.InvokeID = int32(data[1])
+		service = data[2]
+		data = nil
+	case apduComplexACK:
+		offset := 2
+		if segments {
+			offset += 2
+		}
+
+		if len(data) <= offset {
+			return
+		}
+
+		b.InvokeID = int32(data[1])
+		service = data[offset]
+		data = data[offset+1:]
+	case apduError:
+		if len(data) < 3 {
+			return
+		}
+
+		b.InvokeID = int32(data[1])
+		service = data[2]
+		decodeBACnetError(data[3:], b)
+
+		data = nil
+	case apduSegmentACK, apduReject, apduAbort:
+		b.InvokeID = int32(data[1])
+		if len(data) > 2 && typ != apduSegmentACK {
+			b.Reason = int32(data[2])
+		}
+
+		return
+	}
+
+	b.ServiceChoice = int32(service)
+	if name, ok := names[service]; ok {
+		b.Service = name
+	} else {
+		b.Service = "service " + strconv.Itoa(int(service))
+	}
+
+	if len(data) == 0 {
+		return
+	}
+
+	tags, err := parseBACnetTags(data)
+	if err != nil {
+		decoderLog.Debug("failed to parse BACnet tags", zap.Error(err), zap.String("service", b.Service))
+	}
+
+	if typ == apduUnconfirmedRequest {
+		switch service {
+		case bacnetServiceIAm:
+			decodeIAm(tags, b)
+		case bacnetServiceWhoIs:
+			for _, t := range tags {
+				switch {
+				case t.context && t.number == 0:
+					b.WhoIsLow = int32(bacnetUnsigned(t.data))
+				case t.context && t.number == 1:
+					b.WhoIsHigh = int32(bacnetUnsigned(t.data))
+				}
+			}
+		}
+
+		return
+	}
+
+	decodeBACnetProperty(tags, b)
+}
+
+// decodeIAm parses the device identifier, max APDU length, segmentation and vendor of an I-Am request.
+func decodeIAm(tags []bacnetTag, b *types.BACnet) {
+	if len(tags) < 4 || tags[0].context || tags[0].number != bacnetTagObjectID {
+		return
+	}
+
+	typ, instance := bacnetObjectID(tags[0].data)
+	b.ObjectType = bacnetObjectType(typ)
+	b.ObjectInstance = int32(instance)
+	b.DeviceInstance = int32(instance)
+	b.MaxAPDU = int32(bacnetUnsigned(tags[1].data))
+
+	if name, ok := bacnetSegmentation[bacnetUnsigned(tags[2].data)]; ok {
+		b.Segmentation = name
+	}
+
+	b.VendorID = int32(bacnetUnsigned(tags[3].data))
+}
+
+// decodeBACnetProperty collects the object identifier, property and value of property access services.
+// The context tag numbers are shared by ReadProperty, ReadProperty-ACK and WriteProperty.
+func decodeBACnetProperty(tags []bacnetTag, b *types.BACnet) {
+	var depth int
+
+	for i, t := range tags {
+		switch {
+		case t.opening:
+			depth++
+
+			// the first application tagged value enclosed in context tag 3
+			if depth == 1 && t.number == 3 && i+1 < len(tags) && !tags[i+1].context {
+				b.Value = bacnetValue(tags[i+1])
+			}
+
+			continue
+		case t.closing:
+			depth--
+
+			continue
+		case depth > 0 || !t.context:
+			continue
+		}
+
+		switch t.number {
+		case 0:
+			if b.ObjectType == "" && len(t.data) == 4 {
+				typ, instance := bacnetObjectID(t.data)
+				b.ObjectType = bacnetObjectType(typ)
+				b.ObjectInstance = int32(instance)
+
+				if typ == bacnetObjectTypeDevice {
+					b.DeviceInstance = int32(instance)
+				}
+			}
+		case 1:
+			if b.Property == "" {
+				id := bacnetUnsigned(t.data)
+				b.PropertyID = int32(id)
+
+				if name, ok := bacnetProperties[id]; ok {
+					b.Property = name
+				} else {
+					b.Property = "property " + strconv.Itoa(int(id))
+				}
+			}
+		case 4:
+			if b.ServiceChoice == bacnetServiceWriteProperty {
+				b.WritePriority = int32(bacnetUnsigned(t.data))
+			}
+		}
+	}
+}
+
+// decodeBACnetError parses the error class and code, which are application tagged enumerations.
+func decodeBACnetError(data []byte, b *types.BACnet) {
+	tags, _ := parseBACnetTags(data)
+
+	// some services wrap the error in context tag 0
+	var values []bacnetTag
+
+	for _, t := range tags {
+		if !t.context && t.number == bacnetTagEnumerated {
+			values = append(values, t)
+		}
+	}
+
+	if len(values) < 2 {
+		return
+	}
+
+	class := bacnetUnsigned(values[0].data)
+	if name, ok := bacnetErrorClasses[class]; ok {
+		b.ErrorClass = name
+	} else {
+		b.ErrorClass = "class " + strconv.Itoa(int(class))
+	}
+
+	b.ErrorCode = int32(bacnetUnsigned(values[1].data))
+}
+
+// parseBACnetTags parses a sequence of application and context tags.
+func parseBACnetTags(data []byte) ([]bacnetTag, error) {
+	var tags []bacnetTag
+
+	for len(data) > 0 {
+		var (
+			initial = data[0]
+			t       = bacnetTag{
+				number:  initial >> 4,
+				context: initial&0x08 != 0,
+			}
+			length = int(initial & 0x07)
+		)
+
+		data = data[1:]
+
+		if t.number == 0x0f {
+			if len(data) < 1 {
+				return tags, errBACnetTruncated
+			}
+
+			t.number = data[0]
+			data = data[1:]
+		}
+
+		switch {
+		case t.context && length == 6:
+			t.opening = true
+			tags = append(tags, t)
+
+			continue
+		case t.context && length == 7:
+			t.closing = true
+			tags = append(tags, t)
+
+			continue
+		case !t.context && t.number == bacnetTagBoolean:
+			// the value of application booleans is encoded in the length field
+			t.data = []byte{byte(length)}
+			tags = append(tags, t)
+
+			continue
+		case length == 5:
+			if len(data) < 1 {
+				return tags, errBACnetTruncated
+			}
+
+			length = int(data[0])
+			data = data[1:]
+
+			switch length {
+			case 254:
+				if len(data) < 2 {
+					return tags, errBACnetTruncated
+				}
+
+				length = int(binary.BigEndian.Uint16(data))
+				data = data[2:]
+			case 255:
+				if len(data) < 4 {
+					return tags, errBACnetTruncated
+				}
+
+				length = int(binary.BigEndian.Uint32(data))
+				data = data[4:]
+			}
+		}
+
+		if length > len(data) || length < 0 {
+			return tags, errBACnetTruncated
+		}
+
+		t.data = data[:length]
+		data = data[length:]
+		tags = append(tags, t)
+	}
+
+	return tags, nil
+}
+
+// bacnetValue formats an application tagged value.
+func bacnetValue(t bacnetTag) string {
+	switch t.number {
+	case bacnetTagNull:
+		return "null"
+	case bacnetTagBoolean:
+		return strconv.FormatBool(len(t.data) == 1 && t.data[0] == 1)
+	case bacnetTagUnsigned, bacnetTagEnumerated:
+		return strconv.FormatUint(uint64(bacnetUnsigned(t.data)), 10)
+	case bacnetTagSigned:
+		return strconv.FormatInt(bacnetSigned(t.data), 10)
+	case bacnetTagReal:
+		if len(t.data) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(t.data))), 'f', -1, 32)
+		}
+	case bacnetTagDouble:
+		if len(t.data) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(t.data)), 'f', -1, 64)
+		}
+	case bacnetTagCharacterString:
+		// the first byte is the character set, only UTF-8 is decoded
+		if len(t.data) > 0 && t.data[0] == 0 && utf8.Valid(t.data[1:]) {
+			return string(t.data[1:])
+		}
+	case bacnetTagObjectID:
+		if len(t.data) == 4 {
+			typ, instance := bacnetObjectID(t.data)
+
+			return bacnetObjectType(typ) + "," + strconv.Itoa(int(instance))
+		}
+	}
+
+	return hex.EncodeToString(t.data)
+}
+
+func bacnetObjectID(data []byte) (typ, instance uint32) {
+	v := bacnetUnsigned(data)
+
+	return v >> 22, v & 0x3fffff
+}
+
+func bacnetObjectType(typ uint32) string {
+	if name, ok := bacnetObjectTypes[typ]; ok {
+		return name
+	}
+
+	return "object-type " + strconv.Itoa(int(typ))
+}
+
+func bacnetUnsigned(data []byte) uint32 {
+	var v uint32
+
+	for i := 0; i < len(data) && i < 4; i++ {
+		v = v<<8 | uint32(data[i])
+	}
+
+	return v
+}
+
+func bacnetSigned(data []byte) int64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	v := int64(int8(data[0]))
+	for _, c := range data[1:] {
+		v = v<<8 | int64(c)
+	}
+
+	return v
+}
+
+// bacnetDeviceAddress returns the IP address of the device whose instance number is contained in the message.
+// Devices announce their instance number via I-Am, forwarded broadcasts carry the address of the originating device.
+// Property reads of device objects are answered by the device itself.
+func bacnetDeviceAddress(b *types.BACnet) string {
+	if b.ObjectType != bacnetObjectTypes[bacnetObjectTypeDevice] {
+		return ""
+	}
+
+	switch {
+	case b.APDUType == bacnetAPDUTypes[apduUnconfirmedRequest] && b.ServiceChoice == bacnetServiceIAm:
+		if b.ForwardedAddress != "" {
+			ip, _, _ := net.SplitHostPort(b.ForwardedAddress)
+
+			return ip
+		}
+
+		return b.SrcIP
+	case b.APDUType == bacnetAPDUTypes[apduComplexACK] && b.ServiceChoice == bacnetServiceReadProperty:
+		return b.SrcIP
+	}
+
+	return ""
+}
+
+// bacnetDeviceID formats a device identifier for the device profiles.
+func bacnetDeviceID(instance int32) string {
+	return "bacnet:" + strconv.Itoa(int(instance))
+}
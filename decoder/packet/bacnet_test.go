@@ -0,0 +1,93 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestBACnetIAm(t *testing.T) {
+	payload := []byte{
+		0x81, 0x0b, 0x00, 0x18,
+		0x01, 0x20, 0xff, 0xff, 0x00, 0xff,
+		0x10, 0x00, 0xc4, 0x02, 0x00, 0x04, 0xd2, 0x22, 0x05, 0xc4, 0x91, 0x03, 0x21, 0x05,
+	}
+
+	r := bacnetDecoder.Handler(testUDPPacket(t, "192.168.1.20", "192.168.1.255", 47808, 47808, payload))
+	if r == nil {
+		t.Fatal("expected BACnet record")
+	}
+
+	b := r.(*types.BACnet)
+	if b.BVLCFunction != "Original-Broadcast-NPDU" || b.DestinationNetwork != 65535 || b.HopCount != 255 {
+		t.Fatalf("unexpected BACnet network layer: %+v", b)
+	}
+
+	if b.Service != "i-Am" || b.DeviceInstance != 1234 || b.MaxAPDU != 1476 || b.Segmentation != "no-segmentation" || b.VendorID != 5 {
+		t.Fatalf("unexpected I-Am: %+v", b)
+	}
+
+	ids := decoderutils.DeviceIdentifiers("192.168.1.20")
+
+	if len(ids) != 1 || ids[0] != "bacnet:1234" {
+		t.Fatalf("unexpected device identifiers: %v", ids)
+	}
+
+	dp := &types.DeviceProfile{DeviceIPs: []string{"192.168.1.20"}}
+	applyDeviceInfo(dp)
+	applyDeviceInfo(dp)
+
+	if len(dp.DeviceIDs) != 1 || dp.DeviceIDs[0] != "bacnet:1234" {
+		t.Fatalf("unexpected device profile identifiers: %v", dp.DeviceIDs)
+	}
+}
+
+func TestBACnetWriteProperty(t *testing.T) {
+	// write 100.0 to the present value of analog-output 5 with priority 8
+	payload := []byte{
+		0x81, 0x0a, 0x00, 0x1a,
+		0x01, 0x04,
+		0x00, 0x05, 0x01, 0x0f, 0x0c, 0x00, 0x40, 0x00, 0x05, 0x19, 0x55, 0x3e, 0x44, 0x42, 0xc8, 0x00, 0x00, 0x3f, 0x49, 0x08,
+	}
+
+	r := bacnetDecoder.Handler(testUDPPacket(t, "192.168.1.10", "192.168.1.20", 47808, 47808, payload))
+	if r == nil {
+		t.Fatal("expected BACnet record")
+	}
+
+	b := r.(*types.BACnet)
+	if b.APDUType != "Confirmed-Request" || b.Service != "writeProperty" || b.InvokeID != 1 || !b.ExpectingReply {
+		t.Fatalf("unexpected write request: %+v", b)
+	}
+
+	if b.ObjectType != "analog-output" || b.ObjectInstance != 5 || b.Property != "present-value" || b.Value != "100" || b.WritePriority != 8 {
+		t.Fatalf("unexpected write property: %+v", b)
+	}
+
+	// the device rejects the write
+	payload = []byte{0x81, 0x0a, 0x00, 0x0d, 0x01, 0x00, 0x50, 0x01, 0x0f, 0x91, 0x02, 0x91, 0x20}
+
+	r = bacnetDecoder.Handler(testUDPPacket(t, "192.168.1.20", "192.168.1.10", 47808, 47808, payload))
+	if r == nil {
+		t.Fatal("expected BACnet record")
+	}
+
+	b = r.(*types.BACnet)
+	if b.APDUType != "Error" || b.Service != "writeProperty" || b.ErrorClass != "property" || b.ErrorCode != 32 {
+		t.Fatalf("unexpected error: %+v", b)
+	}
+}
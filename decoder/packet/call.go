@@ -0,0 +1,559 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// reasons for the termination of a call.
+const (
+	callEndBye        = "BYE"
+	callEndCancel     = "CANCEL"
+	callEndRejected   = "rejected"
+	callEndIncomplete = "incomplete"
+	callEndTimeout    = "timeout"
+)
+
+// callTimeout is the time after which a call without any signaling or media packets is flushed.
+const callTimeout = 5 * time.Minute
+
+// callFileNameReplacer removes path separators from Call-IDs used in file names.
+var callFileNameReplacer = strings.NewReplacer("/", "-", "\\", "-", ":", "-")
+
+// sdpCodec describes a payload type from a SDP media description.
+type sdpCodec struct {
+	name      string
+	clockRate uint32
+}
+
+// staticPayloadTypes contains the statically assigned RTP payload types from RFC 3551.
+var staticPayloadTypes = map[uint8]sdpCodec{
+	0:  {"PCMU", 8000},
+	3:  {"GSM", 8000},
+	4:  {"G723", 8000},
+	8:  {"PCMA", 8000},
+	9:  {"G722", 8000},
+	13: {"CN", 8000},
+	18: {"G729", 8000},
+	26: {"JPEG", 90000},
+	31: {"H261", 90000},
+	34: {"H263", 90000},
+}
+
+// voipCall correlates a SIP dialog with the RTP streams negotiated via SDP.
+type voipCall struct {
+	record *types.Call
+
+	// codecs negotiated for the dynamic payload types
+	codecs map[uint8]sdpCodec
+
+	// RTP streams by SSRC
+	streams map[uint32]*rtpStream
+
+	// media endpoints registered for the call
+	media []string
+
+	// timestamp of the last SIP or RTP packet for the call
+	last int64
+}
+
+// voipCalls holds the active calls by Call-ID and by their media endpoints.
+var voipCalls = struct {
+	sync.Mutex
+	calls     map[string]*voipCall
+	media     map[string]*voipCall
+	lastSweep time.Time
+
+	// decoder used to write the calls that timed out
+	decoder *Decoder
+}{
+	calls: make(map[string]*voipCall),
+	media: make(map[string]*voipCall),
+}
+
+var callDecoder = newPacketDecoder(
+	types.Type_NC_Call,
+	"Call",
+	"A VoIP call, correlated from the SIP dialog and the RTP streams negotiated via SDP",
+	func(d *Decoder) error {
+		voipCalls.Lock()
+		voipCalls.decoder = d
+		voipCalls.Unlock()
+
+		return nil
+	},
+	func(p gopacket.Packet) proto.Message {
+		if l := p.Layer(layers.LayerTypeSIP); l != nil {
+			if sip, ok := l.(*layers.SIP); ok {
+				return handleSIPDialog(sip, p)
+			}
+
+			return nil
+		}
+
+		if l := p.Layer(layers.LayerTypeUDP); l != nil {
+			if udp, ok := l.(*layers.UDP); ok && len(udp.Payload) > 0 {
+				handleRTP(udp, p)
+			}
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		voipCalls.Lock()
+		defer voipCalls.Unlock()
+
+		// flush calls that have not been terminated
+		for _, c := range voipCalls.calls {
+			d.write(c.finish(callEndIncomplete, c.lastSeen()))
+		}
+
+		return nil
+	},
+)
+
+// handleSIPDialog updates the call state for SIP requests and responses.
+// It returns the audit record once a call has been terminated.
+func handleSIPDialog(sip *layers.SIP, p gopacket.Packet) proto.Message {
+	callID := sip.GetCallID()
+	if callID == "" {
+		return nil
+	}
+
+	var (
+		srcIP string
+		ts    = p.Metadata().Timestamp.UnixNano()
+	)
+
+	if nl := p.NetworkLayer(); nl != nil {
+		srcIP = nl.NetworkFlow().Src().String()
+	}
+
+	voipCalls.Lock()
+	defer voipCalls.Unlock()
+
+	expireCalls(p.Metadata().Timestamp)
+
+	c, exists := voipCalls.calls[callID]
+	if exists {
+		c.last = ts
+	}
+
+	if !sip.IsResponse {
+		switch sip.Method {
+		case layers.SIPMethodInvite:
+			if !exists {
+				c = &voipCall{
+					record: &types.Call{
+						Timestamp:       ts,
+						CallID:          callID,
+						From:            sip.GetFrom(),
+						To:              sip.GetTo(),
+						CallerUserAgent: sip.GetUserAgent(),
+						CallerIP:        srcIP,
+					},
+					codecs:  make(map[uint8]sdpCodec),
+					streams: make(map[uint32]*rtpStream),
+					last:    ts,
+				}
+				voipCalls.calls[callID] = c
+			}
+
+			c.applySDP(sip.Payload())
+		case layers.SIPMethodAck:
+			// the answer can be delivered in the ACK for a late offer
+			if exists {
+				c.applySDP(sip.Payload())
+			}
+		case layers.SIPMethodBye:
+			if exists {
+				return c.finish(callEndBye, ts)
+			}
+		case layers.SIPMethodCancel:
+			if exists {
+				return c.finish(callEndCancel, ts)
+			}
+		}
+
+		return nil
+	}
+
+	// responses are matched to the INVITE via the method in the CSeq header
+	if !exists || sip.Method != layers.SIPMethodInvite {
+		return nil
+	}
+
+	c.record.StatusCode = int32(sip.ResponseCode)
+
+	switch {
+	case sip.ResponseCode >= 200 && sip.ResponseCode < 300:
+		if !c.record.Answered {
+			c.record.Answered = true
+			c.record.AnswerTimestamp = ts
+			c.record.CalleeIP = srcIP
+
+			c.record.CalleeUserAgent = sip.GetFirstHeader("Server")
+			if c.record.CalleeUserAgent == "" {
+				c.record.CalleeUserAgent = sip.GetUserAgent()
+			}
+		}
+
+		c.applySDP(sip.Payload())
+	case sip.ResponseCode == 401 || sip.ResponseCode == 407:
+		// authentication challenge, the caller will retry with credentials
+	case sip.ResponseCode >= 300:
+		return c.finish(callEndRejected, ts)
+	}
+
+	return nil
+}
+
+// handleRTP updates the RTP stream statistics for a packet sent to or from a known media endpoint.
+func handleRTP(udp *layers.UDP, p gopacket.Packet) {
+	nl := p.NetworkLayer()
+	if nl == nil {
+		return
+	}
+
+	var (
+		srcIP = nl.NetworkFlow().Src().String()
+		dstIP = nl.NetworkFlow().Dst().String()
+	)
+
+	voipCalls.Lock()
+	defer voipCalls.Unlock()
+
+	expireCalls(p.Metadata().Timestamp)
+
+	c, ok := voipCalls.media[net.JoinHostPort(dstIP, strconv.Itoa(int(udp.DstPort)))]
+	if !ok {
+		if c, ok = voipCalls.media[net.JoinHostPort(srcIP, strconv.Itoa(int(udp.SrcPort)))]; !ok {
+			return
+		}
+	}
+
+	if isRTCP(udp.Payload) {
+		reports, err := parseRTCP(udp.Payload)
+		if err != nil {
+			decoderLog.Debug("failed to parse RTCP packet", zap.Error(err))
+		}
+
+		for _, r := range reports {
+			if s, exists := c.streams[r.ssrc]; exists {
+				s.applyReport(r)
+			}
+		}
+
+		return
+	}
+
+	h, err := parseRTP(udp.Payload)
+	if err != nil {
+		return
+	}
+
+	s, exists := c.streams[h.ssrc]
+	if !exists {
+		codec := c.codec(h.payloadType)
+
+		s = newRTPStream(h, codec.clockRate)
+		s.Codec = codec.name
+		s.SrcIP = srcIP
+		s.SrcPort = int32(udp.SrcPort)
+		s.DstIP = dstIP
+		s.DstPort = int32(udp.DstPort)
+
+		c.streams[h.ssrc] = s
+	}
+
+	c.last = p.Metadata().Timestamp.UnixNano()
+
+	collectAudio := conf != nil && conf.FileStorage != "" && (h.payloadType == 0 || h.payloadType == 8)
+
+	s.update(h, p.Metadata().Timestamp, collectAudio)
+}
+
+// codec returns the codec for a payload type.
+func (c *voipCall) codec(pt uint8) sdpCodec {
+	if codec, ok := c.codecs[pt]; ok {
+		return codec
+	}
+
+	if codec, ok := staticPayloadTypes[pt]; ok {
+		return codec
+	}
+
+	return sdpCodec{
+		name:      strconv.Itoa(int(pt)),
+		clockRate: 8000,
+	}
+}
+
+// applySDP registers the media endpoints and codecs from a session description.
+func (c *voipCall) applySDP(body []byte) {
+	endpoints, codecs := parseSDP(body)
+
+	for pt, codec := range codecs {
+		c.codecs[pt] = codec
+	}
+
+	for _, e := range endpoints {
+		if _, exists := voipCalls.media[e]; exists {
+			continue
+		}
+
+		voipCalls.media[e] = c
+		c.media = append(c.media, e)
+	}
+}
+
+// parseSDP returns the RTP and RTCP endpoints and the codecs for the dynamic payload types.
+func parseSDP(body []byte) (endpoints []string, codecs map[uint8]sdpCodec) {
+	codecs = make(map[uint8]sdpCodec)
+
+	var (
+		sessionAddr string
+		mediaAddr   string
+		inMedia     bool
+		ports       []int
+		scanner     = bufio.NewScanner(bytes.NewReader(body))
+	)
+
+	addMedia := func() {
+		addr := mediaAddr
+		if addr == "" {
+			addr = sessionAddr
+		}
+
+		for _, port := range ports {
+			endpoints = append(endpoints,
+				net.JoinHostPort(addr, strconv.Itoa(port)),
+				net.JoinHostPort(addr, strconv.Itoa(port+1)),
+			)
+		}
+
+		ports = nil
+		mediaAddr = ""
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+
+		value := line[2:]
+
+		switch line[0] {
+		case 'c':
+			// c=IN IP4 192.0.2.1
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				continue
+			}
+
+			addr := strings.Split(fields[2], "/")[0]
+			if !inMedia {
+				sessionAddr = addr
+			} else {
+				mediaAddr = addr
+			}
+		case 'm':
+			addMedia()
+
+			inMedia = true
+
+			// m=audio 49170 RTP/AVP 0 8 101
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				continue
+			}
+
+			port, err := strconv.Atoi(strings.Split(fields[1], "/")[0])
+			if err != nil || port == 0 {
+				// a port of zero rejects the media stream
+				continue
+			}
+
+			ports = append(ports, port)
+		case 'a':
+			// a=rtpmap:101 telephone-event/8000
+			if !strings.HasPrefix(value, "rtpmap:") {
+				continue
+			}
+
+			fields := strings.Fields(strings.TrimPrefix(value, "rtpmap:"))
+			if len(fields) < 2 {
+				continue
+			}
+
+			pt, err := strconv.ParseUint(fields[0], 10, 8)
+			if err != nil {
+				continue
+			}
+
+			var (
+				enc   = strings.Split(fields[1], "/")
+				codec = sdpCodec{name: enc[0]}
+			)
+
+			if len(enc) > 1 {
+				if rate, errRate := strconv.ParseUint(enc[1], 10, 32); errRate == nil {
+					codec.clockRate = uint32(rate)
+				}
+			}
+
+			codecs[uint8(pt)] = codec
+		}
+	}
+
+	addMedia()
+
+	return endpoints, codecs
+}
+
+// lastSeen returns the timestamp of the last packet seen for the call.
+func (c *voipCall) lastSeen() int64 {
+	last := c.last
+	for _, s := range c.streams {
+		if s.TimestampLast > last {
+			last = s.TimestampLast
+		}
+	}
+
+	return last
+}
+
+// expireCalls flushes the calls that have not seen any packets within the callTimeout.
+// The lock on voipCalls must be held by the caller.
+func expireCalls(ts time.Time) {
+	// check at most once per timeout,
+	// time jumps backwards when the timestamps of the input are not ordered
+	if d := ts.Sub(voipCalls.lastSweep); d <= callTimeout && d >= -callTimeout {
+		return
+	}
+
+	voipCalls.lastSweep = ts
+
+	for _, c := range voipCalls.calls {
+		last := c.lastSeen()
+		if d := ts.Sub(time.Unix(0, last)); d > callTimeout || d < -callTimeout {
+			r := c.finish(callEndTimeout, last)
+
+			// prevent nil pointer access if the call decoder is not initialized
+			if voipCalls.decoder != nil {
+				voipCalls.decoder.write(r)
+			}
+		}
+	}
+}
+
+// finish removes the call from the state, saves the audio and returns the audit record.
+// The lock on voipCalls must be held by the caller.
+func (c *voipCall) finish(reason string, ts int64) *types.Call {
+	delete(voipCalls.calls, c.record.CallID)
+
+	for _, e := range c.media {
+		delete(voipCalls.media, e)
+	}
+
+	r := c.record
+	r.EndReason = reason
+	r.EndTimestamp = ts
+
+	if r.Answered {
+		r.Duration = time.Unix(0, ts).Sub(time.Unix(0, r.AnswerTimestamp)).Nanoseconds()
+	}
+
+	streams := make([]*rtpStream, 0, len(c.streams))
+	for _, s := range c.streams {
+		streams = append(streams, s)
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].TimestampFirst < streams[j].TimestampFirst
+	})
+
+	codecs := make(map[string]struct{})
+
+	for _, s := range streams {
+		r.Streams = append(r.Streams, s.RTPStream)
+
+		if _, ok := codecs[s.Codec]; !ok {
+			codecs[s.Codec] = struct{}{}
+			r.Codecs = append(r.Codecs, s.Codec)
+		}
+
+		if len(s.audio) > 0 {
+			saveCallAudio(r, s)
+		}
+	}
+
+	return r
+}
+
+// saveCallAudio writes the G.711 audio of a stream to disk as WAV file.
+func saveCallAudio(r *types.Call, s *rtpStream) {
+	var wav []byte
+
+	switch s.PayloadType {
+	case 0:
+		wav = g711ToWAV(s.audioPayload(pcmuSilence), decodeMuLaw)
+	case 8:
+		wav = g711ToWAV(s.audioPayload(pcmaSilence), decodeALaw)
+	default:
+		return
+	}
+
+	var (
+		srcPort = strconv.Itoa(int(s.SrcPort))
+		dstPort = strconv.Itoa(int(s.DstPort))
+		conv    = &core.ConversationInfo{
+			Ident:             utils.CreateFlowIdent(s.SrcIP, srcPort, s.DstIP, dstPort),
+			FirstClientPacket: time.Unix(0, s.TimestampFirst),
+			FirstServerPacket: time.Unix(0, s.TimestampFirst),
+			ClientIP:          s.SrcIP,
+			ServerIP:          s.DstIP,
+			ClientPort:        s.SrcPort,
+			ServerPort:        s.DstPort,
+		}
+		name = "call-" + callFileNameReplacer.Replace(r.CallID) + "-" + strconv.FormatUint(uint64(s.SSRC), 16) + ".wav"
+	)
+
+	err := streamutils.SaveFile(conv, "RTP "+s.Codec, name, nil, wav, nil, "", "audio/wav")
+	if err != nil {
+		decoderLog.Error("failed to save call audio", zap.Error(err), zap.String("callID", r.CallID))
+	}
+
+	// release the audio buffer
+	s.audio = nil
+}
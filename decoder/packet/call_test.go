@@ -0,0 +1,307 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// testUDPPacket returns an IPv4 packet with a UDP datagram carrying the payload.
+func testUDPPacket(t *testing.T, srcIP, dstIP string, srcPort, dstPort uint16, payload []byte) gopacket.Packet {
+	t.Helper()
+
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP(srcIP),
+		DstIP:    net.ParseIP(dstIP),
+	}
+	udp := &layers.UDP{
+		SrcPort: layers.UDPPort(srcPort),
+		DstPort: layers.UDPPort(dstPort),
+	}
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+
+	if err := gopacket.SerializeLayers(buf, opts, ip, udp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+	p.Metadata().Timestamp = time.Now()
+
+	return p
+}
+
+const testSDPOffer = "v=0\r\n" +
+	"o=alice 2890844526 2890844526 IN IP4 10.0.0.1\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 10.0.0.1\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 49170 RTP/AVP 0 8 101\r\n" +
+	"a=rtpmap:0 PCMU/8000\r\n" +
+	"a=rtpmap:101 telephone-event/8000\r\n" +
+	"m=video 0 RTP/AVP 31\r\n"
+
+const testSDPAnswer = "v=0\r\n" +
+	"o=bob 2808844564 2808844564 IN IP4 10.0.0.2\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 10.0.0.2\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 3456 RTP/AVP 0\r\n"
+
+func testSIPMessage(firstLine, method string, cseq int, body string) []byte {
+	msg := firstLine + "\r\n" +
+		"Via: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK776asdhds\r\n" +
+		"From: Alice <sip:alice@example.com>;tag=1928301774\r\n" +
+		"To: Bob <sip:bob@example.com>\r\n" +
+		"Call-ID: a84b4c76e66710@pc33.example.com\r\n" +
+		"CSeq: " + strconv.Itoa(cseq) + " " + method + "\r\n" +
+		"User-Agent: softphone 1.0\r\n"
+
+	if body != "" {
+		msg += "Content-Type: application/sdp\r\n"
+	}
+
+	return []byte(msg + "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body)
+}
+
+func testRTPPacket(seq uint16, ts uint32, ssrc uint32, pt uint8, payload []byte) []byte {
+	b := make([]byte, 12, 12+len(payload))
+	b[0] = 0x80
+	b[1] = pt
+	binary.BigEndian.PutUint16(b[2:4], seq)
+	binary.BigEndian.PutUint32(b[4:8], ts)
+	binary.BigEndian.PutUint32(b[8:12], ssrc)
+
+	return append(b, payload...)
+}
+
+func TestParseSDP(t *testing.T) {
+	endpoints, codecs := parseSDP([]byte(testSDPOffer))
+
+	if len(endpoints) != 2 || endpoints[0] != "10.0.0.1:49170" || endpoints[1] != "10.0.0.1:49171" {
+		t.Fatal("unexpected endpoints", endpoints)
+	}
+
+	if c := codecs[101]; c.name != "telephone-event" || c.clockRate != 8000 {
+		t.Fatal("unexpected codec", c)
+	}
+}
+
+func TestRTPStreamStatistics(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		s     *rtpStream
+	)
+
+	// packet 3 is lost, the sequence number rolls over
+	for i, seq := range []uint16{65534, 65535, 1, 2} {
+		h, err := parseRTP(testRTPPacket(seq, uint32(i)*160, 1, 0, make([]byte, 160)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s == nil {
+			s = newRTPStream(h, 8000)
+		}
+
+		s.update(h, start.Add(time.Duration(i)*20*time.Millisecond), true)
+	}
+
+	if s.Packets != 4 || s.Lost != 1 {
+		t.Fatal("unexpected packet statistics", s.Packets, s.Lost)
+	}
+
+	// packets arrived in perfect intervals
+	if s.Jitter > 0.2 {
+		t.Fatal("unexpected jitter", s.Jitter)
+	}
+
+	if audio := s.audioPayload(pcmuSilence); len(audio) != 5*160 {
+		t.Fatal("unexpected audio length", len(audio))
+	}
+}
+
+func TestParseRTCP(t *testing.T) {
+	// receiver report with a single report block
+	rr := []byte{
+		0x81, 201, 0x00, 0x07,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x01,
+		0x40, 0x00, 0x00, 0x05,
+		0x00, 0x00, 0x01, 0x00,
+		0x00, 0x00, 0x00, 0x50,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+
+	if !isRTCP(rr) {
+		t.Fatal("receiver report not detected")
+	}
+
+	reports, err := parseRTCP(rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 1 || reports[0].ssrc != 1 || reports[0].fractionLost != 0x40 || reports[0].cumLost != 5 || reports[0].jitter != 80 {
+		t.Fatal("unexpected reports", reports)
+	}
+}
+
+func TestG711(t *testing.T) {
+	if decodeMuLaw(pcmuSilence) != 0 || decodeALaw(pcmaSilence) != 8 {
+		t.Fatal("unexpected silence values", decodeMuLaw(pcmuSilence), decodeALaw(pcmaSilence))
+	}
+
+	if decodeMuLaw(0x00) != -32124 || decodeMuLaw(0x80) != 32124 {
+		t.Fatal("unexpected µ-law peak values", decodeMuLaw(0x00), decodeMuLaw(0x80))
+	}
+
+	wav := g711ToWAV([]byte{0xff, 0xff}, decodeMuLaw)
+	if len(wav) != 48 || string(wav[:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		t.Fatal("invalid WAV file")
+	}
+}
+
+func TestCallDialog(t *testing.T) {
+	const (
+		caller = "10.0.0.1"
+		callee = "10.0.0.2"
+	)
+
+	packets := []gopacket.Packet{
+		testUDPPacket(t, caller, callee, 5060, 5060, testSIPMessage("INVITE sip:bob@example.com SIP/2.0", "INVITE", 1, testSDPOffer)),
+		testUDPPacket(t, callee, caller, 5060, 5060, testSIPMessage("SIP/2.0 200 OK", "INVITE", 1, testSDPAnswer)),
+		testUDPPacket(t, caller, callee, 49170, 3456, testRTPPacket(10, 0, 0xabcd, 0, make([]byte, 160))),
+		testUDPPacket(t, callee, caller, 3456, 49170, testRTPPacket(20, 0, 0x1234, 0, make([]byte, 160))),
+		testUDPPacket(t, caller, callee, 49170, 3456, testRTPPacket(11, 160, 0xabcd, 0, make([]byte, 160))),
+		testUDPPacket(t, caller, callee, 5060, 5060, testSIPMessage("BYE sip:bob@example.com SIP/2.0", "BYE", 2, "")),
+	}
+
+	var record *types.Call
+
+	for _, p := range packets {
+		if r := callDecoder.Handler(p); r != nil {
+			record = r.(*types.Call)
+		}
+	}
+
+	if record == nil {
+		t.Fatal("call has not been terminated")
+	}
+
+	if record.CallID != "a84b4c76e66710@pc33.example.com" || !record.Answered || record.EndReason != callEndBye {
+		t.Fatal("unexpected call values", record.CallID, record.Answered, record.EndReason)
+	}
+
+	if record.CallerUserAgent != "softphone 1.0" || record.CalleeIP != callee {
+		t.Fatal("unexpected dialog values", record.CallerUserAgent, record.CalleeIP)
+	}
+
+	if len(record.Streams) != 2 || record.Streams[0].SSRC != 0xabcd || record.Streams[0].Packets != 2 {
+		t.Fatal("unexpected streams", record.Streams)
+	}
+
+	if len(record.Codecs) != 1 || record.Codecs[0] != "PCMU" {
+		t.Fatal("unexpected codecs", record.Codecs)
+	}
+
+	if len(voipCalls.calls) != 0 || len(voipCalls.media) != 0 {
+		t.Fatal("call state has not been cleaned up")
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	const callID = "a84b4c76e66710@pc33.example.com"
+
+	start := time.Unix(1600000000, 0)
+
+	invite := testUDPPacket(t, "10.0.0.1", "10.0.0.2", 5060, 5060, testSIPMessage("INVITE sip:bob@example.com SIP/2.0", "INVITE", 1, testSDPOffer))
+	invite.Metadata().Timestamp = start
+
+	rtp := testUDPPacket(t, "10.0.0.2", "10.0.0.1", 3456, 49170, testRTPPacket(1, 0, 0x1234, 0, make([]byte, 160)))
+	rtp.Metadata().Timestamp = start.Add(time.Minute)
+
+	for _, p := range []gopacket.Packet{invite, rtp} {
+		if r := callDecoder.Handler(p); r != nil {
+			t.Fatal("unexpected record", r)
+		}
+	}
+
+	voipCalls.Lock()
+	c, ok := voipCalls.calls[callID]
+	voipCalls.Unlock()
+
+	if !ok {
+		t.Fatal("call has not been tracked")
+	}
+
+	// the media is still active, the call must be kept
+	other := testUDPPacket(t, "10.0.0.3", "10.0.0.4", 4000, 4000, testRTPPacket(1, 0, 1, 0, nil))
+	other.Metadata().Timestamp = start.Add(callTimeout + time.Second)
+	callDecoder.Handler(other)
+
+	if _, ok = voipCalls.calls[callID]; !ok {
+		t.Fatal("active call has been flushed")
+	}
+
+	other.Metadata().Timestamp = start.Add(time.Minute + 2*callTimeout + time.Second)
+	callDecoder.Handler(other)
+
+	if len(voipCalls.calls) != 0 || len(voipCalls.media) != 0 {
+		t.Fatal("idle call has not been flushed")
+	}
+
+	if c.record.EndReason != callEndTimeout || c.record.EndTimestamp != rtp.Metadata().Timestamp.UnixNano() || len(c.record.Streams) != 1 {
+		t.Fatal("unexpected values for timed out call", c.record.EndReason, c.record.EndTimestamp, c.record.Streams)
+	}
+}
+
+func TestRTPAudioLimit(t *testing.T) {
+	var s *rtpStream
+
+	for seq := uint16(0); seq < 3; seq++ {
+		h, err := parseRTP(testRTPPacket(seq, uint32(seq)*160, 1, 0, make([]byte, maxRTPAudioSize/2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s == nil {
+			s = newRTPStream(h, 8000)
+		}
+
+		s.update(h, time.Unix(1600000000, 0), true)
+	}
+
+	if len(s.audio) != 2 || s.audioSize != maxRTPAudioSize || s.Packets != 3 {
+		t.Fatal("unexpected buffered audio", len(s.audio), s.audioSize, s.Packets)
+	}
+}
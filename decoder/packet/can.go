@@ -0,0 +1,383 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	canHeaderSize = 8
+
+	// flags in the upper bits of the SocketCAN identifier
+	canEFFFlag = 0x80000000
+	canRTRFlag = 0x40000000
+	canERRFlag = 0x20000000
+
+	canSFFMask = 0x000007ff
+	canEFFMask = 0x1fffffff
+
+	// CAN FD frames are marked with a flag, or recognized by the size of the SocketCAN structure
+	canFDFlag = 0x04
+	canFDMTU  = 72
+
+	// number of intervals after which the mean interval of an identifier is considered stable
+	canIntervalWarmup = 10
+
+	// limits the number of distinct payloads tracked per identifier
+	canMaxPayloads = 1024
+)
+
+// LinkTypeCANSocketCAN is the link type for CAN frames captured via Linux SocketCAN.
+const LinkTypeCANSocketCAN layers.LinkType = 227
+
+// error classes encoded in the identifier of SocketCAN error frames.
+var canErrorClasses = []string{
+	"TXTimeout",
+	"LostArbitration",
+	"Controller",
+	"Protocol",
+	"Transceiver",
+	"NoACK",
+	"BusOff",
+	"BusError",
+	"Restarted",
+}
+
+// LayerTypeCAN is the gopacket layer type for SocketCAN frames.
+var LayerTypeCAN = gopacket.RegisterLayerType(layerNumCAN, gopacket.LayerTypeMetadata{
+	Name:    "CAN",
+	Decoder: gopacket.DecodeFunc(decodeCANLayer),
+})
+
+// CAN is a Controller Area Network frame in the SocketCAN format.
+type CAN struct {
+	layers.BaseLayer
+	ID       uint32
+	Extended bool
+	Remote   bool
+	Error    bool
+	FD       bool
+	Data     []byte
+}
+
+// LayerType returns LayerTypeCAN.
+func (c *CAN) LayerType() gopacket.LayerType {
+	return LayerTypeCAN
+}
+
+// DecodeFromBytes decodes a SocketCAN frame, the identifier is in network byte order.
+func (c *CAN) DecodeFromBytes(data []byte) error {
+	if len(data) < canHeaderSize {
+		return errors.New("CAN frame too short")
+	}
+
+	id := binary.BigEndian.Uint32(data[0:4])
+
+	c.Extended = id&canEFFFlag != 0
+	c.Remote = id&canRTRFlag != 0
+	c.Error = id&canERRFlag != 0
+
+	if c.Extended || c.Error {
+		c.ID = id & canEFFMask
+	} else {
+		c.ID = id & canSFFMask
+	}
+
+	length := int(data[4])
+	c.FD = data[5]&canFDFlag != 0 || len(data) == canFDMTU
+
+	if length > len(data)-canHeaderSize {
+		length = len(data) - canHeaderSize
+	}
+
+	c.Data = data[canHeaderSize : canHeaderSize+length]
+	c.BaseLayer = layers.BaseLayer{Contents: data}
+
+	return nil
+}
+
+func decodeCANLayer(data []byte, p gopacket.PacketBuilder) error {
+	c := &CAN{}
+	if err := c.DecodeFromBytes(data); err != nil {
+		return err
+	}
+
+	p.AddLayer(c)
+
+	return nil
+}
+
+// canDBC holds the DBC file configured for decoding signals, it is loaded on first use.
+var canDBC struct {
+	sync.Once
+	db dbcDatabase
+}
+
+// canMessage returns the message definition for the frame from the configured DBC file.
+func canMessage(c *CAN) *dbcMessage {
+	canDBC.Do(func() {
+		if conf == nil || conf.DBCFile == "" {
+			return
+		}
+
+		f, err := os.Open(conf.DBCFile)
+		if err != nil {
+			decoderLog.Error("failed to open DBC file", zap.Error(err))
+
+			return
+		}
+		defer f.Close()
+
+		if canDBC.db, err = parseDBC(f); err != nil {
+			decoderLog.Error("failed to parse DBC file", zap.Error(err))
+		}
+	})
+
+	if canDBC.db == nil {
+		return nil
+	}
+
+	id := c.ID
+	if c.Extended {
+		id |= canEFFFlag
+	}
+
+	return canDBC.db[id]
+}
+
+var canDecoder = newGoPacketDecoder(
+	types.Type_NC_CAN,
+	LayerTypeCAN,
+	"The Controller Area Network connects the electronic control units in vehicles and industrial machines, signals are decoded if a DBC file is provided",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if c, ok := layer.(*CAN); ok {
+			r := decodeCAN(c)
+			r.Timestamp = timestamp
+
+			return r
+		}
+
+		return nil
+	},
+)
+
+// decodeCAN converts the frame to an audit record.
+func decodeCAN(c *CAN) *types.CAN {
+	r := &types.CAN{
+		ID:       c.ID,
+		Extended: c.Extended,
+		Remote:   c.Remote,
+		Error:    c.Error,
+		FD:       c.FD,
+		Length:   int32(len(c.Data)),
+		Data:     c.Data,
+	}
+
+	if c.Error {
+		for i, class := range canErrorClasses {
+			if c.ID&(1<<uint(i)) != 0 {
+				r.ErrorClasses = append(r.ErrorClasses, class)
+			}
+		}
+
+		return r
+	}
+
+	if m := canMessage(c); m != nil {
+		r.Message = m.name
+
+		if !c.Remote {
+			r.Signals = m.decode(c.Data)
+		}
+	}
+
+	return r
+}
+
+// canStats collects the timing and payload statistics of an identifier.
+type canStats struct {
+	summary *types.CANSummary
+	last    int64
+
+	// intervals in milliseconds
+	intervals int64
+	sum       float64
+	sumSq     float64
+
+	payloads map[string]struct{}
+
+	// per data byte position
+	byteCount [64]int64
+	byteSum   [64]float64
+	byteSumSq [64]float64
+}
+
+// canIdentifiers tracks the statistics for each CAN identifier.
+type canIdentifiers struct {
+	sync.Mutex
+	items map[uint32]*canStats
+}
+
+func newCANIdentifiers() *canIdentifiers {
+	return &canIdentifiers{
+		items: make(map[uint32]*canStats),
+	}
+}
+
+var canIDs = newCANIdentifiers()
+
+// update adds a frame to the statistics of its identifier.
+// An interval shorter than half the mean interval so far hints at frames injected in addition to the legitimate sender.
+func (c *canIdentifiers) update(f *CAN, ts time.Time) {
+	key := f.ID
+	if f.Extended {
+		key |= canEFFFlag
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, ok := c.items[key]
+	if !ok {
+		s = &canStats{
+			summary: &types.CANSummary{
+				Timestamp: ts.UnixNano(),
+				ID:        f.ID,
+				Extended:  f.Extended,
+			},
+			payloads: make(map[string]struct{}),
+		}
+
+		if m := canMessage(f); m != nil {
+			s.summary.Message = m.name
+		}
+
+		c.items[key] = s
+	}
+
+	sum := s.summary
+
+	if sum.NumFrames > 0 {
+		interval := float64(ts.UnixNano()-s.last) / float64(time.Millisecond)
+
+		if s.intervals >= canIntervalWarmup && interval < s.sum/float64(s.intervals)/2 {
+			sum.ShortIntervals++
+		}
+
+		if s.intervals == 0 || interval < sum.MinInterval {
+			sum.MinInterval = interval
+		}
+
+		s.intervals++
+		s.sum += interval
+		s.sumSq += interval * interval
+	}
+
+	sum.NumFrames++
+	sum.LastSeen = ts.UnixNano()
+	s.last = ts.UnixNano()
+
+	if !containsInt32(sum.Lengths, int32(len(f.Data))) {
+		sum.Lengths = append(sum.Lengths, int32(len(f.Data)))
+	}
+
+	if len(s.payloads) < canMaxPayloads {
+		s.payloads[string(f.Data)] = struct{}{}
+	}
+
+	for i, b := range f.Data {
+		s.byteCount[i]++
+		s.byteSum[i] += float64(b)
+		s.byteSumSq[i] += float64(b) * float64(b)
+	}
+}
+
+// finish completes the statistics of an identifier.
+func (s *canStats) finish() *types.CANSummary {
+	sum := s.summary
+	sum.DistinctPayloads = int32(len(s.payloads))
+
+	if s.intervals > 0 {
+		sum.MeanInterval = s.sum / float64(s.intervals)
+		sum.IntervalStdDev = math.Sqrt(math.Max(0, s.sumSq/float64(s.intervals)-sum.MeanInterval*sum.MeanInterval))
+	}
+
+	var (
+		variance  float64
+		positions int
+	)
+
+	for i, n := range s.byteCount {
+		if n == 0 {
+			continue
+		}
+
+		mean := s.byteSum[i] / float64(n)
+		variance += math.Max(0, s.byteSumSq[i]/float64(n)-mean*mean)
+		positions++
+	}
+
+	if positions > 0 {
+		sum.PayloadVariance = variance / float64(positions)
+	}
+
+	return sum
+}
+
+func containsInt32(list []int32, v int32) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+var canSummaryDecoder = newPacketDecoder(
+	types.Type_NC_CANSummary,
+	"CANSummary",
+	"Timing and payload statistics for each CAN identifier, deviations from the regular cycle time of a message hint at injected frames",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		if f, ok := p.Layer(LayerTypeCAN).(*CAN); ok && !f.Error {
+			canIDs.update(f, p.Metadata().Timestamp)
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		canIDs.Lock()
+		defer canIDs.Unlock()
+
+		for _, s := range canIDs.items {
+			d.write(s.finish())
+		}
+
+		return nil
+	},
+)
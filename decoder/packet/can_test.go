@@ -0,0 +1,120 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+)
+
+// testCANPacket returns a SocketCAN frame with the given identifier including the flags.
+func testCANPacket(id uint32, data ...byte) gopacket.Packet {
+	frame := []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id), byte(len(data)), 0x00, 0x00, 0x00}
+	frame = append(frame, data...)
+
+	return gopacket.NewPacket(frame, LayerTypeCAN, gopacket.Default)
+}
+
+func TestDecodeCAN(t *testing.T) {
+	c, ok := testCANPacket(0x123, 0xde, 0xad, 0xbe, 0xef).Layer(LayerTypeCAN).(*CAN)
+	if !ok {
+		t.Fatal("CAN layer missing")
+	}
+
+	if c.ID != 0x123 || c.Extended || c.Remote || c.Error || c.FD {
+		t.Fatalf("unexpected frame: %+v", c)
+	}
+
+	r := decodeCAN(c)
+	if r.Length != 4 || !reflect.DeepEqual(r.Data, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+
+	c = testCANPacket(0x18daf110 | canEFFFlag | canRTRFlag).Layer(LayerTypeCAN).(*CAN)
+	if c.ID != 0x18daf110 || !c.Extended || !c.Remote || len(c.Data) != 0 {
+		t.Fatalf("unexpected extended remote frame: %+v", c)
+	}
+
+	// no ACK and bus off
+	c = testCANPacket(0x60|canERRFlag, 0, 0, 0, 0, 0, 0, 0, 0).Layer(LayerTypeCAN).(*CAN)
+
+	r = decodeCAN(c)
+	if !r.Error || !reflect.DeepEqual(r.ErrorClasses, []string{"NoACK", "BusOff"}) {
+		t.Fatalf("unexpected error frame: %+v", r)
+	}
+}
+
+func TestDecodeCANSignals(t *testing.T) {
+	db, err := parseDBC(strings.NewReader(testDBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canDBC.Do(func() {})
+	canDBC.db = db
+
+	defer func() {
+		canDBC.db = nil
+	}()
+
+	r := decodeCAN(testCANPacket(0x200|canEFFFlag, 0x00, 0xe0, 0x2e).Layer(LayerTypeCAN).(*CAN))
+	if r.Message != "Diagnostics" || !reflect.DeepEqual(r.Signals, []string{"Page=0", "Voltage=12 V"}) {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+
+	// the standard identifier 0x200 is not defined
+	r = decodeCAN(testCANPacket(0x200, 0x00, 0xe0, 0x2e).Layer(LayerTypeCAN).(*CAN))
+	if r.Message != "" || len(r.Signals) != 0 {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
+func TestCANIdentifierStatistics(t *testing.T) {
+	var (
+		ids = newCANIdentifiers()
+		ts  = time.Unix(1600000000, 0)
+	)
+
+	// cyclic message every 10ms with a varying counter
+	for i := 0; i < 20; i++ {
+		ids.update(testCANPacket(0x100, byte(i), 0xff).Layer(LayerTypeCAN).(*CAN), ts)
+		ts = ts.Add(10 * time.Millisecond)
+	}
+
+	// injected frame right after the legitimate one
+	ts = ts.Add(-9 * time.Millisecond)
+	ids.update(testCANPacket(0x100, 0x00, 0x00, 0x01).Layer(LayerTypeCAN).(*CAN), ts)
+
+	if len(ids.items) != 1 {
+		t.Fatal("expected 1 identifier, got", len(ids.items))
+	}
+
+	s := ids.items[0x100].finish()
+
+	if s.NumFrames != 21 || s.ShortIntervals != 1 || s.MinInterval != 1 {
+		t.Fatalf("unexpected timing: %+v", s)
+	}
+
+	if !reflect.DeepEqual(s.Lengths, []int32{2, 3}) || s.DistinctPayloads != 21 {
+		t.Fatalf("unexpected payloads: %+v", s)
+	}
+
+	if s.MeanInterval <= 9 || s.MeanInterval >= 10 || s.IntervalStdDev == 0 || s.PayloadVariance == 0 {
+		t.Fatalf("unexpected statistics: %+v", s)
+	}
+}
@@ -15,6 +15,7 @@ package packet
 
 import (
 	"log"
+	"math"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -29,14 +30,17 @@ import (
 // connectionID is a bidirectional connection
 // between two devices over the network
 // that includes the Link, Network and TransportLayer.
+// Decapsulated connections are additionally identified by their tunnel,
+// since the address spaces of different tunnels can overlap.
 type connectionID struct {
 	LinkFlowID      uint64
 	NetworkFlowID   uint64
 	TransportFlowID uint64
+	Tunnel          string
 }
 
 func (c connectionID) String() string {
-	return strconv.FormatUint(c.LinkFlowID, 10) + strconv.FormatUint(c.NetworkFlowID, 10) + strconv.FormatUint(c.TransportFlowID, 10)
+	return strconv.FormatUint(c.LinkFlowID, 10) + strconv.FormatUint(c.NetworkFlowID, 10) + strconv.FormatUint(c.TransportFlowID, 10) + c.Tunnel
 }
 
 type connection struct {
@@ -98,6 +102,10 @@ func handlePacket(p gopacket.Packet) proto.Message {
 		connID.TransportFlowID = tl.TransportFlow().FastHash()
 	}
 
+	if t := TunnelOf(p); t != nil {
+		connID.Tunnel = t.String()
+	}
+
 	// lookup flow
 	conns.Lock()
 
@@ -152,6 +160,7 @@ func handlePacket(p gopacket.Packet) proto.Message {
 		co.TimestampLast = p.Metadata().Timestamp.UnixNano()
 		co.TotalSize = int32(p.Metadata().Length)
 		co.NumPackets = 1
+		co.Tunnel = connID.Tunnel
 
 		if ll := p.LinkLayer(); ll != nil {
 			co.LinkProto = ll.LayerType().String()
@@ -190,6 +199,61 @@ func handlePacket(p gopacket.Packet) proto.Message {
 	return nil
 }
 
+// handleFlow adds an exported flow to the connection with the same link, network and transport layer identifiers.
+func handleFlow(f *flowRecord) {
+	var (
+		connID = f.connectionID()
+		start  = f.start.UnixNano()
+		end    = f.end.UnixNano()
+	)
+
+	conns.Lock()
+
+	if conn, ok := conns.Items[connID.String()]; ok {
+		conn.Lock()
+
+		// the flow started before the connections first seen timestamp,
+		// so it decides about the connection direction
+		if start < conn.TimestampFirst {
+			conn.TimestampFirst = start
+			f.setAddresses(conn.Connection)
+		}
+
+		if end > conn.TimestampLast {
+			conn.TimestampLast = end
+		}
+
+		conn.NumPackets = addInt32(conn.NumPackets, f.packets)
+		conn.TotalSize = addInt32(conn.TotalSize, f.bytes)
+
+		conn.Unlock()
+	} else {
+		co := &types.Connection{
+			UID:            calcMd5(connID.String()),
+			TimestampFirst: start,
+			TimestampLast:  end,
+			TotalSize:      addInt32(0, f.bytes),
+			NumPackets:     addInt32(0, f.packets),
+		}
+		f.setAddresses(co)
+
+		conns.Items[connID.String()] = &connection{
+			Connection: co,
+		}
+	}
+
+	conns.Unlock()
+}
+
+// addInt32 adds the counter of a flow to a connection counter, without overflowing it.
+func addInt32(a int32, b uint64) int32 {
+	if b > uint64(math.MaxInt32-int64(a)) {
+		return math.MaxInt32
+	}
+
+	return a + int32(b)
+}
+
 /*func flushConns(p gopacket.Packet) {
 	var selectConns []*types.Connection
 
@@ -0,0 +1,233 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// BO_ <id> <name>: <length> <sender>
+	dbcMessageRegex = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)`)
+
+	// SG_ <name> [M|m<value>] : <start>|<length>@<byte order><sign> (<factor>,<offset>) [<min>|<max>] "<unit>" <receivers>
+	dbcSignalRegex = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(\s*([^,\s]+)\s*,\s*([^)\s]+)\s*\)\s*\[[^\]]*\]\s*"([^"]*)"`)
+)
+
+// dbcSignal is a signal definition from a DBC file.
+type dbcSignal struct {
+	name         string
+	start        int
+	length       int
+	littleEndian bool
+	signed       bool
+	factor       float64
+	offset       float64
+	unit         string
+
+	// multiplexor signals select which multiplexed signals are present in a frame
+	multiplexor bool
+	multiplexed bool
+	muxValue    uint64
+}
+
+// dbcMessage is a message definition from a DBC file.
+type dbcMessage struct {
+	name    string
+	signals []*dbcSignal
+}
+
+// dbcDatabase maps CAN identifiers to message definitions.
+// As in DBC files, extended identifiers have the most significant bit set, which matches the SocketCAN flag.
+type dbcDatabase map[uint32]*dbcMessage
+
+// parseDBC parses the message and signal definitions from a DBC file, all other definitions are ignored.
+func parseDBC(r io.Reader) (dbcDatabase, error) {
+	var (
+		db      = make(dbcDatabase)
+		current *dbcMessage
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := dbcMessageRegex.FindStringSubmatch(line); m != nil {
+			id, err := strconv.ParseUint(m[1], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+
+			current = &dbcMessage{name: m[2]}
+			db[uint32(id)] = current
+
+			continue
+		}
+
+		m := dbcSignalRegex.FindStringSubmatch(line)
+		if m == nil {
+			if !strings.HasPrefix(line, "SG_") {
+				current = nil
+			}
+
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		s, err := parseDBCSignal(m)
+		if err != nil {
+			return nil, err
+		}
+
+		current.signals = append(current.signals, s)
+	}
+
+	return db, scanner.Err()
+}
+
+func parseDBCSignal(m []string) (*dbcSignal, error) {
+	s := &dbcSignal{
+		name:         m[1],
+		littleEndian: m[5] == "1",
+		signed:       m[6] == "-",
+		unit:         m[9],
+	}
+
+	var err error
+
+	switch {
+	case m[2] == "M":
+		s.multiplexor = true
+	case m[2] != "":
+		s.multiplexed = true
+		if s.muxValue, err = strconv.ParseUint(m[2][1:], 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.start, err = strconv.Atoi(m[3]); err != nil {
+		return nil, err
+	}
+
+	if s.length, err = strconv.Atoi(m[4]); err != nil {
+		return nil, err
+	}
+
+	if s.factor, err = strconv.ParseFloat(m[7], 64); err != nil {
+		return nil, err
+	}
+
+	if s.offset, err = strconv.ParseFloat(m[8], 64); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// raw extracts the unscaled value of the signal from the frame data.
+// Intel signals start at their least significant bit, Motorola signals at their most significant bit,
+// with the bits of each byte counted from the least significant one.
+func (s *dbcSignal) raw(data []byte) (uint64, bool) {
+	if s.length <= 0 || s.length > 64 {
+		return 0, false
+	}
+
+	var (
+		v   uint64
+		pos = s.start
+	)
+
+	for i := 0; i < s.length; i++ {
+		idx := pos / 8
+		if idx >= len(data) {
+			return 0, false
+		}
+
+		bit := uint64(data[idx]>>(uint(pos)%8)) & 1
+
+		if s.littleEndian {
+			v |= bit << uint(i)
+			pos++
+
+			continue
+		}
+
+		v = v<<1 | bit
+
+		if pos%8 == 0 {
+			pos += 15
+		} else {
+			pos--
+		}
+	}
+
+	return v, true
+}
+
+// value returns the scaled physical value of the signal.
+func (s *dbcSignal) value(data []byte) (float64, bool) {
+	raw, ok := s.raw(data)
+	if !ok {
+		return 0, false
+	}
+
+	if s.signed {
+		// sign extend the two's complement value
+		if s.length < 64 && raw&(1<<uint(s.length-1)) != 0 {
+			raw |= ^uint64(0) << uint(s.length)
+		}
+
+		return float64(int64(raw))*s.factor + s.offset, true
+	}
+
+	return float64(raw)*s.factor + s.offset, true
+}
+
+// decode returns the signals contained in the frame data, formatted as name=value followed by the unit.
+// Multiplexed signals are only included if the multiplexor selects them.
+func (m *dbcMessage) decode(data []byte) []string {
+	var (
+		mux    uint64
+		hasMux bool
+		out    []string
+	)
+
+	for _, s := range m.signals {
+		if s.multiplexor {
+			mux, hasMux = s.raw(data)
+		}
+	}
+
+	for _, s := range m.signals {
+		if s.multiplexed && (!hasMux || s.muxValue != mux) {
+			continue
+		}
+
+		v, ok := s.value(data)
+		if !ok {
+			continue
+		}
+
+		out = append(out, strings.TrimSpace(s.name+"="+strconv.FormatFloat(v, 'f', -1, 64)+" "+s.unit))
+	}
+
+	return out
+}
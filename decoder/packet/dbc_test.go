@@ -0,0 +1,103 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDBC = `VERSION ""
+
+BO_ 256 EngineData: 8 ECU
+ SG_ RPM : 0|16@1+ (0.25,0) [0|16383.75] "rpm" Dashboard
+ SG_ Temperature : 23|8@0+ (1,-40) [-40|215] "degC" Dashboard
+ SG_ Torque : 24|12@1- (0.5,0) [-1024|1023.5] "Nm" Dashboard
+
+BO_ 2147484160 Diagnostics: 8 ECU
+ SG_ Page M : 0|8@1+ (1,0) [0|255] "" Tester
+ SG_ Voltage m0 : 8|16@1+ (0.001,0) [0|65.535] "V" Tester
+ SG_ Current m1 : 8|16@1- (0.01,0) [-327.68|327.67] "A" Tester
+
+CM_ SG_ 256 RPM "engine speed";
+`
+
+func TestParseDBC(t *testing.T) {
+	db, err := parseDBC(strings.NewReader(testDBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db) != 2 {
+		t.Fatal("expected 2 messages, got", len(db))
+	}
+
+	m, ok := db[256]
+	if !ok || m.name != "EngineData" || len(m.signals) != 3 {
+		t.Fatalf("unexpected standard message: %+v", m)
+	}
+
+	m, ok = db[0x200|canEFFFlag]
+	if !ok || m.name != "Diagnostics" || len(m.signals) != 3 {
+		t.Fatalf("unexpected extended message: %+v", m)
+	}
+
+	if !m.signals[0].multiplexor || !m.signals[2].multiplexed || m.signals[2].muxValue != 1 {
+		t.Fatalf("unexpected multiplexing: %+v %+v", m.signals[0], m.signals[2])
+	}
+}
+
+func TestDBCMessageDecode(t *testing.T) {
+	db, err := parseDBC(strings.NewReader(testDBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RPM 0x1f40 * 0.25 = 2000, Temperature 0x5a - 40 = 50, Torque 0xf38 (-200) * 0.5 = -100
+	data := []byte{0x40, 0x1f, 0x5a, 0x38, 0x0f, 0x00, 0x00, 0x00}
+
+	got := db[256].decode(data)
+	expected := []string{"RPM=2000 rpm", "Temperature=50 degC", "Torque=-100 Nm"}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatal("expected", expected, "got", got)
+	}
+
+	diag := db[0x200|canEFFFlag]
+
+	got = diag.decode([]byte{0x00, 0xe0, 0x2e})
+	if !reflect.DeepEqual(got, []string{"Page=0", "Voltage=12 V"}) {
+		t.Fatal("unexpected signals for page 0:", got)
+	}
+
+	got = diag.decode([]byte{0x01, 0x9c, 0xff})
+	if !reflect.DeepEqual(got, []string{"Page=1", "Current=-1 A"}) {
+		t.Fatal("unexpected signals for page 1:", got)
+	}
+}
+
+func TestDBCSignalMotorola(t *testing.T) {
+	s := &dbcSignal{start: 7, length: 16, factor: 1}
+
+	v, ok := s.value([]byte{0x12, 0x34})
+	if !ok || v != 0x1234 {
+		t.Fatalf("expected %d, got %v", 0x1234, v)
+	}
+
+	// signal exceeding the frame data
+	if _, ok = s.value([]byte{0x12}); ok {
+		t.Fatal("expected signal to be missing")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// tunnelProtocols maps the tunnel protocol names accepted for decapsulation to the layer types of their headers.
+var tunnelProtocols = map[string]gopacket.LayerType{
+	"vxlan":  layers.LayerTypeVXLAN,
+	"geneve": layers.LayerTypeGeneve,
+	"gre":    layers.LayerTypeGRE,
+	"mpls":   layers.LayerTypeMPLS,
+	"erspan": LayerTypeERSPAN,
+}
+
+// Tunnel identifies the tunnel that carried a decapsulated packet.
+type Tunnel struct {
+	Protocol string
+	ID       uint32 // VXLAN and GENEVE VNI, GRE key, MPLS label or ERSPAN session id
+}
+
+// String returns the protocol name and the tunnel id separated by a colon, e.g. vxlan:42.
+func (t *Tunnel) String() string {
+	return t.Protocol + ":" + strconv.FormatUint(uint64(t.ID), 10)
+}
+
+// newTunnel returns the tunnel for the given tunnel header.
+func newTunnel(protocol string, l gopacket.Layer) *Tunnel {
+	t := &Tunnel{Protocol: protocol}
+
+	switch h := l.(type) {
+	case *layers.VXLAN:
+		t.ID = h.VNI
+	case *layers.Geneve:
+		t.ID = h.VNI
+	case *layers.GRE:
+		t.ID = h.Key
+	case *layers.MPLS:
+		t.ID = h.Label
+	case *ERSPAN:
+		t.ID = uint32(h.SessionID)
+	}
+
+	return t
+}
+
+// TunnelLayers parses a comma separated list of tunnel protocols and returns the layer types to strip.
+// Valid names are vxlan, geneve, gre, mpls and erspan, all selects every tunnel protocol.
+func TunnelLayers(names string) (map[gopacket.LayerType]string, error) {
+	tunnels := make(map[gopacket.LayerType]string)
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		switch name {
+		case "":
+			continue
+		case "all":
+			for n, lt := range tunnelProtocols {
+				tunnels[lt] = n
+			}
+
+			continue
+		}
+
+		lt, ok := tunnelProtocols[name]
+		if !ok {
+			return nil, errors.New("unknown tunnel protocol: " + name)
+		}
+
+		tunnels[lt] = name
+	}
+
+	return tunnels, nil
+}
+
+// Decapsulate strips all headers up to and including the innermost layer of the given tunnel layers
+// and returns the encapsulated packet decoded with the given options.
+// The capture info of the outer packet is kept and the tunnel is added to its ancillary data, see TunnelOf.
+// Nil is returned if the packet does not contain a tunnel layer or the tunneled payload could not be decoded.
+func Decapsulate(p gopacket.Packet, tunnels map[gopacket.LayerType]string, opts gopacket.DecodeOptions) gopacket.Packet {
+	var (
+		pktLayers = p.Layers()
+		index     = -1
+		protocol  string
+	)
+
+	for i, l := range pktLayers {
+		if name, ok := tunnels[l.LayerType()]; ok {
+			index, protocol = i, name
+		}
+	}
+
+	if index == -1 || index+1 == len(pktLayers) {
+		return nil
+	}
+
+	next := pktLayers[index+1].LayerType()
+	if next == gopacket.LayerTypePayload || next == gopacket.LayerTypeDecodeFailure {
+		return nil
+	}
+
+	var (
+		data   = pktLayers[index].LayerPayload()
+		outer  = p.Metadata()
+		inner  = gopacket.NewPacket(data, next, opts)
+		md     = inner.Metadata()
+		tunnel = newTunnel(protocol, pktLayers[index])
+	)
+
+	md.CaptureInfo = outer.CaptureInfo
+	md.CaptureLength = len(data)
+	md.Length = len(data) + outer.Length - outer.CaptureLength
+	md.AncillaryData = append(append(make([]interface{}, 0, len(outer.AncillaryData)+1), outer.AncillaryData...), tunnel)
+	md.Truncated = md.Truncated || outer.Truncated
+
+	return inner
+}
+
+// TunnelOf returns the tunnel of a packet returned by Decapsulate, or nil if the packet was not tunneled.
+func TunnelOf(p gopacket.Packet) *Tunnel {
+	for _, data := range p.Metadata().AncillaryData {
+		if t, ok := data.(*Tunnel); ok {
+			return t
+		}
+	}
+
+	return nil
+}
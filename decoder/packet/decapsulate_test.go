@@ -0,0 +1,168 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// testGREPacket returns an IPv4 packet with the given GRE header and payload.
+func testGREPacket(t *testing.T, header, payload []byte) gopacket.Packet {
+	t.Helper()
+
+	var (
+		ip = &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolGRE,
+			SrcIP:    net.ParseIP("192.0.2.1"),
+			DstIP:    net.ParseIP("192.0.2.2"),
+		}
+		buf = gopacket.NewSerializeBuffer()
+	)
+
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, ip, gopacket.Payload(append(header, payload...))); err != nil {
+		t.Fatal(err)
+	}
+
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+}
+
+func TestTunnelLayers(t *testing.T) {
+	tunnels, err := TunnelLayers(" VXLAN, erspan,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tunnels) != 2 || tunnels[layers.LayerTypeVXLAN] != "vxlan" || tunnels[LayerTypeERSPAN] != "erspan" {
+		t.Fatalf("unexpected tunnel layers: %v", tunnels)
+	}
+
+	if tunnels, err = TunnelLayers("all"); err != nil || len(tunnels) != len(tunnelProtocols) {
+		t.Fatalf("expected all tunnel layers, got %v: %v", tunnels, err)
+	}
+
+	if _, err = TunnelLayers("vxlan,ipip"); err == nil {
+		t.Fatal("expected error for unknown tunnel protocol")
+	}
+}
+
+func TestDecapsulateVXLAN(t *testing.T) {
+	var (
+		frame   = testSampledFrame(t)
+		header  = []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a, 0x00} // vni 42
+		outer   = testUDPPacket(t, "192.0.2.1", "192.0.2.2", 50000, 4789, append(header, frame...))
+		tunnels = map[gopacket.LayerType]string{layers.LayerTypeVXLAN: "vxlan"}
+	)
+
+	outer.Metadata().CaptureInfo = gopacket.CaptureInfo{
+		Timestamp:     time.Unix(1600000000, 0),
+		CaptureLength: len(outer.Data()),
+		Length:        len(outer.Data()) + 10,
+	}
+
+	inner := Decapsulate(outer, tunnels, gopacket.Default)
+	if inner == nil {
+		t.Fatalf("expected inner packet, got nil for %v", outer.Layers())
+	}
+
+	if inner.NetworkLayer().NetworkFlow().Src().String() != "10.2.0.1" || inner.TransportLayer().LayerType() != layers.LayerTypeTCP {
+		t.Fatalf("unexpected inner packet: %v", inner)
+	}
+
+	md := inner.Metadata()
+	if !md.Timestamp.Equal(time.Unix(1600000000, 0)) || md.CaptureLength != len(frame) || md.Length != len(frame)+10 {
+		t.Fatalf("unexpected inner packet metadata: %+v", md.CaptureInfo)
+	}
+
+	if tunnel := TunnelOf(inner); tunnel == nil || tunnel.String() != "vxlan:42" {
+		t.Fatalf("unexpected tunnel: %v", tunnel)
+	}
+
+	if TunnelOf(outer) != nil {
+		t.Fatal("expected no tunnel for the outer packet")
+	}
+
+	// GENEVE is not configured
+	if Decapsulate(outer, map[gopacket.LayerType]string{layers.LayerTypeGeneve: "geneve"}, gopacket.Default) != nil {
+		t.Fatal("expected no inner packet for unconfigured tunnel protocol")
+	}
+}
+
+func TestDecapsulateERSPAN(t *testing.T) {
+	var (
+		gre    = []byte{0x10, 0x00, 0x88, 0xbe, 0x00, 0x00, 0x00, 0x01} // sequence number present
+		erspan = []byte{0x10, 0x0a, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01} // session 7
+		outer  = testGREPacket(t, gre, append(erspan, testSampledFrame(t)...))
+	)
+
+	if outer.Layer(LayerTypeERSPAN) == nil {
+		t.Fatalf("expected ERSPAN layer, got %v", outer.Layers())
+	}
+
+	// stripping GRE keeps the ERSPAN header on the inner packet
+	inner := Decapsulate(outer, map[gopacket.LayerType]string{layers.LayerTypeGRE: "gre"}, gopacket.Default)
+	if inner == nil || inner.Layers()[0].LayerType() != LayerTypeERSPAN || TunnelOf(inner).String() != "gre:0" {
+		t.Fatalf("unexpected inner packet: %v", inner)
+	}
+
+	tunnels, err := TunnelLayers("gre,erspan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner = Decapsulate(outer, tunnels, gopacket.Default)
+	if inner == nil || inner.LinkLayer() == nil || inner.Layers()[0].LayerType() != layers.LayerTypeEthernet {
+		t.Fatalf("unexpected inner packet: %v", inner)
+	}
+
+	if tunnel := TunnelOf(inner); tunnel == nil || tunnel.String() != "erspan:7" {
+		t.Fatalf("unexpected tunnel: %v", tunnel)
+	}
+}
+
+func TestDecapsulatedConnection(t *testing.T) {
+	var (
+		frame   = testSampledFrame(t)
+		tunnels = map[gopacket.LayerType]string{layers.LayerTypeVXLAN: "vxlan"}
+		a       = Decapsulate(testUDPPacket(t, "192.0.2.1", "192.0.2.2", 50000, 4789, append([]byte{0x08, 0, 0, 0, 0, 0, 0x01, 0}, frame...)), tunnels, gopacket.Default)
+		b       = Decapsulate(testUDPPacket(t, "192.0.2.1", "192.0.2.2", 50000, 4789, append([]byte{0x08, 0, 0, 0, 0, 0, 0x02, 0}, frame...)), tunnels, gopacket.Default)
+	)
+
+	handlePacket(a)
+	handlePacket(b)
+
+	// the same inner addresses in different tunnels are separate connections
+	for _, tunnel := range []string{"vxlan:1", "vxlan:2"} {
+		id := connectionID{
+			LinkFlowID:      a.LinkLayer().LinkFlow().FastHash(),
+			NetworkFlowID:   a.NetworkLayer().NetworkFlow().FastHash(),
+			TransportFlowID: a.TransportLayer().TransportFlow().FastHash(),
+			Tunnel:          tunnel,
+		}
+
+		conns.Lock()
+		conn, ok := conns.Items[id.String()]
+		conns.Unlock()
+
+		if !ok || conn.Tunnel != tunnel || conn.SrcIP != "10.2.0.1" || conn.NumPackets != 1 {
+			t.Fatalf("unexpected connection for tunnel %s: %+v", tunnel, conn)
+		}
+	}
+}
@@ -171,6 +171,34 @@ func applyDeviceProfileUpdate(p *deviceProfile, i *decoderutils.PacketInfo) {
 	p.Unlock()
 }
 
+// updateDeviceProfileFlow updates the profile of the device that sent an exported flow,
+// along with the profiles of the source and destination address.
+func updateDeviceProfileFlow(f *flowRecord, src, dst *ipProfile) {
+	mac := f.srcMAC.String()
+
+	DeviceProfiles.Lock()
+	p, ok := DeviceProfiles.Items[mac]
+	if !ok {
+		p = &deviceProfile{
+			DeviceProfile: &types.DeviceProfile{
+				MacAddr:            mac,
+				DeviceManufacturer: resolvers.LookupManufacturer(mac),
+				Timestamp:          f.start.UnixNano(),
+			},
+		}
+		DeviceProfiles.Items[mac] = p
+		deviceProfiles++
+	}
+	DeviceProfiles.Unlock()
+
+	p.Lock()
+	p.DeviceIPs = decoderutils.AppendUnique(p.DeviceIPs, src.Addr)
+	p.Contacts = decoderutils.AppendUnique(p.Contacts, dst.Addr)
+	p.NumPackets += int64(f.packets)
+	p.Bytes += f.bytes
+	p.Unlock()
+}
+
 var deviceProfileDecoder = newPacketDecoder(
 	types.Type_NC_DeviceProfile,
 	"DeviceProfile",
@@ -188,6 +216,7 @@ var deviceProfileDecoder = newPacketDecoder(
 		// flush writer
 		for _, item := range DeviceProfiles.Items {
 			item.Lock()
+			applyDeviceInfo(item.DeviceProfile)
 			d.writeDeviceProfile(item.DeviceProfile)
 			item.Unlock()
 		}
@@ -196,6 +225,19 @@ var deviceProfileDecoder = newPacketDecoder(
 	},
 )
 
+// applyDeviceInfo adds the identifiers, hostnames, services and models
+// registered for the MAC address or any of the IP addresses of the device.
+func applyDeviceInfo(dp *types.DeviceProfile) {
+	for _, addr := range append([]string{dp.MacAddr}, dp.DeviceIPs...) {
+		info := decoderutils.LookupDeviceInfo(addr)
+
+		dp.DeviceIDs = decoderutils.AppendUnique(dp.DeviceIDs, info.IDs...)
+		dp.Hostnames = decoderutils.AppendUnique(dp.Hostnames, info.Hostnames...)
+		dp.Services = decoderutils.AppendUnique(dp.Services, info.Services...)
+		dp.Models = decoderutils.AppendUnique(dp.Models, info.Models...)
+	}
+}
+
 // writeDeviceProfile writes the profile.
 func (d *Decoder) writeDeviceProfile(dp *types.DeviceProfile) {
 	if conf.ExportMetrics {
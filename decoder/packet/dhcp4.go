@@ -14,6 +14,7 @@
 package packet
 
 import (
+	"net"
 	"strconv"
 	"strings"
 
@@ -21,6 +22,7 @@ import (
 	"github.com/dreadl0ck/gopacket/layers"
 	"github.com/gogo/protobuf/proto"
 
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
 	"github.com/dreadl0ck/netcap/types"
 )
 
@@ -32,9 +34,11 @@ var dhcpv4Decoder = newGoPacketDecoder(
 		if dhcp4, ok := layer.(*layers.DHCPv4); ok {
 
 			var (
-				opts   []*types.DHCPOption
-				fp     strings.Builder
-				length = len(dhcp4.Options) - 1
+				opts        []*types.DHCPOption
+				fp          strings.Builder
+				length      = len(dhcp4.Options) - 1
+				hostname    string
+				requestedIP net.IP
 			)
 			for i, o := range dhcp4.Options {
 				opts = append(opts, &types.DHCPOption{
@@ -46,6 +50,19 @@ var dhcpv4Decoder = newGoPacketDecoder(
 				if i != length {
 					fp.WriteString(",")
 				}
+
+				switch o.Type {
+				case layers.DHCPOptHostname:
+					hostname = string(o.Data)
+				case layers.DHCPOptRequestIP:
+					if len(o.Data) == net.IPv4len {
+						requestedIP = net.IP(o.Data)
+					}
+				}
+			}
+
+			if hostname != "" {
+				addDHCPHostname(dhcp4, hostname, requestedIP)
 			}
 
 			return &types.DHCPv4{
@@ -72,3 +89,17 @@ var dhcpv4Decoder = newGoPacketDecoder(
 		return nil
 	},
 )
+
+// addDHCPHostname registers the hostname from option 12 for the client hardware address
+// and the address used, requested or assigned to the client.
+func addDHCPHostname(dhcp4 *layers.DHCPv4, hostname string, requestedIP net.IP) {
+	decoderutils.AddHostname(dhcp4.ClientHWAddr.String(), hostname)
+
+	for _, ip := range []net.IP{dhcp4.ClientIP, requestedIP, dhcp4.YourClientIP} {
+		if ip != nil && !ip.IsUnspecified() {
+			decoderutils.AddHostname(ip.String(), hostname)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	dot11TypeBeacon        = "beacon"
+	dot11TypeProbeResponse = "probe response"
+
+	// privacy bit of the capability information field
+	dot11CapabilityPrivacy = 0x0010
+
+	// management frame protection bits of the RSN capabilities
+	rsnCapabilityMFPRequired = 0x0040
+	rsnCapabilityMFPCapable  = 0x0080
+
+	// vendor element type of the WPA element in the Microsoft OUI
+	wpaVendorType = 1
+)
+
+var dot11BeaconDecoder = newPacketDecoder(
+	types.Type_NC_Dot11Beacon,
+	"Dot11Beacon",
+	"IEEE 802.11 beacons and probe responses announce wireless networks along with their channel and security configuration",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		dot11, ok := p.Layer(layers.LayerTypeDot11).(*layers.Dot11)
+		if !ok {
+			return nil
+		}
+
+		var (
+			typ      string
+			interval uint16
+			flags    uint16
+			body     []byte
+		)
+
+		if beacon, ok := p.Layer(layers.LayerTypeDot11MgmtBeacon).(*layers.Dot11MgmtBeacon); ok {
+			typ, interval, flags, body = dot11TypeBeacon, beacon.Interval, beacon.Flags, beacon.Payload
+		} else if resp, ok := p.Layer(layers.LayerTypeDot11MgmtProbeResp).(*layers.Dot11MgmtProbeResp); ok {
+			typ, interval, flags, body = dot11TypeProbeResponse, resp.Interval, resp.Flags, resp.Payload
+		} else {
+			return nil
+		}
+
+		ies := parseDot11IEs(body)
+
+		b := decodeDot11Beacon(ies, flags)
+		b.Timestamp = p.Metadata().Timestamp.UnixNano()
+		b.Type = typ
+		b.BSSID = dot11.Address3.String()
+		b.SrcMAC = dot11.Address2.String()
+		b.DstMAC = dot11.Address1.String()
+		b.Interval = int32(interval)
+		b.Channel = dot11Channel(ies, p)
+
+		// probe responses reveal the SSID of hidden networks
+		if !b.Hidden {
+			addDot11Network(b.BSSID, b.SSID)
+		}
+
+		return b
+	},
+	nil,
+)
+
+// decodeDot11Beacon decodes the network name and security configuration
+// from the information elements of a beacon or probe response.
+func decodeDot11Beacon(ies []dot11IE, capabilities uint16) *types.Dot11Beacon {
+	b := &types.Dot11Beacon{
+		Capabilities: int32(capabilities),
+		VendorIEs:    dot11VendorIEs(ies),
+	}
+
+	b.SSID, b.Hidden = dot11SSID(ies)
+
+	var rsn, wpa *dot11RSN
+
+	for _, ie := range ies {
+		switch {
+		case ie.id == layers.Dot11InformationElementIDRSNInfo:
+			rsn = parseDot11RSN(ie.info)
+		case ie.id == layers.Dot11InformationElementIDVendor && len(ie.info) > 4 &&
+			string(ie.info[:3]) == string(ouiMicrosoft) && ie.info[3] == wpaVendorType:
+			wpa = parseDot11RSN(ie.info[4:])
+		}
+	}
+
+	b.Security = dot11Security(rsn, wpa, capabilities&dot11CapabilityPrivacy != 0)
+
+	params := rsn
+	if params == nil {
+		params = wpa
+	}
+
+	if params != nil {
+		b.GroupCipher = params.groupCipher
+		b.PairwiseCiphers = params.pairwiseCiphers
+		b.AKMSuites = params.akmSuites
+	}
+
+	if rsn != nil {
+		b.RSNCapabilities = rsn.capabilities
+		b.MFPRequired = rsn.capabilities&rsnCapabilityMFPRequired != 0
+		b.MFPCapable = rsn.capabilities&rsnCapabilityMFPCapable != 0
+	}
+
+	return b
+}
+
+// dot11Security summarizes the security of a network.
+// WPA3 networks use SAE, transition mode networks additionally offer WPA2 AKMs.
+func dot11Security(rsn, wpa *dot11RSN, privacy bool) string {
+	if rsn == nil {
+		switch {
+		case wpa != nil:
+			return "WPA"
+		case privacy:
+			return "WEP"
+		default:
+			return "OPEN"
+		}
+	}
+
+	var sae, owe, other bool
+
+	for _, akm := range rsn.akmSuites {
+		switch akm {
+		case "SAE", "FT-SAE":
+			sae = true
+		case "OWE":
+			owe = true
+		default:
+			other = true
+		}
+	}
+
+	sec := "WPA2"
+
+	switch {
+	case owe && !sae && !other:
+		return "OWE"
+	case sae && other:
+		sec = "WPA2/WPA3"
+	case sae:
+		sec = "WPA3"
+	}
+
+	if wpa != nil {
+		sec = "WPA/" + sec
+	}
+
+	return sec
+}
@@ -0,0 +1,116 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	dot11TypeDeauthentication = "deauthentication"
+	dot11TypeDisassociation   = "disassociation"
+
+	// deauthentication and disassociation frames are rare during normal operation,
+	// a source sending dot11FloodThreshold or more frames for a BSSID within dot11FloodWindow is flagged as a flood.
+	dot11FloodWindow    = time.Second
+	dot11FloodThreshold = 10
+)
+
+// dot11FloodTracker counts the frames per source and BSSID within the current detection window.
+type dot11FloodTracker struct {
+	sync.Mutex
+	items map[string]*dot11FloodWindowState
+}
+
+type dot11FloodWindowState struct {
+	start time.Time
+	count int32
+}
+
+func newDot11FloodTracker() *dot11FloodTracker {
+	return &dot11FloodTracker{
+		items: make(map[string]*dot11FloodWindowState),
+	}
+}
+
+// seen registers a frame and returns the number of frames within the current window,
+// along with a flag indicating whether the flood threshold has been reached.
+func (t *dot11FloodTracker) seen(src, bssid string, ts time.Time) (int32, bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	key := src + "-" + bssid
+
+	w, ok := t.items[key]
+	if !ok || ts.Sub(w.start) > dot11FloodWindow {
+		w = &dot11FloodWindowState{start: ts}
+		t.items[key] = w
+	}
+
+	w.count++
+
+	return w.count, w.count >= dot11FloodThreshold
+}
+
+var dot11Floods = newDot11FloodTracker()
+
+var dot11DeauthDecoder = newPacketDecoder(
+	types.Type_NC_Dot11Deauth,
+	"Dot11Deauth",
+	"IEEE 802.11 deauthentication and disassociation frames terminate a client connection, floods of them indicate denial of service attacks or attempts to capture WPA handshakes",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		dot11, ok := p.Layer(layers.LayerTypeDot11).(*layers.Dot11)
+		if !ok {
+			return nil
+		}
+
+		var (
+			typ    string
+			reason layers.Dot11Reason
+		)
+
+		if deauth, ok := p.Layer(layers.LayerTypeDot11MgmtDeauthentication).(*layers.Dot11MgmtDeauthentication); ok {
+			typ, reason = dot11TypeDeauthentication, deauth.Reason
+		} else if disassoc, ok := p.Layer(layers.LayerTypeDot11MgmtDisassociation).(*layers.Dot11MgmtDisassociation); ok {
+			typ, reason = dot11TypeDisassociation, disassoc.Reason
+		} else {
+			return nil
+		}
+
+		d := &types.Dot11Deauth{
+			Timestamp:  p.Metadata().Timestamp.UnixNano(),
+			Type:       typ,
+			SrcMAC:     dot11.Address2.String(),
+			DstMAC:     dot11.Address1.String(),
+			BSSID:      dot11.Address3.String(),
+			ReasonCode: int32(reason),
+			Reason:     reason.String(),
+		}
+
+		d.Broadcast = bytes.Equal(dot11.Address1, layers.EthernetBroadcast)
+		d.FrameCount, d.Flood = dot11Floods.seen(d.SrcMAC, d.BSSID, p.Metadata().Timestamp)
+
+		return d
+	},
+	nil,
+)
@@ -0,0 +1,355 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	dot11HandshakePMKID = "PMKID"
+	dot11HandshakeEAPOL = "EAPOL"
+
+	// hashcat 22000 hash types
+	wpaHashPMKID = 1
+	wpaHashEAPOL = 2
+
+	// hashcat 22000 message pairs, the EAPOL frame is always taken from M2
+	messagePairM1M2 = 0 // ANonce from M1
+	messagePairM2M3 = 2 // ANonce from M3
+
+	// offsets in the EAPOL frame, including the 4 byte EAPOL header
+	eapolKeyMICOffset  = 81
+	eapolKeyMICLength  = 16
+	eapolKeyDataOffset = 99
+
+	// PMKID key data encapsulation in the IEEE 802.11 OUI
+	kdeTypePMKID = 4
+	pmkidLength  = 16
+
+	// file the hashes are exported to, inside the file storage directory
+	dot11HandshakeFile = "handshakes.22000"
+)
+
+// wpaHash holds the fields of a hashcat 22000 hash line,
+// which is completed with the ESSID once the network name is known.
+type wpaHash struct {
+	typ         int
+	mic         []byte // or the PMKID
+	ap          net.HardwareAddr
+	client      net.HardwareAddr
+	anonce      []byte
+	eapol       []byte
+	messagePair byte
+}
+
+// line returns the hash in hashcat 22000 format:
+// WPA*TYPE*PMKID/MIC*MACAP*MACCLIENT*ESSID*ANONCE*EAPOL*MESSAGEPAIR
+// PMKID hashes leave the last three fields empty.
+func (h *wpaHash) line(essid string) string {
+	if h.typ == wpaHashPMKID {
+		return fmt.Sprintf("WPA*%02d*%x*%x*%x*%x***", h.typ, h.mic, []byte(h.ap), []byte(h.client), essid)
+	}
+
+	return fmt.Sprintf("WPA*%02d*%x*%x*%x*%x*%x*%x*%02x", h.typ, h.mic, []byte(h.ap), []byte(h.client), essid, h.anonce, h.eapol, h.messagePair)
+}
+
+// dot11HandshakeState tracks the messages of the 4-way handshake between an access point and a client.
+type dot11HandshakeState struct {
+	// ANonce and replay counter of the last M1
+	anonce       []byte
+	anonceReplay uint64
+
+	// M2 with the MIC zeroed, kept to pair it with an M3 if M1 was missed
+	m2       []byte
+	m2MIC    []byte
+	m2Replay uint64
+	m2Paired bool
+
+	pmkids map[string]struct{}
+}
+
+// dot11HandshakeTracker pairs EAPOL-Key messages to crackable hashes.
+type dot11HandshakeTracker struct {
+	sync.Mutex
+	items map[string]*dot11HandshakeState
+
+	// hashes for networks whose SSID has not been seen yet
+	pending []*dot11PendingHash
+
+	// all hash lines, for the export
+	lines []string
+}
+
+type dot11PendingHash struct {
+	record *types.Dot11Handshake
+	hash   *wpaHash
+}
+
+func newDot11HandshakeTracker() *dot11HandshakeTracker {
+	return &dot11HandshakeTracker{
+		items: make(map[string]*dot11HandshakeState),
+	}
+}
+
+var dot11Handshakes = newDot11HandshakeTracker()
+
+var dot11HandshakeDecoder = newPacketDecoder(
+	types.Type_NC_Dot11Handshake,
+	"Dot11Handshake",
+	"WPA PMKIDs and 4-way handshakes, exported in hashcat 22000 format for authorized wireless assessments",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		dot11, ok := p.Layer(layers.LayerTypeDot11).(*layers.Dot11)
+		if !ok {
+			return nil
+		}
+
+		// association requests carry the SSID, in case no beacon for the network was captured
+		if req, ok := p.Layer(layers.LayerTypeDot11MgmtAssociationReq).(*layers.Dot11MgmtAssociationReq); ok {
+			if ssid, hidden := dot11SSID(parseDot11IEs(req.Payload)); !hidden {
+				addDot11Network(dot11.Address1.String(), ssid)
+			}
+
+			return nil
+		}
+
+		key, ok := p.Layer(layers.LayerTypeEAPOLKey).(*layers.EAPOLKey)
+		if !ok {
+			return nil
+		}
+
+		eapol, ok := p.Layer(layers.LayerTypeEAPOL).(*layers.EAPOL)
+		if !ok {
+			return nil
+		}
+
+		frame := append(append([]byte{}, eapol.Contents...), eapol.Payload...)
+		if len(frame) < 4+int(eapol.Length) {
+			return nil
+		}
+
+		return dot11Handshakes.add(dot11.Address2, dot11.Address1, key, frame[:4+int(eapol.Length)], p.Metadata().Timestamp.UnixNano())
+	},
+	func(d *Decoder) error {
+		dot11Handshakes.Lock()
+		defer dot11Handshakes.Unlock()
+
+		for _, ph := range dot11Handshakes.pending {
+			ph.record.SSID = lookupDot11Network(ph.record.BSSID)
+			ph.record.Hash = ph.hash.line(ph.record.SSID)
+			dot11Handshakes.lines = append(dot11Handshakes.lines, ph.record.Hash)
+			d.write(ph.record)
+		}
+
+		dot11Handshakes.pending = nil
+
+		if conf == nil || conf.FileStorage == "" || len(dot11Handshakes.lines) == 0 {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		for _, l := range dot11Handshakes.lines {
+			buf.WriteString(l + "\n")
+		}
+
+		root := filepath.Join(conf.Out, conf.FileStorage)
+		if err := os.MkdirAll(root, defaults.DirectoryPermission); err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(filepath.Join(root, dot11HandshakeFile), buf.Bytes(), defaults.FilePermission)
+	},
+)
+
+// eapolKeyMessage returns the number of the 4-way handshake message, or 0 for other EAPOL-Key frames.
+// M2 and M4 are both sent by the client with a MIC, M4 has the secure bit set (WPA2) or no nonce (WPA).
+func eapolKeyMessage(k *layers.EAPOLKey) int {
+	if k.KeyType != layers.EAPOLKeyTypePairwise {
+		return 0
+	}
+
+	switch {
+	case k.KeyACK && !k.KeyMIC:
+		return 1
+	case k.KeyACK && k.KeyMIC && k.Install:
+		return 3
+	case !k.KeyACK && k.KeyMIC && !k.Secure && !isZero(k.Nonce):
+		return 2
+	case !k.KeyACK && k.KeyMIC:
+		return 4
+	}
+
+	return 0
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// add processes an EAPOL-Key frame sent from the transmitter to the receiver address,
+// and returns a record once a PMKID or a pair of messages has been captured.
+// Records for networks with an unknown SSID are held back until the end of the capture.
+func (t *dot11HandshakeTracker) add(transmitter, receiver net.HardwareAddr, k *layers.EAPOLKey, frame []byte, ts int64) *types.Dot11Handshake {
+	msg := eapolKeyMessage(k)
+	if msg == 0 {
+		return nil
+	}
+
+	ap, client := transmitter, receiver
+	if msg == 2 || msg == 4 {
+		ap, client = receiver, transmitter
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	id := ap.String() + "-" + client.String()
+
+	s, ok := t.items[id]
+	if !ok {
+		s = &dot11HandshakeState{
+			pmkids: make(map[string]struct{}),
+		}
+		t.items[id] = s
+	}
+
+	var h *wpaHash
+
+	switch msg {
+	case 1:
+		s.anonce = append([]byte{}, k.Nonce...)
+		s.anonceReplay = k.ReplayCounter
+
+		pmkid := findPMKID(frame)
+		if pmkid == nil {
+			return nil
+		}
+
+		if _, ok := s.pmkids[string(pmkid)]; ok {
+			return nil
+		}
+
+		s.pmkids[string(pmkid)] = struct{}{}
+		h = &wpaHash{
+			typ: wpaHashPMKID,
+			mic: pmkid,
+		}
+	case 2:
+		if len(frame) < eapolKeyMICOffset+eapolKeyMICLength {
+			return nil
+		}
+
+		s.m2 = append([]byte{}, frame...)
+		copy(s.m2[eapolKeyMICOffset:eapolKeyMICOffset+eapolKeyMICLength], make([]byte, eapolKeyMICLength))
+		s.m2MIC = append([]byte{}, k.MIC...)
+		s.m2Replay = k.ReplayCounter
+		s.m2Paired = false
+
+		if s.anonce == nil || s.anonceReplay != k.ReplayCounter {
+			return nil
+		}
+
+		s.m2Paired = true
+		h = &wpaHash{
+			typ:         wpaHashEAPOL,
+			anonce:      s.anonce,
+			messagePair: messagePairM1M2,
+		}
+	case 3:
+		if s.m2 == nil || s.m2Paired || k.ReplayCounter != s.m2Replay+1 {
+			return nil
+		}
+
+		s.m2Paired = true
+		h = &wpaHash{
+			typ:         wpaHashEAPOL,
+			anonce:      append([]byte{}, k.Nonce...),
+			messagePair: messagePairM2M3,
+		}
+	default:
+		return nil
+	}
+
+	h.ap, h.client = ap, client
+
+	r := &types.Dot11Handshake{
+		Timestamp:     ts,
+		BSSID:         ap.String(),
+		ClientMAC:     client.String(),
+		ReplayCounter: k.ReplayCounter,
+		KeyVersion:    int32(k.KeyDescriptorVersion),
+	}
+
+	if h.typ == wpaHashPMKID {
+		r.Type = dot11HandshakePMKID
+	} else {
+		h.mic, h.eapol = s.m2MIC, s.m2
+		r.Type = dot11HandshakeEAPOL
+		r.MessagePair = int32(h.messagePair)
+	}
+
+	r.SSID = lookupDot11Network(r.BSSID)
+	if r.SSID == "" {
+		t.pending = append(t.pending, &dot11PendingHash{record: r, hash: h})
+
+		return nil
+	}
+
+	r.Hash = h.line(r.SSID)
+	t.lines = append(t.lines, r.Hash)
+
+	return r
+}
+
+// findPMKID returns the PMKID from the key data of an M1 EAPOL frame.
+func findPMKID(frame []byte) []byte {
+	if len(frame) < eapolKeyDataOffset {
+		return nil
+	}
+
+	length := int(binary.BigEndian.Uint16(frame[eapolKeyDataOffset-2 : eapolKeyDataOffset]))
+	if len(frame) < eapolKeyDataOffset+length {
+		return nil
+	}
+
+	for _, ie := range parseDot11IEs(frame[eapolKeyDataOffset : eapolKeyDataOffset+length]) {
+		if ie.id == layers.Dot11InformationElementIDVendor && len(ie.info) == 4+pmkidLength &&
+			string(ie.info[:3]) == string(ouiIEEE80211) && ie.info[3] == kdeTypePMKID && !isZero(ie.info[4:]) {
+			return append([]byte{}, ie.info[4:]...)
+		}
+	}
+
+	return nil
+}
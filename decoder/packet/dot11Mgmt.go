@@ -0,0 +1,246 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+)
+
+// dot11IE is an information element of an 802.11 management frame.
+type dot11IE struct {
+	id   layers.Dot11InformationElementID
+	info []byte
+}
+
+// parseDot11IEs parses the information elements in the body of a management frame.
+// gopacket decodes the elements into individual layers, but fails on short elements
+// and splits vendor elements differently, so they are parsed from the raw body instead.
+// A truncated trailing element (e.g. the frame check sequence) is ignored.
+func parseDot11IEs(data []byte) []dot11IE {
+	var ies []dot11IE
+
+	for len(data) >= 2 {
+		length := int(data[1])
+		if len(data) < 2+length {
+			break
+		}
+
+		ies = append(ies, dot11IE{
+			id:   layers.Dot11InformationElementID(data[0]),
+			info: data[2 : 2+length],
+		})
+		data = data[2+length:]
+	}
+
+	return ies
+}
+
+// dot11SSID returns the SSID from the information elements and whether it is hidden.
+// Hidden networks send an empty SSID or one made of null bytes.
+func dot11SSID(ies []dot11IE) (ssid string, hidden bool) {
+	for _, ie := range ies {
+		if ie.id != layers.Dot11InformationElementIDSSID {
+			continue
+		}
+
+		for _, b := range ie.info {
+			if b != 0 {
+				return string(ie.info), false
+			}
+		}
+
+		return "", true
+	}
+
+	return "", true
+}
+
+// dot11Channel returns the channel announced in the DS parameter set or the HT operation element,
+// falling back to the frequency from the radiotap or PPI header.
+func dot11Channel(ies []dot11IE, p gopacket.Packet) int32 {
+	for _, ie := range ies {
+		if (ie.id == layers.Dot11InformationElementIDDSSet || ie.id == layers.Dot11InformationElementIDHTInfo) && len(ie.info) > 0 {
+			return int32(ie.info[0])
+		}
+	}
+
+	if p != nil {
+		if rt, ok := p.Layer(layers.LayerTypeRadioTap).(*layers.RadioTap); ok {
+			return dot11FrequencyToChannel(int(rt.ChannelFrequency))
+		}
+
+		if ppi, ok := p.Layer(LayerTypePPI).(*PPI); ok {
+			return dot11FrequencyToChannel(int(ppi.ChannelFrequency))
+		}
+	}
+
+	return 0
+}
+
+// dot11FrequencyToChannel converts a frequency in MHz to the channel number.
+func dot11FrequencyToChannel(freq int) int32 {
+	switch {
+	case freq == 2484:
+		return 14
+	case freq >= 2412 && freq < 2484:
+		return int32((freq - 2407) / 5)
+	case freq >= 5955 && freq <= 7115:
+		return int32((freq - 5950) / 5)
+	case freq >= 5000 && freq < 5955:
+		return int32((freq - 5000) / 5)
+	}
+
+	return 0
+}
+
+// dot11VendorIEs returns the OUI and type of all vendor specific elements.
+func dot11VendorIEs(ies []dot11IE) []string {
+	var out []string
+
+	for _, ie := range ies {
+		if ie.id != layers.Dot11InformationElementIDVendor || len(ie.info) < 4 {
+			continue
+		}
+
+		out = decoderutils.AppendUnique(out, fmt.Sprintf("%02x:%02x:%02x/%d", ie.info[0], ie.info[1], ie.info[2], ie.info[3]))
+	}
+
+	return out
+}
+
+var (
+	// IEEE 802.11 OUI used in RSN suite selectors
+	ouiIEEE80211 = []byte{0x00, 0x0f, 0xac}
+
+	// Microsoft OUI used for the WPA vendor element
+	ouiMicrosoft = []byte{0x00, 0x50, 0xf2}
+)
+
+var dot11Ciphers = map[byte]string{
+	1:  "WEP-40",
+	2:  "TKIP",
+	4:  "CCMP-128",
+	5:  "WEP-104",
+	6:  "BIP-CMAC-128",
+	8:  "GCMP-128",
+	9:  "GCMP-256",
+	10: "CCMP-256",
+	11: "BIP-GMAC-128",
+	12: "BIP-GMAC-256",
+	13: "BIP-CMAC-256",
+}
+
+var dot11AKMs = map[byte]string{
+	1:  "802.1X",
+	2:  "PSK",
+	3:  "FT-802.1X",
+	4:  "FT-PSK",
+	5:  "802.1X-SHA256",
+	6:  "PSK-SHA256",
+	8:  "SAE",
+	9:  "FT-SAE",
+	11: "802.1X-SuiteB",
+	12: "802.1X-SuiteB-192",
+	18: "OWE",
+}
+
+// dot11Suite formats a cipher or AKM suite selector.
+func dot11Suite(selector []byte, names map[byte]string) string {
+	if (string(selector[:3]) == string(ouiIEEE80211) || string(selector[:3]) == string(ouiMicrosoft)) && names[selector[3]] != "" {
+		return names[selector[3]]
+	}
+
+	return hex.EncodeToString(selector)
+}
+
+// dot11RSN holds the security parameters of an RSN element or WPA vendor element.
+type dot11RSN struct {
+	groupCipher     string
+	pairwiseCiphers []string
+	akmSuites       []string
+	capabilities    int32
+}
+
+// parseDot11RSN parses the body of an RSN element, or a WPA vendor element after its OUI and type.
+// Both start with a version followed by the group cipher, pairwise cipher and AKM suite lists,
+// only the RSN element carries capabilities.
+func parseDot11RSN(data []byte) *dot11RSN {
+	if len(data) < 6 {
+		return nil
+	}
+
+	r := &dot11RSN{
+		groupCipher: dot11Suite(data[2:6], dot11Ciphers),
+	}
+	data = data[6:]
+
+	suites := func(names map[byte]string) []string {
+		if len(data) < 2 {
+			return nil
+		}
+
+		n := int(binary.LittleEndian.Uint16(data[:2]))
+		data = data[2:]
+
+		var out []string
+		for i := 0; i < n && len(data) >= 4; i++ {
+			out = append(out, dot11Suite(data[:4], names))
+			data = data[4:]
+		}
+
+		return out
+	}
+
+	r.pairwiseCiphers = suites(dot11Ciphers)
+	r.akmSuites = suites(dot11AKMs)
+
+	if len(data) >= 2 {
+		r.capabilities = int32(binary.LittleEndian.Uint16(data[:2]))
+	}
+
+	return r
+}
+
+// dot11Networks maps BSSIDs to the SSIDs learned from beacons, probe responses and association requests.
+var dot11Networks = struct {
+	sync.Mutex
+	items map[string]string
+}{
+	items: make(map[string]string),
+}
+
+func addDot11Network(bssid, ssid string) {
+	if ssid == "" {
+		return
+	}
+
+	dot11Networks.Lock()
+	dot11Networks.items[bssid] = ssid
+	dot11Networks.Unlock()
+}
+
+func lookupDot11Network(bssid string) string {
+	dot11Networks.Lock()
+	defer dot11Networks.Unlock()
+
+	return dot11Networks.items[bssid]
+}
@@ -0,0 +1,330 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	testAP     = []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	testClient = []byte{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb}
+)
+
+// testDot11Packet returns an 802.11 frame with the given frame control, addresses and body,
+// followed by a frame check sequence.
+func testDot11Packet(fc []byte, a1, a2, a3 []byte, body []byte, ts time.Time) gopacket.Packet {
+	frame := append([]byte{}, fc...)
+	frame = append(frame, 0x00, 0x00)
+	frame = append(frame, a1...)
+	frame = append(frame, a2...)
+	frame = append(frame, a3...)
+	frame = append(frame, 0x00, 0x00)
+	frame = append(frame, body...)
+	frame = append(frame, 0x00, 0x00, 0x00, 0x00)
+
+	p := gopacket.NewPacket(frame, layers.LayerTypeDot11, gopacket.Default)
+	p.Metadata().Timestamp = ts
+
+	return p
+}
+
+func testIE(id byte, info ...byte) []byte {
+	return append([]byte{id, byte(len(info))}, info...)
+}
+
+func TestDot11BeaconWPA3Transition(t *testing.T) {
+	body := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x64, 0x00, 0x11, 0x04}
+	body = append(body, testIE(0, []byte("netcap")...)...)
+	body = append(body, testIE(3, 6)...)
+	body = append(body, testIE(48,
+		0x01, 0x00,
+		0x00, 0x0f, 0xac, 0x04,
+		0x01, 0x00, 0x00, 0x0f, 0xac, 0x04,
+		0x02, 0x00, 0x00, 0x0f, 0xac, 0x02, 0x00, 0x0f, 0xac, 0x08,
+		0x80, 0x00,
+	)...)
+	body = append(body, testIE(221, 0x00, 0x50, 0xf2, 0x04, 0x10, 0x4a)...)
+
+	p := testDot11Packet([]byte{0x80, 0x00}, layers.EthernetBroadcast, testAP, testAP, body, time.Now())
+
+	r := dot11BeaconDecoder.Handler(p)
+	if r == nil {
+		t.Fatal("expected beacon record")
+	}
+
+	b := r.(*types.Dot11Beacon)
+	if b.Type != dot11TypeBeacon || b.SSID != "netcap" || b.Hidden || b.Channel != 6 || b.Interval != 100 {
+		t.Fatal("unexpected beacon", b)
+	}
+
+	if b.BSSID != "00:11:22:33:44:55" {
+		t.Fatal("unexpected BSSID", b.BSSID)
+	}
+
+	if b.Security != "WPA2/WPA3" || b.GroupCipher != "CCMP-128" || strings.Join(b.AKMSuites, ",") != "PSK,SAE" {
+		t.Fatal("unexpected security", b.Security, b.GroupCipher, b.AKMSuites)
+	}
+
+	if !b.MFPCapable || b.MFPRequired {
+		t.Fatal("expected management frame protection to be capable but not required")
+	}
+
+	if len(b.VendorIEs) != 1 || b.VendorIEs[0] != "00:50:f2/4" {
+		t.Fatal("unexpected vendor elements", b.VendorIEs)
+	}
+
+	if lookupDot11Network(b.BSSID) != "netcap" {
+		t.Fatal("expected network to be registered")
+	}
+}
+
+func TestDot11Security(t *testing.T) {
+	wpa := &dot11RSN{akmSuites: []string{"PSK"}}
+
+	tests := []struct {
+		rsn, wpa *dot11RSN
+		privacy  bool
+		expected string
+	}{
+		{nil, nil, false, "OPEN"},
+		{nil, nil, true, "WEP"},
+		{nil, wpa, true, "WPA"},
+		{&dot11RSN{akmSuites: []string{"PSK"}}, wpa, true, "WPA/WPA2"},
+		{&dot11RSN{akmSuites: []string{"SAE"}}, nil, true, "WPA3"},
+		{&dot11RSN{akmSuites: []string{"OWE"}}, nil, true, "OWE"},
+	}
+
+	for _, test := range tests {
+		if s := dot11Security(test.rsn, test.wpa, test.privacy); s != test.expected {
+			t.Fatal("expected", test.expected, "got", s)
+		}
+	}
+}
+
+func TestDot11HiddenSSID(t *testing.T) {
+	if _, hidden := dot11SSID(parseDot11IEs(testIE(0, 0, 0, 0))); !hidden {
+		t.Fatal("expected null SSID to be hidden")
+	}
+
+	if ssid, hidden := dot11SSID(parseDot11IEs(testIE(0, 'a'))); hidden || ssid != "a" {
+		t.Fatal("unexpected SSID", ssid)
+	}
+}
+
+func TestDot11FrequencyToChannel(t *testing.T) {
+	for freq, channel := range map[int]int32{2412: 1, 2484: 14, 5180: 36, 5955: 1, 900: 0} {
+		if c := dot11FrequencyToChannel(freq); c != channel {
+			t.Fatal("expected channel", channel, "for", freq, "got", c)
+		}
+	}
+}
+
+func TestDot11ProbeRequest(t *testing.T) {
+	body := append(testIE(0, []byte("home")...), testIE(1, 0x82, 0x84)...)
+	p := testDot11Packet([]byte{0x40, 0x00}, layers.EthernetBroadcast, testClient, layers.EthernetBroadcast, body, time.Now())
+
+	r := dot11ProbeRequestDecoder.Handler(p)
+	if r == nil {
+		t.Fatal("expected probe request record")
+	}
+
+	req := r.(*types.Dot11ProbeRequest)
+	if req.ClientMAC != "66:77:88:99:aa:bb" || len(req.SSIDs) != 1 || req.SSIDs[0] != "home" || req.Wildcard {
+		t.Fatal("unexpected probe request", req)
+	}
+
+	if !decodeDot11ProbeRequest(parseDot11IEs(testIE(0))).Wildcard {
+		t.Fatal("expected wildcard probe request")
+	}
+}
+
+func TestDot11DeauthFlood(t *testing.T) {
+	src := []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	start := time.Now()
+
+	var d *types.Dot11Deauth
+	for i := 0; i < dot11FloodThreshold; i++ {
+		p := testDot11Packet([]byte{0xc0, 0x00}, layers.EthernetBroadcast, src, src, []byte{0x07, 0x00}, start.Add(time.Duration(i)*time.Millisecond))
+
+		r := dot11DeauthDecoder.Handler(p)
+		if r == nil {
+			t.Fatal("expected deauth record")
+		}
+
+		d = r.(*types.Dot11Deauth)
+		if i < dot11FloodThreshold-1 && d.Flood {
+			t.Fatal("unexpected flood after", i+1, "frames")
+		}
+	}
+
+	if d.Type != dot11TypeDeauthentication || d.ReasonCode != 7 || !d.Broadcast || !d.Flood || d.FrameCount != dot11FloodThreshold {
+		t.Fatal("unexpected deauth", d)
+	}
+
+	// a new window starts after the detection window elapsed
+	p := testDot11Packet([]byte{0xa0, 0x00}, testClient, src, src, []byte{0x08, 0x00}, start.Add(2*dot11FloodWindow))
+
+	d = dot11DeauthDecoder.Handler(p).(*types.Dot11Deauth)
+	if d.Type != dot11TypeDisassociation || d.Flood || d.FrameCount != 1 || d.Broadcast {
+		t.Fatal("unexpected disassociation", d)
+	}
+}
+
+// testEAPOLKeyFrame returns an EAPOL-Key frame including the EAPOL header.
+func testEAPOLKeyFrame(info uint16, replay uint64, nonce, mic byte, keyData []byte) []byte {
+	body := make([]byte, 95)
+	body[0] = 2
+	binary.BigEndian.PutUint16(body[1:3], info)
+	binary.BigEndian.PutUint16(body[3:5], 16)
+	binary.BigEndian.PutUint64(body[5:13], replay)
+
+	if nonce != 0 {
+		for i := 13; i < 45; i++ {
+			body[i] = nonce
+		}
+	}
+
+	if mic != 0 {
+		for i := 77; i < 93; i++ {
+			body[i] = mic
+		}
+	}
+
+	binary.BigEndian.PutUint16(body[93:95], uint16(len(keyData)))
+	body = append(body, keyData...)
+
+	frame := []byte{0x02, 0x03, 0x00, 0x00}
+	binary.BigEndian.PutUint16(frame[2:4], uint16(len(body)))
+
+	return append(frame, body...)
+}
+
+func testEAPOLKey(t *testing.T, frame []byte) *layers.EAPOLKey {
+	k := new(layers.EAPOLKey)
+	if err := k.DecodeFromBytes(frame[4:], gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+func TestDot11HandshakeExport(t *testing.T) {
+	tracker := newDot11HandshakeTracker()
+	addDot11Network("00:11:22:33:44:55", "netcap")
+
+	pmkid := []byte{0xdd, 0x14, 0x00, 0x0f, 0xac, 0x04}
+	for i := 0; i < 16; i++ {
+		pmkid = append(pmkid, 0xcc)
+	}
+
+	m1 := testEAPOLKeyFrame(0x008a, 1, 0xaa, 0, pmkid)
+
+	r := tracker.add(testAP, testClient, testEAPOLKey(t, m1), m1, 1)
+	if r == nil || r.Type != dot11HandshakePMKID {
+		t.Fatal("expected PMKID record", r)
+	}
+
+	if r.Hash != "WPA*01*"+strings.Repeat("cc", 16)+"*001122334455*66778899aabb*"+"6e6574636170"+"***" {
+		t.Fatal("unexpected PMKID hash", r.Hash)
+	}
+
+	// the same PMKID is exported only once
+	if tracker.add(testAP, testClient, testEAPOLKey(t, m1), m1, 2) != nil {
+		t.Fatal("expected duplicate PMKID to be ignored")
+	}
+
+	m2 := testEAPOLKeyFrame(0x010a, 1, 0xbb, 0xee, nil)
+
+	r = tracker.add(testClient, testAP, testEAPOLKey(t, m2), m2, 3)
+	if r == nil || r.Type != dot11HandshakeEAPOL || r.MessagePair != messagePairM1M2 || r.KeyVersion != 2 {
+		t.Fatal("expected EAPOL record", r)
+	}
+
+	fields := strings.Split(r.Hash, "*")
+	if len(fields) != 9 || fields[1] != "02" || fields[2] != strings.Repeat("ee", 16) || fields[6] != strings.Repeat("aa", 32) || fields[8] != "00" {
+		t.Fatal("unexpected EAPOL hash", r.Hash)
+	}
+
+	// the MIC is zeroed in the exported EAPOL frame
+	if strings.Contains(fields[7], "ee") || len(fields[7]) != 2*len(m2) {
+		t.Fatal("unexpected EAPOL frame", fields[7])
+	}
+
+	// M3 does not produce another hash for an already paired M2
+	m3 := testEAPOLKeyFrame(0x13ca, 2, 0xaa, 0xdd, nil)
+	if tracker.add(testAP, testClient, testEAPOLKey(t, m3), m3, 4) != nil {
+		t.Fatal("expected M3 to be ignored")
+	}
+
+	m4 := testEAPOLKeyFrame(0x030a, 2, 0, 0xdd, nil)
+	if eapolKeyMessage(testEAPOLKey(t, m4)) != 4 {
+		t.Fatal("expected M4")
+	}
+}
+
+func TestDot11HandshakeM2M3(t *testing.T) {
+	tracker := newDot11HandshakeTracker()
+	ap := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+
+	m2 := testEAPOLKeyFrame(0x010a, 5, 0xbb, 0xee, nil)
+	if tracker.add(testClient, ap, testEAPOLKey(t, m2), m2, 1) != nil {
+		t.Fatal("expected no record without M1")
+	}
+
+	m3 := testEAPOLKeyFrame(0x13ca, 6, 0xaa, 0xdd, nil)
+	if tracker.add(ap, testClient, testEAPOLKey(t, m3), m3, 2) != nil {
+		t.Fatal("expected record for unknown network to be held back")
+	}
+
+	if len(tracker.pending) != 1 {
+		t.Fatal("expected pending hash")
+	}
+
+	ph := tracker.pending[0]
+	if ph.record.MessagePair != messagePairM2M3 || ph.hash.line("x") == "" || !strings.HasSuffix(ph.hash.line("x"), "*02") {
+		t.Fatal("unexpected pending hash", ph.hash.line("x"))
+	}
+}
+
+func TestDot11HandshakeDecoder(t *testing.T) {
+	ap := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x77}
+	addDot11Network("00:11:22:33:44:77", "lab")
+
+	pmkid := []byte{0xdd, 0x14, 0x00, 0x0f, 0xac, 0x04}
+	for i := 0; i < 16; i++ {
+		pmkid = append(pmkid, 0x01)
+	}
+
+	body := append([]byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x88, 0x8e}, testEAPOLKeyFrame(0x008a, 1, 0xaa, 0, pmkid)...)
+	p := testDot11Packet([]byte{0x08, 0x02}, testClient, ap, ap, body, time.Now())
+
+	r := dot11HandshakeDecoder.Handler(p)
+	if r == nil {
+		t.Fatal("expected handshake record")
+	}
+
+	h := r.(*types.Dot11Handshake)
+	if h.Type != dot11HandshakePMKID || h.BSSID != "00:11:22:33:44:77" || h.ClientMAC != "66:77:88:99:aa:bb" || h.SSID != "lab" {
+		t.Fatal("unexpected handshake", h)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var dot11ProbeRequestDecoder = newPacketDecoder(
+	types.Type_NC_Dot11ProbeRequest,
+	"Dot11ProbeRequest",
+	"IEEE 802.11 probe requests are sent by clients searching for networks, and reveal the networks a device has previously connected to",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		dot11, ok := p.Layer(layers.LayerTypeDot11).(*layers.Dot11)
+		if !ok {
+			return nil
+		}
+
+		req, ok := p.Layer(layers.LayerTypeDot11MgmtProbeReq).(*layers.Dot11MgmtProbeReq)
+		if !ok {
+			return nil
+		}
+
+		r := decodeDot11ProbeRequest(parseDot11IEs(req.Contents))
+		r.Timestamp = p.Metadata().Timestamp.UnixNano()
+		r.ClientMAC = dot11.Address2.String()
+		r.DstMAC = dot11.Address1.String()
+		r.BSSID = dot11.Address3.String()
+
+		return r
+	},
+	nil,
+)
+
+// decodeDot11ProbeRequest collects the requested SSIDs from the information elements of a probe request.
+// A probe request without an SSID is a wildcard request for any network.
+func decodeDot11ProbeRequest(ies []dot11IE) *types.Dot11ProbeRequest {
+	r := &types.Dot11ProbeRequest{
+		VendorIEs: dot11VendorIEs(ies),
+	}
+
+	for _, ie := range ies {
+		if ie.id == layers.Dot11InformationElementIDSSID && len(ie.info) > 0 {
+			r.SSIDs = decoderutils.AppendUnique(r.SSIDs, string(ie.info))
+		}
+	}
+
+	r.Wildcard = len(r.SSIDs) == 0
+
+	return r
+}
@@ -0,0 +1,165 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// SNAP protocol id of DTP messages
+	ethernetTypeDTP layers.EthernetType = 0x2004
+
+	dtpTLVHeaderSize = 4
+
+	// TLV types
+	dtpTLVDomain   = 1
+	dtpTLVStatus   = 2
+	dtpTLVType     = 3
+	dtpTLVNeighbor = 4
+
+	// status and type fields carry the operating value in the upper and the administrative value in the lower bits
+	dtpOperatingStatus     = 0x80
+	dtpAdministrativeValue = 0x07
+	dtpOperatingTypeShift  = 5
+)
+
+var dtpAdministrativeStatus = map[byte]string{
+	1: "On",
+	2: "Off",
+	3: "Desirable",
+	4: "Auto",
+}
+
+var dtpTrunkTypes = map[byte]string{
+	0: "Negotiated",
+	1: "ISL",
+	2: "802.1Q",
+	5: "802.1Q",
+}
+
+// LayerTypeDTP is the gopacket layer type for the Cisco Dynamic Trunking Protocol.
+var LayerTypeDTP = gopacket.RegisterLayerType(layerNumDTP, gopacket.LayerTypeMetadata{
+	Name:    "DTP",
+	Decoder: gopacket.DecodeFunc(decodeDTPLayer),
+})
+
+func init() {
+	layers.EthernetTypeMetadata[ethernetTypeDTP] = layers.EnumMetadata{
+		DecodeWith: LayerTypeDTP,
+		Name:       "DTP",
+		LayerType:  LayerTypeDTP,
+	}
+}
+
+// DTP is a Dynamic Trunking Protocol message.
+type DTP struct {
+	layers.BaseLayer
+}
+
+// LayerType returns LayerTypeDTP.
+func (d *DTP) LayerType() gopacket.LayerType {
+	return LayerTypeDTP
+}
+
+func decodeDTPLayer(data []byte, p gopacket.PacketBuilder) error {
+	p.AddLayer(&DTP{BaseLayer: layers.BaseLayer{Contents: data}})
+
+	return nil
+}
+
+var dtpDecoder = newGoPacketDecoder(
+	types.Type_NC_DTP,
+	LayerTypeDTP,
+	"The Cisco Dynamic Trunking Protocol negotiates trunking between switch ports, which can be abused to turn an access port into a trunk for VLAN hopping",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if l, ok := layer.(*DTP); ok {
+			d, err := decodeDTP(l.Contents)
+			if err != nil {
+				decoderLog.Debug("failed to decode DTP message", zap.Error(err))
+
+				return nil
+			}
+
+			d.Timestamp = timestamp
+
+			return d
+		}
+
+		return nil
+	},
+)
+
+// decodeDTP parses the version and the TLVs of a DTP message.
+func decodeDTP(data []byte) (*types.DTP, error) {
+	if len(data) < 1 {
+		return nil, errors.New("empty DTP message")
+	}
+
+	d := &types.DTP{
+		Version: int32(data[0]),
+	}
+
+	for data = data[1:]; len(data) >= dtpTLVHeaderSize; {
+		var (
+			typ    = binary.BigEndian.Uint16(data[:2])
+			length = int(binary.BigEndian.Uint16(data[2:4]))
+		)
+
+		if length < dtpTLVHeaderSize || length > len(data) {
+			break
+		}
+
+		value := data[dtpTLVHeaderSize:length]
+		data = data[length:]
+
+		switch {
+		case typ == dtpTLVDomain:
+			d.Domain = strings.TrimRight(string(value), "\x00")
+		case typ == dtpTLVStatus && len(value) == 1:
+			d.OperatingStatus = "Access"
+			if value[0]&dtpOperatingStatus != 0 {
+				d.OperatingStatus = "Trunk"
+			}
+
+			d.AdministrativeStatus = dtpName(dtpAdministrativeStatus, value[0]&dtpAdministrativeValue)
+		case typ == dtpTLVType && len(value) == 1:
+			d.OperatingType = dtpName(dtpTrunkTypes, value[0]>>dtpOperatingTypeShift)
+			d.AdministrativeType = dtpName(dtpTrunkTypes, value[0]&dtpAdministrativeValue)
+		case typ == dtpTLVNeighbor && len(value) == 6:
+			d.Neighbor = net.HardwareAddr(value).String()
+		}
+	}
+
+	return d, nil
+}
+
+func dtpName(names map[byte]string, v byte) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(v))
+}
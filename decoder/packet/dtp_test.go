@@ -0,0 +1,55 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestDTPDesirable(t *testing.T) {
+	frame := []byte{
+		0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc,
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55,
+		0x00, 0x22,
+		0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x04, // SNAP
+		0x01,
+		0x00, 0x01, 0x00, 0x05, 0x00, // empty domain
+		0x00, 0x02, 0x00, 0x05, 0x03, // access, desirable
+		0x00, 0x03, 0x00, 0x05, 0xa5, // 802.1Q
+		0x00, 0x04, 0x00, 0x0a, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55,
+	}
+
+	p := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+
+	l := p.Layer(LayerTypeDTP)
+	if l == nil {
+		t.Fatalf("expected DTP layer, got %v", p)
+	}
+
+	r := dtpDecoder.Handler(l, 1)
+	if r == nil {
+		t.Fatal("expected DTP record")
+	}
+
+	d := r.(*types.DTP)
+	if d.Version != 1 || d.OperatingStatus != "Access" || d.AdministrativeStatus != "Desirable" ||
+		d.OperatingType != "802.1Q" || d.AdministrativeType != "802.1Q" || d.Neighbor != "00:11:22:33:44:55" {
+		t.Fatalf("unexpected DTP record: %+v", d)
+	}
+}
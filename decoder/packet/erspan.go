@@ -0,0 +1,180 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// GRE protocol types of the mirroring session headers
+	ethernetTypeERSPANTypeII  layers.EthernetType = 0x88be
+	ethernetTypeERSPANTypeIII layers.EthernetType = 0x22eb
+
+	// header versions
+	erspanVersionTypeII  = 1
+	erspanVersionTypeIII = 2
+
+	erspanTypeIIHeaderSize  = 8
+	erspanTypeIIIHeaderSize = 12
+
+	// the optional platform specific sub header of type III headers
+	erspanPlatformHeaderSize = 8
+
+	// frame type of ethernet frames in type III headers
+	erspanFrameTypeEthernet = 0
+)
+
+var errTruncatedERSPAN = errors.New("truncated ERSPAN header")
+
+// LayerTypeERSPAN is the gopacket layer type for the Encapsulated Remote Switched Port Analyzer header.
+// It is registered for the type II and type III GRE protocol types, so that the mirrored ethernet frames get decoded.
+var LayerTypeERSPAN = gopacket.RegisterLayerType(layerNumERSPAN, gopacket.LayerTypeMetadata{
+	Name:    "ERSPAN",
+	Decoder: gopacket.DecodeFunc(decodeERSPANLayer),
+})
+
+func init() {
+	layers.EthernetTypeMetadata[ethernetTypeERSPANTypeII] = layers.EnumMetadata{
+		DecodeWith: LayerTypeERSPAN,
+		Name:       "ERSPAN",
+		LayerType:  LayerTypeERSPAN,
+	}
+	layers.EthernetTypeMetadata[ethernetTypeERSPANTypeIII] = layers.EnumMetadata{
+		DecodeWith: LayerTypeERSPAN,
+		Name:       "ERSPAN",
+		LayerType:  LayerTypeERSPAN,
+	}
+}
+
+// ERSPAN is a type II or type III ERSPAN header.
+type ERSPAN struct {
+	layers.BaseLayer
+	Version           uint8
+	VLAN              uint16
+	CoS               uint8
+	Encapsulation     uint8
+	Truncated         bool
+	SessionID         uint16
+	Index             uint32
+	HardwareTimestamp uint32
+	SecurityGroupTag  uint16
+	FrameType         uint8
+	HardwareID        uint8
+	Egress            bool
+	Granularity       uint8
+}
+
+// LayerType returns LayerTypeERSPAN.
+func (e *ERSPAN) LayerType() gopacket.LayerType {
+	return LayerTypeERSPAN
+}
+
+// decodeERSPANLayer decodes the ERSPAN header, unknown versions are treated as payload.
+func decodeERSPANLayer(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) < erspanTypeIIHeaderSize {
+		return errTruncatedERSPAN
+	}
+
+	var (
+		word = binary.BigEndian.Uint32(data[:4])
+		e    = &ERSPAN{
+			Version:       data[0] >> 4,
+			VLAN:          binary.BigEndian.Uint16(data[:2]) & 0x0fff,
+			CoS:           uint8(word >> 13 & 0x07),
+			Encapsulation: uint8(word >> 11 & 0x03),
+			Truncated:     word&0x0400 != 0,
+			SessionID:     uint16(word & 0x03ff),
+		}
+		size int
+	)
+
+	switch e.Version {
+	case erspanVersionTypeII:
+		size = erspanTypeIIHeaderSize
+		e.Index = binary.BigEndian.Uint32(data[4:8]) & 0x000fffff
+	case erspanVersionTypeIII:
+		if len(data) < erspanTypeIIIHeaderSize {
+			return errTruncatedERSPAN
+		}
+
+		size = erspanTypeIIIHeaderSize
+		e.HardwareTimestamp = binary.BigEndian.Uint32(data[4:8])
+		e.SecurityGroupTag = binary.BigEndian.Uint16(data[8:10])
+
+		flags := binary.BigEndian.Uint16(data[10:12])
+		e.FrameType = uint8(flags >> 10 & 0x1f)
+		e.HardwareID = uint8(flags >> 4 & 0x3f)
+		e.Egress = flags&0x0008 != 0
+		e.Granularity = uint8(flags >> 1 & 0x03)
+
+		// optional platform specific sub header
+		if flags&0x0001 != 0 {
+			size += erspanPlatformHeaderSize
+			if len(data) < size {
+				return errTruncatedERSPAN
+			}
+		}
+	default:
+		return gopacket.DecodePayload.Decode(data, p)
+	}
+
+	e.BaseLayer = layers.BaseLayer{Contents: data[:size], Payload: data[size:]}
+	p.AddLayer(e)
+
+	if len(e.Payload) == 0 {
+		return nil
+	}
+
+	if e.FrameType != erspanFrameTypeEthernet {
+		return p.NextDecoder(gopacket.LayerTypePayload)
+	}
+
+	return p.NextDecoder(layers.LayerTypeEthernet)
+}
+
+var erspanDecoder = newGoPacketDecoder(
+	types.Type_NC_ERSPAN,
+	LayerTypeERSPAN,
+	"The Encapsulated Remote Switched Port Analyzer transports mirrored ethernet frames from a switch port over GRE to a remote collector",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if e, ok := layer.(*ERSPAN); ok {
+			return &types.ERSPAN{
+				Timestamp:         timestamp,
+				Version:           int32(e.Version),
+				VLAN:              int32(e.VLAN),
+				CoS:               int32(e.CoS),
+				Encapsulation:     int32(e.Encapsulation),
+				Truncated:         e.Truncated,
+				SessionID:         int32(e.SessionID),
+				Index:             int32(e.Index),
+				HardwareTimestamp: e.HardwareTimestamp,
+				SecurityGroupTag:  int32(e.SecurityGroupTag),
+				FrameType:         int32(e.FrameType),
+				HardwareID:        int32(e.HardwareID),
+				Egress:            e.Egress,
+				Granularity:       int32(e.Granularity),
+			}
+		}
+
+		return nil
+	},
+)
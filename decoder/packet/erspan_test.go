@@ -0,0 +1,83 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestERSPANTypeII(t *testing.T) {
+	data := append([]byte{
+		0x10, 0x0a, 0x28, 0x05, // version 1, vlan 10, cos 1, session 5
+		0x00, 0x00, 0x00, 0x07, // index 7
+	}, testSampledFrame(t)...)
+
+	p := gopacket.NewPacket(data, LayerTypeERSPAN, gopacket.Default)
+
+	l := p.Layer(LayerTypeERSPAN)
+	if l == nil {
+		t.Fatalf("expected ERSPAN layer, got %v", p)
+	}
+
+	if p.Layer(layers.LayerTypeTCP) == nil {
+		t.Fatalf("expected mirrored TCP layer, got %v", p.Layers())
+	}
+
+	r := erspanDecoder.Handler(l, 1)
+	if r == nil {
+		t.Fatal("expected ERSPAN record")
+	}
+
+	e := r.(*types.ERSPAN)
+	if e.Version != 1 || e.VLAN != 10 || e.CoS != 1 || e.SessionID != 5 || e.Index != 7 {
+		t.Fatalf("unexpected ERSPAN record: %+v", e)
+	}
+}
+
+func TestERSPANTypeIII(t *testing.T) {
+	data := append([]byte{
+		0x20, 0x00, 0x04, 0x2a, // version 2, truncated, session 42
+		0x00, 0x00, 0x10, 0x00, // timestamp
+		0x00, 0x64, 0x00, 0x19, // sgt 100, hardware id 1, egress, platform sub header
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}, testSampledFrame(t)...)
+
+	p := gopacket.NewPacket(data, LayerTypeERSPAN, gopacket.Default)
+
+	e, ok := p.Layer(LayerTypeERSPAN).(*ERSPAN)
+	if !ok {
+		t.Fatalf("expected ERSPAN layer, got %v", p)
+	}
+
+	if e.Version != 2 || !e.Truncated || e.SessionID != 42 || e.HardwareTimestamp != 4096 ||
+		e.SecurityGroupTag != 100 || e.HardwareID != 1 || !e.Egress || len(e.Contents) != 20 {
+		t.Fatalf("unexpected ERSPAN layer: %+v", e)
+	}
+
+	if p.Layer(layers.LayerTypeEthernet) == nil {
+		t.Fatalf("expected mirrored ethernet frame, got %v", p.Layers())
+	}
+}
+
+func TestERSPANTruncated(t *testing.T) {
+	p := gopacket.NewPacket([]byte{0x20, 0x00, 0x04, 0x2a, 0x00, 0x00, 0x10, 0x00}, LayerTypeERSPAN, gopacket.Default)
+	if p.ErrorLayer() == nil {
+		t.Fatal("expected decoding error for truncated type III header")
+	}
+}
@@ -0,0 +1,386 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// export protocols, as reported in the FlowExporter audit records.
+const (
+	protoNetFlowV5 = "NetFlow v5"
+	protoNetFlowV9 = "NetFlow v9"
+	protoIPFIX     = "IPFIX"
+	protoSFlow     = "sFlow v5"
+)
+
+var (
+	errInvalidFlowExport = errors.New("invalid flow export datagram")
+	errUnknownFlowExport = errors.New("unknown flow export protocol")
+)
+
+// default ports of the export protocols, used to find the datagrams in a capture.
+var flowExportPorts = map[layers.UDPPort]struct{}{
+	2055: {}, // NetFlow
+	2056: {}, // NetFlow
+	4739: {}, // IPFIX
+	6343: {}, // sFlow
+	9995: {}, // NetFlow
+	9996: {}, // NetFlow
+}
+
+// flowRecord is a flow exported by a router or switch, independent of the export protocol.
+// Packet and byte counters are scaled by the sampling rate of the exporter.
+type flowRecord struct {
+	start, end       time.Time
+	srcMAC, dstMAC   net.HardwareAddr
+	srcIP, dstIP     net.IP
+	srcPort, dstPort uint16
+	protocol         layers.IPProtocol
+	packets, bytes   uint64
+}
+
+// transport returns the endpoint type and the name of the transport layer,
+// if the protocol of the flow has ports.
+func (f *flowRecord) transport() (gopacket.EndpointType, string) {
+	switch f.protocol {
+	case layers.IPProtocolTCP:
+		return layers.EndpointTCPPort, layers.LayerTypeTCP.String()
+	case layers.IPProtocolUDP:
+		return layers.EndpointUDPPort, layers.LayerTypeUDP.String()
+	case layers.IPProtocolSCTP:
+		return layers.EndpointSCTPPort, layers.LayerTypeSCTP.String()
+	default:
+		return gopacket.EndpointInvalid, ""
+	}
+}
+
+// network returns the endpoint type and the name of the network layer.
+func (f *flowRecord) network() (gopacket.EndpointType, string) {
+	if f.srcIP.To4() != nil {
+		return layers.EndpointIPv4, layers.LayerTypeIPv4.String()
+	}
+
+	return layers.EndpointIPv6, layers.LayerTypeIPv6.String()
+}
+
+// connectionID returns the same identifier as for the packets of the flow,
+// so that exported flows and sampled packets are merged into the same connection.
+func (f *flowRecord) connectionID() connectionID {
+	var id connectionID
+
+	if f.srcMAC != nil && f.dstMAC != nil {
+		id.LinkFlowID = gopacket.NewFlow(layers.EndpointMAC, f.srcMAC, f.dstMAC).FastHash()
+	}
+
+	nt, _ := f.network()
+	id.NetworkFlowID = gopacket.NewFlow(nt, f.ip(f.srcIP), f.ip(f.dstIP)).FastHash()
+
+	if tt, transport := f.transport(); transport != "" {
+		id.TransportFlowID = gopacket.NewFlow(tt, port(f.srcPort), port(f.dstPort)).FastHash()
+	}
+
+	return id
+}
+
+// setAddresses sets the protocols and addresses of the connection.
+func (f *flowRecord) setAddresses(co *types.Connection) {
+	if f.srcMAC != nil && f.dstMAC != nil {
+		co.LinkProto = layers.LayerTypeEthernet.String()
+		co.SrcMAC = f.srcMAC.String()
+		co.DstMAC = f.dstMAC.String()
+	}
+
+	_, co.NetworkProto = f.network()
+	co.SrcIP = f.srcIP.String()
+	co.DstIP = f.dstIP.String()
+
+	if _, transport := f.transport(); transport != "" {
+		co.TransportProto = transport
+		co.SrcPort = strconv.Itoa(int(f.srcPort))
+		co.DstPort = strconv.Itoa(int(f.dstPort))
+	}
+}
+
+// ip returns the address in the length that is used for the network flow of a packet.
+func (f *flowRecord) ip(addr net.IP) net.IP {
+	if v4 := addr.To4(); v4 != nil {
+		return v4
+	}
+
+	return addr
+}
+
+func port(p uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, p)
+
+	return b
+}
+
+// flowDatagram holds the results of decoding a single export datagram.
+type flowDatagram struct {
+	exporter string
+	ts       time.Time
+	protocol string
+
+	// observation domain of NetFlow v9, IPFIX and sFlow
+	domain    uint32
+	hasDomain bool
+
+	// the last known sampling rate of the exporter, updated by options records and samples
+	samplingRate uint32
+
+	flows            []*flowRecord
+	templates        int64
+	unknownTemplates int64
+}
+
+// decode dispatches the datagram to the decoder for its protocol, which is identified by the version field.
+func (d *flowDatagram) decode(data []byte) error {
+	if len(data) < 4 {
+		return errInvalidFlowExport
+	}
+
+	switch binary.BigEndian.Uint16(data) {
+	case 5:
+		return d.decodeNetFlowV5(data)
+	case 9:
+		return d.decodeNetFlowV9(data)
+	case 10:
+		return d.decodeIPFIX(data)
+	case 0:
+		// sFlow uses a 32 bit version field
+		if binary.BigEndian.Uint32(data) == 5 {
+			return d.decodeSFlow(data)
+		}
+	}
+
+	return errUnknownFlowExport
+}
+
+// add appends the flow and scales its counters by the sampling rate.
+func (d *flowDatagram) add(f *flowRecord, rate uint32) {
+	if f.srcIP == nil || f.dstIP == nil {
+		return
+	}
+
+	if rate > 1 {
+		f.packets *= uint64(rate)
+		f.bytes *= uint64(rate)
+	}
+
+	d.flows = append(d.flows, f)
+}
+
+// flowReader reads the 32 bit aligned values of sFlow datagrams and records whether the data was too short.
+type flowReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *flowReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *flowReader) bytes(n int) []byte {
+	if r.err || n < 0 || len(r.data) < n {
+		r.err = true
+
+		return nil
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+
+	return b
+}
+
+// flowExporter wraps the summary of an exporter, so that it can be locked.
+type flowExporter struct {
+	sync.Mutex
+	*types.FlowExporter
+}
+
+// atomicFlowExporterMap contains all exporters and provides synchronized access.
+type atomicFlowExporterMap struct {
+	sync.Mutex
+	Items map[string]*flowExporter
+}
+
+var flowExporters = &atomicFlowExporterMap{
+	Items: make(map[string]*flowExporter),
+}
+
+// getFlowExporter fetches a known exporter or returns a new one.
+func getFlowExporter(addr string, ts time.Time) *flowExporter {
+	flowExporters.Lock()
+	defer flowExporters.Unlock()
+
+	e, ok := flowExporters.Items[addr]
+	if !ok {
+		e = &flowExporter{
+			FlowExporter: &types.FlowExporter{
+				Timestamp: ts.UnixNano(),
+				Addr:      addr,
+			},
+		}
+		flowExporters.Items[addr] = e
+	}
+
+	return e
+}
+
+// update adds the results of a decoded datagram to the summary of the exporter.
+func (e *flowExporter) update(d *flowDatagram) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.NumDatagrams++
+	e.NumFlows += int64(len(d.flows))
+	e.NumTemplates += d.templates
+	e.UnknownTemplates += d.unknownTemplates
+
+	if ts := d.ts.UnixNano(); ts > e.LastSeen {
+		e.LastSeen = ts
+	}
+
+	if d.protocol != "" {
+		e.Protocols = decoderutils.AppendUnique(e.Protocols, d.protocol)
+	}
+
+	if d.hasDomain && !containsUint32(e.Domains, d.domain) {
+		e.Domains = append(e.Domains, d.domain)
+	}
+
+	if d.samplingRate != 0 {
+		e.SamplingRate = d.samplingRate
+	}
+
+	for _, f := range d.flows {
+		e.Packets += f.packets
+		e.Bytes += f.bytes
+	}
+}
+
+func containsUint32(values []uint32, v uint32) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HandleFlowExport decodes a NetFlow v5, NetFlow v9, IPFIX or sFlow v5 datagram that was sent by the exporter at addr.
+// The flows are converted into connections and update the IP and device profiles, if the respective decoders are enabled.
+// Flows decoded before an error occurred are kept.
+func HandleFlowExport(addr string, data []byte, ts time.Time) error {
+	e := getFlowExporter(addr, ts)
+
+	e.Lock()
+	d := &flowDatagram{
+		exporter:     addr,
+		ts:           ts,
+		samplingRate: e.SamplingRate,
+	}
+	e.Unlock()
+
+	err := d.decode(data)
+
+	e.update(d)
+
+	for _, f := range d.flows {
+		ingestFlow(f)
+	}
+
+	return err
+}
+
+// ingestFlow passes an exported flow to the connection and profile decoders.
+// The device profile is only updated if the flow contains the MAC address of the source.
+func ingestFlow(f *flowRecord) {
+	if connectionDecoder.Writer != nil {
+		handleFlow(f)
+	}
+
+	if ipProfileDecoder.Writer == nil && deviceProfileDecoder.Writer == nil {
+		return
+	}
+
+	var (
+		src = getIPProfileFlow(f.srcIP.String(), f, true)
+		dst = getIPProfileFlow(f.dstIP.String(), f, false)
+	)
+
+	if deviceProfileDecoder.Writer != nil && f.srcMAC != nil {
+		updateDeviceProfileFlow(f, src, dst)
+	}
+}
+
+var flowExporterDecoder = newPacketDecoder(
+	types.Type_NC_FlowExporter,
+	"FlowExporter",
+	"NetFlow v5 and v9, IPFIX and sFlow datagrams of routers and switches are converted into connections and update the IP and device profiles, each exporter is summarized",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || p.NetworkLayer() == nil {
+			return nil
+		}
+
+		if _, ok = flowExportPorts[udp.DstPort]; !ok {
+			return nil
+		}
+
+		addr := p.NetworkLayer().NetworkFlow().Src().String()
+
+		if err := HandleFlowExport(addr, udp.Payload, p.Metadata().Timestamp); err != nil {
+			decoderLog.Debug("failed to decode flow export", zap.String("exporter", addr), zap.Error(err))
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		flowExporters.Lock()
+		defer flowExporters.Unlock()
+
+		for _, e := range flowExporters.Items {
+			e.Lock()
+			d.write(e.FlowExporter)
+			e.Unlock()
+		}
+
+		return nil
+	},
+)
@@ -0,0 +1,179 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+func TestFlowConnectionID(t *testing.T) {
+	var (
+		p  = gopacket.NewPacket(testSampledFrame(t), layers.LayerTypeEthernet, gopacket.Default)
+		id = connectionID{
+			LinkFlowID:      p.LinkLayer().LinkFlow().FastHash(),
+			NetworkFlowID:   p.NetworkLayer().NetworkFlow().FastHash(),
+			TransportFlowID: p.TransportLayer().TransportFlow().FastHash(),
+		}
+		f = &flowRecord{
+			srcMAC:   net.HardwareAddr{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb},
+			dstMAC:   net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+			srcIP:    net.ParseIP("10.2.0.2"),
+			dstIP:    net.ParseIP("10.2.0.1"),
+			srcPort:  80,
+			dstPort:  51000,
+			protocol: layers.IPProtocolTCP,
+		}
+	)
+
+	// the reply of the packet must be merged into the same connection
+	if f.connectionID() != id {
+		t.Fatalf("expected %+v, got %+v", id, f.connectionID())
+	}
+}
+
+func TestHandleFlow(t *testing.T) {
+	var (
+		start    = time.Unix(1600000000, 0)
+		response = &flowRecord{
+			start:    start.Add(time.Second),
+			end:      start.Add(3 * time.Second),
+			srcIP:    net.ParseIP("10.4.0.2"),
+			dstIP:    net.ParseIP("10.4.0.1"),
+			srcPort:  443,
+			dstPort:  50000,
+			protocol: layers.IPProtocolTCP,
+			packets:  8,
+			bytes:    9000,
+		}
+		request = &flowRecord{
+			start:    start,
+			end:      start.Add(2 * time.Second),
+			srcIP:    net.ParseIP("10.4.0.1"),
+			dstIP:    net.ParseIP("10.4.0.2"),
+			srcPort:  50000,
+			dstPort:  443,
+			protocol: layers.IPProtocolTCP,
+			packets:  5,
+			bytes:    700,
+		}
+	)
+
+	handleFlow(response)
+	handleFlow(request)
+
+	conns.Lock()
+	conn, ok := conns.Items[request.connectionID().String()]
+	conns.Unlock()
+
+	if !ok {
+		t.Fatal("connection missing")
+	}
+
+	// the earlier flow decides about the direction
+	if conn.SrcIP != "10.4.0.1" || conn.DstPort != "443" || conn.TransportProto != "TCP" || conn.NetworkProto != "IPv4" || conn.LinkProto != "" {
+		t.Fatalf("unexpected connection %+v", conn.Connection)
+	}
+
+	if conn.NumPackets != 13 || conn.TotalSize != 9700 || conn.TimestampFirst != start.UnixNano() || conn.TimestampLast != start.Add(3*time.Second).UnixNano() {
+		t.Fatalf("unexpected connection counters %+v", conn.Connection)
+	}
+
+	if addInt32(10, 1<<40) != 1<<31-1 {
+		t.Fatal("expected the counter to saturate")
+	}
+}
+
+func TestFlowProfiles(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		f     = &flowRecord{
+			start:    start,
+			end:      start.Add(time.Second),
+			srcMAC:   net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x05, 0x01},
+			dstMAC:   net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x05, 0x02},
+			srcIP:    net.ParseIP("10.5.0.1"),
+			dstIP:    net.ParseIP("10.5.0.2"),
+			srcPort:  40000,
+			dstPort:  53,
+			protocol: layers.IPProtocolUDP,
+			packets:  4,
+			bytes:    400,
+		}
+		src = getIPProfileFlow("10.5.0.1", f, true)
+		dst = getIPProfileFlow("10.5.0.2", f, false)
+	)
+
+	updateDeviceProfileFlow(f, src, dst)
+
+	// a second flow updates the counters of the existing profile
+	getIPProfileFlow("10.5.0.1", f, true)
+
+	if src.NumPackets != 8 || src.Bytes != 800 || src.TimestampLast != start.Add(time.Second).UnixNano() {
+		t.Fatalf("unexpected source profile %+v", src.IPProfile)
+	}
+
+	if len(src.SrcPorts) != 1 || src.SrcPorts[0].PortNumber != 40000 || src.SrcPorts[0].Stats.Packets != 8 || src.ContactedPorts[0].PortNumber != 53 {
+		t.Fatalf("unexpected source ports %+v", src.SrcPorts)
+	}
+
+	if len(dst.DstPorts) != 1 || dst.DstPorts[0].PortNumber != 53 || dst.DstPorts[0].Protocol != "UDP" {
+		t.Fatalf("unexpected destination ports %+v", dst.DstPorts)
+	}
+
+	DeviceProfiles.Lock()
+	p, ok := DeviceProfiles.Items["02:00:00:00:05:01"]
+	DeviceProfiles.Unlock()
+
+	if !ok {
+		t.Fatal("device profile missing")
+	}
+
+	if len(p.DeviceIPs) != 1 || p.DeviceIPs[0] != "10.5.0.1" || len(p.Contacts) != 1 || p.Contacts[0] != "10.5.0.2" || p.NumPackets != 4 {
+		t.Fatalf("unexpected device profile %+v", p.DeviceProfile)
+	}
+}
+
+func TestHandleFlowExport(t *testing.T) {
+	var (
+		ts   = time.Unix(1600000000, 0)
+		addr = "192.0.2.30"
+		data = testFlowData(
+			uint16(5), uint16(1), uint32(10000), uint32(ts.Unix()), uint32(0), uint32(1), uint16(0), uint16(0),
+			net.ParseIP("10.6.0.1"), net.ParseIP("10.6.0.2"), uint32(0), uint16(1), uint16(2), uint32(3), uint32(300), uint32(9000), uint32(9500),
+			uint16(1234), uint16(80), uint8(0), uint8(0), uint8(6), uint8(0), uint32(0), uint16(0), uint16(0),
+		)
+	)
+
+	if err := HandleFlowExport(addr, data, ts); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := HandleFlowExport(addr, []byte{0, 7, 0, 0}, ts.Add(time.Second)); err != errUnknownFlowExport {
+		t.Fatalf("expected %v, got %v", errUnknownFlowExport, err)
+	}
+
+	e := getFlowExporter(addr, ts)
+	if e.NumDatagrams != 2 || e.NumFlows != 1 || e.Packets != 3 || e.Bytes != 300 || e.LastSeen != ts.Add(time.Second).UnixNano() {
+		t.Fatalf("unexpected exporter %+v", e.FlowExporter)
+	}
+
+	if len(e.Protocols) != 1 || e.Protocols[0] != protoNetFlowV5 || len(e.Domains) != 0 {
+		t.Fatalf("unexpected protocols %v and domains %v", e.Protocols, e.Domains)
+	}
+}
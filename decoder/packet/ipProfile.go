@@ -76,6 +76,7 @@ var ipProfileDecoder = newPacketDecoder(
 		// flush writer
 		for _, item := range ipProfiles.Items {
 			item.Lock()
+			applyIPProfileInfo(item.IPProfile)
 			d.writeIPProfile(item.IPProfile)
 			item.Unlock()
 		}
@@ -84,6 +85,15 @@ var ipProfileDecoder = newPacketDecoder(
 	},
 )
 
+// applyIPProfileInfo adds the hostnames, services and models registered for the address of the profile.
+func applyIPProfileInfo(p *types.IPProfile) {
+	info := decoderutils.LookupDeviceInfo(p.Addr)
+
+	p.Hostnames = decoderutils.AppendUnique(p.Hostnames, info.Hostnames...)
+	p.Services = decoderutils.AppendUnique(p.Services, info.Services...)
+	p.Models = decoderutils.AppendUnique(p.Models, info.Models...)
+}
+
 // GetIPProfile fetches a known profile and updates it or returns a new one.
 func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipProfile {
 	if ipAddr == "" {
@@ -105,11 +115,11 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 		// Transport Layer
 		if tl := i.Packet.TransportLayer(); tl != nil {
 			if source {
-				doSrcPortUpdate(p, utils.DecodePort(tl.TransportFlow().Src().Raw()), tl.LayerType().String(), dataLen)
-				doContactedPortUpdate(p, utils.DecodePort(tl.TransportFlow().Dst().Raw()), tl.LayerType().String(), dataLen)
+				doSrcPortUpdate(p, utils.DecodePort(tl.TransportFlow().Src().Raw()), tl.LayerType().String(), dataLen, 1)
+				doContactedPortUpdate(p, utils.DecodePort(tl.TransportFlow().Dst().Raw()), tl.LayerType().String(), dataLen, 1)
 			} else {
-				doDstPortUpdate(p, utils.DecodePort(tl.TransportFlow().Dst().Raw()), tl.LayerType().String(), dataLen)
-				doContactedPortUpdate(p, utils.DecodePort(tl.TransportFlow().Src().Raw()), tl.LayerType().String(), dataLen)
+				doDstPortUpdate(p, utils.DecodePort(tl.TransportFlow().Dst().Raw()), tl.LayerType().String(), dataLen, 1)
+				doContactedPortUpdate(p, utils.DecodePort(tl.TransportFlow().Src().Raw()), tl.LayerType().String(), dataLen, 1)
 			}
 		}
 
@@ -159,9 +169,6 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 		sniMap  = make(map[string]int64)
 	)
 
-	// Network Layer: IP Geolocation
-	loc, _ := resolvers.LookupGeolocation(ipAddr)
-
 	// Transport Layer: Port information
 	srcPorts, dstPorts, contactedPorts := initPorts(i, source)
 
@@ -187,6 +194,29 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 		protos[protocol] = dpi.NewProto(&res)
 	}
 
+	// create new profile
+	p := newIPProfile(ipAddr, i.Timestamp)
+	p.NumPackets = 1
+	p.Ja3 = ja3Map
+	p.Protocols = protos
+	p.Bytes = dataLen
+	p.SrcPorts = srcPorts
+	p.DstPorts = dstPorts
+	p.ContactedPorts = contactedPorts
+	p.SNIs = sniMap
+
+	ipProfiles.Lock()
+	ipProfiles.Items[ipAddr] = p
+	ipProfiles.Unlock()
+
+	return p
+}
+
+// newIPProfile creates a profile for the address with its geolocation and DNS names.
+func newIPProfile(ipAddr string, ts int64) *ipProfile {
+	// Network Layer: IP Geolocation
+	loc, _ := resolvers.LookupGeolocation(ipAddr)
+
 	var names []string
 	if LocalDNS {
 		if name := resolvers.LookupDNSNameLocal(ipAddr); len(name) != 0 {
@@ -196,32 +226,59 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 		names = resolvers.LookupDNSNames(ipAddr)
 	}
 
-	// create new profile
-	p := &ipProfile{
+	return &ipProfile{
 		IPProfile: &types.IPProfile{
 			Addr:           ipAddr,
-			NumPackets:     1,
 			Geolocation:    loc,
 			DNSNames:       names,
-			TimestampFirst: i.Timestamp,
-			Ja3:            ja3Map,
-			Protocols:      protos,
-			Bytes:          dataLen,
-			SrcPorts:       srcPorts,
-			DstPorts:       dstPorts,
-			ContactedPorts: contactedPorts,
-			SNIs:           sniMap,
+			TimestampFirst: ts,
+			Ja3:            make(map[string]string),
+			Protocols:      make(map[string]*types.Protocol),
+			SNIs:           make(map[string]int64),
 		},
 	}
+}
 
+// getIPProfileFlow fetches a known profile and updates it with the counters of an exported flow or returns a new one.
+func getIPProfileFlow(ipAddr string, f *flowRecord, source bool) *ipProfile {
 	ipProfiles.Lock()
-	ipProfiles.Items[ipAddr] = p
+	p, ok := ipProfiles.Items[ipAddr]
+	if !ok {
+		p = newIPProfile(ipAddr, f.start.UnixNano())
+		ipProfiles.Items[ipAddr] = p
+	}
 	ipProfiles.Unlock()
 
+	p.Lock()
+
+	p.NumPackets += int64(f.packets)
+	p.Bytes += f.bytes
+
+	if start := f.start.UnixNano(); start < p.TimestampFirst {
+		p.TimestampFirst = start
+	}
+
+	if end := f.end.UnixNano(); end > p.TimestampLast {
+		p.TimestampLast = end
+	}
+
+	// Transport Layer
+	if _, transport := f.transport(); transport != "" {
+		if source {
+			doSrcPortUpdate(p, int32(f.srcPort), transport, f.bytes, f.packets)
+			doContactedPortUpdate(p, int32(f.dstPort), transport, f.bytes, f.packets)
+		} else {
+			doDstPortUpdate(p, int32(f.dstPort), transport, f.bytes, f.packets)
+			doContactedPortUpdate(p, int32(f.srcPort), transport, f.bytes, f.packets)
+		}
+	}
+
+	p.Unlock()
+
 	return p
 }
 
-func doSrcPortUpdate(p *ipProfile, srcPort int32, layerType string, dataLen uint64) {
+func doSrcPortUpdate(p *ipProfile, srcPort int32, layerType string, dataLen, packets uint64) {
 	var found bool
 
 	// source port
@@ -229,7 +286,7 @@ func doSrcPortUpdate(p *ipProfile, srcPort int32, layerType string, dataLen uint
 		if port.PortNumber == srcPort && port.Protocol == layerType {
 
 			atomic.AddUint64(&port.Stats.Bytes, dataLen)
-			atomic.AddUint64(&port.Stats.Packets, 1)
+			atomic.AddUint64(&port.Stats.Packets, packets)
 
 			found = true
 
@@ -243,20 +300,20 @@ func doSrcPortUpdate(p *ipProfile, srcPort int32, layerType string, dataLen uint
 			Protocol:   layerType,
 			Stats: &types.PortStats{
 				Bytes:   dataLen,
-				Packets: 1,
+				Packets: packets,
 			},
 		})
 	}
 }
 
-func doContactedPortUpdate(p *ipProfile, dstPort int32, layerType string, dataLen uint64) {
+func doContactedPortUpdate(p *ipProfile, dstPort int32, layerType string, dataLen, packets uint64) {
 	var found bool
 
 	for _, port := range p.ContactedPorts {
 		if port.PortNumber == dstPort && port.Protocol == layerType {
 
 			atomic.AddUint64(&port.Stats.Bytes, dataLen)
-			atomic.AddUint64(&port.Stats.Packets, 1)
+			atomic.AddUint64(&port.Stats.Packets, packets)
 
 			found = true
 
@@ -270,20 +327,20 @@ func doContactedPortUpdate(p *ipProfile, dstPort int32, layerType string, dataLe
 			Protocol:   layerType,
 			Stats: &types.PortStats{
 				Bytes:   dataLen,
-				Packets: 1,
+				Packets: packets,
 			},
 		})
 	}
 }
 
-func doDstPortUpdate(p *ipProfile, dstPort int32, layerType string, dataLen uint64) {
+func doDstPortUpdate(p *ipProfile, dstPort int32, layerType string, dataLen, packets uint64) {
 	var found bool
 
 	// destination port
 	for _, port := range p.DstPorts {
 		if port.PortNumber == dstPort && port.Protocol == layerType {
 			atomic.AddUint64(&port.Stats.Bytes, dataLen)
-			atomic.AddUint64(&port.Stats.Packets, 1)
+			atomic.AddUint64(&port.Stats.Packets, packets)
 
 			found = true
 
@@ -297,7 +354,7 @@ func doDstPortUpdate(p *ipProfile, dstPort int32, layerType string, dataLen uint
 			Protocol:   layerType,
 			Stats: &types.PortStats{
 				Bytes:   dataLen,
-				Packets: 1,
+				Packets: packets,
 			},
 		})
 	}
@@ -0,0 +1,454 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+const (
+	ipfixHeaderSize = 16
+
+	netflowVersion9 = 9
+	ipfixVersion    = 10
+
+	// set ids of templates, data sets use the id of their template
+	setNetFlowTemplate        = 0
+	setNetFlowOptionsTemplate = 1
+	setIPFIXTemplate          = 2
+	setIPFIXOptionsTemplate   = 3
+	minDataSetID              = 256
+
+	// fields with this length are encoded with a length prefix in IPFIX
+	variableLength = 0xffff
+
+	// set for enterprise specific information elements in IPFIX templates
+	enterpriseBit = 0x8000
+)
+
+// information elements of NetFlow v9 and IPFIX that are converted into flows, see RFC 3954 and RFC 7012.
+const (
+	ieOctetDeltaCount            = 1
+	iePacketDeltaCount           = 2
+	ieProtocolIdentifier         = 4
+	ieSourceTransportPort        = 7
+	ieSourceIPv4Address          = 8
+	ieDestinationTransportPort   = 11
+	ieDestinationIPv4Address     = 12
+	ieFlowEndSysUpTime           = 21
+	ieFlowStartSysUpTime         = 22
+	iePostOctetDeltaCount        = 23
+	iePostPacketDeltaCount       = 24
+	ieSourceIPv6Address          = 27
+	ieDestinationIPv6Address     = 28
+	ieSamplingInterval           = 34
+	ieSamplerRandomInterval      = 50
+	ieSourceMacAddress           = 56
+	iePostDestinationMacAddress  = 57
+	ieDestinationMacAddress      = 80
+	iePostSourceMacAddress       = 81
+	ieOctetTotalCount            = 85
+	iePacketTotalCount           = 86
+	ieFlowStartSeconds           = 150
+	ieFlowEndSeconds             = 151
+	ieFlowStartMilliseconds      = 152
+	ieFlowEndMilliseconds        = 153
+	ieSystemInitTimeMilliseconds = 160
+	ieSamplingPacketInterval     = 305
+)
+
+type flowTemplateField struct {
+	id         uint16
+	enterprise uint32
+	length     uint16
+}
+
+// flowTemplate describes the fields of the data records of a NetFlow v9 or IPFIX data set.
+type flowTemplate struct {
+	fields []flowTemplateField
+
+	// options templates describe the exporter, e.g. its sampling rate, instead of flows
+	options bool
+}
+
+// template ids are only unique for the observation domain of an exporter.
+type flowTemplateKey struct {
+	exporter string
+	version  uint16
+	domain   uint32
+	id       uint16
+}
+
+// atomicFlowTemplateMap contains the templates of all exporters and provides synchronized access.
+type atomicFlowTemplateMap struct {
+	sync.Mutex
+	Items map[flowTemplateKey]*flowTemplate
+}
+
+var flowTemplates = &atomicFlowTemplateMap{
+	Items: make(map[flowTemplateKey]*flowTemplate),
+}
+
+func (d *flowDatagram) templateKey(version, id uint16) flowTemplateKey {
+	return flowTemplateKey{
+		exporter: d.exporter,
+		version:  version,
+		domain:   d.domain,
+		id:       id,
+	}
+}
+
+// storeTemplate adds or replaces a template, a template without fields withdraws it.
+func (d *flowDatagram) storeTemplate(version, id uint16, t *flowTemplate) {
+	flowTemplates.Lock()
+	defer flowTemplates.Unlock()
+
+	if len(t.fields) == 0 {
+		delete(flowTemplates.Items, d.templateKey(version, id))
+
+		return
+	}
+
+	flowTemplates.Items[d.templateKey(version, id)] = t
+	d.templates++
+}
+
+func (d *flowDatagram) template(version, id uint16) *flowTemplate {
+	flowTemplates.Lock()
+	defer flowTemplates.Unlock()
+
+	return flowTemplates.Items[d.templateKey(version, id)]
+}
+
+// decodeIPFIX decodes the header of an IPFIX message, followed by template, options template and data sets.
+func (d *flowDatagram) decodeIPFIX(data []byte) error {
+	d.protocol = protoIPFIX
+
+	if len(data) < ipfixHeaderSize {
+		return errInvalidFlowExport
+	}
+
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < ipfixHeaderSize || length > len(data) {
+		return errInvalidFlowExport
+	}
+
+	d.domain = binary.BigEndian.Uint32(data[12:16])
+	d.hasDomain = true
+
+	return d.decodeSets(
+		data[ipfixHeaderSize:length],
+		ipfixVersion,
+		time.Unix(int64(binary.BigEndian.Uint32(data[4:8])), 0),
+		0,
+	)
+}
+
+// decodeSets decodes the sets of a NetFlow v9 or IPFIX message.
+// The uptime of the exporter is only sent by NetFlow v9 and used to convert the relative flow timestamps.
+func (d *flowDatagram) decodeSets(data []byte, version uint16, exported time.Time, uptime uint32) error {
+	for len(data) >= 4 {
+		var (
+			id     = binary.BigEndian.Uint16(data[0:2])
+			length = int(binary.BigEndian.Uint16(data[2:4]))
+			err    error
+		)
+
+		if length < 4 || length > len(data) {
+			return errInvalidFlowExport
+		}
+
+		body := data[4:length]
+		data = data[length:]
+
+		switch {
+		case version == netflowVersion9 && id == setNetFlowTemplate, version == ipfixVersion && id == setIPFIXTemplate:
+			err = d.decodeTemplates(body, version, false)
+		case version == ipfixVersion && id == setIPFIXOptionsTemplate:
+			err = d.decodeTemplates(body, version, true)
+		case version == netflowVersion9 && id == setNetFlowOptionsTemplate:
+			err = d.decodeNetFlowOptionsTemplates(body)
+		case id >= minDataSetID:
+			d.decodeDataSet(body, version, id, exported, uptime)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeTemplates decodes the templates of NetFlow v9 and IPFIX, and the options templates of IPFIX.
+func (d *flowDatagram) decodeTemplates(data []byte, version uint16, options bool) error {
+	for len(data) >= 4 {
+		var (
+			id    = binary.BigEndian.Uint16(data[0:2])
+			count = int(binary.BigEndian.Uint16(data[2:4]))
+			t     = &flowTemplate{options: options}
+		)
+
+		// padding at the end of the set
+		if id < minDataSetID {
+			return nil
+		}
+
+		data = data[4:]
+
+		// the scope fields are the first fields of the template, only their number is announced
+		if options && count > 0 {
+			if len(data) < 2 {
+				return errInvalidFlowExport
+			}
+
+			data = data[2:]
+		}
+
+		for i := 0; i < count; i++ {
+			if len(data) < 4 {
+				return errInvalidFlowExport
+			}
+
+			f := flowTemplateField{
+				id:     binary.BigEndian.Uint16(data[0:2]),
+				length: binary.BigEndian.Uint16(data[2:4]),
+			}
+			data = data[4:]
+
+			if version == ipfixVersion && f.id&enterpriseBit != 0 {
+				if len(data) < 4 {
+					return errInvalidFlowExport
+				}
+
+				f.id &^= enterpriseBit
+				f.enterprise = binary.BigEndian.Uint32(data[0:4])
+				data = data[4:]
+			}
+
+			t.fields = append(t.fields, f)
+		}
+
+		d.storeTemplate(version, id, t)
+	}
+
+	return nil
+}
+
+// decodeDataSet decodes the records of a data set with the template they refer to.
+// Options records update the sampling rate, all others are converted into flows.
+func (d *flowDatagram) decodeDataSet(data []byte, version, id uint16, exported time.Time, uptime uint32) {
+	t := d.template(version, id)
+	if t == nil {
+		d.unknownTemplates++
+
+		return
+	}
+
+	for len(data) > 0 {
+		r := &flowDataRecord{}
+
+		n, ok := t.decode(data, version, r.set)
+		if !ok || n == 0 {
+			// padding at the end of the set
+			return
+		}
+
+		data = data[n:]
+
+		if t.options {
+			if r.samplingRate != 0 {
+				d.samplingRate = r.samplingRate
+			}
+
+			continue
+		}
+
+		rate := d.samplingRate
+		if r.samplingRate != 0 {
+			rate = r.samplingRate
+		}
+
+		d.add(r.flow(exported, uptime, version), rate)
+	}
+}
+
+// decode calls fn with the information element and value of each field of a data record
+// and returns the length of the record.
+func (t *flowTemplate) decode(data []byte, version uint16, fn func(id uint16, value []byte)) (int, bool) {
+	offset := 0
+
+	for _, f := range t.fields {
+		length := int(f.length)
+
+		if version == ipfixVersion && f.length == variableLength {
+			if offset >= len(data) {
+				return 0, false
+			}
+
+			length = int(data[offset])
+			offset++
+
+			// lengths from 255 on are encoded in the following two bytes
+			if length == 255 {
+				if offset+2 > len(data) {
+					return 0, false
+				}
+
+				length = int(binary.BigEndian.Uint16(data[offset : offset+2]))
+				offset += 2
+			}
+		}
+
+		if offset+length > len(data) {
+			return 0, false
+		}
+
+		if f.enterprise == 0 && f.id != 0 {
+			fn(f.id, data[offset:offset+length])
+		}
+
+		offset += length
+	}
+
+	return offset, true
+}
+
+// flowDataRecord collects the values of a data record.
+type flowDataRecord struct {
+	flowRecord
+
+	// timestamps relative to the system uptime or the system init time
+	startUptime, endUptime uint64
+	hasStart, hasEnd       bool
+	initTime               uint64
+
+	// absolute timestamps in milliseconds
+	startMillis, endMillis uint64
+
+	samplingRate uint32
+}
+
+// set applies the value of an information element, unsigned values may be sent with reduced size.
+func (r *flowDataRecord) set(id uint16, value []byte) {
+	switch id {
+	case ieOctetDeltaCount, iePostOctetDeltaCount, ieOctetTotalCount:
+		if r.bytes == 0 {
+			r.bytes = flowUint(value)
+		}
+	case iePacketDeltaCount, iePostPacketDeltaCount, iePacketTotalCount:
+		if r.packets == 0 {
+			r.packets = flowUint(value)
+		}
+	case ieProtocolIdentifier:
+		r.protocol = layers.IPProtocol(flowUint(value))
+	case ieSourceTransportPort:
+		r.srcPort = uint16(flowUint(value))
+	case ieDestinationTransportPort:
+		r.dstPort = uint16(flowUint(value))
+	case ieSourceIPv4Address, ieSourceIPv6Address:
+		r.srcIP = flowIP(value)
+	case ieDestinationIPv4Address, ieDestinationIPv6Address:
+		r.dstIP = flowIP(value)
+	case ieSourceMacAddress, iePostSourceMacAddress:
+		if r.srcMAC == nil {
+			r.srcMAC = flowMAC(value)
+		}
+	case ieDestinationMacAddress, iePostDestinationMacAddress:
+		if r.dstMAC == nil {
+			r.dstMAC = flowMAC(value)
+		}
+	case ieFlowStartSysUpTime:
+		r.startUptime, r.hasStart = flowUint(value), true
+	case ieFlowEndSysUpTime:
+		r.endUptime, r.hasEnd = flowUint(value), true
+	case ieSystemInitTimeMilliseconds:
+		r.initTime = flowUint(value)
+	case ieFlowStartSeconds:
+		r.startMillis = flowUint(value) * 1000
+	case ieFlowEndSeconds:
+		r.endMillis = flowUint(value) * 1000
+	case ieFlowStartMilliseconds:
+		r.startMillis = flowUint(value)
+	case ieFlowEndMilliseconds:
+		r.endMillis = flowUint(value)
+	case ieSamplingInterval, ieSamplerRandomInterval, ieSamplingPacketInterval:
+		r.samplingRate = uint32(flowUint(value))
+	}
+}
+
+// flow returns the flow with absolute timestamps, the export time is used if the record does not contain them.
+func (r *flowDataRecord) flow(exported time.Time, uptime uint32, version uint16) *flowRecord {
+	f := r.flowRecord
+	f.start, f.end = exported, exported
+
+	switch {
+	case r.startMillis != 0:
+		f.start = millisTime(r.startMillis)
+	case r.hasStart && version == netflowVersion9:
+		f.start = uptimeTime(exported, uptime, uint32(r.startUptime))
+	case r.hasStart && r.initTime != 0:
+		f.start = millisTime(r.initTime + r.startUptime)
+	}
+
+	switch {
+	case r.endMillis != 0:
+		f.end = millisTime(r.endMillis)
+	case r.hasEnd && version == netflowVersion9:
+		f.end = uptimeTime(exported, uptime, uint32(r.endUptime))
+	case r.hasEnd && r.initTime != 0:
+		f.end = millisTime(r.initTime + r.endUptime)
+	}
+
+	return &f
+}
+
+func millisTime(ms uint64) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond))
+}
+
+// flowUint decodes an unsigned integer of up to 8 bytes.
+func flowUint(value []byte) uint64 {
+	if len(value) > 8 {
+		return 0
+	}
+
+	var v uint64
+	for _, b := range value {
+		v = v<<8 | uint64(b)
+	}
+
+	return v
+}
+
+func flowIP(value []byte) net.IP {
+	if len(value) != net.IPv4len && len(value) != net.IPv6len {
+		return nil
+	}
+
+	return append(net.IP(nil), value...)
+}
+
+func flowMAC(value []byte) net.HardwareAddr {
+	if len(value) != 6 {
+		return nil
+	}
+
+	return append(net.HardwareAddr(nil), value...)
+}
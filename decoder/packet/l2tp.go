@@ -0,0 +1,376 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	l2tpPort    = 1701
+	l2tpVersion = 2
+
+	// header flags
+	l2tpFlagControl  = 0x8000
+	l2tpFlagLength   = 0x4000
+	l2tpFlagSequence = 0x0800
+	l2tpFlagOffset   = 0x0200
+
+	l2tpAVPHeaderSize = 6
+
+	// AVP flags
+	l2tpAVPHidden = 0x4000
+	l2tpAVPLength = 0x03ff
+
+	// AVP attribute types
+	l2tpAVPMessageType       = 0
+	l2tpAVPResultCode        = 1
+	l2tpAVPHostName          = 7
+	l2tpAVPVendorName        = 8
+	l2tpAVPAssignedTunnelID  = 9
+	l2tpAVPAssignedSessionID = 14
+	l2tpAVPCallSerialNumber  = 15
+	l2tpAVPCalledNumber      = 21
+	l2tpAVPCallingNumber     = 22
+	l2tpAVPTxConnectSpeed    = 24
+	l2tpAVPProxyAuthType     = 29
+	l2tpAVPProxyAuthName     = 30
+	l2tpAVPProxyAuthResponse = 33
+
+	// proxy authentication type carrying the cleartext PAP password as response
+	l2tpProxyAuthPAP = 3
+)
+
+// LayerTypeL2TP is the gopacket layer type for the Layer 2 Tunneling Protocol version 2.
+// It is registered for UDP port 1701, so that the PPP frames of data messages and the tunneled
+// IP packets get decoded by gopacket and passed to the regular decoders.
+var LayerTypeL2TP = gopacket.RegisterLayerType(layerNumL2TP, gopacket.LayerTypeMetadata{
+	Name:    "L2TP",
+	Decoder: gopacket.DecodeFunc(decodeL2TPLayer),
+})
+
+func init() {
+	layers.RegisterUDPPortLayerType(l2tpPort, LayerTypeL2TP)
+}
+
+// L2TP is the header of an L2TPv2 message.
+type L2TP struct {
+	layers.BaseLayer
+	Control   bool
+	Version   uint8
+	TunnelID  uint16
+	SessionID uint16
+	Ns        uint16
+	Nr        uint16
+}
+
+// LayerType returns LayerTypeL2TP.
+func (l *L2TP) LayerType() gopacket.LayerType {
+	return LayerTypeL2TP
+}
+
+// decodeL2TPLayer decodes the L2TPv2 header, other versions are treated as payload.
+func decodeL2TPLayer(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) < 6 || binary.BigEndian.Uint16(data[:2])&0x0f != l2tpVersion {
+		return gopacket.DecodePayload.Decode(data, p)
+	}
+
+	var (
+		flags  = binary.BigEndian.Uint16(data[:2])
+		offset = 2
+		end    = len(data)
+		l      = &L2TP{
+			Control: flags&l2tpFlagControl != 0,
+			Version: l2tpVersion,
+		}
+	)
+
+	if flags&l2tpFlagLength != 0 {
+		end = int(binary.BigEndian.Uint16(data[2:4]))
+		offset += 2
+	}
+
+	if offset+4 > len(data) || end > len(data) {
+		return errors.New("truncated L2TP header")
+	}
+
+	l.TunnelID = binary.BigEndian.Uint16(data[offset : offset+2])
+	l.SessionID = binary.BigEndian.Uint16(data[offset+2 : offset+4])
+	offset += 4
+
+	if flags&l2tpFlagSequence != 0 {
+		if offset+4 > len(data) {
+			return errors.New("truncated L2TP header")
+		}
+
+		l.Ns = binary.BigEndian.Uint16(data[offset : offset+2])
+		l.Nr = binary.BigEndian.Uint16(data[offset+2 : offset+4])
+		offset += 4
+	}
+
+	if flags&l2tpFlagOffset != 0 {
+		if offset+2 > len(data) {
+			return errors.New("truncated L2TP header")
+		}
+
+		offset += 2 + int(binary.BigEndian.Uint16(data[offset:offset+2]))
+	}
+
+	if offset > end {
+		return errors.New("invalid L2TP header length")
+	}
+
+	l.BaseLayer = layers.BaseLayer{Contents: data[:offset], Payload: data[offset:end]}
+	p.AddLayer(l)
+
+	if l.Control || len(l.Payload) == 0 {
+		return nil
+	}
+
+	return p.NextDecoder(layers.LayerTypePPP)
+}
+
+var l2tpMessageTypes = map[uint16]string{
+	1:  "SCCRQ",
+	2:  "SCCRP",
+	3:  "SCCCN",
+	4:  "StopCCN",
+	6:  "HELLO",
+	7:  "OCRQ",
+	8:  "OCRP",
+	9:  "OCCN",
+	10: "ICRQ",
+	11: "ICRP",
+	12: "ICCN",
+	14: "CDN",
+	15: "WEN",
+	16: "SLI",
+}
+
+var l2tpAVPs = map[uint16]string{
+	0:  "Message Type",
+	1:  "Result Code",
+	2:  "Protocol Version",
+	3:  "Framing Capabilities",
+	4:  "Bearer Capabilities",
+	5:  "Tie Breaker",
+	6:  "Firmware Revision",
+	7:  "Host Name",
+	8:  "Vendor Name",
+	9:  "Assigned Tunnel ID",
+	10: "Receive Window Size",
+	11: "Challenge",
+	12: "Q.931 Cause Code",
+	13: "Challenge Response",
+	14: "Assigned Session ID",
+	15: "Call Serial Number",
+	16: "Minimum BPS",
+	17: "Maximum BPS",
+	18: "Bearer Type",
+	19: "Framing Type",
+	21: "Called Number",
+	22: "Calling Number",
+	23: "Sub-Address",
+	24: "Tx Connect Speed",
+	25: "Physical Channel ID",
+	26: "Initial Received LCP CONFREQ",
+	27: "Last Sent LCP CONFREQ",
+	28: "Last Received LCP CONFREQ",
+	29: "Proxy Authen Type",
+	30: "Proxy Authen Name",
+	31: "Proxy Authen Challenge",
+	32: "Proxy Authen ID",
+	33: "Proxy Authen Response",
+	34: "Call Errors",
+	35: "ACCM",
+	36: "Random Vector",
+	37: "Private Group ID",
+	38: "Rx Connect Speed",
+	39: "Sequencing Required",
+}
+
+var l2tpProxyAuthTypes = map[uint16]string{
+	1: "Text",
+	2: "CHAP",
+	3: "PAP",
+	4: "None",
+	5: "MS-CHAPv1",
+}
+
+var l2tpDecoder = newGoPacketDecoder(
+	types.Type_NC_L2TP,
+	LayerTypeL2TP,
+	"The Layer 2 Tunneling Protocol tunnels PPP sessions over UDP between an access concentrator and a network server, audit records are created for control messages",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if l, ok := layer.(*L2TP); ok {
+			// data messages are handled by the PPP and the network layer decoders
+			if !l.Control {
+				return nil
+			}
+
+			r, password, err := decodeL2TPControl(l)
+			if err != nil {
+				decoderLog.Debug("failed to decode L2TP control message", zap.Error(err))
+
+				return nil
+			}
+
+			r.Timestamp = timestamp
+
+			if password != "" && credentials.Decoder.Writer != nil {
+				credentials.WriteCredentials(&types.Credentials{
+					Timestamp: timestamp,
+					Service:   servicePAP,
+					User:      r.ProxyAuthName,
+					Password:  password,
+					Notes:     "L2TP proxy authentication",
+				})
+			}
+
+			return r
+		}
+
+		return nil
+	},
+)
+
+// decodeL2TPControl parses the AVPs of a control message.
+// The cleartext password is returned if the LAC proxied a PAP authentication.
+func decodeL2TPControl(l *L2TP) (*types.L2TP, string, error) {
+	var (
+		data = l.Payload
+		r    = &types.L2TP{
+			Version:   int32(l.Version),
+			TunnelID:  int32(l.TunnelID),
+			SessionID: int32(l.SessionID),
+			Ns:        int32(l.Ns),
+			Nr:        int32(l.Nr),
+		}
+		proxyAuthResponse string
+	)
+
+	// zero length body messages acknowledge received control messages
+	if len(data) == 0 {
+		r.MessageType = "ZLB"
+
+		return r, "", nil
+	}
+
+	for len(data) > 0 {
+		if len(data) < l2tpAVPHeaderSize {
+			return nil, "", errors.New("truncated L2TP AVP")
+		}
+
+		var (
+			flags  = binary.BigEndian.Uint16(data[:2])
+			length = int(flags & l2tpAVPLength)
+			vendor = binary.BigEndian.Uint16(data[2:4])
+			typ    = binary.BigEndian.Uint16(data[4:6])
+		)
+
+		if length < l2tpAVPHeaderSize || length > len(data) {
+			return nil, "", errors.New("invalid L2TP AVP length")
+		}
+
+		value := data[l2tpAVPHeaderSize:length]
+		data = data[length:]
+
+		name, ok := l2tpAVPs[typ]
+		if vendor != 0 || !ok {
+			name = strconv.Itoa(int(vendor)) + ":" + strconv.Itoa(int(typ))
+		}
+
+		if flags&l2tpAVPHidden != 0 {
+			r.AVPs = append(r.AVPs, name+" (hidden)")
+
+			continue
+		}
+
+		r.AVPs = append(r.AVPs, name)
+
+		if vendor != 0 {
+			continue
+		}
+
+		switch typ {
+		case l2tpAVPMessageType:
+			if len(value) == 2 {
+				t := binary.BigEndian.Uint16(value)
+				if r.MessageType, ok = l2tpMessageTypes[t]; !ok {
+					r.MessageType = strconv.Itoa(int(t))
+				}
+			}
+		case l2tpAVPResultCode:
+			if len(value) >= 2 {
+				r.ResultCode = int32(binary.BigEndian.Uint16(value))
+			}
+
+			if len(value) >= 4 {
+				r.ErrorCode = int32(binary.BigEndian.Uint16(value[2:4]))
+				r.ErrorMessage = string(value[4:])
+			}
+		case l2tpAVPHostName:
+			r.HostName = string(value)
+		case l2tpAVPVendorName:
+			r.VendorName = string(value)
+		case l2tpAVPAssignedTunnelID:
+			if len(value) == 2 {
+				r.AssignedTunnelID = int32(binary.BigEndian.Uint16(value))
+			}
+		case l2tpAVPAssignedSessionID:
+			if len(value) == 2 {
+				r.AssignedSessionID = int32(binary.BigEndian.Uint16(value))
+			}
+		case l2tpAVPCallSerialNumber:
+			if len(value) == 4 {
+				r.CallSerialNumber = binary.BigEndian.Uint32(value)
+			}
+		case l2tpAVPCalledNumber:
+			r.CalledNumber = string(value)
+		case l2tpAVPCallingNumber:
+			r.CallingNumber = string(value)
+		case l2tpAVPTxConnectSpeed:
+			if len(value) == 4 {
+				r.TxConnectSpeed = binary.BigEndian.Uint32(value)
+			}
+		case l2tpAVPProxyAuthType:
+			if len(value) == 2 {
+				t := binary.BigEndian.Uint16(value)
+				if r.ProxyAuthType, ok = l2tpProxyAuthTypes[t]; !ok {
+					r.ProxyAuthType = strconv.Itoa(int(t))
+				}
+			}
+		case l2tpAVPProxyAuthName:
+			r.ProxyAuthName = string(value)
+		case l2tpAVPProxyAuthResponse:
+			proxyAuthResponse = string(value)
+		}
+	}
+
+	if r.ProxyAuthType == l2tpProxyAuthTypes[l2tpProxyAuthPAP] {
+		return r, proxyAuthResponse, nil
+	}
+
+	return r, "", nil
+}
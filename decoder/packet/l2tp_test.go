@@ -0,0 +1,85 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestL2TPDataMessage(t *testing.T) {
+	inner := []byte{
+		0x45, 0x00, 0x00, 0x1c, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11, 0x00, 0x00,
+		10, 0, 0, 1, 10, 0, 0, 2,
+		0x30, 0x39, 0x00, 0x35, 0x00, 0x08, 0x00, 0x00,
+	}
+	payload := append([]byte{
+		0x40, 0x02, 0x00, byte(12 + len(inner)), // length bit, version 2
+		0x00, 0x07, 0x00, 0x03, // tunnel 7, session 3
+		0xff, 0x03, 0x00, 0x21, // PPP IPv4
+	}, inner...)
+
+	pkt := testUDPPacket(t, "192.0.2.1", "192.0.2.2", 1701, 1701, payload)
+
+	l, ok := pkt.Layer(LayerTypeL2TP).(*L2TP)
+	if !ok || l.Control || l.TunnelID != 7 || l.SessionID != 3 {
+		t.Fatalf("unexpected L2TP layer: %+v", pkt.Layer(LayerTypeL2TP))
+	}
+
+	if l2tpDecoder.Handler(l, 1) != nil {
+		t.Fatal("expected no record for data message")
+	}
+
+	if pkt.Layer(layers.LayerTypePPP) == nil {
+		t.Fatal("expected PPP layer")
+	}
+
+	udp, ok := pkt.Layers()[len(pkt.Layers())-1].(*layers.UDP)
+	if !ok || udp.DstPort != 53 {
+		t.Fatalf("expected tunneled UDP layer, got %v", pkt.Layers())
+	}
+}
+
+func TestL2TPControlMessage(t *testing.T) {
+	payload := []byte{
+		0xc8, 0x02, 0x00, 0x00, // control, length, sequence, version 2
+		0x00, 0x00, 0x00, 0x00, // tunnel 0, session 0
+		0x00, 0x00, 0x00, 0x00, // ns, nr
+		0x80, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // SCCRQ
+		0x80, 0x0b, 0x00, 0x00, 0x00, 0x07, 'l', 'a', 'c', '0', '1', // host name
+		0x80, 0x08, 0x00, 0x00, 0x00, 0x09, 0x00, 0x2a, // assigned tunnel id
+		0xc0, 0x0a, 0x00, 0x00, 0x00, 0x0b, 0x01, 0x02, 0x03, 0x04, // hidden challenge
+	}
+	payload[3] = byte(len(payload))
+
+	pkt := testUDPPacket(t, "192.0.2.1", "192.0.2.2", 1701, 1701, payload)
+
+	l := pkt.Layer(LayerTypeL2TP)
+	if l == nil {
+		t.Fatal("expected L2TP layer")
+	}
+
+	r := l2tpDecoder.Handler(l, 1)
+	if r == nil {
+		t.Fatal("expected L2TP record")
+	}
+
+	c := r.(*types.L2TP)
+	if c.MessageType != "SCCRQ" || c.HostName != "lac01" || c.AssignedTunnelID != 42 || len(c.AVPs) != 4 || c.AVPs[3] != "Challenge (hidden)" {
+		t.Fatalf("unexpected L2TP record: %+v", c)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// ethernet type of the slow protocols, which include LACP, the marker protocol and OAM
+	ethernetTypeSlowProtocols layers.EthernetType = 0x8809
+
+	slowProtocolLACP = 1
+
+	lacpSize          = 46
+	lacpActorOffset   = 4
+	lacpPartnerOffset = 24
+	lacpCollectorTLV  = 42
+)
+
+// LACP actor and partner state flags, from the least significant bit.
+var lacpStateFlags = []string{
+	"Activity",
+	"Timeout",
+	"Aggregation",
+	"Synchronization",
+	"Collecting",
+	"Distributing",
+	"Defaulted",
+	"Expired",
+}
+
+// LayerTypeLACP is the gopacket layer type for the Link Aggregation Control Protocol.
+var LayerTypeLACP = gopacket.RegisterLayerType(layerNumLACP, gopacket.LayerTypeMetadata{
+	Name:    "LACP",
+	Decoder: gopacket.DecodeFunc(decodeLACPLayer),
+})
+
+func init() {
+	layers.EthernetTypeMetadata[ethernetTypeSlowProtocols] = layers.EnumMetadata{
+		DecodeWith: LayerTypeLACP,
+		Name:       "SlowProtocols",
+		LayerType:  LayerTypeLACP,
+	}
+}
+
+// LACP is a Link Aggregation Control Protocol data unit.
+type LACP struct {
+	layers.BaseLayer
+}
+
+// LayerType returns LayerTypeLACP.
+func (l *LACP) LayerType() gopacket.LayerType {
+	return LayerTypeLACP
+}
+
+// decodeLACPLayer decodes LACP data units, the other slow protocols are treated as payload.
+func decodeLACPLayer(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) == 0 || data[0] != slowProtocolLACP {
+		return gopacket.DecodePayload.Decode(data, p)
+	}
+
+	p.AddLayer(&LACP{BaseLayer: layers.BaseLayer{Contents: data}})
+
+	return nil
+}
+
+var lacpDecoder = newGoPacketDecoder(
+	types.Type_NC_LACP,
+	LayerTypeLACP,
+	"The Link Aggregation Control Protocol bundles multiple physical links between two systems into a single logical link",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if l, ok := layer.(*LACP); ok {
+			r, err := decodeLACP(l.Contents)
+			if err != nil {
+				decoderLog.Debug("failed to decode LACP data unit", zap.Error(err))
+
+				return nil
+			}
+
+			r.Timestamp = timestamp
+
+			return r
+		}
+
+		return nil
+	},
+)
+
+// decodeLACP parses the actor, partner and collector information of an LACP data unit.
+func decodeLACP(data []byte) (*types.LACP, error) {
+	if len(data) < lacpSize {
+		return nil, errors.New("truncated LACP data unit")
+	}
+
+	var (
+		actor   = data[lacpActorOffset:]
+		partner = data[lacpPartnerOffset:]
+	)
+
+	return &types.LACP{
+		Version:               int32(data[1]),
+		ActorSystemPriority:   int32(binary.BigEndian.Uint16(actor[0:2])),
+		ActorSystem:           net.HardwareAddr(actor[2:8]).String(),
+		ActorKey:              int32(binary.BigEndian.Uint16(actor[8:10])),
+		ActorPortPriority:     int32(binary.BigEndian.Uint16(actor[10:12])),
+		ActorPort:             int32(binary.BigEndian.Uint16(actor[12:14])),
+		ActorState:            lacpState(actor[14]),
+		PartnerSystemPriority: int32(binary.BigEndian.Uint16(partner[0:2])),
+		PartnerSystem:         net.HardwareAddr(partner[2:8]).String(),
+		PartnerKey:            int32(binary.BigEndian.Uint16(partner[8:10])),
+		PartnerPortPriority:   int32(binary.BigEndian.Uint16(partner[10:12])),
+		PartnerPort:           int32(binary.BigEndian.Uint16(partner[12:14])),
+		PartnerState:          lacpState(partner[14]),
+		CollectorMaxDelay:     int32(binary.BigEndian.Uint16(data[lacpCollectorTLV+2 : lacpCollectorTLV+4])),
+	}, nil
+}
+
+func lacpState(state byte) []string {
+	var flags []string
+
+	for i, name := range lacpStateFlags {
+		if state&(1<<uint(i)) != 0 {
+			flags = append(flags, name)
+		}
+	}
+
+	return flags
+}
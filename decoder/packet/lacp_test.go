@@ -0,0 +1,40 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+)
+
+func TestLACP(t *testing.T) {
+	data := []byte{
+		0x01, 0x01,
+		0x01, 0x14, 0x80, 0x00, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x00, 0x0d, 0x80, 0x00, 0x00, 0x16, 0x3d, 0x00, 0x00, 0x00,
+		0x02, 0x14, 0x80, 0x00, 0x00, 0x66, 0x77, 0x88, 0x99, 0xaa, 0x00, 0x0e, 0x80, 0x00, 0x00, 0x01, 0x3d, 0x00, 0x00, 0x00,
+		0x03, 0x10, 0x00, 0x32,
+	}
+
+	l, err := decodeLACP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.ActorSystem != "00:11:22:33:44:55" || l.ActorKey != 13 || l.ActorPort != 22 || l.PartnerSystem != "00:66:77:88:99:aa" {
+		t.Fatalf("unexpected LACP data unit: %+v", l)
+	}
+
+	if len(l.ActorState) != 5 || l.ActorState[0] != "Activity" || l.ActorState[4] != "Distributing" || l.CollectorMaxDelay != 50 {
+		t.Fatalf("unexpected LACP state: %+v", l)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+const (
+	linuxSLL2HeaderSize = 20
+
+	// protocol type used by the cooked capture for frames with an 802.2 LLC header
+	linuxSLLProtocolLLC = 0x0004
+)
+
+// LinkTypeLinuxSLL2 is the link type of the Linux cooked capture v2 header,
+// used by tcpdump when capturing on the any interface.
+// The link type number is 276, gopacket stores link types as uint8 and truncates it to 20,
+// which is not assigned to any other link type.
+const LinkTypeLinuxSLL2 = layers.LinkType(276 & 0xff)
+
+// LayerTypeLinuxSLL2 is the gopacket layer type for the Linux cooked capture v2 header.
+var LayerTypeLinuxSLL2 = gopacket.RegisterLayerType(layerNumLinuxSLL2, gopacket.LayerTypeMetadata{
+	Name:    "LinuxSLL2",
+	Decoder: gopacket.DecodeFunc(decodeLinuxSLL2),
+})
+
+// LinuxSLL2 is the Linux cooked capture v2 header, which replaces the link layer header
+// of the captured frames and adds the index of the interface the packet was captured on.
+type LinuxSLL2 struct {
+	layers.BaseLayer
+	EthernetType   layers.EthernetType
+	InterfaceIndex uint32
+	ARPHardware    uint16
+	PacketType     layers.LinuxSLLPacketType
+	Addr           net.HardwareAddr
+}
+
+// LayerType returns LayerTypeLinuxSLL2.
+func (s *LinuxSLL2) LayerType() gopacket.LayerType {
+	return LayerTypeLinuxSLL2
+}
+
+// LinkFlow returns a flow from the link layer address of the sender.
+func (s *LinuxSLL2) LinkFlow() gopacket.Flow {
+	return gopacket.NewFlow(layers.EndpointMAC, s.Addr, nil)
+}
+
+// DecodeFromBytes decodes the header.
+func (s *LinuxSLL2) DecodeFromBytes(data []byte) error {
+	if len(data) < linuxSLL2HeaderSize {
+		return errors.New("Linux SLL2 header too short")
+	}
+
+	s.EthernetType = layers.EthernetType(binary.BigEndian.Uint16(data[0:2]))
+	s.InterfaceIndex = binary.BigEndian.Uint32(data[4:8])
+	s.ARPHardware = binary.BigEndian.Uint16(data[8:10])
+	s.PacketType = layers.LinuxSLLPacketType(data[10])
+
+	addrLen := int(data[11])
+	if addrLen > 8 {
+		addrLen = 8
+	}
+
+	s.Addr = net.HardwareAddr(data[12 : 12+addrLen])
+	s.BaseLayer = layers.BaseLayer{Contents: data[:linuxSLL2HeaderSize], Payload: data[linuxSLL2HeaderSize:]}
+
+	return nil
+}
+
+func decodeLinuxSLL2(data []byte, p gopacket.PacketBuilder) error {
+	s := &LinuxSLL2{}
+	if err := s.DecodeFromBytes(data); err != nil {
+		return err
+	}
+
+	p.AddLayer(s)
+	p.SetLinkLayer(s)
+
+	if s.EthernetType == linuxSLLProtocolLLC {
+		return p.NextDecoder(layers.LayerTypeLLC)
+	}
+
+	return p.NextDecoder(s.EthernetType)
+}
@@ -0,0 +1,62 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+func TestLinuxSLL2(t *testing.T) {
+	frame := []byte{
+		0x08, 0x00, // IPv4
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x03, // interface index
+		0x00, 0x01, // ethernet
+		0x04,                                           // outgoing
+		0x06,                                           // address length
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x00, 0x00, // address
+	}
+	frame = append(frame, testIPv4UDPHeader()...)
+
+	p := gopacket.NewPacket(frame, LayerTypeLinuxSLL2, gopacket.Default)
+
+	sll, ok := p.Layer(LayerTypeLinuxSLL2).(*LinuxSLL2)
+	if !ok {
+		t.Fatal("expected SLL2 layer", p)
+	}
+
+	if sll.InterfaceIndex != 3 || sll.PacketType != layers.LinuxSLLPacketType(4) || sll.Addr.String() != "00:11:22:33:44:55" {
+		t.Fatal("unexpected SLL2 header", sll)
+	}
+
+	if p.LinkLayer() == nil || p.LinkLayer().LinkFlow().Src().String() != "00:11:22:33:44:55" {
+		t.Fatal("expected link layer")
+	}
+
+	if p.Layer(layers.LayerTypeUDP) == nil {
+		t.Fatal("expected UDP layer", p)
+	}
+}
+
+// testIPv4UDPHeader returns an IPv4 packet with an empty UDP datagram.
+func testIPv4UDPHeader() []byte {
+	return []byte{
+		0x45, 0x00, 0x00, 0x1c, 0x00, 0x00, 0x40, 0x00, 0x40, 0x11, 0x00, 0x00,
+		0x0a, 0x00, 0x00, 0x01, 0x0a, 0x00, 0x00, 0x02,
+		0x30, 0x39, 0x00, 0x35, 0x00, 0x08, 0x00, 0x00,
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	llmnrPort = 5355
+
+	// poisoningThreshold is the number of distinct names a single host may answer for
+	// via LLMNR or NBNS before its responses are flagged as poisoning.
+	// Legitimate hosts only answer for their own name.
+	poisoningThreshold = 3
+
+	// nameResponderTimeout is the time after which the names a host answered for are forgotten.
+	nameResponderTimeout = time.Hour
+)
+
+// nameResponders tracks the distinct names each host answered for and the time of the last answer.
+type nameResponders struct {
+	sync.Mutex
+	items     map[string]map[string]time.Time
+	lastSweep time.Time
+}
+
+func newNameResponders() *nameResponders {
+	return &nameResponders{
+		items: make(map[string]map[string]time.Time),
+	}
+}
+
+// answered registers that the host answered for the name and returns the number of distinct names
+// it answered for within the nameResponderTimeout, along with a flag indicating whether the poisoning threshold has been reached.
+func (n *nameResponders) answered(ip, name string, ts time.Time) (int32, bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	// discard names that have not been answered recently, at most once per timeout,
+	// or when the packet time jumped backwards
+	if d := ts.Sub(n.lastSweep); d > nameResponderTimeout || d < -nameResponderTimeout {
+		for host, names := range n.items {
+			for k, last := range names {
+				if d := ts.Sub(last); d > nameResponderTimeout || d < -nameResponderTimeout {
+					delete(names, k)
+				}
+			}
+
+			if len(names) == 0 {
+				delete(n.items, host)
+			}
+		}
+
+		n.lastSweep = ts
+	}
+
+	names, ok := n.items[ip]
+	if !ok {
+		names = make(map[string]time.Time)
+		n.items[ip] = names
+	}
+
+	names[strings.ToLower(name)] = ts
+
+	var count int32
+
+	for _, last := range names {
+		if ts.Sub(last) <= nameResponderTimeout {
+			count++
+		}
+	}
+
+	return count, count >= poisoningThreshold
+}
+
+var llmnrResponders = newNameResponders()
+
+var llmnrDecoder = newPacketDecoder(
+	types.Type_NC_LLMNR,
+	"LLMNR",
+	"Link-Local Multicast Name Resolution is used by Windows hosts to resolve names in the local network when DNS fails, and is frequently abused to capture credentials by answering for every queried name",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udpLayer := p.Layer(layers.LayerTypeUDP)
+		if udpLayer == nil {
+			return nil
+		}
+
+		udp, ok := udpLayer.(*layers.UDP)
+		if !ok || (udp.SrcPort != llmnrPort && udp.DstPort != llmnrPort) {
+			return nil
+		}
+
+		dns := new(layers.DNS)
+		if err := dns.DecodeFromBytes(udp.Payload, gopacket.NilDecodeFeedback); err != nil {
+			decoderLog.Debug("failed to decode LLMNR packet", zap.Error(err))
+
+			return nil
+		}
+
+		l := decodeLLMNR(dns)
+		l.Timestamp = p.Metadata().Timestamp.UnixNano()
+		l.SrcPort = int32(udp.SrcPort)
+		l.DstPort = int32(udp.DstPort)
+
+		if nl := p.NetworkLayer(); nl != nil {
+			l.SrcIP = nl.NetworkFlow().Src().String()
+			l.DstIP = nl.NetworkFlow().Dst().String()
+		}
+
+		if l.Response && l.Name != "" && len(l.Answers) > 0 {
+			l.ResponderNames, l.Poisoning = llmnrResponders.answered(l.SrcIP, l.Name, p.Metadata().Timestamp)
+
+			if !l.Poisoning {
+				for _, rr := range dns.Answers {
+					if rr.Type == layers.DNSTypeA || rr.Type == layers.DNSTypeAAAA {
+						decoderutils.AddHostname(rr.IP.String(), string(rr.Name))
+					}
+				}
+			}
+		}
+
+		return l
+	},
+	nil,
+)
+
+// decodeLLMNR converts the LLMNR message, which uses the DNS message format
+// with the conflict flag in place of AA and the tentative flag in place of RD.
+func decodeLLMNR(dns *layers.DNS) *types.LLMNR {
+	l := &types.LLMNR{
+		ID:           int32(dns.ID),
+		Response:     dns.QR,
+		Conflict:     dns.AA,
+		Tentative:    dns.RD,
+		ResponseCode: int32(dns.ResponseCode),
+	}
+
+	if len(dns.Questions) > 0 {
+		l.Name = string(dns.Questions[0].Name)
+		l.Type = dns.Questions[0].Type.String()
+	}
+
+	for _, rr := range dns.Answers {
+		switch rr.Type {
+		case layers.DNSTypeA, layers.DNSTypeAAAA:
+			l.Answers = append(l.Answers, rr.IP.String())
+		case layers.DNSTypePTR:
+			l.Answers = append(l.Answers, string(rr.PTR))
+		default:
+			l.Answers = append(l.Answers, rr.Type.String())
+		}
+	}
+
+	return l
+}
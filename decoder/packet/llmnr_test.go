@@ -0,0 +1,88 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func testLLMNRResponse(t *testing.T, name string) []byte {
+	t.Helper()
+
+	return testDNSPayload(t, &layers.DNS{
+		ID: 0x1234,
+		QR: true,
+		Questions: []layers.DNSQuestion{
+			{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN},
+		},
+		Answers: []layers.DNSResourceRecord{
+			{
+				Name:  []byte(name),
+				Type:  layers.DNSTypeA,
+				Class: layers.DNSClassIN,
+				TTL:   30,
+				IP:    net.ParseIP("10.0.0.66").To4(),
+			},
+		},
+	})
+}
+
+func TestLLMNRPoisoning(t *testing.T) {
+	for i, name := range []string{"fileserver", "FILESERVER", "wpad", "intranet"} {
+		r := llmnrDecoder.Handler(testUDPPacket(t, "10.0.0.66", "10.0.0.5", 5355, 50000, testLLMNRResponse(t, name)))
+		if r == nil {
+			t.Fatal("expected LLMNR record")
+		}
+
+		l := r.(*types.LLMNR)
+		if !l.Response || l.Name != name || l.Type != "A" || len(l.Answers) != 1 || l.Answers[0] != "10.0.0.66" {
+			t.Fatalf("unexpected LLMNR record: %+v", l)
+		}
+
+		// names are case insensitive, the third distinct name reaches the threshold
+		if expected := i >= 3; l.Poisoning != expected {
+			t.Fatalf("unexpected poisoning flag for %s after %d names", name, l.ResponderNames)
+		}
+	}
+}
+
+func TestNameRespondersExpiry(t *testing.T) {
+	var (
+		n     = newNameResponders()
+		start = time.Unix(1600000000, 0)
+	)
+
+	n.answered("10.0.0.9", "a", start)
+	n.answered("10.0.0.9", "b", start)
+
+	// names answered before the timeout are forgotten
+	if count, poisoning := n.answered("10.0.0.9", "c", start.Add(2*nameResponderTimeout)); count != 1 || poisoning {
+		t.Fatalf("unexpected responder state: %d %v", count, poisoning)
+	}
+
+	n.answered("10.0.0.10", "d", start.Add(4*nameResponderTimeout))
+
+	n.Lock()
+	defer n.Unlock()
+
+	if _, ok := n.items["10.0.0.9"]; ok || len(n.items) != 1 {
+		t.Fatalf("expected idle responder to be discarded: %v", n.items)
+	}
+}
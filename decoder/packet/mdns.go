@@ -0,0 +1,177 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	mdnsPort = 5353
+
+	// meta query owner name used to enumerate the available service types
+	dnssdServiceTypes = "_services._dns-sd._udp.local"
+)
+
+// TXT record keys carrying the device model, as used for AirPlay, Google Cast, printers and Apple device info.
+var mdnsModelKeys = map[string]bool{
+	"model":   true,
+	"md":      true,
+	"am":      true,
+	"ty":      true,
+	"usb_mdl": true,
+}
+
+var mdnsDecoder = newPacketDecoder(
+	types.Type_NC_MDNS,
+	"MDNS",
+	"Multicast DNS and DNS service discovery are used to resolve hostnames and advertise services such as printers, media players and file shares in the local network without a DNS server",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udpLayer := p.Layer(layers.LayerTypeUDP)
+		if udpLayer == nil {
+			return nil
+		}
+
+		udp, ok := udpLayer.(*layers.UDP)
+		if !ok || (udp.SrcPort != mdnsPort && udp.DstPort != mdnsPort) {
+			return nil
+		}
+
+		dns := new(layers.DNS)
+		if err := dns.DecodeFromBytes(udp.Payload, gopacket.NilDecodeFeedback); err != nil {
+			decoderLog.Debug("failed to decode mDNS packet", zap.Error(err))
+
+			return nil
+		}
+
+		m := decodeMDNS(dns)
+		m.Timestamp = p.Metadata().Timestamp.UnixNano()
+		m.SrcPort = int32(udp.SrcPort)
+		m.DstPort = int32(udp.DstPort)
+
+		if nl := p.NetworkLayer(); nl != nil {
+			m.SrcIP = nl.NetworkFlow().Src().String()
+			m.DstIP = nl.NetworkFlow().Dst().String()
+		}
+
+		if m.Response {
+			addMDNSDeviceInfo(m, dns)
+		}
+
+		return m
+	},
+	nil,
+)
+
+// decodeMDNS collects the questions, hostnames, services and TXT records of a multicast DNS message.
+func decodeMDNS(dns *layers.DNS) *types.MDNS {
+	m := &types.MDNS{
+		ID:       int32(dns.ID),
+		Response: dns.QR,
+	}
+
+	for _, q := range dns.Questions {
+		m.Questions = append(m.Questions, string(q.Name))
+	}
+
+	for _, rr := range mdnsRecords(dns) {
+		name := string(rr.Name)
+
+		switch rr.Type {
+		case layers.DNSTypeA, layers.DNSTypeAAAA:
+			m.Hostnames = decoderutils.AppendUnique(m.Hostnames, name)
+			m.Addresses = decoderutils.AppendUnique(m.Addresses, name+" -> "+rr.IP.String())
+		case layers.DNSTypePTR:
+			target := string(rr.PTR)
+
+			switch {
+			case strings.HasSuffix(name, ".arpa"):
+				// reverse lookup
+				m.Hostnames = decoderutils.AppendUnique(m.Hostnames, target)
+			case name == dnssdServiceTypes:
+				m.ServiceTypes = decoderutils.AppendUnique(m.ServiceTypes, target)
+			default:
+				m.ServiceTypes = decoderutils.AppendUnique(m.ServiceTypes, name)
+				m.Services = decoderutils.AppendUnique(m.Services, target)
+			}
+		case layers.DNSTypeSRV:
+			target := string(rr.SRV.Name)
+
+			m.Services = decoderutils.AppendUnique(m.Services, name)
+			m.Hostnames = decoderutils.AppendUnique(m.Hostnames, target)
+			m.Targets = decoderutils.AppendUnique(m.Targets, name+" -> "+target+":"+strconv.Itoa(int(rr.SRV.Port)))
+		case layers.DNSTypeTXT:
+			for _, txt := range rr.TXTs {
+				if len(txt) == 0 {
+					continue
+				}
+
+				m.TXT = decoderutils.AppendUnique(m.TXT, string(txt))
+
+				if model := mdnsModel(string(txt)); model != "" {
+					m.Models = decoderutils.AppendUnique(m.Models, model)
+				}
+			}
+		}
+	}
+
+	return m
+}
+
+// mdnsRecords returns the answer, authority and additional records of the message.
+func mdnsRecords(dns *layers.DNS) []layers.DNSResourceRecord {
+	records := make([]layers.DNSResourceRecord, 0, len(dns.Answers)+len(dns.Authorities)+len(dns.Additionals))
+	records = append(records, dns.Answers...)
+	records = append(records, dns.Authorities...)
+
+	return append(records, dns.Additionals...)
+}
+
+// mdnsModel returns the device model if the TXT entry is a key value pair with a known model key.
+func mdnsModel(txt string) string {
+	i := strings.IndexByte(txt, '=')
+	if i <= 0 || !mdnsModelKeys[strings.ToLower(txt[:i])] {
+		return ""
+	}
+
+	return strings.TrimSpace(txt[i+1:])
+}
+
+// addMDNSDeviceInfo registers the resolved hostnames for their addresses,
+// and the advertised services and device models for the responding host.
+func addMDNSDeviceInfo(m *types.MDNS, dns *layers.DNS) {
+	for _, rr := range mdnsRecords(dns) {
+		if rr.Type == layers.DNSTypeA || rr.Type == layers.DNSTypeAAAA {
+			decoderutils.AddHostname(rr.IP.String(), string(rr.Name))
+		}
+	}
+
+	for _, s := range m.Services {
+		decoderutils.AddService(m.SrcIP, s)
+	}
+
+	for _, model := range m.Models {
+		decoderutils.AddModel(m.SrcIP, model)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func testDNSPayload(t *testing.T, dns *layers.DNS) []byte {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := dns.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestMDNSServiceAnnouncement(t *testing.T) {
+	payload := testDNSPayload(t, &layers.DNS{
+		QR: true,
+		AA: true,
+		Answers: []layers.DNSResourceRecord{
+			{
+				Name:  []byte("_ipp._tcp.local"),
+				Type:  layers.DNSTypePTR,
+				Class: layers.DNSClassIN,
+				PTR:   []byte("Office Printer._ipp._tcp.local"),
+			},
+			{
+				Name:  []byte("Office Printer._ipp._tcp.local"),
+				Type:  layers.DNSTypeSRV,
+				Class: layers.DNSClassIN,
+				SRV:   layers.DNSSRV{Port: 631, Name: []byte("printer.local")},
+			},
+			{
+				Name:  []byte("Office Printer._ipp._tcp.local"),
+				Type:  layers.DNSTypeTXT,
+				Class: layers.DNSClassIN,
+				TXTs:  [][]byte{[]byte("txtvers=1"), []byte("ty=HP LaserJet 400")},
+			},
+			{
+				Name:  []byte("printer.local"),
+				Type:  layers.DNSTypeA,
+				Class: layers.DNSClassIN,
+				IP:    net.ParseIP("192.168.1.30").To4(),
+			},
+		},
+	})
+
+	r := mdnsDecoder.Handler(testUDPPacket(t, "192.168.1.30", "224.0.0.251", 5353, 5353, payload))
+	if r == nil {
+		t.Fatal("expected mDNS record")
+	}
+
+	m := r.(*types.MDNS)
+	if !m.Response || len(m.ServiceTypes) != 1 || m.ServiceTypes[0] != "_ipp._tcp.local" {
+		t.Fatalf("unexpected service types: %+v", m)
+	}
+
+	if len(m.Services) != 1 || m.Services[0] != "Office Printer._ipp._tcp.local" {
+		t.Fatalf("unexpected services: %v", m.Services)
+	}
+
+	if len(m.Targets) != 1 || m.Targets[0] != "Office Printer._ipp._tcp.local -> printer.local:631" {
+		t.Fatalf("unexpected targets: %v", m.Targets)
+	}
+
+	if len(m.Models) != 1 || m.Models[0] != "HP LaserJet 400" || len(m.TXT) != 2 {
+		t.Fatalf("unexpected TXT records: %v %v", m.TXT, m.Models)
+	}
+
+	if len(m.Addresses) != 1 || m.Addresses[0] != "printer.local -> 192.168.1.30" {
+		t.Fatalf("unexpected addresses: %v", m.Addresses)
+	}
+
+	ip := &types.IPProfile{Addr: "192.168.1.30"}
+	applyIPProfileInfo(ip)
+
+	if len(ip.Hostnames) != 1 || ip.Hostnames[0] != "printer.local" ||
+		len(ip.Services) != 1 || len(ip.Models) != 1 {
+		t.Fatalf("unexpected IP profile info: %+v", ip)
+	}
+
+	dp := &types.DeviceProfile{MacAddr: "00:11:22:33:44:55", DeviceIPs: []string{"192.168.1.30"}}
+	decoderutils.AddHostname(dp.MacAddr, "printer")
+	applyDeviceInfo(dp)
+
+	if len(dp.Hostnames) != 2 || dp.Hostnames[0] != "printer" || dp.Models[0] != "HP LaserJet 400" {
+		t.Fatalf("unexpected device profile info: %+v", dp)
+	}
+}
@@ -0,0 +1,437 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	nbnsPort       = 137
+	nbnsHeaderSize = 12
+
+	// header flags
+	nbnsResponse      = 0x8000
+	nbnsAuthoritative = 0x0400
+	nbnsBroadcast     = 0x0010
+
+	// opcodes
+	nbnsOpQuery        = 0
+	nbnsOpRegistration = 5
+	nbnsOpRelease      = 6
+	nbnsOpRefresh      = 8
+	nbnsOpRefreshAlt   = 9
+	nbnsOpMultiHomed   = 15
+
+	// resource record types
+	nbnsTypeNB     = 0x20
+	nbnsTypeNBSTAT = 0x21
+
+	// length of a first level encoded NetBIOS name
+	nbnsEncodedNameLength = 32
+
+	// group name flag of NB and NBSTAT entries
+	nbnsGroupName = 0x8000
+
+	// name suffixes identifying a host
+	nbnsSuffixWorkstation = 0x00
+	nbnsSuffixServer      = 0x20
+)
+
+var errNBNSTruncated = errors.New("truncated NBNS message")
+
+var nbnsOpcodes = map[int]string{
+	nbnsOpQuery:        "Query",
+	nbnsOpRegistration: "Registration",
+	nbnsOpRelease:      "Release",
+	7:                  "WACK",
+	nbnsOpRefresh:      "Refresh",
+	nbnsOpRefreshAlt:   "Refresh",
+	nbnsOpMultiHomed:   "Multi-Homed Registration",
+}
+
+var nbnsTypes = map[uint16]string{
+	0x01:           "A",
+	0x02:           "NS",
+	0x0a:           "NULL",
+	nbnsTypeNB:     "NB",
+	nbnsTypeNBSTAT: "NBSTAT",
+}
+
+// nbnsName is a decoded NetBIOS name.
+type nbnsName struct {
+	name   string
+	suffix byte
+}
+
+func (n nbnsName) String() string {
+	return fmt.Sprintf("%s<%02X>", n.name, n.suffix)
+}
+
+// nbnsEntry is a name or address entry of an NB or NBSTAT resource record.
+type nbnsEntry struct {
+	name    nbnsName
+	address string
+	group   bool
+}
+
+// nbnsMessage is a decoded NetBIOS name service message.
+type nbnsMessage struct {
+	record  *types.NBNS
+	name    nbnsName
+	entries []nbnsEntry
+}
+
+// nbnsQueryTimeout is the time in which responses to a broadcast query are expected,
+// clients retry unanswered queries after 750ms and give up after three attempts.
+const nbnsQueryTimeout = 5 * time.Second
+
+// nbnsQueries tracks the time of recent broadcast name queries.
+// WINS servers answer unicast queries for many names, therefore only responses
+// to broadcast queries count towards the poisoning threshold.
+var nbnsQueries = struct {
+	sync.Mutex
+	items     map[string]time.Time
+	lastSweep time.Time
+}{
+	items: make(map[string]time.Time),
+}
+
+var nbnsResponders = newNameResponders()
+
+var nbnsDecoder = newPacketDecoder(
+	types.Type_NC_NBNS,
+	"NBNS",
+	"The NetBIOS Name Service resolves NetBIOS names of Windows hosts via broadcasts or a WINS server, and is frequently abused to capture credentials by answering for every queried name",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udpLayer := p.Layer(layers.LayerTypeUDP)
+		if udpLayer == nil {
+			return nil
+		}
+
+		udp, ok := udpLayer.(*layers.UDP)
+		if !ok || (udp.SrcPort != nbnsPort && udp.DstPort != nbnsPort) {
+			return nil
+		}
+
+		msg, err := decodeNBNS(udp.Payload)
+		if err != nil {
+			decoderLog.Debug("failed to decode NBNS packet", zap.Error(err))
+
+			return nil
+		}
+
+		n := msg.record
+		n.Timestamp = p.Metadata().Timestamp.UnixNano()
+		n.SrcPort = int32(udp.SrcPort)
+		n.DstPort = int32(udp.DstPort)
+
+		if nl := p.NetworkLayer(); nl != nil {
+			n.SrcIP = nl.NetworkFlow().Src().String()
+			n.DstIP = nl.NetworkFlow().Dst().String()
+		}
+
+		trackNBNSPoisoning(msg)
+		addNBNSDeviceInfo(msg)
+
+		return n
+	},
+	nil,
+)
+
+// trackNBNSPoisoning remembers broadcast queries and counts the names answered in response to them.
+func trackNBNSPoisoning(msg *nbnsMessage) {
+	n := msg.record
+	if n.Opcode != nbnsOpcodes[nbnsOpQuery] || n.Type != nbnsTypes[nbnsTypeNB] {
+		return
+	}
+
+	var (
+		key = strconv.Itoa(int(n.ID)) + "/" + n.Name
+		ts  = time.Unix(0, n.Timestamp)
+	)
+
+	nbnsQueries.Lock()
+	defer nbnsQueries.Unlock()
+
+	// discard queries that are no longer answered, at most once per timeout,
+	// or when the packet time jumped backwards
+	if d := ts.Sub(nbnsQueries.lastSweep); d > nbnsQueryTimeout || d < -nbnsQueryTimeout {
+		for k, queried := range nbnsQueries.items {
+			if d := ts.Sub(queried); d > nbnsQueryTimeout || d < -nbnsQueryTimeout {
+				delete(nbnsQueries.items, k)
+			}
+		}
+
+		nbnsQueries.lastSweep = ts
+	}
+
+	if !n.Response {
+		if n.Broadcast {
+			nbnsQueries.items[key] = ts
+		}
+
+		return
+	}
+
+	queried, ok := nbnsQueries.items[key]
+	if !ok || ts.Sub(queried) > nbnsQueryTimeout || n.ResponseCode != 0 || len(n.Addresses) == 0 {
+		return
+	}
+
+	n.ResponderNames, n.Poisoning = nbnsResponders.answered(n.SrcIP, msg.name.name, ts)
+}
+
+// addNBNSDeviceInfo registers the unique workstation and server names for the addresses
+// they were registered or resolved for, and the names from node status responses for the responder.
+func addNBNSDeviceInfo(msg *nbnsMessage) {
+	n := msg.record
+
+	if n.Poisoning {
+		return
+	}
+
+	switch {
+	case n.Type == nbnsTypes[nbnsTypeNBSTAT] && n.Response:
+		for _, e := range msg.entries {
+			if !e.group && e.name.suffix == nbnsSuffixWorkstation {
+				decoderutils.AddHostname(n.SrcIP, e.name.name)
+			}
+		}
+	case n.Type == nbnsTypes[nbnsTypeNB]:
+		var (
+			query = n.Opcode == nbnsOpcodes[nbnsOpQuery]
+			// registration and refresh requests announce the names of the sender
+			announcement = !n.Response && !query && n.Opcode != nbnsOpcodes[nbnsOpRelease]
+			answer       = n.Response && query && n.ResponseCode == 0
+		)
+
+		if !announcement && !answer {
+			return
+		}
+
+		if msg.name.suffix != nbnsSuffixWorkstation && msg.name.suffix != nbnsSuffixServer {
+			return
+		}
+
+		for _, e := range msg.entries {
+			if !e.group && e.address != "" {
+				decoderutils.AddHostname(e.address, msg.name.name)
+			}
+		}
+	}
+}
+
+// decodeNBNS parses the header, the question and the resource records of a NetBIOS name service message.
+func decodeNBNS(data []byte) (*nbnsMessage, error) {
+	if len(data) < nbnsHeaderSize {
+		return nil, errNBNSTruncated
+	}
+
+	var (
+		flags  = binary.BigEndian.Uint16(data[2:4])
+		opcode = int(flags>>11) & 0x0f
+		qd     = int(binary.BigEndian.Uint16(data[4:6]))
+		rrs    = int(binary.BigEndian.Uint16(data[6:8])) +
+			int(binary.BigEndian.Uint16(data[8:10])) +
+			int(binary.BigEndian.Uint16(data[10:12]))
+		offset = nbnsHeaderSize
+		msg    = &nbnsMessage{
+			record: &types.NBNS{
+				ID:            int32(binary.BigEndian.Uint16(data[0:2])),
+				Response:      flags&nbnsResponse != 0,
+				Opcode:        nbnsOpcodes[opcode],
+				Authoritative: flags&nbnsAuthoritative != 0,
+				Broadcast:     flags&nbnsBroadcast != 0,
+				ResponseCode:  int32(flags & 0x0f),
+			},
+		}
+	)
+
+	if msg.record.Opcode == "" {
+		msg.record.Opcode = strconv.Itoa(opcode)
+	}
+
+	for i := 0; i < qd; i++ {
+		name, next, err := decodeNBNSName(data, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		if next+4 > len(data) {
+			return nil, errNBNSTruncated
+		}
+
+		if i == 0 {
+			msg.setName(name, binary.BigEndian.Uint16(data[next:next+2]))
+		}
+
+		offset = next + 4
+	}
+
+	for i := 0; i < rrs; i++ {
+		name, next, err := decodeNBNSName(data, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		if next+10 > len(data) {
+			return nil, errNBNSTruncated
+		}
+
+		var (
+			typ    = binary.BigEndian.Uint16(data[next : next+2])
+			length = int(binary.BigEndian.Uint16(data[next+8 : next+10]))
+			rdata  = data[next+10:]
+		)
+
+		if length > len(rdata) {
+			return nil, errNBNSTruncated
+		}
+
+		if msg.record.Name == "" {
+			msg.setName(name, typ)
+		}
+
+		msg.decodeRData(typ, rdata[:length])
+
+		offset = next + 10 + length
+	}
+
+	return msg, nil
+}
+
+func (m *nbnsMessage) setName(name nbnsName, typ uint16) {
+	m.name = name
+	m.record.Name = name.String()
+	m.record.Type = nbnsTypes[typ]
+
+	if m.record.Type == "" {
+		m.record.Type = strconv.Itoa(int(typ))
+	}
+}
+
+// decodeRData collects the addresses of NB records, and the names and unit id of NBSTAT records.
+func (m *nbnsMessage) decodeRData(typ uint16, rdata []byte) {
+	switch typ {
+	case nbnsTypeNB:
+		for ; len(rdata) >= 6; rdata = rdata[6:] {
+			e := nbnsEntry{
+				address: net.IP(rdata[2:6]).String(),
+				group:   binary.BigEndian.Uint16(rdata[:2])&nbnsGroupName != 0,
+			}
+
+			m.entries = append(m.entries, e)
+			m.record.Addresses = append(m.record.Addresses, e.address)
+		}
+	case nbnsTypeNBSTAT:
+		if len(rdata) == 0 {
+			return
+		}
+
+		num := int(rdata[0])
+		rdata = rdata[1:]
+
+		for i := 0; i < num && len(rdata) >= 18; i++ {
+			e := nbnsEntry{
+				name: nbnsName{
+					name:   strings.TrimRight(string(rdata[:15]), " \x00"),
+					suffix: rdata[15],
+				},
+				group: binary.BigEndian.Uint16(rdata[16:18])&nbnsGroupName != 0,
+			}
+
+			m.entries = append(m.entries, e)
+			m.record.Names = append(m.record.Names, e.name.String())
+			rdata = rdata[18:]
+		}
+
+		if len(rdata) >= 6 {
+			m.record.MAC = net.HardwareAddr(rdata[:6]).String()
+		}
+	}
+}
+
+// decodeNBNSName decodes a first level encoded NetBIOS name at the offset,
+// skips the scope labels and returns the offset following the name.
+// Names in resource records may be compressed using a pointer to a previous name.
+func decodeNBNSName(data []byte, offset int) (nbnsName, int, error) {
+	if offset >= len(data) {
+		return nbnsName{}, 0, errNBNSTruncated
+	}
+
+	if data[offset]&0xc0 == 0xc0 {
+		if offset+2 > len(data) {
+			return nbnsName{}, 0, errNBNSTruncated
+		}
+
+		ptr := int(binary.BigEndian.Uint16(data[offset:offset+2]) & 0x3fff)
+		if ptr >= offset {
+			return nbnsName{}, 0, errors.New("invalid NBNS name pointer")
+		}
+
+		name, _, err := decodeNBNSName(data, ptr)
+
+		return name, offset + 2, err
+	}
+
+	if data[offset] != nbnsEncodedNameLength || offset+1+nbnsEncodedNameLength > len(data) {
+		return nbnsName{}, 0, errors.New("invalid NBNS name")
+	}
+
+	var (
+		encoded = data[offset+1 : offset+1+nbnsEncodedNameLength]
+		decoded = make([]byte, nbnsEncodedNameLength/2)
+	)
+
+	for i := range decoded {
+		hi, lo := encoded[2*i]-'A', encoded[2*i+1]-'A'
+		if hi > 0x0f || lo > 0x0f {
+			return nbnsName{}, 0, errors.New("invalid NBNS name encoding")
+		}
+
+		decoded[i] = hi<<4 | lo
+	}
+
+	// skip the scope labels
+	offset += 1 + nbnsEncodedNameLength
+	for offset < len(data) && data[offset] != 0 {
+		offset += 1 + int(data[offset])
+	}
+
+	if offset >= len(data) {
+		return nbnsName{}, 0, errNBNSTruncated
+	}
+
+	return nbnsName{
+		name:   strings.TrimRight(string(decoded[:15]), " \x00"),
+		suffix: decoded[15],
+	}, offset + 1, nil
+}
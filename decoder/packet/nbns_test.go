@@ -0,0 +1,145 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+	"time"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func testNBNSName(name string, suffix byte) []byte {
+	padded := make([]byte, 16)
+	copy(padded, name+"                ")
+	padded[15] = suffix
+
+	out := []byte{nbnsEncodedNameLength}
+	for _, c := range padded {
+		out = append(out, 'A'+c>>4, 'A'+c&0x0f)
+	}
+
+	return append(out, 0x00)
+}
+
+func testNBNSQuery(id byte, name string) []byte {
+	payload := []byte{0x00, id, 0x01, 0x10, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	payload = append(payload, testNBNSName(name, 0x20)...)
+
+	return append(payload, 0x00, 0x20, 0x00, 0x01)
+}
+
+func testNBNSResponse(id byte, name string) []byte {
+	payload := []byte{0x00, id, 0x85, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+	payload = append(payload, testNBNSName(name, 0x20)...)
+
+	return append(payload,
+		0x00, 0x20, 0x00, 0x01, // type NB, class IN
+		0x00, 0x04, 0x93, 0xe0, // ttl
+		0x00, 0x06, // rdlength
+		0x00, 0x00, 10, 0, 0, 66,
+	)
+}
+
+func TestNBNSPoisoning(t *testing.T) {
+	for i, name := range []string{"FILESRV", "PRINTSRV", "WPAD"} {
+		r := nbnsDecoder.Handler(testUDPPacket(t, "10.0.0.5", "10.0.0.255", 137, 137, testNBNSQuery(byte(i), name)))
+		if r == nil {
+			t.Fatal("expected NBNS query")
+		}
+
+		q := r.(*types.NBNS)
+		if q.Response || !q.Broadcast || q.Opcode != "Query" || q.Type != "NB" || q.Name != name+"<20>" {
+			t.Fatalf("unexpected NBNS query: %+v", q)
+		}
+
+		r = nbnsDecoder.Handler(testUDPPacket(t, "10.0.0.66", "10.0.0.5", 137, 137, testNBNSResponse(byte(i), name)))
+		if r == nil {
+			t.Fatal("expected NBNS response")
+		}
+
+		res := r.(*types.NBNS)
+		if !res.Response || !res.Authoritative || len(res.Addresses) != 1 || res.Addresses[0] != "10.0.0.66" {
+			t.Fatalf("unexpected NBNS response: %+v", res)
+		}
+
+		if res.ResponderNames != int32(i+1) || res.Poisoning != (i == 2) {
+			t.Fatalf("unexpected poisoning state for %s: %+v", name, res)
+		}
+	}
+
+	// unicast queries to a WINS server are not counted
+	r := nbnsDecoder.Handler(testUDPPacket(t, "10.0.0.66", "10.0.0.5", 137, 137, testNBNSResponse(9, "MAILSRV")))
+	if n := r.(*types.NBNS); n.ResponderNames != 0 || n.Poisoning {
+		t.Fatalf("unexpected poisoning state for unicast response: %+v", n)
+	}
+}
+
+func TestNBNSQueryExpiry(t *testing.T) {
+	var (
+		start    = time.Unix(1600000000, 0)
+		query    = testUDPPacket(t, "10.0.0.5", "10.0.0.255", 137, 137, testNBNSQuery(42, "BACKUPSRV"))
+		response = testUDPPacket(t, "10.0.0.77", "10.0.0.5", 137, 137, testNBNSResponse(42, "BACKUPSRV"))
+		later    = testUDPPacket(t, "10.0.0.5", "10.0.0.255", 137, 137, testNBNSQuery(43, "OTHERSRV"))
+	)
+
+	query.Metadata().Timestamp = start
+	response.Metadata().Timestamp = start.Add(2 * nbnsQueryTimeout)
+	later.Metadata().Timestamp = start.Add(2 * nbnsQueryTimeout)
+
+	nbnsDecoder.Handler(query)
+
+	// responses after the timeout are not counted
+	if n := nbnsDecoder.Handler(response).(*types.NBNS); n.ResponderNames != 0 {
+		t.Fatalf("unexpected poisoning state for late response: %+v", n)
+	}
+
+	nbnsDecoder.Handler(later)
+
+	nbnsQueries.Lock()
+	_, expired := nbnsQueries.items["42/BACKUPSRV<20>"]
+	nbnsQueries.Unlock()
+
+	if expired {
+		t.Fatal("expected unanswered query to be discarded")
+	}
+}
+
+func TestNBNSNodeStatus(t *testing.T) {
+	payload := []byte{0x00, 0x01, 0x84, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+	payload = append(payload, testNBNSName("*", 0x00)...)
+	payload = append(payload, 0x00, 0x21, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2b, 0x02)
+	payload = append(payload, []byte("WS01           \x00\x04\x00")...)
+	payload = append(payload, []byte("CORP           \x00\x84\x00")...)
+	payload = append(payload, 0x00, 0x0c, 0x29, 0x01, 0x02, 0x03)
+
+	r := nbnsDecoder.Handler(testUDPPacket(t, "10.0.0.20", "10.0.0.5", 137, 50000, payload))
+	if r == nil {
+		t.Fatal("expected NBNS record")
+	}
+
+	n := r.(*types.NBNS)
+	if n.Type != "NBSTAT" || n.Name != "*<00>" || n.MAC != "00:0c:29:01:02:03" {
+		t.Fatalf("unexpected node status: %+v", n)
+	}
+
+	if len(n.Names) != 2 || n.Names[0] != "WS01<00>" || n.Names[1] != "CORP<00>" {
+		t.Fatalf("unexpected names: %v", n.Names)
+	}
+
+	if hostnames := decoderutils.LookupDeviceInfo("10.0.0.20").Hostnames; len(hostnames) != 1 || hostnames[0] != "WS01" {
+		t.Fatalf("unexpected hostnames: %v", hostnames)
+	}
+}
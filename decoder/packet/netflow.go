@@ -0,0 +1,144 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+const (
+	netflowV5HeaderSize = 24
+	netflowV5RecordSize = 48
+	netflowV9HeaderSize = 20
+)
+
+// decodeNetFlowV5 decodes the fixed size flow records of a NetFlow v5 datagram.
+func (d *flowDatagram) decodeNetFlowV5(data []byte) error {
+	d.protocol = protoNetFlowV5
+
+	if len(data) < netflowV5HeaderSize {
+		return errInvalidFlowExport
+	}
+
+	var (
+		count    = int(binary.BigEndian.Uint16(data[2:4]))
+		uptime   = binary.BigEndian.Uint32(data[4:8])
+		exported = time.Unix(int64(binary.BigEndian.Uint32(data[8:12])), int64(binary.BigEndian.Uint32(data[12:16])))
+
+		// the upper two bits contain the sampling mode
+		rate = uint32(binary.BigEndian.Uint16(data[22:24]) & 0x3fff)
+	)
+
+	if rate != 0 {
+		d.samplingRate = rate
+	}
+
+	data = data[netflowV5HeaderSize:]
+
+	for i := 0; i < count; i++ {
+		if len(data) < netflowV5RecordSize {
+			return errInvalidFlowExport
+		}
+
+		r := data[:netflowV5RecordSize]
+		data = data[netflowV5RecordSize:]
+
+		d.add(&flowRecord{
+			srcIP:    append(net.IP(nil), r[0:4]...),
+			dstIP:    append(net.IP(nil), r[4:8]...),
+			packets:  uint64(binary.BigEndian.Uint32(r[16:20])),
+			bytes:    uint64(binary.BigEndian.Uint32(r[20:24])),
+			start:    uptimeTime(exported, uptime, binary.BigEndian.Uint32(r[24:28])),
+			end:      uptimeTime(exported, uptime, binary.BigEndian.Uint32(r[28:32])),
+			srcPort:  binary.BigEndian.Uint16(r[32:34]),
+			dstPort:  binary.BigEndian.Uint16(r[34:36]),
+			protocol: layers.IPProtocol(r[38]),
+		}, rate)
+	}
+
+	return nil
+}
+
+// decodeNetFlowV9 decodes the header of a NetFlow v9 datagram, followed by template, options template and data flow sets.
+func (d *flowDatagram) decodeNetFlowV9(data []byte) error {
+	d.protocol = protoNetFlowV9
+
+	if len(data) < netflowV9HeaderSize {
+		return errInvalidFlowExport
+	}
+
+	d.domain = binary.BigEndian.Uint32(data[16:20])
+	d.hasDomain = true
+
+	return d.decodeSets(
+		data[netflowV9HeaderSize:],
+		netflowVersion9,
+		time.Unix(int64(binary.BigEndian.Uint32(data[8:12])), 0),
+		binary.BigEndian.Uint32(data[4:8]),
+	)
+}
+
+// decodeNetFlowOptionsTemplates decodes the options templates of NetFlow v9,
+// which announce the length of the scope and option fields in bytes instead of their number.
+func (d *flowDatagram) decodeNetFlowOptionsTemplates(data []byte) error {
+	for len(data) >= 6 {
+		var (
+			id        = binary.BigEndian.Uint16(data[0:2])
+			scopeLen  = int(binary.BigEndian.Uint16(data[2:4]))
+			optionLen = int(binary.BigEndian.Uint16(data[4:6]))
+		)
+
+		// padding at the end of the flow set
+		if id < minDataSetID {
+			return nil
+		}
+
+		data = data[6:]
+
+		if scopeLen+optionLen > len(data) {
+			return errInvalidFlowExport
+		}
+
+		t := &flowTemplate{options: true}
+
+		for i := 0; i+4 <= scopeLen+optionLen; i += 4 {
+			f := flowTemplateField{
+				id:     binary.BigEndian.Uint16(data[i : i+2]),
+				length: binary.BigEndian.Uint16(data[i+2 : i+4]),
+			}
+
+			// scope field types are not information elements, their values are only skipped
+			if i < scopeLen {
+				f.id = 0
+			}
+
+			t.fields = append(t.fields, f)
+		}
+
+		data = data[scopeLen+optionLen:]
+
+		d.storeTemplate(netflowVersion9, id, t)
+	}
+
+	return nil
+}
+
+// uptimeTime converts a timestamp relative to the system uptime of the exporter into an absolute time.
+func uptimeTime(exported time.Time, uptime, value uint32) time.Time {
+	return exported.Add(-time.Duration(int64(uptime)-int64(value)) * time.Millisecond)
+}
@@ -0,0 +1,229 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// testFlowData encodes uint8, uint16, uint32, uint64 and byte slice values in network byte order.
+func testFlowData(values ...interface{}) []byte {
+	var b []byte
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case uint8:
+			b = append(b, v)
+		case uint16:
+			b = append(b, 0, 0)
+			binary.BigEndian.PutUint16(b[len(b)-2:], v)
+		case uint32:
+			b = append(b, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(b[len(b)-4:], v)
+		case uint64:
+			b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.BigEndian.PutUint64(b[len(b)-8:], v)
+		case []byte:
+			b = append(b, v...)
+		case net.IP:
+			if v4 := v.To4(); v4 != nil {
+				v = v4
+			}
+
+			b = append(b, v...)
+		}
+	}
+
+	return b
+}
+
+// testFlowSet returns a NetFlow v9 flow set or IPFIX set with the given id.
+func testFlowSet(id uint16, body ...interface{}) []byte {
+	data := testFlowData(body...)
+
+	return append(testFlowData(id, uint16(len(data)+4)), data...)
+}
+
+func TestNetFlowV5(t *testing.T) {
+	var (
+		exported = time.Unix(1600000000, 0)
+		data     = testFlowData(
+			// version, count, uptime, export time, sequence, engine, sampling mode 1 with interval 10
+			uint16(5), uint16(1), uint32(10000), uint32(exported.Unix()), uint32(0), uint32(1), uint16(0), uint16(0x400a),
+			// addresses, next hop, interfaces, packets, bytes, first and last switched
+			net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), uint32(0), uint16(1), uint16(2), uint32(5), uint32(500), uint32(4000), uint32(9000),
+			// ports, padding, flags, protocol, tos, autonomous systems, masks and padding
+			uint16(50000), uint16(443), uint8(0), uint8(0x18), uint8(6), uint8(0), uint32(0), uint16(0), uint16(0),
+		)
+		d = &flowDatagram{exporter: "192.0.2.5", ts: exported}
+	)
+
+	if err := d.decode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.flows) != 1 {
+		t.Fatalf("expected 1 flow, got %d", len(d.flows))
+	}
+
+	f := d.flows[0]
+	if f.srcIP.String() != "10.0.0.1" || f.dstIP.String() != "10.0.0.2" || f.srcPort != 50000 || f.dstPort != 443 || f.protocol != 6 {
+		t.Fatalf("unexpected flow %+v", f)
+	}
+
+	if f.packets != 50 || f.bytes != 5000 || d.samplingRate != 10 {
+		t.Fatalf("expected counters scaled by the sampling rate, got %d packets and %d bytes", f.packets, f.bytes)
+	}
+
+	if !f.start.Equal(exported.Add(-6*time.Second)) || !f.end.Equal(exported.Add(-time.Second)) {
+		t.Fatalf("unexpected timestamps %v and %v", f.start, f.end)
+	}
+
+	// truncated record
+	if err := (&flowDatagram{}).decode(data[:len(data)-1]); err == nil {
+		t.Fatal("expected an error for a truncated datagram")
+	}
+}
+
+func TestNetFlowV9(t *testing.T) {
+	var (
+		exported = time.Unix(1600000000, 0)
+		exporter = "192.0.2.9"
+		header   = testFlowData(uint16(9), uint16(4), uint32(60000), uint32(exported.Unix()), uint32(1), uint32(42))
+
+		template = testFlowSet(setNetFlowTemplate,
+			uint16(256), uint16(11),
+			uint16(ieSourceIPv4Address), uint16(4),
+			uint16(ieDestinationIPv4Address), uint16(4),
+			uint16(ieSourceTransportPort), uint16(2),
+			uint16(ieDestinationTransportPort), uint16(2),
+			uint16(ieProtocolIdentifier), uint16(1),
+			uint16(iePacketDeltaCount), uint16(4),
+			uint16(ieOctetDeltaCount), uint16(4),
+			uint16(ieFlowStartSysUpTime), uint16(4),
+			uint16(ieFlowEndSysUpTime), uint16(4),
+			uint16(ieSourceMacAddress), uint16(6),
+			uint16(ieDestinationMacAddress), uint16(6),
+		)
+
+		// system scope and the sampling interval
+		optionsTemplate = testFlowSet(setNetFlowOptionsTemplate,
+			uint16(257), uint16(4), uint16(4), uint16(1), uint16(4), uint16(ieSamplingInterval), uint16(4),
+		)
+		options = testFlowSet(257, uint32(0), uint32(100))
+
+		// the record is padded to 4 bytes
+		flows = testFlowSet(256,
+			net.ParseIP("10.1.0.1"), net.ParseIP("10.1.0.2"), uint16(53211), uint16(53), uint8(17),
+			uint32(2), uint32(150), uint32(58000), uint32(59000),
+			[]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, []byte{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb},
+			[]byte{0, 0, 0},
+		)
+		unknown = testFlowSet(300, uint32(0))
+
+		d = &flowDatagram{exporter: exporter, ts: exported}
+	)
+
+	data := append(append(append(append(append(header, template...), optionsTemplate...), options...), flows...), unknown...)
+
+	if err := d.decode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.templates != 2 || d.unknownTemplates != 1 || d.domain != 42 || d.samplingRate != 100 {
+		t.Fatalf("unexpected datagram summary %+v", d)
+	}
+
+	if len(d.flows) != 1 {
+		t.Fatalf("expected 1 flow, got %d", len(d.flows))
+	}
+
+	f := d.flows[0]
+	if f.srcIP.String() != "10.1.0.1" || f.dstPort != 53 || f.srcMAC.String() != "00:11:22:33:44:55" || f.dstMAC.String() != "66:77:88:99:aa:bb" {
+		t.Fatalf("unexpected flow %+v", f)
+	}
+
+	if f.packets != 200 || f.bytes != 15000 {
+		t.Fatalf("unexpected counters %d packets and %d bytes", f.packets, f.bytes)
+	}
+
+	if !f.start.Equal(exported.Add(-2*time.Second)) || !f.end.Equal(exported.Add(-time.Second)) {
+		t.Fatalf("unexpected timestamps %v and %v", f.start, f.end)
+	}
+
+	// the templates are kept for the next datagram of the exporter
+	next := &flowDatagram{exporter: exporter, ts: exported, samplingRate: 100}
+	if err := next.decode(append(append([]byte(nil), header...), flows...)); err != nil || len(next.flows) != 1 {
+		t.Fatalf("expected a flow from the stored template, got %d: %v", len(next.flows), err)
+	}
+
+	// the template is not known to another exporter
+	other := &flowDatagram{exporter: "192.0.2.10", ts: exported}
+	if err := other.decode(append(append([]byte(nil), header...), flows...)); err != nil || len(other.flows) != 0 || other.unknownTemplates != 1 {
+		t.Fatalf("expected an unknown template, got %+v: %v", other, err)
+	}
+}
+
+func TestIPFIX(t *testing.T) {
+	var (
+		exported = time.Unix(1600000000, 0)
+		start    = exported.Add(-30 * time.Second)
+
+		template = testFlowSet(setIPFIXTemplate,
+			uint16(256), uint16(9),
+			uint16(ieSourceIPv6Address), uint16(16),
+			uint16(ieDestinationIPv6Address), uint16(16),
+			uint16(ieProtocolIdentifier), uint16(1),
+			uint16(ieSourceTransportPort), uint16(2),
+			uint16(ieDestinationTransportPort), uint16(2),
+			uint16(ieFlowStartMilliseconds), uint16(8),
+			uint16(ieFlowEndMilliseconds), uint16(8),
+			// reduced size encoding
+			uint16(iePacketDeltaCount), uint16(2),
+			// enterprise specific with variable length
+			uint16(enterpriseBit|1), uint16(variableLength), uint32(29305),
+		)
+
+		flows = testFlowSet(256,
+			net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), uint8(6), uint16(40000), uint16(22),
+			uint64(start.UnixNano()/int64(time.Millisecond)), uint64(exported.UnixNano()/int64(time.Millisecond)),
+			uint16(12), uint8(3), []byte("abc"),
+		)
+	)
+
+	body := append(template, flows...)
+	data := append(testFlowData(uint16(10), uint16(len(body)+ipfixHeaderSize), uint32(exported.Unix()), uint32(7), uint32(3)), body...)
+
+	d := &flowDatagram{exporter: "192.0.2.11", ts: exported}
+	if err := d.decode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.flows) != 1 || d.domain != 3 || d.protocol != protoIPFIX {
+		t.Fatalf("unexpected datagram %+v", d)
+	}
+
+	f := d.flows[0]
+	if f.srcIP.String() != "2001:db8::1" || f.dstPort != 22 || f.packets != 12 || !f.start.Equal(start) || !f.end.Equal(exported) {
+		t.Fatalf("unexpected flow %+v", f)
+	}
+
+	// the message length exceeds the datagram
+	if err := (&flowDatagram{}).decode(data[:len(data)-4]); err == nil {
+		t.Fatal("expected an error for a truncated message")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+const (
+	ppiHeaderSize      = 8
+	ppiFieldHeaderSize = 4
+
+	// field type and size of the 802.11-Common field
+	ppiField80211Common     = 2
+	ppiField80211CommonSize = 20
+)
+
+// LinkTypePPI is the link type of the Per-Packet Information header,
+// used by some wireless capture tools instead of radiotap.
+const LinkTypePPI layers.LinkType = 192
+
+// LayerTypePPI is the gopacket layer type for the Per-Packet Information header.
+var LayerTypePPI = gopacket.RegisterLayerType(layerNumPPI, gopacket.LayerTypeMetadata{
+	Name:    "PPI",
+	Decoder: gopacket.DecodeFunc(decodePPILayer),
+})
+
+// PPI is a Per-Packet Information header, which precedes a frame of the contained link type.
+// The radio information is taken from the 802.11-Common field, if present.
+type PPI struct {
+	layers.BaseLayer
+	Version          uint8
+	Flags            uint8
+	LinkType         layers.LinkType
+	ChannelFrequency uint16
+	ChannelFlags     uint16
+	AntennaSignal    int8
+	AntennaNoise     int8
+}
+
+// LayerType returns LayerTypePPI.
+func (p *PPI) LayerType() gopacket.LayerType {
+	return LayerTypePPI
+}
+
+// DecodeFromBytes decodes the header and the 802.11-Common field.
+func (p *PPI) DecodeFromBytes(data []byte) error {
+	if len(data) < ppiHeaderSize {
+		return errors.New("PPI header too short")
+	}
+
+	p.Version = data[0]
+	p.Flags = data[1]
+
+	length := int(binary.LittleEndian.Uint16(data[2:4]))
+	if length < ppiHeaderSize || length > len(data) {
+		return errors.New("invalid PPI header length")
+	}
+
+	p.LinkType = layers.LinkType(binary.LittleEndian.Uint32(data[4:8]))
+
+	for fields := data[ppiHeaderSize:length]; len(fields) >= ppiFieldHeaderSize; {
+		typ := binary.LittleEndian.Uint16(fields[0:2])
+		size := int(binary.LittleEndian.Uint16(fields[2:4]))
+
+		if len(fields) < ppiFieldHeaderSize+size {
+			break
+		}
+
+		if typ == ppiField80211Common && size >= ppiField80211CommonSize {
+			f := fields[ppiFieldHeaderSize:]
+			p.ChannelFrequency = binary.LittleEndian.Uint16(f[12:14])
+			p.ChannelFlags = binary.LittleEndian.Uint16(f[14:16])
+			p.AntennaSignal = int8(f[18])
+			p.AntennaNoise = int8(f[19])
+		}
+
+		fields = fields[ppiFieldHeaderSize+size:]
+	}
+
+	p.BaseLayer = layers.BaseLayer{Contents: data[:length], Payload: data[length:]}
+
+	return nil
+}
+
+func decodePPILayer(data []byte, pb gopacket.PacketBuilder) error {
+	p := &PPI{}
+	if err := p.DecodeFromBytes(data); err != nil {
+		return err
+	}
+
+	pb.AddLayer(p)
+
+	return pb.NextDecoder(p.LinkType)
+}
@@ -0,0 +1,63 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+func TestPPI(t *testing.T) {
+	frame := []byte{
+		0x00, 0x00, 0x20, 0x00, // version, flags, length 32
+		0x69, 0x00, 0x00, 0x00, // 802.11
+		0x02, 0x00, 0x14, 0x00, // 802.11-Common, 20 bytes
+		0, 0, 0, 0, 0, 0, 0, 0, // TSF timer
+		0x00, 0x00, 0x02, 0x00,
+		0x85, 0x09, 0xa0, 0x00, // 2437 MHz
+		0x00, 0x00, 0xc4, 0xa1, // -60 dBm signal, -95 dBm noise
+	}
+
+	// beacon without information elements
+	frame = append(frame, 0x80, 0x00, 0x00, 0x00)
+	frame = append(frame, layers.EthernetBroadcast...)
+	frame = append(frame, testAP...)
+	frame = append(frame, testAP...)
+	frame = append(frame, 0x00, 0x00)
+	frame = append(frame, 0, 0, 0, 0, 0, 0, 0, 0, 0x64, 0x00, 0x01, 0x00)
+	frame = append(frame, 0x00, 0x00, 0x00, 0x00)
+
+	p := gopacket.NewPacket(frame, LayerTypePPI, gopacket.Default)
+	p.Metadata().Timestamp = time.Now()
+
+	ppi, ok := p.Layer(LayerTypePPI).(*PPI)
+	if !ok {
+		t.Fatal("expected PPI layer", p)
+	}
+
+	if ppi.LinkType != layers.LinkTypeIEEE802_11 || ppi.ChannelFrequency != 2437 || ppi.AntennaSignal != -60 || ppi.AntennaNoise != -95 {
+		t.Fatal("unexpected PPI header", ppi)
+	}
+
+	if dot11Channel(nil, p) != 6 {
+		t.Fatal("expected channel from PPI header")
+	}
+
+	if p.Layer(layers.LayerTypeDot11MgmtBeacon) == nil {
+		t.Fatal("expected beacon", p)
+	}
+}
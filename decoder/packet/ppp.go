@@ -0,0 +1,519 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// PPP protocol numbers of the control and authentication protocols.
+const (
+	pppTypeLCP    layers.PPPType = 0xc021
+	pppTypePAP    layers.PPPType = 0xc023
+	pppTypeCHAP   layers.PPPType = 0xc223
+	pppTypeEAP    layers.PPPType = 0xc227
+	pppTypeIPCP   layers.PPPType = 0x8021
+	pppTypeIPV6CP layers.PPPType = 0x8057
+	pppTypeCCP    layers.PPPType = 0x80fd
+)
+
+const (
+	pppHeaderSize       = 4
+	pppOptionHeaderSize = 2
+
+	// control protocol codes
+	pppConfigureRequest  = 1
+	pppConfigureReject   = 4
+	pppTerminateRequest  = 5
+	pppTerminateAck      = 6
+	pppEchoRequest       = 9
+	pppIdentification    = 12
+	pppMagicNumberLength = 4
+
+	// PAP codes
+	papAuthenticateRequest = 1
+
+	// CHAP codes
+	chapChallenge = 1
+	chapResponse  = 2
+
+	// LCP options
+	lcpOptionMRU          = 1
+	lcpOptionAuthProtocol = 3
+	lcpOptionMagicNumber  = 5
+
+	// IPCP options, the address options starting from IP-Address carry an IPv4 address
+	ipcpOptionIPAddress    = 3
+	ipcpOptionPrimaryDNS   = 129
+	ipcpOptionSecondaryDNS = 131
+
+	// IPV6CP options
+	ipv6cpOptionInterfaceID = 1
+
+	// CHAP value sizes
+	chapMD5ResponseLength       = 16
+	mschapv2ChallengeLength     = 16
+	mschapv2ResponseLength      = 49
+	mschapv2PeerChallengeLength = 16
+	mschapv2NTResponseOffset    = 24
+	mschapv2NTResponseLength    = 24
+
+	serviceCHAP = "CHAP"
+	servicePAP  = "PAP"
+)
+
+var pppProtocols = map[layers.PPPType]string{
+	pppTypeLCP:    "LCP",
+	pppTypePAP:    "PAP",
+	pppTypeCHAP:   "CHAP",
+	pppTypeIPCP:   "IPCP",
+	pppTypeIPV6CP: "IPV6CP",
+	pppTypeCCP:    "CCP",
+}
+
+var pppCodes = map[byte]string{
+	1:  "Configure-Request",
+	2:  "Configure-Ack",
+	3:  "Configure-Nak",
+	4:  "Configure-Reject",
+	5:  "Terminate-Request",
+	6:  "Terminate-Ack",
+	7:  "Code-Reject",
+	8:  "Protocol-Reject",
+	9:  "Echo-Request",
+	10: "Echo-Reply",
+	11: "Discard-Request",
+	12: "Identification",
+	13: "Time-Remaining",
+	14: "Reset-Request",
+	15: "Reset-Ack",
+}
+
+var papCodes = map[byte]string{
+	1: "Authenticate-Request",
+	2: "Authenticate-Ack",
+	3: "Authenticate-Nak",
+}
+
+var chapCodes = map[byte]string{
+	1: "Challenge",
+	2: "Response",
+	3: "Success",
+	4: "Failure",
+}
+
+var pppOptions = map[layers.PPPType]map[byte]string{
+	pppTypeLCP: {
+		1:  "MRU",
+		2:  "ACCM",
+		3:  "Auth-Protocol",
+		4:  "Quality-Protocol",
+		5:  "Magic-Number",
+		7:  "PFC",
+		8:  "ACFC",
+		13: "Callback",
+		17: "MRRU",
+		18: "Short-Sequence-Number",
+		19: "Endpoint-Discriminator",
+	},
+	pppTypeIPCP: {
+		2:   "IP-Compression-Protocol",
+		3:   "IP-Address",
+		129: "Primary-DNS",
+		130: "Primary-NBNS",
+		131: "Secondary-DNS",
+		132: "Secondary-NBNS",
+	},
+	pppTypeIPV6CP: {
+		1: "Interface-Identifier",
+		2: "IPv6-Compression-Protocol",
+	},
+	pppTypeCCP: {
+		17: "Stac-LZS",
+		18: "MPPC",
+		26: "Deflate",
+	},
+}
+
+var chapAlgorithms = map[byte]string{
+	5:    "MD5",
+	0x80: "MS-CHAP",
+	0x81: "MS-CHAPv2",
+}
+
+func init() {
+	// decode the control protocols as payload instead of failing,
+	// and hand EAP over PPP to the EAP decoder
+	for t, name := range pppProtocols {
+		layers.PPPTypeMetadata[t] = layers.EnumMetadata{
+			DecodeWith: gopacket.DecodePayload,
+			Name:       name,
+			LayerType:  gopacket.LayerTypePayload,
+		}
+	}
+
+	layers.PPPTypeMetadata[pppTypeEAP] = layers.EnumMetadata{
+		DecodeWith: layers.LayerTypeEAP,
+		Name:       "EAP",
+		LayerType:  layers.LayerTypeEAP,
+	}
+}
+
+// chapChallenges holds the CHAP challenges by identifier until the response has been seen.
+var chapChallenges = struct {
+	sync.Mutex
+	items map[byte][]byte
+}{
+	items: make(map[byte][]byte),
+}
+
+var pppDecoder = newGoPacketDecoder(
+	types.Type_NC_PPP,
+	layers.LayerTypePPP,
+	"The Point-to-Point Protocol negotiates links via LCP, network layer configuration via IPCP and IPV6CP and authenticates peers via PAP or CHAP, for dial-up, DSL and tunneled connections",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if ppp, ok := layer.(*layers.PPP); ok {
+			if _, ok = pppProtocols[ppp.PPPType]; !ok {
+				// network layer protocols are handled by their decoders
+				return nil
+			}
+
+			p, err := decodePPP(ppp.PPPType, ppp.Payload)
+			if err != nil {
+				decoderLog.Debug("failed to decode PPP packet", zap.Error(err))
+
+				return nil
+			}
+
+			p.Timestamp = timestamp
+
+			if credentials.Decoder.Writer != nil {
+				if c := pppCredentials(p, ppp.PPPType, ppp.Payload); c != nil {
+					credentials.WriteCredentials(c)
+				}
+			}
+
+			return p
+		}
+
+		return nil
+	},
+)
+
+// decodePPP parses the header and data of a PPP control or authentication protocol packet.
+func decodePPP(t layers.PPPType, data []byte) (*types.PPP, error) {
+	if len(data) < pppHeaderSize {
+		return nil, errors.New("PPP packet too short")
+	}
+
+	var (
+		code   = data[0]
+		length = int(binary.BigEndian.Uint16(data[2:4]))
+		p      = &types.PPP{
+			Protocol:   pppProtocols[t],
+			Identifier: int32(data[1]),
+		}
+	)
+
+	if length < pppHeaderSize || length > len(data) {
+		return nil, errors.New("invalid PPP length")
+	}
+
+	data = data[pppHeaderSize:length]
+
+	switch t {
+	case pppTypePAP:
+		p.Code = pppCodeName(papCodes, code)
+		decodePAP(p, code, data)
+	case pppTypeCHAP:
+		p.Code = pppCodeName(chapCodes, code)
+		decodeCHAP(p, code, data)
+	default:
+		p.Code = pppCodeName(pppCodes, code)
+
+		switch {
+		case code >= pppConfigureRequest && code <= pppConfigureReject:
+			decodePPPOptions(p, t, data)
+		case code == pppTerminateRequest || code == pppTerminateAck:
+			p.Message = string(data)
+		case t == pppTypeLCP && code >= pppEchoRequest && code <= pppIdentification:
+			if len(data) >= pppMagicNumberLength {
+				p.MagicNumber = binary.BigEndian.Uint32(data[:pppMagicNumberLength])
+
+				if code == pppIdentification {
+					p.Message = string(data[pppMagicNumberLength:])
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func pppCodeName(codes map[byte]string, code byte) string {
+	if name, ok := codes[code]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(code))
+}
+
+// decodePPPOptions parses the configuration options of LCP, IPCP, IPV6CP and CCP packets.
+func decodePPPOptions(p *types.PPP, t layers.PPPType, data []byte) {
+	for len(data) >= pppOptionHeaderSize {
+		var (
+			typ    = data[0]
+			length = int(data[1])
+		)
+
+		if length < pppOptionHeaderSize || length > len(data) {
+			return
+		}
+
+		value := data[pppOptionHeaderSize:length]
+		data = data[length:]
+
+		name, ok := pppOptions[t][typ]
+		if !ok {
+			name = strconv.Itoa(int(typ))
+		}
+
+		var v string
+
+		switch {
+		case t == pppTypeLCP && typ == lcpOptionMRU && len(value) == 2:
+			p.MRU = int32(binary.BigEndian.Uint16(value))
+			v = strconv.Itoa(int(p.MRU))
+		case t == pppTypeLCP && typ == lcpOptionAuthProtocol && len(value) >= 2:
+			p.AuthProtocol = pppAuthProtocol(value)
+			v = p.AuthProtocol
+		case t == pppTypeLCP && typ == lcpOptionMagicNumber && len(value) == pppMagicNumberLength:
+			p.MagicNumber = binary.BigEndian.Uint32(value)
+			v = fmt.Sprintf("0x%08x", p.MagicNumber)
+		case t == pppTypeIPCP && typ >= ipcpOptionIPAddress && len(value) == net.IPv4len:
+			v = net.IP(value).String()
+
+			switch typ {
+			case ipcpOptionIPAddress:
+				p.IPAddress = v
+			case ipcpOptionPrimaryDNS:
+				p.PrimaryDNS = v
+			case ipcpOptionSecondaryDNS:
+				p.SecondaryDNS = v
+			}
+		case t == pppTypeIPV6CP && typ == ipv6cpOptionInterfaceID && len(value) == 8:
+			p.InterfaceID = net.HardwareAddr(value).String()
+			v = p.InterfaceID
+		default:
+			v = hex.EncodeToString(value)
+		}
+
+		if v != "" {
+			name += "=" + v
+		}
+
+		p.Options = append(p.Options, name)
+	}
+}
+
+// pppAuthProtocol returns the name of the authentication protocol of an LCP option,
+// including the algorithm for CHAP.
+func pppAuthProtocol(value []byte) string {
+	t := layers.PPPType(binary.BigEndian.Uint16(value[:2]))
+
+	switch t {
+	case pppTypePAP:
+		return servicePAP
+	case pppTypeCHAP:
+		if len(value) > 2 {
+			if alg, ok := chapAlgorithms[value[2]]; ok {
+				return serviceCHAP + "/" + alg
+			}
+		}
+
+		return serviceCHAP
+	case pppTypeEAP:
+		return "EAP"
+	default:
+		return fmt.Sprintf("0x%04x", uint16(t))
+	}
+}
+
+// decodePAP extracts the peer id of authenticate requests and the message of the replies.
+func decodePAP(p *types.PPP, code byte, data []byte) {
+	if code != papAuthenticateRequest {
+		if len(data) > 0 && int(data[0]) < len(data) {
+			p.Message = string(data[1 : 1+int(data[0])])
+		}
+
+		return
+	}
+
+	if user, _, ok := papCredentials(data); ok {
+		p.User = user
+	}
+}
+
+// papCredentials returns the peer id and password of an authenticate request.
+func papCredentials(data []byte) (user, password string, ok bool) {
+	if len(data) < 1 || 1+int(data[0]) >= len(data) {
+		return "", "", false
+	}
+
+	var (
+		userLen = int(data[0])
+		pwLen   = int(data[1+userLen])
+	)
+
+	if 2+userLen+pwLen > len(data) {
+		return "", "", false
+	}
+
+	return string(data[1 : 1+userLen]), string(data[2+userLen : 2+userLen+pwLen]), true
+}
+
+// decodeCHAP extracts the value and name of challenges and responses, and the message of the results.
+func decodeCHAP(p *types.PPP, code byte, data []byte) {
+	if code != chapChallenge && code != chapResponse {
+		p.Message = string(data)
+
+		return
+	}
+
+	if len(data) < 1 || 1+int(data[0]) > len(data) {
+		return
+	}
+
+	var (
+		value = hex.EncodeToString(data[1 : 1+int(data[0])])
+		name  = string(data[1+int(data[0]):])
+	)
+
+	if code == chapChallenge {
+		p.Challenge = value
+	} else {
+		p.Response = value
+	}
+
+	p.User = name
+}
+
+// pppCredentials returns the credentials of PAP authenticate requests,
+// and the hash of CHAP responses paired with their challenges.
+// The challenges are kept until the response with the same identifier has been seen.
+func pppCredentials(p *types.PPP, t layers.PPPType, data []byte) *types.Credentials {
+	switch t {
+	case pppTypePAP:
+		if p.Code != papCodes[papAuthenticateRequest] {
+			return nil
+		}
+
+		user, password, ok := papCredentials(data[pppHeaderSize:])
+		if !ok {
+			return nil
+		}
+
+		return &types.Credentials{
+			Timestamp: p.Timestamp,
+			Service:   servicePAP,
+			User:      user,
+			Password:  password,
+		}
+	case pppTypeCHAP:
+		chapChallenges.Lock()
+		defer chapChallenges.Unlock()
+
+		id := byte(p.Identifier)
+
+		switch p.Code {
+		case chapCodes[chapChallenge]:
+			challenge, err := hex.DecodeString(p.Challenge)
+			if err == nil && len(challenge) > 0 {
+				chapChallenges.items[id] = challenge
+			}
+		case chapCodes[chapResponse]:
+			challenge, ok := chapChallenges.items[id]
+			if !ok {
+				return nil
+			}
+
+			delete(chapChallenges.items, id)
+
+			response, err := hex.DecodeString(p.Response)
+			if err != nil {
+				return nil
+			}
+
+			hash := chapHash(id, p.User, challenge, response)
+			if hash == "" {
+				return nil
+			}
+
+			return &types.Credentials{
+				Timestamp: p.Timestamp,
+				Service:   serviceCHAP,
+				User:      p.User,
+				Notes:     hash,
+			}
+		}
+	}
+
+	return nil
+}
+
+// chapHash returns the challenge and response in the formats used by hashcat:
+// mode 4800 (<response>:<challenge>:<identifier>) for CHAP-MD5,
+// and mode 5500 (<user>::::<nt response>:<challenge hash>) for MS-CHAPv2.
+func chapHash(id byte, user string, challenge, response []byte) string {
+	switch {
+	case len(challenge) == mschapv2ChallengeLength && len(response) == mschapv2ResponseLength:
+		var (
+			peerChallenge = response[:mschapv2PeerChallengeLength]
+			ntResponse    = response[mschapv2NTResponseOffset : mschapv2NTResponseOffset+mschapv2NTResponseLength]
+			username      = user
+		)
+
+		// the challenge hash uses the user name without the domain
+		if i := strings.LastIndexByte(username, '\\'); i >= 0 {
+			username = username[i+1:]
+		}
+
+		h := sha1.New()
+		h.Write(peerChallenge)
+		h.Write(challenge)
+		h.Write([]byte(username))
+
+		return user + "::::" + hex.EncodeToString(ntResponse) + ":" + hex.EncodeToString(h.Sum(nil)[:8])
+	case len(response) == chapMD5ResponseLength:
+		return hex.EncodeToString(response) + ":" + hex.EncodeToString(challenge) + ":" + fmt.Sprintf("%02x", id)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestPPPoEDiscovery(t *testing.T) {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb},
+		EthernetType: layers.EthernetTypePPPoEDiscovery,
+	}
+	pppoe := &layers.PPPoE{Version: 1, Type: 1, Code: layers.PPPoECodePADO}
+	tags := []byte{
+		0x01, 0x01, 0x00, 0x00, // empty Service-Name
+		0x01, 0x02, 0x00, 0x05, 'B', 'R', 'A', 'S', '1',
+		0x01, 0x03, 0x00, 0x02, 0xbe, 0xef,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, eth, pppoe, gopacket.Payload(tags)); err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	l := pkt.Layer(layers.LayerTypePPPoE)
+	if l == nil {
+		t.Fatal("expected PPPoE layer")
+	}
+
+	r := pppoeDecoder.Handler(l, 1)
+	if r == nil {
+		t.Fatal("expected PPPoE record")
+	}
+
+	p := r.(*types.PPPoE)
+	if p.Code != "PADO" || p.ACName != "BRAS1" || p.HostUniq != "beef" || len(p.Tags) != 3 || p.Tags[0] != "Service-Name" {
+		t.Fatalf("unexpected PPPoE record: %+v", p)
+	}
+}
+
+func TestPPPConfiguration(t *testing.T) {
+	lcp := []byte{
+		0x01, 0x01, 0x00, 0x13,
+		0x01, 0x04, 0x05, 0xd4, // MRU 1492
+		0x03, 0x05, 0xc2, 0x23, 0x05, // CHAP with MD5
+		0x05, 0x06, 0x12, 0x34, 0x56, 0x78, // magic number
+	}
+
+	p, err := decodePPP(pppTypeLCP, lcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Protocol != "LCP" || p.Code != "Configure-Request" || p.MRU != 1492 || p.AuthProtocol != "CHAP/MD5" || p.MagicNumber != 0x12345678 {
+		t.Fatalf("unexpected LCP record: %+v", p)
+	}
+
+	if len(p.Options) != 3 || p.Options[2] != "Magic-Number=0x12345678" {
+		t.Fatalf("unexpected LCP options: %v", p.Options)
+	}
+
+	ipcp := []byte{
+		0x03, 0x02, 0x00, 0x16,
+		0x03, 0x06, 100, 64, 1, 10,
+		0x81, 0x06, 8, 8, 8, 8,
+		0x83, 0x06, 8, 8, 4, 4,
+	}
+
+	p, err = decodePPP(pppTypeIPCP, ipcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Code != "Configure-Nak" || p.IPAddress != "100.64.1.10" || p.PrimaryDNS != "8.8.8.8" || p.SecondaryDNS != "8.8.4.4" {
+		t.Fatalf("unexpected IPCP record: %+v", p)
+	}
+}
+
+func TestPAPCredentials(t *testing.T) {
+	payload := []byte{0x01, 0x07, 0x00, 0x12, 0x05, 'a', 'l', 'i', 'c', 'e', 0x07, 's', '3', 'c', 'r', '3', 't', '!'}
+
+	p, err := decodePPP(pppTypePAP, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Code != "Authenticate-Request" || p.User != "alice" {
+		t.Fatalf("unexpected PAP record: %+v", p)
+	}
+
+	c := pppCredentials(p, pppTypePAP, payload)
+	if c == nil || c.Service != "PAP" || c.User != "alice" || c.Password != "s3cr3t!" {
+		t.Fatalf("unexpected PAP credentials: %+v", c)
+	}
+}
+
+func TestCHAPCredentials(t *testing.T) {
+	challenge := []byte{0x01, 0x2a, 0x00, 0x0d, 0x04, 0xde, 0xad, 0xbe, 0xef, 'b', 'r', 'a', 's'}
+	response := append([]byte{0x02, 0x2a, 0x00, 0x1a, 0x10}, make([]byte, 16)...)
+	response = append(response, 'b', 'o', 'b', 'b', 'y')
+
+	for _, data := range [][]byte{challenge, response} {
+		p, err := decodePPP(pppTypeCHAP, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := pppCredentials(p, pppTypeCHAP, data)
+		if p.Code == "Challenge" {
+			if c != nil || p.Challenge != "deadbeef" || p.User != "bras" {
+				t.Fatalf("unexpected CHAP challenge: %+v", p)
+			}
+
+			continue
+		}
+
+		if c == nil || c.User != "bobby" || c.Notes != "00000000000000000000000000000000:deadbeef:2a" {
+			t.Fatalf("unexpected CHAP credentials: %+v", c)
+		}
+	}
+}
+
+func TestMSCHAPv2Hash(t *testing.T) {
+	// test vectors from RFC 2759 section 9.2
+	var (
+		authChallenge, _ = hex.DecodeString("5b5d7c7d7b3f2f3e3c2c602132262628")
+		peerChallenge, _ = hex.DecodeString("21402324255e262a28295f2b3a337c7e")
+		ntResponse, _    = hex.DecodeString("82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df")
+		response         = make([]byte, 0, mschapv2ResponseLength)
+	)
+
+	response = append(response, peerChallenge...)
+	response = append(response, make([]byte, 8)...)
+	response = append(response, ntResponse...)
+	response = append(response, 0)
+
+	expected := "User::::82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df:d02e4386bce91226"
+	if h := chapHash(1, "User", authChallenge, response); h != expected {
+		t.Fatalf("unexpected MS-CHAPv2 hash: %s", h)
+	}
+}
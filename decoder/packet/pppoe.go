@@ -0,0 +1,153 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// PPPoE discovery tags and their sizes
+const (
+	pppoeTagEndOfList        = 0x0000
+	pppoeTagServiceName      = 0x0101
+	pppoeTagACName           = 0x0102
+	pppoeTagHostUniq         = 0x0103
+	pppoeTagACCookie         = 0x0104
+	pppoeTagVendorSpecific   = 0x0105
+	pppoeTagRelaySessionID   = 0x0110
+	pppoeTagMaxPayload       = 0x0120
+	pppoeTagServiceNameError = 0x0201
+	pppoeTagACSystemError    = 0x0202
+	pppoeTagGenericError     = 0x0203
+
+	pppoeTagHeaderSize        = 4
+	pppoeMaxPayloadTagLength  = 2
+	pppoeVendorSpecificPrefix = 4
+)
+
+var pppoeCodes = map[layers.PPPoECode]string{
+	layers.PPPoECodePADI: "PADI",
+	layers.PPPoECodePADO: "PADO",
+	layers.PPPoECodePADR: "PADR",
+	layers.PPPoECodePADS: "PADS",
+	layers.PPPoECodePADT: "PADT",
+}
+
+var pppoeTags = map[uint16]string{
+	pppoeTagEndOfList:        "End-Of-List",
+	pppoeTagServiceName:      "Service-Name",
+	pppoeTagACName:           "AC-Name",
+	pppoeTagHostUniq:         "Host-Uniq",
+	pppoeTagACCookie:         "AC-Cookie",
+	pppoeTagVendorSpecific:   "Vendor-Specific",
+	pppoeTagRelaySessionID:   "Relay-Session-Id",
+	pppoeTagMaxPayload:       "PPP-Max-Payload",
+	pppoeTagServiceNameError: "Service-Name-Error",
+	pppoeTagACSystemError:    "AC-System-Error",
+	pppoeTagGenericError:     "Generic-Error",
+}
+
+var pppoeDecoder = newGoPacketDecoder(
+	types.Type_NC_PPPoE,
+	layers.LayerTypePPPoE,
+	"PPP over Ethernet is used by DSL and fiber access providers to establish PPP sessions between the subscriber and the access concentrator, audit records are created for the discovery stage and session termination",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if pppoe, ok := layer.(*layers.PPPoE); ok {
+			// session traffic is handled by the PPP and the network layer decoders
+			if pppoe.Code == layers.PPPoECodeSession {
+				return nil
+			}
+
+			p := decodePPPoETags(pppoe.Payload)
+			p.Timestamp = timestamp
+			p.Version = int32(pppoe.Version)
+			p.Type = int32(pppoe.Type)
+			p.Code = pppoeCodes[pppoe.Code]
+			p.SessionID = int32(pppoe.SessionId)
+
+			if p.Code == "" {
+				p.Code = strconv.Itoa(int(pppoe.Code))
+			}
+
+			return p
+		}
+
+		return nil
+	},
+)
+
+// decodePPPoETags parses the tags of a PPPoE discovery message.
+func decodePPPoETags(data []byte) *types.PPPoE {
+	p := &types.PPPoE{}
+
+	for len(data) >= pppoeTagHeaderSize {
+		var (
+			typ    = binary.BigEndian.Uint16(data[:2])
+			length = int(binary.BigEndian.Uint16(data[2:4]))
+		)
+
+		if pppoeTagHeaderSize+length > len(data) {
+			break
+		}
+
+		value := data[pppoeTagHeaderSize : pppoeTagHeaderSize+length]
+		data = data[pppoeTagHeaderSize+length:]
+
+		name, ok := pppoeTags[typ]
+		if !ok {
+			name = "0x" + strconv.FormatUint(uint64(typ), 16)
+		}
+
+		p.Tags = append(p.Tags, name)
+
+		switch typ {
+		case pppoeTagEndOfList:
+			return p
+		case pppoeTagServiceName:
+			p.ServiceName = string(value)
+		case pppoeTagACName:
+			p.ACName = string(value)
+		case pppoeTagHostUniq:
+			p.HostUniq = hex.EncodeToString(value)
+		case pppoeTagACCookie:
+			p.ACCookie = hex.EncodeToString(value)
+		case pppoeTagRelaySessionID:
+			p.RelaySessionID = hex.EncodeToString(value)
+		case pppoeTagMaxPayload:
+			if length == pppoeMaxPayloadTagLength {
+				p.MaxPayload = int32(binary.BigEndian.Uint16(value))
+			}
+		case pppoeTagVendorSpecific:
+			// the value starts with the vendor id in network byte order
+			if length >= pppoeVendorSpecificPrefix {
+				p.VendorSpecific = append(p.VendorSpecific, strconv.FormatUint(uint64(binary.BigEndian.Uint32(value)), 10)+":"+hex.EncodeToString(value[pppoeVendorSpecificPrefix:]))
+			}
+		case pppoeTagServiceNameError, pppoeTagACSystemError, pppoeTagGenericError:
+			p.Error = name
+			if length > 0 {
+				p.Error += ": " + string(value)
+			}
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,442 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// RADIUS accounting, and the ports used before the assignment of 1812 and 1813
+	radiusAccountingPort    = 1813
+	radiusOldPort           = 1645
+	radiusOldAccountingPort = 1646
+
+	// Acct-Status-Type values, see RFC 2866
+	radiusAcctStart   = 1
+	radiusAcctStop    = 2
+	radiusAcctInterim = 3
+)
+
+func init() {
+	// port 1812 is registered by gopacket
+	layers.RegisterUDPPortLayerType(radiusAccountingPort, layers.LayerTypeRADIUS)
+	layers.RegisterUDPPortLayerType(radiusOldPort, layers.LayerTypeRADIUS)
+	layers.RegisterUDPPortLayerType(radiusOldAccountingPort, layers.LayerTypeRADIUS)
+}
+
+// attributes whose values are addresses, integers or binary data, all others are text.
+var (
+	radiusAddressAttributes = map[layers.RADIUSAttributeType]bool{
+		layers.RADIUSAttributeTypeNASIPAddress:    true,
+		layers.RADIUSAttributeTypeFramedIPAddress: true,
+		layers.RADIUSAttributeTypeFramedIPNetmask: true,
+		layers.RADIUSAttributeTypeLoginIPHost:     true,
+	}
+	radiusIntegerAttributes = map[layers.RADIUSAttributeType]bool{
+		layers.RADIUSAttributeTypeNASPort:             true,
+		layers.RADIUSAttributeTypeFramedProtocol:      true,
+		layers.RADIUSAttributeTypeFramedRouting:       true,
+		layers.RADIUSAttributeTypeFramedMTU:           true,
+		layers.RADIUSAttributeTypeFramedCompression:   true,
+		layers.RADIUSAttributeTypeLoginService:        true,
+		layers.RADIUSAttributeTypeLoginTCPPort:        true,
+		layers.RADIUSAttributeTypeSessionTimeout:      true,
+		layers.RADIUSAttributeTypeIdleTimeout:         true,
+		layers.RADIUSAttributeTypeTerminationAction:   true,
+		layers.RADIUSAttributeTypeAcctDelayTime:       true,
+		layers.RADIUSAttributeTypeAcctInputOctets:     true,
+		layers.RADIUSAttributeTypeAcctOutputOctets:    true,
+		layers.RADIUSAttributeTypeAcctAuthentic:       true,
+		layers.RADIUSAttributeTypeAcctSessionTime:     true,
+		layers.RADIUSAttributeTypeAcctInputPackets:    true,
+		layers.RADIUSAttributeTypeAcctOutputPackets:   true,
+		layers.RADIUSAttributeTypeAcctLinkCount:       true,
+		layers.RADIUSAttributeTypeAcctInputGigawords:  true,
+		layers.RADIUSAttributeTypeAcctOutputGigawords: true,
+		layers.RADIUSAttributeTypeEventTimestamp:      true,
+		layers.RADIUSAttributeTypePortLimit:           true,
+		layers.RADIUSAttributeTypeAcctInterimInterval: true,
+	}
+	radiusBinaryAttributes = map[layers.RADIUSAttributeType]bool{
+		layers.RADIUSAttributeTypeUserPassword:         true,
+		layers.RADIUSAttributeTypeCHAPPassword:         true,
+		layers.RADIUSAttributeTypeState:                true,
+		layers.RADIUSAttributeTypeClass:                true,
+		layers.RADIUSAttributeTypeProxyState:           true,
+		layers.RADIUSAttributeTypeCHAPChallenge:        true,
+		layers.RADIUSAttributeTypeEAPMessage:           true,
+		layers.RADIUSAttributeTypeMessageAuthenticator: true,
+	}
+)
+
+var radiusServiceTypes = map[uint32]string{
+	1:  "Login",
+	2:  "Framed",
+	3:  "Callback-Login",
+	4:  "Callback-Framed",
+	5:  "Outbound",
+	6:  "Administrative",
+	7:  "NAS-Prompt",
+	8:  "Authenticate-Only",
+	9:  "Callback-NAS-Prompt",
+	10: "Call-Check",
+	11: "Callback-Administrative",
+}
+
+var radiusAcctStatusTypes = map[uint32]string{
+	radiusAcctStart:   "Start",
+	radiusAcctStop:    "Stop",
+	radiusAcctInterim: "Interim-Update",
+	7:                 "Accounting-On",
+	8:                 "Accounting-Off",
+}
+
+var radiusTerminateCauses = map[uint32]string{
+	1:  "User-Request",
+	2:  "Lost-Carrier",
+	3:  "Lost-Service",
+	4:  "Idle-Timeout",
+	5:  "Session-Timeout",
+	6:  "Admin-Reset",
+	7:  "Admin-Reboot",
+	8:  "Port-Error",
+	9:  "NAS-Error",
+	10: "NAS-Request",
+	11: "NAS-Reboot",
+	12: "Port-Unneeded",
+	13: "Port-Preempted",
+	14: "Port-Suspended",
+	15: "Service-Unavailable",
+	16: "Callback",
+	17: "User-Error",
+	18: "Host-Request",
+}
+
+var radiusNASPortTypes = map[uint32]string{
+	0:  "Async",
+	1:  "Sync",
+	2:  "ISDN-Sync",
+	5:  "Virtual",
+	15: "Ethernet",
+	19: "Wireless-802.11",
+}
+
+// vendors of common vendor specific attributes, by private enterprise number.
+var radiusVendors = map[uint32]string{
+	9:     "Cisco",
+	311:   "Microsoft",
+	2011:  "Huawei",
+	2636:  "Juniper",
+	3076:  "Cisco-VPN3000",
+	4874:  "Juniper-ERX",
+	10415: "3GPP",
+	12356: "Fortinet",
+	14179: "Airespace",
+	14823: "Aruba",
+	25461: "PaloAlto",
+}
+
+var radiusDecoder = newGoPacketDecoder(
+	types.Type_NC_RADIUS,
+	layers.LayerTypeRADIUS,
+	"The Remote Authentication Dial-In User Service authenticates users of network access servers and network devices, and collects accounting information about their sessions",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if r, ok := layer.(*layers.RADIUS); ok {
+			rec := decodeRADIUS(r)
+			rec.Timestamp = timestamp
+
+			return rec
+		}
+
+		return nil
+	},
+)
+
+// decodeRADIUS converts the attributes of a RADIUS packet into an audit record.
+func decodeRADIUS(r *layers.RADIUS) *types.RADIUS {
+	var (
+		rec = &types.RADIUS{
+			Code:       int32(r.Code),
+			CodeName:   r.Code.String(),
+			Identifier: int32(r.Identifier),
+			Length:     int32(r.Length),
+		}
+		inputGigawords, outputGigawords uint64
+	)
+
+	for _, a := range r.Attributes {
+		if a.Type == layers.RADIUSAttributeTypeVendorSpecific {
+			rec.VendorAttributes = append(rec.VendorAttributes, radiusVendorAttributes(a.Value)...)
+
+			continue
+		}
+
+		value := radiusAttributeValue(a)
+		rec.Attributes = append(rec.Attributes, a.Type.String()+"="+value)
+
+		switch a.Type {
+		case layers.RADIUSAttributeTypeUserName:
+			rec.UserName = value
+		case layers.RADIUSAttributeTypeNASIPAddress:
+			rec.NASIPAddress = value
+		case layers.RADIUSAttributeTypeNASIdentifier:
+			rec.NASIdentifier = value
+		case layers.RADIUSAttributeTypeCallingStationId:
+			rec.CallingStationID = value
+		case layers.RADIUSAttributeTypeCalledStationId:
+			rec.CalledStationID = value
+		case layers.RADIUSAttributeTypeFramedIPAddress:
+			rec.FramedIPAddress = value
+		case layers.RADIUSAttributeTypeReplyMessage:
+			rec.ReplyMessage = value
+		case layers.RADIUSAttributeTypeAcctStatusType:
+			rec.AcctStatusType = value
+		case layers.RADIUSAttributeTypeAcctSessionId:
+			rec.AcctSessionID = value
+		case layers.RADIUSAttributeTypeAcctSessionTime:
+			rec.AcctSessionTime = radiusInteger(a.Value)
+		case layers.RADIUSAttributeTypeAcctInputOctets:
+			rec.AcctInputOctets += uint64(radiusInteger(a.Value))
+		case layers.RADIUSAttributeTypeAcctOutputOctets:
+			rec.AcctOutputOctets += uint64(radiusInteger(a.Value))
+		case layers.RADIUSAttributeTypeAcctInputGigawords:
+			inputGigawords = uint64(radiusInteger(a.Value))
+		case layers.RADIUSAttributeTypeAcctOutputGigawords:
+			outputGigawords = uint64(radiusInteger(a.Value))
+		case layers.RADIUSAttributeTypeAcctTerminateCause:
+			rec.AcctTerminateCause = value
+		}
+	}
+
+	rec.AcctInputOctets += inputGigawords << 32
+	rec.AcctOutputOctets += outputGigawords << 32
+
+	return rec
+}
+
+func radiusInteger(v []byte) uint32 {
+	if len(v) != 4 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(v)
+}
+
+// radiusAttributeValue formats the value of an attribute according to its data type.
+func radiusAttributeValue(a layers.RADIUSAttribute) string {
+	switch {
+	case radiusAddressAttributes[a.Type] && len(a.Value) == net.IPv4len:
+		return net.IP(a.Value).String()
+	case a.Type == layers.RADIUSAttributeTypeServiceType:
+		return radiusEnum(radiusServiceTypes, a.Value)
+	case a.Type == layers.RADIUSAttributeTypeAcctStatusType:
+		return radiusEnum(radiusAcctStatusTypes, a.Value)
+	case a.Type == layers.RADIUSAttributeTypeAcctTerminateCause:
+		return radiusEnum(radiusTerminateCauses, a.Value)
+	case a.Type == layers.RADIUSAttributeTypeNASPortType:
+		return radiusEnum(radiusNASPortTypes, a.Value)
+	case radiusIntegerAttributes[a.Type] && len(a.Value) == 4:
+		return strconv.FormatUint(uint64(binary.BigEndian.Uint32(a.Value)), 10)
+	case radiusBinaryAttributes[a.Type]:
+		return hex.EncodeToString(a.Value)
+	}
+
+	return radiusText(a.Value)
+}
+
+func radiusEnum(names map[uint32]string, v []byte) string {
+	i := radiusInteger(v)
+	if name, ok := names[i]; ok {
+		return name
+	}
+
+	return strconv.FormatUint(uint64(i), 10)
+}
+
+// radiusText returns printable values as text and others hex encoded.
+func radiusText(v []byte) string {
+	if !utf8.Valid(v) {
+		return hex.EncodeToString(v)
+	}
+
+	for _, r := range string(v) {
+		if !unicode.IsPrint(r) {
+			return hex.EncodeToString(v)
+		}
+	}
+
+	return string(v)
+}
+
+// radiusVendorAttributes parses the sub attributes of a vendor specific attribute in the format recommended by RFC 2865,
+// values that do not follow this format are returned hex encoded.
+func radiusVendorAttributes(v []byte) []string {
+	if len(v) < 4 {
+		return nil
+	}
+
+	var (
+		id     = binary.BigEndian.Uint32(v[:4])
+		vendor = radiusVendors[id]
+		out    []string
+	)
+
+	if vendor == "" {
+		vendor = strconv.FormatUint(uint64(id), 10)
+	}
+
+	for data := v[4:]; len(data) > 0; {
+		if len(data) < 2 || data[1] < 2 || int(data[1]) > len(data) {
+			return append(out, vendor+":raw="+hex.EncodeToString(v[4:]))
+		}
+
+		out = append(out, vendor+":"+strconv.Itoa(int(data[0]))+"="+radiusText(data[2:data[1]]))
+		data = data[data[1]:]
+	}
+
+	return out
+}
+
+// radiusSessions tracks accounting sessions by the address of the NAS and the session id.
+var radiusSessions = struct {
+	sync.Mutex
+	items map[string]*types.RADIUSSession
+}{
+	items: make(map[string]*types.RADIUSSession),
+}
+
+// updateRADIUSSession updates the accounting session with an accounting request
+// and returns the session once it has been stopped.
+func updateRADIUSSession(r *types.RADIUS, clientIP, serverIP string, ts int64) *types.RADIUSSession {
+	if r.Code != int32(layers.RADIUSCodeAccountingRequest) || r.AcctSessionID == "" {
+		return nil
+	}
+
+	var (
+		key     = clientIP + "/" + r.AcctSessionID
+		stopped = r.AcctStatusType == radiusAcctStatusTypes[radiusAcctStop]
+	)
+
+	switch r.AcctStatusType {
+	case radiusAcctStatusTypes[radiusAcctStart], radiusAcctStatusTypes[radiusAcctInterim], radiusAcctStatusTypes[radiusAcctStop]:
+	default:
+		return nil
+	}
+
+	radiusSessions.Lock()
+	defer radiusSessions.Unlock()
+
+	s, ok := radiusSessions.items[key]
+	if !ok {
+		s = &types.RADIUSSession{
+			Timestamp: ts,
+			SessionID: r.AcctSessionID,
+			ClientIP:  clientIP,
+			ServerIP:  serverIP,
+		}
+		radiusSessions.items[key] = s
+	}
+
+	s.LastSeen = ts
+
+	if r.AcctStatusType == radiusAcctStatusTypes[radiusAcctInterim] {
+		s.InterimUpdates++
+	}
+
+	// requests after the start carry the same identity attributes, and the counters from the start of the session
+	updateString(&s.UserName, r.UserName)
+	updateString(&s.NASIPAddress, r.NASIPAddress)
+	updateString(&s.NASIdentifier, r.NASIdentifier)
+	updateString(&s.CallingStationID, r.CallingStationID)
+	updateString(&s.CalledStationID, r.CalledStationID)
+	updateString(&s.FramedIPAddress, r.FramedIPAddress)
+
+	if r.AcctSessionTime > s.SessionTime {
+		s.SessionTime = r.AcctSessionTime
+	}
+
+	if r.AcctInputOctets > s.InputOctets {
+		s.InputOctets = r.AcctInputOctets
+	}
+
+	if r.AcctOutputOctets > s.OutputOctets {
+		s.OutputOctets = r.AcctOutputOctets
+	}
+
+	if !stopped {
+		return nil
+	}
+
+	s.Stopped = true
+	s.TerminateCause = r.AcctTerminateCause
+
+	delete(radiusSessions.items, key)
+
+	return s
+}
+
+func updateString(s *string, v string) {
+	if v != "" {
+		*s = v
+	}
+}
+
+var radiusSessionDecoder = newPacketDecoder(
+	types.Type_NC_RADIUSSession,
+	"RADIUSSession",
+	"RADIUS accounting sessions from the Start to the Stop request, with the user, the NAS, the duration and the transferred bytes",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		l, ok := p.Layer(layers.LayerTypeRADIUS).(*layers.RADIUS)
+		if !ok || l.Code != layers.RADIUSCodeAccountingRequest {
+			return nil
+		}
+
+		var clientIP, serverIP string
+		if nl := p.NetworkLayer(); nl != nil {
+			clientIP = nl.NetworkFlow().Src().String()
+			serverIP = nl.NetworkFlow().Dst().String()
+		}
+
+		if s := updateRADIUSSession(decodeRADIUS(l), clientIP, serverIP, p.Metadata().Timestamp.UnixNano()); s != nil {
+			return s
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		radiusSessions.Lock()
+		defer radiusSessions.Unlock()
+
+		// sessions that are still active at the end of the capture
+		for _, s := range radiusSessions.items {
+			d.write(s)
+		}
+
+		return nil
+	},
+)
@@ -0,0 +1,144 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// testRADIUSAttribute returns an encoded attribute.
+func testRADIUSAttribute(t layers.RADIUSAttributeType, v []byte) []byte {
+	return append([]byte{byte(t), byte(len(v) + 2)}, v...)
+}
+
+func testRADIUSInteger(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+
+	return b
+}
+
+// testRADIUS returns a decoded RADIUS layer with the given code and attributes.
+func testRADIUS(t *testing.T, code layers.RADIUSCode, attrs ...[]byte) *layers.RADIUS {
+	t.Helper()
+
+	data := append([]byte{byte(code), 0x2a, 0, 0}, make([]byte, 16)...)
+	for _, a := range attrs {
+		data = append(data, a...)
+	}
+
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(data)))
+
+	r, ok := gopacket.NewPacket(data, layers.LayerTypeRADIUS, gopacket.Default).Layer(layers.LayerTypeRADIUS).(*layers.RADIUS)
+	if !ok {
+		t.Fatal("RADIUS layer missing")
+	}
+
+	return r
+}
+
+// testAccounting returns an accounting request for the session with the given status and counters.
+func testAccounting(t *testing.T, status, sessionTime, inputOctets uint32, extra ...[]byte) *layers.RADIUS {
+	t.Helper()
+
+	attrs := [][]byte{
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctStatusType, testRADIUSInteger(status)),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctSessionId, []byte("00000042")),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeUserName, []byte("alice")),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctSessionTime, testRADIUSInteger(sessionTime)),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctInputOctets, testRADIUSInteger(inputOctets)),
+	}
+
+	return testRADIUS(t, layers.RADIUSCodeAccountingRequest, append(attrs, extra...)...)
+}
+
+func TestDecodeRADIUS(t *testing.T) {
+	r := decodeRADIUS(testRADIUS(t, layers.RADIUSCodeAccessRequest,
+		testRADIUSAttribute(layers.RADIUSAttributeTypeUserName, []byte("admin")),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeUserPassword, []byte{0xde, 0xad, 0xbe, 0xef}),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeNASIPAddress, []byte{10, 0, 0, 1}),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeServiceType, testRADIUSInteger(6)),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeCallingStationId, []byte("00-11-22-33-44-55")),
+		// Cisco AV pair
+		testRADIUSAttribute(layers.RADIUSAttributeTypeVendorSpecific, append([]byte{0, 0, 0, 9}, testRADIUSAttribute(1, []byte("shell:priv-lvl=15"))...)),
+		// vendor with a different format
+		testRADIUSAttribute(layers.RADIUSAttributeTypeVendorSpecific, []byte{0, 0, 0x30, 0x39, 0x00, 0x01, 0x02}),
+	))
+
+	if r.CodeName != "Access-Request" || r.Identifier != 0x2a || r.UserName != "admin" || r.NASIPAddress != "10.0.0.1" || r.CallingStationID != "00-11-22-33-44-55" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+
+	expected := []string{
+		"User-Name=admin",
+		"User-Password=deadbeef",
+		"NAS-IP-Address=10.0.0.1",
+		"Service-Type=Administrative",
+		"Calling-Station-Id=00-11-22-33-44-55",
+	}
+	if !reflect.DeepEqual(r.Attributes, expected) {
+		t.Fatal("expected", expected, "got", r.Attributes)
+	}
+
+	expected = []string{"Cisco:1=shell:priv-lvl=15", "12345:raw=000102"}
+	if !reflect.DeepEqual(r.VendorAttributes, expected) {
+		t.Fatal("expected", expected, "got", r.VendorAttributes)
+	}
+
+	r = decodeRADIUS(testAccounting(t, radiusAcctStop, 3600, 10,
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctInputGigawords, testRADIUSInteger(1)),
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctTerminateCause, testRADIUSInteger(4)),
+	))
+
+	if r.AcctStatusType != "Stop" || r.AcctSessionID != "00000042" || r.AcctSessionTime != 3600 || r.AcctInputOctets != 1<<32+10 || r.AcctTerminateCause != "Idle-Timeout" {
+		t.Fatalf("unexpected accounting record: %+v", r)
+	}
+}
+
+func TestRADIUSSessions(t *testing.T) {
+	if s := updateRADIUSSession(decodeRADIUS(testAccounting(t, radiusAcctStart, 0, 0)), "10.0.0.1", "10.0.0.2", 1); s != nil {
+		t.Fatal("unexpected session after start")
+	}
+
+	if s := updateRADIUSSession(decodeRADIUS(testAccounting(t, radiusAcctInterim, 60, 1000)), "10.0.0.1", "10.0.0.2", 2); s != nil {
+		t.Fatal("unexpected session after interim update")
+	}
+
+	// same session id from another NAS
+	if s := updateRADIUSSession(decodeRADIUS(testAccounting(t, radiusAcctStop, 5, 5)), "10.0.0.3", "10.0.0.2", 3); s == nil || s.Timestamp != 3 {
+		t.Fatalf("unexpected session for other NAS: %+v", s)
+	}
+
+	s := updateRADIUSSession(decodeRADIUS(testAccounting(t, radiusAcctStop, 120, 2000,
+		testRADIUSAttribute(layers.RADIUSAttributeTypeAcctTerminateCause, testRADIUSInteger(1)),
+	)), "10.0.0.1", "10.0.0.2", 4)
+
+	if s == nil {
+		t.Fatal("expected session after stop")
+	}
+
+	if s.Timestamp != 1 || s.LastSeen != 4 || s.UserName != "alice" || s.SessionTime != 120 || s.InputOctets != 2000 ||
+		s.InterimUpdates != 1 || !s.Stopped || s.TerminateCause != "User-Request" || s.ClientIP != "10.0.0.1" {
+		t.Fatalf("unexpected session: %+v", s)
+	}
+
+	if len(radiusSessions.items) != 0 {
+		t.Fatal("expected no remaining sessions, got", len(radiusSessions.items))
+	}
+}
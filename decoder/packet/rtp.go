@@ -0,0 +1,317 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	errRTPInvalid  = errors.New("invalid RTP packet")
+	errRTCPInvalid = errors.New("invalid RTCP packet")
+)
+
+// RTCP packet types, see RFC 3550.
+const (
+	rtcpSenderReport   = 200
+	rtcpReceiverReport = 201
+	rtcpBye            = 203
+)
+
+// maxRTPGap is the maximum number of missing packets that will be replaced with silence.
+const maxRTPGap = 500
+
+// maxRTPAudioSize limits the audio buffered per stream, which is about 70 minutes of G.711.
+const maxRTPAudioSize = 32 << 20
+
+// rtpHeader contains the fields of a RTP header needed for stream tracking.
+type rtpHeader struct {
+	payloadType uint8
+	seq         uint16
+	timestamp   uint32
+	ssrc        uint32
+	payload     []byte
+}
+
+// parseRTP parses a RTP header and strips CSRCs, extensions and padding from the payload.
+func parseRTP(data []byte) (*rtpHeader, error) {
+	if len(data) < 12 || data[0]>>6 != 2 {
+		return nil, errRTPInvalid
+	}
+
+	var (
+		padding   = data[0]&0x20 != 0
+		extension = data[0]&0x10 != 0
+		csrcCount = int(data[0] & 0x0f)
+		offset    = 12 + 4*csrcCount
+	)
+
+	h := &rtpHeader{
+		payloadType: data[1] & 0x7f,
+		seq:         binary.BigEndian.Uint16(data[2:4]),
+		timestamp:   binary.BigEndian.Uint32(data[4:8]),
+		ssrc:        binary.BigEndian.Uint32(data[8:12]),
+	}
+
+	// payload types 72-76 collide with RTCP packet types
+	if h.payloadType >= 72 && h.payloadType <= 76 {
+		return nil, errRTPInvalid
+	}
+
+	if extension {
+		if len(data) < offset+4 {
+			return nil, errRTPInvalid
+		}
+
+		offset += 4 + 4*int(binary.BigEndian.Uint16(data[offset+2:offset+4]))
+	}
+
+	end := len(data)
+	if padding && end > 0 {
+		end -= int(data[end-1])
+	}
+
+	if offset > end {
+		return nil, errRTPInvalid
+	}
+
+	h.payload = data[offset:end]
+
+	return h, nil
+}
+
+// isRTCP checks if the data looks like a RTCP packet.
+func isRTCP(data []byte) bool {
+	return len(data) >= 8 && data[0]>>6 == 2 && data[1] >= 200 && data[1] <= 204
+}
+
+// rtcpReport is a reception report block from a sender or receiver report.
+type rtcpReport struct {
+	ssrc         uint32
+	fractionLost uint8
+	cumLost      int32
+	jitter       uint32
+}
+
+// parseRTCP extracts the reception report blocks from a compound RTCP packet.
+func parseRTCP(data []byte) ([]rtcpReport, error) {
+	var reports []rtcpReport
+
+	for len(data) >= 4 {
+		if data[0]>>6 != 2 {
+			return reports, errRTCPInvalid
+		}
+
+		var (
+			count  = int(data[0] & 0x1f)
+			typ    = data[1]
+			length = 4 * (int(binary.BigEndian.Uint16(data[2:4])) + 1)
+		)
+
+		if length > len(data) {
+			return reports, errRTCPInvalid
+		}
+
+		var offset int
+
+		switch typ {
+		case rtcpSenderReport:
+			// header, SSRC of sender and sender info
+			offset = 28
+		case rtcpReceiverReport:
+			// header and SSRC of packet sender
+			offset = 8
+		}
+
+		if offset > 0 {
+			for i := 0; i < count && offset+24 <= length; i++ {
+				block := data[offset : offset+24]
+
+				// cumulative number of packets lost is a signed 24 bit integer
+				lost := int32(binary.BigEndian.Uint32(block[4:8])<<8) >> 8
+
+				reports = append(reports, rtcpReport{
+					ssrc:         binary.BigEndian.Uint32(block[0:4]),
+					fractionLost: block[4],
+					cumLost:      lost,
+					jitter:       binary.BigEndian.Uint32(block[12:16]),
+				})
+
+				offset += 24
+			}
+		}
+
+		data = data[length:]
+	}
+
+	return reports, nil
+}
+
+// rtpStream tracks the statistics for a single synchronization source.
+type rtpStream struct {
+	*types.RTPStream
+
+	clockRate uint32
+
+	// extended sequence numbers
+	baseSeq uint32
+	maxSeq  uint16
+	cycles  uint32
+
+	// interarrival jitter state in timestamp units, see RFC 3550 A.8
+	transit int64
+	jitter  float64
+
+	// audio payloads by extended sequence number, only collected when files are extracted
+	audio map[uint32][]byte
+
+	// number of buffered audio bytes
+	audioSize int
+}
+
+func newRTPStream(h *rtpHeader, clockRate uint32) *rtpStream {
+	return &rtpStream{
+		RTPStream: &types.RTPStream{
+			SSRC:        h.ssrc,
+			PayloadType: int32(h.payloadType),
+		},
+		clockRate: clockRate,
+		baseSeq:   uint32(h.seq),
+		maxSeq:    h.seq,
+	}
+}
+
+// update processes a RTP packet of the stream, arriving at the passed time.
+func (s *rtpStream) update(h *rtpHeader, arrival time.Time, collectAudio bool) {
+	ts := arrival.UnixNano()
+
+	if s.Packets == 0 {
+		s.TimestampFirst = ts
+	}
+
+	s.TimestampLast = ts
+	s.Packets++
+	s.Bytes += int64(len(h.payload))
+
+	switch {
+	case h.seq < s.maxSeq && s.maxSeq-h.seq > 0x8000:
+		// sequence number roll over
+		s.cycles += 0x10000
+		s.maxSeq = h.seq
+	case h.seq > s.maxSeq && h.seq-s.maxSeq < 0x8000:
+		s.maxSeq = h.seq
+	}
+
+	// interarrival jitter
+	if s.clockRate > 0 {
+		var (
+			arrivalUnits = int64(float64(ts) / float64(time.Second) * float64(s.clockRate))
+			transit      = arrivalUnits - int64(h.timestamp)
+		)
+
+		if s.Packets > 1 {
+			d := math.Abs(float64(transit - s.transit))
+			s.jitter += (d - s.jitter) / 16
+			s.Jitter = s.jitter / float64(s.clockRate) * 1000
+		}
+
+		s.transit = transit
+	}
+
+	if collectAudio {
+		if s.audio == nil {
+			s.audio = make(map[uint32][]byte)
+		}
+
+		ext := s.extendedSeq(h.seq)
+		if _, ok := s.audio[ext]; !ok && s.audioSize+len(h.payload) <= maxRTPAudioSize {
+			buf := make([]byte, len(h.payload))
+			copy(buf, h.payload)
+			s.audio[ext] = buf
+			s.audioSize += len(buf)
+		}
+	}
+
+	s.Lost = s.expected() - s.Packets
+	if s.Lost < 0 {
+		s.Lost = 0
+	}
+}
+
+// extendedSeq maps a sequence number to the extended sequence number space.
+func (s *rtpStream) extendedSeq(seq uint16) uint32 {
+	ext := s.cycles + uint32(seq)
+
+	// late packet from the previous cycle
+	if seq > s.maxSeq && seq-s.maxSeq > 0x8000 && s.cycles > 0 {
+		ext -= 0x10000
+	}
+
+	return ext
+}
+
+// expected returns the number of packets expected for the stream.
+func (s *rtpStream) expected() int64 {
+	return int64(s.cycles+uint32(s.maxSeq)) - int64(s.baseSeq) + 1
+}
+
+// applyReport updates the stream with the values from a RTCP reception report.
+func (s *rtpStream) applyReport(r rtcpReport) {
+	s.ReportedLost = int64(r.cumLost)
+	s.ReportedFractionLost = int32(r.fractionLost)
+
+	if s.clockRate > 0 {
+		s.ReportedJitter = float64(r.jitter) / float64(s.clockRate) * 1000
+	}
+}
+
+// audioPayload returns the collected audio in sequence order.
+// Missing packets are filled with silence of the size of the previous packet.
+func (s *rtpStream) audioPayload(silence byte) []byte {
+	seqs := make([]uint32, 0, len(s.audio))
+	for seq := range s.audio {
+		seqs = append(seqs, seq)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+
+	var (
+		out  []byte
+		size int
+	)
+
+	for i, seq := range seqs {
+		// do not fill large gaps, e.g. caused by a restarted stream
+		if i > 0 && seq-seqs[i-1] <= maxRTPGap {
+			for missing := seqs[i-1] + 1; missing < seq && size > 0; missing++ {
+				for j := 0; j < size; j++ {
+					out = append(out, silence)
+				}
+			}
+		}
+
+		size = len(s.audio[seq])
+		out = append(out, s.audio[seq]...)
+	}
+
+	return out
+}
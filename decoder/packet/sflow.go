@@ -0,0 +1,273 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// sFlow v5 sample and flow record formats of the standard enterprise, see https://sflow.org/sflow_version_5.txt.
+const (
+	sflowFlowSample         = 1
+	sflowExpandedFlowSample = 3
+
+	sflowRawPacketHeader = 1
+	sflowEthernetFrame   = 2
+	sflowIPv4            = 3
+	sflowIPv6            = 4
+
+	// protocols of the raw packet header
+	sflowHeaderEthernet = 1
+	sflowHeaderIPv4     = 11
+	sflowHeaderIPv6     = 12
+
+	sflowAddressIPv4 = 1
+	sflowAddressIPv6 = 2
+)
+
+// decodeSFlow decodes the flow samples of a sFlow v5 datagram, counter samples are ignored.
+// Each sample is converted into a flow with the counters of the sampled packet scaled by the sampling rate.
+func (d *flowDatagram) decodeSFlow(data []byte) error {
+	d.protocol = protoSFlow
+
+	r := &flowReader{data: data}
+
+	// version
+	r.uint32()
+
+	switch r.uint32() {
+	case sflowAddressIPv4:
+		r.bytes(net.IPv4len)
+	case sflowAddressIPv6:
+		r.bytes(net.IPv6len)
+	default:
+		return errInvalidFlowExport
+	}
+
+	d.domain = r.uint32()
+	d.hasDomain = true
+
+	// sequence number and uptime
+	r.uint32()
+	r.uint32()
+
+	samples := int(r.uint32())
+
+	for i := 0; i < samples && !r.err; i++ {
+		var (
+			format = r.uint32()
+			body   = r.bytes(int(r.uint32()))
+		)
+
+		if r.err {
+			break
+		}
+
+		// samples of other enterprises have a non zero prefix
+		switch format {
+		case sflowFlowSample:
+			d.decodeSFlowSample(body, false)
+		case sflowExpandedFlowSample:
+			d.decodeSFlowSample(body, true)
+		}
+	}
+
+	if r.err {
+		return errInvalidFlowExport
+	}
+
+	return nil
+}
+
+// decodeSFlowSample decodes a flow sample and the flow records describing the sampled packet.
+func (d *flowDatagram) decodeSFlowSample(data []byte, expanded bool) {
+	r := &flowReader{data: data}
+
+	// sequence number and source id, the expanded format uses separate fields for the source id type and index
+	r.uint32()
+	r.uint32()
+
+	if expanded {
+		r.uint32()
+	}
+
+	rate := r.uint32()
+
+	// sample pool and drops
+	r.uint32()
+	r.uint32()
+
+	// input and output interface, the expanded format uses separate fields for the format and value
+	r.uint32()
+	r.uint32()
+
+	if expanded {
+		r.uint32()
+		r.uint32()
+	}
+
+	var (
+		records = int(r.uint32())
+		f       *flowRecord
+		eth     *flowRecord
+	)
+
+	for i := 0; i < records && !r.err; i++ {
+		var (
+			format = r.uint32()
+			body   = r.bytes(int(r.uint32()))
+		)
+
+		if r.err {
+			return
+		}
+
+		switch format {
+		case sflowRawPacketHeader:
+			if h := sflowPacketHeader(body); h != nil {
+				f = h
+			}
+		case sflowIPv4, sflowIPv6:
+			if f == nil {
+				f = sflowSampledIP(body, format == sflowIPv6)
+			}
+		case sflowEthernetFrame:
+			eth = sflowSampledEthernet(body)
+		}
+	}
+
+	if f == nil {
+		return
+	}
+
+	if f.srcMAC == nil && eth != nil {
+		f.srcMAC, f.dstMAC = eth.srcMAC, eth.dstMAC
+	}
+
+	f.start, f.end = d.ts, d.ts
+	f.packets = 1
+
+	d.samplingRate = rate
+	d.add(f, rate)
+}
+
+// sflowPacketHeader decodes the header of the sampled packet.
+func sflowPacketHeader(data []byte) *flowRecord {
+	var (
+		r      = &flowReader{data: data}
+		proto  = r.uint32()
+		length = r.uint32()
+	)
+
+	// bytes stripped from the frame
+	r.uint32()
+
+	header := r.bytes(int(r.uint32()))
+	if r.err {
+		return nil
+	}
+
+	var first gopacket.LayerType
+
+	switch proto {
+	case sflowHeaderEthernet:
+		first = layers.LayerTypeEthernet
+	case sflowHeaderIPv4:
+		first = layers.LayerTypeIPv4
+	case sflowHeaderIPv6:
+		first = layers.LayerTypeIPv6
+	default:
+		return nil
+	}
+
+	var (
+		p = gopacket.NewPacket(header, first, gopacket.Default)
+		f = &flowRecord{bytes: uint64(length)}
+	)
+
+	if eth, ok := p.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		f.srcMAC, f.dstMAC = eth.SrcMAC, eth.DstMAC
+	}
+
+	switch ip := p.NetworkLayer().(type) {
+	case *layers.IPv4:
+		f.srcIP, f.dstIP, f.protocol = ip.SrcIP, ip.DstIP, ip.Protocol
+	case *layers.IPv6:
+		f.srcIP, f.dstIP, f.protocol = ip.SrcIP, ip.DstIP, ip.NextHeader
+	default:
+		return nil
+	}
+
+	switch tl := p.TransportLayer().(type) {
+	case *layers.TCP:
+		f.srcPort, f.dstPort = uint16(tl.SrcPort), uint16(tl.DstPort)
+	case *layers.UDP:
+		f.srcPort, f.dstPort = uint16(tl.SrcPort), uint16(tl.DstPort)
+	case *layers.SCTP:
+		f.srcPort, f.dstPort = uint16(tl.SrcPort), uint16(tl.DstPort)
+	}
+
+	return f
+}
+
+// sflowSampledIP decodes the addresses and ports of a sampled IPv4 or IPv6 packet.
+func sflowSampledIP(data []byte, v6 bool) *flowRecord {
+	size := net.IPv4len
+	if v6 {
+		size = net.IPv6len
+	}
+
+	var (
+		r = &flowReader{data: data}
+		f = &flowRecord{bytes: uint64(r.uint32())}
+	)
+
+	f.protocol = layers.IPProtocol(r.uint32())
+	f.srcIP = flowIP(r.bytes(size))
+	f.dstIP = flowIP(r.bytes(size))
+	f.srcPort = uint16(r.uint32())
+	f.dstPort = uint16(r.uint32())
+
+	if r.err {
+		return nil
+	}
+
+	return f
+}
+
+// sflowSampledEthernet decodes the MAC addresses of a sampled ethernet frame.
+func sflowSampledEthernet(data []byte) *flowRecord {
+	r := &flowReader{data: data}
+
+	// frame length
+	r.uint32()
+
+	// the addresses are padded to 8 bytes
+	var (
+		src = r.bytes(8)
+		dst = r.bytes(8)
+	)
+
+	if r.err {
+		return nil
+	}
+
+	return &flowRecord{
+		srcMAC: flowMAC(src[:6]),
+		dstMAC: flowMAC(dst[:6]),
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// testSampledFrame returns an ethernet frame with a TCP segment.
+func testSampledFrame(t *testing.T) []byte {
+	t.Helper()
+
+	var (
+		eth = &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+			DstMAC:       net.HardwareAddr{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb},
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		ip = &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    net.ParseIP("10.2.0.1"),
+			DstIP:    net.ParseIP("10.2.0.2"),
+		}
+		tcp = &layers.TCP{SrcPort: 51000, DstPort: 80, SYN: true}
+		buf = gopacket.NewSerializeBuffer()
+	)
+
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, eth, ip, tcp); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSFlow(t *testing.T) {
+	var (
+		frame  = testSampledFrame(t)
+		header = testFlowData(uint32(sflowHeaderEthernet), uint32(1514), uint32(4), uint32(len(frame)), frame)
+
+		// sequence, source id, sampling rate, sample pool, drops, interfaces and a single raw packet header record
+		sample = testFlowData(
+			uint32(1), uint32(3), uint32(512), uint32(1024), uint32(0), uint32(1), uint32(2), uint32(1),
+			uint32(sflowRawPacketHeader), uint32(len(header)), header,
+		)
+
+		// counter samples are skipped
+		counters = testFlowData(uint32(1), uint32(0), uint32(0))
+
+		data = testFlowData(
+			uint32(5), uint32(sflowAddressIPv4), net.ParseIP("192.0.2.20"), uint32(9), uint32(1), uint32(1000), uint32(2),
+			uint32(2), uint32(len(counters)), counters,
+			uint32(sflowFlowSample), uint32(len(sample)), sample,
+		)
+
+		ts = time.Unix(1600000000, 0)
+		d  = &flowDatagram{exporter: "192.0.2.20", ts: ts}
+	)
+
+	if err := d.decode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.flows) != 1 || d.domain != 9 || d.samplingRate != 512 || d.protocol != protoSFlow {
+		t.Fatalf("unexpected datagram %+v", d)
+	}
+
+	f := d.flows[0]
+	if f.srcIP.String() != "10.2.0.1" || f.dstIP.String() != "10.2.0.2" || f.srcPort != 51000 || f.dstPort != 80 || f.protocol != layers.IPProtocolTCP {
+		t.Fatalf("unexpected flow %+v", f)
+	}
+
+	if f.srcMAC.String() != "00:11:22:33:44:55" || f.packets != 512 || f.bytes != 1514*512 || !f.start.Equal(ts) {
+		t.Fatalf("unexpected flow %+v", f)
+	}
+
+	// the announced number of samples exceeds the datagram
+	if err := (&flowDatagram{}).decode(data[:len(data)-8]); err == nil {
+		t.Fatal("expected an error for a truncated datagram")
+	}
+}
+
+func TestSFlowSampledIPv4(t *testing.T) {
+	var (
+		record = testFlowData(uint32(100), uint32(17), net.ParseIP("10.3.0.1"), net.ParseIP("10.3.0.2"), uint32(5353), uint32(53), uint32(0), uint32(0))
+
+		// expanded flow sample with separate source id and interface fields
+		sample = testFlowData(
+			uint32(1), uint32(0), uint32(3), uint32(64), uint32(128), uint32(0), uint32(0), uint32(1), uint32(0), uint32(2), uint32(1),
+			uint32(sflowIPv4), uint32(len(record)), record,
+		)
+
+		data = testFlowData(
+			uint32(5), uint32(sflowAddressIPv4), net.ParseIP("192.0.2.21"), uint32(0), uint32(1), uint32(1000), uint32(1),
+			uint32(sflowExpandedFlowSample), uint32(len(sample)), sample,
+		)
+
+		d = &flowDatagram{exporter: "192.0.2.21", ts: time.Unix(1600000000, 0)}
+	)
+
+	if err := d.decode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.flows) != 1 {
+		t.Fatalf("expected 1 flow, got %d", len(d.flows))
+	}
+
+	if f := d.flows[0]; f.srcIP.String() != "10.3.0.1" || f.dstPort != 53 || f.protocol != layers.IPProtocolUDP || f.packets != 64 || f.bytes != 6400 {
+		t.Fatalf("unexpected flow %+v", f)
+	}
+}
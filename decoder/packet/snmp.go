@@ -0,0 +1,721 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+const (
+	serviceSNMP   = "SNMP"
+	serviceSNMPv3 = "SNMPv3"
+
+	snmpPort     = 161
+	snmpTrapPort = 162
+)
+
+// BER tags used by SNMP.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+
+	snmpIPAddress      = 0x40
+	snmpCounter32      = 0x41
+	snmpGauge32        = 0x42
+	snmpTimeTicks      = 0x43
+	snmpOpaque         = 0x44
+	snmpCounter64      = 0x46
+	snmpNoSuchObject   = 0x80
+	snmpNoSuchInstance = 0x81
+	snmpEndOfMibView   = 0x82
+
+	snmpTrapV1 = 0xa4
+)
+
+var (
+	errSNMPTruncated = errors.New("truncated BER element")
+	errSNMPTag       = errors.New("unexpected BER tag")
+)
+
+var snmpPDUTypes = map[byte]string{
+	0xa0: "GetRequest",
+	0xa1: "GetNextRequest",
+	0xa2: "Response",
+	0xa3: "SetRequest",
+	0xa4: "Trap",
+	0xa5: "GetBulkRequest",
+	0xa6: "InformRequest",
+	0xa7: "SNMPv2-Trap",
+	0xa8: "Report",
+}
+
+var snmpDecoder = newPacketDecoder(
+	types.Type_NC_SNMP,
+	"SNMP",
+	"The Simple Network Management Protocol is used to collect and organize information about managed devices on IP networks",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udpLayer := p.Layer(layers.LayerTypeUDP)
+		if udpLayer == nil {
+			return nil
+		}
+
+		udp, ok := udpLayer.(*layers.UDP)
+		if !ok || !isSNMPPort(udp) || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		s, err := decodeSNMP(udp.Payload)
+		if err != nil {
+			decoderLog.Debug("failed to decode SNMP packet", zap.Error(err))
+
+			return nil
+		}
+
+		s.Timestamp = p.Metadata().Timestamp.UnixNano()
+		s.SrcPort = int32(udp.SrcPort)
+		s.DstPort = int32(udp.DstPort)
+
+		var flow string
+		if nl := p.NetworkLayer(); nl != nil {
+			s.SrcIP = nl.NetworkFlow().Src().String()
+			s.DstIP = nl.NetworkFlow().Dst().String()
+			flow = utils.CreateFlowIdentFromLayerFlows(nl.NetworkFlow(), udp.TransportFlow())
+		}
+
+		writeSNMPCredentials(s, udp.Payload, flow)
+
+		return s
+	},
+	nil,
+)
+
+func isSNMPPort(udp *layers.UDP) bool {
+	return udp.SrcPort == snmpPort || udp.DstPort == snmpPort ||
+		udp.SrcPort == snmpTrapPort || udp.DstPort == snmpTrapPort
+}
+
+// writeSNMPCredentials exports community strings and,
+// for authenticated SNMPv3 messages, the user name together
+// with a hash that can be cracked with hashcat (mode 25000).
+func writeSNMPCredentials(s *types.SNMP, payload []byte, flow string) {
+	// prevent nil pointer access if the credentials decoder is not initialized
+	if credentials.Decoder.Writer == nil {
+		return
+	}
+
+	if s.Community != "" {
+		credentials.WriteCredentials(&types.Credentials{
+			Timestamp: s.Timestamp,
+			Service:   serviceSNMP,
+			Flow:      flow,
+			Password:  s.Community,
+			Notes:     "community string, SNMP " + snmpVersionName(s.Version),
+		})
+	}
+
+	// authFlag is set and a user is present
+	if s.Version == 3 && s.MsgFlags&0x1 != 0 && s.UserName != "" && s.AuthParams != "" {
+		hash := snmpv3Hash(s, payload)
+		if hash == "" {
+			return
+		}
+
+		credentials.WriteCredentials(&types.Credentials{
+			Timestamp: s.Timestamp,
+			Service:   serviceSNMPv3,
+			Flow:      flow,
+			User:      s.UserName,
+			Notes:     hash,
+		})
+	}
+}
+
+// snmpv3Hash returns the message in the format used by hashcat mode 25000:
+// $SNMPv3$0$<msgID>$<message with zeroed auth params>$<engineID>$<auth params>.
+func snmpv3Hash(s *types.SNMP, payload []byte) string {
+	auth, err := hex.DecodeString(s.AuthParams)
+	if err != nil {
+		return ""
+	}
+
+	off := snmpAuthParamsOffset(payload)
+	if off < 0 || off+len(auth) > len(payload) {
+		return ""
+	}
+
+	msg := make([]byte, len(payload))
+	copy(msg, payload)
+
+	for i := off; i < off+len(auth); i++ {
+		msg[i] = 0
+	}
+
+	return "$SNMPv3$0$" + strconv.Itoa(int(s.MsgID)) + "$" + hex.EncodeToString(msg) + "$" + s.EngineID + "$" + s.AuthParams
+}
+
+func snmpVersionName(v int32) string {
+	switch v {
+	case 0:
+		return "v1"
+	case 1:
+		return "v2c"
+	case 3:
+		return "v3"
+	default:
+		return strconv.Itoa(int(v))
+	}
+}
+
+// berElement is a single decoded BER TLV.
+type berElement struct {
+	tag   byte
+	value []byte
+}
+
+// readBER reads a single BER element and returns it together with the remaining data.
+func readBER(data []byte) (e berElement, rest []byte, err error) {
+	if len(data) < 2 {
+		return e, nil, errSNMPTruncated
+	}
+
+	e.tag = data[0]
+
+	var (
+		length = int(data[1])
+		offset = 2
+	)
+
+	// long form length
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return e, nil, errSNMPTruncated
+		}
+
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+
+		offset += n
+	}
+
+	if length < 0 || len(data) < offset+length {
+		return e, nil, errSNMPTruncated
+	}
+
+	e.value = data[offset : offset+length]
+
+	return e, data[offset+length:], nil
+}
+
+// readBERTag reads a BER element and ensures it carries the expected tag.
+func readBERTag(data []byte, tag byte) ([]byte, []byte, error) {
+	e, rest, err := readBER(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if e.tag != tag {
+		return nil, nil, fmt.Errorf("%w: 0x%x, expected 0x%x", errSNMPTag, e.tag, tag)
+	}
+
+	return e.value, rest, nil
+}
+
+// readBERInt reads a BER integer.
+func readBERInt(data []byte) (int64, []byte, error) {
+	v, rest, err := readBERTag(data, berInteger)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return berInt(v), rest, nil
+}
+
+func berInt(v []byte) int64 {
+	if len(v) == 0 {
+		return 0
+	}
+
+	var n int64
+
+	// sign extension
+	if v[0]&0x80 != 0 {
+		n = -1
+	}
+
+	for _, b := range v {
+		n = n<<8 | int64(b)
+	}
+
+	return n
+}
+
+func berUint(v []byte) uint64 {
+	var n uint64
+	for _, b := range v {
+		n = n<<8 | uint64(b)
+	}
+
+	return n
+}
+
+// berOIDString converts an encoded object identifier into dotted notation.
+func berOIDString(v []byte) string {
+	var (
+		arcs []uint64
+		cur  uint64
+	)
+
+	for _, c := range v {
+		cur = cur<<7 | uint64(c&0x7f)
+
+		if c&0x80 == 0 {
+			arcs = append(arcs, cur)
+			cur = 0
+		}
+	}
+
+	if len(arcs) == 0 {
+		return ""
+	}
+
+	// the first sub identifier encodes the first two arcs
+	var first, second uint64
+
+	switch {
+	case arcs[0] < 40:
+		second = arcs[0]
+	case arcs[0] < 80:
+		first, second = 1, arcs[0]-40
+	default:
+		first, second = 2, arcs[0]-80
+	}
+
+	var b strings.Builder
+
+	b.WriteString(strconv.FormatUint(first, 10))
+	b.WriteString(".")
+	b.WriteString(strconv.FormatUint(second, 10))
+
+	for _, a := range arcs[1:] {
+		b.WriteString(".")
+		b.WriteString(strconv.FormatUint(a, 10))
+	}
+
+	return b.String()
+}
+
+// decodeSNMP parses an SNMP message.
+func decodeSNMP(data []byte) (*types.SNMP, error) {
+	msg, _, err := readBERTag(data, berSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	version, msg, err := readBERInt(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &types.SNMP{
+		Version: int32(version),
+	}
+
+	switch version {
+	case 0, 1:
+		var community []byte
+
+		community, msg, err = readBERTag(msg, berOctetString)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Community = string(community)
+
+		return s, decodeSNMPPDU(s, msg)
+	case 3:
+		return s, decodeSNMPv3(s, msg)
+	default:
+		return nil, fmt.Errorf("unknown SNMP version: %d", version)
+	}
+}
+
+// decodeSNMPv3 parses the header data, the user based security model parameters and the scoped PDU.
+func decodeSNMPv3(s *types.SNMP, msg []byte) error {
+	global, msg, err := readBERTag(msg, berSequence)
+	if err != nil {
+		return err
+	}
+
+	msgID, global, err := readBERInt(global)
+	if err != nil {
+		return err
+	}
+
+	s.MsgID = int32(msgID)
+
+	// msgMaxSize
+	_, global, err = readBERInt(global)
+	if err != nil {
+		return err
+	}
+
+	flags, global, err := readBERTag(global, berOctetString)
+	if err != nil {
+		return err
+	}
+
+	if len(flags) > 0 {
+		s.MsgFlags = int32(flags[0])
+	}
+
+	model, _, err := readBERInt(global)
+	if err != nil {
+		return err
+	}
+
+	s.SecurityModel = int32(model)
+
+	secParams, msg, err := readBERTag(msg, berOctetString)
+	if err != nil {
+		return err
+	}
+
+	// user based security model
+	if model == 3 && len(secParams) > 0 {
+		if err = decodeSNMPUSM(s, secParams); err != nil {
+			return err
+		}
+	}
+
+	data, _, err := readBER(msg)
+	if err != nil {
+		return err
+	}
+
+	switch data.tag {
+	case berOctetString:
+		s.Encrypted = true
+
+		return nil
+	case berSequence:
+		// contextEngineID
+		_, scoped, errScoped := readBERTag(data.value, berOctetString)
+		if errScoped != nil {
+			return errScoped
+		}
+
+		// contextName
+		_, scoped, errScoped = readBERTag(scoped, berOctetString)
+		if errScoped != nil {
+			return errScoped
+		}
+
+		return decodeSNMPPDU(s, scoped)
+	default:
+		return fmt.Errorf("%w: 0x%x for scoped PDU", errSNMPTag, data.tag)
+	}
+}
+
+// decodeSNMPUSM parses the UsmSecurityParameters.
+func decodeSNMPUSM(s *types.SNMP, data []byte) error {
+	usm, _, err := readBERTag(data, berSequence)
+	if err != nil {
+		return err
+	}
+
+	engineID, usm, err := readBERTag(usm, berOctetString)
+	if err != nil {
+		return err
+	}
+
+	s.EngineID = hex.EncodeToString(engineID)
+
+	boots, usm, err := readBERInt(usm)
+	if err != nil {
+		return err
+	}
+
+	s.EngineBoots = int32(boots)
+
+	engineTime, usm, err := readBERInt(usm)
+	if err != nil {
+		return err
+	}
+
+	s.EngineTime = int32(engineTime)
+
+	user, usm, err := readBERTag(usm, berOctetString)
+	if err != nil {
+		return err
+	}
+
+	s.UserName = string(user)
+
+	auth, _, err := readBERTag(usm, berOctetString)
+	if err != nil {
+		return err
+	}
+
+	s.AuthParams = hex.EncodeToString(auth)
+
+	return nil
+}
+
+// snmpAuthParamsOffset returns the offset of the msgAuthenticationParameters value
+// inside an SNMPv3 message or -1 if it cannot be determined.
+func snmpAuthParamsOffset(data []byte) int {
+	// all values returned by the BER reader are sub slices of data,
+	// so the difference in capacity equals the offset
+	offset := func(b []byte) int {
+		return cap(data) - cap(b)
+	}
+
+	msg, _, err := readBERTag(data, berSequence)
+	if err != nil {
+		return -1
+	}
+
+	// version
+	_, msg, err = readBERInt(msg)
+	if err != nil {
+		return -1
+	}
+
+	// msgGlobalData
+	_, msg, err = readBERTag(msg, berSequence)
+	if err != nil {
+		return -1
+	}
+
+	secParams, _, err := readBERTag(msg, berOctetString)
+	if err != nil {
+		return -1
+	}
+
+	usm, _, err := readBERTag(secParams, berSequence)
+	if err != nil {
+		return -1
+	}
+
+	// engineID, boots, time and user name
+	for _, tag := range []byte{berOctetString, berInteger, berInteger, berOctetString} {
+		_, usm, err = readBERTag(usm, tag)
+		if err != nil {
+			return -1
+		}
+	}
+
+	auth, _, err := readBERTag(usm, berOctetString)
+	if err != nil || len(auth) == 0 {
+		return -1
+	}
+
+	return offset(auth)
+}
+
+// decodeSNMPPDU parses the protocol data unit and its variable bindings.
+func decodeSNMPPDU(s *types.SNMP, data []byte) error {
+	pdu, _, err := readBER(data)
+	if err != nil {
+		return err
+	}
+
+	name, ok := snmpPDUTypes[pdu.tag]
+	if !ok {
+		return fmt.Errorf("%w: 0x%x for PDU", errSNMPTag, pdu.tag)
+	}
+
+	s.PDUType = name
+
+	var (
+		body = pdu.value
+		val  int64
+	)
+
+	if pdu.tag == snmpTrapV1 {
+		var enterprise, agent []byte
+
+		enterprise, body, err = readBERTag(body, berOID)
+		if err != nil {
+			return err
+		}
+
+		s.Enterprise = snmpOIDName(berOIDString(enterprise))
+
+		agent, body, err = readBERTag(body, snmpIPAddress)
+		if err != nil {
+			return err
+		}
+
+		if len(agent) == 4 {
+			s.AgentAddr = net.IP(agent).String()
+		}
+
+		val, body, err = readBERInt(body)
+		if err != nil {
+			return err
+		}
+
+		s.GenericTrap = int32(val)
+
+		val, body, err = readBERInt(body)
+		if err != nil {
+			return err
+		}
+
+		s.SpecificTrap = int32(val)
+
+		var ticks []byte
+
+		ticks, body, err = readBERTag(body, snmpTimeTicks)
+		if err != nil {
+			return err
+		}
+
+		s.Uptime = int64(berUint(ticks))
+	} else {
+		val, body, err = readBERInt(body)
+		if err != nil {
+			return err
+		}
+
+		s.RequestID = int32(val)
+
+		val, body, err = readBERInt(body)
+		if err != nil {
+			return err
+		}
+
+		s.ErrorStatus = int32(val)
+
+		val, body, err = readBERInt(body)
+		if err != nil {
+			return err
+		}
+
+		s.ErrorIndex = int32(val)
+	}
+
+	bindings, _, err := readBERTag(body, berSequence)
+	if err != nil {
+		return err
+	}
+
+	for len(bindings) > 0 {
+		var binding, oid []byte
+
+		binding, bindings, err = readBERTag(bindings, berSequence)
+		if err != nil {
+			return err
+		}
+
+		oid, binding, err = readBERTag(binding, berOID)
+		if err != nil {
+			return err
+		}
+
+		value, _, errValue := readBER(binding)
+		if errValue != nil {
+			return errValue
+		}
+
+		o := berOIDString(oid)
+		typ, v := snmpValue(value)
+
+		s.Variables = append(s.Variables, &types.SNMPVariable{
+			OID:   o,
+			Name:  snmpOIDName(o),
+			Type:  typ,
+			Value: v,
+		})
+
+		// SNMPv2 traps and informs carry the agent uptime as the first variable binding
+		if o == "1.3.6.1.2.1.1.3.0" && value.tag == snmpTimeTicks {
+			s.Uptime = int64(berUint(value.value))
+		}
+	}
+
+	return nil
+}
+
+// snmpValue returns the type name and a printable representation of a variable binding value.
+func snmpValue(e berElement) (typ, value string) {
+	switch e.tag {
+	case berInteger:
+		return "Integer", strconv.FormatInt(berInt(e.value), 10)
+	case berOctetString:
+		return "OctetString", snmpOctets(e.value)
+	case berNull:
+		return "Null", ""
+	case berOID:
+		return "ObjectIdentifier", snmpOIDName(berOIDString(e.value))
+	case snmpIPAddress:
+		if len(e.value) == 4 {
+			return "IpAddress", net.IP(e.value).String()
+		}
+
+		return "IpAddress", hex.EncodeToString(e.value)
+	case snmpCounter32:
+		return "Counter32", strconv.FormatUint(berUint(e.value), 10)
+	case snmpGauge32:
+		return "Gauge32", strconv.FormatUint(berUint(e.value), 10)
+	case snmpTimeTicks:
+		return "TimeTicks", strconv.FormatUint(berUint(e.value), 10)
+	case snmpOpaque:
+		return "Opaque", hex.EncodeToString(e.value)
+	case snmpCounter64:
+		return "Counter64", strconv.FormatUint(berUint(e.value), 10)
+	case snmpNoSuchObject:
+		return "NoSuchObject", ""
+	case snmpNoSuchInstance:
+		return "NoSuchInstance", ""
+	case snmpEndOfMibView:
+		return "EndOfMibView", ""
+	default:
+		return "0x" + strconv.FormatInt(int64(e.tag), 16), hex.EncodeToString(e.value)
+	}
+}
+
+// snmpOctets returns the octets as string if they are printable and hex encoded otherwise.
+func snmpOctets(v []byte) string {
+	for _, r := range string(v) {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return hex.EncodeToString(v)
+		}
+	}
+
+	return string(v)
+}
@@ -0,0 +1,164 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import "strings"
+
+// snmpMIB maps well known object identifiers to their names from the standard MIBs.
+// Unknown identifiers are resolved to the longest known prefix.
+var snmpMIB = map[string]string{
+	// SNMPv2-SMI
+	"1.3.6.1":       "internet",
+	"1.3.6.1.2.1":   "mib-2",
+	"1.3.6.1.4.1":   "enterprises",
+	"1.3.6.1.6.3":   "snmpModules",
+	"1.3.6.1.6.3.1": "snmpMIB",
+
+	// SNMPv2-MIB system group
+	"1.3.6.1.2.1.1":   "system",
+	"1.3.6.1.2.1.1.1": "sysDescr",
+	"1.3.6.1.2.1.1.2": "sysObjectID",
+	"1.3.6.1.2.1.1.3": "sysUpTime",
+	"1.3.6.1.2.1.1.4": "sysContact",
+	"1.3.6.1.2.1.1.5": "sysName",
+	"1.3.6.1.2.1.1.6": "sysLocation",
+	"1.3.6.1.2.1.1.7": "sysServices",
+	"1.3.6.1.2.1.1.8": "sysORLastChange",
+	"1.3.6.1.2.1.1.9": "sysORTable",
+
+	// IF-MIB
+	"1.3.6.1.2.1.2":           "interfaces",
+	"1.3.6.1.2.1.2.1":         "ifNumber",
+	"1.3.6.1.2.1.2.2":         "ifTable",
+	"1.3.6.1.2.1.2.2.1":       "ifEntry",
+	"1.3.6.1.2.1.2.2.1.1":     "ifIndex",
+	"1.3.6.1.2.1.2.2.1.2":     "ifDescr",
+	"1.3.6.1.2.1.2.2.1.3":     "ifType",
+	"1.3.6.1.2.1.2.2.1.4":     "ifMtu",
+	"1.3.6.1.2.1.2.2.1.5":     "ifSpeed",
+	"1.3.6.1.2.1.2.2.1.6":     "ifPhysAddress",
+	"1.3.6.1.2.1.2.2.1.7":     "ifAdminStatus",
+	"1.3.6.1.2.1.2.2.1.8":     "ifOperStatus",
+	"1.3.6.1.2.1.2.2.1.9":     "ifLastChange",
+	"1.3.6.1.2.1.2.2.1.10":    "ifInOctets",
+	"1.3.6.1.2.1.2.2.1.11":    "ifInUcastPkts",
+	"1.3.6.1.2.1.2.2.1.13":    "ifInDiscards",
+	"1.3.6.1.2.1.2.2.1.14":    "ifInErrors",
+	"1.3.6.1.2.1.2.2.1.16":    "ifOutOctets",
+	"1.3.6.1.2.1.2.2.1.17":    "ifOutUcastPkts",
+	"1.3.6.1.2.1.2.2.1.19":    "ifOutDiscards",
+	"1.3.6.1.2.1.2.2.1.20":    "ifOutErrors",
+	"1.3.6.1.2.1.31.1.1":      "ifXTable",
+	"1.3.6.1.2.1.31.1.1.1.1":  "ifName",
+	"1.3.6.1.2.1.31.1.1.1.6":  "ifHCInOctets",
+	"1.3.6.1.2.1.31.1.1.1.10": "ifHCOutOctets",
+	"1.3.6.1.2.1.31.1.1.1.15": "ifHighSpeed",
+	"1.3.6.1.2.1.31.1.1.1.18": "ifAlias",
+
+	// IP-MIB, TCP-MIB and UDP-MIB
+	"1.3.6.1.2.1.3.1.1.2":  "atPhysAddress",
+	"1.3.6.1.2.1.4":        "ip",
+	"1.3.6.1.2.1.4.1":      "ipForwarding",
+	"1.3.6.1.2.1.4.2":      "ipDefaultTTL",
+	"1.3.6.1.2.1.4.20":     "ipAddrTable",
+	"1.3.6.1.2.1.4.20.1.1": "ipAdEntAddr",
+	"1.3.6.1.2.1.4.20.1.2": "ipAdEntIfIndex",
+	"1.3.6.1.2.1.4.20.1.3": "ipAdEntNetMask",
+	"1.3.6.1.2.1.4.21":     "ipRouteTable",
+	"1.3.6.1.2.1.4.22":     "ipNetToMediaTable",
+	"1.3.6.1.2.1.4.22.1.2": "ipNetToMediaPhysAddress",
+	"1.3.6.1.2.1.4.22.1.3": "ipNetToMediaNetAddress",
+	"1.3.6.1.2.1.5":        "icmp",
+	"1.3.6.1.2.1.6":        "tcp",
+	"1.3.6.1.2.1.6.13":     "tcpConnTable",
+	"1.3.6.1.2.1.7":        "udp",
+	"1.3.6.1.2.1.7.5":      "udpTable",
+	"1.3.6.1.2.1.11":       "snmp",
+
+	// HOST-RESOURCES-MIB
+	"1.3.6.1.2.1.25":         "host",
+	"1.3.6.1.2.1.25.1.1":     "hrSystemUptime",
+	"1.3.6.1.2.1.25.1.2":     "hrSystemDate",
+	"1.3.6.1.2.1.25.1.5":     "hrSystemNumUsers",
+	"1.3.6.1.2.1.25.1.6":     "hrSystemProcesses",
+	"1.3.6.1.2.1.25.2.2":     "hrMemorySize",
+	"1.3.6.1.2.1.25.2.3":     "hrStorageTable",
+	"1.3.6.1.2.1.25.3.2":     "hrDeviceTable",
+	"1.3.6.1.2.1.25.4.2":     "hrSWRunTable",
+	"1.3.6.1.2.1.25.4.2.1.2": "hrSWRunName",
+	"1.3.6.1.2.1.25.4.2.1.4": "hrSWRunPath",
+	"1.3.6.1.2.1.25.4.2.1.5": "hrSWRunParameters",
+	"1.3.6.1.2.1.25.6.3":     "hrSWInstalledTable",
+	"1.3.6.1.2.1.25.6.3.1.2": "hrSWInstalledName",
+
+	// ENTITY-MIB and BRIDGE-MIB
+	"1.3.6.1.2.1.47.1.1.1":      "entPhysicalTable",
+	"1.3.6.1.2.1.47.1.1.1.1.2":  "entPhysicalDescr",
+	"1.3.6.1.2.1.47.1.1.1.1.11": "entPhysicalSerialNum",
+	"1.3.6.1.2.1.47.1.1.1.1.13": "entPhysicalModelName",
+	"1.3.6.1.2.1.17":            "dot1dBridge",
+	"1.3.6.1.2.1.17.4.3":        "dot1dTpFdbTable",
+
+	// SNMPv2-MIB traps and notification objects
+	"1.3.6.1.6.3.1.1.4.1": "snmpTrapOID",
+	"1.3.6.1.6.3.1.1.4.3": "snmpTrapEnterprise",
+	"1.3.6.1.6.3.1.1.5.1": "coldStart",
+	"1.3.6.1.6.3.1.1.5.2": "warmStart",
+	"1.3.6.1.6.3.1.1.5.3": "linkDown",
+	"1.3.6.1.6.3.1.1.5.4": "linkUp",
+	"1.3.6.1.6.3.1.1.5.5": "authenticationFailure",
+	"1.3.6.1.6.3.1.1.5.6": "egpNeighborLoss",
+
+	// SNMP-FRAMEWORK-MIB and SNMP-USER-BASED-SM-MIB
+	"1.3.6.1.6.3.10.2.1.1": "snmpEngineID",
+	"1.3.6.1.6.3.10.2.1.2": "snmpEngineBoots",
+	"1.3.6.1.6.3.10.2.1.3": "snmpEngineTime",
+	"1.3.6.1.6.3.10.2.1.4": "snmpEngineMaxMessageSize",
+	"1.3.6.1.6.3.15.1.1.1": "usmStatsUnsupportedSecLevels",
+	"1.3.6.1.6.3.15.1.1.2": "usmStatsNotInTimeWindows",
+	"1.3.6.1.6.3.15.1.1.3": "usmStatsUnknownUserNames",
+	"1.3.6.1.6.3.15.1.1.4": "usmStatsUnknownEngineIDs",
+	"1.3.6.1.6.3.15.1.1.5": "usmStatsWrongDigests",
+	"1.3.6.1.6.3.15.1.1.6": "usmStatsDecryptionErrors",
+
+	// private enterprise numbers
+	"1.3.6.1.4.1.9":     "cisco",
+	"1.3.6.1.4.1.11":    "hp",
+	"1.3.6.1.4.1.311":   "microsoft",
+	"1.3.6.1.4.1.2021":  "ucdavis",
+	"1.3.6.1.4.1.2636":  "juniperMIB",
+	"1.3.6.1.4.1.8072":  "netSnmp",
+	"1.3.6.1.4.1.12356": "fortinet",
+	"1.3.6.1.4.1.25461": "paloaltoNetworks",
+}
+
+// snmpOIDName resolves an object identifier to its name, keeping the instance suffix.
+// e.g. 1.3.6.1.2.1.1.5.0 becomes sysName.0.
+// The identifier is returned unchanged if no prefix is known.
+func snmpOIDName(oid string) string {
+	prefix := oid
+
+	for {
+		if name, ok := snmpMIB[prefix]; ok {
+			return name + oid[len(prefix):]
+		}
+
+		i := strings.LastIndexByte(prefix, '.')
+		if i < 0 {
+			return oid
+		}
+
+		prefix = prefix[:i]
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strings"
+	"testing"
+)
+
+// sysDescr.0 GetRequest, community public, SNMPv2c
+var snmpGetRequest = []byte{
+	0x30, 0x29, 0x02, 0x01, 0x01, 0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
+	0xa0, 0x1c, 0x02, 0x04, 0x1b, 0x6c, 0x58, 0x2e, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+	0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+}
+
+// linkDown SNMPv1 trap from 10.0.0.1, community private
+var snmpTrap = []byte{
+	0x30, 0x3a, 0x02, 0x01, 0x00, 0x04, 0x07, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65,
+	0xa4, 0x2c, 0x06, 0x07, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x09, 0x01,
+	0x40, 0x04, 0x0a, 0x00, 0x00, 0x01,
+	0x02, 0x01, 0x02, 0x02, 0x01, 0x00, 0x43, 0x02, 0x30, 0x39,
+	0x30, 0x11, 0x30, 0x0f, 0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x01, 0x01, 0x02, 0x01, 0x03,
+}
+
+// authenticated SNMPv3 GetRequest for user netcap
+var snmpV3Request = []byte{
+	0x30, 0x6d, 0x02, 0x01, 0x03,
+	0x30, 0x11, 0x02, 0x04, 0x00, 0x00, 0x30, 0x39, 0x02, 0x03, 0x00, 0xff, 0xe3, 0x04, 0x01, 0x05, 0x02, 0x01, 0x03,
+	0x04, 0x2b, 0x30, 0x29,
+	0x04, 0x08, 0x80, 0x00, 0x1f, 0x88, 0x80, 0x01, 0x02, 0x03,
+	0x02, 0x01, 0x01, 0x02, 0x02, 0x01, 0x00,
+	0x04, 0x06, 0x6e, 0x65, 0x74, 0x63, 0x61, 0x70,
+	0x04, 0x0c, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc,
+	0x04, 0x00,
+	0x30, 0x28, 0x04, 0x08, 0x80, 0x00, 0x1f, 0x88, 0x80, 0x01, 0x02, 0x03, 0x04, 0x00,
+	0xa0, 0x1a, 0x02, 0x02, 0x01, 0x02, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+	0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00, 0x05, 0x00,
+}
+
+func TestDecodeSNMPGetRequest(t *testing.T) {
+	s, err := decodeSNMP(snmpGetRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != 1 || s.Community != "public" || s.PDUType != "GetRequest" {
+		t.Fatal("unexpected header values", s.Version, s.Community, s.PDUType)
+	}
+
+	if s.RequestID != 0x1b6c582e {
+		t.Fatal("unexpected request id", s.RequestID)
+	}
+
+	if len(s.Variables) != 1 {
+		t.Fatal("expected one variable binding, got", len(s.Variables))
+	}
+
+	v := s.Variables[0]
+	if v.OID != "1.3.6.1.2.1.1.1.0" || v.Name != "sysDescr.0" || v.Type != "Null" {
+		t.Fatal("unexpected variable binding", v)
+	}
+}
+
+func TestDecodeSNMPTrap(t *testing.T) {
+	s, err := decodeSNMP(snmpTrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.PDUType != "Trap" || s.Community != "private" {
+		t.Fatal("unexpected header values", s.PDUType, s.Community)
+	}
+
+	if s.Enterprise != "cisco.1" || s.AgentAddr != "10.0.0.1" {
+		t.Fatal("unexpected trap source", s.Enterprise, s.AgentAddr)
+	}
+
+	if s.GenericTrap != 2 || s.Uptime != 12345 {
+		t.Fatal("unexpected trap values", s.GenericTrap, s.Uptime)
+	}
+
+	if len(s.Variables) != 1 || s.Variables[0].Name != "ifIndex.1" || s.Variables[0].Value != "3" {
+		t.Fatal("unexpected variable bindings", s.Variables)
+	}
+}
+
+func TestDecodeSNMPv3(t *testing.T) {
+	s, err := decodeSNMP(snmpV3Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != 3 || s.MsgID != 12345 || s.MsgFlags != 5 || s.SecurityModel != 3 {
+		t.Fatal("unexpected header values", s.Version, s.MsgID, s.MsgFlags, s.SecurityModel)
+	}
+
+	if s.UserName != "netcap" || s.EngineID != "80001f8880010203" || s.AuthParams != "112233445566778899aabbcc" {
+		t.Fatal("unexpected security parameters", s.UserName, s.EngineID, s.AuthParams)
+	}
+
+	if s.PDUType != "GetRequest" || len(s.Variables) != 1 || s.Variables[0].Name != "sysName.0" {
+		t.Fatal("unexpected scoped PDU", s.PDUType, s.Variables)
+	}
+
+	hash := snmpv3Hash(s, snmpV3Request)
+	if !strings.HasPrefix(hash, "$SNMPv3$0$12345$") || !strings.HasSuffix(hash, "$80001f8880010203$112233445566778899aabbcc") {
+		t.Fatal("unexpected hash format", hash)
+	}
+
+	if strings.Contains(strings.Split(hash, "$")[4], "112233445566778899aabbcc") {
+		t.Fatal("auth params have not been zeroed in the message", hash)
+	}
+}
+
+func TestDecodeSNMPTruncated(t *testing.T) {
+	for i := range snmpGetRequest {
+		if _, err := decodeSNMP(snmpGetRequest[:i]); err == nil {
+			t.Fatal("expected error for truncated message of length", i)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// SNAP protocol id of Cisco PVST+ BPDUs
+	ethernetTypePVST layers.EthernetType = 0x010b
+
+	bpduTCNSize    = 4
+	bpduConfigSize = 35
+	bpduRSTSize    = 36
+
+	// offset of the version 3 length of MSTP BPDUs
+	bpduMSTOffset      = 36
+	bpduMSTConfigSize  = 51
+	bpduMSTCISTSize    = 13
+	bpduMSTIRecordSize = 16
+
+	// BPDU types
+	bpduTypeConfig = 0x00
+	bpduTypeRST    = 0x02
+	bpduTypeTCN    = 0x80
+
+	// BPDU flags
+	bpduFlagTopologyChange    = 0x01
+	bpduFlagProposal          = 0x02
+	bpduFlagPortRole          = 0x0c
+	bpduFlagLearning          = 0x10
+	bpduFlagForwarding        = 0x20
+	bpduFlagAgreement         = 0x40
+	bpduFlagTopologyChangeAck = 0x80
+
+	// PVST+ originating VLAN TLV
+	pvstVLANTLVSize = 6
+	pvstVLANTLVType = 0
+)
+
+var errBPDUTruncated = errors.New("truncated BPDU")
+
+var stpVersions = map[byte]string{
+	0: "STP",
+	2: "RSTP",
+	3: "MSTP",
+}
+
+var stpBPDUTypes = map[byte]string{
+	bpduTypeConfig: "Configuration",
+	bpduTypeRST:    "RST/MST",
+	bpduTypeTCN:    "Topology Change Notification",
+}
+
+var stpPortRoles = []string{
+	"Master",
+	"Alternate/Backup",
+	"Root",
+	"Designated",
+}
+
+func init() {
+	// PVST+ BPDUs are sent with a SNAP header instead of the 802.2 LLC header used for IEEE BPDUs
+	layers.EthernetTypeMetadata[ethernetTypePVST] = layers.EnumMetadata{
+		DecodeWith: layers.LayerTypeSTP,
+		Name:       "PVST",
+		LayerType:  layers.LayerTypeSTP,
+	}
+}
+
+var stpDecoder = newGoPacketDecoder(
+	types.Type_NC_STP,
+	layers.LayerTypeSTP,
+	"The Spanning Tree Protocol prevents loops in bridged networks by electing a root bridge and blocking redundant ports, forged BPDUs can be used to take over the root bridge role",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if stp, ok := layer.(*layers.STP); ok {
+			s, err := decodeBPDU(stp.Contents)
+			if err != nil {
+				decoderLog.Debug("failed to decode BPDU", zap.Error(err))
+
+				return nil
+			}
+
+			s.Timestamp = timestamp
+
+			return s
+		}
+
+		return nil
+	},
+)
+
+// decodeBPDU parses STP configuration and topology change notification BPDUs,
+// RSTP and MSTP BPDUs and the originating VLAN TLV of PVST+ BPDUs.
+func decodeBPDU(data []byte) (*types.STP, error) {
+	if len(data) < bpduTCNSize {
+		return nil, errBPDUTruncated
+	}
+
+	s := &types.STP{
+		Version:  stpVersions[data[2]],
+		BPDUType: stpBPDUTypes[data[3]],
+	}
+
+	if s.Version == "" {
+		s.Version = strconv.Itoa(int(data[2]))
+	}
+
+	if s.BPDUType == "" {
+		s.BPDUType = strconv.Itoa(int(data[3]))
+	}
+
+	if data[3] == bpduTypeTCN {
+		return s, nil
+	}
+
+	if len(data) < bpduConfigSize {
+		return nil, errBPDUTruncated
+	}
+
+	flags := data[4]
+	s.Flags = int32(flags)
+	s.TopologyChange = flags&bpduFlagTopologyChange != 0
+	s.TopologyChangeAck = flags&bpduFlagTopologyChangeAck != 0
+	s.RootID = bridgeID(data[5:13])
+	s.RootPathCost = binary.BigEndian.Uint32(data[13:17])
+	s.BridgeID = bridgeID(data[17:25])
+	s.PortID = int32(binary.BigEndian.Uint16(data[25:27]))
+	s.MessageAge = int32(binary.BigEndian.Uint16(data[27:29]))
+	s.MaxAge = int32(binary.BigEndian.Uint16(data[29:31]))
+	s.HelloTime = int32(binary.BigEndian.Uint16(data[31:33]))
+	s.ForwardDelay = int32(binary.BigEndian.Uint16(data[33:35]))
+
+	rest := data[bpduConfigSize:]
+
+	if data[3] == bpduTypeRST {
+		s.Proposal = flags&bpduFlagProposal != 0
+		s.Agreement = flags&bpduFlagAgreement != 0
+		s.Learning = flags&bpduFlagLearning != 0
+		s.Forwarding = flags&bpduFlagForwarding != 0
+		s.PortRole = stpPortRoles[(flags&bpduFlagPortRole)>>2]
+
+		if len(data) < bpduRSTSize {
+			return nil, errBPDUTruncated
+		}
+
+		rest = data[bpduRSTSize:]
+
+		if data[2] == 3 && len(data) >= bpduMSTOffset+2+bpduMSTConfigSize+bpduMSTCISTSize {
+			var (
+				length = int(binary.BigEndian.Uint16(data[bpduMSTOffset : bpduMSTOffset+2]))
+				config = data[bpduMSTOffset+2+1 : bpduMSTOffset+2+bpduMSTConfigSize]
+			)
+
+			s.MSTConfigName = strings.TrimRight(string(config[:32]), "\x00")
+			s.MSTConfigRevision = int32(binary.BigEndian.Uint16(config[32:34]))
+
+			if length >= bpduMSTConfigSize+bpduMSTCISTSize {
+				s.MSTIs = int32((length - bpduMSTConfigSize - bpduMSTCISTSize) / bpduMSTIRecordSize)
+			}
+
+			if bpduMSTOffset+2+length <= len(data) {
+				rest = data[bpduMSTOffset+2+length:]
+			}
+		}
+	}
+
+	if len(rest) >= pvstVLANTLVSize && binary.BigEndian.Uint16(rest[:2]) == pvstVLANTLVType && binary.BigEndian.Uint16(rest[2:4]) == 2 {
+		s.VLAN = int32(binary.BigEndian.Uint16(rest[4:6]))
+	}
+
+	return s, nil
+}
+
+// bridgeID formats a bridge identifier as priority/MAC,
+// the priority includes the extended system id, which is usually the VLAN.
+func bridgeID(data []byte) string {
+	return strconv.Itoa(int(binary.BigEndian.Uint16(data[:2]))) + "/" + net.HardwareAddr(data[2:8]).String()
+}
+
+// compareBridgeIDs compares two identifiers formatted by bridgeID,
+// a lower priority or for equal priorities a lower MAC address is superior.
+func compareBridgeIDs(a, b string) int {
+	pa, ma := splitBridgeID(a)
+	pb, mb := splitBridgeID(b)
+
+	switch {
+	case pa < pb:
+		return -1
+	case pa > pb:
+		return 1
+	default:
+		return strings.Compare(ma, mb)
+	}
+}
+
+func splitBridgeID(id string) (int, string) {
+	i := strings.IndexByte(id, '/')
+	if i < 0 {
+		return 0, id
+	}
+
+	p, _ := strconv.Atoi(id[:i])
+
+	return p, id[i+1:]
+}
+
+// stpDomains holds the spanning tree summaries by VLAN, 0 for the common spanning tree.
+var stpDomains = struct {
+	sync.Mutex
+	items map[int32]*types.STPSummary
+}{
+	items: make(map[int32]*types.STPSummary),
+}
+
+var stpSummaryDecoder = newPacketDecoder(
+	types.Type_NC_STPSummary,
+	"STPSummary",
+	"A summary of the spanning tree for each VLAN, that flags changes of the root bridge and sources of BPDUs claiming a root bridge other than the established one",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		l := p.Layer(layers.LayerTypeSTP)
+		if l == nil {
+			return nil
+		}
+
+		s, err := decodeBPDU(l.LayerContents())
+		if err != nil {
+			return nil
+		}
+
+		var src string
+		if ll := p.LinkLayer(); ll != nil {
+			src = ll.LinkFlow().Src().String()
+		}
+
+		vlan := s.VLAN
+		if vlan == 0 {
+			if dot1q, ok := p.Layer(layers.LayerTypeDot1Q).(*layers.Dot1Q); ok {
+				vlan = int32(dot1q.VLANIdentifier)
+			}
+		}
+
+		updateSTPDomain(vlan, src, s, p.Metadata().Timestamp.UnixNano())
+
+		return nil
+	},
+	func(d *Decoder) error {
+		stpDomains.Lock()
+		defer stpDomains.Unlock()
+
+		for _, sum := range stpDomains.items {
+			d.write(sum)
+		}
+
+		return nil
+	},
+)
+
+// updateSTPDomain adds the BPDU to the summary of the VLAN.
+// The first root bridge seen is considered established, a source announcing a superior root bridge
+// causes a root change, while a source announcing any other root bridge is flagged as unexpected.
+func updateSTPDomain(vlan int32, src string, s *types.STP, ts int64) {
+	stpDomains.Lock()
+	defer stpDomains.Unlock()
+
+	sum, ok := stpDomains.items[vlan]
+	if !ok {
+		sum = &types.STPSummary{
+			Timestamp: ts,
+			VLAN:      vlan,
+		}
+		stpDomains.items[vlan] = sum
+	}
+
+	sum.NumBPDUs++
+	sum.Sources = decoderutils.AppendUnique(sum.Sources, src)
+
+	if s.BPDUType == stpBPDUTypes[bpduTypeTCN] || s.TopologyChange {
+		sum.TopologyChanges++
+	}
+
+	if s.RootID == "" {
+		return
+	}
+
+	sum.Bridges = decoderutils.AppendUnique(sum.Bridges, s.BridgeID)
+
+	switch {
+	case sum.Root == "":
+		sum.Root = s.RootID
+		sum.RootBridges = append(sum.RootBridges, s.RootID)
+	case s.RootID != sum.Root:
+		if compareBridgeIDs(s.RootID, sum.Root) < 0 {
+			sum.Root = s.RootID
+			sum.RootBridges = append(sum.RootBridges, s.RootID)
+			sum.RootChanges++
+		}
+
+		sum.UnexpectedSources = decoderutils.AppendUnique(sum.UnexpectedSources, src+" ("+s.BridgeID+")")
+	}
+}
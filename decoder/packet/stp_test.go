@@ -0,0 +1,118 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// testBPDUPacket returns an 802.3 frame with an LLC header and a configuration BPDU
+// announcing the root bridge and bridge with the given priorities and last MAC octets.
+func testBPDUPacket(src byte, rootPrio byte, rootMAC byte, bridgeMAC byte, flags byte) gopacket.Packet {
+	frame := []byte{
+		0x01, 0x80, 0xc2, 0x00, 0x00, 0x00,
+		0x00, 0x11, 0x22, 0x33, 0x44, src,
+		0x00, 0x26,
+		0x42, 0x42, 0x03,
+		0x00, 0x00, 0x00, 0x00, flags,
+		rootPrio, 0x00, 0x00, 0x11, 0x22, 0x33, 0x44, rootMAC,
+		0x00, 0x00, 0x00, 0x04,
+		0x80, 0x00, 0x00, 0x11, 0x22, 0x33, 0x44, bridgeMAC,
+		0x80, 0x01, 0x01, 0x00, 0x14, 0x00, 0x02, 0x00, 0x0f, 0x00,
+	}
+
+	p := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+	p.Metadata().Timestamp = time.Now()
+
+	return p
+}
+
+func TestSTPConfigurationBPDU(t *testing.T) {
+	p := testBPDUPacket(0x01, 0x80, 0x01, 0x02, 0x01)
+
+	l := p.Layer(layers.LayerTypeSTP)
+	if l == nil {
+		t.Fatal("expected STP layer")
+	}
+
+	r := stpDecoder.Handler(l, 1)
+	if r == nil {
+		t.Fatal("expected STP record")
+	}
+
+	s := r.(*types.STP)
+	if s.Version != "STP" || s.BPDUType != "Configuration" || !s.TopologyChange || s.RootPathCost != 4 {
+		t.Fatalf("unexpected BPDU: %+v", s)
+	}
+
+	if s.RootID != "32768/00:11:22:33:44:01" || s.BridgeID != "32768/00:11:22:33:44:02" || s.PortID != 0x8001 || s.MaxAge != 0x1400 {
+		t.Fatalf("unexpected bridge identifiers: %+v", s)
+	}
+}
+
+func TestRSTPBPDUWithPVSTVLAN(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x02, 0x02, 0x3c,
+		0x80, 0x0a, 0x00, 0x11, 0x22, 0x33, 0x44, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x80, 0x0a, 0x00, 0x11, 0x22, 0x33, 0x44, 0x01,
+		0x80, 0x01, 0x00, 0x00, 0x14, 0x00, 0x02, 0x00, 0x0f, 0x00,
+		0x00,
+		0x00, 0x00, 0x00, 0x02, 0x00, 0x0a,
+	}
+
+	s, err := decodeBPDU(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != "RSTP" || s.PortRole != "Designated" || !s.Learning || !s.Forwarding || s.Agreement || s.VLAN != 10 {
+		t.Fatalf("unexpected RSTP BPDU: %+v", s)
+	}
+}
+
+func TestSTPSummaryRootChange(t *testing.T) {
+	for _, p := range []gopacket.Packet{
+		testBPDUPacket(0x01, 0x80, 0x01, 0x01, 0x00),
+		testBPDUPacket(0x02, 0x80, 0x01, 0x02, 0x00),
+		// rogue bridge announcing itself as root with a lower priority
+		testBPDUPacket(0x66, 0x00, 0x66, 0x66, 0x01),
+	} {
+		if stpSummaryDecoder.Handler(p) != nil {
+			t.Fatal("expected no record before flushing")
+		}
+	}
+
+	stpDomains.Lock()
+	sum := stpDomains.items[0]
+	stpDomains.Unlock()
+
+	if sum == nil || sum.NumBPDUs != 3 || sum.RootChanges != 1 || sum.TopologyChanges != 1 || sum.Root != "0/00:11:22:33:44:66" {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+
+	if len(sum.RootBridges) != 2 || len(sum.Sources) != 3 || len(sum.Bridges) != 3 {
+		t.Fatalf("unexpected summary bridges: %+v", sum)
+	}
+
+	if len(sum.UnexpectedSources) != 1 || sum.UnexpectedSources[0] != "00:11:22:33:44:66 (32768/00:11:22:33:44:66)" {
+		t.Fatalf("unexpected sources: %v", sum.UnexpectedSources)
+	}
+}
@@ -0,0 +1,372 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+const (
+	tftpPort             = 69
+	tftpDefaultBlockSize = 512
+)
+
+// TFTP opcodes, see RFC 1350 and RFC 2347.
+const (
+	tftpRRQ   = 1
+	tftpWRQ   = 2
+	tftpDATA  = 3
+	tftpACK   = 4
+	tftpERROR = 5
+	tftpOACK  = 6
+)
+
+var errTFTPIncomplete = errors.New("incomplete TFTP transfer")
+
+// tftpTransfer tracks the state of a single read or write request.
+type tftpTransfer struct {
+	record *types.TFTP
+
+	// transfer identifier chosen by the server for the transfer
+	serverTID uint16
+
+	// data blocks by their absolute index, starting at 1
+	blocks map[int][]byte
+
+	// last seen block number and offset for block number roll over
+	lastBlock uint16
+	base      int
+
+	// index of the last block, zero until a short block has been seen
+	final int
+
+	start time.Time
+	last  time.Time
+}
+
+// tftpTransfers holds all active transfers,
+// keyed by the client endpoint and the server address.
+var tftpTransfers = struct {
+	sync.Mutex
+	items map[string]*tftpTransfer
+}{
+	items: make(map[string]*tftpTransfer),
+}
+
+var tftpDecoder = newPacketDecoder(
+	types.Type_NC_TFTP,
+	"TFTP",
+	"The Trivial File Transfer Protocol is a simple lockstep protocol for transferring files, frequently used for network device configuration and network booting",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udpLayer := p.Layer(layers.LayerTypeUDP)
+		if udpLayer == nil {
+			return nil
+		}
+
+		udp, ok := udpLayer.(*layers.UDP)
+		if !ok || len(udp.Payload) < 4 {
+			return nil
+		}
+
+		nl := p.NetworkLayer()
+		if nl == nil {
+			return nil
+		}
+
+		var (
+			srcIP = nl.NetworkFlow().Src().String()
+			dstIP = nl.NetworkFlow().Dst().String()
+			ts    = p.Metadata().Timestamp
+		)
+
+		tftpTransfers.Lock()
+		defer tftpTransfers.Unlock()
+
+		op := binary.BigEndian.Uint16(udp.Payload[:2])
+
+		// new requests are sent to the well known port
+		if udp.DstPort == tftpPort && (op == tftpRRQ || op == tftpWRQ) {
+			ident := tftpIdent(srcIP, int32(udp.SrcPort), dstIP)
+
+			// a new request from the same client port replaces the previous transfer
+			var prev proto.Message
+			if t, exists := tftpTransfers.items[ident]; exists && len(t.blocks) > 0 {
+				prev = t.finish(errTFTPIncomplete)
+			}
+
+			if t := newTFTPTransfer(udp.Payload, ts); t != nil {
+				t.record.ClientIP = srcIP
+				t.record.ServerIP = dstIP
+				t.record.ClientPort = int32(udp.SrcPort)
+				tftpTransfers.items[ident] = t
+			}
+
+			return prev
+		}
+
+		// lookup an existing transfer, the sender is either the client or the server
+		var (
+			fromServer bool
+			ident      = tftpIdent(srcIP, int32(udp.SrcPort), dstIP)
+			t, exists  = tftpTransfers.items[ident]
+		)
+
+		if !exists {
+			ident = tftpIdent(dstIP, int32(udp.DstPort), srcIP)
+			if t, exists = tftpTransfers.items[ident]; !exists {
+				return nil
+			}
+
+			fromServer = true
+		}
+
+		// the server picks a new port for the transfer
+		if fromServer {
+			if t.serverTID == 0 {
+				t.serverTID = uint16(udp.SrcPort)
+				t.record.ServerPort = int32(udp.SrcPort)
+			} else if t.serverTID != uint16(udp.SrcPort) {
+				return nil
+			}
+		} else if t.serverTID != 0 && t.serverTID != uint16(udp.DstPort) {
+			return nil
+		}
+
+		t.last = ts
+
+		switch op {
+		case tftpOACK:
+			t.applyOptions(udp.Payload[2:])
+		case tftpDATA:
+			if t.addBlock(binary.BigEndian.Uint16(udp.Payload[2:4]), udp.Payload[4:]) {
+				delete(tftpTransfers.items, ident)
+
+				return t.finish(nil)
+			}
+		case tftpERROR:
+			t.record.ErrorCode = int32(binary.BigEndian.Uint16(udp.Payload[2:4]))
+			t.record.ErrorMessage = string(bytes.TrimRight(udp.Payload[4:], "\x00"))
+			delete(tftpTransfers.items, ident)
+
+			return t.finish(errTFTPIncomplete)
+		case tftpACK:
+			// nothing to do, data blocks carry all the information
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		tftpTransfers.Lock()
+		defer tftpTransfers.Unlock()
+
+		// flush transfers that did not complete
+		for ident, t := range tftpTransfers.items {
+			d.write(t.finish(errTFTPIncomplete))
+			delete(tftpTransfers.items, ident)
+		}
+
+		return nil
+	},
+)
+
+func tftpIdent(clientIP string, clientPort int32, serverIP string) string {
+	return clientIP + ":" + strconv.Itoa(int(clientPort)) + "->" + serverIP
+}
+
+// newTFTPTransfer parses a read or write request.
+func newTFTPTransfer(payload []byte, ts time.Time) *tftpTransfer {
+	fields := bytes.Split(payload[2:], []byte{0})
+	if len(fields) < 2 || len(fields[0]) == 0 {
+		return nil
+	}
+
+	operation := "RRQ"
+	if binary.BigEndian.Uint16(payload[:2]) == tftpWRQ {
+		operation = "WRQ"
+	}
+
+	t := &tftpTransfer{
+		record: &types.TFTP{
+			Timestamp: ts.UnixNano(),
+			Operation: operation,
+			Filename:  string(fields[0]),
+			Mode:      strings.ToLower(string(fields[1])),
+			BlockSize: tftpDefaultBlockSize,
+		},
+		blocks: make(map[int][]byte),
+		start:  ts,
+		last:   ts,
+	}
+
+	// options requested by the client, see RFC 2347.
+	// They are only recorded, since a server that ignores them uses the defaults.
+	parseTFTPOptions(bytes.Join(fields[2:], []byte{0}), t.setOption)
+
+	return t
+}
+
+// parseTFTPOptions calls fn for every null terminated option name and value.
+func parseTFTPOptions(data []byte, fn func(name, value string)) {
+	fields := bytes.Split(data, []byte{0})
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		if name := strings.ToLower(string(fields[i])); name != "" {
+			fn(name, string(fields[i+1]))
+		}
+	}
+}
+
+// applyOptions applies the options acknowledged by the server in an OACK,
+// their values overwrite the ones requested by the client.
+func (t *tftpTransfer) applyOptions(data []byte) {
+	parseTFTPOptions(data, func(name, value string) {
+		t.setOption(name, value)
+
+		switch name {
+		case "blksize":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				t.record.BlockSize = int32(n)
+			}
+		case "tsize":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				t.record.TransferSize = n
+			}
+		}
+	})
+}
+
+func (t *tftpTransfer) setOption(name, value string) {
+	opt := name + "=" + value
+
+	for i, o := range t.record.Options {
+		if strings.HasPrefix(o, name+"=") {
+			t.record.Options[i] = opt
+
+			return
+		}
+	}
+
+	t.record.Options = append(t.record.Options, opt)
+}
+
+// addBlock stores a data block and reports whether the transfer is complete.
+func (t *tftpTransfer) addBlock(num uint16, data []byte) bool {
+	// block numbers roll over after 65535
+	if num < 0x1000 && t.lastBlock > 0xf000 {
+		t.base += 0x10000
+	}
+
+	t.lastBlock = num
+	index := t.base + int(num)
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+	t.blocks[index] = buf
+
+	// a block smaller than the negotiated size terminates the transfer
+	if len(data) < int(t.record.BlockSize) {
+		t.final = index
+	}
+
+	if t.final == 0 {
+		return false
+	}
+
+	for i := t.final; i > 0; i-- {
+		if _, ok := t.blocks[i]; !ok {
+			// implementations rolling over to block one leave a gap at block zero
+			if i%0x10000 == 0 {
+				continue
+			}
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// finish assembles the data blocks in order, saves the file and returns the audit record.
+func (t *tftpTransfer) finish(err error) *types.TFTP {
+	indices := make([]int, 0, len(t.blocks))
+	for i := range t.blocks {
+		indices = append(indices, i)
+	}
+
+	sort.Ints(indices)
+
+	var body bytes.Buffer
+	for _, i := range indices {
+		body.Write(t.blocks[i])
+	}
+
+	r := t.record
+	r.Blocks = int32(len(t.blocks))
+	r.Bytes = int64(body.Len())
+	r.Complete = err == nil
+	r.Duration = t.last.Sub(t.start).Nanoseconds()
+
+	if conf != nil && conf.FileStorage != "" && (err == nil || conf.WriteIncomplete) {
+		conv := &core.ConversationInfo{
+			Ident:             utils.CreateFlowIdent(r.ClientIP, strconv.Itoa(int(r.ClientPort)), r.ServerIP, strconv.Itoa(int(r.ServerPort))),
+			FirstClientPacket: t.start,
+			FirstServerPacket: t.start,
+			ClientIP:          r.ClientIP,
+			ServerIP:          r.ServerIP,
+			ClientPort:        r.ClientPort,
+			ServerPort:        r.ServerPort,
+		}
+
+		errSave := streamutils.SaveFile(
+			conv,
+			"TFTP "+r.Operation,
+			tftpFileName(r.Filename),
+			err,
+			body.Bytes(),
+			nil,
+			r.ServerIP,
+			"",
+		)
+		if errSave != nil {
+			decoderLog.Error("failed to save TFTP file", zap.Error(errSave), zap.String("file", r.Filename))
+		}
+	}
+
+	return r
+}
+
+// tftpFileName strips directories from the requested file name,
+// to make sure files are not written outside of the storage directory.
+func tftpFileName(name string) string {
+	return path.Base(strings.ReplaceAll(name, "\\", "/"))
+}
@@ -0,0 +1,192 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func tftpPacket(t *testing.T, srcIP, dstIP string, srcPort, dstPort uint16, payload []byte) gopacket.Packet {
+	t.Helper()
+
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP(srcIP),
+		DstIP:    net.ParseIP(dstIP),
+	}
+	udp := &layers.UDP{
+		SrcPort: layers.UDPPort(srcPort),
+		DstPort: layers.UDPPort(dstPort),
+	}
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+
+	if err := gopacket.SerializeLayers(buf, opts, ip, udp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+	p.Metadata().Timestamp = time.Now()
+
+	return p
+}
+
+func tftpMessage(op uint16, fields ...[]byte) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, op)
+
+	for _, f := range fields {
+		b = append(b, f...)
+	}
+
+	return b
+}
+
+func tftpBlock(num uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, num)
+
+	return b
+}
+
+func TestTFTPReadRequest(t *testing.T) {
+	const (
+		client = "10.0.0.2"
+		server = "10.0.0.1"
+	)
+
+	var (
+		first  = bytes.Repeat([]byte("a"), 1024)
+		second = []byte("hostname router1\n")
+	)
+
+	packets := []gopacket.Packet{
+		tftpPacket(t, client, server, 50000, tftpPort, tftpMessage(tftpRRQ, []byte("configs/router1.cfg\x00octet\x00blksize\x001024\x00tsize\x000\x00"))),
+		tftpPacket(t, server, client, 42000, 50000, tftpMessage(tftpOACK, []byte("blksize\x001024\x00tsize\x001041\x00"))),
+		tftpPacket(t, client, server, 50000, 42000, tftpMessage(tftpACK, tftpBlock(0))),
+		// the final block arrives before the first one
+		tftpPacket(t, server, client, 42000, 50000, tftpMessage(tftpDATA, tftpBlock(2), second)),
+		// data from an unexpected port must be ignored
+		tftpPacket(t, server, client, 42001, 50000, tftpMessage(tftpDATA, tftpBlock(1), []byte("bogus"))),
+		tftpPacket(t, server, client, 42000, 50000, tftpMessage(tftpDATA, tftpBlock(1), first)),
+	}
+
+	var record *types.TFTP
+
+	for i, p := range packets {
+		if r := tftpDecoder.Handler(p); r != nil {
+			if i != len(packets)-1 {
+				t.Fatal("transfer completed early at packet", i)
+			}
+
+			record = r.(*types.TFTP)
+		}
+	}
+
+	if record == nil {
+		t.Fatal("transfer did not complete")
+	}
+
+	if record.Operation != "RRQ" || record.Filename != "configs/router1.cfg" || record.Mode != "octet" {
+		t.Fatal("unexpected request values", record.Operation, record.Filename, record.Mode)
+	}
+
+	if record.BlockSize != 1024 || record.TransferSize != 1041 {
+		t.Fatal("unexpected options", record.BlockSize, record.TransferSize, record.Options)
+	}
+
+	if !record.Complete || record.Blocks != 2 || record.Bytes != int64(len(first)+len(second)) {
+		t.Fatal("unexpected transfer values", record.Complete, record.Blocks, record.Bytes)
+	}
+
+	if record.ServerPort != 42000 || record.ClientPort != 50000 {
+		t.Fatal("unexpected ports", record.ClientPort, record.ServerPort)
+	}
+
+	if len(tftpTransfers.items) != 0 {
+		t.Fatal("transfer has not been removed")
+	}
+}
+
+func TestTFTPIgnoredOptions(t *testing.T) {
+	const (
+		client = "10.0.0.2"
+		server = "10.0.0.1"
+	)
+
+	var (
+		block = bytes.Repeat([]byte("b"), tftpDefaultBlockSize)
+		last  = []byte("end\n")
+	)
+
+	// the server does not send an OACK and falls back to the default block size
+	packets := []gopacket.Packet{
+		tftpPacket(t, client, server, 50001, tftpPort, tftpMessage(tftpRRQ, []byte("firmware.bin\x00octet\x00blksize\x001468\x00tsize\x000\x00"))),
+		tftpPacket(t, server, client, 42002, 50001, tftpMessage(tftpDATA, tftpBlock(1), block)),
+		tftpPacket(t, client, server, 50001, 42002, tftpMessage(tftpACK, tftpBlock(1))),
+		tftpPacket(t, server, client, 42002, 50001, tftpMessage(tftpDATA, tftpBlock(2), block)),
+		tftpPacket(t, client, server, 50001, 42002, tftpMessage(tftpACK, tftpBlock(2))),
+		tftpPacket(t, server, client, 42002, 50001, tftpMessage(tftpDATA, tftpBlock(3), last)),
+	}
+
+	var record *types.TFTP
+
+	for i, p := range packets {
+		if r := tftpDecoder.Handler(p); r != nil {
+			if i != len(packets)-1 {
+				t.Fatal("transfer completed early at packet", i)
+			}
+
+			record = r.(*types.TFTP)
+		}
+	}
+
+	if record == nil {
+		t.Fatal("transfer did not complete")
+	}
+
+	if record.BlockSize != tftpDefaultBlockSize || record.TransferSize != 0 || len(record.Options) != 2 || record.Options[0] != "blksize=1468" {
+		t.Fatal("unexpected options", record.BlockSize, record.TransferSize, record.Options)
+	}
+
+	if !record.Complete || record.Blocks != 3 || record.Bytes != int64(2*len(block)+len(last)) {
+		t.Fatal("unexpected transfer values", record.Complete, record.Blocks, record.Bytes)
+	}
+}
+
+func TestTFTPFileName(t *testing.T) {
+	if n := tftpFileName("../../etc/passwd"); n != "passwd" {
+		t.Fatal("unexpected file name", n)
+	}
+
+	if n := tftpFileName("pxelinux.cfg\\default"); n != "default" {
+		t.Fatal("unexpected file name", n)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// USB descriptor types and the HID interface class.
+const (
+	usbDescriptorConfiguration = 2
+	usbDescriptorInterface     = 4
+	usbDescriptorEndpoint      = 5
+
+	usbClassHID = 3
+)
+
+// HID boot protocol devices, as announced in the interface descriptor.
+const (
+	usbHIDUnknown = iota
+	usbHIDKeyboard
+	usbHIDMouse
+)
+
+// usbEndpoint identifies an endpoint of a device on a bus.
+type usbEndpoint struct {
+	bus      uint16
+	device   uint8
+	endpoint uint8
+}
+
+// usbHIDDevices maps interrupt endpoints to the kind of HID device behind them.
+type usbHIDDevices struct {
+	sync.Mutex
+	items map[usbEndpoint]int
+}
+
+var usbHID = &usbHIDDevices{
+	items: make(map[usbEndpoint]int),
+}
+
+// usbTransferData returns the USB layer and the transferred data of a packet.
+func usbTransferData(p gopacket.Packet) (*layers.USB, []byte) {
+	u, ok := p.Layer(layers.LayerTypeUSB).(*layers.USB)
+	if !ok || !u.Data || u.Setup {
+		return nil, nil
+	}
+
+	return u, u.LayerPayload()
+}
+
+// addConfiguration registers the HID endpoints from a configuration descriptor returned by a device.
+func (h *usbHIDDevices) addConfiguration(bus uint16, device uint8, data []byte) {
+	if len(data) < 9 || data[0] != 9 || data[1] != usbDescriptorConfiguration {
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	kind := usbHIDUnknown
+
+	for len(data) >= 2 {
+		size := int(data[0])
+		if size < 2 || size > len(data) {
+			return
+		}
+
+		switch {
+		case data[1] == usbDescriptorInterface && size >= 9:
+			kind = usbHIDUnknown
+
+			// boot interface subclass
+			if data[5] == usbClassHID && data[6] == 1 {
+				kind = int(data[7])
+			}
+		case data[1] == usbDescriptorEndpoint && size >= 7:
+			// interrupt IN endpoints of keyboards and mice
+			if kind != usbHIDUnknown && data[2]&0x80 != 0 && data[3]&0x03 == 0x03 {
+				h.items[usbEndpoint{bus: bus, device: device, endpoint: data[2] & 0x7f}] = kind
+			}
+		}
+
+		data = data[size:]
+	}
+}
+
+// kind returns the device kind for a report, if the endpoint has not been announced in a configuration descriptor,
+// it is guessed from the report format: keyboard boot reports have 8 bytes with a reserved zero byte after the modifiers.
+func (h *usbHIDDevices) kind(e usbEndpoint, report []byte) int {
+	h.Lock()
+	defer h.Unlock()
+
+	if k, ok := h.items[e]; ok {
+		return k
+	}
+
+	var k int
+
+	switch {
+	case len(report) == 8 && report[1] == 0:
+		k = usbHIDKeyboard
+	case len(report) >= 3 && len(report) <= 8:
+		k = usbHIDMouse
+	default:
+		return usbHIDUnknown
+	}
+
+	h.items[e] = k
+
+	return k
+}
+
+// usbHIDReport returns the endpoint and data of a completed interrupt transfer from the device to the host,
+// configuration descriptors are registered along the way.
+func usbHIDReport(p gopacket.Packet) (usbEndpoint, []byte, bool) {
+	u, data := usbTransferData(p)
+	if u == nil || len(data) == 0 || u.EventType != layers.USBEventTypeComplete || u.Direction != layers.USBDirectionTypeIn {
+		return usbEndpoint{}, nil, false
+	}
+
+	switch u.TransferType {
+	case layers.USBTransportTypeControl:
+		usbHID.addConfiguration(u.BusID, u.DeviceAddress, data)
+	case layers.USBTransportTypeInterrupt:
+		return usbEndpoint{bus: u.BusID, device: u.DeviceAddress, endpoint: u.EndpointNumber}, data, true
+	}
+
+	return usbEndpoint{}, nil, false
+}
+
+// usbMousePositions tracks the position of each mouse relative to its first report.
+var usbMousePositions = struct {
+	sync.Mutex
+	items map[usbEndpoint][2]int64
+}{
+	items: make(map[usbEndpoint][2]int64),
+}
+
+// decodeUSBMouse decodes a mouse boot protocol report: buttons, X and Y displacement and an optional wheel.
+func decodeUSBMouse(e usbEndpoint, report []byte) *types.USBMouse {
+	m := &types.USBMouse{
+		BusID:          int32(e.bus),
+		DeviceAddress:  int32(e.device),
+		EndpointNumber: int32(e.endpoint),
+		Buttons:        int32(report[0] & 0x07),
+		DX:             int32(int8(report[1])),
+		DY:             int32(int8(report[2])),
+	}
+
+	if len(report) > 3 {
+		m.Wheel = int32(int8(report[3]))
+	}
+
+	usbMousePositions.Lock()
+	pos := usbMousePositions.items[e]
+	pos[0] += int64(m.DX)
+	pos[1] += int64(m.DY)
+	usbMousePositions.items[e] = pos
+	usbMousePositions.Unlock()
+
+	m.X, m.Y = pos[0], pos[1]
+
+	return m
+}
+
+var usbMouseDecoder = newPacketDecoder(
+	types.Type_NC_USBMouse,
+	"USBMouse",
+	"Movements and button states of USB mice, decoded from HID boot protocol reports",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		e, report, ok := usbHIDReport(p)
+		if !ok || len(report) < 3 || usbHID.kind(e, report) != usbHIDMouse {
+			return nil
+		}
+
+		m := decodeUSBMouse(e, report)
+		m.Timestamp = p.Metadata().Timestamp.UnixNano()
+
+		return m
+	},
+	nil,
+)
@@ -0,0 +1,173 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// testUSBPacket returns a packet with a Linux usbmon header for a transfer carrying data.
+func testUSBPacket(event layers.USBEventType, transfer layers.USBTransportType, endpoint byte, data []byte) gopacket.Packet {
+	header := make([]byte, 48)
+	header[8] = byte(event)
+	header[9] = byte(transfer)
+	header[10] = endpoint
+	header[11] = 5
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	header[14] = '-'
+	binary.LittleEndian.PutUint32(header[32:36], uint32(len(data)))
+	binary.LittleEndian.PutUint32(header[36:40], uint32(len(data)))
+
+	return gopacket.NewPacket(append(header, data...), layers.LayerTypeUSB, gopacket.Default)
+}
+
+func TestUSBHIDConfiguration(t *testing.T) {
+	h := &usbHIDDevices{items: make(map[usbEndpoint]int)}
+
+	h.addConfiguration(1, 5, []byte{
+		0x09, 0x02, 0x3b, 0x00, 0x02, 0x01, 0x00, 0xa0, 0x32,
+		// keyboard interface, HID descriptor and interrupt IN endpoint 1
+		0x09, 0x04, 0x00, 0x00, 0x01, 0x03, 0x01, 0x01, 0x00,
+		0x09, 0x21, 0x11, 0x01, 0x00, 0x01, 0x22, 0x41, 0x00,
+		0x07, 0x05, 0x81, 0x03, 0x08, 0x00, 0x0a,
+		// mouse interface and interrupt IN endpoint 2
+		0x09, 0x04, 0x01, 0x00, 0x01, 0x03, 0x01, 0x02, 0x00,
+		0x07, 0x05, 0x82, 0x03, 0x04, 0x00, 0x0a,
+	})
+
+	// announced keyboards are recognized regardless of the report format
+	if k := h.kind(usbEndpoint{bus: 1, device: 5, endpoint: 1}, []byte{1, 2, 3, 4}); k != usbHIDKeyboard {
+		t.Fatal("expected keyboard, got", k)
+	}
+
+	if k := h.kind(usbEndpoint{bus: 1, device: 5, endpoint: 2}, []byte{0, 0, 0, 0, 0, 0, 0, 0}); k != usbHIDMouse {
+		t.Fatal("expected mouse, got", k)
+	}
+
+	if k := h.kind(usbEndpoint{bus: 2, device: 1, endpoint: 1}, []byte{0x02, 0, 0x0b, 0, 0, 0, 0, 0}); k != usbHIDKeyboard {
+		t.Fatal("expected guessed keyboard, got", k)
+	}
+
+	if k := h.kind(usbEndpoint{bus: 2, device: 2, endpoint: 1}, []byte{0x01, 0xfe, 0x03}); k != usbHIDMouse {
+		t.Fatal("expected guessed mouse, got", k)
+	}
+
+	if k := h.kind(usbEndpoint{bus: 2, device: 3, endpoint: 1}, []byte{0x02}); k != usbHIDUnknown {
+		t.Fatal("expected unknown device, got", k)
+	}
+}
+
+// testKeyboardReports feeds keyboard reports with the given modifiers and key to the keyboard,
+// each followed by a report releasing all keys.
+func testKeyboardReports(k *usbKeyboard, reports ...[2]byte) {
+	ts := time.Unix(1600000000, 0)
+
+	for _, r := range reports {
+		k.report([]byte{r[0], 0, r[1], 0, 0, 0, 0, 0}, ts)
+		k.report(make([]byte, 8), ts)
+	}
+}
+
+func TestUSBKeyboard(t *testing.T) {
+	k := newUSBKeyboard(usbEndpoint{bus: 1, device: 5, endpoint: 1}, time.Unix(1600000000, 0))
+
+	testKeyboardReports(k,
+		[2]byte{usbModLeftShift, 0x0b},  // H
+		[2]byte{0, 0x0c},                // i
+		[2]byte{usbModRightShift, 0x1e}, // !
+		[2]byte{0, 0x2a},                // backspace
+		[2]byte{usbModLeftCtrl, 0x06},   // ctrl+c
+		[2]byte{0, 0x39},                // caps lock
+		[2]byte{0, 0x04},                // A
+		[2]byte{usbModLeftShift, 0x05},  // b
+		[2]byte{0, 0x28},                // enter
+	)
+
+	r := k.finish()
+
+	if r.Layout != "us" || r.NumKeystrokes != 9 {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+
+	if r.Text != "HiAb\n" {
+		t.Fatalf("unexpected text: %q", r.Text)
+	}
+
+	if r.Keys != "Hi!<BACKSPACE><CTRL+c><CAPSLOCK>Ab<ENTER>" {
+		t.Fatalf("unexpected keys: %q", r.Keys)
+	}
+
+	// keys held down over several reports are only counted once
+	k = newUSBKeyboard(usbEndpoint{}, time.Unix(1600000000, 0))
+	k.report([]byte{0, 0, 0x04, 0, 0, 0, 0, 0}, time.Unix(1600000000, 0))
+	k.report([]byte{0, 0, 0x04, 0x05, 0, 0, 0, 0}, time.Unix(1600000000, 0))
+	k.report([]byte{0, 0, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01}, time.Unix(1600000000, 0))
+
+	if r = k.finish(); r.Text != "ab" || r.NumKeystrokes != 2 {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
+func TestUSBKeyboardLayout(t *testing.T) {
+	k := newUSBKeyboard(usbEndpoint{}, time.Unix(1600000000, 0))
+	k.layout = usbKeyboardLayouts["de"]
+
+	testKeyboardReports(k,
+		[2]byte{0, 0x1c},               // z
+		[2]byte{usbModRightAlt, 0x14},  // @
+		[2]byte{usbModLeftShift, 0x24}, // /
+		[2]byte{0, 0x33},               // ö
+		[2]byte{usbModRightAlt, 0x04},  // a, no AltGr mapping
+	)
+
+	if r := k.finish(); r.Text != "z@/öa" {
+		t.Fatalf("unexpected text: %q", r.Text)
+	}
+}
+
+func TestUSBMouseDecoder(t *testing.T) {
+	var (
+		reports = [][]byte{{0x00, 0x05, 0xfe, 0x00}, {0x01, 0x03, 0x02, 0xff}}
+		x, y    int64
+	)
+
+	for i, report := range reports {
+		p := testUSBPacket(layers.USBEventTypeComplete, layers.USBTransportTypeInterrupt, 0x83, report)
+
+		m, ok := usbMouseDecoder.Handler(p).(*types.USBMouse)
+		if !ok {
+			t.Fatal("expected mouse record for report", i)
+		}
+
+		x += int64(int8(report[1]))
+		y += int64(int8(report[2]))
+
+		if m.Buttons != int32(report[0]) || m.X != x || m.Y != y || m.Wheel != int32(int8(report[3])) {
+			t.Fatalf("unexpected record for report %d: %+v", i, m)
+		}
+	}
+
+	// reports sent to the device are ignored
+	p := testUSBPacket(layers.USBEventTypeSubmit, layers.USBTransportTypeInterrupt, 0x03, reports[0])
+	if usbMouseDecoder.Handler(p) != nil {
+		t.Fatal("expected no record for outgoing transfer")
+	}
+}
@@ -0,0 +1,322 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// modifier bits of a keyboard boot report.
+const (
+	usbModLeftCtrl = 1 << iota
+	usbModLeftShift
+	usbModLeftAlt
+	usbModLeftGUI
+	usbModRightCtrl
+	usbModRightShift
+	usbModRightAlt
+	usbModRightGUI
+)
+
+const (
+	usbKeyErrorRollOver = 0x01
+	usbKeyEnter         = 0x28
+	usbKeyBackspace     = 0x2a
+	usbKeyTab           = 0x2b
+	usbKeyCapsLock      = 0x39
+	usbKeyKeypadEnter   = 0x58
+
+	usbDefaultLayout = "us"
+)
+
+// usbKeys contains the characters for HID keyboard usages,
+// unshifted, shifted and with AltGr, for the US layout.
+var usbKeys = map[byte][3]string{
+	0x1e: {"1", "!"}, 0x1f: {"2", "@"}, 0x20: {"3", "#"}, 0x21: {"4", "$"}, 0x22: {"5", "%"},
+	0x23: {"6", "^"}, 0x24: {"7", "&"}, 0x25: {"8", "*"}, 0x26: {"9", "("}, 0x27: {"0", ")"},
+	0x2c: {" ", " "}, 0x2d: {"-", "_"}, 0x2e: {"=", "+"}, 0x2f: {"[", "{"}, 0x30: {"]", "}"},
+	0x31: {"\\", "|"}, 0x32: {"#", "~"}, 0x33: {";", ":"}, 0x34: {"'", "\""}, 0x35: {"`", "~"},
+	0x36: {",", "<"}, 0x37: {".", ">"}, 0x38: {"/", "?"},
+	0x54: {"/", "/"}, 0x55: {"*", "*"}, 0x56: {"-", "-"}, 0x57: {"+", "+"},
+	0x59: {"1", "1"}, 0x5a: {"2", "2"}, 0x5b: {"3", "3"}, 0x5c: {"4", "4"}, 0x5d: {"5", "5"},
+	0x5e: {"6", "6"}, 0x5f: {"7", "7"}, 0x60: {"8", "8"}, 0x61: {"9", "9"}, 0x62: {"0", "0"},
+	0x63: {".", "."}, 0x64: {"\\", "|"},
+}
+
+// usbKeyNames contains the names of non printable keys.
+var usbKeyNames = map[byte]string{
+	0x28: "ENTER", 0x29: "ESC", 0x2a: "BACKSPACE", 0x2b: "TAB", 0x39: "CAPSLOCK",
+	0x3a: "F1", 0x3b: "F2", 0x3c: "F3", 0x3d: "F4", 0x3e: "F5", 0x3f: "F6",
+	0x40: "F7", 0x41: "F8", 0x42: "F9", 0x43: "F10", 0x44: "F11", 0x45: "F12",
+	0x46: "PRINTSCREEN", 0x47: "SCROLLLOCK", 0x48: "PAUSE", 0x49: "INSERT", 0x4a: "HOME", 0x4b: "PAGEUP",
+	0x4c: "DELETE", 0x4d: "END", 0x4e: "PAGEDOWN", 0x4f: "RIGHT", 0x50: "LEFT", 0x51: "DOWN", 0x52: "UP",
+	0x53: "NUMLOCK", 0x58: "ENTER", 0x65: "MENU",
+}
+
+// usbKeyboardLayouts contains the differences of other layouts to the US layout.
+var usbKeyboardLayouts = map[string]map[byte][3]string{
+	"us": {},
+	"uk": {
+		0x1f: {"2", "\""}, 0x20: {"3", "£"}, 0x34: {"'", "@"}, 0x32: {"#", "~"},
+		0x35: {"`", "¬"}, 0x64: {"\\", "|"},
+	},
+	"de": {
+		0x1c: {"z", "Z"}, 0x1d: {"y", "Y"}, 0x14: {"q", "Q", "@"}, 0x08: {"e", "E", "€"}, 0x10: {"m", "M", "µ"},
+		0x1f: {"2", "\"", "²"}, 0x20: {"3", "§", "³"}, 0x23: {"6", "&"}, 0x24: {"7", "/", "{"},
+		0x25: {"8", "(", "["}, 0x26: {"9", ")", "]"}, 0x27: {"0", "=", "}"},
+		0x2d: {"ß", "?", "\\"}, 0x2e: {"´", "`"}, 0x2f: {"ü", "Ü"}, 0x30: {"+", "*", "~"},
+		0x32: {"#", "'"}, 0x33: {"ö", "Ö"}, 0x34: {"ä", "Ä"}, 0x35: {"^", "°"},
+		0x36: {",", ";"}, 0x37: {".", ":"}, 0x38: {"-", "_"}, 0x64: {"<", ">", "|"},
+	},
+}
+
+// usbKeyboardLayout returns the configured layout name and its keys.
+func usbKeyboardLayout() (string, map[byte][3]string) {
+	name := usbDefaultLayout
+	if conf != nil && conf.USBKeyboardLayout != "" {
+		name = strings.ToLower(conf.USBKeyboardLayout)
+	}
+
+	layout, ok := usbKeyboardLayouts[name]
+	if !ok {
+		decoderLog.Warn("unknown USB keyboard layout, using the default", zap.String("layout", name))
+
+		return usbDefaultLayout, usbKeyboardLayouts[usbDefaultLayout]
+	}
+
+	return name, layout
+}
+
+// usbKeyboard collects the keystrokes of a keyboard.
+type usbKeyboard struct {
+	record   *types.USBKeystrokes
+	layout   map[byte][3]string
+	previous []byte
+	capsLock bool
+	text     strings.Builder
+	keys     strings.Builder
+}
+
+func newUSBKeyboard(e usbEndpoint, ts time.Time) *usbKeyboard {
+	name, layout := usbKeyboardLayout()
+
+	return &usbKeyboard{
+		record: &types.USBKeystrokes{
+			Timestamp:      ts.UnixNano(),
+			BusID:          int32(e.bus),
+			DeviceAddress:  int32(e.device),
+			EndpointNumber: int32(e.endpoint),
+			Layout:         name,
+		},
+		layout: layout,
+	}
+}
+
+// character returns the character produced by a key, or an empty string for non printable keys.
+func (k *usbKeyboard) character(key byte, shift, altGr bool) string {
+	var chars [3]string
+
+	switch {
+	case key >= 0x04 && key <= 0x1d:
+		letter := string(rune('a' + key - 0x04))
+		chars = [3]string{letter, strings.ToUpper(letter)}
+	default:
+		chars = usbKeys[key]
+	}
+
+	if c, ok := k.layout[key]; ok {
+		chars = c
+	}
+
+	if altGr && chars[2] != "" {
+		return chars[2]
+	}
+
+	// caps lock only affects letters
+	if r, _ := utf8.DecodeRuneInString(chars[0]); k.capsLock && unicode.IsLetter(r) {
+		shift = !shift
+	}
+
+	if shift {
+		return chars[1]
+	}
+
+	return chars[0]
+}
+
+// report processes a keyboard boot report: modifiers, a reserved byte and up to six pressed keys.
+// Only keys that were not pressed in the previous report are new keystrokes.
+func (k *usbKeyboard) report(report []byte, ts time.Time) {
+	if len(report) < 3 || report[2] == usbKeyErrorRollOver {
+		return
+	}
+
+	var (
+		mods  = report[0]
+		shift = mods&(usbModLeftShift|usbModRightShift) != 0
+		altGr = mods&usbModRightAlt != 0
+		// AltGr is handled by the layout, other modifiers than shift form shortcuts
+		shortcut = mods&(usbModLeftCtrl|usbModRightCtrl|usbModLeftAlt|usbModLeftGUI|usbModRightGUI) != 0
+	)
+
+	for _, key := range report[2:] {
+		if key == 0 || containsByte(k.previous, key) {
+			continue
+		}
+
+		k.record.NumKeystrokes++
+		k.record.LastSeen = ts.UnixNano()
+
+		c := k.character(key, shift, altGr)
+
+		switch {
+		case shortcut:
+			if c == "" {
+				c = usbKeyNames[key]
+			}
+
+			if c != "" {
+				k.keys.WriteString("<" + usbModifierNames(mods) + c + ">")
+			}
+		case c != "":
+			k.keys.WriteString(c)
+			k.text.WriteString(c)
+		default:
+			if name, ok := usbKeyNames[key]; ok {
+				k.keys.WriteString("<" + name + ">")
+			}
+
+			k.special(key)
+		}
+	}
+
+	k.previous = append(k.previous[:0], report[2:]...)
+}
+
+// special applies the effect of a non printable key to the typed text.
+func (k *usbKeyboard) special(key byte) {
+	switch key {
+	case usbKeyEnter, usbKeyKeypadEnter:
+		k.text.WriteString("\n")
+	case usbKeyTab:
+		k.text.WriteString("\t")
+	case usbKeyCapsLock:
+		k.capsLock = !k.capsLock
+	case usbKeyBackspace:
+		text := k.text.String()
+		if text == "" {
+			return
+		}
+
+		_, size := utf8.DecodeLastRuneInString(text)
+
+		k.text.Reset()
+		k.text.WriteString(text[:len(text)-size])
+	}
+}
+
+// finish returns the audit record with the collected keystrokes.
+func (k *usbKeyboard) finish() *types.USBKeystrokes {
+	k.record.Text = k.text.String()
+	k.record.Keys = k.keys.String()
+
+	return k.record
+}
+
+// usbModifierNames returns the names of the pressed modifiers other than shift, followed by a plus sign.
+func usbModifierNames(mods byte) string {
+	var b strings.Builder
+
+	if mods&(usbModLeftCtrl|usbModRightCtrl) != 0 {
+		b.WriteString("CTRL+")
+	}
+
+	if mods&usbModLeftAlt != 0 {
+		b.WriteString("ALT+")
+	}
+
+	if mods&(usbModLeftGUI|usbModRightGUI) != 0 {
+		b.WriteString("GUI+")
+	}
+
+	return b.String()
+}
+
+func containsByte(list []byte, v byte) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usbKeyboards contains the keyboards seen in the capture.
+var usbKeyboards = struct {
+	sync.Mutex
+	items map[usbEndpoint]*usbKeyboard
+}{
+	items: make(map[usbEndpoint]*usbKeyboard),
+}
+
+var usbKeystrokesDecoder = newPacketDecoder(
+	types.Type_NC_USBKeystrokes,
+	"USBKeystrokes",
+	"Text typed on USB keyboards, reconstructed from HID boot protocol reports using the configured keyboard layout",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		e, report, ok := usbHIDReport(p)
+		if !ok || usbHID.kind(e, report) != usbHIDKeyboard {
+			return nil
+		}
+
+		ts := p.Metadata().Timestamp
+
+		usbKeyboards.Lock()
+		defer usbKeyboards.Unlock()
+
+		k, ok := usbKeyboards.items[e]
+		if !ok {
+			k = newUSBKeyboard(e, ts)
+			usbKeyboards.items[e] = k
+		}
+
+		k.report(report, ts)
+
+		return nil
+	},
+	func(d *Decoder) error {
+		usbKeyboards.Lock()
+		defer usbKeyboards.Unlock()
+
+		for _, k := range usbKeyboards.items {
+			if k.record.NumKeystrokes > 0 {
+				d.write(k.finish())
+			}
+		}
+
+		return nil
+	},
+)
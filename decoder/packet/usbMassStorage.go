@@ -0,0 +1,387 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// bulk-only transport wrappers, see the USB Mass Storage Class Bulk-Only Transport specification.
+const (
+	usbCBWSignature = 0x43425355 // USBC
+	usbCSWSignature = 0x53425355 // USBS
+	usbCBWLength    = 31
+	usbCSWLength    = 13
+
+	usbStorageDefaultBlockSize = 512
+
+	// reads and writes of contiguous blocks are merged into a single file up to this size
+	usbStorageMaxExtent = 64 << 20
+)
+
+// SCSI operation codes.
+const (
+	scsiInquiry         = 0x12
+	scsiReadCapacity10  = 0x25
+	scsiServiceAction16 = 0x9e
+	scsiRead6           = 0x08
+	scsiWrite6          = 0x0a
+	scsiRead10          = 0x28
+	scsiWrite10         = 0x2a
+	scsiRead12          = 0xa8
+	scsiWrite12         = 0xaa
+	scsiRead16          = 0x88
+	scsiWrite16         = 0x8a
+)
+
+var scsiCommands = map[byte]string{
+	0x00:                "TEST UNIT READY",
+	0x03:                "REQUEST SENSE",
+	0x1a:                "MODE SENSE(6)",
+	0x1b:                "START STOP UNIT",
+	0x1e:                "PREVENT ALLOW MEDIUM REMOVAL",
+	0x23:                "READ FORMAT CAPACITIES",
+	0x2f:                "VERIFY(10)",
+	0x35:                "SYNCHRONIZE CACHE(10)",
+	0x5a:                "MODE SENSE(10)",
+	scsiInquiry:         "INQUIRY",
+	scsiReadCapacity10:  "READ CAPACITY(10)",
+	scsiServiceAction16: "SERVICE ACTION IN(16)",
+	scsiRead6:           "READ(6)",
+	scsiWrite6:          "WRITE(6)",
+	scsiRead10:          "READ(10)",
+	scsiWrite10:         "WRITE(10)",
+	scsiRead12:          "READ(12)",
+	scsiWrite12:         "WRITE(12)",
+	scsiRead16:          "READ(16)",
+	scsiWrite16:         "WRITE(16)",
+}
+
+// scsiBlocks returns the logical block address and number of blocks of a read or write command block.
+func scsiBlocks(cb []byte) (lba uint64, blocks uint32, write bool, ok bool) {
+	if len(cb) == 0 {
+		return 0, 0, false, false
+	}
+
+	switch cb[0] {
+	case scsiRead6, scsiWrite6:
+		if len(cb) < 6 {
+			return 0, 0, false, false
+		}
+
+		lba = uint64(cb[1]&0x1f)<<16 | uint64(cb[2])<<8 | uint64(cb[3])
+
+		// a transfer length of zero means 256 blocks
+		blocks = uint32(cb[4])
+		if blocks == 0 {
+			blocks = 256
+		}
+	case scsiRead10, scsiWrite10:
+		if len(cb) < 10 {
+			return 0, 0, false, false
+		}
+
+		lba = uint64(binary.BigEndian.Uint32(cb[2:6]))
+		blocks = uint32(binary.BigEndian.Uint16(cb[7:9]))
+	case scsiRead12, scsiWrite12:
+		if len(cb) < 12 {
+			return 0, 0, false, false
+		}
+
+		lba = uint64(binary.BigEndian.Uint32(cb[2:6]))
+		blocks = binary.BigEndian.Uint32(cb[6:10])
+	case scsiRead16, scsiWrite16:
+		if len(cb) < 16 {
+			return 0, 0, false, false
+		}
+
+		lba = binary.BigEndian.Uint64(cb[2:10])
+		blocks = binary.BigEndian.Uint32(cb[10:14])
+	default:
+		return 0, 0, false, false
+	}
+
+	write = cb[0] == scsiWrite6 || cb[0] == scsiWrite10 || cb[0] == scsiWrite12 || cb[0] == scsiWrite16
+
+	return lba, blocks, write, true
+}
+
+// usbStorageCommand is a SCSI command in progress.
+type usbStorageCommand struct {
+	start    time.Time
+	tag      uint32
+	expected uint32
+	in       bool
+	lun      uint8
+	cb       []byte
+	data     bytes.Buffer
+}
+
+// usbStorageExtent contains the data of contiguous blocks read from or written to a device.
+type usbStorageExtent struct {
+	start    time.Time
+	lun      uint8
+	write    bool
+	firstLBA uint64
+	nextLBA  uint64
+	data     bytes.Buffer
+}
+
+// usbStorageDevice is a mass storage device using the bulk-only transport.
+type usbStorageDevice struct {
+	bus       uint16
+	address   uint8
+	vendor    string
+	product   string
+	revision  string
+	blockSize uint32
+	command   *usbStorageCommand
+	extent    *usbStorageExtent
+}
+
+// usbStorageDevices contains the mass storage devices seen in the capture.
+var usbStorageDevices = struct {
+	sync.Mutex
+	items map[usbEndpoint]*usbStorageDevice
+}{
+	items: make(map[usbEndpoint]*usbStorageDevice),
+}
+
+// transfer processes a bulk transfer to or from the device and returns an audit record once a command completed.
+func (s *usbStorageDevice) transfer(u *layers.USB, data []byte, ts time.Time) *types.USBStorage {
+	switch {
+	case u.Direction == layers.USBDirectionTypeOut && u.EventType == layers.USBEventTypeSubmit:
+		if len(data) == usbCBWLength && binary.LittleEndian.Uint32(data[0:4]) == usbCBWSignature {
+			cbLength := int(data[14] & 0x1f)
+			if cbLength > 16 {
+				cbLength = 16
+			}
+
+			s.command = &usbStorageCommand{
+				start:    ts,
+				tag:      binary.LittleEndian.Uint32(data[4:8]),
+				expected: binary.LittleEndian.Uint32(data[8:12]),
+				in:       data[12]&0x80 != 0,
+				lun:      data[13] & 0x0f,
+				cb:       append([]byte(nil), data[15:15+cbLength]...),
+			}
+
+			return nil
+		}
+
+		if s.command != nil && !s.command.in {
+			s.command.add(data)
+		}
+	case u.Direction == layers.USBDirectionTypeIn && u.EventType == layers.USBEventTypeComplete:
+		if s.command == nil {
+			return nil
+		}
+
+		if len(data) == usbCSWLength && binary.LittleEndian.Uint32(data[0:4]) == usbCSWSignature &&
+			binary.LittleEndian.Uint32(data[4:8]) == s.command.tag {
+			r := s.complete(data)
+			s.command = nil
+
+			return r
+		}
+
+		if s.command.in {
+			s.command.add(data)
+		}
+	}
+
+	return nil
+}
+
+// add appends data transferred for the command, up to the length announced in the command block wrapper.
+func (c *usbStorageCommand) add(data []byte) {
+	if remaining := int(c.expected) - c.data.Len(); len(data) > remaining {
+		data = data[:remaining]
+	}
+
+	c.data.Write(data)
+}
+
+// complete handles the command status wrapper of the current command.
+func (s *usbStorageDevice) complete(csw []byte) *types.USBStorage {
+	var (
+		c    = s.command
+		data = c.data.Bytes()
+		r    = &types.USBStorage{
+			Timestamp:      c.start.UnixNano(),
+			BusID:          int32(s.bus),
+			DeviceAddress:  int32(s.address),
+			LUN:            int32(c.lun),
+			Tag:            c.tag,
+			ExpectedLength: c.expected,
+			Length:         int64(len(data)),
+			Residue:        binary.LittleEndian.Uint32(csw[8:12]),
+			Status:         int32(csw[12]),
+		}
+	)
+
+	if len(c.cb) > 0 {
+		r.Opcode = int32(c.cb[0])
+		r.Command = scsiCommands[c.cb[0]]
+	}
+
+	if r.Status == 0 {
+		switch r.Opcode {
+		case scsiInquiry:
+			if len(data) >= 36 {
+				s.vendor = strings.TrimSpace(string(data[8:16]))
+				s.product = strings.TrimSpace(string(data[16:32]))
+				s.revision = strings.TrimSpace(string(data[32:36]))
+			}
+		case scsiReadCapacity10:
+			if len(data) >= 8 {
+				s.blockSize = binary.BigEndian.Uint32(data[4:8])
+			}
+		case scsiServiceAction16:
+			// READ CAPACITY(16)
+			if len(c.cb) > 1 && c.cb[1]&0x1f == 0x10 && len(data) >= 12 {
+				s.blockSize = binary.BigEndian.Uint32(data[8:12])
+			}
+		}
+	}
+
+	r.Vendor, r.Product, r.Revision = s.vendor, s.product, s.revision
+	r.BlockSize = s.blockSize
+
+	if r.BlockSize == 0 {
+		r.BlockSize = usbStorageDefaultBlockSize
+	}
+
+	if lba, blocks, write, ok := scsiBlocks(c.cb); ok {
+		r.LBA, r.Blocks = lba, blocks
+
+		if r.Status == 0 && len(data) > 0 {
+			s.addExtent(c, lba, blocks, write, data)
+		}
+	}
+
+	return r
+}
+
+// addExtent appends the data of a read or write to the current extent if the blocks are contiguous,
+// otherwise the current extent is saved and a new one is started.
+func (s *usbStorageDevice) addExtent(c *usbStorageCommand, lba uint64, blocks uint32, write bool, data []byte) {
+	e := s.extent
+	if e == nil || e.write != write || e.lun != c.lun || e.nextLBA != lba || e.data.Len()+len(data) > usbStorageMaxExtent {
+		s.saveExtent()
+
+		e = &usbStorageExtent{
+			start:    c.start,
+			lun:      c.lun,
+			write:    write,
+			firstLBA: lba,
+		}
+		s.extent = e
+	}
+
+	e.nextLBA = lba + uint64(blocks)
+	e.data.Write(data)
+}
+
+// saveExtent writes the data of the current extent to disk and emits a file audit record.
+func (s *usbStorageDevice) saveExtent() {
+	e := s.extent
+	s.extent = nil
+
+	if e == nil || conf == nil || conf.FileStorage == "" {
+		return
+	}
+
+	op := "read"
+	if e.write {
+		op = "write"
+	}
+
+	conv := &core.ConversationInfo{
+		Ident:             fmt.Sprintf("usb-%d.%d-lun%d", s.bus, s.address, e.lun),
+		FirstClientPacket: e.start,
+		FirstServerPacket: e.start,
+	}
+
+	err := streamutils.SaveFile(
+		conv,
+		"USB Mass Storage "+strings.ToUpper(op),
+		fmt.Sprintf("%s-lba%d", op, e.firstLBA),
+		nil,
+		e.data.Bytes(),
+		nil,
+		strings.TrimSpace(s.vendor+" "+s.product),
+		"",
+	)
+	if err != nil {
+		decoderLog.Error("failed to save USB mass storage data", zap.Error(err), zap.String("ident", conv.Ident))
+	}
+}
+
+var usbStorageDecoder = newPacketDecoder(
+	types.Type_NC_USBStorage,
+	"USBStorage",
+	"SCSI commands sent to USB mass storage devices, the data of reads and writes is saved as files",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		u, data := usbTransferData(p)
+		if u == nil || u.TransferType != layers.USBTransportTypeBulk || len(data) == 0 {
+			return nil
+		}
+
+		key := usbEndpoint{bus: u.BusID, device: u.DeviceAddress}
+
+		usbStorageDevices.Lock()
+		defer usbStorageDevices.Unlock()
+
+		s, ok := usbStorageDevices.items[key]
+		if !ok {
+			s = &usbStorageDevice{
+				bus:     u.BusID,
+				address: u.DeviceAddress,
+			}
+			usbStorageDevices.items[key] = s
+		}
+
+		if r := s.transfer(u, data, p.Metadata().Timestamp); r != nil {
+			return r
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		usbStorageDevices.Lock()
+		defer usbStorageDevices.Unlock()
+
+		for _, s := range usbStorageDevices.items {
+			s.saveExtent()
+		}
+
+		return nil
+	},
+)
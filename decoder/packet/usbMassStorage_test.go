@@ -0,0 +1,148 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// testCBW returns a command block wrapper for the command block.
+func testCBW(tag uint32, length uint32, in bool, cb ...byte) []byte {
+	cbw := make([]byte, usbCBWLength)
+	binary.LittleEndian.PutUint32(cbw[0:4], usbCBWSignature)
+	binary.LittleEndian.PutUint32(cbw[4:8], tag)
+	binary.LittleEndian.PutUint32(cbw[8:12], length)
+
+	if in {
+		cbw[12] = 0x80
+	}
+
+	cbw[14] = byte(len(cb))
+	copy(cbw[15:], cb)
+
+	return cbw
+}
+
+// testCSW returns a command status wrapper.
+func testCSW(tag uint32, status byte) []byte {
+	csw := make([]byte, usbCSWLength)
+	binary.LittleEndian.PutUint32(csw[0:4], usbCSWSignature)
+	binary.LittleEndian.PutUint32(csw[4:8], tag)
+	csw[12] = status
+
+	return csw
+}
+
+// testStorageCommand runs a command with an optional data phase against the device and returns the audit record.
+func testStorageCommand(t *testing.T, s *usbStorageDevice, tag uint32, in bool, data []byte, cb ...byte) *types.USBStorage {
+	t.Helper()
+
+	var (
+		ts       = time.Unix(1600000000, 0)
+		transfer = func(event layers.USBEventType, endpoint byte, data []byte) *types.USBStorage {
+			p := testUSBPacket(event, layers.USBTransportTypeBulk, endpoint, data)
+
+			u, payload := usbTransferData(p)
+			if u == nil {
+				t.Fatal("USB layer missing")
+			}
+
+			return s.transfer(u, payload, ts)
+		}
+	)
+
+	if r := transfer(layers.USBEventTypeSubmit, 0x02, testCBW(tag, uint32(len(data)), in, cb...)); r != nil {
+		t.Fatal("unexpected record for command block wrapper")
+	}
+
+	if len(data) > 0 {
+		if in {
+			transfer(layers.USBEventTypeComplete, 0x81, data)
+		} else {
+			transfer(layers.USBEventTypeSubmit, 0x02, data)
+		}
+	}
+
+	r := transfer(layers.USBEventTypeComplete, 0x81, testCSW(tag, 0))
+	if r == nil {
+		t.Fatal("expected record for command status wrapper")
+	}
+
+	return r
+}
+
+func TestUSBStorage(t *testing.T) {
+	s := &usbStorageDevice{bus: 1, address: 5}
+
+	inquiry := make([]byte, 36)
+	copy(inquiry[8:], "Generic ")
+	copy(inquiry[16:], "Flash Disk      ")
+	copy(inquiry[32:], "8.07")
+
+	r := testStorageCommand(t, s, 1, true, inquiry, scsiInquiry, 0, 0, 0, 36, 0)
+	if r.Command != "INQUIRY" || r.Vendor != "Generic" || r.Product != "Flash Disk" || r.Revision != "8.07" {
+		t.Fatalf("unexpected inquiry: %+v", r)
+	}
+
+	r = testStorageCommand(t, s, 2, true, []byte{0, 0x3c, 0xff, 0xff, 0, 0, 0x10, 0}, scsiReadCapacity10, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	if r.Command != "READ CAPACITY(10)" || s.blockSize != 4096 {
+		t.Fatalf("unexpected capacity: %+v", r)
+	}
+
+	block := bytes.Repeat([]byte{0xaa}, 4096)
+
+	r = testStorageCommand(t, s, 3, false, block, scsiWrite10, 0, 0, 0, 0, 100, 0, 0, 1, 0)
+	if r.Command != "WRITE(10)" || r.LBA != 100 || r.Blocks != 1 || r.BlockSize != 4096 || r.Length != 4096 || r.Status != 0 {
+		t.Fatalf("unexpected write: %+v", r)
+	}
+
+	// contiguous writes are merged
+	testStorageCommand(t, s, 4, false, block, scsiWrite10, 0, 0, 0, 0, 101, 0, 0, 1, 0)
+
+	if s.extent == nil || !s.extent.write || s.extent.firstLBA != 100 || s.extent.nextLBA != 102 || s.extent.data.Len() != 8192 {
+		t.Fatalf("unexpected extent: %+v", s.extent)
+	}
+
+	r = testStorageCommand(t, s, 5, true, block, scsiRead16, 0, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 1, 0, 0)
+	if r.Command != "READ(16)" || r.LBA != 7 || r.Blocks != 1 || r.Length != 4096 {
+		t.Fatalf("unexpected read: %+v", r)
+	}
+
+	if s.extent == nil || s.extent.write || s.extent.firstLBA != 7 || s.extent.data.Len() != 4096 {
+		t.Fatalf("unexpected extent: %+v", s.extent)
+	}
+}
+
+func TestSCSIBlocks(t *testing.T) {
+	lba, blocks, write, ok := scsiBlocks([]byte{scsiWrite6, 0x01, 0x02, 0x03, 0x00, 0x00})
+	if !ok || !write || lba != 0x010203 || blocks != 256 {
+		t.Fatal("unexpected WRITE(6):", lba, blocks, write, ok)
+	}
+
+	lba, blocks, write, ok = scsiBlocks([]byte{scsiRead12, 0, 0, 0, 0x10, 0, 0, 0, 0, 0x20, 0, 0})
+	if !ok || write || lba != 0x1000 || blocks != 0x20 {
+		t.Fatal("unexpected READ(12):", lba, blocks, write, ok)
+	}
+
+	if _, _, _, ok = scsiBlocks([]byte{scsiInquiry, 0, 0, 0, 36, 0}); ok {
+		t.Fatal("expected no blocks for INQUIRY")
+	}
+}
@@ -215,9 +215,10 @@ func ShowDecoders(verbose bool) {
 	printDecoderStats("Stream", func() []core.DecoderAPI {
 		var res []core.DecoderAPI
 
-		for _, s := range stream.DefaultStreamDecoders {
+		// decoders registered for multiple ports are only listed once
+		stream.ApplyActionToStreamDecoders(func(s core.StreamDecoderAPI) {
 			res = append(res, s)
-		}
+		})
 
 		return res
 	}())
@@ -0,0 +1,183 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// SNAP protocol id of VTP messages
+	ethernetTypeVTP layers.EthernetType = 0x2003
+
+	vtpHeaderSize     = 4
+	vtpDomainSize     = 32
+	vtpSummarySize    = vtpHeaderSize + vtpDomainSize + 36
+	vtpSubsetSize     = vtpHeaderSize + vtpDomainSize + 4
+	vtpTimestampSize  = 12
+	vtpMD5Size        = 16
+	vtpVLANInfoSize   = 12
+	vtpVLANNameOffset = 12
+
+	// message codes
+	vtpSummaryAdvert = 1
+	vtpSubsetAdvert  = 2
+	vtpAdvertRequest = 3
+)
+
+var vtpCodes = map[byte]string{
+	vtpSummaryAdvert: "Summary Advertisement",
+	vtpSubsetAdvert:  "Subset Advertisement",
+	vtpAdvertRequest: "Advertisement Request",
+	4:                "Join",
+}
+
+// LayerTypeVTP is the gopacket layer type for the Cisco VLAN Trunking Protocol.
+var LayerTypeVTP = gopacket.RegisterLayerType(layerNumVTP, gopacket.LayerTypeMetadata{
+	Name:    "VTP",
+	Decoder: gopacket.DecodeFunc(decodeVTPLayer),
+})
+
+func init() {
+	layers.EthernetTypeMetadata[ethernetTypeVTP] = layers.EnumMetadata{
+		DecodeWith: LayerTypeVTP,
+		Name:       "VTP",
+		LayerType:  LayerTypeVTP,
+	}
+}
+
+// VTP is a VLAN Trunking Protocol message.
+type VTP struct {
+	layers.BaseLayer
+}
+
+// LayerType returns LayerTypeVTP.
+func (v *VTP) LayerType() gopacket.LayerType {
+	return LayerTypeVTP
+}
+
+func decodeVTPLayer(data []byte, p gopacket.PacketBuilder) error {
+	p.AddLayer(&VTP{BaseLayer: layers.BaseLayer{Contents: data}})
+
+	return nil
+}
+
+var vtpDecoder = newGoPacketDecoder(
+	types.Type_NC_VTP,
+	LayerTypeVTP,
+	"The Cisco VLAN Trunking Protocol distributes the VLAN configuration within a domain, an advertisement with a higher configuration revision overwrites the VLAN database of all switches",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if l, ok := layer.(*VTP); ok {
+			v, err := decodeVTP(l.Contents)
+			if err != nil {
+				decoderLog.Debug("failed to decode VTP message", zap.Error(err))
+
+				return nil
+			}
+
+			v.Timestamp = timestamp
+
+			return v
+		}
+
+		return nil
+	},
+)
+
+// decodeVTP parses summary and subset advertisements, and advertisement requests.
+func decodeVTP(data []byte) (*types.VTP, error) {
+	if len(data) < vtpHeaderSize+vtpDomainSize {
+		return nil, errors.New("truncated VTP message")
+	}
+
+	var (
+		code      = data[1]
+		domainLen = int(data[3])
+		v         = &types.VTP{
+			Version: int32(data[0]),
+			Code:    vtpCodes[code],
+		}
+	)
+
+	if v.Code == "" {
+		v.Code = strconv.Itoa(int(code))
+	}
+
+	if domainLen > vtpDomainSize {
+		domainLen = vtpDomainSize
+	}
+
+	v.Domain = string(data[vtpHeaderSize : vtpHeaderSize+domainLen])
+	body := data[vtpHeaderSize+vtpDomainSize:]
+
+	switch code {
+	case vtpSummaryAdvert:
+		v.Followers = int32(data[2])
+
+		if len(data) < vtpSummarySize {
+			return nil, errors.New("truncated VTP summary advertisement")
+		}
+
+		v.ConfigRevision = binary.BigEndian.Uint32(body[:4])
+		v.UpdaterIP = net.IP(body[4:8]).String()
+		v.UpdateTimestamp = string(body[8 : 8+vtpTimestampSize])
+		v.MD5 = hex.EncodeToString(body[8+vtpTimestampSize : 8+vtpTimestampSize+vtpMD5Size])
+	case vtpSubsetAdvert:
+		v.Sequence = int32(data[2])
+
+		if len(data) < vtpSubsetSize {
+			return nil, errors.New("truncated VTP subset advertisement")
+		}
+
+		v.ConfigRevision = binary.BigEndian.Uint32(body[:4])
+		v.VLANs = decodeVTPVLANs(body[4:])
+	}
+
+	return v, nil
+}
+
+// decodeVTPVLANs parses the VLAN information fields of a subset advertisement into id:name pairs.
+func decodeVTPVLANs(data []byte) []string {
+	var vlans []string
+
+	for len(data) >= vtpVLANInfoSize {
+		var (
+			length  = int(data[0])
+			nameLen = int(data[3])
+			id      = binary.BigEndian.Uint16(data[4:6])
+		)
+
+		if length < vtpVLANInfoSize || length > len(data) || vtpVLANNameOffset+nameLen > length {
+			break
+		}
+
+		name := strings.TrimRight(string(data[vtpVLANNameOffset:vtpVLANNameOffset+nameLen]), "\x00")
+		vlans = append(vlans, strconv.Itoa(int(id))+":"+name)
+		data = data[length:]
+	}
+
+	return vlans
+}
@@ -0,0 +1,60 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"testing"
+)
+
+func testVTPHeader(code, followers byte, domain string) []byte {
+	data := []byte{0x02, code, followers, byte(len(domain))}
+	name := make([]byte, vtpDomainSize)
+	copy(name, domain)
+
+	return append(data, name...)
+}
+
+func TestVTPSummaryAdvertisement(t *testing.T) {
+	data := testVTPHeader(vtpSummaryAdvert, 1, "corp")
+	data = append(data, 0x00, 0x00, 0x00, 0x2a, 10, 0, 0, 1)
+	data = append(data, []byte("201019120000")...)
+	data = append(data, make([]byte, vtpMD5Size)...)
+
+	v, err := decodeVTP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Code != "Summary Advertisement" || v.Domain != "corp" || v.ConfigRevision != 42 || v.UpdaterIP != "10.0.0.1" || v.UpdateTimestamp != "201019120000" {
+		t.Fatalf("unexpected VTP summary advertisement: %+v", v)
+	}
+}
+
+func TestVTPSubsetAdvertisement(t *testing.T) {
+	data := testVTPHeader(vtpSubsetAdvert, 1, "corp")
+	data = append(data, 0x00, 0x00, 0x00, 0x2a)
+	data = append(data,
+		0x14, 0x00, 0x01, 0x05, 0x00, 0x0a, 0x05, 0xdc, 0x00, 0x01, 0x86, 0xaa,
+		'u', 's', 'e', 'r', 's', 0x00, 0x00, 0x00,
+	)
+
+	v, err := decodeVTP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Sequence != 1 || len(v.VLANs) != 1 || v.VLANs[0] != "10:users" {
+		t.Fatalf("unexpected VTP subset advertisement: %+v", v)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	g711SampleRate = 8000
+
+	// silence encoded in G.711
+	pcmuSilence = 0xff
+	pcmaSilence = 0xd5
+)
+
+// decodeMuLaw converts a G.711 µ-law sample to linear 16 bit PCM.
+func decodeMuLaw(u byte) int16 {
+	u = ^u
+
+	var (
+		sign     = u & 0x80
+		exponent = (u >> 4) & 0x07
+		mantissa = u & 0x0f
+		sample   = ((int16(mantissa) << 3) + 0x84) << exponent
+	)
+
+	sample -= 0x84
+
+	if sign != 0 {
+		return -sample
+	}
+
+	return sample
+}
+
+// decodeALaw converts a G.711 A-law sample to linear 16 bit PCM.
+func decodeALaw(a byte) int16 {
+	a ^= 0x55
+
+	var (
+		sign     = a & 0x80
+		exponent = (a >> 4) & 0x07
+		mantissa = int16(a & 0x0f)
+		sample   int16
+	)
+
+	if exponent == 0 {
+		sample = (mantissa << 4) + 8
+	} else {
+		sample = ((mantissa << 4) + 0x108) << (exponent - 1)
+	}
+
+	if sign == 0 {
+		return -sample
+	}
+
+	return sample
+}
+
+// g711ToWAV decodes G.711 audio and returns a WAV file with 16 bit mono PCM.
+func g711ToWAV(data []byte, decode func(byte) int16) []byte {
+	var (
+		buf      bytes.Buffer
+		dataSize = uint32(len(data) * 2)
+	)
+
+	buf.Grow(44 + int(dataSize))
+
+	// RIFF header
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVE")
+
+	// format chunk
+	buf.WriteString("fmt ")
+	for _, v := range []interface{}{
+		uint32(16),                 // chunk size
+		uint16(1),                  // PCM
+		uint16(1),                  // channels
+		uint32(g711SampleRate),     // sample rate
+		uint32(g711SampleRate * 2), // byte rate
+		uint16(2),                  // block align
+		uint16(16),                 // bits per sample
+	} {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	// data chunk
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, dataSize)
+
+	sample := make([]byte, 2)
+	for _, b := range data {
+		binary.LittleEndian.PutUint16(sample, uint16(decode(b)))
+		buf.Write(sample)
+	}
+
+	return buf.Bytes()
+}
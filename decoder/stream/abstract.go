@@ -5,13 +5,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
 	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
 	"github.com/dreadl0ck/netcap/decoder/stream/exploit"
 	"github.com/dreadl0ck/netcap/decoder/stream/file"
 	"github.com/dreadl0ck/netcap/decoder/stream/mail"
 	"github.com/dreadl0ck/netcap/decoder/stream/service"
 	"github.com/dreadl0ck/netcap/decoder/stream/software"
+	"github.com/dreadl0ck/netcap/decoder/stream/token"
+	"github.com/dreadl0ck/netcap/decoder/stream/tunnel"
 	"github.com/dreadl0ck/netcap/decoder/stream/vulnerability"
+	"github.com/dreadl0ck/netcap/decoder/stream/websocket"
 
 	"github.com/mgutz/ansi"
 	"github.com/pkg/errors"
@@ -38,6 +42,10 @@ var DefaultAbstractDecoders = []core.DecoderAPI{
 	software.Decoder,
 	vulnerability.Decoder,
 	credentials.Decoder,
+	websocket.Decoder,
+	token.Decoder,
+	tunnel.Decoder,
+	control.Decoder,
 } // contains all available abstract decoders
 
 // package level init.
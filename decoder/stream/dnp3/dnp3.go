@@ -0,0 +1,68 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dnp3
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var dnp3Log = zap.NewNop()
+
+const (
+	startByte1 = 0x05
+	startByte2 = 0x64
+
+	// start bytes, length, control, destination, source and header CRC
+	linkHeaderSize = 10
+
+	// minimum value of the length field, covering control, destination and source
+	minLength = 5
+
+	protocolDNP3 = "DNP3"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_DNP3,
+	Name:        "DNP3",
+	Description: "The Distributed Network Protocol is used between components in process automation systems, mainly by electric and water utilities",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		dnp3Log, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"dnp3",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isLinkFrame(client) && (len(server) == 0 || isLinkFrame(server))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return dnp3Log.Sync()
+	},
+	Factory: &dnp3Reader{},
+	Typ:     core.TCP,
+}
+
+// isLinkFrame checks whether the data starts with a DNP3 link layer header.
+func isLinkFrame(data []byte) bool {
+	return len(data) >= linkHeaderSize && data[0] == startByte1 && data[1] == startByte2 && data[2] >= minLength
+}
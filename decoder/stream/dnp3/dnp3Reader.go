@@ -0,0 +1,442 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dnp3
+
+import (
+	"encoding/binary"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// link layer control field.
+const (
+	linkPrimary  = 0x40
+	linkFunction = 0x0f
+
+	// maximum number of user data bytes before a CRC is inserted
+	blockSize = 16
+	crcSize   = 2
+)
+
+// transport and application control fields.
+const (
+	transportFIN      = 0x80
+	transportFIR      = 0x40
+	appConfirm        = 0x20
+	appUnsolicited    = 0x10
+	appSequence       = 0x0f
+	transportSequence = 0x3f
+)
+
+// function codes.
+const (
+	fcConfirm               = 0
+	fcRead                  = 1
+	fcWrite                 = 2
+	fcSelect                = 3
+	fcOperate               = 4
+	fcDirectOperate         = 5
+	fcDirectOperateNoAck    = 6
+	fcImmediateFreeze       = 7
+	fcImmediateFreezeNoAck  = 8
+	fcFreezeClear           = 9
+	fcFreezeClearNoAck      = 10
+	fcFreezeAtTime          = 11
+	fcFreezeAtTimeNoAck     = 12
+	fcColdRestart           = 13
+	fcWarmRestart           = 14
+	fcInitializeData        = 15
+	fcInitializeApplication = 16
+	fcStartApplication      = 17
+	fcStopApplication       = 18
+	fcSaveConfiguration     = 19
+	fcEnableUnsolicited     = 20
+	fcDisableUnsolicited    = 21
+	fcAssignClass           = 22
+	fcDelayMeasure          = 23
+	fcRecordCurrentTime     = 24
+	fcOpenFile              = 25
+	fcCloseFile             = 26
+	fcDeleteFile            = 27
+	fcGetFileInfo           = 28
+	fcAuthenticateFile      = 29
+	fcAbortFile             = 30
+	fcActivateConfig        = 31
+	fcAuthenticateRequest   = 32
+	fcAuthenticateError     = 33
+	fcResponse              = 129
+	fcUnsolicitedResponse   = 130
+	fcAuthenticateResponse  = 131
+)
+
+var functionNames = map[byte]string{
+	fcConfirm:               "Confirm",
+	fcRead:                  "Read",
+	fcWrite:                 "Write",
+	fcSelect:                "Select",
+	fcOperate:               "Operate",
+	fcDirectOperate:         "Direct Operate",
+	fcDirectOperateNoAck:    "Direct Operate No Ack",
+	fcImmediateFreeze:       "Immediate Freeze",
+	fcImmediateFreezeNoAck:  "Immediate Freeze No Ack",
+	fcFreezeClear:           "Freeze Clear",
+	fcFreezeClearNoAck:      "Freeze Clear No Ack",
+	fcFreezeAtTime:          "Freeze At Time",
+	fcFreezeAtTimeNoAck:     "Freeze At Time No Ack",
+	fcColdRestart:           "Cold Restart",
+	fcWarmRestart:           "Warm Restart",
+	fcInitializeData:        "Initialize Data",
+	fcInitializeApplication: "Initialize Application",
+	fcStartApplication:      "Start Application",
+	fcStopApplication:       "Stop Application",
+	fcSaveConfiguration:     "Save Configuration",
+	fcEnableUnsolicited:     "Enable Unsolicited",
+	fcDisableUnsolicited:    "Disable Unsolicited",
+	fcAssignClass:           "Assign Class",
+	fcDelayMeasure:          "Delay Measure",
+	fcRecordCurrentTime:     "Record Current Time",
+	fcOpenFile:              "Open File",
+	fcCloseFile:             "Close File",
+	fcDeleteFile:            "Delete File",
+	fcGetFileInfo:           "Get File Info",
+	fcAuthenticateFile:      "Authenticate File",
+	fcAbortFile:             "Abort File",
+	fcActivateConfig:        "Activate Config",
+	fcAuthenticateRequest:   "Authenticate Request",
+	fcAuthenticateError:     "Authenticate Error",
+	fcResponse:              "Response",
+	fcUnsolicitedResponse:   "Unsolicited Response",
+	fcAuthenticateResponse:  "Authenticate Response",
+}
+
+// controlFunctions change outputs, data or the state of the outstation.
+var controlFunctions = map[byte]struct{}{
+	fcWrite:                 {},
+	fcSelect:                {},
+	fcOperate:               {},
+	fcDirectOperate:         {},
+	fcDirectOperateNoAck:    {},
+	fcFreezeClear:           {},
+	fcFreezeClearNoAck:      {},
+	fcColdRestart:           {},
+	fcWarmRestart:           {},
+	fcInitializeData:        {},
+	fcInitializeApplication: {},
+	fcStartApplication:      {},
+	fcStopApplication:       {},
+	fcSaveConfiguration:     {},
+	fcDeleteFile:            {},
+	fcActivateConfig:        {},
+}
+
+// internal indications, IIN1 in the upper byte and IIN2 in the lower byte.
+var iinFlags = []struct {
+	bit  int32
+	name string
+}{
+	{0x8000, "Device Restart"},
+	{0x4000, "Device Trouble"},
+	{0x2000, "Local Control"},
+	{0x1000, "Need Time"},
+	{0x0800, "Class 3 Events"},
+	{0x0400, "Class 2 Events"},
+	{0x0200, "Class 1 Events"},
+	{0x0100, "All Stations"},
+	{0x0020, "Config Corrupt"},
+	{0x0010, "Already Executing"},
+	{0x0008, "Event Buffer Overflow"},
+	{0x0004, "Parameter Error"},
+	{0x0002, "Object Unknown"},
+	{0x0001, "Function Code Not Supported"},
+}
+
+// iinErrors are the IIN2 bits that indicate a request was rejected.
+const iinErrors = 0x0007
+
+// fragment is a reassembled application layer fragment.
+type fragment struct {
+	client      bool
+	source      uint16
+	destination uint16
+	control     byte
+	frames      int
+	data        []byte
+	timestamp   time.Time
+}
+
+type dnp3Reader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new DNP3 reader.
+func (h *dnp3Reader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &dnp3Reader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the DNP3 protocol.
+func (h *dnp3Reader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	records := h.records(decoderconfig.Instance.IncludePayloads)
+
+	if control.Decoder.Writer != nil {
+		for _, c := range h.controlActions(records) {
+			control.WriteControlAction(c)
+		}
+	}
+
+	for _, r := range records {
+		writeDNP3(r)
+	}
+}
+
+// records parses the application fragments of both directions.
+func (h *dnp3Reader) records(includePayloads bool) []*types.DNP3 {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		out            []*types.DNP3
+	)
+
+	for _, f := range append(parseFragments(client), parseFragments(server)...) {
+		if r := h.record(f, includePayloads); r != nil {
+			out = append(out, r)
+		}
+	}
+
+	return out
+}
+
+// parseFragments parses the link frames sent in the given direction and reassembles the transport segments.
+// Link layer frames without user data, such as link status requests, are not reported.
+func parseFragments(dir *core.Direction) []*fragment {
+	var (
+		fragments []*fragment
+		pending   = make(map[uint32]*fragment)
+		data      = dir.Data
+		offset    int
+	)
+
+	for len(data)-offset >= linkHeaderSize {
+		if !isLinkFrame(data[offset:]) {
+			dnp3Log.Debug("invalid DNP3 link header", zap.Int("offset", offset))
+
+			break
+		}
+
+		userLen := int(data[offset+2]) - minLength
+		end := offset + linkHeaderSize + userLen + (userLen+blockSize-1)/blockSize*crcSize
+
+		if end > len(data) {
+			// incomplete frame at the end of the stream
+			break
+		}
+
+		var (
+			ctrl        = data[offset+3]
+			destination = binary.LittleEndian.Uint16(data[offset+4 : offset+6])
+			source      = binary.LittleEndian.Uint16(data[offset+6 : offset+8])
+			user        = stripCRCs(data[offset+linkHeaderSize : end])
+			ts          = dir.TimeAt(offset)
+		)
+
+		offset = end
+
+		if len(user) == 0 {
+			continue
+		}
+
+		var (
+			th  = user[0]
+			key = uint32(source)<<16 | uint32(destination)
+			f   = pending[key]
+		)
+
+		if th&transportFIR != 0 || f == nil {
+			f = &fragment{
+				client:      dir.Client,
+				source:      source,
+				destination: destination,
+				control:     ctrl,
+				timestamp:   ts,
+			}
+			pending[key] = f
+		}
+
+		f.frames++
+		f.data = append(f.data, user[1:]...)
+
+		if th&transportFIN != 0 {
+			delete(pending, key)
+			fragments = append(fragments, f)
+		}
+	}
+
+	return fragments
+}
+
+// stripCRCs removes the CRC following each block of user data.
+func stripCRCs(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+
+	for len(data) > crcSize {
+		n := blockSize
+		if len(data)-crcSize < n {
+			n = len(data) - crcSize
+		}
+
+		out = append(out, data[:n]...)
+		data = data[n+crcSize:]
+	}
+
+	return out
+}
+
+// record parses the application layer of a fragment.
+func (h *dnp3Reader) record(f *fragment, includePayloads bool) *types.DNP3 {
+	if len(f.data) < 2 {
+		return nil
+	}
+
+	var (
+		ac = f.data[0]
+		fc = f.data[1]
+		r  = &types.DNP3{
+			Timestamp:    f.timestamp.UnixNano(),
+			ClientIP:     h.conversation.ClientIP,
+			ServerIP:     h.conversation.ServerIP,
+			ClientPort:   h.conversation.ClientPort,
+			ServerPort:   h.conversation.ServerPort,
+			IsClient:     f.client,
+			Source:       int32(f.source),
+			Destination:  int32(f.destination),
+			LinkFunction: int32(f.control & linkFunction),
+			Primary:      f.control&linkPrimary != 0,
+			Frames:       int32(f.frames),
+			Sequence:     int32(ac & appSequence),
+			Confirm:      ac&appConfirm != 0,
+			Unsolicited:  ac&appUnsolicited != 0,
+			FunctionCode: int32(fc),
+			Function:     functionName(fc),
+		}
+		objects = f.data[2:]
+	)
+
+	if includePayloads {
+		r.Payload = f.data
+	}
+
+	if fc == fcResponse || fc == fcUnsolicitedResponse || fc == fcAuthenticateResponse {
+		if len(objects) < 2 {
+			return r
+		}
+
+		r.IIN = int32(objects[0])<<8 | int32(objects[1])
+		objects = objects[2:]
+
+		for _, flag := range iinFlags {
+			if r.IIN&flag.bit != 0 {
+				r.IINFlags = append(r.IINFlags, flag.name)
+			}
+		}
+	} else {
+		_, r.Control = controlFunctions[fc]
+	}
+
+	r.Objects = parseObjects(objects, fc == fcRead)
+
+	return r
+}
+
+// controlActions pairs the control requests with the responses carrying the same application sequence number.
+func (h *dnp3Reader) controlActions(records []*types.DNP3) []*types.ControlAction {
+	var actions []*types.ControlAction
+
+	for _, r := range records {
+		if !r.Control {
+			continue
+		}
+
+		c := &types.ControlAction{
+			Timestamp:  r.Timestamp,
+			Protocol:   protocolDNP3,
+			Flow:       h.conversation.Ident,
+			ClientIP:   r.ClientIP,
+			ServerIP:   r.ServerIP,
+			ClientPort: r.ClientPort,
+			ServerPort: r.ServerPort,
+			UnitID:     r.Destination,
+			Operation:  r.Function,
+			Data:       r.Payload,
+		}
+
+		if len(r.Objects) > 0 {
+			o := r.Objects[0]
+			c.Target = o.Name + " g" + strconv.Itoa(int(o.Group)) + "v" + strconv.Itoa(int(o.Variation))
+			c.Address = o.Start
+			c.Quantity = o.Count
+		}
+
+		for _, res := range records {
+			if res.IsClient == r.IsClient || res.Timestamp < r.Timestamp || res.FunctionCode != fcResponse || res.Sequence != r.Sequence || res.Destination != r.Source {
+				continue
+			}
+
+			c.Answered = true
+			c.Status = res.IIN & iinErrors
+			c.Success = c.Status == 0
+
+			break
+		}
+
+		actions = append(actions, c)
+	}
+
+	return actions
+}
+
+func functionName(fc byte) string {
+	if name, ok := functionNames[fc]; ok {
+		return name
+	}
+
+	return "Function " + strconv.Itoa(int(fc))
+}
+
+func writeDNP3(r *types.DNP3) {
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		dnp3Log.Error("failed to write DNP3 record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dnp3
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func fragmentData(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+// linkFrame builds a link layer frame, CRCs are not validated and set to zero.
+func linkFrame(ctrl byte, dst, src uint16, user []byte) []byte {
+	out := []byte{startByte1, startByte2, byte(len(user) + minLength), ctrl, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(out[4:], dst)
+	binary.LittleEndian.PutUint16(out[6:], src)
+
+	for len(user) > 0 {
+		n := blockSize
+		if len(user) < n {
+			n = len(user)
+		}
+
+		out = append(out, user[:n]...)
+		out = append(out, 0, 0)
+		user = user[n:]
+	}
+
+	return out
+}
+
+func TestRecords(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.1",
+			ServerIP:   "10.0.0.2",
+			ClientPort: 50000,
+			ServerPort: 20000,
+		}
+
+		// direct operate on binary output 3 with a latch on CROB, sequence 5
+		operate = []byte{
+			0xc5, 0x05, 0x0c, 0x01, 0x28, 0x01, 0x00, 0x03, 0x00,
+			0x03, 0x01, 0x64, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}
+
+		// read class 1 and class 2 events, sequence 6
+		read = []byte{0xc6, 0x01, 0x3c, 0x02, 0x06, 0x3c, 0x03, 0x06}
+
+		// response to the operate with a parameter error and the echoed object
+		response = append([]byte{0xc5, 0x81, 0x90, 0x04}, operate[2:]...)
+
+		client = append(append(
+			// operate split into two transport segments
+			linkFrame(0xc4, 10, 1, append([]byte{transportFIR}, operate[:10]...)),
+			linkFrame(0xc4, 10, 1, append([]byte{transportFIN | 0x01}, operate[10:]...))...),
+			linkFrame(0xc4, 10, 1, append([]byte{transportFIR | transportFIN | 0x02}, read...))...,
+		)
+		server = linkFrame(0x44, 1, 10, append([]byte{transportFIR | transportFIN}, response...))
+	)
+
+	if !Decoder.CanDecode(client, server) {
+		t.Fatal("expected DNP3 stream to be detected")
+	}
+
+	if Decoder.CanDecode([]byte("GET / HTTP/1.1\r\n"), nil) {
+		t.Fatal("unexpected detection of HTTP as DNP3")
+	}
+
+	conv.Data = append(conv.Data,
+		fragmentData(client[:7], reassembly.TCPDirClientToServer, start),
+		fragmentData(client[7:], reassembly.TCPDirClientToServer, start.Add(time.Millisecond)),
+		fragmentData(server, reassembly.TCPDirServerToClient, start.Add(3*time.Millisecond)),
+	)
+
+	h := (&dnp3Reader{}).New(conv).(*dnp3Reader)
+
+	records := h.records(false)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	op := records[0]
+	if op.Function != "Direct Operate" || !op.Control || op.Frames != 2 || op.Sequence != 5 || op.Source != 1 || op.Destination != 10 || !op.Primary {
+		t.Fatalf("unexpected operate record: %+v", op)
+	}
+
+	if len(op.Objects) != 1 || op.Objects[0].Name != "Binary Output Command" || op.Objects[0].Start != 3 || op.Objects[0].Count != 1 {
+		t.Fatalf("unexpected operate objects: %v", op.Objects)
+	}
+
+	rd := records[1]
+	if rd.Function != "Read" || rd.Control || len(rd.Objects) != 2 || rd.Objects[0].Name != "Class 1 Data" || rd.Objects[1].Name != "Class 2 Data" {
+		t.Fatalf("unexpected read record: %+v", rd)
+	}
+
+	res := records[2]
+	if res.Function != "Response" || res.IsClient || res.IIN != 0x9004 || len(res.Objects) != 1 {
+		t.Fatalf("unexpected response record: %+v", res)
+	}
+
+	if len(res.IINFlags) != 3 || res.IINFlags[0] != "Device Restart" || res.IINFlags[1] != "Need Time" || res.IINFlags[2] != "Parameter Error" {
+		t.Fatalf("unexpected IIN flags: %v", res.IINFlags)
+	}
+
+	actions := h.controlActions(records)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 control action, got %d", len(actions))
+	}
+
+	if a := actions[0]; a.Target != "Binary Output Command g12v1" || a.Address != 3 || a.UnitID != 10 || !a.Answered || a.Success || a.Status != 4 {
+		t.Fatalf("unexpected control action: %+v", a)
+	}
+}
@@ -0,0 +1,277 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dnp3
+
+import (
+	"strconv"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// qualifier range codes.
+const (
+	rangeStartStop1 = 0x0
+	rangeStartStop2 = 0x1
+	rangeStartStop4 = 0x2
+	rangeVirtual1   = 0x3
+	rangeVirtual2   = 0x4
+	rangeVirtual4   = 0x5
+	rangeAll        = 0x6
+	rangeCount1     = 0x7
+	rangeCount2     = 0x8
+	rangeCount4     = 0x9
+	rangeVariable   = 0xb
+)
+
+// qualifier prefix codes.
+const (
+	prefixNone   = 0x0
+	prefixIndex4 = 0x3
+	prefixSize1  = 0x4
+	prefixSize4  = 0x6
+)
+
+var groupNames = map[byte]string{
+	1:   "Binary Input",
+	2:   "Binary Input Event",
+	3:   "Double-bit Binary Input",
+	4:   "Double-bit Binary Input Event",
+	10:  "Binary Output",
+	11:  "Binary Output Event",
+	12:  "Binary Output Command",
+	13:  "Binary Output Command Event",
+	20:  "Counter",
+	21:  "Frozen Counter",
+	22:  "Counter Event",
+	23:  "Frozen Counter Event",
+	30:  "Analog Input",
+	31:  "Frozen Analog Input",
+	32:  "Analog Input Event",
+	33:  "Frozen Analog Input Event",
+	34:  "Analog Input Deadband",
+	40:  "Analog Output Status",
+	41:  "Analog Output",
+	42:  "Analog Output Event",
+	43:  "Analog Output Command Event",
+	50:  "Time and Date",
+	51:  "Common Time of Occurrence",
+	52:  "Time Delay",
+	60:  "Class Data",
+	70:  "File Control",
+	80:  "Internal Indications",
+	110: "Octet String",
+	111: "Octet String Event",
+	120: "Authentication",
+}
+
+// objectSizes maps group and variation to the size of a single object in bytes.
+var objectSizes = map[[2]byte]int{
+	{1, 2}: 1,
+	{2, 1}: 1, {2, 2}: 7, {2, 3}: 3,
+	{3, 2}: 1,
+	{4, 1}: 1, {4, 2}: 7, {4, 3}: 3,
+	{10, 2}: 1,
+	{11, 1}: 1, {11, 2}: 7,
+	{12, 1}: 11, {12, 2}: 11,
+	{13, 1}: 1, {13, 2}: 7,
+	{20, 1}: 5, {20, 2}: 3, {20, 5}: 4, {20, 6}: 2,
+	{21, 1}: 5, {21, 2}: 3, {21, 5}: 11, {21, 6}: 9, {21, 9}: 4, {21, 10}: 2,
+	{22, 1}: 5, {22, 2}: 3, {22, 5}: 11, {22, 6}: 9,
+	{23, 1}: 5, {23, 2}: 3, {23, 5}: 11, {23, 6}: 9,
+	{30, 1}: 5, {30, 2}: 3, {30, 3}: 4, {30, 4}: 2, {30, 5}: 5, {30, 6}: 9,
+	{32, 1}: 5, {32, 2}: 3, {32, 3}: 11, {32, 4}: 9, {32, 5}: 5, {32, 6}: 9, {32, 7}: 11, {32, 8}: 15,
+	{40, 1}: 5, {40, 2}: 3, {40, 3}: 5, {40, 4}: 9,
+	{41, 1}: 5, {41, 2}: 3, {41, 3}: 5, {41, 4}: 9,
+	{42, 1}: 5, {42, 2}: 3, {42, 3}: 11, {42, 4}: 9, {42, 5}: 5, {42, 6}: 9, {42, 7}: 11, {42, 8}: 15,
+	{43, 1}: 5, {43, 2}: 3, {43, 3}: 11, {43, 4}: 9,
+	{50, 1}: 6, {50, 2}: 10, {50, 3}: 6, {50, 4}: 11,
+	{51, 1}: 6, {51, 2}: 6,
+	{52, 1}: 2, {52, 2}: 2,
+}
+
+// packedObjects maps group and variation of bit packed objects to the number of bits per object.
+var packedObjects = map[[2]byte]int{
+	{1, 1}:  1,
+	{3, 1}:  2,
+	{10, 1}: 1,
+	{12, 3}: 1,
+	{80, 1}: 1,
+}
+
+// parseObjects parses the object headers of an application fragment and skips over the object data.
+// Parsing stops at the first object whose size is unknown.
+// Read requests only carry headers and optional indices.
+func parseObjects(data []byte, headersOnly bool) []*types.DNP3Object {
+	var objects []*types.DNP3Object
+
+	for len(data) >= 3 {
+		var (
+			group     = data[0]
+			variation = data[1]
+			qualifier = data[2]
+			prefix    = qualifier >> 4 & 0x07
+			rng       = qualifier & 0x0f
+			o         = &types.DNP3Object{
+				Group:     int32(group),
+				Variation: int32(variation),
+				Name:      objectName(group, variation),
+				Qualifier: int32(qualifier),
+			}
+			ok bool
+		)
+
+		data = data[3:]
+
+		switch rng {
+		case rangeStartStop1, rangeStartStop2, rangeStartStop4, rangeVirtual1, rangeVirtual2, rangeVirtual4:
+			width := 1 << (rng % 3)
+
+			var start, stop uint32
+			if start, data, ok = readUint(data, width); !ok {
+				return objects
+			}
+
+			if stop, data, ok = readUint(data, width); !ok {
+				return objects
+			}
+
+			o.Start, o.Stop = int32(start), int32(stop)
+			if stop >= start {
+				o.Count = int32(stop - start + 1)
+			}
+		case rangeAll:
+		case rangeCount1, rangeCount2, rangeCount4, rangeVariable:
+			width := 1 << (rng - rangeCount1)
+			if rng == rangeVariable {
+				width = 1
+			}
+
+			var count uint32
+			if count, data, ok = readUint(data, width); !ok {
+				return objects
+			}
+
+			o.Count = int32(count)
+		default:
+			objects = append(objects, o)
+
+			return objects
+		}
+
+		objects = append(objects, o)
+
+		if rng == rangeAll {
+			continue
+		}
+
+		var (
+			key  = [2]byte{group, variation}
+			size = objectSize(group, variation)
+		)
+
+		if headersOnly || variation == 0 {
+			size = 0
+		} else if bits, packed := packedObjects[key]; packed && prefix == prefixNone {
+			n := (int(o.Count)*bits + 7) / 8
+			if n > len(data) {
+				return objects
+			}
+
+			data = data[n:]
+
+			continue
+		}
+
+		if size < 0 && prefix < prefixSize1 {
+			return objects
+		}
+
+		for i := 0; i < int(o.Count); i++ {
+			var (
+				objSize = size
+				value   uint32
+			)
+
+			switch {
+			case prefix == prefixNone:
+			case prefix <= prefixIndex4:
+				if value, data, ok = readUint(data, 1<<(prefix-1)); !ok {
+					return objects
+				}
+
+				if i == 0 {
+					o.Start = int32(value)
+				}
+
+				o.Stop = int32(value)
+			case prefix <= prefixSize4:
+				if value, data, ok = readUint(data, 1<<(prefix-prefixSize1)); !ok {
+					return objects
+				}
+
+				objSize = int(value)
+			default:
+				return objects
+			}
+
+			if objSize > len(data) {
+				return objects
+			}
+
+			data = data[objSize:]
+		}
+	}
+
+	return objects
+}
+
+// objectSize returns the size of a single object, or -1 if unknown.
+func objectSize(group, variation byte) int {
+	if size, ok := objectSizes[[2]byte{group, variation}]; ok {
+		return size
+	}
+
+	// octet strings use the variation as length
+	if group == 110 || group == 111 {
+		return int(variation)
+	}
+
+	return -1
+}
+
+func objectName(group, variation byte) string {
+	if group == 60 && variation > 0 {
+		return "Class " + strconv.Itoa(int(variation)-1) + " Data"
+	}
+
+	if name, ok := groupNames[group]; ok {
+		return name
+	}
+
+	return "Group " + strconv.Itoa(int(group))
+}
+
+// readUint reads a little endian unsigned integer of the given width.
+func readUint(data []byte, width int) (uint32, []byte, bool) {
+	if len(data) < width {
+		return 0, data, false
+	}
+
+	var v uint32
+	for i := width - 1; i >= 0; i-- {
+		v = v<<8 | uint32(data[i])
+	}
+
+	return v, data[width:], true
+}
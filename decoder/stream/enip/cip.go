@@ -0,0 +1,392 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package enip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// CIP services.
+const (
+	serviceGetAttributesAll       = 0x01
+	serviceSetAttributesAll       = 0x02
+	serviceGetAttributeList       = 0x03
+	serviceSetAttributeList       = 0x04
+	serviceReset                  = 0x05
+	serviceStart                  = 0x06
+	serviceStop                   = 0x07
+	serviceCreate                 = 0x08
+	serviceDelete                 = 0x09
+	serviceMultipleServicePacket  = 0x0a
+	serviceApplyAttributes        = 0x0d
+	serviceGetAttributeSingle     = 0x0e
+	serviceSetAttributeSingle     = 0x10
+	serviceFindNextObjectInstance = 0x11
+	serviceExecutePCCC            = 0x4b
+	serviceReadTag                = 0x4c
+	serviceWriteTag               = 0x4d
+	serviceReadModifyWriteTag     = 0x4e
+	serviceReadTagFragmented      = 0x52
+	serviceWriteTagFragmented     = 0x53
+	serviceGetInstanceAttributes  = 0x55
+
+	// services of the connection manager object
+	serviceForwardClose      = 0x4e
+	serviceUnconnectedSend   = 0x52
+	serviceForwardOpen       = 0x54
+	serviceLargeForwardOpen  = 0x5b
+	serviceGetConnectionData = 0x56
+
+	responseFlag = 0x80
+
+	classConnectionManager = 0x06
+)
+
+// EPATH segment types.
+const (
+	segmentClass8         = 0x20
+	segmentClass16        = 0x21
+	segmentClass32        = 0x22
+	segmentInstance8      = 0x24
+	segmentInstance16     = 0x25
+	segmentInstance32     = 0x26
+	segmentMember8        = 0x28
+	segmentMember16       = 0x29
+	segmentConnPoint8     = 0x2c
+	segmentConnPoint16    = 0x2d
+	segmentAttribute8     = 0x30
+	segmentAttribute16    = 0x31
+	segmentANSISymbol     = 0x91
+	segmentPortExtended   = 0x10
+	segmentPortIdentifier = 0x0f
+)
+
+var serviceNames = map[byte]string{
+	serviceGetAttributesAll:       "Get_Attributes_All",
+	serviceSetAttributesAll:       "Set_Attributes_All",
+	serviceGetAttributeList:       "Get_Attribute_List",
+	serviceSetAttributeList:       "Set_Attribute_List",
+	serviceReset:                  "Reset",
+	serviceStart:                  "Start",
+	serviceStop:                   "Stop",
+	serviceCreate:                 "Create",
+	serviceDelete:                 "Delete",
+	serviceMultipleServicePacket:  "Multiple_Service_Packet",
+	serviceApplyAttributes:        "Apply_Attributes",
+	serviceGetAttributeSingle:     "Get_Attribute_Single",
+	serviceSetAttributeSingle:     "Set_Attribute_Single",
+	serviceFindNextObjectInstance: "Find_Next_Object_Instance",
+	serviceExecutePCCC:            "Execute_PCCC",
+	serviceReadTag:                "Read_Tag",
+	serviceWriteTag:               "Write_Tag",
+	serviceReadModifyWriteTag:     "Read_Modify_Write_Tag",
+	serviceReadTagFragmented:      "Read_Tag_Fragmented",
+	serviceWriteTagFragmented:     "Write_Tag_Fragmented",
+	serviceGetInstanceAttributes:  "Get_Instance_Attribute_List",
+}
+
+var connectionManagerServiceNames = map[byte]string{
+	serviceForwardClose:      "Forward_Close",
+	serviceUnconnectedSend:   "Unconnected_Send",
+	serviceForwardOpen:       "Forward_Open",
+	serviceLargeForwardOpen:  "Large_Forward_Open",
+	serviceGetConnectionData: "Get_Connection_Data",
+}
+
+// writeServices modify attributes, tags or the state of an object.
+var writeServices = map[byte]struct{}{
+	serviceSetAttributesAll:   {},
+	serviceSetAttributeList:   {},
+	serviceReset:              {},
+	serviceStart:              {},
+	serviceStop:               {},
+	serviceCreate:             {},
+	serviceDelete:             {},
+	serviceSetAttributeSingle: {},
+	serviceWriteTag:           {},
+	serviceReadModifyWriteTag: {},
+	serviceWriteTagFragmented: {},
+}
+
+// cipRequest is a CIP explicit message request.
+type cipRequest struct {
+	service     byte
+	classID     uint32
+	instanceID  uint32
+	attributeID uint32
+	tag         string
+	data        []byte
+
+	// requests embedded in a multiple service packet
+	embedded []*cipRequest
+}
+
+// cipResponse is a CIP explicit message response.
+type cipResponse struct {
+	service          byte
+	status           byte
+	additionalStatus []uint32
+	data             []byte
+
+	// responses embedded in a multiple service packet
+	embedded []*cipResponse
+}
+
+// parseCIPRequest parses a CIP request and unwraps unconnected send and multiple service packet requests.
+func parseCIPRequest(data []byte) *cipRequest {
+	if len(data) < 2 || data[0]&responseFlag != 0 {
+		return nil
+	}
+
+	pathEnd := 2 + int(data[1])*2
+	if pathEnd > len(data) {
+		return nil
+	}
+
+	r := &cipRequest{
+		service: data[0],
+		data:    data[pathEnd:],
+	}
+	r.parsePath(data[2:pathEnd])
+
+	switch {
+	case r.classID == classConnectionManager && r.service == serviceUnconnectedSend:
+		// priority / time tick, timeout ticks, message size, embedded message
+		if len(r.data) < 4 {
+			return r
+		}
+
+		size := int(binary.LittleEndian.Uint16(r.data[2:4]))
+		if 4+size > len(r.data) {
+			return r
+		}
+
+		if embedded := parseCIPRequest(r.data[4 : 4+size]); embedded != nil {
+			return embedded
+		}
+	case r.service == serviceMultipleServicePacket:
+		for _, b := range splitMultipleService(r.data) {
+			if embedded := parseCIPRequest(b); embedded != nil {
+				r.embedded = append(r.embedded, embedded)
+			}
+		}
+	}
+
+	return r
+}
+
+// parseCIPResponse parses a CIP response.
+func parseCIPResponse(data []byte) *cipResponse {
+	if len(data) < 4 || data[0]&responseFlag == 0 {
+		return nil
+	}
+
+	dataStart := 4 + int(data[3])*2
+	if dataStart > len(data) {
+		return nil
+	}
+
+	r := &cipResponse{
+		service: data[0] &^ responseFlag,
+		status:  data[2],
+		data:    data[dataStart:],
+	}
+
+	for i := 4; i < dataStart; i += 2 {
+		r.additionalStatus = append(r.additionalStatus, uint32(binary.LittleEndian.Uint16(data[i:i+2])))
+	}
+
+	if r.service == serviceMultipleServicePacket {
+		for _, b := range splitMultipleService(r.data) {
+			if embedded := parseCIPResponse(b); embedded != nil {
+				r.embedded = append(r.embedded, embedded)
+			}
+		}
+	}
+
+	return r
+}
+
+// splitMultipleService returns the services embedded in a multiple service packet.
+// The data starts with the number of services, followed by their offsets relative to the start of the data.
+func splitMultipleService(data []byte) [][]byte {
+	if len(data) < 2 {
+		return nil
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[0:2]))
+	if 2+count*2 > len(data) {
+		return nil
+	}
+
+	var services [][]byte
+
+	for i := 0; i < count; i++ {
+		start := int(binary.LittleEndian.Uint16(data[2+i*2:]))
+		end := len(data)
+
+		if i+1 < count {
+			end = int(binary.LittleEndian.Uint16(data[4+i*2:]))
+		}
+
+		if start < 2+count*2 || start > end || end > len(data) {
+			return services
+		}
+
+		services = append(services, data[start:end])
+	}
+
+	return services
+}
+
+// parsePath decodes the logical and symbolic segments of the request path.
+func (r *cipRequest) parsePath(path []byte) {
+	var symbols []string
+
+	for len(path) > 0 {
+		var n int
+
+		switch t := path[0]; {
+		case t == segmentClass8, t == segmentInstance8, t == segmentAttribute8, t == segmentMember8, t == segmentConnPoint8:
+			if len(path) < 2 {
+				return
+			}
+
+			r.setLogical(t, uint32(path[1]))
+			n = 2
+		case t == segmentClass16, t == segmentInstance16, t == segmentAttribute16, t == segmentMember16, t == segmentConnPoint16:
+			if len(path) < 4 {
+				return
+			}
+
+			r.setLogical(t&^0x01, uint32(binary.LittleEndian.Uint16(path[2:4])))
+			n = 4
+		case t == segmentClass32, t == segmentInstance32:
+			if len(path) < 6 {
+				return
+			}
+
+			r.setLogical(t&^0x02, binary.LittleEndian.Uint32(path[2:6]))
+			n = 6
+		case t == segmentANSISymbol:
+			if len(path) < 2 || 2+int(path[1]) > len(path) {
+				return
+			}
+
+			symbols = append(symbols, string(path[2:2+int(path[1])]))
+			n = 2 + int(path[1])
+			n += n % 2
+		case t&0xe0 == 0:
+			// port segment
+			if t&segmentPortExtended != 0 {
+				if len(path) < 2 {
+					return
+				}
+
+				n = 2 + int(path[1])
+			} else {
+				n = 2
+			}
+
+			if t&segmentPortIdentifier == segmentPortIdentifier {
+				n += 2
+			}
+
+			n += n % 2
+		default:
+			r.tag = strings.Join(symbols, ".")
+
+			return
+		}
+
+		if n > len(path) {
+			break
+		}
+
+		path = path[n:]
+	}
+
+	r.tag = strings.Join(symbols, ".")
+}
+
+func (r *cipRequest) setLogical(segment byte, value uint32) {
+	switch segment {
+	case segmentClass8:
+		r.classID = value
+	case segmentInstance8:
+		r.instanceID = value
+	case segmentAttribute8:
+		r.attributeID = value
+	}
+}
+
+// name returns the service name, taking the object specific services of the connection manager into account.
+func (r *cipRequest) name() string {
+	return serviceName(r.service, r.classID)
+}
+
+// isWrite returns true if the request modifies the target object.
+func (r *cipRequest) isWrite() bool {
+	// the connection manager shares service codes with the tag services
+	if r.classID == classConnectionManager {
+		return false
+	}
+
+	_, ok := writeServices[r.service]
+
+	return ok
+}
+
+// target returns a description of the object addressed by the request.
+func (r *cipRequest) target() string {
+	if r.tag != "" {
+		return r.tag
+	}
+
+	s := fmt.Sprintf("class 0x%x instance %d", r.classID, r.instanceID)
+	if r.attributeID != 0 {
+		s += fmt.Sprintf(" attribute %d", r.attributeID)
+	}
+
+	return s
+}
+
+// elements returns the number of elements written by a tag service.
+func (r *cipRequest) elements() int32 {
+	switch r.service {
+	case serviceWriteTag, serviceWriteTagFragmented:
+		// data type, element count
+		if len(r.data) >= 4 {
+			return int32(binary.LittleEndian.Uint16(r.data[2:4]))
+		}
+	}
+
+	return 0
+}
+
+func serviceName(service byte, classID uint32) string {
+	if classID == classConnectionManager {
+		if name, ok := connectionManagerServiceNames[service]; ok {
+			return name
+		}
+	}
+
+	if name, ok := serviceNames[service]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Service 0x%x", service)
+}
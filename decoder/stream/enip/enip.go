@@ -0,0 +1,100 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package enip
+
+import (
+	"encoding/binary"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var enipLog = zap.NewNop()
+
+const (
+	// size of the encapsulation header
+	headerSize = 24
+
+	protocolENIP = "EtherNet/IP"
+)
+
+// encapsulation commands.
+const (
+	commandNOP               = 0x0000
+	commandListServices      = 0x0004
+	commandListIdentity      = 0x0063
+	commandListInterfaces    = 0x0064
+	commandRegisterSession   = 0x0065
+	commandUnRegisterSession = 0x0066
+	commandSendRRData        = 0x006f
+	commandSendUnitData      = 0x0070
+	commandIndicateStatus    = 0x0072
+	commandCancel            = 0x0073
+)
+
+var commandNames = map[uint16]string{
+	commandNOP:               "NOP",
+	commandListServices:      "ListServices",
+	commandListIdentity:      "ListIdentity",
+	commandListInterfaces:    "ListInterfaces",
+	commandRegisterSession:   "RegisterSession",
+	commandUnRegisterSession: "UnRegisterSession",
+	commandSendRRData:        "SendRRData",
+	commandSendUnitData:      "SendUnitData",
+	commandIndicateStatus:    "IndicateStatus",
+	commandCancel:            "Cancel",
+}
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_ENIPTransaction,
+	Name:        "ENIPTransaction",
+	Description: "EtherNet/IP encapsulated requests and their embedded CIP messages, paired with the responses from the reassembled TCP stream",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		enipLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"enip",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isEncapsulation(client) && (len(server) == 0 || isEncapsulation(server))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return enipLog.Sync()
+	},
+	Factory: &enipReader{},
+	Typ:     core.TCP,
+}
+
+// isEncapsulation checks whether the data starts with a plausible EtherNet/IP encapsulation header.
+func isEncapsulation(data []byte) bool {
+	if len(data) < headerSize {
+		return false
+	}
+
+	if _, ok := commandNames[binary.LittleEndian.Uint16(data[0:2])]; !ok {
+		return false
+	}
+
+	// the options field must be zero
+	return binary.LittleEndian.Uint32(data[20:24]) == 0
+}
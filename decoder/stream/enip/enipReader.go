@@ -0,0 +1,393 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package enip
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// common packet format item types.
+const (
+	itemListIdentity    = 0x000c
+	itemConnectedData   = 0x00b1
+	itemUnconnectedData = 0x00b2
+)
+
+// size of the identity item fields preceding the product name.
+const identityFixedSize = 2 + 16 + 2 + 2 + 2 + 2 + 2 + 4 + 1
+
+// encapsulation is a single EtherNet/IP encapsulation message.
+type encapsulation struct {
+	command       uint16
+	sessionHandle uint32
+	status        uint32
+	senderContext []byte
+	data          []byte
+	raw           []byte
+	timestamp     time.Time
+}
+
+// item is a common packet format item.
+type item struct {
+	typeID uint16
+	data   []byte
+}
+
+// transaction is an encapsulated request paired with its response.
+type transaction struct {
+	record   *types.ENIPTransaction
+	request  *cipRequest
+	response *cipResponse
+}
+
+type enipReader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new EtherNet/IP reader.
+func (h *enipReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &enipReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the EtherNet/IP protocol.
+func (h *enipReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	includePayloads := decoderconfig.Instance.IncludePayloads
+
+	for _, t := range h.transactions(includePayloads) {
+		if t.record.Write && control.Decoder.Writer != nil {
+			for _, c := range h.controlActions(t, includePayloads) {
+				control.WriteControlAction(c)
+			}
+		}
+
+		writeTransaction(t.record)
+	}
+}
+
+// transactions parses the messages from both directions and pairs requests with their responses.
+// Unconnected messages are paired via the sender context, connected messages via their sequence count.
+func (h *enipReader) transactions(includePayloads bool) []*transaction {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		pending        = make(map[string][]*transaction)
+		out            []*transaction
+	)
+
+	for _, e := range parseEncapsulations(client) {
+		t := &transaction{
+			record: h.newRecord(e),
+		}
+
+		if includePayloads {
+			t.record.RequestData = e.raw
+		}
+
+		cip, key := cipMessage(e)
+		if cip != nil {
+			t.request = parseCIPRequest(cip)
+			if t.request != nil {
+				t.record.CIPService = int32(t.request.service)
+				t.record.CIPServiceName = t.request.name()
+				t.record.ClassID = t.request.classID
+				t.record.InstanceID = t.request.instanceID
+				t.record.AttributeID = t.request.attributeID
+				t.record.Tag = t.request.tag
+				t.record.Write = t.request.isWrite()
+
+				for _, r := range t.request.embedded {
+					t.record.Write = t.record.Write || r.isWrite()
+				}
+			}
+		}
+
+		pending[key] = append(pending[key], t)
+		out = append(out, t)
+	}
+
+	for _, e := range parseEncapsulations(server) {
+		cip, key := cipMessage(e)
+
+		q := pending[key]
+		if len(q) == 0 {
+			// unsolicited messages and responses without a request are not paired
+			enipLog.Debug("unmatched EtherNet/IP response",
+				zap.String("command", commandName(e.command)),
+				zap.String("ident", h.conversation.Ident),
+			)
+
+			continue
+		}
+
+		t := q[0]
+		pending[key] = q[1:]
+
+		t.record.Answered = true
+		t.record.Duration = e.timestamp.UnixNano() - t.record.Timestamp
+		t.record.Status = e.status
+
+		if t.record.SessionHandle == 0 {
+			t.record.SessionHandle = e.sessionHandle
+		}
+
+		if includePayloads {
+			t.record.ResponseData = e.raw
+		}
+
+		if e.command == commandListIdentity {
+			setIdentity(t.record, e.data)
+		}
+
+		if cip != nil {
+			t.response = parseCIPResponse(cip)
+			if t.response != nil {
+				t.record.CIPStatus = int32(t.response.status)
+				t.record.AdditionalStatus = t.response.additionalStatus
+			}
+		}
+	}
+
+	return out
+}
+
+func (h *enipReader) newRecord(e *encapsulation) *types.ENIPTransaction {
+	return &types.ENIPTransaction{
+		Timestamp:     e.timestamp.UnixNano(),
+		ClientIP:      h.conversation.ClientIP,
+		ServerIP:      h.conversation.ServerIP,
+		ClientPort:    h.conversation.ClientPort,
+		ServerPort:    h.conversation.ServerPort,
+		Command:       uint32(e.command),
+		CommandName:   commandName(e.command),
+		SessionHandle: e.sessionHandle,
+		SenderContext: hex.EncodeToString(e.senderContext),
+	}
+}
+
+// parseEncapsulations returns all complete encapsulation messages sent in the given direction.
+func parseEncapsulations(dir *core.Direction) []*encapsulation {
+	var (
+		messages []*encapsulation
+		data     = dir.Data
+		offset   int
+	)
+
+	for len(data)-offset >= headerSize {
+		if !isEncapsulation(data[offset:]) {
+			enipLog.Debug("invalid EtherNet/IP header", zap.Int("offset", offset))
+
+			break
+		}
+
+		end := offset + headerSize + int(binary.LittleEndian.Uint16(data[offset+2:offset+4]))
+		if end > len(data) {
+			// incomplete message at the end of the stream
+			break
+		}
+
+		messages = append(messages, &encapsulation{
+			command:       binary.LittleEndian.Uint16(data[offset : offset+2]),
+			sessionHandle: binary.LittleEndian.Uint32(data[offset+4 : offset+8]),
+			status:        binary.LittleEndian.Uint32(data[offset+8 : offset+12]),
+			senderContext: data[offset+12 : offset+20],
+			data:          data[offset+headerSize : end],
+			raw:           data[offset:end],
+			timestamp:     dir.TimeAt(offset),
+		})
+
+		offset = end
+	}
+
+	return messages
+}
+
+// cipMessage returns the CIP message carried in the encapsulation, if any,
+// and the key used to pair the request with the response.
+func cipMessage(e *encapsulation) (cip []byte, key string) {
+	key = strconv.Itoa(int(e.command)) + "/" + hex.EncodeToString(e.senderContext)
+
+	if e.command != commandSendRRData && e.command != commandSendUnitData {
+		return nil, key
+	}
+
+	// interface handle and timeout precede the items
+	if len(e.data) < 6 {
+		return nil, key
+	}
+
+	for _, i := range parseItems(e.data[6:]) {
+		switch i.typeID {
+		case itemUnconnectedData:
+			return i.data, key
+		case itemConnectedData:
+			// the sequence count precedes the CIP message and is echoed by the target
+			if len(i.data) < 2 {
+				return nil, key
+			}
+
+			return i.data[2:], "seq/" + strconv.Itoa(int(binary.LittleEndian.Uint16(i.data[0:2])))
+		}
+	}
+
+	return nil, key
+}
+
+// parseItems parses a list of common packet format items.
+func parseItems(data []byte) []item {
+	if len(data) < 2 {
+		return nil
+	}
+
+	var (
+		count  = int(binary.LittleEndian.Uint16(data[0:2]))
+		items  []item
+		offset = 2
+	)
+
+	for i := 0; i < count && offset+4 <= len(data); i++ {
+		length := int(binary.LittleEndian.Uint16(data[offset+2 : offset+4]))
+		if offset+4+length > len(data) {
+			break
+		}
+
+		items = append(items, item{
+			typeID: binary.LittleEndian.Uint16(data[offset : offset+2]),
+			data:   data[offset+4 : offset+4+length],
+		})
+
+		offset += 4 + length
+	}
+
+	return items
+}
+
+// setIdentity populates the device identity from a ListIdentity response.
+func setIdentity(r *types.ENIPTransaction, data []byte) {
+	for _, i := range parseItems(data) {
+		if i.typeID != itemListIdentity || len(i.data) < identityFixedSize {
+			continue
+		}
+
+		// skip the encapsulation protocol version and socket address
+		d := i.data[18:]
+
+		r.VendorID = uint32(binary.LittleEndian.Uint16(d[0:2]))
+		r.DeviceType = uint32(binary.LittleEndian.Uint16(d[2:4]))
+		r.ProductCode = uint32(binary.LittleEndian.Uint16(d[4:6]))
+		r.Revision = strconv.Itoa(int(d[6])) + "." + strconv.Itoa(int(d[7]))
+		r.SerialNumber = binary.LittleEndian.Uint32(d[10:14])
+
+		if n := int(d[14]); 15+n <= len(d) {
+			r.ProductName = string(d[15 : 15+n])
+		}
+
+		return
+	}
+}
+
+// controlActions creates the control action audit records for the write requests of a transaction.
+func (h *enipReader) controlActions(t *transaction, includePayloads bool) []*types.ControlAction {
+	var actions []*types.ControlAction
+
+	if t.request == nil {
+		return nil
+	}
+
+	add := func(req *cipRequest, res *cipResponse) {
+		c := &types.ControlAction{
+			Timestamp:  t.record.Timestamp,
+			Protocol:   protocolENIP,
+			Flow:       h.conversation.Ident,
+			ClientIP:   t.record.ClientIP,
+			ServerIP:   t.record.ServerIP,
+			ClientPort: t.record.ClientPort,
+			ServerPort: t.record.ServerPort,
+			Operation:  req.name(),
+			Target:     req.target(),
+			Address:    int32(req.attributeID),
+			Quantity:   req.elements(),
+			Answered:   res != nil,
+		}
+
+		if includePayloads {
+			c.Data = req.data
+		}
+
+		if res != nil {
+			c.Success = res.status == 0
+			c.Status = int32(res.status)
+		}
+
+		actions = append(actions, c)
+	}
+
+	if t.request.isWrite() {
+		add(t.request, t.response)
+	}
+
+	for i, req := range t.request.embedded {
+		if !req.isWrite() {
+			continue
+		}
+
+		var res *cipResponse
+		if t.response != nil && i < len(t.response.embedded) {
+			res = t.response.embedded[i]
+		}
+
+		add(req, res)
+	}
+
+	return actions
+}
+
+func commandName(command uint16) string {
+	if name, ok := commandNames[command]; ok {
+		return name
+	}
+
+	return "0x" + strconv.FormatUint(uint64(command), 16)
+}
+
+func writeTransaction(r *types.ENIPTransaction) {
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		enipLog.Error("failed to write EtherNet/IP transaction", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
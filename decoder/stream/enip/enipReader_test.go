@@ -0,0 +1,182 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package enip
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func encap(command uint16, session uint32, context string, data []byte) []byte {
+	b := make([]byte, headerSize, headerSize+len(data))
+	binary.LittleEndian.PutUint16(b[0:2], command)
+	binary.LittleEndian.PutUint16(b[2:4], uint16(len(data)))
+	binary.LittleEndian.PutUint32(b[4:8], session)
+	copy(b[12:20], context)
+
+	return append(b, data...)
+}
+
+// rrData wraps a CIP message into the items of a SendRRData command.
+func rrData(cip []byte) []byte {
+	b := []byte{0, 0, 0, 0, 10, 0, 2, 0, 0, 0, 0, 0, itemUnconnectedData, 0, 0, 0}
+	binary.LittleEndian.PutUint16(b[14:16], uint16(len(cip)))
+
+	return append(b, cip...)
+}
+
+func fragment(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.1",
+			ServerIP:   "10.0.0.2",
+			ClientPort: 50000,
+			ServerPort: 44818,
+		}
+
+		// Write_Tag Motor.Speed, DINT, 1 element, embedded into an Unconnected_Send to the connection manager
+		writeTag = []byte{
+			serviceWriteTag, 8,
+			segmentANSISymbol, 5, 'M', 'o', 't', 'o', 'r', 0,
+			segmentANSISymbol, 5, 'S', 'p', 'e', 'e', 'd', 0,
+			0xc4, 0x00, 0x01, 0x00, 0xe8, 0x03, 0x00, 0x00,
+		}
+		unconnectedSend = append([]byte{
+			serviceUnconnectedSend, 2, segmentClass8, classConnectionManager, segmentInstance8, 1,
+			0x07, 0xe9, byte(len(writeTag)), 0,
+		}, writeTag...)
+
+		// Get_Attribute_Single and Set_Attribute_Single bundled into a multiple service packet
+		getAttr = []byte{serviceGetAttributeSingle, 3, segmentClass8, 0x01, segmentInstance8, 1, segmentAttribute8, 7}
+		setAttr = []byte{serviceSetAttributeSingle, 3, segmentClass8, 0x64, segmentInstance8, 1, segmentAttribute8, 3, 0x01}
+		msp     = append([]byte{
+			serviceMultipleServicePacket, 2, segmentClass8, 0x02, segmentInstance8, 1,
+			2, 0, 6, 0, byte(6 + len(getAttr)), 0,
+		}, append(getAttr, setAttr...)...)
+
+		// responses
+		mspRes = []byte{
+			serviceMultipleServicePacket | responseFlag, 0, 0, 0,
+			2, 0, 6, 0, 12, 0,
+			serviceGetAttributeSingle | responseFlag, 0, 0, 0, 'a', 'b',
+			serviceSetAttributeSingle | responseFlag, 0, 0x0f, 0,
+		}
+		writeRes = []byte{serviceWriteTag | responseFlag, 0, 0, 0}
+
+		identity = []byte{1, 0, itemListIdentity, 0, 0, 0}
+	)
+
+	// identity item: version, socket address, vendor, device type, product code, revision, status, serial, name, state
+	id := make([]byte, 18, 64)
+	id = append(id, 0x01, 0x00, 0x0e, 0x00, 0x36, 0x00, 20, 11, 0x60, 0x00, 0x78, 0x56, 0x34, 0x12)
+	id = append(id, 14)
+	id = append(id, "1756-L61/B LOG"...)
+	id = append(id, 3)
+	binary.LittleEndian.PutUint16(identity[4:6], uint16(len(id)))
+	identity = append(identity, id...)
+
+	var (
+		register = encap(commandRegisterSession, 0, "ctx00001", []byte{1, 0, 0, 0})
+		list     = encap(commandListIdentity, 0, "ctx00002", nil)
+		write    = encap(commandSendRRData, 0x1234, "ctx00003", rrData(unconnectedSend))
+		multi    = encap(commandSendRRData, 0x1234, "ctx00004", rrData(msp))
+		client   = append(append(append(register, list...), write...), multi...)
+	)
+
+	if !Decoder.CanDecode(client, nil) {
+		t.Fatal("expected EtherNet/IP stream to be detected")
+	}
+
+	conv.Data = append(conv.Data,
+		fragment(client[:30], reassembly.TCPDirClientToServer, start),
+		fragment(client[30:], reassembly.TCPDirClientToServer, start.Add(time.Millisecond)),
+		fragment(encap(commandRegisterSession, 0x1234, "ctx00001", []byte{1, 0, 0, 0}), reassembly.TCPDirServerToClient, start.Add(2*time.Millisecond)),
+		fragment(encap(commandListIdentity, 0, "ctx00002", identity), reassembly.TCPDirServerToClient, start.Add(3*time.Millisecond)),
+		fragment(encap(commandSendRRData, 0x1234, "ctx00004", rrData(mspRes)), reassembly.TCPDirServerToClient, start.Add(4*time.Millisecond)),
+		fragment(encap(commandSendRRData, 0x1234, "ctx00003", rrData(writeRes)), reassembly.TCPDirServerToClient, start.Add(5*time.Millisecond)),
+	)
+
+	h := (&enipReader{}).New(conv).(*enipReader)
+
+	txs := h.transactions(false)
+	if len(txs) != 4 {
+		t.Fatalf("expected 4 transactions, got %d", len(txs))
+	}
+
+	for i, tx := range txs {
+		if !tx.record.Answered {
+			t.Fatalf("transaction %d was not answered: %+v", i, tx.record)
+		}
+	}
+
+	if r := txs[0].record; r.CommandName != "RegisterSession" || r.SessionHandle != 0x1234 {
+		t.Fatalf("unexpected register session: %+v", r)
+	}
+
+	if r := txs[1].record; r.ProductName != "1756-L61/B LOG" || r.VendorID != 1 || r.DeviceType != 14 || r.Revision != "20.11" || r.SerialNumber != 0x12345678 {
+		t.Fatalf("unexpected identity: %+v", r)
+	}
+
+	r := txs[2].record
+	if r.CIPServiceName != "Write_Tag" || r.Tag != "Motor.Speed" || !r.Write || r.CIPStatus != 0 || r.Duration != int64(4*time.Millisecond) {
+		t.Fatalf("unexpected write tag transaction: %+v", r)
+	}
+
+	actions := h.controlActions(txs[2], false)
+	if len(actions) != 1 || actions[0].Target != "Motor.Speed" || actions[0].Quantity != 1 || !actions[0].Success {
+		t.Fatalf("unexpected write tag control actions: %+v", actions)
+	}
+
+	r = txs[3].record
+	if r.CIPServiceName != "Multiple_Service_Packet" || !r.Write {
+		t.Fatalf("unexpected multiple service packet transaction: %+v", r)
+	}
+
+	actions = h.controlActions(txs[3], false)
+	if len(actions) != 1 {
+		t.Fatalf("expected one control action, got %+v", actions)
+	}
+
+	if a := actions[0]; a.Operation != "Set_Attribute_Single" || a.Target != "class 0x64 instance 1 attribute 3" || a.Success || a.Status != 0x0f {
+		t.Fatalf("unexpected set attribute control action: %+v", a)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	r := parseCIPRequest([]byte{serviceGetAttributeSingle, 4, segmentClass16, 0, 0x00, 0x01, segmentInstance8, 2, segmentAttribute8, 5})
+	if r == nil || r.classID != 0x100 || r.instanceID != 2 || r.attributeID != 5 || r.isWrite() {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+
+	// Forward_Close shares the service code with Read_Modify_Write_Tag
+	r = parseCIPRequest([]byte{serviceForwardClose, 2, segmentClass8, classConnectionManager, segmentInstance8, 1})
+	if r == nil || r.name() != "Forward_Close" || r.isWrite() {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+}
@@ -0,0 +1,346 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+)
+
+const (
+	// maxRangeFileSize is the maximum size of files that are reassembled from ranges,
+	// the total and the end of each range are checked against it, since both are controlled by the server.
+	maxRangeFileSize = 1 << 30
+
+	// at least 1/minRangeCoverage of an incomplete file must have been received,
+	// files with less data are not saved instead of filling huge gaps with zeroes.
+	minRangeCoverage = 100
+)
+
+var (
+	errRangeIncomplete   = errors.New("missing byte ranges")
+	errInvalidRange      = errors.New("invalid content range")
+	errUnknownRangeTotal = errors.New("unknown total size for content range")
+)
+
+// rangeSegment is a part of a file received in a partial response.
+type rangeSegment struct {
+	start int64
+	data  []byte
+}
+
+// rangeFile collects the segments of a file downloaded with range requests.
+type rangeFile struct {
+	conv        *core.ConversationInfo
+	source      string
+	name        string
+	host        string
+	contentType string
+	encoding    []string
+	total       int64
+	segments    []rangeSegment
+}
+
+// rangeFiles contains the files that are currently reassembled from partial responses.
+// Range requests for the same file are frequently sent over different connections,
+// so the state is kept across all streams and keyed by URL and ETag.
+var rangeFiles = struct {
+	sync.Mutex
+	items map[string]*rangeFile
+}{
+	items: make(map[string]*rangeFile),
+}
+
+// parseContentRange parses a content range, e.g. bytes 0-1023/146515.
+// A total of -1 is returned if the total size is unknown.
+func parseContentRange(s string) (start, end, total int64, err error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, 0, errInvalidRange
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(s, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, 0, errInvalidRange
+	}
+
+	bounds := strings.SplitN(parts[0], "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, 0, errInvalidRange
+	}
+
+	if start, err = strconv.ParseInt(bounds[0], 10, 64); err != nil {
+		return 0, 0, 0, errInvalidRange
+	}
+
+	if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil || end < start {
+		return 0, 0, 0, errInvalidRange
+	}
+
+	total = -1
+	if parts[1] != "*" {
+		if total, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return 0, 0, 0, errInvalidRange
+		}
+	}
+
+	return start, end, total, nil
+}
+
+// validRange checks whether a segment of the given length starts within the file
+// and whether the file and the segment end are within maxRangeFileSize,
+// to avoid huge allocations when assembling files from bogus ranges.
+func validRange(start int64, length int, total int64) bool {
+	if total > maxRangeFileSize || (total >= 0 && start >= total) {
+		return false
+	}
+
+	return start >= 0 && start+int64(length) <= maxRangeFileSize
+}
+
+// saveRange adds the body of a partial response to the file it belongs to,
+// and saves the file once all ranges have been received.
+func (h *httpReader) saveRange(req *http.Request, header http.Header, body []byte) error {
+	var (
+		host = req.Host
+		key  = host + req.URL.Path + "|" + header.Get("ETag")
+		f    = &rangeFile{
+			conv:        h.conversation,
+			source:      "HTTP RANGE RESPONSES from " + host + req.URL.Path,
+			name:        path.Base(req.URL.Path),
+			host:        host,
+			contentType: header.Get(headerContentType),
+			encoding:    header[headerContentEncoding],
+			total:       -1,
+		}
+		segments []rangeSegment
+	)
+
+	mediaType, params, err := mime.ParseMediaType(f.contentType)
+	if err == nil && mediaType == "multipart/byteranges" {
+		// multiple ranges are sent as parts of a multipart response
+		f.contentType = ""
+
+		r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, errPart := r.NextPart()
+			if errPart != nil {
+				break
+			}
+
+			data, errRead := ioutil.ReadAll(part)
+			start, _, total, errRange := parseContentRange(part.Header.Get("Content-Range"))
+
+			if errRead == nil && errRange == nil && validRange(start, len(data), total) {
+				segments = append(segments, rangeSegment{start: start, data: data})
+				f.total = total
+				f.contentType = part.Header.Get(headerContentType)
+			}
+		}
+	} else {
+		start, _, total, errRange := parseContentRange(header.Get("Content-Range"))
+		if errRange != nil {
+			return errRange
+		}
+
+		if !validRange(start, len(body), total) {
+			return errInvalidRange
+		}
+
+		segments = append(segments, rangeSegment{start: start, data: body})
+		f.total = total
+	}
+
+	rangeFiles.Lock()
+
+	existing, ok := rangeFiles.items[key]
+	if ok {
+		// use the most recent connection for the file record
+		existing.conv = h.conversation
+		if existing.total < 0 {
+			existing.total = f.total
+		}
+
+		f = existing
+	} else {
+		rangeFiles.items[key] = f
+	}
+
+	f.segments = append(f.segments, segments...)
+
+	complete := f.complete()
+	if complete {
+		delete(rangeFiles.items, key)
+	}
+
+	rangeFiles.Unlock()
+
+	if !complete {
+		return nil
+	}
+
+	return streamutils.SaveFile(f.conv, f.source, f.name, nil, f.assemble(), f.encoding, f.host, f.contentType)
+}
+
+// complete checks whether all bytes of the file have been received.
+func (f *rangeFile) complete() bool {
+	sort.SliceStable(f.segments, func(i, j int) bool {
+		return f.segments[i].start < f.segments[j].start
+	})
+
+	var covered int64
+
+	for _, s := range f.segments {
+		if s.start > covered {
+			return false
+		}
+
+		if end := s.start + int64(len(s.data)); end > covered {
+			covered = end
+		}
+	}
+
+	return f.total >= 0 && covered >= f.total
+}
+
+// assemble merges the segments, missing ranges are filled with zeroes.
+// Nil is returned if only a tiny fraction of the file has been received.
+func (f *rangeFile) assemble() []byte {
+	var size, received int64
+
+	for _, s := range f.segments {
+		received += int64(len(s.data))
+
+		if end := s.start + int64(len(s.data)); end > size {
+			size = end
+		}
+	}
+
+	if f.total >= 0 && f.total < size {
+		size = f.total
+	}
+
+	if received*minRangeCoverage < size {
+		return nil
+	}
+
+	data := make([]byte, size)
+	for _, s := range f.segments {
+		if s.start < size {
+			copy(data[s.start:], s.data)
+		}
+	}
+
+	return data
+}
+
+// flushRanges saves the files for which not all ranges have been received, if configured.
+func flushRanges() {
+	rangeFiles.Lock()
+	defer rangeFiles.Unlock()
+
+	for key, f := range rangeFiles.items {
+		delete(rangeFiles.items, key)
+
+		if decoderconfig.Instance.FileStorage == "" || !decoderconfig.Instance.WriteIncomplete {
+			continue
+		}
+
+		data := f.assemble()
+		if data == nil {
+			httpLog.Debug("skipping range file with too few received bytes", zap.String("name", f.name))
+
+			continue
+		}
+
+		err := errRangeIncomplete
+		if f.total < 0 {
+			err = errUnknownRangeTotal
+		}
+
+		errSave := streamutils.SaveFile(f.conv, f.source, f.name, err, data, f.encoding, f.host, f.contentType)
+		if errSave != nil {
+			httpLog.Error("failed to save range file", zap.String("name", f.name), zap.Error(errSave))
+		}
+	}
+}
+
+// saveUploads extracts the files from a multipart form submitted by the client.
+// It returns false if the body is not a multipart form.
+func (h *httpReader) saveUploads(req *http.Request, body []byte) bool {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get(headerContentType))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return false
+	}
+
+	r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, errPart := r.NextPart()
+		if errPart != nil {
+			break
+		}
+
+		// the file name is parsed manually, since part.FileName() strips the path provided by the client
+		_, disposition, errDisposition := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		if errDisposition != nil || disposition["filename"] == "" {
+			// skip regular form values
+			continue
+		}
+
+		data, errRead := ioutil.ReadAll(part)
+		if errRead != nil {
+			httpLog.Debug("failed to read multipart file",
+				zap.String("ident", h.conversation.Ident),
+				zap.String("field", part.FormName()),
+				zap.Error(errRead),
+			)
+
+			continue
+		}
+
+		errSave := streamutils.SaveUploadedFile(
+			h.conversation,
+			"HTTP MULTIPART UPLOAD to "+req.Host+req.URL.Path,
+			part.FormName(),
+			disposition["filename"],
+			data,
+			req.Host,
+			part.Header.Get(headerContentType),
+		)
+		if errSave != nil {
+			httpLog.Error("failed to save uploaded file",
+				zap.String("ident", h.conversation.Ident),
+				zap.String("field", part.FormName()),
+				zap.Error(errSave),
+			)
+		}
+	}
+
+	return true
+}
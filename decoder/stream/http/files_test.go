@@ -0,0 +1,86 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseContentRange(t *testing.T) {
+	start, end, total, err := parseContentRange("bytes 0-1023/146515")
+	if err != nil || start != 0 || end != 1023 || total != 146515 {
+		t.Fatal("unexpected range: ", start, end, total, err)
+	}
+
+	_, _, total, err = parseContentRange("bytes 1024-2047/*")
+	if err != nil || total != -1 {
+		t.Fatal("expected unknown total: ", total, err)
+	}
+
+	for _, r := range []string{"", "bytes */1000", "items 0-1/2", "bytes 10-5/20", "bytes 0-1"} {
+		if _, _, _, err = parseContentRange(r); err == nil {
+			t.Fatal("expected error for", r)
+		}
+	}
+}
+
+func TestRangeFileAssemble(t *testing.T) {
+	f := &rangeFile{total: 10}
+
+	// segments arrive out of order and overlap
+	f.segments = append(f.segments, rangeSegment{start: 6, data: []byte("6789")})
+	if f.complete() {
+		t.Fatal("file should be incomplete")
+	}
+
+	f.segments = append(f.segments, rangeSegment{start: 0, data: []byte("0123")})
+	if f.complete() {
+		t.Fatal("file with gap should be incomplete")
+	}
+
+	if data := f.assemble(); !bytes.Equal(data, []byte("0123\x00\x006789")) {
+		t.Fatal("unexpected incomplete data: ", data)
+	}
+
+	f.segments = append(f.segments, rangeSegment{start: 3, data: []byte("3456")})
+	if !f.complete() {
+		t.Fatal("file should be complete")
+	}
+
+	if data := f.assemble(); string(data) != "0123456789" {
+		t.Fatal("unexpected data: ", string(data))
+	}
+
+	if validRange(10, 1, 10) || !validRange(9, 1, 10) || validRange(maxRangeFileSize, 1, -1) || validRange(maxRangeFileSize-1, 2, -1) {
+		t.Fatal("unexpected range validation")
+	}
+}
+
+func TestRangeHugeTotal(t *testing.T) {
+	start, _, total, err := parseContentRange("bytes 999999999990-999999999999/1000000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if validRange(start, 10, total) {
+		t.Fatal("expected range of huge file to be rejected")
+	}
+
+	// without a total, a single segment far into the file must not be zero filled
+	f := &rangeFile{total: -1, segments: []rangeSegment{{start: maxRangeFileSize - 10, data: []byte("0123456789")}}}
+	if data := f.assemble(); data != nil {
+		t.Fatal("expected sparse file to be skipped, got", len(data), "bytes")
+	}
+}
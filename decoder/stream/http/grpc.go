@@ -0,0 +1,64 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	contentTypeGRPC = "application/grpc"
+
+	// each gRPC message is prefixed with a compressed flag and a 4 byte length
+	grpcMessageHeaderLen = 5
+)
+
+// isGRPC checks whether the content type denotes a gRPC call, e.g. application/grpc+proto.
+func isGRPC(contentType string) bool {
+	return strings.HasPrefix(contentType, contentTypeGRPC)
+}
+
+// setGRPC sets the service, method, message counts and status of a gRPC call.
+func setGRPC(h *types.HTTP, s *http2Stream) {
+	// the path has the format /package.Service/Method
+	p := strings.TrimPrefix(headerValue(s.requestHeaders, ":path"), "/")
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		h.GRPCService = p[:i]
+		h.GRPCMethod = p[i+1:]
+	} else {
+		h.GRPCService = p
+	}
+
+	h.GRPCRequestMessages = countGRPCMessages(s.requestBody)
+	h.GRPCResponseMessages = countGRPCMessages(s.responseBody)
+
+	// the status is sent in the trailers, unless the response consists of headers only
+	h.GRPCStatus = headerValue(s.trailers, "grpc-status")
+	if h.GRPCStatus == "" {
+		h.GRPCStatus = headerValue(s.responseHeaders, "grpc-status")
+	}
+}
+
+// countGRPCMessages returns the number of length prefixed messages in the body.
+// A truncated message at the end is counted as well.
+func countGRPCMessages(body []byte) (n int32) {
+	for off := 0; off+grpcMessageHeaderLen <= len(body); n++ {
+		off += grpcMessageHeaderLen + int(binary.BigEndian.Uint32(body[off+1:off+grpcMessageHeaderLen]))
+	}
+
+	return n
+}
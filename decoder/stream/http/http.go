@@ -42,9 +42,11 @@ var Decoder = &decoder.StreamDecoder{
 		return err
 	},
 	CanDecode: func(client, server []byte) bool {
-		return containsHTTPProtocolName(server) && containsHTTPMethod(client)
+		return bytes.HasPrefix(client, http2Preface) || (containsHTTPProtocolName(server) && containsHTTPMethod(client))
 	},
 	DeInit: func(sd *decoder.StreamDecoder) error {
+		flushRanges()
+
 		return httpLog.Sync()
 	},
 	Factory: &httpReader{},
@@ -0,0 +1,593 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/http2/hpack"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// HTTP/2 frame types and flags, see RFC 7540 section 6.
+const (
+	frameData         = 0x0
+	frameHeaders      = 0x1
+	framePushPromise  = 0x5
+	frameContinuation = 0x9
+
+	flagEndStream  = 0x1
+	flagEndHeaders = 0x4
+	flagPadded     = 0x8
+	flagPriority   = 0x20
+
+	http2FrameHeaderLen = 9
+	protoHTTP2          = "HTTP/2.0"
+
+	// as a passive observer we cannot enforce the table size negotiated via SETTINGS,
+	// so dynamic table size updates are accepted up to a generous limit.
+	http2MaxHeaderTableSize = 1 << 20
+)
+
+var (
+	http2Preface       = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+	switchingProtocols = []byte("HTTP/1.1 101")
+	headerEnd          = []byte("\r\n\r\n")
+	http2Hop2HopKeys   = map[string]struct{}{
+		"Connection":     {},
+		"Upgrade":        {},
+		"Http2-Settings": {},
+	}
+
+	errHTTP2FrameTruncated   = errors.New("truncated HTTP/2 frame")
+	errHTTP2InvalidPadding   = errors.New("invalid HTTP/2 frame padding")
+	errHTTP2Continuation     = errors.New("unexpected HTTP/2 CONTINUATION frame")
+	errHTTP2StreamIncomplete = errors.New("HTTP/2 stream incomplete")
+)
+
+// http2Stream collects the headers and bodies exchanged on a single HTTP/2 stream.
+type http2Stream struct {
+	id uint32
+
+	requestHeaders  []hpack.HeaderField
+	responseHeaders []hpack.HeaderField
+	trailers        []hpack.HeaderField
+
+	requestBody  []byte
+	responseBody []byte
+
+	requestTime  time.Time
+	responseTime time.Time
+
+	requestComplete  bool
+	responseComplete bool
+	pushed           bool
+}
+
+// http2Conn contains the streams of an HTTP/2 connection.
+type http2Conn struct {
+	streams map[uint32]*http2Stream
+}
+
+func (c *http2Conn) stream(id uint32) *http2Stream {
+	s, ok := c.streams[id]
+	if !ok {
+		s = &http2Stream{id: id}
+		c.streams[id] = s
+	}
+
+	return s
+}
+
+// sorted returns the streams ordered by their identifiers.
+func (c *http2Conn) sorted() []*http2Stream {
+	streams := make([]*http2Stream, 0, len(c.streams))
+	for _, s := range c.streams {
+		streams = append(streams, s)
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].id < streams[j].id
+	})
+
+	return streams
+}
+
+// parseHTTP2 checks whether the conversation uses HTTP/2 with prior knowledge or via an h2c upgrade,
+// and collects the streams of the connection.
+func (h *httpReader) parseHTTP2() (*http2Conn, bool) {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		conn           = &http2Conn{streams: make(map[uint32]*http2Stream)}
+		clientStart    int
+		serverStart    int
+	)
+
+	if bytes.HasPrefix(client.Data, http2Preface) {
+		clientStart = len(http2Preface)
+	} else {
+		i := bytes.Index(client.Data, http2Preface)
+		if i <= 0 || !bytes.HasPrefix(server.Data, switchingProtocols) {
+			return nil, false
+		}
+
+		// the request that initiated the upgrade is answered on stream 1
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(client.Data[:i])))
+		if err != nil || !strings.EqualFold(req.Header.Get("Upgrade"), "h2c") {
+			return nil, false
+		}
+
+		end := bytes.Index(server.Data, headerEnd)
+		if end < 0 {
+			return nil, false
+		}
+
+		conn.upgrade(req, client.TimeAt(0))
+
+		clientStart = i + len(http2Preface)
+		serverStart = end + len(headerEnd)
+	}
+
+	if err := conn.parseFrames(client, clientStart); err != nil {
+		httpLog.Debug("failed to parse HTTP/2 client frames",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+	}
+
+	if err := conn.parseFrames(server, serverStart); err != nil {
+		httpLog.Debug("failed to parse HTTP/2 server frames",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+	}
+
+	return conn, true
+}
+
+// upgrade adds the HTTP/1.1 request that initiated an h2c upgrade as stream 1.
+func (c *http2Conn) upgrade(req *http.Request, ts time.Time) {
+	s := c.stream(1)
+	s.requestTime = ts
+	s.requestComplete = true
+	s.requestHeaders = []hpack.HeaderField{
+		{Name: ":method", Value: req.Method},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: req.Host},
+		{Name: ":path", Value: req.RequestURI},
+	}
+
+	for k, vals := range req.Header {
+		if _, ok := http2Hop2HopKeys[k]; ok {
+			continue
+		}
+
+		for _, v := range vals {
+			s.requestHeaders = append(s.requestHeaders, hpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+
+	if body, err := ioutil.ReadAll(req.Body); err == nil {
+		s.requestBody = body
+	}
+}
+
+// parseFrames reads the frames sent in one direction, starting at offset.
+func (c *http2Conn) parseFrames(dir *core.Direction, offset int) error {
+	var (
+		data = dir.Data
+		dec  = hpack.NewDecoder(4096, nil)
+
+		// header block fragments are buffered until END_HEADERS is set
+		block      []byte
+		blockType  byte
+		blockID    uint32
+		blockFlags byte
+		blockTime  time.Time
+		promisedID uint32
+	)
+
+	dec.SetAllowedMaxDynamicTableSize(http2MaxHeaderTableSize)
+
+	for offset+http2FrameHeaderLen <= len(data) {
+		var (
+			length = int(data[offset])<<16 | int(data[offset+1])<<8 | int(data[offset+2])
+			typ    = data[offset+3]
+			flags  = data[offset+4]
+			id     = binary.BigEndian.Uint32(data[offset+5:offset+9]) & 0x7fffffff
+			ts     = dir.TimeAt(offset)
+		)
+
+		if offset+http2FrameHeaderLen+length > len(data) {
+			return errHTTP2FrameTruncated
+		}
+
+		payload := data[offset+http2FrameHeaderLen : offset+http2FrameHeaderLen+length]
+		offset += http2FrameHeaderLen + length
+
+		if block != nil && typ != frameContinuation {
+			return errHTTP2Continuation
+		}
+
+		switch typ {
+		case frameData:
+			p, err := stripPadding(payload, flags)
+			if err != nil {
+				return err
+			}
+
+			s := c.stream(id)
+			if dir.Client {
+				s.requestBody = append(s.requestBody, p...)
+				s.requestComplete = flags&flagEndStream != 0
+			} else {
+				s.responseBody = append(s.responseBody, p...)
+				s.responseComplete = flags&flagEndStream != 0
+			}
+
+			continue
+		case frameHeaders:
+			p, err := stripPadding(payload, flags)
+			if err != nil {
+				return err
+			}
+
+			if flags&flagPriority != 0 {
+				if len(p) < 5 {
+					return errHTTP2FrameTruncated
+				}
+
+				p = p[5:]
+			}
+
+			block = append([]byte{}, p...)
+		case framePushPromise:
+			p, err := stripPadding(payload, flags)
+			if err != nil {
+				return err
+			}
+
+			if len(p) < 4 {
+				return errHTTP2FrameTruncated
+			}
+
+			promisedID = binary.BigEndian.Uint32(p[:4]) & 0x7fffffff
+			block = append([]byte{}, p[4:]...)
+		case frameContinuation:
+			if block == nil || id != blockID {
+				return errHTTP2Continuation
+			}
+
+			block = append(block, payload...)
+		default:
+			// SETTINGS, PING, PRIORITY, GOAWAY and WINDOW_UPDATE carry no data of interest
+			continue
+		}
+
+		if typ != frameContinuation {
+			blockType, blockID, blockFlags, blockTime = typ, id, flags, ts
+		}
+
+		if flags&flagEndHeaders == 0 {
+			continue
+		}
+
+		// the header block must be decoded even if the stream is of no interest,
+		// to keep the dynamic table in sync with the encoder
+		fields, err := dec.DecodeFull(block)
+		if err != nil {
+			return err
+		}
+
+		block = nil
+
+		if blockType == framePushPromise {
+			s := c.stream(promisedID)
+			s.pushed = true
+			s.requestHeaders = fields
+			s.requestTime = blockTime
+			s.requestComplete = true
+
+			continue
+		}
+
+		c.addHeaders(c.stream(blockID), dir.Client, fields, blockTime, blockFlags&flagEndStream != 0)
+	}
+
+	if offset < len(data) {
+		return errHTTP2FrameTruncated
+	}
+
+	return nil
+}
+
+// addHeaders assigns a decoded header block to the request, response or trailers of a stream.
+func (c *http2Conn) addHeaders(s *http2Stream, client bool, fields []hpack.HeaderField, ts time.Time, endStream bool) {
+	if client {
+		if s.requestHeaders == nil {
+			s.requestHeaders = fields
+			s.requestTime = ts
+		}
+
+		s.requestComplete = endStream
+
+		return
+	}
+
+	if s.responseHeaders == nil {
+		// skip informational responses such as 100 Continue
+		if strings.HasPrefix(headerValue(fields, ":status"), "1") {
+			return
+		}
+
+		s.responseHeaders = fields
+		s.responseTime = ts
+	} else {
+		s.trailers = append(s.trailers, fields...)
+	}
+
+	s.responseComplete = endStream
+}
+
+// stripPadding removes the padding from DATA, HEADERS and PUSH_PROMISE frames.
+func stripPadding(payload []byte, flags byte) ([]byte, error) {
+	if flags&flagPadded == 0 {
+		return payload, nil
+	}
+
+	if len(payload) == 0 || int(payload[0]) >= len(payload) {
+		return nil, errHTTP2InvalidPadding
+	}
+
+	return payload[1 : len(payload)-int(payload[0])], nil
+}
+
+func headerValue(fields []hpack.HeaderField, name string) string {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+
+	return ""
+}
+
+// toHeader converts all regular header fields to an http.Header.
+func toHeader(fields []hpack.HeaderField) http.Header {
+	header := make(http.Header)
+
+	for _, f := range fields {
+		if !strings.HasPrefix(f.Name, ":") {
+			header.Add(f.Name, f.Value)
+		}
+	}
+
+	return header
+}
+
+// request converts the stream into an http.Request.
+func (s *http2Stream) request() *http.Request {
+	var (
+		p         = headerValue(s.requestHeaders, ":path")
+		authority = headerValue(s.requestHeaders, ":authority")
+		header    = toHeader(s.requestHeaders)
+	)
+
+	u, err := url.ParseRequestURI(p)
+	if err != nil {
+		u = &url.URL{Path: p}
+	}
+
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+
+	return &http.Request{
+		Method:        headerValue(s.requestHeaders, ":method"),
+		URL:           u,
+		Proto:         protoHTTP2,
+		ProtoMajor:    2,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(s.requestBody)),
+		ContentLength: int64(len(s.requestBody)),
+		Host:          authority,
+		RequestURI:    p,
+	}
+}
+
+// response converts the stream into an http.Response.
+func (s *http2Stream) response(req *http.Request) *http.Response {
+	var (
+		status        = headerValue(s.responseHeaders, ":status")
+		code, _       = strconv.Atoi(status)
+		header        = toHeader(s.responseHeaders)
+		contentLength = int64(-1)
+	)
+
+	if l, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		contentLength = l
+	}
+
+	return &http.Response{
+		Status:        status + " " + http.StatusText(code),
+		StatusCode:    code,
+		Proto:         protoHTTP2,
+		ProtoMajor:    2,
+		Header:        header,
+		Trailer:       toHeader(s.trailers),
+		Body:          ioutil.NopCloser(bytes.NewReader(s.responseBody)),
+		ContentLength: contentLength,
+		Request:       req,
+	}
+}
+
+// http2Record creates the audit record for a stream.
+func (h *httpReader) http2Record(s *http2Stream, req *http.Request) *types.HTTP {
+	ht := &types.HTTP{}
+
+	if s.responseHeaders != nil {
+		ht = newHTTPFromResponse(s.response(req))
+	}
+
+	ts := s.requestTime
+	if ts.IsZero() {
+		ts = h.conversation.FirstClientPacket
+	}
+
+	setRequest(ht, &httpRequest{
+		request:   req,
+		timestamp: ts.UnixNano(),
+		clientIP:  h.conversation.ClientIP,
+		serverIP:  h.conversation.ServerIP,
+	})
+
+	ht.Proto = protoHTTP2
+
+	if isGRPC(req.Header.Get(headerContentType)) {
+		setGRPC(ht, s)
+	}
+
+	return ht
+}
+
+// decodeHTTP2 writes audit records for all streams of an HTTP/2 connection.
+// It returns false if the conversation does not use HTTP/2.
+func (h *httpReader) decodeHTTP2() bool {
+	conn, ok := h.parseHTTP2()
+	if !ok {
+		return false
+	}
+
+	for _, s := range conn.sorted() {
+		// stream 0 is used for connection control only and reset streams may lack a request
+		if s.requestHeaders == nil {
+			continue
+		}
+
+		req := s.request()
+
+		// parsing the form consumes the body, so it has to be restored afterwards
+		err := req.ParseForm()
+		if err != nil {
+			httpLog.Debug("failed to read HTTP/2 form values",
+				zap.String("ident", h.conversation.Ident),
+				zap.Error(err),
+			)
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(s.requestBody))
+
+		ht := h.http2Record(s, req)
+
+		atomic.AddInt64(&streamutils.Stats.NumRequests, 1)
+
+		if s.responseHeaders != nil {
+			atomic.AddInt64(&streamutils.Stats.NumResponses, 1)
+		} else {
+			atomic.AddInt64(&streamutils.Stats.NumUnansweredRequests, 1)
+		}
+
+		if credentials.Decoder.Writer != nil {
+			h.searchForLoginParams(req)
+			h.searchForBasicAuth(req)
+		}
+
+		writeHTTP(ht, h.conversation.Ident)
+
+		if decoderconfig.Instance.FileStorage != "" {
+			h.saveHTTP2Bodies(s, req)
+		}
+	}
+
+	return true
+}
+
+// saveHTTP2Bodies writes the request and response bodies of a stream to disk.
+func (h *httpReader) saveHTTP2Bodies(s *http2Stream, req *http.Request) {
+	var (
+		name = path.Base(req.URL.Path)
+		err  error
+	)
+
+	if len(s.requestBody) > 0 {
+		if !s.requestComplete {
+			err = errHTTP2StreamIncomplete
+		}
+
+		// files uploaded in multipart forms are extracted individually
+		if (err == nil || decoderconfig.Instance.WriteIncomplete) && !h.saveUploads(req, s.requestBody) {
+			errSave := streamutils.SaveFile(
+				h.conversation,
+				"HTTP/2 REQUEST to "+req.URL.Path,
+				name,
+				err,
+				s.requestBody,
+				req.Header[headerContentEncoding],
+				req.Host,
+				req.Header.Get(headerContentType),
+			)
+			if errSave != nil {
+				httpLog.Error("failed to save HTTP/2 request body", zap.String("ident", h.conversation.Ident), zap.Error(errSave))
+			}
+		}
+	}
+
+	if len(s.responseBody) > 0 {
+		err = nil
+		if !s.responseComplete {
+			err = errHTTP2StreamIncomplete
+		}
+
+		header := toHeader(s.responseHeaders)
+
+		if err == nil && headerValue(s.responseHeaders, ":status") == strconv.Itoa(http.StatusPartialContent) {
+			// partial responses are reassembled into the complete file
+			if errSave := h.saveRange(req, header, s.responseBody); errSave != nil {
+				httpLog.Error("failed to save HTTP/2 range response", zap.String("ident", h.conversation.Ident), zap.Error(errSave))
+			}
+		} else if err == nil || decoderconfig.Instance.WriteIncomplete {
+			errSave := streamutils.SaveFile(
+				h.conversation,
+				"HTTP/2 RESPONSE from "+req.Host+req.URL.Path,
+				name,
+				err,
+				s.responseBody,
+				header[headerContentEncoding],
+				req.Host,
+				header.Get(headerContentType),
+			)
+			if errSave != nil {
+				httpLog.Error("failed to save HTTP/2 response body", zap.String("ident", h.conversation.Ident), zap.Error(errSave))
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"golang.org/x/net/http2/hpack"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+// http2Encoder produces the frames for one direction of a connection.
+type http2Encoder struct {
+	buf bytes.Buffer
+	enc *hpack.Encoder
+	out []byte
+}
+
+func newHTTP2Encoder() *http2Encoder {
+	e := &http2Encoder{}
+	e.enc = hpack.NewEncoder(&e.buf)
+
+	return e
+}
+
+func (e *http2Encoder) frame(typ, flags byte, id uint32, payload []byte) {
+	var hdr [http2FrameHeaderLen]byte
+
+	hdr[0] = byte(len(payload) >> 16)
+	hdr[1] = byte(len(payload) >> 8)
+	hdr[2] = byte(len(payload))
+	hdr[3] = typ
+	hdr[4] = flags
+	binary.BigEndian.PutUint32(hdr[5:], id)
+
+	e.out = append(e.out, hdr[:]...)
+	e.out = append(e.out, payload...)
+}
+
+// headers writes a header block split into a HEADERS and a CONTINUATION frame.
+func (e *http2Encoder) headers(id uint32, endStream bool, fields ...string) {
+	e.buf.Reset()
+
+	for i := 0; i < len(fields); i += 2 {
+		_ = e.enc.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]})
+	}
+
+	var (
+		block = e.buf.Bytes()
+		half  = len(block) / 2
+		flags byte
+	)
+
+	if endStream {
+		flags = flagEndStream
+	}
+
+	e.frame(frameHeaders, flags, id, block[:half])
+	e.frame(frameContinuation, flagEndHeaders, id, block[half:])
+}
+
+func grpcMessage(msg string) []byte {
+	b := make([]byte, grpcMessageHeaderLen+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	copy(b[grpcMessageHeaderLen:], msg)
+
+	return b
+}
+
+func testConversation(client, server []byte) *core.ConversationInfo {
+	var (
+		ts   = time.Unix(1600000000, 0)
+		conv = &core.ConversationInfo{
+			ClientIP: "10.0.0.2",
+			ServerIP: "10.0.0.1",
+		}
+	)
+
+	conv.Data = append(conv.Data,
+		&core.StreamData{
+			RawData:            client,
+			Dir:                reassembly.TCPDirClientToServer,
+			CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+		},
+		&core.StreamData{
+			RawData:            server,
+			Dir:                reassembly.TCPDirServerToClient,
+			CaptureInformation: gopacket.CaptureInfo{Timestamp: ts.Add(time.Second)},
+		},
+	)
+
+	return conv
+}
+
+func TestHTTP2PriorKnowledgeGRPC(t *testing.T) {
+	var (
+		client = newHTTP2Encoder()
+		server = newHTTP2Encoder()
+	)
+
+	client.out = append(client.out, http2Preface...)
+	client.frame(0x4, 0, 0, nil) // SETTINGS
+
+	// two calls, to make sure the dynamic table is used across streams
+	for _, id := range []uint32{1, 3} {
+		client.headers(id, false,
+			":method", "POST",
+			":scheme", "http",
+			":authority", "api.internal:50051",
+			":path", "/helloworld.Greeter/SayHello",
+			"content-type", "application/grpc",
+			"user-agent", "grpc-go/1.34.0",
+			"authorization", "Basic dXNlcjpwYXNz",
+		)
+
+		// padded DATA frame with two messages
+		body := append(grpcMessage("hello"), grpcMessage("world")...)
+		client.frame(frameData, flagEndStream|flagPadded, id, append(append([]byte{3}, body...), 0, 0, 0))
+
+		server.headers(id, false,
+			":status", "200",
+			"content-type", "application/grpc",
+		)
+		server.frame(frameData, 0, id, grpcMessage("hi"))
+		server.headers(id, true, "grpc-status", "0", "grpc-message", "")
+	}
+
+	h := &httpReader{conversation: testConversation(client.out, server.out)}
+
+	conn, ok := h.parseHTTP2()
+	if !ok {
+		t.Fatal("expected HTTP/2 conversation")
+	}
+
+	streams := conn.sorted()
+	if len(streams) != 2 {
+		t.Fatal("expected 2 streams, got", len(streams))
+	}
+
+	for _, s := range streams {
+		if !s.requestComplete || !s.responseComplete {
+			t.Fatal("expected complete stream", s.id)
+		}
+
+		req := s.request()
+		ht := h.http2Record(s, req)
+
+		if ht.Proto != protoHTTP2 || ht.Method != "POST" || ht.Host != "api.internal:50051" || ht.URL != "/helloworld.Greeter/SayHello" {
+			t.Fatal("unexpected request:", ht.Proto, ht.Method, ht.Host, ht.URL)
+		}
+
+		if ht.StatusCode != 200 || ht.UserAgent != "grpc-go/1.34.0" {
+			t.Fatal("unexpected response:", ht.StatusCode, ht.UserAgent)
+		}
+
+		if ht.GRPCService != "helloworld.Greeter" || ht.GRPCMethod != "SayHello" {
+			t.Fatal("unexpected gRPC call:", ht.GRPCService, ht.GRPCMethod)
+		}
+
+		if ht.GRPCRequestMessages != 2 || ht.GRPCResponseMessages != 1 || ht.GRPCStatus != "0" {
+			t.Fatal("unexpected gRPC messages:", ht.GRPCRequestMessages, ht.GRPCResponseMessages, ht.GRPCStatus)
+		}
+
+		if u, p, ok := req.BasicAuth(); !ok || u != "user" || p != "pass" {
+			t.Fatal("expected basic auth credentials")
+		}
+
+		if !s.requestTime.Before(s.responseTime) {
+			t.Fatal("unexpected timestamps", s.requestTime, s.responseTime)
+		}
+	}
+}
+
+func TestHTTP2Upgrade(t *testing.T) {
+	var (
+		client = newHTTP2Encoder()
+		server = newHTTP2Encoder()
+	)
+
+	client.out = append(client.out, "GET /index.html HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAARAAAAAAAIAAAAA\r\n\r\n"...)
+	client.out = append(client.out, http2Preface...)
+
+	server.out = append(server.out, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n"...)
+	server.headers(1, false, ":status", "200", "content-type", "text/html", "server", "nginx/1.18.0")
+	server.frame(frameData, flagEndStream, 1, []byte("<html></html>"))
+
+	h := &httpReader{conversation: testConversation(client.out, server.out)}
+
+	conn, ok := h.parseHTTP2()
+	if !ok {
+		t.Fatal("expected HTTP/2 conversation")
+	}
+
+	s := conn.streams[1]
+	if s == nil || len(conn.streams) != 1 {
+		t.Fatal("expected upgraded stream")
+	}
+
+	ht := h.http2Record(s, s.request())
+	if ht.Method != "GET" || ht.Host != "example.com" || ht.URL != "/index.html" || ht.StatusCode != 200 {
+		t.Fatal("unexpected record:", ht.Method, ht.Host, ht.URL, ht.StatusCode)
+	}
+
+	if ht.ServerName != "nginx/1.18.0" || ht.ResContentLength != 13 || ht.GRPCService != "" {
+		t.Fatal("unexpected response:", ht.ServerName, ht.ResContentLength)
+	}
+
+	if _, ok := ht.RequestHeader["Upgrade"]; ok {
+		t.Fatal("hop-by-hop header should be removed")
+	}
+}
+
+func TestHTTP1NotHTTP2(t *testing.T) {
+	h := &httpReader{conversation: testConversation(
+		[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		[]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"),
+	)}
+
+	if _, ok := h.parseHTTP2(); ok {
+		t.Fatal("HTTP/1.1 conversation detected as HTTP/2")
+	}
+}
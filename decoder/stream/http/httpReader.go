@@ -30,6 +30,7 @@ import (
 	"github.com/dreadl0ck/netcap/decoder/core"
 	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
 	"github.com/dreadl0ck/netcap/decoder/stream/software"
+	"github.com/dreadl0ck/netcap/decoder/stream/token"
 	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
 	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
 	"github.com/dreadl0ck/netcap/types"
@@ -89,6 +90,10 @@ func (h *httpReader) Decode() {
 		return
 	}
 
+	if h.decodeHTTP2() || h.decodeWebSocket() {
+		return
+	}
+
 	streamutils.DecodeConversation(
 		h.conversation.Ident,
 		h.conversation.Data,
@@ -212,6 +217,11 @@ func writeHTTP(h *types.HTTP, ident string) {
 		// h.ResponseBody = t.server.DataSlice().bytes()
 	}
 
+	// extract tokens before writing, serializing the record modifies the timestamp
+	if token.Decoder.Writer != nil {
+		token.ExtractTokens(h, ident)
+	}
+
 	// export metrics if configured
 	if decoderconfig.Instance.ExportMetrics {
 		h.Inc()
@@ -245,6 +255,23 @@ func writeHTTP(h *types.HTTP, ident string) {
 	})
 }
 
+// readBody reads the body of a request or response.
+// Chunked bodies are decoded from the buffered reader directly instead of the chunked reader of net/http,
+// which fails on the malformed chunk framing sent by some embedded servers.
+// The original body is only closed if it has been read, since closing a chunked body consumes the stream.
+func readBody(b *bufio.Reader, transferEncoding []string, body io.ReadCloser) ([]byte, error) {
+	if body != http.NoBody && len(transferEncoding) > 0 && transferEncoding[0] == "chunked" {
+		return streamutils.DecodeChunked(b)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err == nil {
+		_ = body.Close()
+	}
+
+	return data, err
+}
+
 // HTTP Response
 
 func (h *httpReader) readResponse(b *bufio.Reader) error {
@@ -261,7 +288,7 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 		return err
 	}
 
-	body, err := ioutil.ReadAll(res.Body)
+	body, err := readBody(b, res.TransferEncoding, res.Body)
 	s := len(body)
 	if err != nil {
 		httpLog.Error(
@@ -271,8 +298,6 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 			zap.Int("length", s),
 		)
 	} else {
-		_ = res.Body.Close()
-
 		// Restore body so it can be read again
 		res.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 	}
@@ -331,6 +356,11 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 
 			req := h.requests[numResponses-1]
 			if req != nil {
+				// partial responses are reassembled into the complete file
+				if res.StatusCode == http.StatusPartialContent {
+					return h.saveRange(req.request, res.Header, body)
+				}
+
 				host = req.request.Host
 				name = path.Base(req.request.URL.Path)
 				source += " from " + req.request.Host + req.request.URL.Path
@@ -339,7 +369,7 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 		}
 
 		// save file to disk
-		return streamutils.SaveFile(h.conversation, source, name, err, body, encoding, ctype, host)
+		return streamutils.SaveFile(h.conversation, source, name, err, body, encoding, host, ctype)
 	}
 
 	return nil
@@ -382,7 +412,7 @@ func (h *httpReader) readRequest(b *bufio.Reader) error {
 		return err
 	}
 
-	body, err := ioutil.ReadAll(req.Body)
+	body, err := readBody(b, req.TransferEncoding, req.Body)
 	s := len(body)
 	if err != nil {
 		httpLog.Error(
@@ -393,8 +423,6 @@ func (h *httpReader) readRequest(b *bufio.Reader) error {
 		)
 		// continue execution
 	} else {
-		_ = req.Body.Close()
-
 		// Restore body so it can be read again
 		req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 	}
@@ -438,6 +466,11 @@ func (h *httpReader) readRequest(b *bufio.Reader) error {
 	if req.Method == methodPOST {
 		// write request payload to disk if configured
 		if (err == nil || decoderconfig.Instance.WriteIncomplete) && decoderconfig.Instance.FileStorage != "" {
+			// files uploaded in multipart forms are extracted individually
+			if h.saveUploads(req, body) {
+				return nil
+			}
+
 			return streamutils.SaveFile(
 				h.conversation,
 				"HTTP POST REQUEST to "+req.URL.Path,
@@ -445,8 +478,8 @@ func (h *httpReader) readRequest(b *bufio.Reader) error {
 				err,
 				body,
 				req.Header[headerContentEncoding],
-				strings.Join(req.Header[headerContentType], " "),
 				req.Host,
+				strings.Join(req.Header[headerContentType], " "),
 			)
 		}
 	}
@@ -0,0 +1,49 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadBodyMalformedChunked(t *testing.T) {
+	// chunks framed with bare line feeds, followed by a second response on the same connection
+	b := bufio.NewReader(strings.NewReader(
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n4\nWiki\n5\npedia\n0\n\n" +
+			"HTTP/1.1 404 Not Found\r\nContent-Length: 5\r\n\r\nerror",
+	))
+
+	res, err := http.ReadResponse(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := readBody(b, res.TransferEncoding, res.Body)
+	if err != nil || string(body) != "Wikipedia" {
+		t.Fatal("unexpected chunked body: ", string(body), err)
+	}
+
+	res, err = http.ReadResponse(b, nil)
+	if err != nil || res.StatusCode != http.StatusNotFound {
+		t.Fatal("failed to read the following response: ", err)
+	}
+
+	body, err = readBody(b, res.TransferEncoding, res.Body)
+	if err != nil || string(body) != "error" {
+		t.Fatal("unexpected body: ", string(body), err)
+	}
+}
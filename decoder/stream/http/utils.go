@@ -14,13 +14,12 @@
 package http
 
 import (
-	"bytes"
-	"compress/gzip"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
 
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
 	"github.com/dreadl0ck/netcap/types"
 )
 
@@ -42,18 +41,12 @@ func setRequest(h *types.HTTP, req *httpRequest) {
 
 	body, err := ioutil.ReadAll(req.request.Body)
 	if err == nil {
-		h.ContentTypeDetected = http.DetectContentType(body)
-
 		// decompress if required
-		if h.ReqContentEncoding == "gzip" {
-			r, errReader := gzip.NewReader(bytes.NewReader(body))
-			if errReader == nil {
-				body, err = ioutil.ReadAll(r)
-				if err == nil {
-					h.ContentTypeDetected = http.DetectContentType(body)
-				}
-			}
+		if decoded, errDecode := streamutils.DecodeContent(body, req.request.Header[headerContentEncoding]); errDecode == nil {
+			body = decoded
 		}
+
+		h.ContentTypeDetected = http.DetectContentType(body)
 	}
 
 	// manually replace commas, to avoid breaking them the CSV
@@ -113,17 +106,11 @@ func newHTTPFromResponse(res *http.Response) *types.HTTP {
 		}
 
 		// decompress payload if required
-		if res.Header.Get(headerContentEncoding) == "gzip" {
-			r, errReader := gzip.NewReader(bytes.NewReader(body))
-			if errReader == nil {
-				body, err = ioutil.ReadAll(r)
-				if err == nil {
-					detected = http.DetectContentType(body)
-				}
-			}
-		} else {
-			detected = http.DetectContentType(body)
+		if decoded, errDecode := streamutils.DecodeContent(body, res.Header[headerContentEncoding]); errDecode == nil {
+			body = decoded
 		}
+
+		detected = http.DetectContentType(body)
 	}
 
 	return &types.HTTP{
@@ -0,0 +1,159 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/stream/websocket"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// decodeWebSocket writes the upgrade handshake and the messages of a websocket connection.
+// It returns false if the connection was not upgraded to the websocket protocol.
+func (h *httpReader) decodeWebSocket() bool {
+	req, res, messages, ok := h.parseWebSocket(decoderconfig.Instance.IncludePayloads)
+	if !ok {
+		return false
+	}
+
+	h.writeHandshake(req, res)
+
+	if websocket.Decoder.Writer == nil {
+		return true
+	}
+
+	for _, m := range messages {
+		websocket.WriteMessage(m)
+	}
+
+	return true
+}
+
+// parseWebSocket parses the upgrade handshake and decodes the websocket frames exchanged afterwards.
+func (h *httpReader) parseWebSocket(includePayloads bool) (*http.Request, *http.Response, []*types.WebSocketMessage, bool) {
+	client, server := core.SplitDirections(h.conversation.Data)
+
+	if !bytes.HasPrefix(server.Data, switchingProtocols) {
+		return nil, nil, nil, false
+	}
+
+	var (
+		reqEnd = bytes.Index(client.Data, headerEnd)
+		resEnd = bytes.Index(server.Data, headerEnd)
+	)
+
+	if reqEnd < 0 || resEnd < 0 {
+		return nil, nil, nil, false
+	}
+
+	reqEnd += len(headerEnd)
+	resEnd += len(headerEnd)
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(client.Data[:reqEnd])))
+	if err != nil || !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return nil, nil, nil, false
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(server.Data[:resEnd])), req)
+	if err != nil {
+		return nil, nil, nil, false
+	}
+
+	ext := websocket.ParseExtensions(res.Header.Get("Sec-WebSocket-Extensions"))
+
+	messages := append(
+		h.decodeFrames(client, reqEnd, ext, includePayloads),
+		h.decodeFrames(server, resEnd, ext, includePayloads)...,
+	)
+
+	// merge the messages of both directions
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp < messages[j].Timestamp
+	})
+
+	for _, m := range messages {
+		m.URL = removeCommas(req.URL.String())
+		m.Host = req.Host
+		m.Protocol = res.Header.Get("Sec-WebSocket-Protocol")
+		m.ClientIP = h.conversation.ClientIP
+		m.ServerIP = h.conversation.ServerIP
+		m.ClientPort = h.conversation.ClientPort
+		m.ServerPort = h.conversation.ServerPort
+	}
+
+	return req, res, messages, true
+}
+
+// decodeFrames decodes the websocket frames sent in one direction, beginning at start.
+func (h *httpReader) decodeFrames(dir *core.Direction, start int, ext websocket.Extensions, includePayloads bool) []*types.WebSocketMessage {
+	messages, err := websocket.NewReader(dir.Client, ext, includePayloads).Decode(
+		dir.Data[start:],
+		func(offset int) time.Time {
+			return dir.TimeAt(start + offset)
+		},
+	)
+	if err != nil {
+		httpLog.Debug("failed to decode websocket frames",
+			zap.String("ident", h.conversation.Ident),
+			zap.Bool("client", dir.Client),
+			zap.Error(err),
+		)
+	}
+
+	return messages
+}
+
+// writeHandshake writes the HTTP audit record for the upgrade handshake.
+func (h *httpReader) writeHandshake(req *http.Request, res *http.Response) {
+	err := req.ParseForm()
+	if err != nil {
+		httpLog.Debug("failed to read HTTP form values",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+	}
+
+	res.Request = req
+	ht := newHTTPFromResponse(res)
+
+	if credentials.Decoder.Writer != nil {
+		h.searchForLoginParams(req)
+		h.searchForBasicAuth(req)
+	}
+
+	atomic.AddInt64(&streamutils.Stats.NumRequests, 1)
+	atomic.AddInt64(&streamutils.Stats.NumResponses, 1)
+
+	setRequest(ht, &httpRequest{
+		request:   req,
+		timestamp: h.conversation.FirstClientPacket.UnixNano(),
+		clientIP:  h.conversation.ClientIP,
+		serverIP:  h.conversation.ServerIP,
+	})
+
+	writeHTTP(ht, h.conversation.Ident)
+}
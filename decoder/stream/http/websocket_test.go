@@ -0,0 +1,54 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"testing"
+)
+
+func TestParseWebSocket(t *testing.T) {
+	client := []byte("GET /chat?room=1 HTTP/1.1\r\nHost: ws.example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	client = append(client, 0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58) // masked "Hello"
+
+	server := []byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\nSec-WebSocket-Protocol: chat\r\n\r\n")
+	server = append(server, 0x81, 0x02, 'h', 'i')
+
+	h := &httpReader{conversation: testConversation(client, server)}
+
+	req, res, messages, ok := h.parseWebSocket(false)
+	if !ok {
+		t.Fatal("expected websocket upgrade")
+	}
+
+	if req.URL.Path != "/chat" || res.StatusCode != 101 {
+		t.Fatal("unexpected handshake: ", req.URL, res.StatusCode)
+	}
+
+	if len(messages) != 2 {
+		t.Fatal("expected 2 messages, got", len(messages))
+	}
+
+	if !messages[0].IsClient || messages[0].PayloadSize != 5 || messages[0].Host != "ws.example.com" || messages[0].URL != "/chat?room=1" {
+		t.Fatal("unexpected client message: ", messages[0])
+	}
+
+	if messages[1].IsClient || messages[1].PayloadSize != 2 || messages[1].Protocol != "chat" {
+		t.Fatal("unexpected server message: ", messages[1])
+	}
+
+	// HTTP/2 is handled separately
+	if _, _, _, ok = (&httpReader{conversation: testConversation(http2Preface, nil)}).parseWebSocket(false); ok {
+		t.Fatal("unexpected websocket upgrade")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package iec104
+
+// asduType describes an ASDU type identification.
+type asduType struct {
+	name        string
+	description string
+
+	// size of a single information element without the object address, -1 if variable
+	size int
+
+	// control commands that alter the state of the controlled station
+	control bool
+}
+
+var asduTypes = map[byte]asduType{
+	1:   {"M_SP_NA_1", "Single-point information", 1, false},
+	2:   {"M_SP_TA_1", "Single-point information with time tag", 4, false},
+	3:   {"M_DP_NA_1", "Double-point information", 1, false},
+	4:   {"M_DP_TA_1", "Double-point information with time tag", 4, false},
+	5:   {"M_ST_NA_1", "Step position information", 2, false},
+	6:   {"M_ST_TA_1", "Step position information with time tag", 5, false},
+	7:   {"M_BO_NA_1", "Bitstring of 32 bit", 5, false},
+	8:   {"M_BO_TA_1", "Bitstring of 32 bit with time tag", 8, false},
+	9:   {"M_ME_NA_1", "Measured value, normalized value", 3, false},
+	10:  {"M_ME_TA_1", "Measured value, normalized value with time tag", 6, false},
+	11:  {"M_ME_NB_1", "Measured value, scaled value", 3, false},
+	12:  {"M_ME_TB_1", "Measured value, scaled value with time tag", 6, false},
+	13:  {"M_ME_NC_1", "Measured value, short floating point number", 5, false},
+	14:  {"M_ME_TC_1", "Measured value, short floating point number with time tag", 8, false},
+	15:  {"M_IT_NA_1", "Integrated totals", 5, false},
+	16:  {"M_IT_TA_1", "Integrated totals with time tag", 8, false},
+	17:  {"M_EP_TA_1", "Event of protection equipment with time tag", 6, false},
+	18:  {"M_EP_TB_1", "Packed start events of protection equipment with time tag", 7, false},
+	19:  {"M_EP_TC_1", "Packed output circuit information of protection equipment with time tag", 7, false},
+	20:  {"M_PS_NA_1", "Packed single-point information with status change detection", 5, false},
+	21:  {"M_ME_ND_1", "Measured value, normalized value without quality descriptor", 2, false},
+	30:  {"M_SP_TB_1", "Single-point information with time tag CP56Time2a", 8, false},
+	31:  {"M_DP_TB_1", "Double-point information with time tag CP56Time2a", 8, false},
+	32:  {"M_ST_TB_1", "Step position information with time tag CP56Time2a", 9, false},
+	33:  {"M_BO_TB_1", "Bitstring of 32 bit with time tag CP56Time2a", 12, false},
+	34:  {"M_ME_TD_1", "Measured value, normalized value with time tag CP56Time2a", 10, false},
+	35:  {"M_ME_TE_1", "Measured value, scaled value with time tag CP56Time2a", 10, false},
+	36:  {"M_ME_TF_1", "Measured value, short floating point number with time tag CP56Time2a", 12, false},
+	37:  {"M_IT_TB_1", "Integrated totals with time tag CP56Time2a", 12, false},
+	38:  {"M_EP_TD_1", "Event of protection equipment with time tag CP56Time2a", 10, false},
+	39:  {"M_EP_TE_1", "Packed start events of protection equipment with time tag CP56Time2a", 11, false},
+	40:  {"M_EP_TF_1", "Packed output circuit information of protection equipment with time tag CP56Time2a", 11, false},
+	45:  {"C_SC_NA_1", "Single command", 1, true},
+	46:  {"C_DC_NA_1", "Double command", 1, true},
+	47:  {"C_RC_NA_1", "Regulating step command", 1, true},
+	48:  {"C_SE_NA_1", "Set point command, normalized value", 3, true},
+	49:  {"C_SE_NB_1", "Set point command, scaled value", 3, true},
+	50:  {"C_SE_NC_1", "Set point command, short floating point number", 5, true},
+	51:  {"C_BO_NA_1", "Bitstring of 32 bit command", 4, true},
+	58:  {"C_SC_TA_1", "Single command with time tag CP56Time2a", 8, true},
+	59:  {"C_DC_TA_1", "Double command with time tag CP56Time2a", 8, true},
+	60:  {"C_RC_TA_1", "Regulating step command with time tag CP56Time2a", 8, true},
+	61:  {"C_SE_TA_1", "Set point command, normalized value with time tag CP56Time2a", 10, true},
+	62:  {"C_SE_TB_1", "Set point command, scaled value with time tag CP56Time2a", 10, true},
+	63:  {"C_SE_TC_1", "Set point command, short floating point number with time tag CP56Time2a", 12, true},
+	64:  {"C_BO_TA_1", "Bitstring of 32 bit command with time tag CP56Time2a", 11, true},
+	70:  {"M_EI_NA_1", "End of initialization", 1, false},
+	100: {"C_IC_NA_1", "Interrogation command", 1, false},
+	101: {"C_CI_NA_1", "Counter interrogation command", 1, false},
+	102: {"C_RD_NA_1", "Read command", 0, false},
+	103: {"C_CS_NA_1", "Clock synchronization command", 7, true},
+	104: {"C_TS_NA_1", "Test command", 2, false},
+	105: {"C_RP_NA_1", "Reset process command", 1, true},
+	106: {"C_CD_NA_1", "Delay acquisition command", 2, false},
+	107: {"C_TS_TA_1", "Test command with time tag CP56Time2a", 9, false},
+	110: {"P_ME_NA_1", "Parameter of measured value, normalized value", 3, true},
+	111: {"P_ME_NB_1", "Parameter of measured value, scaled value", 3, true},
+	112: {"P_ME_NC_1", "Parameter of measured value, short floating point number", 5, true},
+	113: {"P_AC_NA_1", "Parameter activation", 1, true},
+	120: {"F_FR_NA_1", "File ready", 6, false},
+	121: {"F_SR_NA_1", "Section ready", 7, false},
+	122: {"F_SC_NA_1", "Call directory, select file, call file, call section", 4, false},
+	123: {"F_LS_NA_1", "Last section, last segment", 5, false},
+	124: {"F_AF_NA_1", "Ack file, ack section", 4, false},
+	125: {"F_SG_NA_1", "Segment", -1, false},
+	126: {"F_DR_TA_1", "Directory", 13, false},
+}
+
+// causes of transmission.
+const (
+	causeActivation             = 6
+	causeActivationConfirmation = 7
+)
+
+var causeNames = map[byte]string{
+	1:  "Periodic",
+	2:  "Background scan",
+	3:  "Spontaneous",
+	4:  "Initialized",
+	5:  "Request",
+	6:  "Activation",
+	7:  "Activation confirmation",
+	8:  "Deactivation",
+	9:  "Deactivation confirmation",
+	10: "Activation termination",
+	11: "Return information caused by a remote command",
+	12: "Return information caused by a local command",
+	13: "File transfer",
+	20: "Interrogated by station interrogation",
+	37: "Requested by general counter request",
+	44: "Unknown type identification",
+	45: "Unknown cause of transmission",
+	46: "Unknown common address of ASDU",
+	47: "Unknown information object address",
+}
+
+// U-format functions.
+var uFunctions = map[byte]string{
+	0x07: "STARTDT act",
+	0x0b: "STARTDT con",
+	0x13: "STOPDT act",
+	0x23: "STOPDT con",
+	0x43: "TESTFR act",
+	0x83: "TESTFR con",
+}
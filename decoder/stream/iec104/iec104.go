@@ -0,0 +1,95 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package iec104
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var iec104Log = zap.NewNop()
+
+const (
+	startByte = 0x68
+
+	// start byte and length
+	apciHeaderSize = 2
+
+	// size of the control field, the minimum value of the length field
+	controlSize = 4
+
+	// maximum value of the length field
+	maxAPDULength = 253
+
+	protocolIEC104 = "IEC104"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_IEC104,
+	Name:        "IEC104",
+	Description: "IEC 60870-5-104 is used for telecontrol in electrical engineering and power system automation",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		iec104Log, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"iec104",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isAPDU(client) && (len(server) == 0 || isAPDU(server))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return iec104Log.Sync()
+	},
+	Factory: &iec104Reader{},
+	Typ:     core.TCP,
+}
+
+// isAPDU checks whether the data starts with an application protocol control information header
+// that is followed by a valid I, S or U format control field.
+// The APDU must end at the end of the data or in front of the start byte of the next APDU.
+func isAPDU(data []byte) bool {
+	if len(data) < apciHeaderSize+controlSize || data[0] != startByte || data[1] < controlSize || data[1] > maxAPDULength {
+		return false
+	}
+
+	var (
+		length  = data[1]
+		control = data[apciHeaderSize : apciHeaderSize+controlSize]
+	)
+
+	switch {
+	case control[0]&0x01 == 0: // I format
+	case control[0] == 0x01: // S format
+		if length != controlSize || control[1] != 0 {
+			return false
+		}
+	default: // U format, exactly one of STARTDT, STOPDT and TESTFR
+		if _, ok := uFunctions[control[0]]; !ok || length != controlSize || control[1] != 0 || control[2] != 0 || control[3] != 0 {
+			return false
+		}
+	}
+
+	end := apciHeaderSize + int(length)
+
+	return end >= len(data) || data[end] == startByte
+}
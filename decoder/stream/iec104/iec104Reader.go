@@ -0,0 +1,288 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package iec104
+
+import (
+	"encoding/binary"
+	"strconv"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// APDU formats.
+const (
+	formatI = "I"
+	formatS = "S"
+	formatU = "U"
+)
+
+const (
+	// type identification, variable structure qualifier, cause of transmission, originator and common address
+	asduHeaderSize = 6
+
+	// size of an information object address
+	ioaSize = 3
+
+	vsqSequence = 0x80
+	vsqCount    = 0x7f
+	cotTest     = 0x80
+	cotNegative = 0x40
+	cotCause    = 0x3f
+)
+
+type iec104Reader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new IEC104 reader.
+func (h *iec104Reader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &iec104Reader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the IEC 60870-5-104 protocol.
+func (h *iec104Reader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	records := h.records(decoderconfig.Instance.IncludePayloads)
+
+	if control.Decoder.Writer != nil {
+		for _, c := range h.controlActions(records) {
+			control.WriteControlAction(c)
+		}
+	}
+
+	for _, r := range records {
+		writeIEC104(r)
+	}
+}
+
+// records parses the APDUs of both directions.
+func (h *iec104Reader) records(includePayloads bool) []*types.IEC104 {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		out            []*types.IEC104
+	)
+
+	for _, dir := range []*core.Direction{client, server} {
+		data := dir.Data
+
+		for offset := 0; len(data)-offset >= apciHeaderSize+controlSize; {
+			if !isAPDU(data[offset:]) {
+				iec104Log.Debug("invalid IEC104 APCI header", zap.Int("offset", offset))
+
+				break
+			}
+
+			end := offset + apciHeaderSize + int(data[offset+1])
+			if end > len(data) {
+				// incomplete APDU at the end of the stream
+				break
+			}
+
+			r := h.record(data[offset+apciHeaderSize:end], includePayloads)
+			r.IsClient = dir.Client
+			r.Timestamp = dir.TimeAt(offset).UnixNano()
+			out = append(out, r)
+
+			offset = end
+		}
+	}
+
+	return out
+}
+
+// record parses the control field and the ASDU of a single APDU.
+func (h *iec104Reader) record(apdu []byte, includePayloads bool) *types.IEC104 {
+	r := &types.IEC104{
+		ClientIP:   h.conversation.ClientIP,
+		ServerIP:   h.conversation.ServerIP,
+		ClientPort: h.conversation.ClientPort,
+		ServerPort: h.conversation.ServerPort,
+	}
+
+	if includePayloads {
+		r.Payload = apdu
+	}
+
+	switch {
+	case apdu[0]&0x01 == 0:
+		r.Format = formatI
+		r.SendSequence = int32(binary.LittleEndian.Uint16(apdu[0:2]) >> 1)
+		r.ReceiveSequence = int32(binary.LittleEndian.Uint16(apdu[2:4]) >> 1)
+
+		parseASDU(apdu[controlSize:], r)
+	case apdu[0]&0x03 == 0x01:
+		r.Format = formatS
+		r.ReceiveSequence = int32(binary.LittleEndian.Uint16(apdu[2:4]) >> 1)
+	default:
+		r.Format = formatU
+		if name, ok := uFunctions[apdu[0]]; ok {
+			r.UFunction = name
+		} else {
+			r.UFunction = "Unknown 0x" + strconv.FormatInt(int64(apdu[0]), 16)
+		}
+	}
+
+	return r
+}
+
+// parseASDU parses the data unit identifier and the information object addresses.
+func parseASDU(asdu []byte, r *types.IEC104) {
+	if len(asdu) < asduHeaderSize {
+		return
+	}
+
+	var (
+		typeID = asdu[0]
+		vsq    = asdu[1]
+		cot    = asdu[2]
+		t, ok  = asduTypes[typeID]
+	)
+
+	r.TypeID = int32(typeID)
+	r.TypeName = t.name
+	r.Sequence = vsq&vsqSequence != 0
+	r.NumObjects = int32(vsq & vsqCount)
+	r.Cause = int32(cot & cotCause)
+	r.CauseName = causeName(cot & cotCause)
+	r.Negative = cot&cotNegative != 0
+	r.Test = cot&cotTest != 0
+	r.Originator = int32(asdu[3])
+	r.CommonAddress = int32(binary.LittleEndian.Uint16(asdu[4:6]))
+	r.Command = ok && t.control && r.Cause == causeActivation
+
+	if !ok {
+		r.TypeName = "Type " + strconv.Itoa(int(typeID))
+	}
+
+	objects := asdu[asduHeaderSize:]
+	if len(objects) < ioaSize || r.NumObjects == 0 {
+		return
+	}
+
+	// a sequence of elements follows a single address
+	if r.Sequence {
+		ioa := readIOA(objects)
+		for i := int32(0); i < r.NumObjects; i++ {
+			r.ObjectAddresses = append(r.ObjectAddresses, ioa+i)
+		}
+
+		return
+	}
+
+	for i := int32(0); i < r.NumObjects && len(objects) >= ioaSize; i++ {
+		r.ObjectAddresses = append(r.ObjectAddresses, readIOA(objects))
+
+		if !ok || t.size < 0 || len(objects) < ioaSize+t.size {
+			return
+		}
+
+		objects = objects[ioaSize+t.size:]
+	}
+}
+
+// controlActions pairs the command activations with the activation confirmations of the other side.
+func (h *iec104Reader) controlActions(records []*types.IEC104) []*types.ControlAction {
+	var actions []*types.ControlAction
+
+	for _, r := range records {
+		if !r.Command {
+			continue
+		}
+
+		c := &types.ControlAction{
+			Timestamp:  r.Timestamp,
+			Protocol:   protocolIEC104,
+			Flow:       h.conversation.Ident,
+			ClientIP:   r.ClientIP,
+			ServerIP:   r.ServerIP,
+			ClientPort: r.ClientPort,
+			ServerPort: r.ServerPort,
+			UnitID:     r.CommonAddress,
+			Operation:  asduTypes[byte(r.TypeID)].description,
+			Quantity:   r.NumObjects,
+			Data:       r.Payload,
+		}
+
+		if len(r.ObjectAddresses) > 0 {
+			c.Address = r.ObjectAddresses[0]
+			c.Target = r.TypeName + " IOA " + strconv.Itoa(int(c.Address))
+		}
+
+		for _, res := range records {
+			if res.IsClient == r.IsClient || res.Timestamp < r.Timestamp || res.TypeID != r.TypeID || res.CommonAddress != r.CommonAddress || res.Cause != causeActivationConfirmation {
+				continue
+			}
+
+			if len(res.ObjectAddresses) > 0 && res.ObjectAddresses[0] != c.Address {
+				continue
+			}
+
+			c.Answered = true
+			c.Success = !res.Negative
+			c.Status = res.Cause
+
+			break
+		}
+
+		actions = append(actions, c)
+	}
+
+	return actions
+}
+
+func readIOA(data []byte) int32 {
+	return int32(data[0]) | int32(data[1])<<8 | int32(data[2])<<16
+}
+
+func causeName(cause byte) string {
+	switch {
+	case cause > 20 && cause <= 36:
+		return "Interrogated by group " + strconv.Itoa(int(cause)-20) + " interrogation"
+	case cause > 37 && cause <= 41:
+		return "Requested by group " + strconv.Itoa(int(cause)-37) + " counter request"
+	}
+
+	if name, ok := causeNames[cause]; ok {
+		return name
+	}
+
+	return "Cause " + strconv.Itoa(int(cause))
+}
+
+func writeIEC104(r *types.IEC104) {
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		iec104Log.Error("failed to write IEC104 record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
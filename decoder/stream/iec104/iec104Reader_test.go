@@ -0,0 +1,135 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package iec104
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func fragment(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+func TestRecords(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.1",
+			ServerIP:   "10.0.0.2",
+			ClientPort: 50000,
+			ServerPort: 2404,
+		}
+
+		startAct = []byte{0x68, 0x04, 0x07, 0x00, 0x00, 0x00}
+		startCon = []byte{0x68, 0x04, 0x0b, 0x00, 0x00, 0x00}
+
+		// single command ON with select/execute set to IOA 4711 of station 1
+		command = []byte{0x68, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x2d, 0x01, 0x06, 0x00, 0x01, 0x00, 0x67, 0x12, 0x00, 0x81}
+		confirm = []byte{0x68, 0x0e, 0x00, 0x00, 0x02, 0x00, 0x2d, 0x01, 0x07, 0x00, 0x01, 0x00, 0x67, 0x12, 0x00, 0x81}
+
+		// two spontaneous short floating point measurements at IOA 1 and 2
+		measured = []byte{
+			0x68, 0x1a, 0x02, 0x00, 0x02, 0x00, 0x0d, 0x02, 0x03, 0x00, 0x01, 0x00,
+			0x01, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00,
+			0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00,
+		}
+		ack = []byte{0x68, 0x04, 0x01, 0x00, 0x04, 0x00}
+	)
+
+	if !Decoder.CanDecode(startAct, startCon) {
+		t.Fatal("expected IEC104 stream to be detected")
+	}
+
+	if Decoder.CanDecode([]byte("GET / HTTP/1.1\r\n"), nil) {
+		t.Fatal("unexpected detection of HTTP as IEC104")
+	}
+
+	for _, data := range [][]byte{
+		[]byte("hello " + strings.Repeat("world ", 20)),  // text starting with the start byte
+		{0x68, 0x04, 0x0f, 0x00, 0x00, 0x00},             // U format with multiple functions
+		{0x68, 0x06, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00}, // S format with invalid length
+		{0x68, 0xfe, 0x00, 0x00, 0x00, 0x00},             // length exceeds the maximum
+		append(append([]byte{}, command...), 0x20),       // next APDU does not start with the start byte
+	} {
+		if isAPDU(data) {
+			t.Fatalf("unexpected detection of %x as IEC104 APDU", data)
+		}
+	}
+
+	if !isAPDU(append(append([]byte{}, command...), ack...)) || !isAPDU(command[:8]) {
+		t.Fatal("expected consecutive and incomplete APDUs to be detected")
+	}
+
+	conv.Data = append(conv.Data,
+		fragment(startAct, reassembly.TCPDirClientToServer, start),
+		fragment(startCon, reassembly.TCPDirServerToClient, start.Add(time.Millisecond)),
+		fragment(command[:4], reassembly.TCPDirClientToServer, start.Add(2*time.Millisecond)),
+		fragment(command[4:], reassembly.TCPDirClientToServer, start.Add(3*time.Millisecond)),
+		fragment(append(confirm, measured...), reassembly.TCPDirServerToClient, start.Add(4*time.Millisecond)),
+		fragment(ack, reassembly.TCPDirClientToServer, start.Add(5*time.Millisecond)),
+	)
+
+	h := (&iec104Reader{}).New(conv).(*iec104Reader)
+
+	records := h.records(false)
+	if len(records) != 6 {
+		t.Fatalf("expected 6 records, got %d", len(records))
+	}
+
+	if r := records[0]; r.Format != formatU || r.UFunction != "STARTDT act" || !r.IsClient {
+		t.Fatalf("unexpected STARTDT record: %+v", r)
+	}
+
+	cmd := records[1]
+	if cmd.Format != formatI || cmd.TypeName != "C_SC_NA_1" || cmd.CauseName != "Activation" || !cmd.Command || cmd.CommonAddress != 1 {
+		t.Fatalf("unexpected command record: %+v", cmd)
+	}
+
+	if len(cmd.ObjectAddresses) != 1 || cmd.ObjectAddresses[0] != 4711 {
+		t.Fatalf("unexpected command object addresses: %v", cmd.ObjectAddresses)
+	}
+
+	if r := records[2]; r.Format != formatS || r.ReceiveSequence != 2 {
+		t.Fatalf("unexpected S-format record: %+v", r)
+	}
+
+	m := records[5]
+	if m.TypeName != "M_ME_NC_1" || m.Command || m.CauseName != "Spontaneous" || m.SendSequence != 1 || m.ReceiveSequence != 1 {
+		t.Fatalf("unexpected measurement record: %+v", m)
+	}
+
+	if len(m.ObjectAddresses) != 2 || m.ObjectAddresses[0] != 1 || m.ObjectAddresses[1] != 2 {
+		t.Fatalf("unexpected measurement object addresses: %v", m.ObjectAddresses)
+	}
+
+	actions := h.controlActions(records)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 control action, got %d", len(actions))
+	}
+
+	if a := actions[0]; a.Operation != "Single command" || a.Target != "C_SC_NA_1 IOA 4711" || a.UnitID != 1 || !a.Answered || !a.Success {
+		t.Fatalf("unexpected control action: %+v", a)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package irc
+
+import (
+	"bytes"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	ircLog = zap.NewNop()
+
+	serviceIRC = "IRC"
+
+	// commands a client sends when registering a connection
+	ircClientRegistration = [][]byte{
+		[]byte("NICK "),
+		[]byte("USER "),
+		[]byte("PASS "),
+		[]byte("CAP LS"),
+	}
+	ircNotice  = []byte(" NOTICE ")
+	ircWelcome = []byte(" 001 ")
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_IRC,
+	Name:        serviceIRC,
+	Description: "Internet Relay Chat is a text based chat protocol, that is also frequently used for botnet command and control",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		ircLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"irc",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		for _, cmd := range ircClientRegistration {
+			if bytes.HasPrefix(client, cmd) {
+				return true
+			}
+		}
+
+		return bytes.HasPrefix(server, []byte(":")) && (bytes.Contains(server, ircNotice) || bytes.Contains(server, ircWelcome))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return ircLog.Sync()
+	},
+	Factory: &ircReader{},
+	Typ:     core.TCP,
+}
@@ -0,0 +1,447 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package irc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/decoder/stream/software"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// IRC commands.
+const (
+	ircNICK         = "NICK"
+	ircUSER         = "USER"
+	ircPASS         = "PASS"
+	ircJOIN         = "JOIN"
+	ircMODE         = "MODE"
+	ircPRIVMSG      = "PRIVMSG"
+	ircNOTICE       = "NOTICE"
+	ircPING         = "PING"
+	ircPONG         = "PONG"
+	ircAUTHENTICATE = "AUTHENTICATE"
+	ircNICKSERV     = "NICKSERV"
+	ircNS           = "NS"
+
+	ircReplyWelcome  = "001"
+	ircReplyYourHost = "002"
+	ircReplyMyInfo   = "004"
+)
+
+// regYourHost matches the server software in the RPL_YOURHOST reply.
+var regYourHost = regexp.MustCompile(`running version (\S+)`)
+
+// regServerVersion splits a server version string, e.g. UnrealIRCd-5.0.7 into product and version.
+var regServerVersion = regexp.MustCompile(`^(.+?)[-_/ ]v?(\d[\w.+\-]*)$`)
+
+// ircMessage is a single parsed IRC line.
+type ircMessage struct {
+	prefix  string
+	command string
+	params  []string
+
+	// true if the last parameter was a trailing parameter
+	trailing bool
+}
+
+// ircLine is a line of the conversation with the time the data arrived.
+type ircLine struct {
+	data      string
+	timestamp time.Time
+	client    bool
+}
+
+type ircReader struct {
+	conversation *core.ConversationInfo
+
+	nick          string
+	bannerWritten bool
+}
+
+// New will instantiate a new IRC reader.
+func (h *ircReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &ircReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the IRC protocol.
+func (h *ircReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	for _, l := range splitLines(h.conversation.Data) {
+		m := parseMessage(l.data)
+		if m == nil {
+			continue
+		}
+
+		if r := h.process(m, l.client, l.timestamp); r != nil {
+			writeIRC(r)
+		}
+	}
+}
+
+// splitLines returns the lines from both directions of the conversation in the order they arrived.
+func splitLines(data core.DataFragments) []ircLine {
+	var (
+		lines     []ircLine
+		clientBuf []byte
+		serverBuf []byte
+	)
+
+	for _, d := range data {
+		var (
+			client = d.Direction() == reassembly.TCPDirClientToServer
+			buf    = &serverBuf
+		)
+
+		if client {
+			buf = &clientBuf
+		}
+
+		*buf = append(*buf, d.Raw()...)
+
+		for {
+			i := bytes.IndexByte(*buf, '\n')
+			if i < 0 {
+				break
+			}
+
+			line := strings.TrimRight(string((*buf)[:i]), "\r")
+			*buf = (*buf)[i+1:]
+
+			if line != "" {
+				lines = append(lines, ircLine{
+					data:      line,
+					timestamp: core.Timestamp(d),
+					client:    client,
+				})
+			}
+		}
+	}
+
+	return lines
+}
+
+// parseMessage parses an IRC message according to RFC 1459.
+// Message tags from the IRCv3 extension are skipped.
+func parseMessage(line string) *ircMessage {
+	m := &ircMessage{}
+
+	if strings.HasPrefix(line, "@") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return nil
+		}
+
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+
+	if strings.HasPrefix(line, ":") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return nil
+		}
+
+		m.prefix = line[1:i]
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+
+	var trailing string
+
+	if i := strings.Index(line, " :"); i >= 0 {
+		trailing = line[i+2:]
+		line = line[:i]
+		m.trailing = true
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	m.command = strings.ToUpper(fields[0])
+	m.params = fields[1:]
+
+	if m.trailing {
+		m.params = append(m.params, trailing)
+	}
+
+	return m
+}
+
+func isChannel(target string) bool {
+	return target != "" && strings.ContainsRune("#&+!", rune(target[0]))
+}
+
+// nickFromPrefix returns the nick from a nick!user@host prefix.
+func nickFromPrefix(prefix string) string {
+	if i := strings.IndexAny(prefix, "!@"); i >= 0 {
+		return prefix[:i]
+	}
+
+	return prefix
+}
+
+// process updates the session state for a message and returns the audit record for it.
+// Numeric replies and keep alive messages do not produce audit records.
+func (h *ircReader) process(m *ircMessage, client bool, ts time.Time) *types.IRC {
+	if client {
+		h.processClientCommand(m, ts)
+	} else {
+		h.processServerMessage(m, ts)
+	}
+
+	if isNumeric(m.command) || m.command == ircPING || m.command == ircPONG {
+		return nil
+	}
+
+	r := &types.IRC{
+		Timestamp:  ts.UnixNano(),
+		IsClient:   client,
+		Prefix:     m.prefix,
+		Command:    m.command,
+		Params:     m.params,
+		ClientIP:   h.conversation.ClientIP,
+		ServerIP:   h.conversation.ServerIP,
+		ClientPort: h.conversation.ClientPort,
+		ServerPort: h.conversation.ServerPort,
+	}
+
+	if client {
+		r.Nick = h.nick
+	} else {
+		r.Nick = nickFromPrefix(m.prefix)
+	}
+
+	if len(m.params) > 0 {
+		if isChannel(m.params[0]) {
+			r.Channel = m.params[0]
+		} else if m.command == ircPRIVMSG || m.command == ircNOTICE {
+			r.Target = m.params[0]
+		}
+	}
+
+	if m.trailing {
+		r.Message = m.params[len(m.params)-1]
+	}
+
+	return r
+}
+
+func isNumeric(cmd string) bool {
+	if len(cmd) != 3 {
+		return false
+	}
+
+	for _, c := range cmd {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// processClientCommand tracks the nick of the client and reports credentials.
+func (h *ircReader) processClientCommand(m *ircMessage, ts time.Time) {
+	switch m.command {
+	case ircNICK:
+		if len(m.params) > 0 {
+			h.nick = m.params[0]
+		}
+	case ircPASS:
+		if len(m.params) > 0 {
+			h.writeCredentials(ts, h.nick, m.params[0], "server password")
+		}
+	case ircJOIN:
+		// JOIN #chan1,#chan2 key1,key2
+		if len(m.params) < 2 {
+			return
+		}
+
+		var (
+			channels = strings.Split(m.params[0], ",")
+			keys     = strings.Split(m.params[1], ",")
+		)
+
+		for i, key := range keys {
+			if i < len(channels) && key != "" {
+				h.writeCredentials(ts, channels[i], key, "channel key")
+			}
+		}
+	case ircMODE:
+		// MODE #chan +k key
+		if len(m.params) >= 3 && isChannel(m.params[0]) && strings.HasPrefix(m.params[1], "+") && strings.Contains(m.params[1], "k") {
+			h.writeCredentials(ts, m.params[0], m.params[len(m.params)-1], "channel key")
+		}
+	case ircPRIVMSG:
+		// PRIVMSG NickServ :IDENTIFY [account] password
+		if len(m.params) == 2 && strings.HasPrefix(strings.ToUpper(m.params[0]), ircNICKSERV) {
+			h.nickServIdentify(strings.Fields(m.params[1]), ts)
+		}
+	case ircNICKSERV, ircNS:
+		// NICKSERV IDENTIFY [account] password
+		var fields []string
+		for _, p := range m.params {
+			fields = append(fields, strings.Fields(p)...)
+		}
+
+		h.nickServIdentify(fields, ts)
+	case ircAUTHENTICATE:
+		// SASL PLAIN: base64(authzid \0 authcid \0 password)
+		if len(m.params) == 0 {
+			return
+		}
+
+		data, err := base64.StdEncoding.DecodeString(m.params[0])
+		if err != nil {
+			return
+		}
+
+		if parts := strings.Split(string(data), "\x00"); len(parts) == 3 {
+			h.writeCredentials(ts, parts[1], parts[2], "SASL PLAIN")
+		}
+	}
+}
+
+func (h *ircReader) nickServIdentify(fields []string, ts time.Time) {
+	if len(fields) < 2 || strings.ToUpper(fields[0]) != "IDENTIFY" {
+		return
+	}
+
+	if len(fields) >= 3 {
+		h.writeCredentials(ts, fields[1], fields[2], "NickServ IDENTIFY")
+	} else {
+		h.writeCredentials(ts, h.nick, fields[1], "NickServ IDENTIFY")
+	}
+}
+
+func (h *ircReader) writeCredentials(ts time.Time, user, password, notes string) {
+	// prevent nil pointer access if the credentials decoder is not initialized
+	if credentials.Decoder.Writer == nil {
+		return
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp: ts.UnixNano(),
+		Service:   serviceIRC,
+		Flow:      h.conversation.Ident,
+		User:      user,
+		Password:  password,
+		Notes:     notes,
+	})
+}
+
+// processServerMessage tracks the nick assigned by the server and extracts the server software.
+func (h *ircReader) processServerMessage(m *ircMessage, ts time.Time) {
+	switch m.command {
+	case ircReplyWelcome:
+		// the first parameter of a numeric reply is the nick of the client
+		if len(m.params) > 0 && h.nick == "" {
+			h.nick = m.params[0]
+		}
+	case ircReplyYourHost:
+		if len(m.params) > 1 {
+			if matches := regYourHost.FindStringSubmatch(m.params[len(m.params)-1]); len(matches) > 1 {
+				h.writeSoftware(strings.TrimSuffix(matches[1], "."), m, ts)
+			}
+		}
+	case ircReplyMyInfo:
+		// 004 <nick> <servername> <version> <user modes> <channel modes>
+		if len(m.params) > 2 {
+			h.writeSoftware(m.params[2], m, ts)
+		}
+	}
+}
+
+// writeSoftware reports the server software from the banner once per connection.
+func (h *ircReader) writeSoftware(banner string, m *ircMessage, ts time.Time) {
+	if h.bannerWritten || banner == "" {
+		return
+	}
+
+	h.bannerWritten = true
+
+	var (
+		product = banner
+		version string
+	)
+
+	if matches := regServerVersion.FindStringSubmatch(banner); len(matches) > 2 {
+		product = matches[1]
+		version = matches[2]
+	}
+
+	ident := h.conversation.Ident
+
+	software.WriteSoftware([]*software.AtomicSoftware{
+		{
+			Software: &types.Software{
+				Timestamp:  ts.UnixNano(),
+				Product:    product,
+				Version:    version,
+				SourceName: "IRC Server Banner",
+				SourceData: ":" + m.prefix + " " + m.command + " " + strings.Join(m.params, " "),
+				Service:    serviceIRC,
+				Flows:      []string{ident},
+				Notes:      "Server: " + m.prefix,
+			},
+		},
+	}, func(s *software.AtomicSoftware) {
+		s.Lock()
+		for _, f := range s.Flows {
+			// prevent duplicates
+			if f == ident {
+				s.Unlock()
+				return
+			}
+		}
+		// add flow
+		s.Flows = append(s.Flows, ident)
+		s.Unlock()
+	})
+}
+
+// writeIRC writes the IRC audit record.
+func writeIRC(r *types.IRC) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		ircLog.Error("failed to write IRC record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package irc
+
+import (
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	m := parseMessage(":alice!a@host.example PRIVMSG #botnet :!ddos 10.0.0.1 80")
+	if m == nil {
+		t.Fatal("failed to parse message")
+	}
+
+	if m.prefix != "alice!a@host.example" || m.command != ircPRIVMSG {
+		t.Fatal("unexpected prefix or command: ", m.prefix, m.command)
+	}
+
+	if len(m.params) != 2 || m.params[0] != "#botnet" || m.params[1] != "!ddos 10.0.0.1 80" || !m.trailing {
+		t.Fatal("unexpected params: ", m.params)
+	}
+
+	if nickFromPrefix(m.prefix) != "alice" {
+		t.Fatal("unexpected nick: ", nickFromPrefix(m.prefix))
+	}
+
+	m = parseMessage("@time=2020-01-01T00:00:00Z join #a,#b key1")
+	if m == nil || m.command != ircJOIN || len(m.params) != 2 || m.params[1] != "key1" || m.trailing {
+		t.Fatal("unexpected message: ", m)
+	}
+
+	if parseMessage(":prefixonly") != nil {
+		t.Fatal("expected nil for message without command")
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	for banner, expected := range map[string][2]string{
+		"UnrealIRCd-5.0.7":      {"UnrealIRCd", "5.0.7"},
+		"InspIRCd-3":            {"InspIRCd", "3"},
+		"ircd-ratbox-3.0.10":    {"ircd-ratbox", "3.0.10"},
+		"solanum-1.0-dev":       {"solanum", "1.0-dev"},
+		"hybrid-8.2.24+plexus4": {"hybrid", "8.2.24+plexus4"},
+	} {
+		matches := regServerVersion.FindStringSubmatch(banner)
+		if len(matches) != 3 || matches[1] != expected[0] || matches[2] != expected[1] {
+			t.Fatal("unexpected result for", banner, matches)
+		}
+	}
+
+	matches := regYourHost.FindStringSubmatch("Your host is irc.example.net, running version UnrealIRCd-5.0.7")
+	if len(matches) != 2 || matches[1] != "UnrealIRCd-5.0.7" {
+		t.Fatal("unexpected result: ", matches)
+	}
+}
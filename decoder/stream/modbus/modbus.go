@@ -0,0 +1,83 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package modbus
+
+import (
+	"encoding/binary"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var modbusLog = zap.NewNop()
+
+const (
+	// size of the modbus application protocol header, including the unit identifier
+	mbapHeaderSize = 7
+
+	// maximum size of the length field, unit identifier and a PDU of 253 bytes
+	maxLength = 254
+
+	protocolModbus = "Modbus/TCP"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_ModbusTransaction,
+	Name:        "ModbusTransaction",
+	Description: "Modbus/TCP requests paired with their responses, decoded from the reassembled TCP stream",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		modbusLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"modbus",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isMBAP(client) && (len(server) == 0 || isMBAP(server))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return modbusLog.Sync()
+	},
+	Factory: &modbusReader{},
+	Typ:     core.TCP,
+}
+
+// isMBAP checks whether the data starts with a plausible modbus application protocol header.
+func isMBAP(data []byte) bool {
+	if len(data) < mbapHeaderSize+1 {
+		return false
+	}
+
+	var (
+		protocolID   = binary.BigEndian.Uint16(data[2:4])
+		length       = binary.BigEndian.Uint16(data[4:6])
+		functionCode = data[7] &^ exceptionFlag
+	)
+
+	if protocolID != 0 || length < 2 || length > maxLength {
+		return false
+	}
+
+	_, ok := functionNames[functionCode]
+
+	return ok
+}
@@ -0,0 +1,443 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package modbus
+
+import (
+	"encoding/binary"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// function codes.
+const (
+	fcReadCoils                  = 1
+	fcReadDiscreteInputs         = 2
+	fcReadHoldingRegisters       = 3
+	fcReadInputRegisters         = 4
+	fcWriteSingleCoil            = 5
+	fcWriteSingleRegister        = 6
+	fcReadExceptionStatus        = 7
+	fcDiagnostics                = 8
+	fcGetCommEventCounter        = 11
+	fcGetCommEventLog            = 12
+	fcWriteMultipleCoils         = 15
+	fcWriteMultipleRegisters     = 16
+	fcReportServerID             = 17
+	fcReadFileRecord             = 20
+	fcWriteFileRecord            = 21
+	fcMaskWriteRegister          = 22
+	fcReadWriteMultipleRegisters = 23
+	fcReadFIFOQueue              = 24
+	fcEncapsulatedInterface      = 43
+
+	exceptionFlag = 0x80
+
+	// diagnostics sub functions that change the state of the device
+	diagRestartCommunications = 0x01
+	diagForceListenOnly       = 0x04
+
+	coilOn = 0xff00
+)
+
+var functionNames = map[byte]string{
+	fcReadCoils:                  "Read Coils",
+	fcReadDiscreteInputs:         "Read Discrete Inputs",
+	fcReadHoldingRegisters:       "Read Holding Registers",
+	fcReadInputRegisters:         "Read Input Registers",
+	fcWriteSingleCoil:            "Write Single Coil",
+	fcWriteSingleRegister:        "Write Single Register",
+	fcReadExceptionStatus:        "Read Exception Status",
+	fcDiagnostics:                "Diagnostics",
+	fcGetCommEventCounter:        "Get Comm Event Counter",
+	fcGetCommEventLog:            "Get Comm Event Log",
+	fcWriteMultipleCoils:         "Write Multiple Coils",
+	fcWriteMultipleRegisters:     "Write Multiple Registers",
+	fcReportServerID:             "Report Server ID",
+	fcReadFileRecord:             "Read File Record",
+	fcWriteFileRecord:            "Write File Record",
+	fcMaskWriteRegister:          "Mask Write Register",
+	fcReadWriteMultipleRegisters: "Read/Write Multiple Registers",
+	fcReadFIFOQueue:              "Read FIFO Queue",
+	fcEncapsulatedInterface:      "Encapsulated Interface Transport",
+}
+
+var diagnosticsNames = map[uint16]string{
+	diagRestartCommunications: "Restart Communications Option",
+	diagForceListenOnly:       "Force Listen Only Mode",
+}
+
+// targets names the data model object affected by write operations.
+var targets = map[byte]string{
+	fcWriteSingleCoil:            "coil",
+	fcWriteMultipleCoils:         "coil",
+	fcWriteSingleRegister:        "holding register",
+	fcWriteMultipleRegisters:     "holding register",
+	fcMaskWriteRegister:          "holding register",
+	fcReadWriteMultipleRegisters: "holding register",
+	fcWriteFileRecord:            "file record",
+	fcDiagnostics:                "device",
+}
+
+// message is a single modbus application data unit.
+type message struct {
+	transactionID uint16
+	unitID        byte
+	functionCode  byte
+	pdu           []byte
+	raw           []byte
+	timestamp     time.Time
+}
+
+type modbusReader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new modbus reader.
+func (h *modbusReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &modbusReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the modbus protocol.
+func (h *modbusReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	for _, tx := range h.transactions(decoderconfig.Instance.IncludePayloads) {
+		if tx.Write && control.Decoder.Writer != nil {
+			control.WriteControlAction(h.controlAction(tx))
+		}
+
+		writeTransaction(tx)
+	}
+}
+
+// transactions parses the messages from both directions and pairs requests with their responses.
+func (h *modbusReader) transactions(includePayloads bool) []*types.ModbusTransaction {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		pending        = make(map[uint16][]*types.ModbusTransaction)
+		quantities     = make(map[*types.ModbusTransaction]int)
+		out            []*types.ModbusTransaction
+	)
+
+	for _, m := range parseMessages(client) {
+		tx := &types.ModbusTransaction{
+			Timestamp:     m.timestamp.UnixNano(),
+			ClientIP:      h.conversation.ClientIP,
+			ServerIP:      h.conversation.ServerIP,
+			ClientPort:    h.conversation.ClientPort,
+			ServerPort:    h.conversation.ServerPort,
+			TransactionID: int32(m.transactionID),
+			UnitID:        int32(m.unitID),
+			FunctionCode:  int32(m.functionCode),
+			Function:      functionName(m.functionCode),
+		}
+
+		if includePayloads {
+			tx.RequestData = m.raw
+		}
+
+		quantities[tx] = parseRequest(tx, m.functionCode, m.pdu)
+		pending[m.transactionID] = append(pending[m.transactionID], tx)
+		out = append(out, tx)
+	}
+
+	for _, m := range parseMessages(server) {
+		var tx *types.ModbusTransaction
+
+		// transaction identifiers can be reused, responses are matched to the oldest pending request
+		if q := pending[m.transactionID]; len(q) > 0 {
+			tx, pending[m.transactionID] = q[0], q[1:]
+			tx.Duration = m.timestamp.UnixNano() - tx.Timestamp
+		} else {
+			// response without a request, e.g. when the capture started in the middle of the connection
+			tx = &types.ModbusTransaction{
+				Timestamp:     m.timestamp.UnixNano(),
+				ClientIP:      h.conversation.ClientIP,
+				ServerIP:      h.conversation.ServerIP,
+				ClientPort:    h.conversation.ClientPort,
+				ServerPort:    h.conversation.ServerPort,
+				TransactionID: int32(m.transactionID),
+				UnitID:        int32(m.unitID),
+				FunctionCode:  int32(m.functionCode &^ exceptionFlag),
+				Function:      functionName(m.functionCode &^ exceptionFlag),
+			}
+			out = append(out, tx)
+		}
+
+		tx.Answered = true
+
+		if includePayloads {
+			tx.ResponseData = m.raw
+		}
+
+		parseResponse(tx, m.functionCode, m.pdu, quantities[tx])
+	}
+
+	return out
+}
+
+// parseMessages returns all complete modbus messages sent in the given direction.
+func parseMessages(dir *core.Direction) []*message {
+	var (
+		messages []*message
+		data     = dir.Data
+		offset   int
+	)
+
+	for len(data)-offset >= mbapHeaderSize+1 {
+		length := int(binary.BigEndian.Uint16(data[offset+4 : offset+6]))
+		if binary.BigEndian.Uint16(data[offset+2:offset+4]) != 0 || length < 2 || length > maxLength {
+			modbusLog.Debug("invalid modbus header",
+				zap.Int("offset", offset),
+				zap.Int("length", length),
+			)
+
+			break
+		}
+
+		end := offset + 6 + length
+		if end > len(data) {
+			// incomplete message at the end of the stream
+			break
+		}
+
+		messages = append(messages, &message{
+			transactionID: binary.BigEndian.Uint16(data[offset : offset+2]),
+			unitID:        data[offset+6],
+			functionCode:  data[offset+7],
+			pdu:           data[offset+8 : end],
+			raw:           data[offset:end],
+			timestamp:     dir.TimeAt(offset),
+		})
+
+		offset = end
+	}
+
+	return messages
+}
+
+// parseRequest populates the transaction with the request fields and returns the quantity of requested items.
+func parseRequest(tx *types.ModbusTransaction, fc byte, pdu []byte) int {
+	switch fc {
+	case fcReadCoils, fcReadDiscreteInputs, fcReadHoldingRegisters, fcReadInputRegisters:
+		if len(pdu) >= 4 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[0:2]))
+			tx.Quantity = int32(binary.BigEndian.Uint16(pdu[2:4]))
+		}
+	case fcWriteSingleCoil:
+		tx.Write = true
+
+		if len(pdu) >= 4 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[0:2]))
+			tx.Quantity = 1
+
+			if binary.BigEndian.Uint16(pdu[2:4]) == coilOn {
+				tx.Values = []int32{1}
+			} else {
+				tx.Values = []int32{0}
+			}
+		}
+	case fcWriteSingleRegister:
+		tx.Write = true
+
+		if len(pdu) >= 4 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[0:2]))
+			tx.Quantity = 1
+			tx.Values = []int32{int32(binary.BigEndian.Uint16(pdu[2:4]))}
+		}
+	case fcWriteMultipleCoils:
+		tx.Write = true
+
+		if len(pdu) >= 5 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[0:2]))
+			tx.Quantity = int32(binary.BigEndian.Uint16(pdu[2:4]))
+			tx.Values = bits(byteCount(pdu[4:]), int(tx.Quantity))
+		}
+	case fcWriteMultipleRegisters:
+		tx.Write = true
+
+		if len(pdu) >= 5 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[0:2]))
+			tx.Quantity = int32(binary.BigEndian.Uint16(pdu[2:4]))
+			tx.Values = registers(byteCount(pdu[4:]))
+		}
+	case fcMaskWriteRegister:
+		tx.Write = true
+
+		if len(pdu) >= 6 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[0:2]))
+			tx.Quantity = 1
+			tx.Values = registers(pdu[2:6])
+		}
+	case fcReadWriteMultipleRegisters:
+		tx.Write = true
+
+		// the write address and quantity follow the read address and quantity
+		if len(pdu) >= 9 {
+			tx.Address = int32(binary.BigEndian.Uint16(pdu[4:6]))
+			tx.Quantity = int32(binary.BigEndian.Uint16(pdu[6:8]))
+			tx.Values = registers(byteCount(pdu[8:]))
+
+			return int(binary.BigEndian.Uint16(pdu[2:4]))
+		}
+	case fcWriteFileRecord:
+		tx.Write = true
+	case fcDiagnostics:
+		if len(pdu) >= 2 {
+			sub := binary.BigEndian.Uint16(pdu[0:2])
+			tx.Address = int32(sub)
+
+			if name, ok := diagnosticsNames[sub]; ok {
+				tx.Function += ": " + name
+				tx.Write = true
+			}
+		}
+	}
+
+	return int(tx.Quantity)
+}
+
+// parseResponse populates the transaction with the response fields.
+func parseResponse(tx *types.ModbusTransaction, fc byte, pdu []byte, quantity int) {
+	if fc&exceptionFlag != 0 {
+		tx.Exception = true
+
+		if len(pdu) > 0 {
+			tx.ExceptionCode = int32(pdu[0])
+		}
+
+		return
+	}
+
+	if len(pdu) == 0 {
+		return
+	}
+
+	switch fc {
+	case fcReadCoils, fcReadDiscreteInputs:
+		if quantity == 0 {
+			quantity = int(pdu[0]) * 8
+		}
+
+		tx.Values = bits(byteCount(pdu), quantity)
+	case fcReadHoldingRegisters, fcReadInputRegisters:
+		tx.Values = registers(byteCount(pdu))
+	}
+}
+
+// byteCount returns the data following a byte count field, limited to the announced length.
+func byteCount(data []byte) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	n := int(data[0])
+	if n > len(data)-1 {
+		n = len(data) - 1
+	}
+
+	return data[1 : 1+n]
+}
+
+// bits unpacks the coil or discrete input states, least significant bit first.
+func bits(data []byte, quantity int) []int32 {
+	if quantity > len(data)*8 {
+		quantity = len(data) * 8
+	}
+
+	values := make([]int32, quantity)
+	for i := range values {
+		values[i] = int32(data[i/8]>>(uint(i)%8)) & 1
+	}
+
+	return values
+}
+
+// registers unpacks 16 bit register values.
+func registers(data []byte) []int32 {
+	values := make([]int32, len(data)/2)
+	for i := range values {
+		values[i] = int32(binary.BigEndian.Uint16(data[i*2:]))
+	}
+
+	return values
+}
+
+func functionName(fc byte) string {
+	if name, ok := functionNames[fc]; ok {
+		return name
+	}
+
+	return "Function " + strconv.Itoa(int(fc))
+}
+
+// controlAction creates the control action audit record for a write transaction.
+func (h *modbusReader) controlAction(tx *types.ModbusTransaction) *types.ControlAction {
+	target := targets[byte(tx.FunctionCode)]
+
+	switch {
+	case tx.Quantity > 1:
+		target += "s " + strconv.Itoa(int(tx.Address)) + "-" + strconv.Itoa(int(tx.Address+tx.Quantity-1))
+	case tx.Quantity == 1:
+		target += " " + strconv.Itoa(int(tx.Address))
+	}
+
+	return &types.ControlAction{
+		Timestamp:  tx.Timestamp,
+		Protocol:   protocolModbus,
+		Flow:       h.conversation.Ident,
+		ClientIP:   tx.ClientIP,
+		ServerIP:   tx.ServerIP,
+		ClientPort: tx.ClientPort,
+		ServerPort: tx.ServerPort,
+		UnitID:     tx.UnitID,
+		Operation:  tx.Function,
+		Target:     target,
+		Address:    tx.Address,
+		Quantity:   tx.Quantity,
+		Values:     tx.Values,
+		Data:       tx.RequestData,
+		Answered:   tx.Answered,
+		Success:    tx.Answered && !tx.Exception,
+		Status:     tx.ExceptionCode,
+	}
+}
+
+func writeTransaction(tx *types.ModbusTransaction) {
+	if decoderconfig.Instance.ExportMetrics {
+		tx.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(tx)
+	if err != nil {
+		modbusLog.Error("failed to write modbus transaction", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func fragment(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.1",
+			ServerIP:   "10.0.0.2",
+			ClientPort: 50000,
+			ServerPort: 502,
+		}
+
+		// read 2 holding registers at 100, split across two segments
+		readReq = []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x01, 0x03, 0x00, 0x64, 0x00, 0x02}
+
+		// write 2 registers at 200 with the values 10 and 258
+		writeReq = []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x0b, 0x01, 0x10, 0x00, 0xc8, 0x00, 0x02, 0x04, 0x00, 0x0a, 0x01, 0x02}
+
+		// responses arrive in reverse order, the write is rejected with illegal data address
+		writeRes = []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x01, 0x90, 0x02}
+		readRes  = []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x07, 0x01, 0x03, 0x04, 0x00, 0x2a, 0x00, 0x07}
+	)
+
+	if !Decoder.CanDecode(readReq, readRes) {
+		t.Fatal("expected modbus stream to be detected")
+	}
+
+	if Decoder.CanDecode([]byte("GET / HTTP/1.1\r\n"), nil) {
+		t.Fatal("unexpected detection of HTTP as modbus")
+	}
+
+	conv.Data = append(conv.Data,
+		fragment(readReq[:5], reassembly.TCPDirClientToServer, start),
+		fragment(append(readReq[5:], writeReq...), reassembly.TCPDirClientToServer, start.Add(time.Millisecond)),
+		fragment(writeRes, reassembly.TCPDirServerToClient, start.Add(3*time.Millisecond)),
+		fragment(readRes, reassembly.TCPDirServerToClient, start.Add(5*time.Millisecond)),
+	)
+
+	h := (&modbusReader{}).New(conv).(*modbusReader)
+
+	txs := h.transactions(false)
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+
+	read, write := txs[0], txs[1]
+
+	if read.Function != "Read Holding Registers" || read.Address != 100 || read.Quantity != 2 || read.Write || !read.Answered {
+		t.Fatalf("unexpected read transaction: %+v", read)
+	}
+
+	if len(read.Values) != 2 || read.Values[0] != 42 || read.Values[1] != 7 {
+		t.Fatalf("unexpected read values: %v", read.Values)
+	}
+
+	if read.Duration != int64(5*time.Millisecond) {
+		t.Fatalf("unexpected read duration: %d", read.Duration)
+	}
+
+	if !write.Write || !write.Exception || write.ExceptionCode != 2 || write.Address != 200 || write.Quantity != 2 {
+		t.Fatalf("unexpected write transaction: %+v", write)
+	}
+
+	if len(write.Values) != 2 || write.Values[0] != 10 || write.Values[1] != 258 {
+		t.Fatalf("unexpected write values: %v", write.Values)
+	}
+
+	action := h.controlAction(write)
+	if action.Target != "holding registers 200-201" || action.Success || !action.Answered || action.Status != 2 {
+		t.Fatalf("unexpected control action: %+v", action)
+	}
+}
+
+func TestBits(t *testing.T) {
+	values := bits([]byte{0x05, 0x01}, 10)
+
+	expected := []int32{1, 0, 1, 0, 0, 0, 0, 0, 1, 0}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values, got %d", len(expected), len(values))
+	}
+
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Fatalf("value %d: expected %d, got %d", i, expected[i], values[i])
+		}
+	}
+}
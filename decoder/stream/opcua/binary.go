@@ -0,0 +1,362 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package opcua
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errTruncated = errors.New("truncated OPC UA message")
+
+// maximum number of array elements decoded, larger arrays are treated as malformed.
+const maxArrayLength = 4096
+
+// NodeId encodings.
+const (
+	nodeIDTwoByte    = 0x00
+	nodeIDFourByte   = 0x01
+	nodeIDNumeric    = 0x02
+	nodeIDString     = 0x03
+	nodeIDGUID       = 0x04
+	nodeIDByteString = 0x05
+
+	// flags of expanded node ids
+	nodeIDNamespaceURI = 0x80
+	nodeIDServerIndex  = 0x40
+)
+
+// buffer decodes the OPC UA binary encoding, the first error is sticky.
+type buffer struct {
+	data []byte
+	err  error
+}
+
+func (b *buffer) next(n int) []byte {
+	if b.err != nil {
+		return nil
+	}
+
+	if n < 0 || n > len(b.data) {
+		b.err = errTruncated
+		b.data = nil
+
+		return nil
+	}
+
+	v := b.data[:n]
+	b.data = b.data[n:]
+
+	return v
+}
+
+func (b *buffer) skip(n int) {
+	b.next(n)
+}
+
+func (b *buffer) uint8() byte {
+	if v := b.next(1); v != nil {
+		return v[0]
+	}
+
+	return 0
+}
+
+func (b *buffer) uint16() uint16 {
+	if v := b.next(2); v != nil {
+		return binary.LittleEndian.Uint16(v)
+	}
+
+	return 0
+}
+
+func (b *buffer) uint32() uint32 {
+	if v := b.next(4); v != nil {
+		return binary.LittleEndian.Uint32(v)
+	}
+
+	return 0
+}
+
+func (b *buffer) int32() int32 {
+	return int32(b.uint32())
+}
+
+// arrayLength reads the number of elements of an array, -1 denotes a null array.
+func (b *buffer) arrayLength() int {
+	n := b.int32()
+	if n > maxArrayLength {
+		b.err = fmt.Errorf("invalid array length %d", n)
+
+		return 0
+	}
+
+	if n < 0 {
+		return 0
+	}
+
+	return int(n)
+}
+
+// byteString reads a length prefixed byte string, strings use the same encoding.
+func (b *buffer) byteString() []byte {
+	n := b.int32()
+	if n < 0 {
+		return nil
+	}
+
+	return b.next(int(n))
+}
+
+func (b *buffer) string() string {
+	return string(b.byteString())
+}
+
+func (b *buffer) stringArray() []string {
+	var out []string
+
+	for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+		out = append(out, b.string())
+	}
+
+	return out
+}
+
+// nodeID reads a node id and formats it in the string notation, e.g. ns=2;s=Temperature.
+func (b *buffer) nodeID() string {
+	id, _ := b.expandedNodeID()
+
+	return id
+}
+
+// expandedNodeID reads an expanded node id and returns its string notation and numeric identifier.
+func (b *buffer) expandedNodeID() (string, uint32) {
+	var (
+		enc = b.uint8()
+		ns  uint16
+		id  string
+		num uint32
+	)
+
+	switch enc & 0x3f {
+	case nodeIDTwoByte:
+		num = uint32(b.uint8())
+		id = "i=" + strconv.FormatUint(uint64(num), 10)
+	case nodeIDFourByte:
+		ns = uint16(b.uint8())
+		num = uint32(b.uint16())
+		id = "i=" + strconv.FormatUint(uint64(num), 10)
+	case nodeIDNumeric:
+		ns = b.uint16()
+		num = b.uint32()
+		id = "i=" + strconv.FormatUint(uint64(num), 10)
+	case nodeIDString:
+		ns = b.uint16()
+		id = "s=" + b.string()
+	case nodeIDGUID:
+		ns = b.uint16()
+		id = "g=" + formatGUID(b.next(16))
+	case nodeIDByteString:
+		ns = b.uint16()
+		id = "b=" + base64.StdEncoding.EncodeToString(b.byteString())
+	default:
+		b.err = fmt.Errorf("invalid node id encoding 0x%x", enc)
+
+		return "", 0
+	}
+
+	if ns != 0 {
+		id = "ns=" + strconv.Itoa(int(ns)) + ";" + id
+	}
+
+	if enc&nodeIDNamespaceURI != 0 {
+		id = "nsu=" + b.string() + ";" + id
+	}
+
+	if enc&nodeIDServerIndex != 0 {
+		id = "svr=" + strconv.FormatUint(uint64(b.uint32()), 10) + ";" + id
+	}
+
+	return id, num
+}
+
+// localizedText reads a localized text and returns the text without locale.
+func (b *buffer) localizedText() string {
+	mask := b.uint8()
+
+	if mask&0x01 != 0 {
+		b.string()
+	}
+
+	if mask&0x02 != 0 {
+		return b.string()
+	}
+
+	return ""
+}
+
+// qualifiedName skips a qualified name.
+func (b *buffer) qualifiedName() {
+	b.uint16()
+	b.string()
+}
+
+// extensionObject reads the type id and body of an extension object.
+func (b *buffer) extensionObject() (uint32, []byte) {
+	_, typ := b.expandedNodeID()
+
+	switch b.uint8() {
+	case 0x00:
+		return typ, nil
+	case 0x01, 0x02:
+		return typ, b.byteString()
+	default:
+		b.err = errors.New("invalid extension object encoding")
+	}
+
+	return typ, nil
+}
+
+// diagnosticInfo skips a diagnostic info structure.
+func (b *buffer) diagnosticInfo() {
+	mask := b.uint8()
+
+	// symbolic id, namespace uri, locale and localized text
+	for _, bit := range []byte{0x01, 0x02, 0x08, 0x04} {
+		if mask&bit != 0 {
+			b.skip(4)
+		}
+	}
+
+	if mask&0x10 != 0 {
+		b.string()
+	}
+
+	if mask&0x20 != 0 {
+		b.skip(4)
+	}
+
+	if mask&0x40 != 0 && b.err == nil {
+		b.diagnosticInfo()
+	}
+}
+
+// sizes of the fixed length built-in types by type id.
+var fixedSizes = map[byte]int{
+	1:  1,  // Boolean
+	2:  1,  // SByte
+	3:  1,  // Byte
+	4:  2,  // Int16
+	5:  2,  // UInt16
+	6:  4,  // Int32
+	7:  4,  // UInt32
+	8:  8,  // Int64
+	9:  8,  // UInt64
+	10: 4,  // Float
+	11: 8,  // Double
+	13: 8,  // DateTime
+	14: 16, // Guid
+	19: 4,  // StatusCode
+}
+
+// variant skips a variant.
+func (b *buffer) variant() {
+	var (
+		mask = b.uint8()
+		typ  = mask & 0x3f
+		n    = 1
+	)
+
+	if mask&0x80 != 0 {
+		n = b.arrayLength()
+	}
+
+	for i := 0; i < n && b.err == nil; i++ {
+		b.builtin(typ)
+	}
+
+	// array dimensions
+	if mask&0x40 != 0 {
+		b.skip(4 * b.arrayLength())
+	}
+}
+
+// builtin skips a value of the given built-in type.
+func (b *buffer) builtin(typ byte) {
+	if size, ok := fixedSizes[typ]; ok {
+		b.skip(size)
+
+		return
+	}
+
+	switch typ {
+	case 0:
+	case 12, 15, 16: // String, ByteString, XmlElement
+		b.byteString()
+	case 17: // NodeId
+		b.nodeID()
+	case 18: // ExpandedNodeId
+		b.expandedNodeID()
+	case 20:
+		b.qualifiedName()
+	case 21:
+		b.localizedText()
+	case 22:
+		b.extensionObject()
+	case 23:
+		b.dataValue()
+	case 24:
+		b.variant()
+	case 25:
+		b.diagnosticInfo()
+	default:
+		b.err = fmt.Errorf("invalid variant type %d", typ)
+	}
+}
+
+// dataValue skips a data value.
+func (b *buffer) dataValue() {
+	mask := b.uint8()
+
+	if mask&0x01 != 0 {
+		b.variant()
+	}
+
+	// status code, source timestamp, source picoseconds, server timestamp and server picoseconds
+	for _, f := range []struct {
+		bit  byte
+		size int
+	}{{0x02, 4}, {0x04, 8}, {0x10, 2}, {0x08, 8}, {0x20, 2}} {
+		if mask&f.bit != 0 {
+			b.skip(f.size)
+		}
+	}
+}
+
+func formatGUID(g []byte) string {
+	if len(g) != 16 {
+		return ""
+	}
+
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8:10],
+		g[10:16],
+	)
+}
@@ -0,0 +1,81 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package opcua
+
+import (
+	"bytes"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var opcuaLog = zap.NewNop()
+
+const (
+	// message type, chunk type and message size
+	headerSize = 8
+
+	serviceName = "OPC UA"
+
+	securityPolicyNone = "http://opcfoundation.org/UA/SecurityPolicy#None"
+)
+
+// message types.
+const (
+	messageHello        = "HEL"
+	messageAcknowledge  = "ACK"
+	messageError        = "ERR"
+	messageReverseHello = "RHE"
+	messageOpen         = "OPN"
+	messageClose        = "CLO"
+	messageMessage      = "MSG"
+)
+
+// chunk types.
+const (
+	chunkFinal        = 'F'
+	chunkIntermediate = 'C'
+	chunkAbort        = 'A'
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_OPCUA,
+	Name:        "OPCUA",
+	Description: "OPC Unified Architecture is a machine to machine communication protocol for industrial automation",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		opcuaLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"opcua",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		// the client says hello first, unless the server initiated the connection with a reverse hello
+		return bytes.HasPrefix(client, []byte(messageHello+string(chunkFinal))) ||
+			bytes.HasPrefix(server, []byte(messageReverseHello+string(chunkFinal)))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return opcuaLog.Sync()
+	},
+	Factory: &opcuaReader{},
+	Typ:     core.TCP,
+}
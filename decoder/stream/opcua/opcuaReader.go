@@ -0,0 +1,324 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package opcua
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// pendingMessage collects the chunks of a message until the final chunk arrives.
+type pendingMessage struct {
+	record *types.OPCUA
+	body   []byte
+}
+
+type opcuaReader struct {
+	conversation *core.ConversationInfo
+
+	// set once a secure channel with a security policy other than None was opened
+	secure bool
+}
+
+// New will instantiate a new OPC UA reader.
+func (h *opcuaReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &opcuaReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the OPC UA binary protocol.
+func (h *opcuaReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	records, creds := h.records(decoderconfig.Instance.IncludePayloads)
+
+	if credentials.Decoder.Writer != nil {
+		for _, c := range creds {
+			credentials.WriteCredentials(c)
+		}
+	}
+
+	if control.Decoder.Writer != nil {
+		for _, c := range h.controlActions(records) {
+			control.WriteControlAction(c)
+		}
+	}
+
+	for _, r := range records {
+		if ownsApplication(r) {
+			ip := r.ClientIP
+			if !r.IsClient {
+				ip = r.ServerIP
+			}
+
+			utils.AddDeviceIdentifier(ip, "opcua:"+r.ApplicationURI)
+		}
+
+		writeOPCUA(r)
+	}
+}
+
+// records parses the messages of both directions.
+func (h *opcuaReader) records(includePayloads bool) ([]*types.OPCUA, []*types.Credentials) {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		out            []*types.OPCUA
+		creds          []*types.Credentials
+	)
+
+	for _, dir := range []*core.Direction{client, server} {
+		var (
+			data    = dir.Data
+			pending = make(map[uint32]*pendingMessage)
+		)
+
+		for offset := 0; len(data)-offset >= headerSize; {
+			size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+			if size < headerSize || offset+size > len(data) {
+				// incomplete chunk at the end of the stream
+				break
+			}
+
+			var (
+				chunk = data[offset : offset+size]
+				r     = &types.OPCUA{
+					Timestamp:   dir.TimeAt(offset).UnixNano(),
+					ClientIP:    h.conversation.ClientIP,
+					ServerIP:    h.conversation.ServerIP,
+					ClientPort:  h.conversation.ClientPort,
+					ServerPort:  h.conversation.ServerPort,
+					IsClient:    dir.Client,
+					MessageType: string(chunk[:3]),
+					ChunkType:   string(chunk[3]),
+				}
+			)
+
+			offset += size
+
+			if includePayloads {
+				r.Payload = chunk
+			}
+
+			if r = h.parseChunk(r, chunk[headerSize:], pending, &creds); r != nil {
+				out = append(out, r)
+			}
+		}
+	}
+
+	return out, creds
+}
+
+// parseChunk parses a single message chunk. Intermediate chunks are buffered
+// and nil is returned until the final chunk of the message arrives.
+func (h *opcuaReader) parseChunk(r *types.OPCUA, data []byte, pending map[uint32]*pendingMessage, creds *[]*types.Credentials) *types.OPCUA {
+	b := &buffer{data: data}
+
+	switch r.MessageType {
+	case messageHello:
+		// protocol version, buffer sizes, max message size and max chunk count
+		b.skip(20)
+		r.EndpointURL = b.string()
+
+		return r
+	case messageAcknowledge:
+		return r
+	case messageError:
+		r.StatusCode = b.uint32()
+		r.Error = b.string()
+
+		return r
+	case messageReverseHello:
+		r.ApplicationURI = b.string()
+		r.EndpointURL = b.string()
+
+		return r
+	case messageOpen:
+		r.SecureChannelID = b.uint32()
+		r.SecurityPolicyURI = b.string()
+
+		// sender certificate and receiver certificate thumbprint
+		b.byteString()
+		b.byteString()
+
+		// the asymmetric security header is followed by encrypted data unless the policy is None
+		if r.SecurityPolicyURI != securityPolicyNone {
+			h.secure = true
+			r.Encrypted = true
+
+			return r
+		}
+	case messageMessage, messageClose:
+		// secure channel and token id
+		r.SecureChannelID = b.uint32()
+		b.skip(4)
+	default:
+		opcuaLog.Debug("unknown OPC UA message type", zap.String("type", r.MessageType))
+
+		return r
+	}
+
+	r.SequenceNumber = b.uint32()
+	r.RequestID = b.uint32()
+
+	if b.err != nil {
+		return r
+	}
+
+	switch r.ChunkType[0] {
+	case chunkIntermediate:
+		if p, ok := pending[r.RequestID]; ok {
+			p.body = append(p.body, b.data...)
+		} else {
+			pending[r.RequestID] = &pendingMessage{record: r, body: b.data}
+		}
+
+		return nil
+	case chunkAbort:
+		delete(pending, r.RequestID)
+
+		r.StatusCode = b.uint32()
+		r.Error = b.string()
+
+		return r
+	case chunkFinal:
+		if p, ok := pending[r.RequestID]; ok {
+			delete(pending, r.RequestID)
+
+			p.body = append(p.body, b.data...)
+			p.record.ChunkType = r.ChunkType
+			r, b = p.record, &buffer{data: p.body}
+		}
+	}
+
+	_, typ := b.expandedNodeID()
+
+	name, ok := services[typ]
+	if !ok || b.err != nil {
+		// symmetrically encrypted messages also encrypt the sequence header
+		if h.secure {
+			r.Encrypted = true
+			r.SequenceNumber = 0
+			r.RequestID = 0
+		}
+
+		return r
+	}
+
+	r.ServiceID = int32(typ)
+	r.Service = name
+
+	user, password := parseService(b, typ, r)
+	if b.err != nil {
+		opcuaLog.Debug("failed to parse OPC UA service", zap.String("service", name), zap.Error(b.err))
+	}
+
+	if password != "" {
+		*creds = append(*creds, &types.Credentials{
+			Timestamp: r.Timestamp,
+			Service:   serviceName,
+			Flow:      h.conversation.Ident,
+			User:      user,
+			Password:  password,
+			Notes:     "unencrypted user name identity token",
+		})
+	}
+
+	return r
+}
+
+// ownsApplication checks whether the application description contained in the record belongs to its sender.
+func ownsApplication(r *types.OPCUA) bool {
+	if r.ApplicationURI == "" {
+		return false
+	}
+
+	switch r.ServiceID {
+	case createSessionRequest, createSessionResponse, getEndpointsResponse:
+		return true
+	}
+
+	return r.MessageType == messageReverseHello
+}
+
+// controlActions pairs the write, call and node management requests with their responses.
+func (h *opcuaReader) controlActions(records []*types.OPCUA) []*types.ControlAction {
+	var actions []*types.ControlAction
+
+	for _, r := range records {
+		operation, ok := controlServices[uint32(r.ServiceID)]
+		if !ok || isResponse(r.Service) {
+			continue
+		}
+
+		c := &types.ControlAction{
+			Timestamp:  r.Timestamp,
+			Protocol:   serviceName,
+			Flow:       h.conversation.Ident,
+			ClientIP:   r.ClientIP,
+			ServerIP:   r.ServerIP,
+			ClientPort: r.ClientPort,
+			ServerPort: r.ServerPort,
+			Operation:  operation,
+			Quantity:   int32(len(r.NodeIDs)),
+			Data:       r.Payload,
+		}
+
+		if len(r.NodeIDs) > 0 {
+			c.Target = r.NodeIDs[0]
+		}
+
+		for _, res := range records {
+			if res.IsClient == r.IsClient || res.Timestamp < r.Timestamp || res.SecureChannelID != r.SecureChannelID || res.RequestID != r.RequestID || !isResponse(res.Service) {
+				continue
+			}
+
+			c.Answered = true
+			c.Status = int32(res.StatusCode)
+			c.Success = res.StatusCode == 0
+
+			break
+		}
+
+		actions = append(actions, c)
+	}
+
+	return actions
+}
+
+func writeOPCUA(r *types.OPCUA) {
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		opcuaLog.Error("failed to write OPC UA record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package opcua
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func fragment(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+// encoder produces the OPC UA binary encoding for the test messages.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) u8(v byte) *encoder {
+	e.WriteByte(v)
+
+	return e
+}
+
+func (e *encoder) u32(v uint32) *encoder {
+	_ = binary.Write(e, binary.LittleEndian, v)
+
+	return e
+}
+
+func (e *encoder) str(s string) *encoder {
+	e.u32(uint32(len(s)))
+	e.WriteString(s)
+
+	return e
+}
+
+func (e *encoder) null() *encoder {
+	return e.u32(0xffffffff)
+}
+
+// numeric writes a numeric node id in four byte encoding.
+func (e *encoder) numeric(ns byte, id uint16) *encoder {
+	e.u8(nodeIDFourByte).u8(ns)
+	_ = binary.Write(e, binary.LittleEndian, id)
+
+	return e
+}
+
+func (e *encoder) requestHeader(handle uint32) *encoder {
+	e.u8(nodeIDTwoByte).u8(0)
+	e.Write(make([]byte, 8))
+
+	return e.u32(handle).u32(0).null().u32(10000).u8(nodeIDTwoByte).u8(0).u8(0)
+}
+
+func (e *encoder) responseHeader(handle, status uint32) *encoder {
+	e.Write(make([]byte, 8))
+
+	return e.u32(handle).u32(status).u8(0).null().u8(nodeIDTwoByte).u8(0).u8(0)
+}
+
+func chunk(typ string, chunkType byte, body []byte) []byte {
+	out := append([]byte(typ), chunkType, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(body)+headerSize))
+
+	return append(out, body...)
+}
+
+// msg wraps a service body into a secure conversation message.
+func msg(chunkType byte, channel, seq, request uint32, body []byte) []byte {
+	e := &encoder{}
+	e.u32(channel).u32(1).u32(seq).u32(request)
+	e.Write(body)
+
+	return chunk(messageMessage, chunkType, e.Bytes())
+}
+
+func TestRecords(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.1",
+			ServerIP:   "10.0.0.2",
+			ClientPort: 50000,
+			ServerPort: 4840,
+			Ident:      "10.0.0.1:50000->10.0.0.2:4840",
+		}
+		endpoint = "opc.tcp://plc:4840"
+
+		hello = chunk(messageHello, chunkFinal, (&encoder{}).u32(0).u32(65536).u32(65536).u32(0).u32(0).str(endpoint).Bytes())
+		ack   = chunk(messageAcknowledge, chunkFinal, (&encoder{}).u32(0).u32(65536).u32(65536).u32(0).u32(0).Bytes())
+
+		open = chunk(messageOpen, chunkFinal, (&encoder{}).
+			u32(0).str(securityPolicyNone).null().null().u32(1).u32(1).
+			numeric(0, openSecureChannelRequest).requestHeader(1).
+			u32(0).u32(0).u32(1).null().u32(3600000).Bytes())
+
+		// a single endpoint without security and with anonymous and user name tokens
+		endpoints = (&encoder{}).numeric(0, getEndpointsResponse).responseHeader(2, 0).
+				u32(1).str(endpoint).
+				str("urn:plc:server").str("urn:vendor:product").u8(0x02).str("PLC Server").u32(0).null().null().u32(1).str(endpoint).
+				null().u32(1).str(securityPolicyNone).
+				u32(2).str("anon").u32(0).null().null().null().str("user").u32(1).null().null().null().
+				str("http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary").u8(0)
+
+		// user name token with a plaintext password
+		token    = (&encoder{}).str("user").str("operator").str("secret").null()
+		activate = (&encoder{}).numeric(0, activateSessionRequest).requestHeader(3).
+				null().null().u32(0).u32(0).
+				numeric(0, userNameIdentityToken).u8(0x01).u32(uint32(token.Len()))
+
+		// write an int32 to ns=2;s=Setpoint
+		write = (&encoder{}).numeric(0, writeRequest).requestHeader(4).
+			u32(1).u8(nodeIDString)
+		writeRes = (&encoder{}).numeric(0, writeResponse).responseHeader(4, 0x80350000).u32(0).null()
+	)
+
+	activate.Write(token.Bytes())
+	activate.null()
+
+	write.Write([]byte{0x02, 0x00})
+	write.str("Setpoint").u32(13).null().u8(0x01).u8(6).u32(42)
+
+	if !Decoder.CanDecode(hello, ack) {
+		t.Fatal("expected OPC UA stream to be detected")
+	}
+
+	if Decoder.CanDecode([]byte("GET / HTTP/1.1\r\n"), nil) {
+		t.Fatal("unexpected detection of HTTP as OPC UA")
+	}
+
+	var (
+		wb     = write.Bytes()
+		client = bytes.Join([][]byte{hello, open, msg(chunkFinal, 5, 2, 3, activate.Bytes()), msg(chunkIntermediate, 5, 3, 4, wb[:20]), msg(chunkFinal, 5, 4, 4, wb[20:])}, nil)
+		server = bytes.Join([][]byte{ack, msg(chunkFinal, 5, 1, 2, endpoints.Bytes()), msg(chunkFinal, 5, 2, 4, writeRes.Bytes())}, nil)
+	)
+
+	conv.Data = append(conv.Data,
+		fragment(client[:10], reassembly.TCPDirClientToServer, start),
+		fragment(client[10:], reassembly.TCPDirClientToServer, start.Add(time.Millisecond)),
+		fragment(server, reassembly.TCPDirServerToClient, start.Add(3*time.Millisecond)),
+	)
+
+	h := (&opcuaReader{}).New(conv).(*opcuaReader)
+
+	records, creds := h.records(false)
+	if len(records) != 7 {
+		t.Fatalf("expected 7 records, got %d", len(records))
+	}
+
+	if r := records[0]; r.MessageType != messageHello || r.EndpointURL != endpoint {
+		t.Fatalf("unexpected hello record: %+v", r)
+	}
+
+	if r := records[1]; r.Service != "OpenSecureChannelRequest" || r.SecurityPolicyURI != securityPolicyNone || r.SecurityMode != "None" || r.Encrypted {
+		t.Fatalf("unexpected open record: %+v", r)
+	}
+
+	if r := records[2]; r.Service != "ActivateSessionRequest" || r.User != "operator" || r.RequestHandle != 3 {
+		t.Fatalf("unexpected activate record: %+v", r)
+	}
+
+	if len(creds) != 1 || creds[0].User != "operator" || creds[0].Password != "secret" || creds[0].Service != serviceName {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+
+	w := records[3]
+	if w.Service != "WriteRequest" || w.RequestID != 4 || w.ChunkType != "F" || len(w.NodeIDs) != 1 || w.NodeIDs[0] != "ns=2;s=Setpoint" {
+		t.Fatalf("unexpected write record: %+v", w)
+	}
+
+	e := records[5]
+	if e.Service != "GetEndpointsResponse" || e.ApplicationURI != "urn:plc:server" || e.ApplicationName != "PLC Server" || e.ProductURI != "urn:vendor:product" {
+		t.Fatalf("unexpected endpoints record: %+v", e)
+	}
+
+	if len(e.Endpoints) != 1 || e.Endpoints[0] != endpoint+" None "+securityPolicyNone || !ownsApplication(e) {
+		t.Fatalf("unexpected endpoints: %v", e.Endpoints)
+	}
+
+	actions := h.controlActions(records)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 control action, got %d", len(actions))
+	}
+
+	if a := actions[0]; a.Operation != "Write" || a.Target != "ns=2;s=Setpoint" || !a.Answered || a.Success || uint32(a.Status) != 0x80350000 {
+		t.Fatalf("unexpected control action: %+v", a)
+	}
+}
+
+func TestEncryptedChannel(t *testing.T) {
+	var (
+		conv = &core.ConversationInfo{ClientIP: "10.0.0.1", ServerIP: "10.0.0.2", ServerPort: 4840}
+		open = chunk(messageOpen, chunkFinal, (&encoder{}).
+			u32(0).str("http://opcfoundation.org/UA/SecurityPolicy#Basic256Sha256").str("cert").str("thumbprint").
+			u32(0xdeadbeef).u32(0xcafebabe).Bytes())
+		encrypted = msg(chunkFinal, 5, 0x11223344, 0x55667788, []byte{0xff, 0xee, 0xdd, 0xcc, 0xbb, 0xaa})
+	)
+
+	conv.Data = append(conv.Data, fragment(append(open, encrypted...), reassembly.TCPDirClientToServer, time.Unix(1600000000, 0)))
+
+	records, _ := (&opcuaReader{}).New(conv).(*opcuaReader).records(false)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	for _, r := range records {
+		if !r.Encrypted || r.Service != "" || r.RequestID != 0 {
+			t.Fatalf("unexpected record on encrypted channel: %+v", r)
+		}
+	}
+}
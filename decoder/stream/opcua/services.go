@@ -0,0 +1,426 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package opcua
+
+import (
+	"strings"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// binary encoding ids of the service requests and responses.
+const (
+	serviceFault                  = 397
+	findServersRequest            = 422
+	findServersResponse           = 425
+	getEndpointsRequest           = 428
+	getEndpointsResponse          = 431
+	registerServerRequest         = 437
+	registerServerResponse        = 440
+	openSecureChannelRequest      = 446
+	openSecureChannelResponse     = 449
+	closeSecureChannelRequest     = 452
+	closeSecureChannelResponse    = 455
+	createSessionRequest          = 461
+	createSessionResponse         = 464
+	activateSessionRequest        = 467
+	activateSessionResponse       = 470
+	closeSessionRequest           = 473
+	closeSessionResponse          = 476
+	cancelRequest                 = 479
+	cancelResponse                = 482
+	addNodesRequest               = 488
+	addNodesResponse              = 491
+	addReferencesRequest          = 494
+	addReferencesResponse         = 497
+	deleteNodesRequest            = 500
+	deleteNodesResponse           = 503
+	deleteReferencesRequest       = 506
+	deleteReferencesResponse      = 509
+	browseRequest                 = 527
+	browseResponse                = 530
+	browseNextRequest             = 533
+	browseNextResponse            = 536
+	translateBrowsePathsRequest   = 554
+	translateBrowsePathsResponse  = 557
+	registerNodesRequest          = 560
+	registerNodesResponse         = 563
+	unregisterNodesRequest        = 566
+	unregisterNodesResponse       = 569
+	queryFirstRequest             = 615
+	queryFirstResponse            = 618
+	queryNextRequest              = 621
+	queryNextResponse             = 624
+	readRequest                   = 631
+	readResponse                  = 634
+	historyReadRequest            = 664
+	historyReadResponse           = 667
+	writeRequest                  = 673
+	writeResponse                 = 676
+	historyUpdateRequest          = 700
+	historyUpdateResponse         = 703
+	callRequest                   = 712
+	callResponse                  = 715
+	createMonitoredItemsRequest   = 751
+	createMonitoredItemsResponse  = 754
+	modifyMonitoredItemsRequest   = 763
+	modifyMonitoredItemsResponse  = 766
+	setMonitoringModeRequest      = 769
+	setMonitoringModeResponse     = 772
+	setTriggeringRequest          = 775
+	setTriggeringResponse         = 778
+	deleteMonitoredItemsRequest   = 781
+	deleteMonitoredItemsResponse  = 784
+	createSubscriptionRequest     = 787
+	createSubscriptionResponse    = 790
+	modifySubscriptionRequest     = 793
+	modifySubscriptionResponse    = 796
+	setPublishingModeRequest      = 799
+	setPublishingModeResponse     = 802
+	publishRequest                = 826
+	publishResponse               = 829
+	republishRequest              = 832
+	republishResponse             = 835
+	transferSubscriptionsRequest  = 841
+	transferSubscriptionsResponse = 844
+	deleteSubscriptionsRequest    = 847
+	deleteSubscriptionsResponse   = 850
+)
+
+// binary encoding ids of the user identity tokens.
+const (
+	anonymousIdentityToken = 321
+	userNameIdentityToken  = 324
+	x509IdentityToken      = 327
+	issuedIdentityToken    = 940
+)
+
+var services = map[uint32]string{
+	serviceFault:                  "ServiceFault",
+	findServersRequest:            "FindServersRequest",
+	findServersResponse:           "FindServersResponse",
+	getEndpointsRequest:           "GetEndpointsRequest",
+	getEndpointsResponse:          "GetEndpointsResponse",
+	registerServerRequest:         "RegisterServerRequest",
+	registerServerResponse:        "RegisterServerResponse",
+	openSecureChannelRequest:      "OpenSecureChannelRequest",
+	openSecureChannelResponse:     "OpenSecureChannelResponse",
+	closeSecureChannelRequest:     "CloseSecureChannelRequest",
+	closeSecureChannelResponse:    "CloseSecureChannelResponse",
+	createSessionRequest:          "CreateSessionRequest",
+	createSessionResponse:         "CreateSessionResponse",
+	activateSessionRequest:        "ActivateSessionRequest",
+	activateSessionResponse:       "ActivateSessionResponse",
+	closeSessionRequest:           "CloseSessionRequest",
+	closeSessionResponse:          "CloseSessionResponse",
+	cancelRequest:                 "CancelRequest",
+	cancelResponse:                "CancelResponse",
+	addNodesRequest:               "AddNodesRequest",
+	addNodesResponse:              "AddNodesResponse",
+	addReferencesRequest:          "AddReferencesRequest",
+	addReferencesResponse:         "AddReferencesResponse",
+	deleteNodesRequest:            "DeleteNodesRequest",
+	deleteNodesResponse:           "DeleteNodesResponse",
+	deleteReferencesRequest:       "DeleteReferencesRequest",
+	deleteReferencesResponse:      "DeleteReferencesResponse",
+	browseRequest:                 "BrowseRequest",
+	browseResponse:                "BrowseResponse",
+	browseNextRequest:             "BrowseNextRequest",
+	browseNextResponse:            "BrowseNextResponse",
+	translateBrowsePathsRequest:   "TranslateBrowsePathsToNodeIdsRequest",
+	translateBrowsePathsResponse:  "TranslateBrowsePathsToNodeIdsResponse",
+	registerNodesRequest:          "RegisterNodesRequest",
+	registerNodesResponse:         "RegisterNodesResponse",
+	unregisterNodesRequest:        "UnregisterNodesRequest",
+	unregisterNodesResponse:       "UnregisterNodesResponse",
+	queryFirstRequest:             "QueryFirstRequest",
+	queryFirstResponse:            "QueryFirstResponse",
+	queryNextRequest:              "QueryNextRequest",
+	queryNextResponse:             "QueryNextResponse",
+	readRequest:                   "ReadRequest",
+	readResponse:                  "ReadResponse",
+	historyReadRequest:            "HistoryReadRequest",
+	historyReadResponse:           "HistoryReadResponse",
+	writeRequest:                  "WriteRequest",
+	writeResponse:                 "WriteResponse",
+	historyUpdateRequest:          "HistoryUpdateRequest",
+	historyUpdateResponse:         "HistoryUpdateResponse",
+	callRequest:                   "CallRequest",
+	callResponse:                  "CallResponse",
+	createMonitoredItemsRequest:   "CreateMonitoredItemsRequest",
+	createMonitoredItemsResponse:  "CreateMonitoredItemsResponse",
+	modifyMonitoredItemsRequest:   "ModifyMonitoredItemsRequest",
+	modifyMonitoredItemsResponse:  "ModifyMonitoredItemsResponse",
+	setMonitoringModeRequest:      "SetMonitoringModeRequest",
+	setMonitoringModeResponse:     "SetMonitoringModeResponse",
+	setTriggeringRequest:          "SetTriggeringRequest",
+	setTriggeringResponse:         "SetTriggeringResponse",
+	deleteMonitoredItemsRequest:   "DeleteMonitoredItemsRequest",
+	deleteMonitoredItemsResponse:  "DeleteMonitoredItemsResponse",
+	createSubscriptionRequest:     "CreateSubscriptionRequest",
+	createSubscriptionResponse:    "CreateSubscriptionResponse",
+	modifySubscriptionRequest:     "ModifySubscriptionRequest",
+	modifySubscriptionResponse:    "ModifySubscriptionResponse",
+	setPublishingModeRequest:      "SetPublishingModeRequest",
+	setPublishingModeResponse:     "SetPublishingModeResponse",
+	publishRequest:                "PublishRequest",
+	publishResponse:               "PublishResponse",
+	republishRequest:              "RepublishRequest",
+	republishResponse:             "RepublishResponse",
+	transferSubscriptionsRequest:  "TransferSubscriptionsRequest",
+	transferSubscriptionsResponse: "TransferSubscriptionsResponse",
+	deleteSubscriptionsRequest:    "DeleteSubscriptionsRequest",
+	deleteSubscriptionsResponse:   "DeleteSubscriptionsResponse",
+}
+
+// controlServices modify values or the address space of the server.
+var controlServices = map[uint32]string{
+	writeRequest:         "Write",
+	callRequest:          "Call",
+	addNodesRequest:      "AddNodes",
+	deleteNodesRequest:   "DeleteNodes",
+	historyUpdateRequest: "HistoryUpdate",
+}
+
+var securityModes = map[uint32]string{
+	0: "Invalid",
+	1: "None",
+	2: "Sign",
+	3: "SignAndEncrypt",
+}
+
+func isResponse(service string) bool {
+	return strings.HasSuffix(service, "Response") || service == "ServiceFault"
+}
+
+// requestHeader parses the common header of all service requests.
+func requestHeader(b *buffer, r *types.OPCUA) {
+	// authentication token, timestamp
+	b.nodeID()
+	b.skip(8)
+
+	r.RequestHandle = b.uint32()
+
+	// return diagnostics, audit entry id, timeout hint and additional header
+	b.skip(4)
+	b.string()
+	b.skip(4)
+	b.extensionObject()
+}
+
+// responseHeader parses the common header of all service responses.
+func responseHeader(b *buffer, r *types.OPCUA) {
+	// timestamp
+	b.skip(8)
+
+	r.RequestHandle = b.uint32()
+	r.StatusCode = b.uint32()
+
+	// service diagnostics, string table and additional header
+	b.diagnosticInfo()
+	b.stringArray()
+	b.extensionObject()
+}
+
+// applicationDescription parses the identity of a client or server application.
+func applicationDescription(b *buffer, r *types.OPCUA) {
+	var (
+		uri     = b.string()
+		product = b.string()
+		name    = b.localizedText()
+	)
+
+	// application type, gateway server uri, discovery profile uri and discovery urls
+	b.skip(4)
+	b.string()
+	b.string()
+	b.stringArray()
+
+	if r.ApplicationURI == "" {
+		r.ApplicationURI = uri
+		r.ProductURI = product
+		r.ApplicationName = name
+	}
+}
+
+// endpointDescriptions parses an array of endpoint descriptions.
+func endpointDescriptions(b *buffer, r *types.OPCUA) {
+	for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+		url := b.string()
+		applicationDescription(b, r)
+
+		// server certificate
+		b.byteString()
+
+		mode := securityModes[b.uint32()]
+		policy := b.string()
+
+		// user identity tokens: policy id, token type, issued token type, issuer endpoint url and security policy uri
+		for j, m := 0, b.arrayLength(); j < m && b.err == nil; j++ {
+			b.string()
+			b.skip(4)
+			b.string()
+			b.string()
+			b.string()
+		}
+
+		// transport profile uri and security level
+		b.string()
+		b.skip(1)
+
+		if r.EndpointURL == "" {
+			r.EndpointURL = url
+		}
+
+		r.Endpoints = append(r.Endpoints, url+" "+mode+" "+policy)
+	}
+}
+
+// parseService parses the request or response header and the parameters of well known services.
+// Parsing stops at the first decoding error, the fields collected until then are kept.
+func parseService(b *buffer, typ uint32, r *types.OPCUA) (user, password string) {
+	if isResponse(r.Service) {
+		responseHeader(b, r)
+	} else {
+		requestHeader(b, r)
+	}
+
+	switch typ {
+	case openSecureChannelRequest:
+		// client protocol version and request type
+		b.skip(8)
+		r.SecurityMode = securityModes[b.uint32()]
+	case getEndpointsRequest:
+		r.EndpointURL = b.string()
+	case getEndpointsResponse:
+		endpointDescriptions(b, r)
+	case findServersResponse:
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			applicationDescription(b, r)
+		}
+	case createSessionRequest:
+		applicationDescription(b, r)
+
+		// server uri
+		b.string()
+		r.EndpointURL = b.string()
+	case createSessionResponse:
+		// session id, authentication token, revised session timeout, server nonce and certificate
+		b.nodeID()
+		b.nodeID()
+		b.skip(8)
+		b.byteString()
+		b.byteString()
+		endpointDescriptions(b, r)
+	case activateSessionRequest:
+		// client signature, software certificates and locale ids
+		b.string()
+		b.byteString()
+
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			b.byteString()
+			b.byteString()
+		}
+
+		b.stringArray()
+
+		return identityToken(b, r)
+	case browseRequest:
+		// view description and requested max references per node
+		b.nodeID()
+		b.skip(8 + 4 + 4)
+
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			r.NodeIDs = append(r.NodeIDs, b.nodeID())
+
+			// browse direction, reference type, include subtypes, node class and result mask
+			b.skip(4)
+			b.nodeID()
+			b.skip(1 + 4 + 4)
+		}
+	case readRequest:
+		// max age and timestamps to return
+		b.skip(8 + 4)
+
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			r.NodeIDs = append(r.NodeIDs, b.nodeID())
+
+			// attribute id, index range and data encoding
+			b.skip(4)
+			b.string()
+			b.qualifiedName()
+		}
+	case writeRequest:
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			r.NodeIDs = append(r.NodeIDs, b.nodeID())
+
+			// attribute id, index range and value
+			b.skip(4)
+			b.string()
+			b.dataValue()
+		}
+	case callRequest:
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			// object and method
+			r.NodeIDs = append(r.NodeIDs, b.nodeID(), b.nodeID())
+
+			for j, m := 0, b.arrayLength(); j < m && b.err == nil; j++ {
+				b.variant()
+			}
+		}
+	case deleteNodesRequest:
+		for i, n := 0, b.arrayLength(); i < n && b.err == nil; i++ {
+			id, _ := b.expandedNodeID()
+			r.NodeIDs = append(r.NodeIDs, id)
+
+			// delete target references
+			b.skip(1)
+		}
+	}
+
+	return "", ""
+}
+
+// identityToken parses the user identity token of a session activation.
+// Passwords are only returned if they are not encrypted.
+func identityToken(b *buffer, r *types.OPCUA) (user, password string) {
+	typ, body := b.extensionObject()
+
+	switch typ {
+	case anonymousIdentityToken:
+		r.User = "anonymous"
+	case x509IdentityToken:
+		r.User = "x509"
+	case issuedIdentityToken:
+		r.User = "issued"
+	case userNameIdentityToken:
+		t := &buffer{data: body}
+
+		// policy id
+		t.string()
+
+		r.User = t.string()
+
+		var (
+			secret    = t.byteString()
+			algorithm = t.string()
+		)
+
+		if t.err == nil && algorithm == "" && len(secret) > 0 {
+			return r.User, string(secret)
+		}
+	}
+
+	return r.User, ""
+}
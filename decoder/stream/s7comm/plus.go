@@ -0,0 +1,150 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package s7comm
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// S7comm-plus opcodes.
+const (
+	plusRequest      = 0x31
+	plusResponse     = 0x32
+	plusNotification = 0x33
+)
+
+var plusOpcodes = map[byte]string{
+	plusRequest:      "Request",
+	plusResponse:     "Response",
+	plusNotification: "Notification",
+}
+
+// S7comm-plus function codes.
+const (
+	plusError               = 0x04b1
+	plusExplore             = 0x04bb
+	plusCreateObject        = 0x04ca
+	plusDeleteObject        = 0x04d4
+	plusSetVariable         = 0x04f2
+	plusGetVariable         = 0x04fc
+	plusAddLink             = 0x0506
+	plusRemoveLink          = 0x051a
+	plusGetLink             = 0x0524
+	plusSetMultiVariables   = 0x0542
+	plusGetMultiVariables   = 0x054c
+	plusBeginSequence       = 0x0556
+	plusEndSequence         = 0x0560
+	plusInvoke              = 0x056b
+	plusSetVarSubStreamed   = 0x057c
+	plusGetVarSubStreamed   = 0x0586
+	plusGetVariablesAddress = 0x0590
+	plusAbort               = 0x059a
+)
+
+var plusFunctions = map[uint16]string{
+	plusError:               "Error",
+	plusExplore:             "Explore",
+	plusCreateObject:        "Create Object",
+	plusDeleteObject:        "Delete Object",
+	plusSetVariable:         "Set Variable",
+	plusGetVariable:         "Get Variable",
+	plusAddLink:             "Add Link",
+	plusRemoveLink:          "Remove Link",
+	plusGetLink:             "Get Link",
+	plusSetMultiVariables:   "Set Multi Variables",
+	plusGetMultiVariables:   "Get Multi Variables",
+	plusBeginSequence:       "Begin Sequence",
+	plusEndSequence:         "End Sequence",
+	plusInvoke:              "Invoke",
+	plusSetVarSubStreamed:   "Set Var Sub Streamed",
+	plusGetVarSubStreamed:   "Get Var Sub Streamed",
+	plusGetVariablesAddress: "Get Variables Address",
+	plusAbort:               "Abort",
+}
+
+// plusControlFunctions modify variables or objects on the PLC.
+var plusControlFunctions = map[uint16]struct{}{
+	plusDeleteObject:      {},
+	plusSetVariable:       {},
+	plusSetMultiVariables: {},
+	plusSetVarSubStreamed: {},
+}
+
+const (
+	// protocol id, version and data length
+	plusHeaderSize = 4
+
+	// the integrity part of version 3 starts with the digest length
+	plusDigestSize = 32
+
+	// opcode, reserved, function, reserved and sequence number
+	plusDataHeaderSize = 9
+
+	plusVersion3 = 0x03
+)
+
+// parseS7Plus parses the header and the function of a S7comm-plus PDU.
+func parseS7Plus(data []byte, r *types.S7Comm) bool {
+	if len(data) < plusHeaderSize {
+		return false
+	}
+
+	r.Protocol = protocolS7Plus
+	r.PlusVersion = int32(data[1])
+
+	data = data[plusHeaderSize:]
+
+	if r.PlusVersion == plusVersion3 && len(data) > plusDigestSize && data[0] == plusDigestSize {
+		data = data[1+plusDigestSize:]
+	}
+
+	if len(data) < plusDataHeaderSize {
+		return true
+	}
+
+	var (
+		opcode = data[0]
+		fn     = binary.BigEndian.Uint16(data[3:5])
+	)
+
+	r.PlusOpcode = int32(opcode)
+	if name, ok := plusOpcodes[opcode]; ok {
+		r.ROSCTRName = name
+	} else {
+		r.ROSCTRName = "Opcode 0x" + strconv.FormatInt(int64(opcode), 16)
+	}
+
+	// notifications carry a subscription object id instead of a function
+	if opcode == plusNotification {
+		return true
+	}
+
+	r.FunctionCode = int32(fn)
+	r.PDUReference = int32(binary.BigEndian.Uint16(data[7:9]))
+
+	if name, ok := plusFunctions[fn]; ok {
+		r.Function = name
+	} else {
+		r.Function = "Function 0x" + strconv.FormatInt(int64(fn), 16)
+	}
+
+	if opcode == plusRequest {
+		_, r.Control = plusControlFunctions[fn]
+	}
+
+	return true
+}
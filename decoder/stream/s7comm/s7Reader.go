@@ -0,0 +1,591 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package s7comm
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/control"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// PDU types.
+const (
+	rosctrJob      = 1
+	rosctrAck      = 2
+	rosctrAckData  = 3
+	rosctrUserdata = 7
+)
+
+var rosctrNames = map[byte]string{
+	rosctrJob:      "Job",
+	rosctrAck:      "Ack",
+	rosctrAckData:  "Ack_Data",
+	rosctrUserdata: "Userdata",
+}
+
+// function codes.
+const (
+	fnCPUServices      = 0x00
+	fnReadVar          = 0x04
+	fnWriteVar         = 0x05
+	fnRequestDownload  = 0x1a
+	fnDownloadBlock    = 0x1b
+	fnDownloadEnded    = 0x1c
+	fnStartUpload      = 0x1d
+	fnUpload           = 0x1e
+	fnEndUpload        = 0x1f
+	fnPIService        = 0x28
+	fnPLCStop          = 0x29
+	fnSetupCommunicate = 0xf0
+)
+
+var functionNames = map[byte]string{
+	fnCPUServices:      "CPU Services",
+	fnReadVar:          "Read Var",
+	fnWriteVar:         "Write Var",
+	fnRequestDownload:  "Request Download",
+	fnDownloadBlock:    "Download Block",
+	fnDownloadEnded:    "Download Ended",
+	fnStartUpload:      "Start Upload",
+	fnUpload:           "Upload",
+	fnEndUpload:        "End Upload",
+	fnPIService:        "PI Service",
+	fnPLCStop:          "PLC Stop",
+	fnSetupCommunicate: "Setup Communication",
+}
+
+// controlFunctions write data, transfer blocks to the PLC or change its operating state.
+var controlFunctions = map[byte]struct{}{
+	fnWriteVar:        {},
+	fnRequestDownload: {},
+	fnDownloadBlock:   {},
+	fnDownloadEnded:   {},
+	fnPIService:       {},
+	fnPLCStop:         {},
+}
+
+var userdataGroups = map[byte]string{
+	0x1: "Programmer Commands",
+	0x2: "Cyclic Data",
+	0x3: "Block Functions",
+	0x4: "CPU Functions",
+	0x5: "Security",
+	0x6: "PBC BSEND/BRECV",
+	0x7: "Time Functions",
+	0xf: "NC Programming",
+}
+
+var areaNames = map[byte]string{
+	0x03: "System Info",
+	0x05: "System Flags",
+	0x06: "Analog Inputs",
+	0x07: "Analog Outputs",
+	0x1c: "Counter",
+	0x1d: "Timer",
+	0x1e: "IEC Counter",
+	0x1f: "IEC Timer",
+	0x80: "Peripheral",
+	0x81: "Inputs",
+	0x82: "Outputs",
+	0x83: "Flags",
+	0x84: "DB",
+	0x85: "DI",
+	0x86: "Local",
+	0x87: "V",
+}
+
+var blockTypes = map[string]string{
+	"08": "OB",
+	"0A": "DB",
+	"0B": "SDB",
+	"0C": "FC",
+	"0D": "SFC",
+	"0E": "FB",
+	"0F": "SFB",
+}
+
+const (
+	// protocol id, ROSCTR, redundancy identification, PDU reference, parameter and data length
+	headerSize = 10
+
+	// Ack and Ack_Data headers carry an additional error class and code
+	ackHeaderSize = 12
+
+	// specification, length, syntax id, transport size, length, DB number, area and address
+	itemSize = 12
+
+	// return code of successful item access
+	returnCodeSuccess = 0xff
+)
+
+// data transport sizes with a length in bits.
+var bitLengths = map[byte]struct{}{
+	0x03: {},
+	0x04: {},
+	0x05: {},
+}
+
+// pdu is a reassembled S7comm or S7comm-plus PDU.
+type pdu struct {
+	client    bool
+	data      []byte
+	timestamp time.Time
+}
+
+type s7Reader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new S7comm reader.
+func (h *s7Reader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &s7Reader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the S7comm and S7comm-plus protocols.
+func (h *s7Reader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	records := h.records(decoderconfig.Instance.IncludePayloads)
+
+	if control.Decoder.Writer != nil {
+		for _, c := range h.controlActions(records) {
+			control.WriteControlAction(c)
+		}
+	}
+
+	for _, r := range records {
+		writeS7Comm(r)
+	}
+}
+
+// records parses the PDUs of both directions.
+func (h *s7Reader) records(includePayloads bool) []*types.S7Comm {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		out            []*types.S7Comm
+	)
+
+	for _, p := range append(pdus(client), pdus(server)...) {
+		r := &types.S7Comm{
+			Timestamp:  p.timestamp.UnixNano(),
+			ClientIP:   h.conversation.ClientIP,
+			ServerIP:   h.conversation.ServerIP,
+			ClientPort: h.conversation.ClientPort,
+			ServerPort: h.conversation.ServerPort,
+			IsClient:   p.client,
+		}
+
+		if includePayloads {
+			r.Payload = p.data
+		}
+
+		switch p.data[0] {
+		case protocolIDS7:
+			if !parseS7(p.data, r) {
+				continue
+			}
+		case protocolIDS7Plus:
+			if !parseS7Plus(p.data, r) {
+				continue
+			}
+		default:
+			s7Log.Debug("unknown protocol id", zap.Uint8("id", p.data[0]))
+
+			continue
+		}
+
+		out = append(out, r)
+	}
+
+	return out
+}
+
+// pdus reassembles the COTP data units sent in the given direction.
+func pdus(dir *core.Direction) []*pdu {
+	var (
+		out     []*pdu
+		current *pdu
+	)
+
+	for _, t := range tpdus(dir.Data) {
+		if t.typ != cotpData {
+			continue
+		}
+
+		if current == nil {
+			current = &pdu{
+				client:    dir.Client,
+				timestamp: dir.TimeAt(t.offset),
+			}
+		}
+
+		current.data = append(current.data, t.data...)
+
+		if t.eot {
+			if len(current.data) > 0 {
+				out = append(out, current)
+			}
+
+			current = nil
+		}
+	}
+
+	return out
+}
+
+// parseS7 parses the header, parameters and data of a S7comm PDU.
+func parseS7(data []byte, r *types.S7Comm) bool {
+	if len(data) < headerSize {
+		return false
+	}
+
+	var (
+		rosctr    = data[1]
+		paramLen  = int(binary.BigEndian.Uint16(data[6:8]))
+		dataLen   = int(binary.BigEndian.Uint16(data[8:10]))
+		offset    = headerSize
+		name, ok  = rosctrNames[rosctr]
+		params    []byte
+		itemsData []byte
+	)
+
+	r.Protocol = protocolS7
+	r.ROSCTR = int32(rosctr)
+	r.PDUReference = int32(binary.BigEndian.Uint16(data[4:6]))
+
+	if ok {
+		r.ROSCTRName = name
+	} else {
+		r.ROSCTRName = "ROSCTR " + strconv.Itoa(int(rosctr))
+	}
+
+	if rosctr == rosctrAck || rosctr == rosctrAckData {
+		if len(data) < ackHeaderSize {
+			return true
+		}
+
+		r.ErrorClass = int32(data[10])
+		r.ErrorCode = int32(data[11])
+		offset = ackHeaderSize
+	}
+
+	if offset+paramLen > len(data) {
+		return true
+	}
+
+	params = data[offset : offset+paramLen]
+	offset += paramLen
+
+	if offset+dataLen <= len(data) {
+		itemsData = data[offset : offset+dataLen]
+	}
+
+	if rosctr == rosctrUserdata {
+		parseUserdata(params, r)
+
+		return true
+	}
+
+	if len(params) == 0 {
+		return true
+	}
+
+	fn := params[0]
+	r.FunctionCode = int32(fn)
+	r.Function = functionName(fn)
+
+	if rosctr == rosctrJob {
+		_, r.Control = controlFunctions[fn]
+	}
+
+	switch fn {
+	case fnReadVar, fnWriteVar:
+		if rosctr == rosctrJob {
+			r.Items = parseItems(params)
+		} else if rosctr == rosctrAckData {
+			r.ReturnCodes = returnCodes(itemsData, fn == fnReadVar)
+		}
+	case fnRequestDownload, fnDownloadBlock, fnDownloadEnded, fnStartUpload, fnUpload, fnEndUpload:
+		if rosctr == rosctrJob {
+			parseFilename(params, r)
+		}
+	case fnPIService:
+		// function, 7 unknown bytes, parameter block length and parameter block
+		if rosctr == rosctrJob && len(params) >= 10 {
+			offset := 10 + int(binary.BigEndian.Uint16(params[8:10]))
+			r.PIService = readString(params, offset)
+		}
+	case fnPLCStop:
+		// function and 5 unknown bytes
+		r.PIService = readString(params, 6)
+	}
+
+	return true
+}
+
+// parseItems parses the S7ANY address specifications of a read or write request.
+func parseItems(params []byte) []*types.S7Item {
+	if len(params) < 2 {
+		return nil
+	}
+
+	var (
+		count = int(params[1])
+		items []*types.S7Item
+	)
+
+	params = params[2:]
+
+	for i := 0; i < count && len(params) >= 2; i++ {
+		// variable specification and length of the address specification
+		end := 2 + int(params[1])
+		if end > len(params) {
+			break
+		}
+
+		spec := params[:end]
+		params = params[end:]
+
+		// only the S7ANY syntax is decoded
+		if len(spec) < itemSize || spec[2] != 0x10 {
+			continue
+		}
+
+		address := int32(spec[9])<<16 | int32(spec[10])<<8 | int32(spec[11])
+
+		items = append(items, &types.S7Item{
+			Area:          areaName(spec[8]),
+			DBNumber:      int32(binary.BigEndian.Uint16(spec[6:8])),
+			Address:       address >> 3,
+			Bit:           address & 0x07,
+			TransportSize: int32(spec[3]),
+			Length:        int32(binary.BigEndian.Uint16(spec[4:6])),
+		})
+	}
+
+	return items
+}
+
+// returnCodes collects the item return codes of a read or write response.
+func returnCodes(data []byte, read bool) []int32 {
+	var codes []int32
+
+	for len(data) > 0 {
+		codes = append(codes, int32(data[0]))
+
+		if !read {
+			data = data[1:]
+
+			continue
+		}
+
+		// return code, transport size, length and value padded to an even number of bytes
+		if len(data) < 4 {
+			break
+		}
+
+		n := int(binary.BigEndian.Uint16(data[2:4]))
+		if _, ok := bitLengths[data[1]]; ok {
+			n = (n + 7) / 8
+		}
+
+		n += 4 + n%2
+		if n > len(data) {
+			break
+		}
+
+		data = data[n:]
+	}
+
+	return codes
+}
+
+// parseFilename extracts the block type and number from the filename of a block transfer, e.g. _0A00001P.
+func parseFilename(params []byte, r *types.S7Comm) {
+	// function, status, 2 unknown bytes and 4 bytes upload id
+	name := readString(params, 8)
+	if name == "" {
+		return
+	}
+
+	r.Filename = name
+
+	if len(name) < 8 || name[0] != '_' {
+		return
+	}
+
+	if typ, ok := blockTypes[strings.ToUpper(name[1:3])]; ok {
+		r.BlockType = typ
+	}
+
+	if n, err := strconv.Atoi(name[3:8]); err == nil {
+		r.BlockNumber = int32(n)
+	}
+}
+
+// parseUserdata parses the function group and subfunction of a userdata PDU.
+func parseUserdata(params []byte, r *types.S7Comm) {
+	// parameter head, parameter length, method, type and group, subfunction
+	if len(params) < 7 {
+		return
+	}
+
+	var (
+		group    = params[5] & 0x0f
+		name, ok = userdataGroups[group]
+	)
+
+	r.UserdataGroup = int32(group)
+	r.UserdataSubfunction = int32(params[6])
+
+	if ok {
+		r.Function = name
+	} else {
+		r.Function = "Group " + strconv.Itoa(int(group))
+	}
+}
+
+// readString reads a string prefixed with a single length byte.
+func readString(data []byte, offset int) string {
+	if offset >= len(data) {
+		return ""
+	}
+
+	end := offset + 1 + int(data[offset])
+	if end > len(data) {
+		return ""
+	}
+
+	return string(data[offset+1 : end])
+}
+
+// controlActions pairs the control requests with the acknowledgements carrying the same PDU reference.
+func (h *s7Reader) controlActions(records []*types.S7Comm) []*types.ControlAction {
+	var actions []*types.ControlAction
+
+	for _, r := range records {
+		if !r.Control {
+			continue
+		}
+
+		c := &types.ControlAction{
+			Timestamp:  r.Timestamp,
+			Protocol:   r.Protocol,
+			Flow:       h.conversation.Ident,
+			ClientIP:   r.ClientIP,
+			ServerIP:   r.ServerIP,
+			ClientPort: r.ClientPort,
+			ServerPort: r.ServerPort,
+			Operation:  r.Function,
+			Target:     target(r),
+			Data:       r.Payload,
+		}
+
+		if len(r.Items) > 0 {
+			c.Address = r.Items[0].Address
+			c.Quantity = r.Items[0].Length
+		} else {
+			c.Address = r.BlockNumber
+		}
+
+		for _, res := range records {
+			if res.IsClient == r.IsClient || res.Timestamp < r.Timestamp || res.Protocol != r.Protocol || res.PDUReference != r.PDUReference || res.Control {
+				continue
+			}
+
+			c.Answered = true
+			c.Status = res.ErrorClass<<8 | res.ErrorCode
+			c.Success = c.Status == 0
+
+			for _, code := range res.ReturnCodes {
+				if code != returnCodeSuccess {
+					c.Success = false
+					c.Status = code
+
+					break
+				}
+			}
+
+			break
+		}
+
+		actions = append(actions, c)
+	}
+
+	return actions
+}
+
+// target describes the object affected by a control request.
+func target(r *types.S7Comm) string {
+	switch {
+	case len(r.Items) > 0:
+		it := r.Items[0]
+		if it.Area == "DB" || it.Area == "DI" {
+			return it.Area + strconv.Itoa(int(it.DBNumber)) + "." + strconv.Itoa(int(it.Address)) + "." + strconv.Itoa(int(it.Bit))
+		}
+
+		return it.Area + " " + strconv.Itoa(int(it.Address)) + "." + strconv.Itoa(int(it.Bit))
+	case r.BlockType != "":
+		return r.BlockType + " " + strconv.Itoa(int(r.BlockNumber))
+	case r.PIService != "":
+		return r.PIService
+	}
+
+	return r.Filename
+}
+
+func functionName(fn byte) string {
+	if name, ok := functionNames[fn]; ok {
+		return name
+	}
+
+	return "Function 0x" + strconv.FormatInt(int64(fn), 16)
+}
+
+func areaName(area byte) string {
+	if name, ok := areaNames[area]; ok {
+		return name
+	}
+
+	return "Area 0x" + strconv.FormatInt(int64(area), 16)
+}
+
+func writeS7Comm(r *types.S7Comm) {
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		s7Log.Error("failed to write S7Comm record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
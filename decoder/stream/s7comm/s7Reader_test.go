@@ -0,0 +1,159 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package s7comm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func fragment(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+func tpkt(cotp []byte) []byte {
+	out := []byte{tpktVersion, 0x00, 0x00, 0x00}
+	binary.BigEndian.PutUint16(out[2:], uint16(len(cotp)+tpktHeaderSize))
+
+	return append(out, cotp...)
+}
+
+func dt(eot bool, payload []byte) []byte {
+	nr := byte(0)
+	if eot {
+		nr = cotpEndOfTransmission
+	}
+
+	return tpkt(append([]byte{0x02, cotpData, nr}, payload...))
+}
+
+func s7(rosctr byte, ref uint16, params, data []byte) []byte {
+	out := []byte{protocolIDS7, rosctr, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(out[4:], ref)
+	binary.BigEndian.PutUint16(out[6:], uint16(len(params)))
+	binary.BigEndian.PutUint16(out[8:], uint16(len(data)))
+
+	if rosctr == rosctrAck || rosctr == rosctrAckData {
+		out = append(out, 0, 0)
+	}
+
+	return append(append(out, params...), data...)
+}
+
+func TestRecords(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.1",
+			ServerIP:   "10.0.0.2",
+			ClientPort: 50000,
+			ServerPort: 102,
+		}
+
+		cr    = tpkt([]byte{0x11, cotpConnectionRequest, 0x00, 0x00, 0x00, 0x01, 0x00, 0xc1, 0x02, 0x01, 0x00, 0xc2, 0x02, 0x01, 0x02, 0xc0, 0x01, 0x0a})
+		cc    = tpkt([]byte{0x11, 0xd0, 0x00, 0x01, 0x00, 0x01, 0x00, 0xc1, 0x02, 0x01, 0x00, 0xc2, 0x02, 0x01, 0x02, 0xc0, 0x01, 0x0a})
+		setup = []byte{fnSetupCommunicate, 0x00, 0x00, 0x01, 0x00, 0x01, 0x01, 0xe0}
+
+		// write a word to DB1.DBB10
+		write     = s7(rosctrJob, 2, []byte{fnWriteVar, 0x01, 0x12, 0x0a, 0x10, 0x02, 0x00, 0x02, 0x00, 0x01, 0x84, 0x00, 0x00, 0x50}, []byte{0x00, 0x04, 0x00, 0x10, 0x12, 0x34})
+		writeAck  = s7(rosctrAckData, 2, []byte{fnWriteVar, 0x01}, []byte{0x05})
+		stop      = s7(rosctrJob, 3, append([]byte{fnPLCStop, 0, 0, 0, 0, 0, 0x09}, "P_PROGRAM"...), nil)
+		stopAck   = s7(rosctrAckData, 3, []byte{fnPLCStop}, nil)
+		download  = s7(rosctrJob, 4, append([]byte{fnRequestDownload, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09}, "_0A00001P"...), nil)
+		plusSetup = append(append([]byte{protocolIDS7Plus, plusVersion3, 0x00, 0x2a, plusDigestSize}, bytes.Repeat([]byte{0xaa}, plusDigestSize)...),
+			plusRequest, 0x00, 0x00, 0x04, 0xf2, 0x00, 0x00, 0x00, 0x07)
+
+		client = bytes.Join([][]byte{cr, dt(true, s7(rosctrJob, 1, setup, nil)), dt(false, write[:12]), dt(true, write[12:]), dt(true, stop), dt(true, download), dt(true, plusSetup)}, nil)
+		server = bytes.Join([][]byte{cc, dt(true, s7(rosctrAckData, 1, setup, nil)), dt(true, writeAck), dt(true, stopAck)}, nil)
+	)
+
+	if !Decoder.CanDecode(client, server) {
+		t.Fatal("expected S7comm stream to be detected")
+	}
+
+	// MMS over COTP
+	if Decoder.CanDecode(append(cr, dt(true, []byte{0x0d, 0xb3, 0x05, 0x06})...), nil) {
+		t.Fatal("unexpected detection of MMS as S7comm")
+	}
+
+	conv.Data = append(conv.Data,
+		fragment(client[:30], reassembly.TCPDirClientToServer, start),
+		fragment(client[30:], reassembly.TCPDirClientToServer, start.Add(time.Millisecond)),
+		fragment(server, reassembly.TCPDirServerToClient, start.Add(3*time.Millisecond)),
+	)
+
+	h := (&s7Reader{}).New(conv).(*s7Reader)
+
+	records := h.records(false)
+	if len(records) != 8 {
+		t.Fatalf("expected 8 records, got %d", len(records))
+	}
+
+	if r := records[0]; r.Function != "Setup Communication" || r.ROSCTRName != "Job" || r.Control {
+		t.Fatalf("unexpected setup record: %+v", r)
+	}
+
+	w := records[1]
+	if w.Function != "Write Var" || !w.Control || w.PDUReference != 2 || len(w.Items) != 1 {
+		t.Fatalf("unexpected write record: %+v", w)
+	}
+
+	if it := w.Items[0]; it.Area != "DB" || it.DBNumber != 1 || it.Address != 10 || it.Bit != 0 || it.Length != 2 {
+		t.Fatalf("unexpected write item: %+v", it)
+	}
+
+	if r := records[2]; r.Function != "PLC Stop" || r.PIService != "P_PROGRAM" || !r.Control {
+		t.Fatalf("unexpected stop record: %+v", r)
+	}
+
+	if r := records[3]; r.Filename != "_0A00001P" || r.BlockType != "DB" || r.BlockNumber != 1 {
+		t.Fatalf("unexpected download record: %+v", r)
+	}
+
+	if r := records[4]; r.Protocol != protocolS7Plus || r.Function != "Set Variable" || r.ROSCTRName != "Request" || r.PDUReference != 7 || !r.Control {
+		t.Fatalf("unexpected S7comm-plus record: %+v", r)
+	}
+
+	if r := records[6]; r.ROSCTRName != "Ack_Data" || len(r.ReturnCodes) != 1 || r.ReturnCodes[0] != 5 {
+		t.Fatalf("unexpected write ack record: %+v", r)
+	}
+
+	actions := h.controlActions(records)
+	if len(actions) != 4 {
+		t.Fatalf("expected 4 control actions, got %d", len(actions))
+	}
+
+	if a := actions[0]; a.Target != "DB1.10.0" || !a.Answered || a.Success || a.Status != 5 {
+		t.Fatalf("unexpected write action: %+v", a)
+	}
+
+	if a := actions[1]; a.Target != "P_PROGRAM" || !a.Answered || !a.Success {
+		t.Fatalf("unexpected stop action: %+v", a)
+	}
+
+	if a := actions[2]; a.Target != "DB 1" || a.Answered {
+		t.Fatalf("unexpected download action: %+v", a)
+	}
+}
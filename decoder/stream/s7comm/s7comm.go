@@ -0,0 +1,130 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package s7comm
+
+import (
+	"encoding/binary"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var s7Log = zap.NewNop()
+
+const (
+	tpktVersion    = 0x03
+	tpktHeaderSize = 4
+
+	cotpConnectionRequest = 0xe0
+	cotpData              = 0xf0
+	cotpEndOfTransmission = 0x80
+
+	protocolIDS7     = 0x32
+	protocolIDS7Plus = 0x72
+
+	protocolS7     = "S7comm"
+	protocolS7Plus = "S7comm-plus"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_S7Comm,
+	Name:        "S7Comm",
+	Description: "S7 communication is the proprietary protocol used by Siemens S7 programmable logic controllers",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		s7Log, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"s7comm",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isS7(client)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return s7Log.Sync()
+	},
+	Factory: &s7Reader{},
+	Typ:     core.TCP,
+}
+
+// tpdu is a COTP data unit carried in a TPKT frame.
+type tpdu struct {
+	typ    byte
+	eot    bool
+	data   []byte
+	offset int
+}
+
+// tpdus parses the TPKT frames in data and returns the contained COTP data units.
+func tpdus(data []byte) []tpdu {
+	var (
+		out    []tpdu
+		offset int
+	)
+
+	for len(data)-offset >= tpktHeaderSize+2 {
+		if data[offset] != tpktVersion || data[offset+1] != 0 {
+			break
+		}
+
+		var (
+			end = offset + int(binary.BigEndian.Uint16(data[offset+2:offset+4]))
+			li  = int(data[offset+tpktHeaderSize])
+		)
+
+		if end > len(data) || end < offset+tpktHeaderSize+1+li || li < 1 {
+			break
+		}
+
+		t := tpdu{
+			typ:    data[offset+tpktHeaderSize+1] & 0xf0,
+			data:   data[offset+tpktHeaderSize+1+li : end],
+			offset: offset,
+		}
+
+		if t.typ == cotpData && li >= 2 {
+			t.eot = data[offset+tpktHeaderSize+2]&cotpEndOfTransmission != 0
+		}
+
+		out = append(out, t)
+		offset = end
+	}
+
+	return out
+}
+
+// isS7 checks whether the client sends S7comm or S7comm-plus over COTP.
+// Other protocols using TPKT and COTP on port 102, such as MMS, are not matched.
+func isS7(client []byte) bool {
+	for _, t := range tpdus(client) {
+		switch t.typ {
+		case cotpConnectionRequest:
+			continue
+		case cotpData:
+			return len(t.data) > 0 && (t.data[0] == protocolIDS7 || t.data[0] == protocolIDS7Plus)
+		}
+
+		return false
+	}
+
+	return false
+}
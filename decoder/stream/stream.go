@@ -5,10 +5,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dreadl0ck/netcap/decoder/stream/dnp3"
+	"github.com/dreadl0ck/netcap/decoder/stream/enip"
 	"github.com/dreadl0ck/netcap/decoder/stream/http"
+	"github.com/dreadl0ck/netcap/decoder/stream/iec104"
+	"github.com/dreadl0ck/netcap/decoder/stream/irc"
+	"github.com/dreadl0ck/netcap/decoder/stream/modbus"
+	"github.com/dreadl0ck/netcap/decoder/stream/opcua"
 	"github.com/dreadl0ck/netcap/decoder/stream/pop3"
+	"github.com/dreadl0ck/netcap/decoder/stream/s7comm"
 	"github.com/dreadl0ck/netcap/decoder/stream/smtp"
 	"github.com/dreadl0ck/netcap/decoder/stream/ssh"
+	"github.com/dreadl0ck/netcap/decoder/stream/syslog"
+	"github.com/dreadl0ck/netcap/decoder/stream/tacacs"
+	"github.com/dreadl0ck/netcap/decoder/stream/telnet"
 
 	"github.com/mgutz/ansi"
 	"github.com/pkg/errors"
@@ -27,10 +37,21 @@ var errInvalidStreamDecoder = errors.New("invalid stream decoder")
 // DefaultStreamDecoders contains stream decoders mapped to their protocols default port
 // int32 is used to avoid casting when looking up values
 var DefaultStreamDecoders = map[int32]core.StreamDecoderAPI{
-	80:  http.Decoder,
-	110: pop3.Decoder,
-	22:  ssh.Decoder,
-	25:  smtp.Decoder,
+	80:    http.Decoder,
+	110:   pop3.Decoder,
+	22:    ssh.Decoder,
+	25:    smtp.Decoder,
+	23:    telnet.Decoder,
+	6667:  irc.Decoder,
+	502:   modbus.Decoder,
+	44818: enip.Decoder,
+	20000: dnp3.Decoder,
+	2404:  iec104.Decoder,
+	102:   s7comm.Decoder,
+	4840:  opcua.Decoder,
+	49:    tacacs.Decoder,
+	514:   syslog.Decoder,
+	601:   syslog.Decoder,
 } // contains all available stream decoders
 
 // package level init.
@@ -42,8 +63,16 @@ func init() {
 }
 
 // ApplyActionToStreamDecoders can be used to run custom code for all stream decoders.
+// Decoders that are registered for multiple ports are visited once.
 func ApplyActionToStreamDecoders(action func(api core.StreamDecoderAPI)) {
+	seen := make(map[core.StreamDecoderAPI]struct{})
+
 	for _, d := range DefaultStreamDecoders {
+		if _, ok := seen[d]; ok {
+			continue
+		}
+
+		seen[d] = struct{}{}
 		action(d)
 	}
 }
@@ -93,20 +122,26 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 				return nil, errors.Wrap(errInvalidStreamDecoder, name)
 			}
 
-			// remove named decoder from defaultPacketDecoders
+			// remove named decoder from defaultPacketDecoders, for all ports it is registered for
 			for port, dec := range DefaultStreamDecoders {
 				if name == dec.GetName() {
 					// remove decoder
 					delete(DefaultStreamDecoders, port)
-
-					break
 				}
 			}
 		}
 	}
 
-	// initialize decoders
+	// initialize decoders, a decoder that is registered for multiple ports must only be initialized once
+	initialized := make(map[core.StreamDecoderAPI]struct{})
+
 	for _, d := range DefaultStreamDecoders {
+		if _, ok := initialized[d]; ok {
+			continue
+		}
+
+		initialized[d] = struct{}{}
+
 		w := netio.NewAuditRecordWriter(&netio.WriterConfig{
 			CSV:     c.CSV,
 			Proto:   c.Proto,
@@ -0,0 +1,199 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package syslog
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// embedded event formats.
+const (
+	formatCEF  = "CEF"
+	formatLEEF = "LEEF"
+
+	prefixCEF  = "CEF:"
+	prefixLEEF = "LEEF:"
+)
+
+// the number of header fields of a CEF event, the last one contains the extension.
+const cefFields = 8
+
+// attributes of LEEF events are separated by tabs, unless a different delimiter is specified in LEEF 2.0.
+const leefDelimiter = "\t"
+
+var cefUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\|`, `|`,
+	`\=`, `=`,
+	`\n`, "\n",
+	`\r`, "\r",
+)
+
+// embedded extracts a CEF or LEEF event from the message.
+func embedded(s *types.Syslog) {
+	if i := strings.Index(s.Message, prefixCEF); i >= 0 {
+		parseCEF(s, s.Message[i:])
+
+		return
+	}
+
+	if i := strings.Index(s.Message, prefixLEEF); i >= 0 {
+		parseLEEF(s, s.Message[i:])
+	}
+}
+
+// parseCEF parses an ArcSight Common Event Format event:
+// CEF:Version|Device Vendor|Device Product|Device Version|Device Event Class ID|Name|Severity|Extension.
+func parseCEF(s *types.Syslog, event string) {
+	fields := splitEscaped(event, '|', cefFields)
+	if len(fields) < cefFields-1 {
+		return
+	}
+
+	s.EmbeddedFormat = formatCEF
+	s.Vendor = cefUnescaper.Replace(fields[1])
+	s.Product = cefUnescaper.Replace(fields[2])
+	s.ProductVersion = cefUnescaper.Replace(fields[3])
+	s.EventID = cefUnescaper.Replace(fields[4])
+	s.EventName = cefUnescaper.Replace(fields[5])
+	s.EventSeverity = cefUnescaper.Replace(fields[6])
+
+	if len(fields) == cefFields {
+		s.Extensions = cefExtension(fields[7])
+	}
+}
+
+// splitEscaped splits the value at separators that are not escaped by a backslash into at most n fields.
+func splitEscaped(value string, sep byte, n int) []string {
+	var (
+		fields []string
+		start  int
+	)
+
+	for i := 0; i < len(value) && len(fields) < n-1; i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			fields = append(fields, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(fields, value[start:])
+}
+
+// cefExtension parses the key=value pairs of a CEF extension.
+// Values may contain spaces, so a value ends at the last space before the next unescaped equal sign.
+func cefExtension(ext string) []string {
+	var (
+		pairs    []string
+		keyStart = -1
+		eq       = -1
+	)
+
+	for i := 0; i < len(ext); i++ {
+		switch ext[i] {
+		case '\\':
+			i++
+		case '=':
+			k := strings.LastIndexByte(ext[:i], ' ') + 1
+			if eq >= 0 {
+				if k <= eq {
+					// unescaped equal sign inside of the value
+					continue
+				}
+
+				pairs = append(pairs, ext[keyStart:eq]+"="+cefUnescaper.Replace(strings.TrimSpace(ext[eq+1:k])))
+			}
+
+			keyStart, eq = k, i
+		}
+	}
+
+	if eq >= 0 {
+		pairs = append(pairs, ext[keyStart:eq]+"="+cefUnescaper.Replace(strings.TrimSpace(ext[eq+1:])))
+	}
+
+	return pairs
+}
+
+// parseLEEF parses an IBM QRadar Log Event Extended Format event:
+// LEEF:Version|Vendor|Product|Version|EventID|Attributes, LEEF 2.0 may add the delimiter before the attributes.
+func parseLEEF(s *types.Syslog, event string) {
+	fields := strings.SplitN(event, "|", 6)
+	if len(fields) < 5 {
+		return
+	}
+
+	s.EmbeddedFormat = formatLEEF
+	s.Vendor = fields[1]
+	s.Product = fields[2]
+	s.ProductVersion = fields[3]
+	s.EventID = fields[4]
+
+	if len(fields) < 6 {
+		return
+	}
+
+	var (
+		attributes = fields[5]
+		delimiter  = leefDelimiter
+	)
+
+	if strings.HasPrefix(strings.TrimPrefix(fields[0], prefixLEEF), "2") {
+		if i := strings.IndexByte(attributes, '|'); i >= 0 && !strings.Contains(attributes[:i], "=") {
+			if d := leefDelimiterChar(attributes[:i]); d != "" {
+				delimiter = d
+			}
+
+			attributes = attributes[i+1:]
+		}
+	}
+
+	for _, a := range strings.Split(attributes, delimiter) {
+		kv := strings.SplitN(strings.TrimSpace(a), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+
+		key, value := kv[0], kv[1]
+
+		if key == "sev" {
+			s.EventSeverity = value
+		}
+
+		s.Extensions = append(s.Extensions, key+"="+value)
+	}
+}
+
+// leefDelimiterChar returns the delimiter of a LEEF 2.0 event,
+// which is either a single character or its hex value with a 0x or x prefix.
+func leefDelimiterChar(value string) string {
+	if len(value) == 1 {
+		return value
+	}
+
+	value = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(value), "0"), "x")
+
+	d, err := hex.DecodeString(value)
+	if err != nil || len(d) != 1 {
+		return ""
+	}
+
+	return string(d)
+}
@@ -0,0 +1,301 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// message formats.
+const (
+	formatRFC3164 = "RFC3164"
+	formatRFC5424 = "RFC5424"
+)
+
+// nil value of the RFC 5424 header fields and the structured data.
+const nilValue = "-"
+
+// the byte order mark that may precede the message of a RFC 5424 message.
+var bom = []byte{0xef, 0xbb, 0xbf}
+
+// parse parses a single syslog message, nil is returned if the message does not start with a valid priority.
+func parse(data []byte) *types.Syslog {
+	pri, rest, ok := priority(data)
+	if !ok {
+		return nil
+	}
+
+	s := &types.Syslog{
+		Priority:     int32(pri),
+		Facility:     int32(pri / 8),
+		FacilityName: facilities[pri/8],
+		Severity:     int32(pri % 8),
+		SeverityName: severities[pri%8],
+	}
+
+	if !parseRFC5424(s, rest) {
+		parseRFC3164(s, rest)
+	}
+
+	embedded(s)
+
+	return s
+}
+
+// parseRFC5424 parses the header, structured data and message of a RFC 5424 message.
+// It returns false if the data after the priority does not start with a version and timestamp.
+func parseRFC5424(s *types.Syslog, data []byte) bool {
+	version, rest := field(data)
+	if len(version) == 0 || len(version) > 2 || strings.Trim(version, "0123456789") != "" || version == "0" {
+		return false
+	}
+
+	ts, rest := field(rest)
+	if ts != nilValue && !isRFC3339(ts) {
+		return false
+	}
+
+	s.Format = formatRFC5424
+	s.Version = int32(atoi(version))
+	s.MessageTimestamp = nilToEmpty(ts)
+
+	var hostname, appName, procID, msgID string
+
+	hostname, rest = field(rest)
+	appName, rest = field(rest)
+	procID, rest = field(rest)
+	msgID, rest = field(rest)
+
+	s.Hostname = nilToEmpty(hostname)
+	s.AppName = nilToEmpty(appName)
+	s.ProcID = nilToEmpty(procID)
+	s.MsgID = nilToEmpty(msgID)
+
+	if bytes.HasPrefix(rest, []byte(nilValue)) {
+		rest = rest[len(nilValue):]
+	} else {
+		s.StructuredData, rest = structuredData(rest)
+	}
+
+	rest = bytes.TrimPrefix(rest, []byte{' '})
+	s.Message = string(bytes.TrimPrefix(rest, bom))
+
+	return true
+}
+
+// structuredData parses the structured data elements of a RFC 5424 message
+// and returns them as id:param=value along with the rest of the message.
+func structuredData(data []byte) (elements []string, rest []byte) {
+	for len(data) > 0 && data[0] == '[' {
+		end := bytes.IndexAny(data, " ]")
+		if end < 0 {
+			return elements, data
+		}
+
+		var (
+			id     = string(data[1:end])
+			params int
+		)
+
+		data = data[end:]
+
+		for {
+			data = bytes.TrimLeft(data, " ")
+			if len(data) == 0 || data[0] == ']' {
+				break
+			}
+
+			eq := bytes.IndexByte(data, '=')
+			if eq < 0 || eq+1 >= len(data) || data[eq+1] != '"' {
+				return elements, data
+			}
+
+			name := string(data[:eq])
+
+			value, n, ok := quoted(data[eq+1:])
+			if !ok {
+				return elements, data
+			}
+
+			elements = append(elements, id+":"+name+"="+value)
+			params++
+
+			data = data[eq+1+n:]
+		}
+
+		if len(data) == 0 {
+			return elements, data
+		}
+
+		if params == 0 {
+			elements = append(elements, id)
+		}
+
+		// skip the closing bracket
+		data = data[1:]
+	}
+
+	return elements, data
+}
+
+// quoted returns the unescaped value of a quoted structured data parameter,
+// and the number of bytes it occupied including the quotes.
+func quoted(data []byte) (string, int, bool) {
+	var b strings.Builder
+
+	for i := 1; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '\\':
+			// only the quote, backslash and closing bracket are escaped
+			if i+1 < len(data) && (data[i+1] == '"' || data[i+1] == '\\' || data[i+1] == ']') {
+				i++
+				c = data[i]
+			}
+
+			b.WriteByte(c)
+		case '"':
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, false
+}
+
+// parseRFC3164 parses the BSD syslog format, which is only loosely specified:
+// an optional timestamp and hostname are followed by a tag with an optional process id.
+func parseRFC3164(s *types.Syslog, data []byte) {
+	s.Format = formatRFC3164
+
+	timestamp := false
+
+	if len(data) >= len(time.Stamp) && isBSDTimestamp(data[:len(time.Stamp)]) {
+		s.MessageTimestamp = string(data[:len(time.Stamp)])
+		data = bytes.TrimPrefix(data[len(time.Stamp):], []byte{' '})
+		timestamp = true
+	} else if ts, rest := field(data); isRFC3339(ts) {
+		s.MessageTimestamp = ts
+		data = rest
+		timestamp = true
+	}
+
+	// the hostname is only present after a timestamp
+	if timestamp && !isEmbedded(data) {
+		if hostname, rest := field(data); hostname != "" && !strings.HasSuffix(hostname, ":") && !strings.Contains(hostname, "[") {
+			s.Hostname = hostname
+			data = rest
+		}
+	}
+
+	if !isEmbedded(data) {
+		data = tag(s, data)
+	}
+
+	s.Message = string(data)
+}
+
+// tag parses the tag of a BSD syslog message, e.g. sshd[1234]:
+// and returns the rest of the message. If there is no tag, the data is returned unchanged.
+func tag(s *types.Syslog, data []byte) []byte {
+	// the tag is at most 32 alphanumeric characters, though some implementations allow more and additional characters
+	const maxTagLength = 48
+
+	i := 0
+	for i < len(data) && i < maxTagLength && isTagChar(data[i]) {
+		i++
+	}
+
+	if i == 0 || i == len(data) {
+		return data
+	}
+
+	var procID string
+
+	rest := data[i:]
+
+	if rest[0] == '[' {
+		end := bytes.IndexByte(rest, ']')
+		if end < 0 {
+			return data
+		}
+
+		procID = string(rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	if len(rest) == 0 || rest[0] != ':' {
+		return data
+	}
+
+	s.AppName = string(data[:i])
+	s.ProcID = procID
+
+	return bytes.TrimPrefix(rest[1:], []byte{' '})
+}
+
+func isTagChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '/'
+}
+
+// isBSDTimestamp checks whether the data is a timestamp in the format Mmm dd hh:mm:ss.
+func isBSDTimestamp(data []byte) bool {
+	_, err := time.Parse(time.Stamp, string(data))
+
+	return err == nil
+}
+
+// isRFC3339 checks whether the value looks like a RFC 3339 timestamp.
+// Devices send a variety of precisions and offsets, so the value is not parsed.
+func isRFC3339(value string) bool {
+	return len(value) >= len("2006-01-02T15") && value[4] == '-' && value[7] == '-' && value[10] == 'T' &&
+		strings.Trim(value[:4], "0123456789") == ""
+}
+
+// isEmbedded checks whether the data starts with a CEF or LEEF event.
+func isEmbedded(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(prefixCEF)) || bytes.HasPrefix(data, []byte(prefixLEEF))
+}
+
+// field returns the data up to the next space and the rest after it.
+func field(data []byte) (string, []byte) {
+	i := bytes.IndexByte(data, ' ')
+	if i < 0 {
+		return string(data), nil
+	}
+
+	return string(data[:i]), data[i+1:]
+}
+
+func nilToEmpty(value string) string {
+	if value == nilValue {
+		return ""
+	}
+
+	return value
+}
+
+// atoi converts a string of digits, that has been validated by the caller.
+func atoi(value string) int {
+	n := 0
+	for _, c := range value {
+		n = n*10 + int(c-'0')
+	}
+
+	return n
+}
@@ -0,0 +1,196 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package syslog
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	s := parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high \"x\" \]"][empty@1] ` + "\xef\xbb\xbf" + `An application event log entry...`))
+	if s == nil {
+		t.Fatal("failed to parse message")
+	}
+
+	expected := &types.Syslog{
+		Format:           formatRFC5424,
+		Priority:         165,
+		Facility:         20,
+		FacilityName:     "local4",
+		Severity:         5,
+		SeverityName:     "notice",
+		Version:          1,
+		MessageTimestamp: "2003-10-11T22:14:15.003Z",
+		Hostname:         "mymachine.example.com",
+		AppName:          "evntslog",
+		MsgID:            "ID47",
+		StructuredData: []string{
+			"exampleSDID@32473:iut=3",
+			"exampleSDID@32473:eventSource=Application",
+			"exampleSDID@32473:eventID=1011",
+			`examplePriority@32473:class=high "x" ]`,
+			"empty@1",
+		},
+		Message: "An application event log entry...",
+	}
+
+	if !reflect.DeepEqual(s, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, s)
+	}
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	s := parse([]byte(`<34>1 - - - - - -`))
+	if s == nil || s.Format != formatRFC5424 || s.Hostname != "" || s.StructuredData != nil || s.Message != "" {
+		t.Fatalf("unexpected result %+v", s)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	tests := []struct {
+		msg      string
+		expected *types.Syslog
+	}{
+		{
+			msg: "<38>Oct  9 22:33:20 gateway sshd[4123]: Failed password for root from 10.0.0.9 port 53211 ssh2",
+			expected: &types.Syslog{
+				Format:           formatRFC3164,
+				Priority:         38,
+				Facility:         4,
+				FacilityName:     "auth",
+				Severity:         6,
+				SeverityName:     "info",
+				MessageTimestamp: "Oct  9 22:33:20",
+				Hostname:         "gateway",
+				AppName:          "sshd",
+				ProcID:           "4123",
+				Message:          "Failed password for root from 10.0.0.9 port 53211 ssh2",
+			},
+		},
+		{
+			// without timestamp and hostname
+			msg: "<13>kernel: link down",
+			expected: &types.Syslog{
+				Format:       formatRFC3164,
+				Priority:     13,
+				Facility:     1,
+				FacilityName: "user",
+				Severity:     5,
+				SeverityName: "notice",
+				AppName:      "kernel",
+				Message:      "link down",
+			},
+		},
+		{
+			// the tag is missing, the timestamp is not in the BSD format
+			msg: "<0>2020-11-02T10:00:00+01:00 router1 interface eth0 changed state to up",
+			expected: &types.Syslog{
+				Format:           formatRFC3164,
+				FacilityName:     "kern",
+				SeverityName:     "emerg",
+				MessageTimestamp: "2020-11-02T10:00:00+01:00",
+				Hostname:         "router1",
+				Message:          "interface eth0 changed state to up",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s := parse([]byte(test.msg))
+		if !reflect.DeepEqual(s, test.expected) {
+			t.Errorf("%q: expected %+v, got %+v", test.msg, test.expected, s)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, msg := range []string{"", "<>", "<192>overflow", "<1a>x", "GET / HTTP/1.1", "<1234>x"} {
+		if s := parse([]byte(msg)); s != nil {
+			t.Errorf("%q: expected nil, got %+v", msg, s)
+		}
+	}
+}
+
+func TestParseCEF(t *testing.T) {
+	s := parse([]byte(`<134>Sep 19 08:26:10 host CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat\=worm. No action needed. cs1Label=path\\to`))
+	if s == nil {
+		t.Fatal("failed to parse message")
+	}
+
+	if s.Hostname != "host" || s.AppName != "" || s.EmbeddedFormat != formatCEF {
+		t.Fatalf("unexpected header %+v", s)
+	}
+
+	if s.Vendor != "Security" || s.Product != "threatmanager" || s.ProductVersion != "1.0" ||
+		s.EventID != "100" || s.EventName != "worm successfully stopped" || s.EventSeverity != "10" {
+		t.Fatalf("unexpected CEF header %+v", s)
+	}
+
+	expected := []string{
+		"src=10.0.0.1",
+		"dst=2.1.2.2",
+		"spt=1232",
+		"msg=Detected a threat=worm. No action needed.",
+		`cs1Label=path\to`,
+	}
+
+	if !reflect.DeepEqual(s.Extensions, expected) {
+		t.Fatalf("expected %q, got %q", expected, s.Extensions)
+	}
+}
+
+func TestParseCEFEscapedHeader(t *testing.T) {
+	s := parse([]byte(`<10>CEF:0|ACME\|Corp|Firewall|2.0|deny|Blocked|5|`))
+	if s == nil || s.Vendor != "ACME|Corp" || s.EventSeverity != "5" || s.Extensions != nil {
+		t.Fatalf("unexpected result %+v", s)
+	}
+}
+
+func TestParseLEEF(t *testing.T) {
+	tests := []struct {
+		msg        string
+		version    string
+		extensions []string
+	}{
+		{
+			msg:        "<13>Jan 18 11:07:53 host LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5",
+			version:    "4.0 SP1",
+			extensions: []string{"src=192.0.2.0", "dst=172.50.123.1", "sev=5"},
+		},
+		{
+			msg:        "<13>1 2020-01-18T11:07:53Z host app - - - LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5",
+			version:    "1.0",
+			extensions: []string{"src=10.0.1.8", "dst=10.0.0.5", "sev=5"},
+		},
+		{
+			msg:        "<13>LEEF:2.0|Vendor|Product|2.1|login|x7C|usrName=admin|sev=3",
+			version:    "2.1",
+			extensions: []string{"usrName=admin", "sev=3"},
+		},
+	}
+
+	for _, test := range tests {
+		s := parse([]byte(test.msg))
+		if s == nil || s.EmbeddedFormat != formatLEEF || s.ProductVersion != test.version || s.EventSeverity != "5" && s.EventSeverity != "3" {
+			t.Fatalf("%q: unexpected result %+v", test.msg, s)
+		}
+
+		if !reflect.DeepEqual(s.Extensions, test.extensions) {
+			t.Errorf("%q: expected %q, got %q", test.msg, test.extensions, s.Extensions)
+		}
+	}
+}
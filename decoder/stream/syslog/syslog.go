@@ -0,0 +1,148 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package syslog
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var syslogLog = zap.NewNop()
+
+// highest valid priority value: facility local7 with severity debug.
+const maxPriority = 191
+
+// Decoder for protocol analysis and writing audit records to disk.
+// It is registered for syslog via UDP on port 514 and TCP on port 601.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_Syslog,
+	Name:        "Syslog",
+	Description: "Syslog transports log messages of network devices and servers, RFC 3164 and RFC 5424 messages are parsed and embedded CEF and LEEF events are extracted",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		syslogLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"syslog",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isMessage(client)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return syslogLog.Sync()
+	},
+	Factory: &syslogReader{},
+	Typ:     core.All,
+}
+
+var facilities = []string{
+	"kern",
+	"user",
+	"mail",
+	"daemon",
+	"auth",
+	"syslog",
+	"lpr",
+	"news",
+	"uucp",
+	"cron",
+	"authpriv",
+	"ftp",
+	"ntp",
+	"security",
+	"console",
+	"solaris-cron",
+	"local0",
+	"local1",
+	"local2",
+	"local3",
+	"local4",
+	"local5",
+	"local6",
+	"local7",
+}
+
+var severities = []string{
+	"emerg",
+	"alert",
+	"crit",
+	"err",
+	"warning",
+	"notice",
+	"info",
+	"debug",
+}
+
+// isMessage checks whether the data starts with a syslog message,
+// optionally preceded by the message length of the octet counting framing.
+func isMessage(data []byte) bool {
+	if n, rest, ok := octetCount(data); ok && n > 0 {
+		data = rest
+	}
+
+	_, _, ok := priority(data)
+
+	return ok
+}
+
+// priority parses the PRI part at the start of a message and returns the rest of the message.
+func priority(data []byte) (int, []byte, bool) {
+	if len(data) < 3 || data[0] != '<' {
+		return 0, nil, false
+	}
+
+	pri := 0
+
+	for i := 1; i < len(data) && i <= 4; i++ {
+		switch c := data[i]; {
+		case c == '>' && i > 1:
+			if pri > maxPriority {
+				return 0, nil, false
+			}
+
+			return pri, data[i+1:], true
+		case c >= '0' && c <= '9':
+			pri = pri*10 + int(c-'0')
+		default:
+			return 0, nil, false
+		}
+	}
+
+	return 0, nil, false
+}
+
+// octetCount parses the message length of the octet counting TCP framing from RFC 6587.
+func octetCount(data []byte) (int, []byte, bool) {
+	n := 0
+
+	for i := 0; i < len(data) && i < 10; i++ {
+		switch c := data[i]; {
+		case c == ' ' && i > 0:
+			return n, data[i+1:], true
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+		default:
+			return 0, nil, false
+		}
+	}
+
+	return 0, nil, false
+}
@@ -0,0 +1,175 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"sync/atomic"
+
+	"github.com/dreadl0ck/gopacket/layers"
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	transportUDP = "UDP"
+	transportTCP = "TCP"
+)
+
+type syslogReader struct {
+	conversation *core.ConversationInfo
+}
+
+// frame is a single message of a TCP stream, along with its offset in the stream.
+type frame struct {
+	offset int
+	data   []byte
+}
+
+// New will instantiate a new syslog reader.
+func (h *syslogReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &syslogReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the messages sent by the client.
+func (h *syslogReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	for _, s := range h.messages() {
+		writeSyslog(s)
+	}
+}
+
+// messages parses the messages sent by the client. Each UDP datagram carries a single message,
+// messages on a TCP connection are framed by octet counting or a trailing newline, see RFC 6587.
+func (h *syslogReader) messages() []*types.Syslog {
+	var out []*types.Syslog
+
+	if isUDP(h.conversation.Data) {
+		for _, d := range h.conversation.Data {
+			if d.Direction() != reassembly.TCPDirClientToServer {
+				continue
+			}
+
+			s := parse(trim(d.Raw()))
+			if s == nil {
+				syslogLog.Debug("invalid syslog message", zap.String("ident", h.conversation.Ident))
+
+				continue
+			}
+
+			s.Timestamp = core.Timestamp(d).UnixNano()
+			out = append(out, h.context(s, transportUDP))
+		}
+
+		return out
+	}
+
+	client, _ := core.SplitDirections(h.conversation.Data)
+
+	for _, f := range frames(client.Data) {
+		s := parse(f.data)
+		if s == nil {
+			syslogLog.Debug("invalid syslog message", zap.String("ident", h.conversation.Ident), zap.Int("offset", f.offset))
+
+			continue
+		}
+
+		s.Timestamp = client.TimeAt(f.offset).UnixNano()
+		out = append(out, h.context(s, transportTCP))
+	}
+
+	return out
+}
+
+// context sets the addresses of the conversation and the transport protocol.
+func (h *syslogReader) context(s *types.Syslog, transport string) *types.Syslog {
+	s.SrcIP = h.conversation.ClientIP
+	s.DstIP = h.conversation.ServerIP
+	s.SrcPort = h.conversation.ClientPort
+	s.DstPort = h.conversation.ServerPort
+	s.Transport = transport
+
+	return s
+}
+
+// frames splits the data of a TCP stream into messages.
+// Messages are either prefixed with their length or terminated by a newline or null byte.
+func frames(data []byte) []frame {
+	var out []frame
+
+	for offset := 0; offset < len(data); {
+		rest := data[offset:]
+
+		if n, msg, ok := octetCount(rest); ok && n > 0 {
+			if n > len(msg) {
+				// incomplete message at the end of the stream
+				break
+			}
+
+			out = append(out, frame{offset: offset, data: trim(msg[:n])})
+			offset += len(rest) - len(msg) + n
+
+			continue
+		}
+
+		end := bytes.IndexAny(rest, "\n\x00")
+		if end < 0 {
+			// the last message may be sent without a trailer
+			end = len(rest)
+		}
+
+		if msg := trim(rest[:end]); len(msg) > 0 {
+			out = append(out, frame{offset: offset, data: msg})
+		}
+
+		offset += end + 1
+	}
+
+	return out
+}
+
+// isUDP checks whether the conversation was transported via UDP.
+func isUDP(data core.DataFragments) bool {
+	return len(data) > 0 && data[0].Transport().EndpointType() == layers.EndpointUDPPort
+}
+
+// trim removes the trailers that are appended to messages by some senders.
+func trim(data []byte) []byte {
+	return bytes.Trim(data, "\r\n\x00")
+}
+
+func writeSyslog(s *types.Syslog) {
+	if decoderconfig.Instance.ExportMetrics {
+		s.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(s)
+	if err != nil {
+		syslogLog.Error("failed to write Syslog record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func testReader(fragments ...*core.StreamData) *syslogReader {
+	conv := &core.ConversationInfo{
+		Ident:      "10.0.0.1:50000->10.0.0.2:514",
+		ClientIP:   "10.0.0.1",
+		ServerIP:   "10.0.0.2",
+		ClientPort: 50000,
+		ServerPort: 514,
+	}
+
+	for _, f := range fragments {
+		conv.Data = append(conv.Data, f)
+	}
+
+	return (&syslogReader{}).New(conv).(*syslogReader)
+}
+
+func TestMessagesUDP(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		flow  = gopacket.NewFlow(layers.EndpointUDPPort, []byte{0xc3, 0x50}, []byte{0x02, 0x02})
+		r     = testReader(
+			&core.StreamData{
+				RawData:            []byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8\n"),
+				Dir:                reassembly.TCPDirClientToServer,
+				CaptureInformation: gopacket.CaptureInfo{Timestamp: start},
+				Trans:              flow,
+			},
+			&core.StreamData{
+				RawData:            []byte("not a syslog message"),
+				Dir:                reassembly.TCPDirClientToServer,
+				CaptureInformation: gopacket.CaptureInfo{Timestamp: start.Add(time.Second)},
+				Trans:              flow,
+			},
+			&core.StreamData{
+				RawData:            []byte("<165>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - - %% It's time to make the do-nuts."),
+				Dir:                reassembly.TCPDirClientToServer,
+				CaptureInformation: gopacket.CaptureInfo{Timestamp: start.Add(2 * time.Second)},
+				Trans:              flow,
+			},
+		)
+		messages = r.messages()
+	)
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+
+	if m := messages[0]; m.Transport != transportUDP || m.Timestamp != start.UnixNano() || m.AppName != "su" ||
+		m.Message != "'su root' failed for lonvick on /dev/pts/8" || m.SrcIP != "10.0.0.1" || m.DstPort != 514 {
+		t.Fatalf("unexpected first message %+v", m)
+	}
+
+	if m := messages[1]; m.Format != formatRFC5424 || m.Timestamp != start.Add(2*time.Second).UnixNano() || m.ProcID != "8710" {
+		t.Fatalf("unexpected second message %+v", m)
+	}
+}
+
+func TestMessagesTCP(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+
+		// octet counted messages split across fragments, followed by newline framed messages
+		r = testReader(
+			&core.StreamData{
+				RawData:            []byte("28 <13>1 - host app - - - first"),
+				Dir:                reassembly.TCPDirClientToServer,
+				CaptureInformation: gopacket.CaptureInfo{Timestamp: start},
+			},
+			&core.StreamData{
+				RawData:            []byte("29 <13>1 - host app - - - sec"),
+				Dir:                reassembly.TCPDirClientToServer,
+				CaptureInformation: gopacket.CaptureInfo{Timestamp: start.Add(time.Second)},
+			},
+			&core.StreamData{
+				RawData:            []byte("ond<14>third\r\n<15>fourth\n<16>fif"),
+				Dir:                reassembly.TCPDirClientToServer,
+				CaptureInformation: gopacket.CaptureInfo{Timestamp: start.Add(2 * time.Second)},
+			},
+		)
+		messages = r.messages()
+		expected = []string{"first", "second", "third", "fourth", "fif"}
+	)
+
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %d: %+v", len(expected), len(messages), messages)
+	}
+
+	for i, m := range messages {
+		if m.Message != expected[i] || m.Transport != transportTCP {
+			t.Errorf("message %d: expected %q, got %+v", i, expected[i], m)
+		}
+	}
+
+	if messages[1].Timestamp != start.Add(time.Second).UnixNano() || messages[3].Timestamp != start.Add(2*time.Second).UnixNano() {
+		t.Fatal("unexpected timestamps")
+	}
+}
+
+func TestCanDecode(t *testing.T) {
+	for data, expected := range map[string]bool{
+		"<13>1 - - - - - -": true,
+		"120 <13>Oct 11":    true,
+		"<?xml version":     false,
+		"SSH-2.0-OpenSSH":   false,
+		"200 OK":            false,
+	} {
+		if Decoder.CanDecode([]byte(data), nil) != expected {
+			t.Errorf("%q: expected %v", data, expected)
+		}
+	}
+}
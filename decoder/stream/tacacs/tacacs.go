@@ -0,0 +1,65 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tacacs
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var tacacsLog = zap.NewNop()
+
+const (
+	headerSize = 12
+
+	// major version of TACACS+, the minor version is 0 or 1
+	majorVersion = 0xc
+
+	// the body is sent in cleartext
+	flagUnencrypted = 0x01
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_TACACS,
+	Name:        "TACACS",
+	Description: "TACACS+ provides authentication, authorization and accounting for the administrative access to network devices, bodies are decrypted if the shared key is configured",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		tacacsLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"tacacs",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isHeader(client) && (len(server) == 0 || isHeader(server))
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return tacacsLog.Sync()
+	},
+	Factory: &tacacsReader{},
+	Typ:     core.TCP,
+}
+
+// isHeader checks whether the data starts with a TACACS+ header.
+func isHeader(data []byte) bool {
+	return len(data) >= headerSize && data[0]>>4 == majorVersion && data[1] >= typeAuthentication && data[1] <= typeAccounting
+}
@@ -0,0 +1,527 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tacacs
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// packet types.
+const (
+	typeAuthentication = 1
+	typeAuthorization  = 2
+	typeAccounting     = 3
+)
+
+// authentication types, services and reply status values, see RFC 8907.
+const (
+	authenTypeASCII = 1
+	authenTypePAP   = 2
+
+	authenServiceEnable = 2
+
+	authenStatusPass    = 1
+	authenStatusFail    = 2
+	authenStatusGetUser = 4
+	authenStatusGetPass = 5
+
+	serviceTACACS = "TACACS+"
+)
+
+var errInvalidBody = errors.New("invalid TACACS+ body")
+
+var packetTypes = map[byte]string{
+	typeAuthentication: "Authentication",
+	typeAuthorization:  "Authorization",
+	typeAccounting:     "Accounting",
+}
+
+var authenActions = map[byte]string{
+	1: "LOGIN",
+	2: "CHPASS",
+	4: "SENDAUTH",
+}
+
+var authenTypes = map[byte]string{
+	authenTypeASCII: "ASCII",
+	authenTypePAP:   "PAP",
+	3:               "CHAP",
+	4:               "ARAP",
+	5:               "MSCHAP",
+	6:               "MSCHAPV2",
+}
+
+var authenServices = map[byte]string{
+	0:                   "NONE",
+	1:                   "LOGIN",
+	authenServiceEnable: "ENABLE",
+	3:                   "PPP",
+	4:                   "ARAP",
+	5:                   "PT",
+	6:                   "RCMD",
+	7:                   "X25",
+	8:                   "NASI",
+	9:                   "FWPROXY",
+}
+
+var authenStatus = map[byte]string{
+	authenStatusPass:    "PASS",
+	authenStatusFail:    "FAIL",
+	3:                   "GETDATA",
+	authenStatusGetUser: "GETUSER",
+	authenStatusGetPass: "GETPASS",
+	6:                   "RESTART",
+	7:                   "ERROR",
+	0x21:                "FOLLOW",
+}
+
+var authorStatus = map[byte]string{
+	0x01: "PASS_ADD",
+	0x02: "PASS_REPL",
+	0x10: "FAIL",
+	0x11: "ERROR",
+	0x21: "FOLLOW",
+}
+
+var acctStatus = map[byte]string{
+	0x01: "SUCCESS",
+	0x02: "ERROR",
+	0x21: "FOLLOW",
+}
+
+var acctFlags = map[byte]string{
+	0x02: "START",
+	0x04: "STOP",
+	0x08: "WATCHDOG",
+}
+
+type tacacsReader struct {
+	conversation *core.ConversationInfo
+}
+
+// packet is a parsed TACACS+ packet, along with the values of the body that are not part of the audit record.
+type packet struct {
+	record     *types.TACACS
+	authenType byte
+	service    byte
+	status     byte
+
+	// data of an authentication start and the message of a continue packet
+	data    string
+	userMsg string
+}
+
+// New will instantiate a new TACACS+ reader.
+func (h *tacacsReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &tacacsReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the TACACS+ protocol.
+func (h *tacacsReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	packets := h.packets([]byte(decoderconfig.Instance.TACACSKey))
+
+	if credentials.Decoder.Writer != nil {
+		for _, c := range h.credentials(packets) {
+			credentials.WriteCredentials(c)
+		}
+	}
+
+	for _, p := range packets {
+		writeTACACS(p.record)
+	}
+}
+
+// packets parses the packets of both directions, bodies are decrypted with the key if it is not empty.
+func (h *tacacsReader) packets(key []byte) []*packet {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		out            []*packet
+	)
+
+	for _, dir := range []*core.Direction{client, server} {
+		data := dir.Data
+
+		for offset := 0; len(data)-offset >= headerSize; {
+			if !isHeader(data[offset:]) {
+				tacacsLog.Debug("invalid TACACS+ header", zap.Int("offset", offset))
+
+				break
+			}
+
+			end := offset + headerSize + int(binary.BigEndian.Uint32(data[offset+8:offset+12]))
+			if end > len(data) || end < offset {
+				// incomplete packet at the end of the stream
+				break
+			}
+
+			p := h.packet(data[offset:end], key)
+			p.record.IsClient = dir.Client
+			p.record.Timestamp = dir.TimeAt(offset).UnixNano()
+			out = append(out, p)
+
+			offset = end
+		}
+	}
+
+	return out
+}
+
+// header parses the header of a packet.
+func (h *tacacsReader) header(data []byte) *types.TACACS {
+	return &types.TACACS{
+		ClientIP:       h.conversation.ClientIP,
+		ServerIP:       h.conversation.ServerIP,
+		ClientPort:     h.conversation.ClientPort,
+		ServerPort:     h.conversation.ServerPort,
+		Version:        int32(data[0]),
+		Type:           packetTypes[data[1]],
+		SequenceNumber: int32(data[2]),
+		Flags:          int32(data[3]),
+		SessionID:      binary.BigEndian.Uint32(data[4:8]),
+		Encrypted:      data[3]&flagUnencrypted == 0,
+	}
+}
+
+// packet parses the header and, if possible, the body of a single packet.
+func (h *tacacsReader) packet(data []byte, key []byte) *packet {
+	var (
+		p    = &packet{record: h.header(data)}
+		body = data[headerSize:]
+	)
+
+	if p.record.Encrypted {
+		if len(key) == 0 {
+			return p
+		}
+
+		body = crypt(body, key, data[4:8], data[0], data[2])
+	}
+
+	if err := parseBody(p, data[1], data[2], body); err != nil {
+		tacacsLog.Debug("failed to parse TACACS+ body, the shared key might be wrong",
+			zap.Error(err),
+			zap.String("ident", h.conversation.Ident),
+			zap.Uint32("session", p.record.SessionID),
+		)
+
+		return &packet{record: h.header(data)}
+	}
+
+	p.record.Decrypted = p.record.Encrypted
+
+	return p
+}
+
+// crypt reverses the obfuscation of the body, it is XORed with a pad of concatenated MD5 hashes over
+// the session id, the key, the version, the sequence number and the previous hash.
+func crypt(body, key, session []byte, version, seq byte) []byte {
+	var (
+		out  = make([]byte, len(body))
+		pad  []byte
+		base = make([]byte, 0, len(session)+len(key)+2)
+	)
+
+	base = append(base, session...)
+	base = append(base, key...)
+	base = append(base, version, seq)
+
+	for i := range body {
+		if i%md5.Size == 0 {
+			sum := md5.Sum(append(base, pad...))
+			pad = sum[:]
+		}
+
+		out[i] = body[i] ^ pad[i%md5.Size]
+	}
+
+	return out
+}
+
+// body is a reader for the length prefixed fields of a packet body.
+type body struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (b *body) bytes(n int) []byte {
+	if b.err != nil || n < 0 || b.pos+n > len(b.data) {
+		b.err = errInvalidBody
+
+		return nil
+	}
+
+	v := b.data[b.pos : b.pos+n]
+	b.pos += n
+
+	return v
+}
+
+func (b *body) byte() byte {
+	if v := b.bytes(1); len(v) == 1 {
+		return v[0]
+	}
+
+	return 0
+}
+
+func (b *body) uint16() int {
+	if v := b.bytes(2); len(v) == 2 {
+		return int(binary.BigEndian.Uint16(v))
+	}
+
+	return 0
+}
+
+func (b *body) string(n int) string {
+	return string(b.bytes(n))
+}
+
+// done returns an error if the fields did not fill the body exactly, which happens when it was decrypted with a wrong key.
+func (b *body) done() error {
+	if b.err == nil && b.pos != len(b.data) {
+		return errInvalidBody
+	}
+
+	return b.err
+}
+
+// parseBody parses the body according to the packet type and the sender, the client uses odd sequence numbers.
+func parseBody(p *packet, typ, seq byte, data []byte) error {
+	var (
+		r      = p.record
+		b      = &body{data: data}
+		client = seq%2 == 1
+	)
+
+	switch {
+	case typ == typeAuthentication && seq == 1:
+		r.Message = "START"
+		r.Action = authenActions[b.byte()]
+		r.PrivLevel = int32(b.byte())
+		p.authenType = b.byte()
+		p.service = b.byte()
+		r.AuthenType = authenTypes[p.authenType]
+		r.Service = authenServices[p.service]
+
+		userLen, portLen, remLen, dataLen := int(b.byte()), int(b.byte()), int(b.byte()), int(b.byte())
+		r.User = b.string(userLen)
+		r.Port = b.string(portLen)
+		r.RemoteAddress = b.string(remLen)
+		p.data = b.string(dataLen)
+	case typ == typeAuthentication && client:
+		r.Message = "CONTINUE"
+
+		msgLen, dataLen := b.uint16(), b.uint16()
+		b.byte()
+		p.userMsg = b.string(msgLen)
+		b.bytes(dataLen)
+	case typ == typeAuthentication:
+		r.Message = "REPLY"
+		p.status = b.byte()
+		r.Status = authenStatus[p.status]
+		b.byte()
+
+		msgLen, dataLen := b.uint16(), b.uint16()
+		r.ServerMessage = b.string(msgLen)
+		b.bytes(dataLen)
+	case client:
+		r.Message = "REQUEST"
+
+		if typ == typeAccounting {
+			flags := b.byte()
+			for _, f := range []byte{0x02, 0x04, 0x08} {
+				if flags&f != 0 {
+					r.Accounting = acctFlags[f]
+				}
+			}
+		}
+
+		// authentication method
+		b.byte()
+		r.PrivLevel = int32(b.byte())
+		p.authenType = b.byte()
+		p.service = b.byte()
+		r.AuthenType = authenTypes[p.authenType]
+		r.Service = authenServices[p.service]
+
+		userLen, portLen, remLen, argCount := int(b.byte()), int(b.byte()), int(b.byte()), int(b.byte())
+		argLens := b.bytes(argCount)
+		r.User = b.string(userLen)
+		r.Port = b.string(portLen)
+		r.RemoteAddress = b.string(remLen)
+
+		for _, l := range argLens {
+			r.Arguments = append(r.Arguments, b.string(int(l)))
+		}
+
+		r.Command = command(r.Arguments)
+	case typ == typeAuthorization:
+		r.Message = "RESPONSE"
+		p.status = b.byte()
+		r.Status = authorStatus[p.status]
+
+		argCount := int(b.byte())
+		msgLen, dataLen := b.uint16(), b.uint16()
+		argLens := b.bytes(argCount)
+		r.ServerMessage = b.string(msgLen)
+		b.bytes(dataLen)
+
+		for _, l := range argLens {
+			r.Arguments = append(r.Arguments, b.string(int(l)))
+		}
+	default:
+		r.Message = "REPLY"
+
+		msgLen, dataLen := b.uint16(), b.uint16()
+		p.status = b.byte()
+		r.Status = acctStatus[p.status]
+		r.ServerMessage = b.string(msgLen)
+		b.bytes(dataLen)
+	}
+
+	return b.done()
+}
+
+// command returns the command line from the arguments of an authorization or accounting request.
+// Authorization requests carry the command and each argument separately, accounting requests the full command.
+func command(args []string) string {
+	var parts []string
+
+	for _, a := range args {
+		i := strings.IndexAny(a, "=*")
+		if i < 0 {
+			continue
+		}
+
+		switch a[:i] {
+		case "cmd", "cmd-arg":
+			if v := a[i+1:]; v != "" && v != "<cr>" {
+				parts = append(parts, v)
+			}
+		}
+	}
+
+	return strings.TrimSuffix(strings.Join(parts, " "), " <cr>")
+}
+
+// credentials collects the passwords sent during PAP and ASCII logins, along with the result of the authentication.
+func (h *tacacsReader) credentials(packets []*packet) []*types.Credentials {
+	var (
+		sessions = make(map[uint32][]*packet)
+		ids      []uint32
+		out      []*types.Credentials
+	)
+
+	for _, p := range packets {
+		if p.record.Type != packetTypes[typeAuthentication] || p.record.Message == "" {
+			continue
+		}
+
+		if _, ok := sessions[p.record.SessionID]; !ok {
+			ids = append(ids, p.record.SessionID)
+		}
+
+		sessions[p.record.SessionID] = append(sessions[p.record.SessionID], p)
+	}
+
+	for _, id := range ids {
+		session := sessions[id]
+		sort.Slice(session, func(i, j int) bool {
+			return session[i].record.SequenceNumber < session[j].record.SequenceNumber
+		})
+
+		var (
+			user, password string
+			service        byte
+			prompt         byte
+			start          int64
+		)
+
+		for _, p := range session {
+			switch p.record.Message {
+			case "START":
+				user, service, start = p.record.User, p.service, p.record.Timestamp
+				if p.authenType == authenTypePAP {
+					password = p.data
+				}
+			case "CONTINUE":
+				switch prompt {
+				case authenStatusGetUser:
+					user = p.userMsg
+				case authenStatusGetPass:
+					password = p.userMsg
+				}
+			case "REPLY":
+				prompt = p.status
+
+				if (p.status != authenStatusPass && p.status != authenStatusFail) || password == "" {
+					continue
+				}
+
+				notes := "authentication " + strings.ToLower(authenStatus[p.status])
+				if service == authenServiceEnable {
+					notes = "enable password, " + notes
+				}
+
+				out = append(out, &types.Credentials{
+					Timestamp: start,
+					Service:   serviceTACACS,
+					Flow:      h.conversation.Ident,
+					User:      user,
+					Password:  password,
+					Notes:     notes,
+				})
+
+				password = ""
+			}
+		}
+	}
+
+	return out
+}
+
+func writeTACACS(r *types.TACACS) {
+	if decoderconfig.Instance.ExportMetrics {
+		r.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(r)
+	if err != nil {
+		tacacsLog.Error("failed to write TACACS record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tacacs
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+var testKey = []byte("s3cr3t")
+
+func fragment(data []byte, dir reassembly.TCPFlowDirection, ts time.Time) *core.StreamData {
+	return &core.StreamData{
+		RawData:            data,
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+// testPacket returns a packet with the body encrypted using the test key.
+func testPacket(typ, seq byte, session uint32, body []byte) []byte {
+	header := []byte{0xc0, typ, seq, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[4:8], session)
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(body)))
+
+	return append(header, crypt(body, testKey, header[4:8], header[0], seq)...)
+}
+
+// testFields returns a body with the fixed fields followed by the length prefixed values.
+func testFields(fixed []byte, values ...string) []byte {
+	b := append([]byte(nil), fixed...)
+	for _, v := range values {
+		b = append(b, v...)
+	}
+
+	return b
+}
+
+func testReader(fragments ...*core.StreamData) *tacacsReader {
+	conv := &core.ConversationInfo{
+		Ident:      "10.0.0.1:50000->10.0.0.2:49",
+		ClientIP:   "10.0.0.1",
+		ServerIP:   "10.0.0.2",
+		ClientPort: 50000,
+		ServerPort: 49,
+	}
+
+	for _, f := range fragments {
+		conv.Data = append(conv.Data, f)
+	}
+
+	return (&tacacsReader{}).New(conv).(*tacacsReader)
+}
+
+func TestASCIILogin(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+
+		// START login, priv-lvl 1, ASCII, service LOGIN, port tty1 and remote address
+		authStart = testPacket(typeAuthentication, 1, 7, testFields(
+			[]byte{0x01, 0x01, authenTypeASCII, 0x01, 0, 4, 8, 0}, "tty1", "10.0.0.9"))
+		getUser = testPacket(typeAuthentication, 2, 7, testFields(
+			[]byte{authenStatusGetUser, 0, 0, 10, 0, 0}, "Username: "))
+		user    = testPacket(typeAuthentication, 3, 7, testFields([]byte{0, 5, 0, 0, 0}, "admin"))
+		getPass = testPacket(typeAuthentication, 4, 7, testFields(
+			[]byte{authenStatusGetPass, 0x01, 0, 10, 0, 0}, "Password: "))
+		pass = testPacket(typeAuthentication, 5, 7, testFields([]byte{0, 6, 0, 0, 0}, "cisco1"))
+		ok   = testPacket(typeAuthentication, 6, 7, []byte{authenStatusPass, 0, 0, 0, 0, 0})
+	)
+
+	if !Decoder.CanDecode(authStart, getUser) {
+		t.Fatal("expected TACACS+ stream to be detected")
+	}
+
+	if Decoder.CanDecode([]byte("SSH-2.0-OpenSSH_8.2\r\n"), nil) {
+		t.Fatal("unexpected detection of SSH as TACACS+")
+	}
+
+	h := testReader(
+		fragment(authStart, reassembly.TCPDirClientToServer, start),
+		fragment(getUser, reassembly.TCPDirServerToClient, start.Add(time.Millisecond)),
+		fragment(append(user, pass[:5]...), reassembly.TCPDirClientToServer, start.Add(2*time.Millisecond)),
+		fragment(getPass, reassembly.TCPDirServerToClient, start.Add(3*time.Millisecond)),
+		fragment(pass[5:], reassembly.TCPDirClientToServer, start.Add(4*time.Millisecond)),
+		fragment(ok, reassembly.TCPDirServerToClient, start.Add(5*time.Millisecond)),
+	)
+
+	packets := h.packets(testKey)
+	if len(packets) != 6 {
+		t.Fatal("expected 6 packets, got", len(packets))
+	}
+
+	r := packets[0].record
+	if !r.IsClient || !r.Encrypted || !r.Decrypted || r.Type != "Authentication" || r.Message != "START" || r.Action != "LOGIN" ||
+		r.AuthenType != "ASCII" || r.Service != "LOGIN" || r.Port != "tty1" || r.RemoteAddress != "10.0.0.9" || r.SessionID != 7 {
+		t.Fatalf("unexpected start: %+v", r)
+	}
+
+	if r = packets[4].record; r.IsClient || r.Message != "REPLY" || r.Status != "GETPASS" || r.ServerMessage != "Password: " {
+		t.Fatalf("unexpected reply: %+v", r)
+	}
+
+	creds := h.credentials(packets)
+	if len(creds) != 1 || creds[0].User != "admin" || creds[0].Password != "cisco1" || creds[0].Notes != "authentication pass" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+
+	// without the key only the headers are decoded
+	packets = h.packets(nil)
+	if r = packets[0].record; !r.Encrypted || r.Decrypted || r.Message != "" || r.SequenceNumber != 1 {
+		t.Fatalf("unexpected record without key: %+v", r)
+	}
+
+	// a wrong key leads to inconsistent lengths
+	packets = h.packets([]byte("wrong"))
+	if r = packets[0].record; r.Decrypted || r.Message != "" {
+		t.Fatalf("unexpected record with wrong key: %+v", r)
+	}
+}
+
+func TestPAPEnableLogin(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+
+		// SENDAUTH is not used for PAP logins, priv-lvl 15, PAP, service ENABLE
+		authStart = testPacket(typeAuthentication, 1, 9, testFields(
+			[]byte{0x01, 0x0f, authenTypePAP, authenServiceEnable, 5, 0, 0, 6}, "admin", "enable"))
+		fail = testPacket(typeAuthentication, 2, 9, []byte{authenStatusFail, 0, 0, 0, 0, 0})
+	)
+
+	h := testReader(
+		fragment(authStart, reassembly.TCPDirClientToServer, start),
+		fragment(fail, reassembly.TCPDirServerToClient, start.Add(time.Millisecond)),
+	)
+
+	creds := h.credentials(h.packets(testKey))
+	if len(creds) != 1 || creds[0].User != "admin" || creds[0].Password != "enable" || creds[0].Notes != "enable password, authentication fail" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+}
+
+func TestCommandAccounting(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		args  = []string{"task_id=55", "service=shell", "priv-lvl=15", "cmd=show running-config <cr>"}
+
+		// STOP record, authentication method TACACS+, priv-lvl 15, ASCII, service LOGIN
+		request = testPacket(typeAccounting, 1, 11, testFields(
+			[]byte{0x04, 0x06, 0x0f, authenTypeASCII, 0x01, 5, 4, 0, 4, 10, 13, 11, 28}, append([]string{"admin", "tty2"}, args...)...))
+		reply = testPacket(typeAccounting, 2, 11, []byte{0, 0, 0, 0, 0x01})
+
+		// authorization of the command, arguments are sent separately
+		authorRequest = testPacket(typeAuthorization, 1, 12, testFields(
+			[]byte{0x06, 0x0f, authenTypeASCII, 0x01, 5, 0, 0, 4, 13, 8, 22, 12}, "admin", "service=shell", "cmd=show", "cmd-arg=running-config", "cmd-arg=<cr>"))
+		authorResponse = testPacket(typeAuthorization, 2, 12, []byte{0x01, 0, 0, 0, 0, 0})
+	)
+
+	h := testReader(
+		fragment(append(request, authorRequest...), reassembly.TCPDirClientToServer, start),
+		fragment(append(reply, authorResponse...), reassembly.TCPDirServerToClient, start.Add(time.Millisecond)),
+	)
+
+	packets := h.packets(testKey)
+	if len(packets) != 4 {
+		t.Fatal("expected 4 packets, got", len(packets))
+	}
+
+	r := packets[0].record
+	if r.Type != "Accounting" || r.Message != "REQUEST" || r.Accounting != "STOP" || r.User != "admin" || r.Port != "tty2" ||
+		r.PrivLevel != 15 || r.Command != "show running-config" || !reflect.DeepEqual(r.Arguments, args) {
+		t.Fatalf("unexpected accounting request: %+v", r)
+	}
+
+	if r = packets[1].record; r.Type != "Authorization" || r.Command != "show running-config" || len(r.Arguments) != 4 {
+		t.Fatalf("unexpected authorization request: %+v", r)
+	}
+
+	if r = packets[2].record; r.Message != "REPLY" || r.Status != "SUCCESS" {
+		t.Fatalf("unexpected accounting reply: %+v", r)
+	}
+
+	if r = packets[3].record; r.Message != "RESPONSE" || r.Status != "PASS_ADD" {
+		t.Fatalf("unexpected authorization response: %+v", r)
+	}
+
+	if creds := h.credentials(packets); len(creds) != 0 {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+}
@@ -369,8 +369,10 @@ func (t *tcpConnection) ReassemblyComplete(ac reassembly.AssemblerContext, first
 func (t *tcpConnection) decode() {
 	// choose the decoder to run against the data stream
 	var (
-		cr, sr = t.client.DataSlice().First(), t.server.DataSlice().First()
-		found  bool
+		cr, sr    = t.client.DataSlice().First(), t.server.DataSlice().First()
+		sd        core.StreamDecoderInterface
+		name      string
+		unwrapped bool
 	)
 
 	conv := &core.ConversationInfo{
@@ -384,27 +386,45 @@ func (t *tcpConnection) decode() {
 		ServerPort:        utils.DecodePort(t.client.Transport().Dst().Raw()),
 	}
 
-	// make a good first guess based on the destination port of the connection
-	if sd, exists := stream.DefaultStreamDecoders[utils.DecodePort(t.server.Transport().Dst().Raw())]; exists {
-		if sd.Transport() == core.TCP || sd.Transport() == core.All {
-			if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
-				t.decoder = sd.GetReaderFactory().New(conv)
-				found = true
-			}
+	// unwrap SOCKS and HTTP CONNECT tunnels, and select the decoder based on the tunneled data instead
+	for i := 0; i < maxTunnelDepth; i++ {
+		h := parseProxyHandshake(handshakeData(conv.Data))
+		if h == nil {
+			break
 		}
-	}
 
-	// if no stream decoder for the port was found, or the stream decoder did not match
-	// try all available decoders and use the first one that matches
-	if !found {
-		for _, sd := range stream.DefaultStreamDecoders {
-			if sd.Transport() == core.TCP || sd.Transport() == core.All {
-				if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
-					t.decoder = sd.GetReaderFactory().New(conv)
-					break
-				}
-			}
+		if !h.tunneled() {
+			writeProxyTunnel(conv, h, nil, "")
+
+			break
+		}
+
+		data, client, server := trimFragments(conv.Data, h.clientLen, h.serverLen)
+		inner := &core.ConversationInfo{
+			Data:              data,
+			Ident:             conv.Ident,
+			FirstClientPacket: conv.FirstClientPacket,
+			FirstServerPacket: conv.FirstServerPacket,
+			ClientIP:          conv.ClientIP,
+			ServerIP:          h.targetIP,
+			ServerHost:        h.targetHost,
+			ClientPort:        conv.ClientPort,
+			ServerPort:        h.targetPort,
 		}
+
+		sd, name = selectDecoder(inner, client, server)
+		writeProxyTunnel(conv, h, data, name)
+
+		conv, cr, sr = inner, client, server
+		unwrapped = true
+	}
+
+	if !unwrapped {
+		sd, _ = selectDecoder(conv, cr, sr)
+	}
+
+	if sd != nil {
+		t.decoder = sd
 	}
 
 	// call the decoder if one was found
@@ -418,6 +438,30 @@ func (t *tcpConnection) decode() {
 	}
 }
 
+// selectDecoder returns a reader for the first stream decoder that understands the conversation.
+func selectDecoder(conv *core.ConversationInfo, cr, sr []byte) (core.StreamDecoderInterface, string) {
+	// make a good first guess based on the destination port of the connection
+	if sd, exists := stream.DefaultStreamDecoders[conv.ServerPort]; exists {
+		if sd.Transport() == core.TCP || sd.Transport() == core.All {
+			if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
+				return sd.GetReaderFactory().New(conv), sd.GetName()
+			}
+		}
+	}
+
+	// if no stream decoder for the port was found, or the stream decoder did not match
+	// try all available decoders and use the first one that matches
+	for _, sd := range stream.DefaultStreamDecoders {
+		if sd.Transport() == core.TCP || sd.Transport() == core.All {
+			if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
+				return sd.GetReaderFactory().New(conv), sd.GetName()
+			}
+		}
+	}
+
+	return nil, ""
+}
+
 // ReassemblePacket takes care of submitting a TCP / UDP packet to the reassembly.
 func ReassemblePacket(packet gopacket.Packet, assembler *reassembly.Assembler) {
 
@@ -0,0 +1,630 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/decoder/stream/tunnel"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// maxHandshakeSize limits how much of each direction is inspected for a proxy handshake.
+const maxHandshakeSize = 8192
+
+// maxTunnelDepth limits how many proxy tunnels nested into each other are unwrapped.
+const maxTunnelDepth = 3
+
+// proxy protocols.
+const (
+	protoSOCKS4      = "SOCKS4"
+	protoSOCKS4a     = "SOCKS4a"
+	protoSOCKS5      = "SOCKS5"
+	protoHTTPConnect = "HTTP CONNECT"
+)
+
+// proxy commands.
+const (
+	commandConnect      = "CONNECT"
+	commandBind         = "BIND"
+	commandUDPAssociate = "UDP ASSOCIATE"
+)
+
+// SOCKS constants.
+const (
+	socks4Version       = 0x04
+	socks4ReplyVersion  = 0x00
+	socks4Granted       = 90
+	socks5Version       = 0x05
+	socks5AuthVersion   = 0x01
+	socks5AuthNone      = 0x00
+	socks5AuthGSSAPI    = 0x01
+	socks5AuthPassword  = 0x02
+	socks5AuthAssigned  = 0x09
+	socks5AuthPrivate   = 0x80
+	socks5AuthNoAccept  = 0xff
+	socks5Succeeded     = 0x00
+	socks5AddressIPv4   = 0x01
+	socks5AddressDomain = 0x03
+	socks5AddressIPv6   = 0x04
+)
+
+var socksCommands = map[byte]string{
+	1: commandConnect,
+	2: commandBind,
+	3: commandUDPAssociate,
+}
+
+var socks5AuthMethods = map[byte]string{
+	socks5AuthNone:     "none",
+	socks5AuthGSSAPI:   "GSSAPI",
+	socks5AuthPassword: "username/password",
+	socks5AuthNoAccept: "no acceptable methods",
+}
+
+// proxyHandshake contains the information extracted from a proxy handshake.
+type proxyHandshake struct {
+	protocol   string
+	command    string
+	authMethod string
+	user       string
+	password   string
+	targetHost string
+	targetIP   string
+	targetPort int32
+	success    bool
+	status     int32
+
+	// number of bytes consumed by the handshake in each direction
+	clientLen int
+	serverLen int
+}
+
+// tunneled returns true if the payload following the handshake belongs to a connection to the target.
+func (h *proxyHandshake) tunneled() bool {
+	return h.success && (h.command == commandConnect || h.command == commandBind)
+}
+
+// parseProxyHandshake checks whether the conversation starts with a SOCKS or HTTP CONNECT handshake.
+// A handshake is only reported once the proxy response has been seen, to avoid false positives on binary protocols.
+func parseProxyHandshake(client, server []byte) *proxyHandshake {
+	if len(client) == 0 || len(server) == 0 {
+		return nil
+	}
+
+	switch {
+	case client[0] == socks4Version:
+		return parseSOCKS4(client, server)
+	case client[0] == socks5Version:
+		return parseSOCKS5(client, server)
+	case bytes.HasPrefix(client, []byte(commandConnect+" ")):
+		return parseHTTPConnect(client, server)
+	}
+
+	return nil
+}
+
+// parseSOCKS4 parses a SOCKS4 or SOCKS4a request and the corresponding reply.
+func parseSOCKS4(client, server []byte) *proxyHandshake {
+	if len(client) < 9 || len(server) < 8 || server[0] != socks4ReplyVersion {
+		return nil
+	}
+
+	command, ok := socksCommands[client[1]]
+	if !ok || command == commandUDPAssociate {
+		return nil
+	}
+
+	// reply codes are in the range 90-93
+	if server[1] < socks4Granted || server[1] > socks4Granted+3 {
+		return nil
+	}
+
+	end := bytes.IndexByte(client[8:], 0)
+	if end == -1 {
+		return nil
+	}
+
+	h := &proxyHandshake{
+		protocol:   protoSOCKS4,
+		command:    command,
+		user:       string(client[8 : 8+end]),
+		targetPort: int32(binary.BigEndian.Uint16(client[2:4])),
+		success:    server[1] == socks4Granted,
+		status:     int32(server[1]),
+		clientLen:  8 + end + 1,
+		serverLen:  8,
+	}
+
+	ip := net.IP(client[4:8])
+
+	// SOCKS4a: an address of 0.0.0.x with x != 0 indicates the hostname follows the user id
+	if ip[0] == 0 && ip[1] == 0 && ip[2] == 0 && ip[3] != 0 {
+		hostEnd := bytes.IndexByte(client[h.clientLen:], 0)
+		if hostEnd == -1 {
+			return nil
+		}
+
+		h.protocol = protoSOCKS4a
+		h.targetHost = string(client[h.clientLen : h.clientLen+hostEnd])
+		h.clientLen += hostEnd + 1
+	} else {
+		h.targetIP = ip.String()
+		h.targetHost = h.targetIP
+	}
+
+	// BIND sends a second reply once the incoming connection has been accepted
+	if command == commandBind && h.success {
+		if len(server) < 16 || server[8] != socks4ReplyVersion {
+			h.success = false
+		} else {
+			h.status = int32(server[9])
+			h.success = server[9] == socks4Granted
+			h.serverLen = 16
+		}
+	}
+
+	if h.user != "" {
+		h.authMethod = "user id"
+	}
+
+	return h
+}
+
+// parseSOCKS5 parses the SOCKS5 method negotiation, the optional username / password
+// subnegotiation and the request and reply.
+func parseSOCKS5(client, server []byte) *proxyHandshake {
+	// greeting: version, number of methods, methods
+	if len(client) < 3 || client[1] == 0 || len(client) < 2+int(client[1]) {
+		return nil
+	}
+
+	methods := client[2 : 2+int(client[1])]
+	for _, m := range methods {
+		if !isSOCKS5AuthMethod(m) {
+			return nil
+		}
+	}
+
+	// method selection: version, method
+	if len(server) < 2 || server[0] != socks5Version {
+		return nil
+	}
+
+	method := server[1]
+	if method != socks5AuthNoAccept && bytes.IndexByte(methods, method) == -1 {
+		return nil
+	}
+
+	h := &proxyHandshake{
+		protocol:   protoSOCKS5,
+		authMethod: socks5AuthMethods[method],
+		status:     int32(method),
+		clientLen:  2 + int(client[1]),
+		serverLen:  2,
+	}
+
+	if h.authMethod == "" {
+		h.authMethod = "0x" + strconv.FormatUint(uint64(method), 16)
+	}
+
+	// an echoed method alone is weak evidence, without authentication the handshake
+	// is only reported if a valid request follows
+	var invalid *proxyHandshake
+
+	switch method {
+	case socks5AuthNoAccept:
+		// the server closes the connection after the method selection
+		if len(server) != h.serverLen {
+			return nil
+		}
+
+		return h
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		// subnegotiation: version, user length, user, password length, password
+		c := client[h.clientLen:]
+		if len(c) < 2 || c[0] != socks5AuthVersion || len(c) < 3+int(c[1]) {
+			return nil
+		}
+
+		ulen := int(c[1])
+		plen := int(c[2+ulen])
+
+		if len(c) < 3+ulen+plen {
+			return nil
+		}
+
+		h.user = string(c[2 : 2+ulen])
+		h.password = string(c[3+ulen : 3+ulen+plen])
+		h.clientLen += 3 + ulen + plen
+
+		// status: version, status
+		s := server[h.serverLen:]
+		if len(s) < 2 {
+			return h
+		}
+
+		if s[0] != socks5AuthVersion {
+			return nil
+		}
+
+		h.serverLen += 2
+		if s[1] != socks5Succeeded {
+			h.status = int32(s[1])
+
+			return h
+		}
+
+		invalid = h
+	case socks5AuthGSSAPI:
+		// the following messages are encapsulated, only check the header of the first one: version, message type
+		c := client[h.clientLen:]
+		if len(c) < 2 || c[0] != socks5AuthVersion || c[1] != 0x01 {
+			return nil
+		}
+
+		return h
+	default:
+		// the messages of private methods cannot be validated
+		return nil
+	}
+
+	// request: version, command, reserved, address type, address, port
+	c := client[h.clientLen:]
+	if len(c) < 4 || c[0] != socks5Version || c[2] != 0 {
+		return invalid
+	}
+
+	command, ok := socksCommands[c[1]]
+	if !ok {
+		return invalid
+	}
+
+	host, ip, port, n := parseSOCKS5Address(c[3:])
+	if n == 0 {
+		return invalid
+	}
+
+	h.command = command
+	h.targetHost = host
+	h.targetIP = ip
+	h.targetPort = port
+	h.clientLen += 3 + n
+
+	// reply: version, reply, reserved, address type, bound address, bound port
+	s := server[h.serverLen:]
+	if len(s) == 0 {
+		return h
+	}
+
+	if len(s) < 4 || s[0] != socks5Version || s[2] != 0 {
+		return invalid
+	}
+
+	_, _, _, n = parseSOCKS5Address(s[3:])
+	if n == 0 {
+		return invalid
+	}
+
+	h.status = int32(s[1])
+	h.success = s[1] == socks5Succeeded
+	h.serverLen += 3 + n
+
+	// BIND sends a second reply once the incoming connection has been accepted
+	if command == commandBind && h.success {
+		s = server[h.serverLen:]
+		if len(s) < 4 || s[0] != socks5Version {
+			h.success = false
+
+			return h
+		}
+
+		_, _, _, n = parseSOCKS5Address(s[3:])
+		if n == 0 {
+			h.success = false
+
+			return h
+		}
+
+		h.status = int32(s[1])
+		h.success = s[1] == socks5Succeeded
+		h.serverLen += 3 + n
+	}
+
+	return h
+}
+
+// isSOCKS5AuthMethod checks if the value is an assigned or private SOCKS5 authentication method.
+func isSOCKS5AuthMethod(m byte) bool {
+	return m <= socks5AuthAssigned || (m >= socks5AuthPrivate && m != socks5AuthNoAccept)
+}
+
+// parseSOCKS5Address parses a SOCKS5 address type, address and port
+// and returns the number of bytes consumed, or zero if the data is incomplete.
+func parseSOCKS5Address(data []byte) (host, ip string, port int32, n int) {
+	if len(data) < 1 {
+		return "", "", 0, 0
+	}
+
+	switch data[0] {
+	case socks5AddressIPv4:
+		n = 1 + net.IPv4len
+	case socks5AddressIPv6:
+		n = 1 + net.IPv6len
+	case socks5AddressDomain:
+		if len(data) < 2 {
+			return "", "", 0, 0
+		}
+
+		n = 2 + int(data[1])
+	default:
+		return "", "", 0, 0
+	}
+
+	if len(data) < n+2 {
+		return "", "", 0, 0
+	}
+
+	if data[0] == socks5AddressDomain {
+		host = string(data[2:n])
+	} else {
+		ip = net.IP(data[1:n]).String()
+		host = ip
+	}
+
+	return host, ip, int32(binary.BigEndian.Uint16(data[n : n+2])), n + 2
+}
+
+// parseHTTPConnect parses HTTP CONNECT requests and the proxy responses.
+// Requests that are rejected, for example because the proxy requires authentication,
+// are skipped as long as the client retries on the same connection.
+func parseHTTPConnect(client, server []byte) *proxyHandshake {
+	var h *proxyHandshake
+
+	for bytes.HasPrefix(client, []byte(commandConnect+" ")) {
+		reqEnd := bytes.Index(client, []byte("\r\n\r\n"))
+		resEnd := bytes.Index(server, []byte("\r\n\r\n"))
+
+		if reqEnd == -1 || resEnd == -1 {
+			break
+		}
+
+		reqLines := strings.Split(string(client[:reqEnd]), "\r\n")
+		resLines := strings.Split(string(server[:resEnd]), "\r\n")
+
+		request := strings.Fields(reqLines[0])
+		response := strings.Fields(resLines[0])
+
+		if len(request) < 3 || len(response) < 2 || !strings.HasPrefix(response[0], "HTTP/") {
+			break
+		}
+
+		status, err := strconv.Atoi(response[1])
+		if err != nil {
+			break
+		}
+
+		next := &proxyHandshake{
+			protocol:  protoHTTPConnect,
+			command:   commandConnect,
+			status:    int32(status),
+			success:   status >= 200 && status < 300,
+			clientLen: reqEnd + 4,
+			serverLen: resEnd + 4,
+		}
+
+		if h != nil {
+			next.clientLen += h.clientLen
+			next.serverLen += h.serverLen
+		}
+
+		host, port, err := net.SplitHostPort(request[1])
+		if err == nil {
+			next.targetHost = host
+			if p, errPort := strconv.Atoi(port); errPort == nil {
+				next.targetPort = int32(p)
+			}
+		} else {
+			next.targetHost = request[1]
+		}
+
+		if ip := net.ParseIP(next.targetHost); ip != nil {
+			next.targetIP = ip.String()
+		}
+
+		if header(reqLines[1:], "Proxy-Authorization") != "" {
+			next.authMethod, next.user, next.password = parseProxyAuthorization(header(reqLines[1:], "Proxy-Authorization"))
+		}
+
+		h = next
+
+		if h.success {
+			break
+		}
+
+		// skip the body of the error response, if the length is unknown the remaining data cannot be aligned
+		contentLength := header(resLines[1:], "Content-Length")
+		if contentLength == "" {
+			break
+		}
+
+		length, err := strconv.Atoi(contentLength)
+		if err != nil || length < 0 || resEnd+4+length > len(server) {
+			break
+		}
+
+		h.serverLen += length
+		client = client[reqEnd+4:]
+		server = server[resEnd+4+length:]
+	}
+
+	return h
+}
+
+// header returns the value of the named header from a list of raw header lines.
+func header(lines []string, name string) string {
+	for _, l := range lines {
+		i := strings.IndexByte(l, ':')
+		if i == -1 {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSpace(l[:i]), name) {
+			return strings.TrimSpace(l[i+1:])
+		}
+	}
+
+	return ""
+}
+
+// parseProxyAuthorization returns the scheme and for basic authentication the user and password.
+func parseProxyAuthorization(value string) (scheme, user, password string) {
+	parts := strings.SplitN(value, " ", 2)
+	scheme = parts[0]
+
+	if len(parts) != 2 || !strings.EqualFold(scheme, "basic") {
+		return scheme, "", ""
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return scheme, "", ""
+	}
+
+	creds := strings.SplitN(string(decoded), ":", 2)
+	if len(creds) != 2 {
+		return scheme, creds[0], ""
+	}
+
+	return scheme, creds[0], creds[1]
+}
+
+// handshakeData returns the first bytes sent in each direction, up to maxHandshakeSize.
+func handshakeData(data core.DataFragments) (client, server []byte) {
+	for _, d := range data {
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			if len(client) < maxHandshakeSize {
+				client = append(client, d.Raw()...)
+			}
+		} else if len(server) < maxHandshakeSize {
+			server = append(server, d.Raw()...)
+		}
+	}
+
+	return client, server
+}
+
+// trimFragments removes the given number of bytes from the start of each direction
+// and returns the remaining fragments, as well as the first fragment of each direction.
+func trimFragments(data core.DataFragments, clientLen, serverLen int) (out core.DataFragments, client, server []byte) {
+	for _, d := range data {
+		skip := &serverLen
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			skip = &clientLen
+		}
+
+		raw := d.Raw()
+		if *skip >= len(raw) {
+			*skip -= len(raw)
+
+			continue
+		}
+
+		if *skip > 0 {
+			raw = raw[*skip:]
+			*skip = 0
+			d = &core.StreamData{
+				RawData:            raw,
+				AssemblerContext:   d.Context(),
+				Dir:                d.Direction(),
+				CaptureInformation: d.CaptureInfo(),
+				Net:                d.Network(),
+				Trans:              d.Transport(),
+			}
+		}
+
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			if client == nil {
+				client = raw
+			}
+		} else if server == nil {
+			server = raw
+		}
+
+		out = append(out, d)
+	}
+
+	return out, client, server
+}
+
+// writeProxyTunnel writes the audit record for the proxy tunnel and the credentials used to authenticate to the proxy.
+// Data contains the tunneled fragments and name the stream decoder selected for them.
+func writeProxyTunnel(conv *core.ConversationInfo, h *proxyHandshake, data core.DataFragments, name string) {
+	ts := conv.FirstClientPacket.UnixNano()
+
+	if credentials.Decoder.Writer != nil && (h.user != "" || h.password != "") {
+		credentials.WriteCredentials(&types.Credentials{
+			Timestamp: ts,
+			Service:   h.protocol,
+			Flow:      conv.Ident,
+			User:      h.user,
+			Password:  h.password,
+			Notes:     "proxy authentication: " + h.authMethod,
+		})
+	}
+
+	if tunnel.Decoder.Writer == nil {
+		return
+	}
+
+	var clientBytes, serverBytes int64
+
+	for _, d := range data {
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			clientBytes += int64(len(d.Raw()))
+		} else {
+			serverBytes += int64(len(d.Raw()))
+		}
+	}
+
+	tunnel.WriteProxyTunnel(&types.ProxyTunnel{
+		Timestamp:   ts,
+		Protocol:    h.protocol,
+		Command:     h.command,
+		Flow:        conv.Ident,
+		ClientIP:    conv.ClientIP,
+		ClientPort:  conv.ClientPort,
+		ProxyIP:     conv.ServerIP,
+		ProxyPort:   conv.ServerPort,
+		TargetHost:  h.targetHost,
+		TargetIP:    h.targetIP,
+		TargetPort:  h.targetPort,
+		AuthMethod:  h.authMethod,
+		User:        h.user,
+		Success:     h.success,
+		Status:      h.status,
+		Decoder:     name,
+		ClientBytes: clientBytes,
+		ServerBytes: serverBytes,
+	})
+}
@@ -0,0 +1,160 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tcp
+
+import (
+	"testing"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func TestParseSOCKS4a(t *testing.T) {
+	client := append([]byte{socks4Version, 1, 0x01, 0xbb, 0, 0, 0, 1}, "bob\x00example.com\x00\x16\x03\x01"...)
+	server := []byte{0, socks4Granted, 0, 0, 0, 0, 0, 0}
+
+	h := parseProxyHandshake(client, server)
+	if h == nil {
+		t.Fatal("expected SOCKS4a handshake")
+	}
+
+	if h.protocol != protoSOCKS4a || h.command != commandConnect || h.targetHost != "example.com" || h.targetPort != 443 || h.user != "bob" {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	if !h.tunneled() || h.clientLen != len(client)-3 || h.serverLen != 8 {
+		t.Fatalf("unexpected handshake lengths: %+v", h)
+	}
+}
+
+func TestParseSOCKS5(t *testing.T) {
+	client := []byte{socks5Version, 2, socks5AuthNone, socks5AuthPassword}
+	client = append(client, socks5AuthVersion, 5)
+	client = append(client, "alice"...)
+	client = append(client, 6)
+	client = append(client, "secret"...)
+	client = append(client, socks5Version, 1, 0, socks5AddressDomain, 8)
+	client = append(client, "ssh.host"...)
+	client = append(client, 0, 22)
+	handshake := len(client)
+	client = append(client, "SSH-2.0-OpenSSH_8.2\r\n"...)
+
+	server := []byte{socks5Version, socks5AuthPassword, socks5AuthVersion, socks5Succeeded, socks5Version, socks5Succeeded, 0, socks5AddressIPv4, 10, 0, 0, 1, 0x1f, 0x90}
+
+	h := parseProxyHandshake(client, server)
+	if h == nil {
+		t.Fatal("expected SOCKS5 handshake")
+	}
+
+	if h.user != "alice" || h.password != "secret" || h.authMethod != "username/password" {
+		t.Fatalf("unexpected authentication: %+v", h)
+	}
+
+	if h.targetHost != "ssh.host" || h.targetIP != "" || h.targetPort != 22 || !h.tunneled() || h.clientLen != handshake || h.serverLen != len(server) {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	// the proxy rejects the authentication
+	server[3] = 1
+	if h = parseProxyHandshake(client, server); h == nil || h.tunneled() || h.status != 1 {
+		t.Fatalf("expected rejected handshake, got %+v", h)
+	}
+
+	// binary data that happens to start with the SOCKS version, but lacks a valid reply
+	if h = parseProxyHandshake([]byte{5, 1, 0}, []byte{0x16, 0x03}); h != nil {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	// the method is echoed, but no valid request follows
+	if h = parseProxyHandshake([]byte{5, 1, 0, 0x42, 0x42, 0x42, 0x42}, []byte{5, 0, 0x10, 0x20}); h != nil {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	// the greeting contains an invalid method
+	if h = parseProxyHandshake([]byte{5, 2, 0, 0x42}, []byte{5, 0}); h != nil {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	// no acceptable methods, the server closes the connection
+	if h = parseProxyHandshake([]byte{5, 1, socks5AuthPassword}, []byte{5, socks5AuthNoAccept}); h == nil || h.tunneled() || h.authMethod != "no acceptable methods" {
+		t.Fatalf("expected rejected handshake, got %+v", h)
+	}
+
+	if h = parseProxyHandshake([]byte{5, 1, socks5AuthPassword}, []byte{5, socks5AuthNoAccept, 0x01}); h != nil {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+}
+
+func TestParseSOCKS5IPv4(t *testing.T) {
+	client := []byte{socks5Version, 1, socks5AuthNone, socks5Version, 1, 0, socks5AddressIPv4, 192, 0, 2, 1, 0, 80}
+	server := []byte{socks5Version, socks5AuthNone, socks5Version, socks5Succeeded, 0, socks5AddressIPv4, 10, 0, 0, 1, 0x1f, 0x90}
+
+	h := parseProxyHandshake(client, server)
+	if h == nil || !h.tunneled() {
+		t.Fatalf("expected SOCKS5 handshake, got %+v", h)
+	}
+
+	if h.targetIP != "192.0.2.1" || h.targetHost != "192.0.2.1" || h.targetPort != 80 || h.clientLen != len(client) || h.serverLen != len(server) {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	// the reserved byte of the request must be zero
+	client[5] = 1
+	if h = parseProxyHandshake(client, server); h != nil {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+}
+
+func TestParseHTTPConnect(t *testing.T) {
+	client := []byte("CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n" +
+		"CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\nProxy-Authorization: Basic dXNlcjpwYXNz\r\n\r\n")
+	server := []byte("HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 4\r\n\r\ndeny" +
+		"HTTP/1.1 200 Connection established\r\n\r\n")
+
+	h := parseProxyHandshake(append(client, "\x16\x03\x01"...), append(server, "\x16\x03\x03"...))
+	if h == nil {
+		t.Fatal("expected HTTP CONNECT handshake")
+	}
+
+	if h.protocol != protoHTTPConnect || h.targetHost != "example.com" || h.targetPort != 443 || h.status != 200 || !h.tunneled() {
+		t.Fatalf("unexpected handshake: %+v", h)
+	}
+
+	if h.authMethod != "Basic" || h.user != "user" || h.password != "pass" {
+		t.Fatalf("unexpected authentication: %+v", h)
+	}
+
+	if h.clientLen != len(client) || h.serverLen != len(server) {
+		t.Fatalf("unexpected lengths: client %d != %d, server %d != %d", h.clientLen, len(client), h.serverLen, len(server))
+	}
+}
+
+func TestTrimFragments(t *testing.T) {
+	data := core.DataFragments{
+		&core.StreamData{RawData: []byte("abc"), Dir: reassembly.TCPDirClientToServer},
+		&core.StreamData{RawData: []byte("xy"), Dir: reassembly.TCPDirServerToClient},
+		&core.StreamData{RawData: []byte("defg"), Dir: reassembly.TCPDirClientToServer},
+		&core.StreamData{RawData: []byte("z123"), Dir: reassembly.TCPDirServerToClient},
+	}
+
+	out, client, server := trimFragments(data, 5, 3)
+	if len(out) != 2 || string(client) != "fg" || string(server) != "123" {
+		t.Fatalf("unexpected result: %d fragments, client %q, server %q", len(out), client, server)
+	}
+
+	// the original fragments must not be modified
+	if string(data[2].Raw()) != "defg" {
+		t.Fatal("original fragment was modified")
+	}
+}
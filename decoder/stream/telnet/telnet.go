@@ -0,0 +1,138 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package telnet
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var telnetLog = zap.NewNop()
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_Telnet,
+	Name:        "Telnet",
+	Description: "Telnet provides a bidirectional interactive text-oriented terminal session, that is still widely used to administrate legacy devices",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		telnetLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"telnet",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return startsWithNegotiation(client) || startsWithNegotiation(server)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return telnetLog.Sync()
+	},
+	Factory: &telnetReader{},
+	Typ:     core.TCP,
+}
+
+// startsWithNegotiation checks whether the data begins with a telnet option negotiation.
+func startsWithNegotiation(data []byte) bool {
+	return len(data) >= 3 && data[0] == iac && data[1] >= will && data[1] <= dont
+}
+
+// Telnet commands, see RFC 854.
+const (
+	se   = 240
+	sb   = 250
+	will = 251
+	wont = 252
+	do   = 253
+	dont = 254
+	iac  = 255
+)
+
+// Telnet options used in subnegotiations.
+const (
+	optTerminalType    = 24
+	optWindowSize      = 31
+	optTerminalSpeed   = 32
+	optDisplayLocation = 35
+	optOldEnviron      = 36
+	optNewEnviron      = 39
+)
+
+var commandNames = map[byte]string{
+	will: "WILL",
+	wont: "WONT",
+	do:   "DO",
+	dont: "DONT",
+}
+
+// optionNames contains the names of the telnet options registered at IANA.
+var optionNames = map[byte]string{
+	0:   "BINARY",
+	1:   "ECHO",
+	2:   "RECONNECTION",
+	3:   "SUPPRESS-GO-AHEAD",
+	4:   "APPROX-MESSAGE-SIZE",
+	5:   "STATUS",
+	6:   "TIMING-MARK",
+	7:   "RCTE",
+	8:   "OUTPUT-LINE-WIDTH",
+	9:   "OUTPUT-PAGE-SIZE",
+	10:  "NAOCRD",
+	11:  "NAOHTS",
+	12:  "NAOHTD",
+	13:  "NAOFFD",
+	14:  "NAOVTS",
+	15:  "NAOVTD",
+	16:  "NAOLFD",
+	17:  "EXTEND-ASCII",
+	18:  "LOGOUT",
+	19:  "BM",
+	20:  "DET",
+	21:  "SUPDUP",
+	22:  "SUPDUP-OUTPUT",
+	23:  "SEND-LOCATION",
+	24:  "TERMINAL-TYPE",
+	25:  "END-OF-RECORD",
+	26:  "TUID",
+	27:  "OUTMRK",
+	28:  "TTYLOC",
+	29:  "3270-REGIME",
+	30:  "X.3-PAD",
+	31:  "NAWS",
+	32:  "TERMINAL-SPEED",
+	33:  "TOGGLE-FLOW-CONTROL",
+	34:  "LINEMODE",
+	35:  "X-DISPLAY-LOCATION",
+	36:  "ENVIRON",
+	37:  "AUTHENTICATION",
+	38:  "ENCRYPT",
+	39:  "NEW-ENVIRON",
+	40:  "TN3270E",
+	41:  "XAUTH",
+	42:  "CHARSET",
+	43:  "RSP",
+	44:  "COM-PORT-OPTION",
+	45:  "SUPPRESS-LOCAL-ECHO",
+	46:  "START-TLS",
+	47:  "KERMIT",
+	48:  "SEND-URL",
+	49:  "FORWARD-X",
+	255: "EXOPL",
+}
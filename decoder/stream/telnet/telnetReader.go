@@ -0,0 +1,425 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package telnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// parser states.
+const (
+	stateData = iota
+	stateIAC
+	stateOption
+	stateSubnegotiation
+	stateSubnegotiationIAC
+	stateCR
+	stateEscape
+	stateCSI
+)
+
+// subnegotiation commands.
+const (
+	subIs   = 0
+	subInfo = 2
+)
+
+// environment variable type codes for NEW-ENVIRON, see RFC 1572.
+// ENVIRON from RFC 1408 swaps the codes for VAR and VALUE.
+const (
+	envVar     = 0
+	envValue   = 1
+	envEsc     = 2
+	envUserVar = 3
+)
+
+var loginPrompts = []string{"login:", "username:", "user name:", "user:"}
+
+type telnetReader struct {
+	conversation *core.ConversationInfo
+
+	session       *types.Telnet
+	options       map[string]struct{}
+	awaitingLogin bool
+}
+
+// direction holds the parser state for one side of the connection.
+type direction struct {
+	client bool
+	state  int
+	verb   byte
+	sub    []byte
+	line   []byte
+}
+
+// New will instantiate a new telnet reader.
+func (h *telnetReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &telnetReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the telnet protocol.
+func (h *telnetReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	if len(h.conversation.Data) == 0 {
+		return
+	}
+
+	writeTelnet(h.decodeSession())
+}
+
+// decodeSession strips the option negotiation from both directions of the conversation
+// and returns the session with the negotiated options and a transcript.
+func (h *telnetReader) decodeSession() *types.Telnet {
+	var (
+		first  = core.Timestamp(h.conversation.Data[0])
+		last   = first
+		client = &direction{client: true}
+		server = &direction{}
+	)
+
+	h.session = &types.Telnet{
+		Timestamp:  first.UnixNano(),
+		ClientIP:   h.conversation.ClientIP,
+		ServerIP:   h.conversation.ServerIP,
+		ClientPort: h.conversation.ClientPort,
+		ServerPort: h.conversation.ServerPort,
+	}
+	h.options = make(map[string]struct{})
+
+	for _, d := range h.conversation.Data {
+		ts := core.Timestamp(d)
+		if ts.After(last) {
+			last = ts
+		}
+
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			h.session.ClientBytes += int64(len(d.Raw()))
+			h.feed(client, d.Raw(), ts)
+		} else {
+			h.session.ServerBytes += int64(len(d.Raw()))
+			h.feed(server, d.Raw(), ts)
+			h.checkLoginPrompt(server)
+		}
+	}
+
+	// flush incomplete lines at the end of the session
+	h.flushLine(server, last)
+	h.flushLine(client, last)
+
+	h.session.Duration = last.Sub(first).Nanoseconds()
+
+	return h.session
+}
+
+// feed processes data from one direction.
+func (h *telnetReader) feed(dir *direction, data []byte, ts time.Time) {
+	for _, b := range data {
+		switch dir.state {
+		case stateIAC:
+			switch b {
+			case iac:
+				// escaped 0xff data byte
+				dir.state = stateData
+				h.addByte(dir, b, ts)
+			case will, wont, do, dont:
+				dir.verb = b
+				dir.state = stateOption
+			case sb:
+				dir.sub = dir.sub[:0]
+				dir.state = stateSubnegotiation
+			default:
+				// NOP, GA, AYT, IP and friends carry no data
+				dir.state = stateData
+			}
+		case stateOption:
+			h.addOption(dir, b)
+			dir.state = stateData
+		case stateSubnegotiation:
+			if b == iac {
+				dir.state = stateSubnegotiationIAC
+			} else {
+				dir.sub = append(dir.sub, b)
+			}
+		case stateSubnegotiationIAC:
+			switch b {
+			case iac:
+				dir.sub = append(dir.sub, b)
+				dir.state = stateSubnegotiation
+			case se:
+				h.subnegotiation(dir.sub)
+				dir.state = stateData
+			default:
+				// malformed, treat as end of subnegotiation
+				h.subnegotiation(dir.sub)
+				dir.state = stateData
+			}
+		default:
+			if b == iac {
+				dir.state = stateIAC
+
+				continue
+			}
+
+			h.addByte(dir, b, ts)
+		}
+	}
+}
+
+// addByte adds a data byte to the current line of the direction.
+func (h *telnetReader) addByte(dir *direction, b byte, ts time.Time) {
+	switch dir.state {
+	case stateCR:
+		dir.state = stateData
+		// CR LF and CR NUL both terminate the line that was ended by CR
+		if b == '\n' || b == 0 {
+			return
+		}
+	case stateEscape:
+		if b == '[' {
+			dir.state = stateCSI
+		} else {
+			dir.state = stateData
+		}
+
+		return
+	case stateCSI:
+		// skip parameters until the final byte of the control sequence
+		if b >= 0x40 && b <= 0x7e {
+			dir.state = stateData
+		}
+
+		return
+	}
+
+	switch {
+	case b == '\r':
+		h.flushLine(dir, ts)
+		dir.state = stateCR
+	case b == '\n':
+		h.flushLine(dir, ts)
+	case b == 0x08 || b == 0x7f:
+		// backspace and delete remove the last character
+		if len(dir.line) > 0 {
+			_, size := utf8.DecodeLastRune(dir.line)
+			dir.line = dir.line[:len(dir.line)-size]
+		}
+	case b == 0x1b:
+		dir.state = stateEscape
+	case b == '\t':
+		dir.line = append(dir.line, b)
+	case b < 0x20:
+		// show other control characters in caret notation, e.g. ^C
+		if dir.client {
+			dir.line = append(dir.line, '^', b+'@')
+		}
+	default:
+		dir.line = append(dir.line, b)
+	}
+}
+
+// flushLine adds the current line of the direction to the transcript.
+func (h *telnetReader) flushLine(dir *direction, ts time.Time) {
+	if len(dir.line) == 0 {
+		return
+	}
+
+	line := string(dir.line)
+	dir.line = dir.line[:0]
+
+	if dir.client && h.awaitingLogin {
+		h.awaitingLogin = false
+		if h.session.User == "" {
+			h.session.User = strings.TrimSpace(line)
+		}
+	}
+
+	h.session.Transcript = append(h.session.Transcript, &types.TelnetLine{
+		Timestamp: ts.UnixNano(),
+		IsClient:  dir.client,
+		Data:      line,
+	})
+}
+
+// checkLoginPrompt checks whether the server is waiting for the user name.
+func (h *telnetReader) checkLoginPrompt(server *direction) {
+	prompt := strings.ToLower(strings.TrimSpace(string(server.line)))
+	for _, p := range loginPrompts {
+		if strings.HasSuffix(prompt, p) {
+			h.awaitingLogin = true
+
+			return
+		}
+	}
+}
+
+// addOption records an option negotiation once per direction.
+func (h *telnetReader) addOption(dir *direction, code byte) {
+	o := &types.TelnetOption{
+		IsClient: dir.client,
+		Command:  commandNames[dir.verb],
+		Code:     int32(code),
+		Name:     optionNames[code],
+	}
+
+	key := side(dir.client) + " " + o.Command + " " + strconv.Itoa(int(code))
+
+	if _, ok := h.options[key]; ok {
+		return
+	}
+
+	h.options[key] = struct{}{}
+	h.session.Options = append(h.session.Options, o)
+}
+
+func side(client bool) string {
+	if client {
+		return "client"
+	}
+
+	return "server"
+}
+
+// subnegotiation extracts the values sent in the subnegotiation of an option.
+func (h *telnetReader) subnegotiation(data []byte) {
+	if len(data) < 2 {
+		return
+	}
+
+	var (
+		opt  = data[0]
+		cmd  = data[1]
+		args = data[2:]
+	)
+
+	switch opt {
+	case optWindowSize:
+		// NAWS has no command byte
+		if len(data) >= 5 {
+			h.session.WindowWidth = int32(binary.BigEndian.Uint16(data[1:3]))
+			h.session.WindowHeight = int32(binary.BigEndian.Uint16(data[3:5]))
+		}
+	case optTerminalType:
+		if cmd == subIs && h.session.TerminalType == "" {
+			h.session.TerminalType = string(args)
+		}
+	case optTerminalSpeed:
+		if cmd == subIs {
+			h.session.TerminalSpeed = string(args)
+		}
+	case optDisplayLocation:
+		if cmd == subIs {
+			h.session.DisplayLocation = string(args)
+		}
+	case optNewEnviron, optOldEnviron:
+		if cmd == subIs || cmd == subInfo {
+			h.addEnvironment(parseEnvironment(args, opt == optOldEnviron))
+		}
+	}
+}
+
+func (h *telnetReader) addEnvironment(vars [][2]string) {
+	for _, v := range vars {
+		h.session.Environment = append(h.session.Environment, v[0]+"="+v[1])
+
+		if v[0] == "USER" && v[1] != "" {
+			h.session.User = v[1]
+		}
+	}
+}
+
+// parseEnvironment parses the variables from an ENVIRON or NEW-ENVIRON subnegotiation.
+func parseEnvironment(data []byte, old bool) (vars [][2]string) {
+	var (
+		varCode   byte = envVar
+		valueCode byte = envValue
+		cur       *bytes.Buffer
+		name      bytes.Buffer
+		value     bytes.Buffer
+		inVar     bool
+	)
+
+	if old {
+		varCode, valueCode = envValue, envVar
+	}
+
+	flush := func() {
+		if inVar {
+			vars = append(vars, [2]string{name.String(), value.String()})
+		}
+
+		name.Reset()
+		value.Reset()
+	}
+
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; b {
+		case varCode, envUserVar:
+			flush()
+
+			inVar = true
+			cur = &name
+		case valueCode:
+			cur = &value
+		case envEsc:
+			if i+1 < len(data) && cur != nil {
+				i++
+				cur.WriteByte(data[i])
+			}
+		default:
+			if cur != nil {
+				cur.WriteByte(b)
+			}
+		}
+	}
+
+	flush()
+
+	return vars
+}
+
+// writeTelnet writes the telnet audit record.
+func writeTelnet(t *types.Telnet) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		t.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(t)
+	if err != nil {
+		telnetLog.Error("failed to write telnet record", zap.Error(err))
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
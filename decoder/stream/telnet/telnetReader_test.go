@@ -0,0 +1,134 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package telnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+func fragment(client bool, ts time.Time, data string) *core.StreamData {
+	dir := reassembly.TCPDirServerToClient
+	if client {
+		dir = reassembly.TCPDirClientToServer
+	}
+
+	return &core.StreamData{
+		RawData:            []byte(data),
+		Dir:                dir,
+		CaptureInformation: gopacket.CaptureInfo{Timestamp: ts},
+	}
+}
+
+func TestDecodeSession(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+		conv  = &core.ConversationInfo{
+			ClientIP:   "10.0.0.2",
+			ServerIP:   "10.0.0.1",
+			ClientPort: 41234,
+			ServerPort: 23,
+		}
+	)
+
+	conv.Data = append(conv.Data,
+		// server: DO TERMINAL-TYPE, DO NAWS, DO NEW-ENVIRON
+		fragment(false, start, "\xff\xfd\x18\xff\xfd\x1f\xff\xfd\x27"),
+		// client: WILL TERMINAL-TYPE, WILL NAWS, NAWS 80x24, WILL NEW-ENVIRON
+		fragment(true, start, "\xff\xfb\x18\xff\xfb\x1f\xff\xfa\x1f\x00\x50\x00\x18\xff\xf0\xff\xfb\x27"),
+		// client: TERMINAL-TYPE IS xterm, NEW-ENVIRON IS VAR USER VALUE root VAR DISPLAY VALUE :0
+		fragment(true, start, "\xff\xfa\x18\x00xterm\xff\xf0\xff\xfa\x27\x00\x00USER\x01root\x00DISPLAY\x01:0\xff\xf0"),
+		fragment(false, start.Add(time.Second), "\x1b[1mrouter\x1b[0m login: "),
+		fragment(true, start.Add(2*time.Second), "admin\r\n"),
+		fragment(false, start.Add(2*time.Second), "admin\r\nPassword: "),
+		fragment(true, start.Add(3*time.Second), "secret\r\x00"),
+		fragment(false, start.Add(3*time.Second), "\r\n# "),
+		fragment(true, start.Add(4*time.Second), "cat /etc/shadw\x7fow\r\n"),
+		fragment(false, start.Add(5*time.Second), "root:x:0:0\r\n# "),
+	)
+
+	r := &telnetReader{conversation: conv}
+	s := r.decodeSession()
+
+	if s.TerminalType != "xterm" || s.WindowWidth != 80 || s.WindowHeight != 24 {
+		t.Fatal("unexpected terminal: ", s.TerminalType, s.WindowWidth, s.WindowHeight)
+	}
+
+	if len(s.Environment) != 2 || s.Environment[0] != "USER=root" || s.Environment[1] != "DISPLAY=:0" {
+		t.Fatal("unexpected environment: ", s.Environment)
+	}
+
+	// the environment takes precedence over the login prompt
+	if s.User != "root" {
+		t.Fatal("unexpected user: ", s.User)
+	}
+
+	if len(s.Options) != 6 {
+		t.Fatal("expected 6 options, got: ", len(s.Options))
+	}
+
+	if s.Options[0].Command != "DO" || s.Options[0].Name != "TERMINAL-TYPE" || s.Options[0].IsClient {
+		t.Fatal("unexpected option: ", s.Options[0])
+	}
+
+	if s.Duration != (5 * time.Second).Nanoseconds() {
+		t.Fatal("unexpected duration: ", s.Duration)
+	}
+
+	var commands []string
+	for _, l := range s.Transcript {
+		if l.IsClient {
+			commands = append(commands, l.Data)
+		}
+	}
+
+	expected := []string{"admin", "secret", "cat /etc/shadow"}
+	if len(commands) != len(expected) {
+		t.Fatal("unexpected commands: ", commands)
+	}
+
+	for i, c := range expected {
+		if commands[i] != c {
+			t.Fatal("expected", c, "got", commands[i])
+		}
+	}
+
+	last := s.Transcript[len(s.Transcript)-1]
+	if last.IsClient || last.Data != "# " {
+		t.Fatal("expected trailing prompt, got: ", last)
+	}
+
+	// the client line is completed before the server echoes it
+	if s.Transcript[1].IsClient || s.Transcript[1].Data != "router login: admin" {
+		t.Fatal("unexpected server line: ", s.Transcript[1])
+	}
+}
+
+func TestLoginPrompt(t *testing.T) {
+	conv := &core.ConversationInfo{}
+	conv.Data = append(conv.Data,
+		fragment(false, time.Now(), "Username: "),
+		fragment(true, time.Now(), "cisco\r\n"),
+	)
+
+	s := (&telnetReader{conversation: conv}).decodeSession()
+	if s.User != "cisco" {
+		t.Fatal("unexpected user: ", s.User)
+	}
+}
@@ -0,0 +1,266 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package token
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// token types.
+const (
+	typeBearer  = "Bearer"
+	typeJWT     = "JWT"
+	typeAPIKey  = "APIKey"
+	typeSession = "Session"
+)
+
+// token sources.
+const (
+	sourceHeader    = "header"
+	sourceParameter = "parameter"
+	sourceCookie    = "cookie"
+)
+
+// authorization schemes that carry user credentials and are handled by the credentials decoder.
+var credentialSchemes = map[string]struct{}{
+	"basic":     {},
+	"digest":    {},
+	"ntlm":      {},
+	"negotiate": {},
+}
+
+// apiKeyHeaders contains headers commonly used to transmit API keys and access tokens, in lower case.
+var apiKeyHeaders = map[string]struct{}{
+	"x-api-key":                 {},
+	"x-apikey":                  {},
+	"api-key":                   {},
+	"apikey":                    {},
+	"x-auth-token":              {},
+	"x-access-token":            {},
+	"x-session-token":           {},
+	"x-amz-security-token":      {},
+	"x-goog-api-key":            {},
+	"x-functions-key":           {},
+	"ocp-apim-subscription-key": {},
+	"private-token":             {},
+	"x-vault-token":             {},
+	"x-consul-token":            {},
+	"x-shopify-access-token":    {},
+	"x-rapidapi-key":            {},
+}
+
+// apiKeyParameters contains query and form parameters commonly used to transmit API keys and access tokens, in lower case.
+var apiKeyParameters = map[string]struct{}{
+	"api_key":       {},
+	"apikey":        {},
+	"api-key":       {},
+	"key":           {},
+	"access_token":  {},
+	"accesstoken":   {},
+	"refresh_token": {},
+	"id_token":      {},
+	"auth_token":    {},
+	"authtoken":     {},
+	"token":         {},
+	"session_token": {},
+	"client_secret": {},
+	"jwt":           {},
+}
+
+// sessionCookies contains cookie names commonly used by web frameworks for session identifiers, in lower case.
+var sessionCookies = map[string]struct{}{
+	"phpsessid":           {},
+	"jsessionid":          {},
+	"asp.net_sessionid":   {},
+	"aspsessionid":        {},
+	".aspxauth":           {},
+	"cfid":                {},
+	"cftoken":             {},
+	"session":             {},
+	"sessionid":           {},
+	"session_id":          {},
+	"sid":                 {},
+	"connect.sid":         {},
+	"laravel_session":     {},
+	"ci_session":          {},
+	"_rails_session":      {},
+	"rack.session":        {},
+	"express:sess":        {},
+	"auth_token":          {},
+	"access_token":        {},
+	"token":               {},
+	"wordpress_logged_in": {},
+	"django_session":      {},
+	"_session_id":         {},
+	"sessid":              {},
+	"x-access-token":      {},
+	"remember_user_token": {},
+}
+
+// ExtractTokens collects all tokens from the HTTP request and writes them as audit records.
+// It must be invoked before the HTTP record is written, since serialization modifies the timestamp.
+func ExtractTokens(h *types.HTTP, ident string) {
+	for _, t := range Extract(h, ident) {
+		WriteToken(t)
+	}
+}
+
+// Extract returns all bearer tokens, API keys, session identifiers and JWTs found in the HTTP request.
+func Extract(h *types.HTTP, ident string) []*types.Token {
+	var (
+		tokens []*types.Token
+		now    = time.Unix(0, h.Timestamp)
+		add    = func(typ, source, name, value string) {
+			t := &types.Token{
+				Timestamp: h.Timestamp,
+				Type:      typ,
+				Source:    source,
+				Name:      name,
+				Value:     value,
+				Host:      h.Host,
+				URL:       h.URL,
+				ClientIP:  h.SrcIP,
+				ServerIP:  h.DstIP,
+				Flow:      ident,
+			}
+
+			if typ == typeJWT {
+				if err := parseJWT(value, t, now); err != nil {
+					t.Type = typeBearer
+				}
+			}
+
+			tokens = append(tokens, t)
+		}
+	)
+
+	// headers
+	for _, name := range sortedKeys(h.RequestHeader) {
+		var (
+			value = strings.TrimSpace(h.RequestHeader[name])
+			lower = strings.ToLower(name)
+		)
+
+		if value == "" {
+			continue
+		}
+
+		switch {
+		case lower == "authorization" || lower == "proxy-authorization":
+			scheme, credentials := splitScheme(value)
+			if _, ok := credentialSchemes[strings.ToLower(scheme)]; ok || credentials == "" {
+				continue
+			}
+
+			switch {
+			case regJWT.MatchString(credentials):
+				add(typeJWT, sourceHeader, name, regJWT.FindString(credentials))
+			case strings.EqualFold(scheme, "bearer"):
+				add(typeBearer, sourceHeader, name, credentials)
+			default:
+				add(typeAPIKey, sourceHeader, name, value)
+			}
+		case lower == "cookie":
+			// cookies are evaluated separately
+			continue
+		case isAPIKeyHeader(lower):
+			if regJWT.MatchString(value) {
+				add(typeJWT, sourceHeader, name, regJWT.FindString(value))
+			} else {
+				add(typeAPIKey, sourceHeader, name, value)
+			}
+		default:
+			for _, jwt := range regJWT.FindAllString(value, -1) {
+				add(typeJWT, sourceHeader, name, jwt)
+			}
+		}
+	}
+
+	// query and form parameters
+	for _, name := range sortedKeys(h.Parameters) {
+		value := strings.Trim(h.Parameters[name], "'")
+		if value == "" {
+			continue
+		}
+
+		if _, ok := apiKeyParameters[strings.ToLower(name)]; ok && !regJWT.MatchString(value) {
+			add(typeAPIKey, sourceParameter, name, value)
+
+			continue
+		}
+
+		for _, jwt := range regJWT.FindAllString(value, -1) {
+			add(typeJWT, sourceParameter, name, jwt)
+		}
+	}
+
+	// cookies
+	for _, c := range h.ReqCookies {
+		if c == nil || c.Value == "" {
+			continue
+		}
+
+		if regJWT.MatchString(c.Value) {
+			add(typeJWT, sourceCookie, c.Name, regJWT.FindString(c.Value))
+
+			continue
+		}
+
+		if isSessionCookie(strings.ToLower(c.Name)) {
+			add(typeSession, sourceCookie, c.Name, c.Value)
+		}
+	}
+
+	return tokens
+}
+
+// splitScheme splits an authorization header value into the scheme and the credentials.
+func splitScheme(value string) (scheme, credentials string) {
+	i := strings.IndexByte(value, ' ')
+	if i == -1 {
+		return "", value
+	}
+
+	return value[:i], strings.TrimSpace(value[i+1:])
+}
+
+func isAPIKeyHeader(lower string) bool {
+	_, ok := apiKeyHeaders[lower]
+
+	return ok
+}
+
+func isSessionCookie(lower string) bool {
+	if _, ok := sessionCookies[lower]; ok {
+		return true
+	}
+
+	// wordpress appends a hash to its cookie names
+	return strings.HasPrefix(lower, "wordpress_logged_in_") || strings.HasPrefix(lower, "wp_sess")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
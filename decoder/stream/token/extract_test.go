@@ -0,0 +1,122 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package token
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func makeJWT(header, claims string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + "."
+}
+
+func TestParseJWT(t *testing.T) {
+	raw := makeJWT(`{"alg":"HS256","typ":"JWT","kid":"k1"}`, `{"iss":"https://auth.example.com","sub":"alice","aud":["api","web"],"iat":1600000000,"exp":1600003600}`) + "c2lnbmF0dXJl"
+
+	var tok types.Token
+	if err := parseJWT(raw, &tok, time.Unix(1600007200, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if tok.Algorithm != "HS256" || tok.KeyID != "k1" || tok.Issuer != "https://auth.example.com" || tok.Subject != "alice" {
+		t.Fatalf("unexpected header or claims: %+v", tok)
+	}
+
+	if len(tok.Audience) != 2 || tok.Audience[0] != "api" || tok.Audience[1] != "web" {
+		t.Fatalf("unexpected audience: %v", tok.Audience)
+	}
+
+	if tok.IssuedAt != time.Unix(1600000000, 0).UnixNano() || tok.Expiry != time.Unix(1600003600, 0).UnixNano() {
+		t.Fatalf("unexpected dates: iat=%d exp=%d", tok.IssuedAt, tok.Expiry)
+	}
+
+	if !tok.Expired || tok.AlgNone {
+		t.Fatalf("expected expired token with signature: %+v", tok)
+	}
+
+	if err := parseJWT("eyJhbGciOiJub25lIn0", &tok, time.Time{}); err == nil {
+		t.Fatal("expected error for truncated token")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	var (
+		none = makeJWT(`{"alg":"none"}`, `{"sub":"admin","aud":"api"}`)
+		h    = &types.HTTP{
+			Timestamp: time.Unix(1600000000, 0).UnixNano(),
+			Host:      "api.example.com",
+			URL:       "/v1/users?api_key=secret",
+			SrcIP:     "10.0.0.1",
+			DstIP:     "10.0.0.2",
+			RequestHeader: map[string]string{
+				"Authorization": "Bearer " + none,
+				"X-Api-Key":     "abcdef",
+				"Accept":        "*/*",
+			},
+			Parameters: map[string]string{
+				"api_key": "secret",
+				"page":    "1",
+			},
+			ReqCookies: []*types.HTTPCookie{
+				{Name: "PHPSESSID", Value: "deadbeef"},
+				{Name: "theme", Value: "dark"},
+			},
+		}
+		proxy = &types.HTTP{
+			RequestHeader: map[string]string{
+				"Authorization":       "Basic dXNlcjpwYXNz",
+				"Proxy-Authorization": "Bearer opaque-token",
+			},
+		}
+	)
+
+	tokens := Extract(h, "flow")
+	if len(tokens) != 4 {
+		t.Fatalf("expected 4 tokens, got %d: %+v", len(tokens), tokens)
+	}
+
+	expected := []struct {
+		typ, source, name, value string
+	}{
+		{typeJWT, sourceHeader, "Authorization", none},
+		{typeAPIKey, sourceHeader, "X-Api-Key", "abcdef"},
+		{typeAPIKey, sourceParameter, "api_key", "secret"},
+		{typeSession, sourceCookie, "PHPSESSID", "deadbeef"},
+	}
+
+	for i, e := range expected {
+		tok := tokens[i]
+		if tok.Type != e.typ || tok.Source != e.source || tok.Name != e.name || tok.Value != e.value {
+			t.Fatalf("token %d: expected %+v, got %+v", i, e, tok)
+		}
+
+		if tok.Host != h.Host || tok.ClientIP != h.SrcIP || tok.ServerIP != h.DstIP || tok.Flow != "flow" {
+			t.Fatalf("token %d: missing connection context: %+v", i, tok)
+		}
+	}
+
+	if !tokens[0].AlgNone || tokens[0].Subject != "admin" || len(tokens[0].Audience) != 1 || tokens[0].Audience[0] != "api" {
+		t.Fatalf("unexpected JWT details: %+v", tokens[0])
+	}
+
+	tokens = Extract(proxy, "flow")
+	if len(tokens) != 1 || tokens[0].Type != typeBearer || tokens[0].Value != "opaque-token" {
+		t.Fatalf("expected single opaque bearer token, got %+v", tokens)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package token
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// regJWT matches compact serialized JSON web tokens.
+// Both the header and the claims are JSON objects and therefore start with eyJ when base64 encoded,
+// the signature part is empty for unsecured tokens.
+var regJWT = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+
+var errInvalidJWT = errors.New("invalid JWT")
+
+// jwtHeader contains the fields of interest from the JOSE header.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jwtClaims contains the registered claims of interest.
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	IssuedAt  json.Number     `json:"iat"`
+	NotBefore json.Number     `json:"nbf"`
+	Expiry    json.Number     `json:"exp"`
+}
+
+// decodeSegment decodes a base64url encoded JWT segment.
+// Padding is not allowed by the spec, but tolerated since some implementations emit it.
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// parseJWT decodes the header and claims of the JWT and populates the token record.
+// The signature is not verified.
+func parseJWT(raw string, t *types.Token, now time.Time) error {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return errInvalidJWT
+	}
+
+	header, err := decodeSegment(parts[0])
+	if err != nil {
+		return err
+	}
+
+	claims, err := decodeSegment(parts[1])
+	if err != nil {
+		return err
+	}
+
+	var h jwtHeader
+	if err = json.Unmarshal(header, &h); err != nil {
+		return err
+	}
+
+	var c jwtClaims
+	dec := json.NewDecoder(bytes.NewReader(claims))
+	dec.UseNumber()
+
+	if err = dec.Decode(&c); err != nil {
+		return err
+	}
+
+	t.JWTHeader = string(header)
+	t.JWTClaims = string(claims)
+	t.Algorithm = h.Algorithm
+	t.KeyID = h.KeyID
+	t.Issuer = c.Issuer
+	t.Subject = c.Subject
+	t.Audience = parseAudience(c.Audience)
+	t.IssuedAt = numericDate(c.IssuedAt)
+	t.NotBefore = numericDate(c.NotBefore)
+	t.Expiry = numericDate(c.Expiry)
+	t.AlgNone = strings.EqualFold(h.Algorithm, "none")
+	t.Expired = t.Expiry != 0 && !now.IsZero() && now.UnixNano() > t.Expiry
+
+	return nil
+}
+
+// parseAudience handles the aud claim, which can be either a single string or an array of strings.
+func parseAudience(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err == nil {
+		return multiple
+	}
+
+	return nil
+}
+
+// numericDate converts a JWT NumericDate (seconds since epoch, possibly fractional) to unix nanoseconds.
+func numericDate(n json.Number) int64 {
+	if n == "" {
+		return 0
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return 0
+	}
+
+	return int64(f * float64(time.Second))
+}
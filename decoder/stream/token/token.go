@@ -0,0 +1,62 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package token
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.AbstractDecoder{
+	Type:        types.Type_NC_Token,
+	Name:        "Token",
+	Description: "A session token, API key or JSON web token transmitted in an HTTP request",
+}
+
+var (
+	// tokenStore is used to deduplicate tokens that are sent repeatedly to the same host.
+	tokenStore   = make(map[string]struct{})
+	tokenStoreMu sync.Mutex
+)
+
+// WriteToken writes the token audit record, each token is only written once per host.
+func WriteToken(t *types.Token) {
+	id := t.Type + t.Name + t.Value + t.Host
+
+	tokenStoreMu.Lock()
+	if _, ok := tokenStore[id]; ok {
+		tokenStoreMu.Unlock()
+
+		return
+	}
+	tokenStore[id] = struct{}{}
+	tokenStoreMu.Unlock()
+
+	if decoderconfig.Instance.ExportMetrics {
+		t.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(t)
+	if err != nil {
+		log.Fatal("failed to write proto: ", err)
+	}
+}
@@ -0,0 +1,225 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	gzip "github.com/klauspost/pgzip"
+)
+
+// maxDecodedSize limits the size of decoded content,
+// since a few kilobytes of compressed data can expand to gigabytes.
+var maxDecodedSize = 256 << 20
+
+var (
+	errUnknownEncoding  = errors.New("unknown content encoding")
+	errInvalidChunk     = errors.New("invalid chunk size")
+	errDecodedSizeLimit = errors.New("decoded content exceeds size limit")
+
+	// encodings that do not alter the data
+	identityEncodings = map[string]struct{}{
+		"":         {},
+		"identity": {},
+		"7bit":     {},
+		"8bit":     {},
+		"binary":   {},
+	}
+)
+
+// normalizeEncodings flattens the provided header values into a list of lower case encodings,
+// e.g. []string{"gzip, br"} becomes []string{"gzip", "br"}. Identity encodings are omitted.
+func normalizeEncodings(encoding []string) []string {
+	var out []string
+
+	for _, value := range encoding {
+		for _, e := range strings.Split(value, ",") {
+			e = strings.ToLower(strings.TrimSpace(e))
+			if _, ok := identityEncodings[e]; ok {
+				continue
+			}
+
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// DecodeContent reverses the content encodings from the provided header values.
+// If decoding fails, the data decoded so far is returned along with the error.
+func DecodeContent(body []byte, encoding []string) ([]byte, error) {
+	return decodeContent(body, normalizeEncodings(encoding))
+}
+
+// decodeContent reverses the encodings applied to body.
+// Encodings are listed in the order they were applied, so they are decoded in reverse order.
+// If decoding fails, the data decoded so far is returned along with the error.
+func decodeContent(body []byte, encodings []string) ([]byte, error) {
+	var err error
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		body, err = decodeEncoding(body, encodings[i])
+		if err != nil {
+			return body, err
+		}
+	}
+
+	return body, nil
+}
+
+// decodeEncoding reverses a single content or transfer encoding.
+func decodeEncoding(data []byte, encoding string) ([]byte, error) {
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		var gr *gzip.Reader
+
+		gr, err = gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return data, err
+		}
+
+		defer gr.Close()
+
+		r = gr
+	case "deflate":
+		r, err = newDeflateReader(data)
+		if err != nil {
+			return data, err
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(data))
+	case "zstd":
+		var zr *zstd.Decoder
+
+		zr, err = zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return data, err
+		}
+
+		defer zr.Close()
+
+		r = zr
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data))
+	case "quoted-printable":
+		r = quotedprintable.NewReader(bytes.NewReader(data))
+	case "chunked":
+		return decodeChunked(data)
+	default:
+		return data, errUnknownEncoding
+	}
+
+	out, err := ioutil.ReadAll(io.LimitReader(r, int64(maxDecodedSize)+1))
+	if err != nil && len(out) == 0 {
+		// nothing could be decoded, keep the data as it is
+		return data, err
+	}
+
+	if len(out) > maxDecodedSize {
+		return out[:maxDecodedSize], errDecodedSizeLimit
+	}
+
+	return out, err
+}
+
+// newDeflateReader returns a reader for deflate encoded data.
+// The deflate content coding is defined as zlib format, but many servers send raw deflate data instead.
+func newDeflateReader(data []byte) (io.Reader, error) {
+	if len(data) >= 2 && data[0]&0x0f == 8 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0 {
+		return zlib.NewReader(bytes.NewReader(data))
+	}
+
+	return flate.NewReader(bytes.NewReader(data)), nil
+}
+
+// decodeChunked decodes data with the chunked transfer coding.
+func decodeChunked(data []byte) ([]byte, error) {
+	return DecodeChunked(bufio.NewReader(bytes.NewReader(data)))
+}
+
+// DecodeChunked decodes a chunked message body from the reader, including the trailers,
+// so that a following message can be read from the same reader.
+// Chunk extensions and trailers are ignored, a truncated last chunk is returned as far as available.
+// Bare line feeds are tolerated in place of CRLF.
+func DecodeChunked(r *bufio.Reader) ([]byte, error) {
+	var out bytes.Buffer
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return out.Bytes(), io.ErrUnexpectedEOF
+		}
+
+		// strip chunk extensions
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+
+		size, err := strconv.ParseUint(strings.TrimSpace(line), 16, 63)
+		if err != nil {
+			return out.Bytes(), errInvalidChunk
+		}
+
+		if size == 0 {
+			// last chunk, skip the trailers up to the empty line
+			for {
+				trailer, errTrailer := r.ReadString('\n')
+				if errTrailer != nil || strings.TrimSpace(trailer) == "" {
+					return out.Bytes(), nil
+				}
+			}
+		}
+
+		if size > uint64(maxDecodedSize-out.Len()) {
+			return out.Bytes(), errDecodedSizeLimit
+		}
+
+		n, err := io.CopyN(&out, r, int64(size))
+		if err != nil || n != int64(size) {
+			return out.Bytes(), io.ErrUnexpectedEOF
+		}
+
+		// consume the CRLF after the chunk data, tolerate a bare LF
+		b, err := r.ReadByte()
+		if err == nil && b == '\r' {
+			b, err = r.ReadByte()
+		}
+
+		if err != nil {
+			return out.Bytes(), io.ErrUnexpectedEOF
+		}
+
+		if b != '\n' {
+			return out.Bytes(), errInvalidChunk
+		}
+	}
+}
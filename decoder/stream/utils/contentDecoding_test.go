@@ -0,0 +1,146 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+var testContent = []byte("<html><body>" + string(bytes.Repeat([]byte("netcap "), 100)) + "</body></html>")
+
+func compress(t *testing.T, data []byte, newWriter func(w io.Writer) io.WriteCloser) []byte {
+	var buf bytes.Buffer
+
+	w := newWriter(&buf)
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func gzipWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func brotliWriter(w io.Writer) io.WriteCloser {
+	return brotli.NewWriter(w)
+}
+
+func zlibWriter(w io.Writer) io.WriteCloser {
+	return zlib.NewWriter(w)
+}
+
+func flateWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+func zstdWriter(w io.Writer) io.WriteCloser {
+	zw, _ := zstd.NewWriter(w)
+	return zw
+}
+
+func TestDecodeContent(t *testing.T) {
+	for _, test := range []struct {
+		encoding []string
+		writers  []func(w io.Writer) io.WriteCloser
+	}{
+		{[]string{"gzip"}, []func(w io.Writer) io.WriteCloser{gzipWriter}},
+		{[]string{"br"}, []func(w io.Writer) io.WriteCloser{brotliWriter}},
+		{[]string{"zstd"}, []func(w io.Writer) io.WriteCloser{zstdWriter}},
+		{[]string{"deflate"}, []func(w io.Writer) io.WriteCloser{zlibWriter}},
+		{[]string{"deflate"}, []func(w io.Writer) io.WriteCloser{flateWriter}},
+		{[]string{"gzip, BR"}, []func(w io.Writer) io.WriteCloser{gzipWriter, brotliWriter}},
+		{[]string{"zstd", "identity", "gzip"}, []func(w io.Writer) io.WriteCloser{zstdWriter, gzipWriter}},
+	} {
+		data := testContent
+		for _, w := range test.writers {
+			data = compress(t, data, w)
+		}
+
+		decoded, err := DecodeContent(data, test.encoding)
+		if err != nil {
+			t.Fatal(test.encoding, err)
+		}
+
+		if !bytes.Equal(decoded, testContent) {
+			t.Fatal("unexpected result for", test.encoding)
+		}
+	}
+
+	if _, err := DecodeContent(testContent, []string{"unknown"}); err != errUnknownEncoding {
+		t.Fatal("expected error for unknown encoding, got", err)
+	}
+
+	if decoded, err := DecodeContent(testContent, []string{"7bit"}); err != nil || !bytes.Equal(decoded, testContent) {
+		t.Fatal("identity encoding should not change the data")
+	}
+}
+
+func TestDecodeChunked(t *testing.T) {
+	decoded, err := decodeChunked([]byte("4;ext=1\r\nWiki\r\n5\r\npedia\r\nE\r\n in\r\n\r\nchunks.\r\n0\r\nExpires: never\r\n\r\n"))
+	if err != nil || string(decoded) != "Wikipedia in\r\n\r\nchunks." {
+		t.Fatal("unexpected result: ", string(decoded), err)
+	}
+
+	// bare line feeds are tolerated
+	decoded, err = decodeChunked([]byte("3\nabc\n0\n"))
+	if err != nil || string(decoded) != "abc" {
+		t.Fatal("unexpected result: ", string(decoded), err)
+	}
+
+	// truncated data is returned as far as available
+	decoded, err = decodeChunked([]byte("4\r\nWiki\r\n10\r\nped"))
+	if err != io.ErrUnexpectedEOF || string(decoded) != "Wikiped" {
+		t.Fatal("unexpected result for truncated data: ", string(decoded), err)
+	}
+
+	if _, err = decodeChunked([]byte("zz\r\n")); err != errInvalidChunk {
+		t.Fatal("expected invalid chunk error, got", err)
+	}
+}
+
+func TestDecodeSizeLimit(t *testing.T) {
+	defer func(size int) {
+		maxDecodedSize = size
+	}(maxDecodedSize)
+
+	maxDecodedSize = 1024
+
+	bomb := compress(t, make([]byte, 1<<20), gzipWriter)
+
+	decoded, err := DecodeContent(bomb, []string{"gzip"})
+	if err != errDecodedSizeLimit || len(decoded) != maxDecodedSize {
+		t.Fatal("expected decoded content to be limited: ", len(decoded), err)
+	}
+
+	decoded, err = decodeChunked([]byte("200\r\n" + string(make([]byte, 512)) + "\r\n801\r\n"))
+	if err != errDecodedSizeLimit || len(decoded) != 512 {
+		t.Fatal("expected chunked content to be limited: ", len(decoded), err)
+	}
+}
@@ -14,11 +14,7 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/base64"
 	"encoding/hex"
-	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -28,7 +24,6 @@ import (
 	"strings"
 
 	"github.com/dreadl0ck/cryptoutils"
-	gzip "github.com/klauspost/pgzip"
 	"go.uber.org/zap"
 
 	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
@@ -48,6 +43,27 @@ func SetLogger(l *zap.Logger) {
 
 // SaveFile TODO: create a structure for passing all the args
 func SaveFile(conv *core.ConversationInfo, source, name string, err error, body []byte, encoding []string, host string, contentType string) error {
+	return saveFile(conv, source, name, err, body, encoding, host, contentType, nil)
+}
+
+// SaveUploadedFile saves a file that was uploaded by the client, e.g. in a multipart form,
+// along with the name of the form field and the file name provided by the client.
+func SaveUploadedFile(conv *core.ConversationInfo, source, field, filename string, body []byte, host string, contentType string) error {
+	// the client file name may contain a windows or unix path
+	name := filename
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return saveFile(conv, source, name, nil, body, nil, host, contentType, func(f *types.File) {
+		f.FormField = field
+		f.ClientFilename = filename
+	})
+}
+
+// saveFile writes the body to disk and writes the file audit record.
+// If update is not nil, it will be called to set additional fields on the record.
+func saveFile(conv *core.ConversationInfo, source, name string, err error, body []byte, encoding []string, host string, contentType string, update func(f *types.File)) error {
 	reassemblyLog.Info("smtpReader.saveFile",
 		zap.String("source", source),
 		zap.String("name", name),
@@ -67,8 +83,25 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 	}
 
 	var (
-		fileName string
+		fileName  string
+		encodings = normalizeEncodings(encoding)
+		rawLength = len(body)
+	)
 
+	// reverse the content encodings, if that fails the data is stored as it is
+	decoded, errDecode := decodeContent(body, encodings)
+	if errDecode != nil {
+		reassemblyLog.Error(
+			"failed to decode content",
+			zap.String("ident", conv.Ident),
+			zap.Strings("encoding", encodings),
+			zap.Error(errDecode),
+		)
+	} else {
+		body = decoded
+	}
+
+	var (
 		// detected content type
 		cType = trimEncoding(http.DetectContentType(body))
 
@@ -93,13 +126,7 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 	}
 
 	// make sure root path exists
-	err = os.MkdirAll(root, defaults.DirectoryPermission)
-	if err != nil {
-		reassemblyLog.Error("failed to create directory",
-			zap.String("path", root),
-			zap.Int("perm", defaults.DirectoryPermission),
-		)
-	}
+	createContentTypePathIfRequired(root)
 
 	base = path.Join(root, base)
 
@@ -123,9 +150,9 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 		}
 
 		if err != nil {
-			target = path.Join(root, filepath.Clean("incomplete-"+name+"-"+utils.CleanIdent(conv.Ident))+"-"+strconv.Itoa(n)+file.ExtensionForContentType(cType))
+			target = path.Join(root, filepath.Clean("incomplete-"+name+"-"+utils.CleanIdent(conv.Ident))+"-"+strconv.Itoa(n)+ext)
 		} else {
-			target = path.Join(root, filepath.Clean(name+"-"+utils.CleanIdent(conv.Ident))+"-"+strconv.Itoa(n)+file.ExtensionForContentType(cType))
+			target = path.Join(root, filepath.Clean(name+"-"+utils.CleanIdent(conv.Ident))+"-"+strconv.Itoa(n)+ext)
 		}
 
 		n++
@@ -133,10 +160,10 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 
 	// fmt.Println("saving file:", target)
 
-	f, err := os.Create(target)
+	err = ioutil.WriteFile(target, body, defaults.FilePermission)
 	if err != nil {
 		reassemblyLog.Error(
-			"failed to create file",
+			"failed to save file",
 			zap.String("ident", conv.Ident),
 			zap.String("target", target),
 			zap.Error(err),
@@ -145,132 +172,46 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 		return err
 	}
 
-	// explicitly declare io.Reader interface
-	var (
-		r             io.Reader
-		length        int
-		hash          string
-		cTypeDetected = trimEncoding(http.DetectContentType(body))
+	reassemblyLog.Debug(
+		"saved file",
+		zap.String("ident", conv.Ident),
+		zap.String("target", target),
+		zap.Int("bytesWritten", len(body)),
 	)
 
-	// now assign a new buffer
-	r = bytes.NewBuffer(body)
-
-	// Decode gzip
-	if len(encoding) > 0 && (encoding[0] == "gzip" || encoding[0] == "deflate") {
-		r, err = gzip.NewReader(r)
-		if err != nil {
-			reassemblyLog.Error(
-				"failed to gzip decode",
-				zap.String("ident", conv.Ident),
-				zap.Error(err),
-			)
-		}
-	}
-
-	// Decode base64
-	if len(encoding) > 0 && (encoding[0] == "base64") {
-		r, _ = base64.NewDecoder(base64.StdEncoding, r).(io.Reader)
-	}
-
-	if err == nil {
-		w, errCopy := io.Copy(f, r)
-		if errCopy != nil {
-			reassemblyLog.Error(
-				"failed to save file",
-				zap.String("ident", conv.Ident),
-				zap.String("target", target),
-				zap.Int64("bytesWritten", w),
-				zap.Error(errCopy),
-			)
-		} else {
-			reassemblyLog.Debug(
-				"saved file",
-				zap.String("ident", conv.Ident),
-				zap.String("target", target),
-				zap.Int64("bytesWritten", w),
-			)
-		}
-
-		if _, ok := r.(*gzip.Reader); ok {
-			errClose := r.(*gzip.Reader).Close()
-			if errClose != nil {
-				reassemblyLog.Error(
-					"failed to close gzip reader",
-					zap.String("ident", conv.Ident),
-					zap.String("target", target),
-					zap.Error(errClose),
-				)
-			}
-		}
-
-		errClose := f.Close()
-		if errClose != nil {
-			reassemblyLog.Error(
-				"failed to close file handle",
-				zap.String("ident", conv.Ident),
-				zap.String("target", target),
-				zap.Error(errClose),
-			)
-		}
-
-		// TODO: refactor to avoid reading the file contents into memory again
-		body, err = ioutil.ReadFile(target)
-		if err == nil {
-			// set hash to value for decompressed content and update size
-			hash = hex.EncodeToString(cryptoutils.MD5Data(body))
-			length = len(body)
-
-			// update content type
-			cTypeDetected = trimEncoding(http.DetectContentType(body))
-
-			// make sure root path exists
-			createContentTypePathIfRequired(path.Join(decoderconfig.Instance.Out, decoderconfig.Instance.FileStorage, cTypeDetected))
-
-			// switch the file extension and the path for the updated content type
-			ext = filepath.Ext(target)
-
-			// create new target: trim extension from old one and replace
-			// and replace the old content type in the path
-			newTarget := strings.Replace(strings.TrimSuffix(target, ext), cType, cTypeDetected, 1) + file.ExtensionForContentType(cTypeDetected)
-
-			err = os.Rename(target, newTarget)
-			if err == nil {
-				target = newTarget
-			} else {
-				fmt.Println("failed to rename file after decompression", err)
-			}
-		}
-	} else {
-		hash = hex.EncodeToString(cryptoutils.MD5Data(body))
-		length = len(body)
-	}
-
-	// set the value for the provided content type to the value from the first content type detection
+	// set the value for the provided content type to the value from the content type detection
 	// if none was provided
 	if contentType == "" {
 		contentType = cType
 	}
 
-	// write file to disk
-	file.WriteFile(&types.File{
+	record := &types.File{
 		// TODO: use the actual timestamp when file has been transferred
 		Timestamp:           conv.FirstClientPacket.UnixNano(),
 		Name:                fileName,
-		Length:              int64(length),
-		Hash:                hash,
+		Length:              int64(len(body)),
+		RawLength:           int64(rawLength),
+		ContentEncoding:     encodings,
+		Hash:                hex.EncodeToString(cryptoutils.MD5Data(body)),
 		Location:            target,
 		Ident:               conv.Ident,
 		Source:              source,
 		ContentType:         contentType,
-		ContentTypeDetected: cTypeDetected,
+		ContentTypeDetected: cType,
 		// TODO: set the actual flow direction of the file, not the one of the connection
 		SrcIP:   conv.ClientIP,
 		DstIP:   conv.ServerIP,
 		SrcPort: conv.ServerPort,
 		DstPort: conv.ClientPort,
 		Host:    host,
-	})
+	}
+
+	if update != nil {
+		update(record)
+	}
+
+	// write file to disk
+	file.WriteFile(record)
 
 	return nil
 }
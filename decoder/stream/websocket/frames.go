@@ -0,0 +1,311 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// Opcodes, see RFC 6455 section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	finalBit = 0x80
+	rsv1Bit  = 0x40
+	maskBit  = 0x80
+
+	// size of the LZ77 window used by permessage-deflate
+	deflateWindowSize = 32768
+)
+
+var (
+	opcodeNames = map[int32]string{
+		opContinuation: "Continuation",
+		opText:         "Text",
+		opBinary:       "Binary",
+		opClose:        "Close",
+		opPing:         "Ping",
+		opPong:         "Pong",
+	}
+
+	// permessage-deflate strips the final empty block of each message,
+	// restore it and add a final stored block to terminate the stream.
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+	errFrameTruncated = errors.New("truncated websocket frame")
+	errContinuation   = errors.New("websocket continuation frame without message")
+)
+
+// Extensions contains the negotiated permessage-deflate parameters.
+type Extensions struct {
+	Deflate                 bool
+	ClientNoContextTakeover bool
+	ServerNoContextTakeover bool
+}
+
+// ParseExtensions parses the Sec-WebSocket-Extensions header of the handshake response.
+func ParseExtensions(header string) (e Extensions) {
+	for _, ext := range strings.Split(header, ",") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+
+		e.Deflate = true
+
+		for _, p := range params[1:] {
+			switch strings.TrimSpace(p) {
+			case "client_no_context_takeover":
+				e.ClientNoContextTakeover = true
+			case "server_no_context_takeover":
+				e.ServerNoContextTakeover = true
+			}
+		}
+	}
+
+	return e
+}
+
+// Reader decodes the frames sent in one direction of a websocket connection.
+type Reader struct {
+	client          bool
+	deflate         bool
+	contextTakeover bool
+	includePayloads bool
+
+	// previously decompressed data, used as dictionary when the context is taken over
+	window []byte
+
+	// message that is currently reassembled from fragments
+	msg     *types.WebSocketMessage
+	payload []byte
+}
+
+// NewReader returns a reader for the client or server side of a connection.
+func NewReader(client bool, ext Extensions, includePayloads bool) *Reader {
+	noContextTakeover := ext.ServerNoContextTakeover
+	if client {
+		noContextTakeover = ext.ClientNoContextTakeover
+	}
+
+	return &Reader{
+		client:          client,
+		deflate:         ext.Deflate,
+		contextTakeover: !noContextTakeover,
+		includePayloads: includePayloads,
+	}
+}
+
+// Decode parses the frames in data and returns the reassembled messages.
+// timeAt is used to look up the timestamp for the frame at an offset.
+// Messages decoded before an error occurred are returned along with the error.
+func (r *Reader) Decode(data []byte, timeAt func(offset int) time.Time) ([]*types.WebSocketMessage, error) {
+	var (
+		messages []*types.WebSocketMessage
+		offset   int
+	)
+
+	for offset < len(data) {
+		if len(data)-offset < 2 {
+			return messages, errFrameTruncated
+		}
+
+		var (
+			b0         = data[offset]
+			b1         = data[offset+1]
+			opcode     = int32(b0 & 0x0f)
+			masked     = b1&maskBit != 0
+			length     = uint64(b1 & 0x7f)
+			headerSize = 2
+		)
+
+		switch length {
+		case 126:
+			if len(data)-offset < 4 {
+				return messages, errFrameTruncated
+			}
+
+			length = uint64(binary.BigEndian.Uint16(data[offset+2:]))
+			headerSize = 4
+		case 127:
+			if len(data)-offset < 10 {
+				return messages, errFrameTruncated
+			}
+
+			length = binary.BigEndian.Uint64(data[offset+2:])
+			headerSize = 10
+		}
+
+		var key []byte
+		if masked {
+			if len(data)-offset < headerSize+4 {
+				return messages, errFrameTruncated
+			}
+
+			key = data[offset+headerSize : offset+headerSize+4]
+			headerSize += 4
+		}
+
+		if length > uint64(len(data)-offset-headerSize) {
+			return messages, errFrameTruncated
+		}
+
+		var (
+			ts      = timeAt(offset)
+			payload = make([]byte, length)
+		)
+
+		copy(payload, data[offset+headerSize:])
+		offset += headerSize + int(length)
+
+		if masked {
+			for i := range payload {
+				payload[i] ^= key[i%4]
+			}
+		}
+
+		// control frames are never fragmented and may be sent in between the fragments of a message
+		if opcode >= opClose {
+			messages = append(messages, r.control(opcode, payload, masked, ts))
+
+			continue
+		}
+
+		if opcode == opContinuation {
+			if r.msg == nil {
+				return messages, errContinuation
+			}
+		} else {
+			r.msg = &types.WebSocketMessage{
+				Timestamp:  ts.UnixNano(),
+				IsClient:   r.client,
+				Opcode:     opcode,
+				OpcodeName: opcodeName(opcode),
+				Compressed: r.deflate && b0&rsv1Bit != 0,
+				Masked:     masked,
+			}
+			r.payload = nil
+		}
+
+		r.msg.Fragments++
+		r.msg.WireSize += int64(length)
+		r.payload = append(r.payload, payload...)
+
+		if b0&finalBit != 0 {
+			messages = append(messages, r.finish())
+		}
+	}
+
+	return messages, nil
+}
+
+// finish decompresses the reassembled message if required.
+func (r *Reader) finish() *types.WebSocketMessage {
+	var (
+		m       = r.msg
+		payload = r.payload
+	)
+
+	r.msg = nil
+	r.payload = nil
+
+	if m.Compressed {
+		p, err := r.inflate(payload)
+		if err == nil {
+			payload = p
+		} else {
+			m.Compressed = false
+		}
+	}
+
+	m.PayloadSize = int64(len(payload))
+
+	if r.includePayloads {
+		m.Payload = payload
+	}
+
+	return m
+}
+
+// inflate decompresses a message compressed with permessage-deflate, see RFC 7692.
+func (r *Reader) inflate(payload []byte) ([]byte, error) {
+	var dict []byte
+	if r.contextTakeover {
+		dict = r.window
+	}
+
+	fr := flate.NewReaderDict(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)), dict)
+
+	out, err := ioutil.ReadAll(fr)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.contextTakeover {
+		r.window = append(r.window, out...)
+		if len(r.window) > deflateWindowSize {
+			r.window = append([]byte{}, r.window[len(r.window)-deflateWindowSize:]...)
+		}
+	}
+
+	return out, nil
+}
+
+// control creates the message for a control frame.
+func (r *Reader) control(opcode int32, payload []byte, masked bool, ts time.Time) *types.WebSocketMessage {
+	m := &types.WebSocketMessage{
+		Timestamp:   ts.UnixNano(),
+		IsClient:    r.client,
+		Opcode:      opcode,
+		OpcodeName:  opcodeName(opcode),
+		Fragments:   1,
+		Masked:      masked,
+		WireSize:    int64(len(payload)),
+		PayloadSize: int64(len(payload)),
+	}
+
+	// the close frame may contain a status code followed by a reason
+	if opcode == opClose && len(payload) >= 2 {
+		m.CloseCode = int32(binary.BigEndian.Uint16(payload))
+		m.CloseReason = string(payload[2:])
+	}
+
+	if r.includePayloads {
+		m.Payload = payload
+	}
+
+	return m
+}
+
+func opcodeName(opcode int32) string {
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+
+	return "Reserved"
+}
@@ -0,0 +1,150 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func frame(b0 byte, payload []byte, mask []byte) []byte {
+	var out []byte
+
+	b1 := byte(0)
+	if mask != nil {
+		b1 = maskBit
+	}
+
+	switch {
+	case len(payload) < 126:
+		out = append(out, b0, b1|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		out = append(out, b0, b1|126, 0, 0)
+		binary.BigEndian.PutUint16(out[2:], uint16(len(payload)))
+	default:
+		out = append(out, b0, b1|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(out[2:], uint64(len(payload)))
+	}
+
+	if mask == nil {
+		return append(out, payload...)
+	}
+
+	out = append(out, mask...)
+	for i, b := range payload {
+		out = append(out, b^mask[i%4])
+	}
+
+	return out
+}
+
+func now(int) time.Time {
+	return time.Unix(1600000000, 0)
+}
+
+func TestDecodeFragmentedMaskedMessage(t *testing.T) {
+	var (
+		mask = []byte{0x37, 0xfa, 0x21, 0x3d}
+		big  = bytes.Repeat([]byte("A"), 300)
+		data []byte
+	)
+
+	data = append(data, frame(opText, []byte("Hel"), mask)...)
+	data = append(data, frame(finalBit|opPing, []byte("ping"), mask)...)
+	data = append(data, frame(finalBit|opContinuation, []byte("lo"), mask)...)
+	data = append(data, frame(finalBit|opBinary, big, mask)...)
+	data = append(data, frame(finalBit|opClose, append([]byte{0x03, 0xe8}, "bye"...), mask)...)
+
+	messages, err := NewReader(true, Extensions{}, true).Decode(data, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 4 {
+		t.Fatal("expected 4 messages, got", len(messages))
+	}
+
+	if messages[0].OpcodeName != "Ping" || string(messages[0].Payload) != "ping" {
+		t.Fatal("unexpected control frame: ", messages[0])
+	}
+
+	m := messages[1]
+	if m.OpcodeName != "Text" || string(m.Payload) != "Hello" || m.Fragments != 2 || !m.Masked || !m.IsClient {
+		t.Fatal("unexpected message: ", m)
+	}
+
+	if messages[2].PayloadSize != 300 || !bytes.Equal(messages[2].Payload, big) {
+		t.Fatal("unexpected binary message size: ", messages[2].PayloadSize)
+	}
+
+	if messages[3].CloseCode != 1000 || messages[3].CloseReason != "bye" {
+		t.Fatal("unexpected close frame: ", messages[3])
+	}
+
+	// truncated frames are reported, decoded messages are returned nevertheless
+	messages, err = NewReader(true, Extensions{}, false).Decode(data[:len(data)-2], now)
+	if err != errFrameTruncated || len(messages) != 3 || messages[0].Payload != nil {
+		t.Fatal("unexpected result for truncated data: ", err, len(messages))
+	}
+}
+
+func TestDecodeDeflate(t *testing.T) {
+	ext := ParseExtensions("permessage-deflate; client_max_window_bits, foo")
+	if !ext.Deflate || ext.ClientNoContextTakeover || ext.ServerNoContextTakeover {
+		t.Fatal("unexpected extensions: ", ext)
+	}
+
+	var (
+		buf  bytes.Buffer
+		data []byte
+	)
+
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second message references the window of the first one
+	for _, msg := range []string{"{\"cmd\":\"whoami\"}", "{\"cmd\":\"whoami\"}"} {
+		buf.Reset()
+
+		_, _ = w.Write([]byte(msg))
+		_ = w.Flush()
+
+		compressed := bytes.TrimSuffix(buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff})
+		data = append(data, frame(finalBit|rsv1Bit|opText, compressed, nil)...)
+	}
+
+	messages, err := NewReader(false, ext, true).Decode(data, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatal("expected 2 messages, got", len(messages))
+	}
+
+	for _, m := range messages {
+		if !m.Compressed || m.Masked || m.IsClient || string(m.Payload) != "{\"cmd\":\"whoami\"}" {
+			t.Fatal("unexpected message: ", m, string(m.Payload))
+		}
+	}
+
+	if messages[1].WireSize >= messages[0].WireSize {
+		t.Fatal("expected second message to use the context of the first one")
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package websocket
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.AbstractDecoder{
+	Type:        types.Type_NC_WebSocketMessage,
+	Name:        "WebSocketMessage",
+	Description: "A message exchanged over a WebSocket connection, after upgrading from HTTP",
+}
+
+// WriteMessage writes the websocket message audit record.
+func WriteMessage(m *types.WebSocketMessage) {
+	if decoderconfig.Instance.ExportMetrics {
+		m.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(m)
+	if err != nil {
+		log.Fatal("failed to write proto: ", err)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import "sync"
+
+// DeviceInfo contains the information learned about a device from protocol messages,
+// such as announcements of name resolution or service discovery protocols.
+type DeviceInfo struct {
+	IDs       []string
+	Hostnames []string
+	Services  []string
+	Models    []string
+}
+
+// deviceInfos holds the learned device information by IP or MAC address.
+var deviceInfos = struct {
+	sync.Mutex
+	items map[string]*DeviceInfo
+}{
+	items: make(map[string]*DeviceInfo),
+}
+
+// addDeviceInfo appends the value to the list selected by field, if it is not present yet.
+func addDeviceInfo(addr, value string, field func(info *DeviceInfo) *[]string) {
+	if addr == "" || value == "" {
+		return
+	}
+
+	deviceInfos.Lock()
+	defer deviceInfos.Unlock()
+
+	info, ok := deviceInfos.items[addr]
+	if !ok {
+		info = new(DeviceInfo)
+		deviceInfos.items[addr] = info
+	}
+
+	list := field(info)
+	*list = AppendUnique(*list, value)
+}
+
+// AddDeviceIdentifier registers a protocol specific identifier for the device using the given IP address,
+// such as a BACnet device instance or the application URI of an OPC UA server.
+// The identifiers are attached to the device profiles owning the address when the profiles are flushed.
+func AddDeviceIdentifier(ip, id string) {
+	addDeviceInfo(ip, id, func(info *DeviceInfo) *[]string { return &info.IDs })
+}
+
+// AddHostname registers a hostname announced by or resolved for the given IP or MAC address.
+func AddHostname(addr, name string) {
+	addDeviceInfo(addr, name, func(info *DeviceInfo) *[]string { return &info.Hostnames })
+}
+
+// AddService registers a service advertised by the given IP or MAC address.
+func AddService(addr, service string) {
+	addDeviceInfo(addr, service, func(info *DeviceInfo) *[]string { return &info.Services })
+}
+
+// AddModel registers a device model advertised by the given IP or MAC address.
+func AddModel(addr, model string) {
+	addDeviceInfo(addr, model, func(info *DeviceInfo) *[]string { return &info.Models })
+}
+
+// DeviceIdentifiers returns the identifiers registered for the given IP address.
+func DeviceIdentifiers(ip string) []string {
+	return LookupDeviceInfo(ip).IDs
+}
+
+// LookupDeviceInfo returns a copy of the information registered for the given IP or MAC address.
+func LookupDeviceInfo(addr string) DeviceInfo {
+	deviceInfos.Lock()
+	defer deviceInfos.Unlock()
+
+	info, ok := deviceInfos.items[addr]
+	if !ok {
+		return DeviceInfo{}
+	}
+
+	return DeviceInfo{
+		IDs:       append([]string(nil), info.IDs...),
+		Hostnames: append([]string(nil), info.Hostnames...),
+		Services:  append([]string(nil), info.Services...),
+		Models:    append([]string(nil), info.Models...),
+	}
+}
+
+// AppendUnique appends the values to the list that are not contained in it yet.
+func AppendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		var found bool
+
+		for _, existing := range list {
+			if existing == v {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
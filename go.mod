@@ -6,6 +6,7 @@ require (
 	github.com/Jeffail/gabs/v2 v2.6.0
 	github.com/Microsoft/go-winio v0.4.16 // indirect
 	github.com/RoaringBitmap/roaring v0.5.5 // indirect
+	github.com/andybalholm/brotli v1.0.2
 	github.com/araddon/dateparse v0.0.0-20210207001429-0eec95c9db7e
 	github.com/blevesearch/bleve v1.0.14
 	github.com/cheggaaa/pb v1.0.29
@@ -35,7 +36,7 @@ require (
 	github.com/google/pprof v0.0.0-20210208152844-1612e9be7af6 // indirect
 	github.com/imdario/mergo v0.3.11 // indirect
 	github.com/kevinburke/ssh_config v0.0.0-20201106050909-4977a11b4351 // indirect
-	github.com/klauspost/compress v1.11.7 // indirect
+	github.com/klauspost/compress v1.11.7
 	github.com/klauspost/pgzip v1.2.5
 	github.com/magefile/mage v1.11.0 // indirect
 	github.com/mcnijman/go-emailaddress v1.1.0
@@ -169,6 +169,90 @@ func InitRecord(typ types.Type) (record proto.Message) {
 		record = new(types.IPProfile)
 	case types.Type_NC_Mail:
 		record = new(types.Mail)
+	case types.Type_NC_SNMP:
+		record = new(types.SNMP)
+	case types.Type_NC_TFTP:
+		record = new(types.TFTP)
+	case types.Type_NC_Call:
+		record = new(types.Call)
+	case types.Type_NC_IRC:
+		record = new(types.IRC)
+	case types.Type_NC_Telnet:
+		record = new(types.Telnet)
+	case types.Type_NC_WebSocketMessage:
+		record = new(types.WebSocketMessage)
+	case types.Type_NC_Token:
+		record = new(types.Token)
+	case types.Type_NC_ProxyTunnel:
+		record = new(types.ProxyTunnel)
+	case types.Type_NC_ModbusTransaction:
+		record = new(types.ModbusTransaction)
+	case types.Type_NC_ENIPTransaction:
+		record = new(types.ENIPTransaction)
+	case types.Type_NC_ControlAction:
+		record = new(types.ControlAction)
+	case types.Type_NC_DNP3:
+		record = new(types.DNP3)
+	case types.Type_NC_IEC104:
+		record = new(types.IEC104)
+	case types.Type_NC_S7Comm:
+		record = new(types.S7Comm)
+	case types.Type_NC_BACnet:
+		record = new(types.BACnet)
+	case types.Type_NC_OPCUA:
+		record = new(types.OPCUA)
+	case types.Type_NC_MDNS:
+		record = new(types.MDNS)
+	case types.Type_NC_LLMNR:
+		record = new(types.LLMNR)
+	case types.Type_NC_NBNS:
+		record = new(types.NBNS)
+	case types.Type_NC_PPPoE:
+		record = new(types.PPPoE)
+	case types.Type_NC_PPP:
+		record = new(types.PPP)
+	case types.Type_NC_L2TP:
+		record = new(types.L2TP)
+	case types.Type_NC_STP:
+		record = new(types.STP)
+	case types.Type_NC_LACP:
+		record = new(types.LACP)
+	case types.Type_NC_DTP:
+		record = new(types.DTP)
+	case types.Type_NC_VTP:
+		record = new(types.VTP)
+	case types.Type_NC_STPSummary:
+		record = new(types.STPSummary)
+	case types.Type_NC_Dot11Beacon:
+		record = new(types.Dot11Beacon)
+	case types.Type_NC_Dot11ProbeRequest:
+		record = new(types.Dot11ProbeRequest)
+	case types.Type_NC_Dot11Deauth:
+		record = new(types.Dot11Deauth)
+	case types.Type_NC_Dot11Handshake:
+		record = new(types.Dot11Handshake)
+	case types.Type_NC_CAN:
+		record = new(types.CAN)
+	case types.Type_NC_CANSummary:
+		record = new(types.CANSummary)
+	case types.Type_NC_USBKeystrokes:
+		record = new(types.USBKeystrokes)
+	case types.Type_NC_USBMouse:
+		record = new(types.USBMouse)
+	case types.Type_NC_USBStorage:
+		record = new(types.USBStorage)
+	case types.Type_NC_RADIUS:
+		record = new(types.RADIUS)
+	case types.Type_NC_RADIUSSession:
+		record = new(types.RADIUSSession)
+	case types.Type_NC_TACACS:
+		record = new(types.TACACS)
+	case types.Type_NC_Syslog:
+		record = new(types.Syslog)
+	case types.Type_NC_FlowExporter:
+		record = new(types.FlowExporter)
+	case types.Type_NC_ERSPAN:
+		record = new(types.ERSPAN)
 	default:
 		panic("InitRecord: unknown type: " + typ.String())
 	}
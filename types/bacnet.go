@@ -0,0 +1,146 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsBACnet = []string{
+	"Timestamp",
+	"SrcIP",              // string
+	"DstIP",              // string
+	"SrcPort",            // int32
+	"DstPort",            // int32
+	"BVLCFunction",       // string
+	"ForwardedAddress",   // string
+	"NetworkMessage",     // string
+	"SourceNetwork",      // int32
+	"SourceAddress",      // string
+	"DestinationNetwork", // int32
+	"DestinationAddress", // string
+	"HopCount",           // int32
+	"ExpectingReply",     // bool
+	"Priority",           // int32
+	"APDUType",           // string
+	"InvokeID",           // int32
+	"ServiceChoice",      // int32
+	"Service",            // string
+	"ObjectType",         // string
+	"ObjectInstance",     // int32
+	"PropertyID",         // int32
+	"Property",           // string
+	"Value",              // string
+	"WritePriority",      // int32
+	"DeviceInstance",     // int32
+	"WhoIsLow",           // int32
+	"WhoIsHigh",          // int32
+	"VendorID",           // int32
+	"MaxAPDU",            // int32
+	"Segmentation",       // string
+	"ErrorClass",         // string
+	"ErrorCode",          // int32
+	"Reason",             // int32
+	"Payload",            // []byte
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (b *BACnet) CSVHeader() []string {
+	return filter(fieldsBACnet)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (b *BACnet) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(b.Timestamp),
+		b.SrcIP,                              // string
+		b.DstIP,                              // string
+		formatInt32(b.SrcPort),               // int32
+		formatInt32(b.DstPort),               // int32
+		b.BVLCFunction,                       // string
+		b.ForwardedAddress,                   // string
+		b.NetworkMessage,                     // string
+		formatInt32(b.SourceNetwork),         // int32
+		b.SourceAddress,                      // string
+		formatInt32(b.DestinationNetwork),    // int32
+		b.DestinationAddress,                 // string
+		formatInt32(b.HopCount),              // int32
+		strconv.FormatBool(b.ExpectingReply), // bool
+		formatInt32(b.Priority),              // int32
+		b.APDUType,                           // string
+		formatInt32(b.InvokeID),              // int32
+		formatInt32(b.ServiceChoice),         // int32
+		b.Service,                            // string
+		b.ObjectType,                         // string
+		formatInt32(b.ObjectInstance),        // int32
+		formatInt32(b.PropertyID),            // int32
+		b.Property,                           // string
+		b.Value,                              // string
+		formatInt32(b.WritePriority),         // int32
+		formatInt32(b.DeviceInstance),        // int32
+		formatInt32(b.WhoIsLow),              // int32
+		formatInt32(b.WhoIsHigh),             // int32
+		formatInt32(b.VendorID),              // int32
+		formatInt32(b.MaxAPDU),               // int32
+		b.Segmentation,                       // string
+		b.ErrorClass,                         // string
+		formatInt32(b.ErrorCode),             // int32
+		formatInt32(b.Reason),                // int32
+		hex.EncodeToString(b.Payload),        // []byte
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (b *BACnet) Time() int64 {
+	return b.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (b *BACnet) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	b.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(b)
+}
+
+var bacnetMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_BACnet.String()),
+		Help: Type_NC_BACnet.String() + " audit records",
+	},
+	[]string{"BVLCFunction", "APDUType", "Service"},
+)
+
+// Inc increments the metrics for the audit record.
+func (b *BACnet) Inc() {
+	bacnetMetric.WithLabelValues(b.BVLCFunction, b.APDUType, b.Service).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (b *BACnet) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (b *BACnet) Src() string {
+	return b.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (b *BACnet) Dst() string {
+	return b.DstIP
+}
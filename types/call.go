@@ -0,0 +1,151 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsCall = []string{
+	"Timestamp",
+	"CallID",          // string
+	"From",            // string
+	"To",              // string
+	"CallerUserAgent", // string
+	"CalleeUserAgent", // string
+	"CallerIP",        // string
+	"CalleeIP",        // string
+	"StatusCode",      // int32
+	"Answered",        // bool
+	"AnswerTimestamp", // int64
+	"EndTimestamp",    // int64
+	"Duration",        // int64
+	"EndReason",       // string
+	"Codecs",          // []string
+	"Streams",         // []*RTPStream
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (c *Call) CSVHeader() []string {
+	return filter(fieldsCall)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (c *Call) CSVRecord() []string {
+	streams := make([]string, 0, len(c.Streams))
+	for _, s := range c.Streams {
+		streams = append(streams, s.toString())
+	}
+
+	return filter([]string{
+		formatTimestamp(c.Timestamp),
+		c.CallID,                           // string
+		c.From,                             // string
+		c.To,                               // string
+		c.CallerUserAgent,                  // string
+		c.CalleeUserAgent,                  // string
+		c.CallerIP,                         // string
+		c.CalleeIP,                         // string
+		formatInt32(c.StatusCode),          // int32
+		strconv.FormatBool(c.Answered),     // bool
+		formatTimestamp(c.AnswerTimestamp), // int64
+		formatTimestamp(c.EndTimestamp),    // int64
+		formatInt64(c.Duration),            // int64
+		c.EndReason,                        // string
+		join(c.Codecs...),                  // []string
+		strings.Join(streams, ""),          // []*RTPStream
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (c *Call) Time() int64 {
+	return c.Timestamp
+}
+
+func (s *RTPStream) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(formatUint32(s.SSRC))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(s.PayloadType))
+	b.WriteString(FieldSeparator)
+	b.WriteString(s.Codec)
+	b.WriteString(FieldSeparator)
+	b.WriteString(s.SrcIP)
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(s.SrcPort))
+	b.WriteString(FieldSeparator)
+	b.WriteString(s.DstIP)
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(s.DstPort))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatTimestamp(s.TimestampFirst))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatTimestamp(s.TimestampLast))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt64(s.Packets))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt64(s.Bytes))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt64(s.Lost))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatFloat64(s.Jitter))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt64(s.ReportedLost))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(s.ReportedFractionLost))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatFloat64(s.ReportedJitter))
+	b.WriteString(StructureEnd)
+
+	return b.String()
+}
+
+// JSON returns the JSON representation of the audit record.
+func (c *Call) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	c.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(c)
+}
+
+var callMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Call.String()),
+		Help: Type_NC_Call.String() + " audit records",
+	},
+	[]string{"StatusCode", "Answered", "EndReason"},
+)
+
+// Inc increments the metrics for the audit record.
+func (c *Call) Inc() {
+	callMetric.WithLabelValues(formatInt32(c.StatusCode), strconv.FormatBool(c.Answered), c.EndReason).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (c *Call) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (c *Call) Src() string {
+	return c.CallerIP
+}
+
+// Dst returns the destination address of the audit record.
+func (c *Call) Dst() string {
+	return c.CalleeIP
+}
@@ -0,0 +1,98 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsCAN = []string{
+	"Timestamp",
+	"ID",           // uint32
+	"Extended",     // bool
+	"Remote",       // bool
+	"Error",        // bool
+	"FD",           // bool
+	"Length",       // int32
+	"Data",         // []byte
+	"ErrorClasses", // []string
+	"Message",      // string
+	"Signals",      // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (c *CAN) CSVHeader() []string {
+	return filter(fieldsCAN)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (c *CAN) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(c.Timestamp),
+		formatUint32(c.ID),             // uint32
+		strconv.FormatBool(c.Extended), // bool
+		strconv.FormatBool(c.Remote),   // bool
+		strconv.FormatBool(c.Error),    // bool
+		strconv.FormatBool(c.FD),       // bool
+		formatInt32(c.Length),          // int32
+		hex.EncodeToString(c.Data),     // []byte
+		join(c.ErrorClasses...),        // []string
+		c.Message,                      // string
+		join(c.Signals...),             // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (c *CAN) Time() int64 {
+	return c.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (c *CAN) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	c.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(c)
+}
+
+var canMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_CAN.String()),
+		Help: Type_NC_CAN.String() + " audit records",
+	},
+	[]string{"Extended", "Remote", "Error"},
+)
+
+// Inc increments the metrics for the audit record.
+func (c *CAN) Inc() {
+	canMetric.WithLabelValues(strconv.FormatBool(c.Extended), strconv.FormatBool(c.Remote), strconv.FormatBool(c.Error)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (c *CAN) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (c *CAN) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (c *CAN) Dst() string {
+	return ""
+}
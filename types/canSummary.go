@@ -0,0 +1,102 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsCANSummary = []string{
+	"Timestamp",
+	"ID",               // uint32
+	"Extended",         // bool
+	"Message",          // string
+	"NumFrames",        // int64
+	"LastSeen",         // int64
+	"MeanInterval",     // float64
+	"IntervalStdDev",   // float64
+	"MinInterval",      // float64
+	"ShortIntervals",   // int64
+	"Lengths",          // []int32
+	"DistinctPayloads", // int32
+	"PayloadVariance",  // float64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *CANSummary) CSVHeader() []string {
+	return filter(fieldsCANSummary)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *CANSummary) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		formatUint32(s.ID),                                 // uint32
+		strconv.FormatBool(s.Extended),                     // bool
+		s.Message,                                          // string
+		formatInt64(s.NumFrames),                           // int64
+		formatTimestamp(s.LastSeen),                        // int64
+		strconv.FormatFloat(s.MeanInterval, 'f', 2, 64),    // float64
+		strconv.FormatFloat(s.IntervalStdDev, 'f', 2, 64),  // float64
+		strconv.FormatFloat(s.MinInterval, 'f', 2, 64),     // float64
+		formatInt64(s.ShortIntervals),                      // int64
+		joinInts(s.Lengths),                                // []int32
+		formatInt32(s.DistinctPayloads),                    // int32
+		strconv.FormatFloat(s.PayloadVariance, 'f', 2, 64), // float64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *CANSummary) Time() int64 {
+	return s.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *CANSummary) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+	s.LastSeen /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var canSummaryMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_CANSummary.String()),
+		Help: Type_NC_CANSummary.String() + " audit records",
+	},
+	[]string{"Extended"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *CANSummary) Inc() {
+	canSummaryMetric.WithLabelValues(strconv.FormatBool(s.Extended)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *CANSummary) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (s *CANSummary) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (s *CANSummary) Dst() string {
+	return ""
+}
@@ -38,6 +38,7 @@ var fieldsConnection = []string{
 	"UID",
 	"Duration",
 	"TimestampLast",
+	"Tunnel",
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -65,6 +66,7 @@ func (c *Connection) CSVRecord() []string {
 		c.UID,
 		formatInt64(c.Duration),
 		formatTimestamp(c.TimestampLast),
+		c.Tunnel,
 	})
 }
 
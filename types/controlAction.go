@@ -0,0 +1,110 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsControlAction = []string{
+	"Timestamp",
+	"Protocol",   // string
+	"Flow",       // string
+	"ClientIP",   // string
+	"ServerIP",   // string
+	"ClientPort", // int32
+	"ServerPort", // int32
+	"UnitID",     // int32
+	"Operation",  // string
+	"Target",     // string
+	"Address",    // int32
+	"Quantity",   // int32
+	"Values",     // []int32
+	"Data",       // []byte
+	"Answered",   // bool
+	"Success",    // bool
+	"Status",     // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (c *ControlAction) CSVHeader() []string {
+	return filter(fieldsControlAction)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (c *ControlAction) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(c.Timestamp),
+		c.Protocol,                     // string
+		c.Flow,                         // string
+		c.ClientIP,                     // string
+		c.ServerIP,                     // string
+		formatInt32(c.ClientPort),      // int32
+		formatInt32(c.ServerPort),      // int32
+		formatInt32(c.UnitID),          // int32
+		c.Operation,                    // string
+		c.Target,                       // string
+		formatInt32(c.Address),         // int32
+		formatInt32(c.Quantity),        // int32
+		joinInts(c.Values),             // []int32
+		hex.EncodeToString(c.Data),     // []byte
+		strconv.FormatBool(c.Answered), // bool
+		strconv.FormatBool(c.Success),  // bool
+		formatInt32(c.Status),          // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (c *ControlAction) Time() int64 {
+	return c.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (c *ControlAction) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	c.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(c)
+}
+
+var controlActionMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_ControlAction.String()),
+		Help: Type_NC_ControlAction.String() + " audit records",
+	},
+	[]string{"Protocol", "Operation", "Success"},
+)
+
+// Inc increments the metrics for the audit record.
+func (c *ControlAction) Inc() {
+	controlActionMetric.WithLabelValues(c.Protocol, c.Operation, strconv.FormatBool(c.Success)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (c *ControlAction) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (c *ControlAction) Src() string {
+	return c.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (c *ControlAction) Dst() string {
+	return c.ServerIP
+}
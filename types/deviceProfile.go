@@ -26,6 +26,10 @@ var fieldsDeviceProfile = []string{
 	"NumContacts",
 	"NumPackets",
 	"Bytes",
+	"DeviceIDs",
+	"Hostnames",
+	"Services",
+	"Models",
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -43,6 +47,10 @@ func (d *DeviceProfile) CSVRecord() []string {
 		strconv.Itoa(len(d.Contacts)),
 		formatInt64(d.NumPackets),
 		formatUint64(d.Bytes),
+		join(d.DeviceIDs...),
+		join(d.Hostnames...),
+		join(d.Services...),
+		join(d.Models...),
 	})
 }
 
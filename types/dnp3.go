@@ -0,0 +1,144 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsDNP3 = []string{
+	"Timestamp",
+	"ClientIP",     // string
+	"ServerIP",     // string
+	"ClientPort",   // int32
+	"ServerPort",   // int32
+	"IsClient",     // bool
+	"Source",       // int32
+	"Destination",  // int32
+	"LinkFunction", // int32
+	"Primary",      // bool
+	"Frames",       // int32
+	"Sequence",     // int32
+	"Confirm",      // bool
+	"Unsolicited",  // bool
+	"FunctionCode", // int32
+	"Function",     // string
+	"IIN",          // int32
+	"IINFlags",     // []string
+	"Objects",      // []*DNP3Object
+	"Control",      // bool
+	"Payload",      // []byte
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (d *DNP3) CSVHeader() []string {
+	return filter(fieldsDNP3)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (d *DNP3) CSVRecord() []string {
+	objects := make([]string, 0, len(d.Objects))
+	for _, v := range d.Objects {
+		objects = append(objects, v.toString())
+	}
+
+	return filter([]string{
+		formatTimestamp(d.Timestamp),
+		d.ClientIP,                        // string
+		d.ServerIP,                        // string
+		formatInt32(d.ClientPort),         // int32
+		formatInt32(d.ServerPort),         // int32
+		strconv.FormatBool(d.IsClient),    // bool
+		formatInt32(d.Source),             // int32
+		formatInt32(d.Destination),        // int32
+		formatInt32(d.LinkFunction),       // int32
+		strconv.FormatBool(d.Primary),     // bool
+		formatInt32(d.Frames),             // int32
+		formatInt32(d.Sequence),           // int32
+		strconv.FormatBool(d.Confirm),     // bool
+		strconv.FormatBool(d.Unsolicited), // bool
+		formatInt32(d.FunctionCode),       // int32
+		d.Function,                        // string
+		formatInt32(d.IIN),                // int32
+		join(d.IINFlags...),               // []string
+		strings.Join(objects, ""),         // []*DNP3Object
+		strconv.FormatBool(d.Control),     // bool
+		hex.EncodeToString(d.Payload),     // []byte
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (d *DNP3) Time() int64 {
+	return d.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (d *DNP3) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	d.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(d)
+}
+
+var dnp3Metric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_DNP3.String()),
+		Help: Type_NC_DNP3.String() + " audit records",
+	},
+	[]string{"Function", "IsClient", "Control"},
+)
+
+// Inc increments the metrics for the audit record.
+func (d *DNP3) Inc() {
+	dnp3Metric.WithLabelValues(d.Function, strconv.FormatBool(d.IsClient), strconv.FormatBool(d.Control)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (d *DNP3) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (d *DNP3) Src() string {
+	return d.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (d *DNP3) Dst() string {
+	return d.ServerIP
+}
+
+func (o *DNP3Object) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(formatInt32(o.Group))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(o.Variation))
+	b.WriteString(FieldSeparator)
+	b.WriteString(o.Name)
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(o.Qualifier))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(o.Start))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(o.Stop))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(o.Count))
+	b.WriteString(StructureEnd)
+
+	return b.String()
+}
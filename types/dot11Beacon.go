@@ -0,0 +1,111 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsDot11Beacon = []string{
+	"Timestamp",
+	"Type",            // string
+	"BSSID",           // string
+	"SrcMAC",          // string
+	"DstMAC",          // string
+	"SSID",            // string
+	"Hidden",          // bool
+	"Channel",         // int32
+	"Interval",        // int32
+	"Capabilities",    // int32
+	"Security",        // string
+	"GroupCipher",     // string
+	"PairwiseCiphers", // []string
+	"AKMSuites",       // []string
+	"RSNCapabilities", // int32
+	"MFPRequired",     // bool
+	"MFPCapable",      // bool
+	"VendorIEs",       // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (b *Dot11Beacon) CSVHeader() []string {
+	return filter(fieldsDot11Beacon)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (b *Dot11Beacon) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(b.Timestamp),
+		b.Type,                            // string
+		b.BSSID,                           // string
+		b.SrcMAC,                          // string
+		b.DstMAC,                          // string
+		b.SSID,                            // string
+		strconv.FormatBool(b.Hidden),      // bool
+		formatInt32(b.Channel),            // int32
+		formatInt32(b.Interval),           // int32
+		formatInt32(b.Capabilities),       // int32
+		b.Security,                        // string
+		b.GroupCipher,                     // string
+		join(b.PairwiseCiphers...),        // []string
+		join(b.AKMSuites...),              // []string
+		formatInt32(b.RSNCapabilities),    // int32
+		strconv.FormatBool(b.MFPRequired), // bool
+		strconv.FormatBool(b.MFPCapable),  // bool
+		join(b.VendorIEs...),              // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (b *Dot11Beacon) Time() int64 {
+	return b.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (b *Dot11Beacon) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	b.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(b)
+}
+
+var dot11BeaconMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Dot11Beacon.String()),
+		Help: Type_NC_Dot11Beacon.String() + " audit records",
+	},
+	[]string{"Type", "Security"},
+)
+
+// Inc increments the metrics for the audit record.
+func (b *Dot11Beacon) Inc() {
+	dot11BeaconMetric.WithLabelValues(b.Type, b.Security).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (b *Dot11Beacon) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (b *Dot11Beacon) Src() string {
+	return b.SrcMAC
+}
+
+// Dst returns the destination address of the audit record.
+func (b *Dot11Beacon) Dst() string {
+	return b.DstMAC
+}
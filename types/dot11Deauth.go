@@ -0,0 +1,95 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsDot11Deauth = []string{
+	"Timestamp",
+	"Type",       // string
+	"SrcMAC",     // string
+	"DstMAC",     // string
+	"BSSID",      // string
+	"ReasonCode", // int32
+	"Reason",     // string
+	"Broadcast",  // bool
+	"FrameCount", // int32
+	"Flood",      // bool
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (d *Dot11Deauth) CSVHeader() []string {
+	return filter(fieldsDot11Deauth)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (d *Dot11Deauth) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(d.Timestamp),
+		d.Type,                          // string
+		d.SrcMAC,                        // string
+		d.DstMAC,                        // string
+		d.BSSID,                         // string
+		formatInt32(d.ReasonCode),       // int32
+		d.Reason,                        // string
+		strconv.FormatBool(d.Broadcast), // bool
+		formatInt32(d.FrameCount),       // int32
+		strconv.FormatBool(d.Flood),     // bool
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (d *Dot11Deauth) Time() int64 {
+	return d.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (d *Dot11Deauth) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	d.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(d)
+}
+
+var dot11DeauthMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Dot11Deauth.String()),
+		Help: Type_NC_Dot11Deauth.String() + " audit records",
+	},
+	[]string{"Type", "Reason", "Flood"},
+)
+
+// Inc increments the metrics for the audit record.
+func (d *Dot11Deauth) Inc() {
+	dot11DeauthMetric.WithLabelValues(d.Type, d.Reason, strconv.FormatBool(d.Flood)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (d *Dot11Deauth) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (d *Dot11Deauth) Src() string {
+	return d.SrcMAC
+}
+
+// Dst returns the destination address of the audit record.
+func (d *Dot11Deauth) Dst() string {
+	return d.DstMAC
+}
@@ -0,0 +1,92 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsDot11Handshake = []string{
+	"Timestamp",
+	"Type",          // string
+	"BSSID",         // string
+	"ClientMAC",     // string
+	"SSID",          // string
+	"MessagePair",   // int32
+	"ReplayCounter", // uint64
+	"KeyVersion",    // int32
+	"Hash",          // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (h *Dot11Handshake) CSVHeader() []string {
+	return filter(fieldsDot11Handshake)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (h *Dot11Handshake) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(h.Timestamp),
+		h.Type,                        // string
+		h.BSSID,                       // string
+		h.ClientMAC,                   // string
+		h.SSID,                        // string
+		formatInt32(h.MessagePair),    // int32
+		formatUint64(h.ReplayCounter), // uint64
+		formatInt32(h.KeyVersion),     // int32
+		h.Hash,                        // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (h *Dot11Handshake) Time() int64 {
+	return h.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (h *Dot11Handshake) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	h.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(h)
+}
+
+var dot11HandshakeMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Dot11Handshake.String()),
+		Help: Type_NC_Dot11Handshake.String() + " audit records",
+	},
+	[]string{"Type"},
+)
+
+// Inc increments the metrics for the audit record.
+func (h *Dot11Handshake) Inc() {
+	dot11HandshakeMetric.WithLabelValues(h.Type).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (h *Dot11Handshake) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (h *Dot11Handshake) Src() string {
+	return h.ClientMAC
+}
+
+// Dst returns the destination address of the audit record.
+func (h *Dot11Handshake) Dst() string {
+	return h.BSSID
+}
@@ -0,0 +1,89 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsDot11ProbeRequest = []string{
+	"Timestamp",
+	"ClientMAC", // string
+	"BSSID",     // string
+	"DstMAC",    // string
+	"SSIDs",     // []string
+	"Wildcard",  // bool
+	"VendorIEs", // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (p *Dot11ProbeRequest) CSVHeader() []string {
+	return filter(fieldsDot11ProbeRequest)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (p *Dot11ProbeRequest) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(p.Timestamp),
+		p.ClientMAC,                    // string
+		p.BSSID,                        // string
+		p.DstMAC,                       // string
+		join(p.SSIDs...),               // []string
+		strconv.FormatBool(p.Wildcard), // bool
+		join(p.VendorIEs...),           // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (p *Dot11ProbeRequest) Time() int64 {
+	return p.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (p *Dot11ProbeRequest) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	p.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(p)
+}
+
+var dot11ProbeRequestMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Dot11ProbeRequest.String()),
+		Help: Type_NC_Dot11ProbeRequest.String() + " audit records",
+	},
+	[]string{"Wildcard"},
+)
+
+// Inc increments the metrics for the audit record.
+func (p *Dot11ProbeRequest) Inc() {
+	dot11ProbeRequestMetric.WithLabelValues(strconv.FormatBool(p.Wildcard)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (p *Dot11ProbeRequest) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (p *Dot11ProbeRequest) Src() string {
+	return p.ClientMAC
+}
+
+// Dst returns the destination address of the audit record.
+func (p *Dot11ProbeRequest) Dst() string {
+	return p.DstMAC
+}
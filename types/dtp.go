@@ -0,0 +1,90 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsDTP = []string{
+	"Timestamp",
+	"Version",              // int32
+	"Domain",               // string
+	"OperatingStatus",      // string
+	"AdministrativeStatus", // string
+	"OperatingType",        // string
+	"AdministrativeType",   // string
+	"Neighbor",             // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (d *DTP) CSVHeader() []string {
+	return filter(fieldsDTP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (d *DTP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(d.Timestamp),
+		formatInt32(d.Version), // int32
+		d.Domain,               // string
+		d.OperatingStatus,      // string
+		d.AdministrativeStatus, // string
+		d.OperatingType,        // string
+		d.AdministrativeType,   // string
+		d.Neighbor,             // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (d *DTP) Time() int64 {
+	return d.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (d *DTP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	d.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(d)
+}
+
+var dtpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_DTP.String()),
+		Help: Type_NC_DTP.String() + " audit records",
+	},
+	[]string{"OperatingStatus", "AdministrativeStatus"},
+)
+
+// Inc increments the metrics for the audit record.
+func (d *DTP) Inc() {
+	dtpMetric.WithLabelValues(d.OperatingStatus, d.AdministrativeStatus).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (d *DTP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (d *DTP) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (d *DTP) Dst() string {
+	return ""
+}
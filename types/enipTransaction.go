@@ -0,0 +1,134 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsENIPTransaction = []string{
+	"Timestamp",
+	"Duration",         // int64
+	"ClientIP",         // string
+	"ServerIP",         // string
+	"ClientPort",       // int32
+	"ServerPort",       // int32
+	"Command",          // uint32
+	"CommandName",      // string
+	"SessionHandle",    // uint32
+	"SenderContext",    // string
+	"Status",           // uint32
+	"CIPService",       // int32
+	"CIPServiceName",   // string
+	"ClassID",          // uint32
+	"InstanceID",       // uint32
+	"AttributeID",      // uint32
+	"Tag",              // string
+	"CIPStatus",        // int32
+	"AdditionalStatus", // []uint32
+	"Write",            // bool
+	"Answered",         // bool
+	"VendorID",         // uint32
+	"DeviceType",       // uint32
+	"ProductCode",      // uint32
+	"Revision",         // string
+	"SerialNumber",     // uint32
+	"ProductName",      // string
+	"RequestData",      // []byte
+	"ResponseData",     // []byte
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (e *ENIPTransaction) CSVHeader() []string {
+	return filter(fieldsENIPTransaction)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (e *ENIPTransaction) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(e.Timestamp),
+		formatInt64(e.Duration),            // int64
+		e.ClientIP,                         // string
+		e.ServerIP,                         // string
+		formatInt32(e.ClientPort),          // int32
+		formatInt32(e.ServerPort),          // int32
+		formatUint32(e.Command),            // uint32
+		e.CommandName,                      // string
+		formatUint32(e.SessionHandle),      // uint32
+		e.SenderContext,                    // string
+		formatUint32(e.Status),             // uint32
+		formatInt32(e.CIPService),          // int32
+		e.CIPServiceName,                   // string
+		formatUint32(e.ClassID),            // uint32
+		formatUint32(e.InstanceID),         // uint32
+		formatUint32(e.AttributeID),        // uint32
+		e.Tag,                              // string
+		formatInt32(e.CIPStatus),           // int32
+		joinUints(e.AdditionalStatus),      // []uint32
+		strconv.FormatBool(e.Write),        // bool
+		strconv.FormatBool(e.Answered),     // bool
+		formatUint32(e.VendorID),           // uint32
+		formatUint32(e.DeviceType),         // uint32
+		formatUint32(e.ProductCode),        // uint32
+		e.Revision,                         // string
+		formatUint32(e.SerialNumber),       // uint32
+		e.ProductName,                      // string
+		hex.EncodeToString(e.RequestData),  // []byte
+		hex.EncodeToString(e.ResponseData), // []byte
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (e *ENIPTransaction) Time() int64 {
+	return e.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (e *ENIPTransaction) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	e.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(e)
+}
+
+var enipTransactionMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_ENIPTransaction.String()),
+		Help: Type_NC_ENIPTransaction.String() + " audit records",
+	},
+	[]string{"CommandName", "CIPServiceName", "Write"},
+)
+
+// Inc increments the metrics for the audit record.
+func (e *ENIPTransaction) Inc() {
+	enipTransactionMetric.WithLabelValues(e.CommandName, e.CIPServiceName, strconv.FormatBool(e.Write)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (e *ENIPTransaction) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (e *ENIPTransaction) Src() string {
+	return e.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (e *ENIPTransaction) Dst() string {
+	return e.ServerIP
+}
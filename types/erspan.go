@@ -0,0 +1,103 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsERSPAN = []string{
+	"Timestamp",
+	"Version",           // int32
+	"VLAN",              // int32
+	"CoS",               // int32
+	"Encapsulation",     // int32
+	"Truncated",         // bool
+	"SessionID",         // int32
+	"Index",             // int32
+	"HardwareTimestamp", // uint32
+	"SecurityGroupTag",  // int32
+	"FrameType",         // int32
+	"HardwareID",        // int32
+	"Egress",            // bool
+	"Granularity",       // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (e *ERSPAN) CSVHeader() []string {
+	return filter(fieldsERSPAN)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (e *ERSPAN) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(e.Timestamp),
+		formatInt32(e.Version),            // int32
+		formatInt32(e.VLAN),               // int32
+		formatInt32(e.CoS),                // int32
+		formatInt32(e.Encapsulation),      // int32
+		strconv.FormatBool(e.Truncated),   // bool
+		formatInt32(e.SessionID),          // int32
+		formatInt32(e.Index),              // int32
+		formatUint32(e.HardwareTimestamp), // uint32
+		formatInt32(e.SecurityGroupTag),   // int32
+		formatInt32(e.FrameType),          // int32
+		formatInt32(e.HardwareID),         // int32
+		strconv.FormatBool(e.Egress),      // bool
+		formatInt32(e.Granularity),        // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (e *ERSPAN) Time() int64 {
+	return e.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (e *ERSPAN) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	e.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(e)
+}
+
+var erspanMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_ERSPAN.String()),
+		Help: Type_NC_ERSPAN.String() + " audit records",
+	},
+	[]string{"Version", "SessionID"},
+)
+
+// Inc increments the metrics for the audit record.
+func (e *ERSPAN) Inc() {
+	erspanMetric.WithLabelValues(formatInt32(e.Version), formatInt32(e.SessionID)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (e *ERSPAN) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (e *ERSPAN) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (e *ERSPAN) Dst() string {
+	return ""
+}
@@ -33,6 +33,10 @@ var fieldsFile = []string{
 	"DstIP",
 	"SrcPort",
 	"DstPort",
+	"FormField",
+	"ClientFilename",
+	"RawLength",
+	"ContentEncoding",
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -55,6 +59,10 @@ func (a *File) CSVRecord() []string {
 		a.DstIP,
 		formatInt32(a.SrcPort),
 		formatInt32(a.DstPort),
+		a.FormField,
+		a.ClientFilename,
+		formatInt64(a.RawLength),
+		join(a.ContentEncoding...),
 	})
 }
 
@@ -107,6 +115,10 @@ func (a *File) metricValues() []string {
 		a.DstIP,
 		formatInt32(a.SrcPort),
 		formatInt32(a.DstPort),
+		a.FormField,
+		a.ClientFilename,
+		formatInt64(a.RawLength),
+		join(a.ContentEncoding...),
 	})
 }
 
@@ -0,0 +1,99 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsFlowExporter = []string{
+	"Timestamp",
+	"LastSeen",         // int64
+	"Addr",             // string
+	"Protocols",        // []string
+	"Domains",          // []uint32
+	"NumDatagrams",     // int64
+	"NumFlows",         // int64
+	"NumTemplates",     // int64
+	"UnknownTemplates", // int64
+	"SamplingRate",     // uint32
+	"Packets",          // uint64
+	"Bytes",            // uint64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (e *FlowExporter) CSVHeader() []string {
+	return filter(fieldsFlowExporter)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (e *FlowExporter) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(e.Timestamp),
+		formatTimestamp(e.LastSeen),     // int64
+		e.Addr,                          // string
+		join(e.Protocols...),            // []string
+		joinUints(e.Domains),            // []uint32
+		formatInt64(e.NumDatagrams),     // int64
+		formatInt64(e.NumFlows),         // int64
+		formatInt64(e.NumTemplates),     // int64
+		formatInt64(e.UnknownTemplates), // int64
+		formatUint32(e.SamplingRate),    // uint32
+		formatUint64(e.Packets),         // uint64
+		formatUint64(e.Bytes),           // uint64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (e *FlowExporter) Time() int64 {
+	return e.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (e *FlowExporter) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	e.Timestamp /= int64(time.Millisecond)
+	e.LastSeen /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(e)
+}
+
+var flowExporterMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_FlowExporter.String()),
+		Help: Type_NC_FlowExporter.String() + " audit records",
+	},
+	[]string{"Addr"},
+)
+
+// Inc increments the metrics for the audit record.
+func (e *FlowExporter) Inc() {
+	flowExporterMetric.WithLabelValues(e.Addr).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (e *FlowExporter) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (e *FlowExporter) Src() string {
+	return e.Addr
+}
+
+// Dst returns the destination address of the audit record.
+func (e *FlowExporter) Dst() string {
+	return ""
+}
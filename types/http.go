@@ -40,6 +40,9 @@ var fieldsHTTP = []string{
 	"ReqContentEncoding",
 	"ResContentEncoding",
 	"ServerName",
+	"GRPCService",
+	"GRPCMethod",
+	"GRPCStatus",
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -76,6 +79,9 @@ func (h *HTTP) CSVRecord() []string {
 		h.ReqContentEncoding,
 		h.ResContentEncoding,
 		h.ServerName,
+		h.GRPCService,
+		h.GRPCMethod,
+		h.GRPCStatus,
 	})
 }
 
@@ -0,0 +1,122 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsIEC104 = []string{
+	"Timestamp",
+	"ClientIP",        // string
+	"ServerIP",        // string
+	"ClientPort",      // int32
+	"ServerPort",      // int32
+	"IsClient",        // bool
+	"Format",          // string
+	"SendSequence",    // int32
+	"ReceiveSequence", // int32
+	"UFunction",       // string
+	"TypeID",          // int32
+	"TypeName",        // string
+	"Sequence",        // bool
+	"NumObjects",      // int32
+	"Cause",           // int32
+	"CauseName",       // string
+	"Negative",        // bool
+	"Test",            // bool
+	"Originator",      // int32
+	"CommonAddress",   // int32
+	"ObjectAddresses", // []int32
+	"Command",         // bool
+	"Payload",         // []byte
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (i *IEC104) CSVHeader() []string {
+	return filter(fieldsIEC104)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (i *IEC104) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(i.Timestamp),
+		i.ClientIP,                     // string
+		i.ServerIP,                     // string
+		formatInt32(i.ClientPort),      // int32
+		formatInt32(i.ServerPort),      // int32
+		strconv.FormatBool(i.IsClient), // bool
+		i.Format,                       // string
+		formatInt32(i.SendSequence),    // int32
+		formatInt32(i.ReceiveSequence), // int32
+		i.UFunction,                    // string
+		formatInt32(i.TypeID),          // int32
+		i.TypeName,                     // string
+		strconv.FormatBool(i.Sequence), // bool
+		formatInt32(i.NumObjects),      // int32
+		formatInt32(i.Cause),           // int32
+		i.CauseName,                    // string
+		strconv.FormatBool(i.Negative), // bool
+		strconv.FormatBool(i.Test),     // bool
+		formatInt32(i.Originator),      // int32
+		formatInt32(i.CommonAddress),   // int32
+		joinInts(i.ObjectAddresses),    // []int32
+		strconv.FormatBool(i.Command),  // bool
+		hex.EncodeToString(i.Payload),  // []byte
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (i *IEC104) Time() int64 {
+	return i.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (i *IEC104) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	i.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(i)
+}
+
+var iec104Metric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_IEC104.String()),
+		Help: Type_NC_IEC104.String() + " audit records",
+	},
+	[]string{"Format", "TypeName", "CauseName"},
+)
+
+// Inc increments the metrics for the audit record.
+func (i *IEC104) Inc() {
+	iec104Metric.WithLabelValues(i.Format, i.TypeName, i.CauseName).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (i *IEC104) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (i *IEC104) Src() string {
+	return i.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (i *IEC104) Dst() string {
+	return i.ServerIP
+}
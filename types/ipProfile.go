@@ -29,6 +29,9 @@ var fieldsIPProfile = []string{
 	//"DstPorts",       // map[string]*Port
 	//"SrcPorts",       // map[string]*Port
 	//"SNIs",           // map[string]int64
+	"Hostnames", // []string
+	"Services",  // []string
+	"Models",    // []string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -52,6 +55,9 @@ func (d *IPProfile) CSVRecord() []string {
 		// d.DstPorts,
 		// d.SrcPorts,
 		// d.SNIs,
+		join(d.Hostnames...),
+		join(d.Services...),
+		join(d.Models...),
 	})
 }
 
@@ -0,0 +1,109 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsIRC = []string{
+	"Timestamp",
+	"IsClient", // bool
+	"Prefix",   // string
+	"Nick",     // string
+	"Command",  // string
+	"Params",   // []string
+	"Channel",  // string
+	"Target",   // string
+	"Message",  // string
+	"ClientIP",
+	"ServerIP",
+	"ClientPort",
+	"ServerPort",
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (i *IRC) CSVHeader() []string {
+	return filter(fieldsIRC)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (i *IRC) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(i.Timestamp),
+		strconv.FormatBool(i.IsClient), // bool
+		i.Prefix,                       // string
+		i.Nick,                         // string
+		i.Command,                      // string
+		join(i.Params...),              // []string
+		i.Channel,                      // string
+		i.Target,                       // string
+		i.Message,                      // string
+		i.ClientIP,
+		i.ServerIP,
+		formatInt32(i.ClientPort),
+		formatInt32(i.ServerPort),
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (i *IRC) Time() int64 {
+	return i.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (i *IRC) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	i.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(i)
+}
+
+var ircMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_IRC.String()),
+		Help: Type_NC_IRC.String() + " audit records",
+	},
+	[]string{"IsClient", "Command"},
+)
+
+// Inc increments the metrics for the audit record.
+func (i *IRC) Inc() {
+	ircMetric.WithLabelValues(strconv.FormatBool(i.IsClient), i.Command).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (i *IRC) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (i *IRC) Src() string {
+	if i.IsClient {
+		return i.ClientIP
+	}
+
+	return i.ServerIP
+}
+
+// Dst returns the destination address of the audit record.
+func (i *IRC) Dst() string {
+	if i.IsClient {
+		return i.ServerIP
+	}
+
+	return i.ClientIP
+}
@@ -0,0 +1,129 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsL2TP = []string{
+	"Timestamp",
+	"SrcIP",             // string
+	"DstIP",             // string
+	"SrcPort",           // int32
+	"DstPort",           // int32
+	"Version",           // int32
+	"TunnelID",          // int32
+	"SessionID",         // int32
+	"Ns",                // int32
+	"Nr",                // int32
+	"MessageType",       // string
+	"ResultCode",        // int32
+	"ErrorCode",         // int32
+	"ErrorMessage",      // string
+	"HostName",          // string
+	"VendorName",        // string
+	"AssignedTunnelID",  // int32
+	"AssignedSessionID", // int32
+	"CallSerialNumber",  // uint32
+	"CalledNumber",      // string
+	"CallingNumber",     // string
+	"TxConnectSpeed",    // uint32
+	"ProxyAuthType",     // string
+	"ProxyAuthName",     // string
+	"AVPs",              // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (l *L2TP) CSVHeader() []string {
+	return filter(fieldsL2TP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (l *L2TP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(l.Timestamp),
+		l.SrcIP,                          // string
+		l.DstIP,                          // string
+		formatInt32(l.SrcPort),           // int32
+		formatInt32(l.DstPort),           // int32
+		formatInt32(l.Version),           // int32
+		formatInt32(l.TunnelID),          // int32
+		formatInt32(l.SessionID),         // int32
+		formatInt32(l.Ns),                // int32
+		formatInt32(l.Nr),                // int32
+		l.MessageType,                    // string
+		formatInt32(l.ResultCode),        // int32
+		formatInt32(l.ErrorCode),         // int32
+		l.ErrorMessage,                   // string
+		l.HostName,                       // string
+		l.VendorName,                     // string
+		formatInt32(l.AssignedTunnelID),  // int32
+		formatInt32(l.AssignedSessionID), // int32
+		formatUint32(l.CallSerialNumber), // uint32
+		l.CalledNumber,                   // string
+		l.CallingNumber,                  // string
+		formatUint32(l.TxConnectSpeed),   // uint32
+		l.ProxyAuthType,                  // string
+		l.ProxyAuthName,                  // string
+		join(l.AVPs...),                  // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (l *L2TP) Time() int64 {
+	return l.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (l *L2TP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	l.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(l)
+}
+
+var l2tpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_L2TP.String()),
+		Help: Type_NC_L2TP.String() + " audit records",
+	},
+	[]string{"MessageType"},
+)
+
+// Inc increments the metrics for the audit record.
+func (l *L2TP) Inc() {
+	l2tpMetric.WithLabelValues(l.MessageType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (l *L2TP) SetPacketContext(ctx *PacketContext) {
+	l.SrcIP = ctx.SrcIP
+	l.DstIP = ctx.DstIP
+	l.SrcPort = ctx.SrcPort
+	l.DstPort = ctx.DstPort
+}
+
+// Src returns the source address of the audit record.
+func (l *L2TP) Src() string {
+	return l.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (l *L2TP) Dst() string {
+	return l.DstIP
+}
@@ -0,0 +1,104 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsLACP = []string{
+	"Timestamp",
+	"Version",               // int32
+	"ActorSystemPriority",   // int32
+	"ActorSystem",           // string
+	"ActorKey",              // int32
+	"ActorPortPriority",     // int32
+	"ActorPort",             // int32
+	"ActorState",            // []string
+	"PartnerSystemPriority", // int32
+	"PartnerSystem",         // string
+	"PartnerKey",            // int32
+	"PartnerPortPriority",   // int32
+	"PartnerPort",           // int32
+	"PartnerState",          // []string
+	"CollectorMaxDelay",     // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (l *LACP) CSVHeader() []string {
+	return filter(fieldsLACP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (l *LACP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(l.Timestamp),
+		formatInt32(l.Version),               // int32
+		formatInt32(l.ActorSystemPriority),   // int32
+		l.ActorSystem,                        // string
+		formatInt32(l.ActorKey),              // int32
+		formatInt32(l.ActorPortPriority),     // int32
+		formatInt32(l.ActorPort),             // int32
+		join(l.ActorState...),                // []string
+		formatInt32(l.PartnerSystemPriority), // int32
+		l.PartnerSystem,                      // string
+		formatInt32(l.PartnerKey),            // int32
+		formatInt32(l.PartnerPortPriority),   // int32
+		formatInt32(l.PartnerPort),           // int32
+		join(l.PartnerState...),              // []string
+		formatInt32(l.CollectorMaxDelay),     // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (l *LACP) Time() int64 {
+	return l.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (l *LACP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	l.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(l)
+}
+
+var lacpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_LACP.String()),
+		Help: Type_NC_LACP.String() + " audit records",
+	},
+	[]string{"ActorSystem", "PartnerSystem"},
+)
+
+// Inc increments the metrics for the audit record.
+func (l *LACP) Inc() {
+	lacpMetric.WithLabelValues(l.ActorSystem, l.PartnerSystem).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (l *LACP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (l *LACP) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (l *LACP) Dst() string {
+	return ""
+}
@@ -0,0 +1,105 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsLLMNR = []string{
+	"Timestamp",
+	"SrcIP",          // string
+	"DstIP",          // string
+	"SrcPort",        // int32
+	"DstPort",        // int32
+	"ID",             // int32
+	"Response",       // bool
+	"Conflict",       // bool
+	"Tentative",      // bool
+	"ResponseCode",   // int32
+	"Name",           // string
+	"Type",           // string
+	"Answers",        // []string
+	"ResponderNames", // int32
+	"Poisoning",      // bool
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (l *LLMNR) CSVHeader() []string {
+	return filter(fieldsLLMNR)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (l *LLMNR) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(l.Timestamp),
+		l.SrcIP,                         // string
+		l.DstIP,                         // string
+		formatInt32(l.SrcPort),          // int32
+		formatInt32(l.DstPort),          // int32
+		formatInt32(l.ID),               // int32
+		strconv.FormatBool(l.Response),  // bool
+		strconv.FormatBool(l.Conflict),  // bool
+		strconv.FormatBool(l.Tentative), // bool
+		formatInt32(l.ResponseCode),     // int32
+		l.Name,                          // string
+		l.Type,                          // string
+		join(l.Answers...),              // []string
+		formatInt32(l.ResponderNames),   // int32
+		strconv.FormatBool(l.Poisoning), // bool
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (l *LLMNR) Time() int64 {
+	return l.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (l *LLMNR) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	l.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(l)
+}
+
+var llmnrMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_LLMNR.String()),
+		Help: Type_NC_LLMNR.String() + " audit records",
+	},
+	[]string{"Response", "Type", "Poisoning"},
+)
+
+// Inc increments the metrics for the audit record.
+func (l *LLMNR) Inc() {
+	llmnrMetric.WithLabelValues(strconv.FormatBool(l.Response), l.Type, strconv.FormatBool(l.Poisoning)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (l *LLMNR) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (l *LLMNR) Src() string {
+	return l.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (l *LLMNR) Dst() string {
+	return l.DstIP
+}
@@ -0,0 +1,105 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsMDNS = []string{
+	"Timestamp",
+	"SrcIP",        // string
+	"DstIP",        // string
+	"SrcPort",      // int32
+	"DstPort",      // int32
+	"ID",           // int32
+	"Response",     // bool
+	"Questions",    // []string
+	"Hostnames",    // []string
+	"Addresses",    // []string
+	"ServiceTypes", // []string
+	"Services",     // []string
+	"Targets",      // []string
+	"TXT",          // []string
+	"Models",       // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (m *MDNS) CSVHeader() []string {
+	return filter(fieldsMDNS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (m *MDNS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(m.Timestamp),
+		m.SrcIP,                        // string
+		m.DstIP,                        // string
+		formatInt32(m.SrcPort),         // int32
+		formatInt32(m.DstPort),         // int32
+		formatInt32(m.ID),              // int32
+		strconv.FormatBool(m.Response), // bool
+		join(m.Questions...),           // []string
+		join(m.Hostnames...),           // []string
+		join(m.Addresses...),           // []string
+		join(m.ServiceTypes...),        // []string
+		join(m.Services...),            // []string
+		join(m.Targets...),             // []string
+		join(m.TXT...),                 // []string
+		join(m.Models...),              // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (m *MDNS) Time() int64 {
+	return m.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (m *MDNS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	m.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(m)
+}
+
+var mdnsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_MDNS.String()),
+		Help: Type_NC_MDNS.String() + " audit records",
+	},
+	[]string{"Response"},
+)
+
+// Inc increments the metrics for the audit record.
+func (m *MDNS) Inc() {
+	mdnsMetric.WithLabelValues(strconv.FormatBool(m.Response)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (m *MDNS) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (m *MDNS) Src() string {
+	return m.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (m *MDNS) Dst() string {
+	return m.DstIP
+}
@@ -85,4 +85,46 @@ var Metrics = []prometheus.Collector{
 	lldMetric,
 	dhcp6Metric,
 	bfdMetric,
+	snmpMetric,
+	tftpMetric,
+	callMetric,
+	ircMetric,
+	telnetMetric,
+	webSocketMessageMetric,
+	tokenMetric,
+	proxyTunnelMetric,
+	modbusTransactionMetric,
+	enipTransactionMetric,
+	controlActionMetric,
+	dnp3Metric,
+	iec104Metric,
+	s7CommMetric,
+	bacnetMetric,
+	opcuaMetric,
+	mdnsMetric,
+	llmnrMetric,
+	nbnsMetric,
+	pppoeMetric,
+	pppMetric,
+	l2tpMetric,
+	stpMetric,
+	lacpMetric,
+	dtpMetric,
+	vtpMetric,
+	stpSummaryMetric,
+	dot11BeaconMetric,
+	dot11ProbeRequestMetric,
+	dot11DeauthMetric,
+	dot11HandshakeMetric,
+	canMetric,
+	canSummaryMetric,
+	usbKeystrokesMetric,
+	usbMouseMetric,
+	usbStorageMetric,
+	radiusMetric,
+	radiusSessionMetric,
+	tacacsMetric,
+	syslogMetric,
+	flowExporterMetric,
+	erspanMetric,
 }
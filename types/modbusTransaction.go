@@ -0,0 +1,114 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsModbusTransaction = []string{
+	"Timestamp",
+	"Duration",      // int64
+	"ClientIP",      // string
+	"ServerIP",      // string
+	"ClientPort",    // int32
+	"ServerPort",    // int32
+	"TransactionID", // int32
+	"UnitID",        // int32
+	"FunctionCode",  // int32
+	"Function",      // string
+	"Address",       // int32
+	"Quantity",      // int32
+	"Values",        // []int32
+	"Write",         // bool
+	"Answered",      // bool
+	"Exception",     // bool
+	"ExceptionCode", // int32
+	"RequestData",   // []byte
+	"ResponseData",  // []byte
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (m *ModbusTransaction) CSVHeader() []string {
+	return filter(fieldsModbusTransaction)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (m *ModbusTransaction) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(m.Timestamp),
+		formatInt64(m.Duration),            // int64
+		m.ClientIP,                         // string
+		m.ServerIP,                         // string
+		formatInt32(m.ClientPort),          // int32
+		formatInt32(m.ServerPort),          // int32
+		formatInt32(m.TransactionID),       // int32
+		formatInt32(m.UnitID),              // int32
+		formatInt32(m.FunctionCode),        // int32
+		m.Function,                         // string
+		formatInt32(m.Address),             // int32
+		formatInt32(m.Quantity),            // int32
+		joinInts(m.Values),                 // []int32
+		strconv.FormatBool(m.Write),        // bool
+		strconv.FormatBool(m.Answered),     // bool
+		strconv.FormatBool(m.Exception),    // bool
+		formatInt32(m.ExceptionCode),       // int32
+		hex.EncodeToString(m.RequestData),  // []byte
+		hex.EncodeToString(m.ResponseData), // []byte
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (m *ModbusTransaction) Time() int64 {
+	return m.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (m *ModbusTransaction) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	m.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(m)
+}
+
+var modbusTransactionMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_ModbusTransaction.String()),
+		Help: Type_NC_ModbusTransaction.String() + " audit records",
+	},
+	[]string{"Function", "Write", "Exception"},
+)
+
+// Inc increments the metrics for the audit record.
+func (m *ModbusTransaction) Inc() {
+	modbusTransactionMetric.WithLabelValues(m.Function, strconv.FormatBool(m.Write), strconv.FormatBool(m.Exception)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (m *ModbusTransaction) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (m *ModbusTransaction) Src() string {
+	return m.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (m *ModbusTransaction) Dst() string {
+	return m.ServerIP
+}
@@ -0,0 +1,111 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsNBNS = []string{
+	"Timestamp",
+	"SrcIP",          // string
+	"DstIP",          // string
+	"SrcPort",        // int32
+	"DstPort",        // int32
+	"ID",             // int32
+	"Response",       // bool
+	"Opcode",         // string
+	"Authoritative",  // bool
+	"Broadcast",      // bool
+	"ResponseCode",   // int32
+	"Name",           // string
+	"Type",           // string
+	"Addresses",      // []string
+	"Names",          // []string
+	"MAC",            // string
+	"ResponderNames", // int32
+	"Poisoning",      // bool
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (n *NBNS) CSVHeader() []string {
+	return filter(fieldsNBNS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (n *NBNS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(n.Timestamp),
+		n.SrcIP,                             // string
+		n.DstIP,                             // string
+		formatInt32(n.SrcPort),              // int32
+		formatInt32(n.DstPort),              // int32
+		formatInt32(n.ID),                   // int32
+		strconv.FormatBool(n.Response),      // bool
+		n.Opcode,                            // string
+		strconv.FormatBool(n.Authoritative), // bool
+		strconv.FormatBool(n.Broadcast),     // bool
+		formatInt32(n.ResponseCode),         // int32
+		n.Name,                              // string
+		n.Type,                              // string
+		join(n.Addresses...),                // []string
+		join(n.Names...),                    // []string
+		n.MAC,                               // string
+		formatInt32(n.ResponderNames),       // int32
+		strconv.FormatBool(n.Poisoning),     // bool
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (n *NBNS) Time() int64 {
+	return n.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (n *NBNS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	n.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(n)
+}
+
+var nbnsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_NBNS.String()),
+		Help: Type_NC_NBNS.String() + " audit records",
+	},
+	[]string{"Opcode", "Type", "Poisoning"},
+)
+
+// Inc increments the metrics for the audit record.
+func (n *NBNS) Inc() {
+	nbnsMetric.WithLabelValues(n.Opcode, n.Type, strconv.FormatBool(n.Poisoning)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (n *NBNS) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (n *NBNS) Src() string {
+	return n.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (n *NBNS) Dst() string {
+	return n.DstIP
+}
@@ -0,0 +1,130 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsOPCUA = []string{
+	"Timestamp",
+	"ClientIP",          // string
+	"ServerIP",          // string
+	"ClientPort",        // int32
+	"ServerPort",        // int32
+	"IsClient",          // bool
+	"MessageType",       // string
+	"ChunkType",         // string
+	"SecureChannelID",   // uint32
+	"RequestID",         // uint32
+	"SequenceNumber",    // uint32
+	"EndpointURL",       // string
+	"SecurityPolicyURI", // string
+	"SecurityMode",      // string
+	"Encrypted",         // bool
+	"ServiceID",         // int32
+	"Service",           // string
+	"RequestHandle",     // uint32
+	"StatusCode",        // uint32
+	"Error",             // string
+	"NodeIDs",           // []string
+	"ApplicationURI",    // string
+	"ProductURI",        // string
+	"ApplicationName",   // string
+	"User",              // string
+	"Payload",           // []byte
+	"Endpoints",         // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (o *OPCUA) CSVHeader() []string {
+	return filter(fieldsOPCUA)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (o *OPCUA) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(o.Timestamp),
+		o.ClientIP,                      // string
+		o.ServerIP,                      // string
+		formatInt32(o.ClientPort),       // int32
+		formatInt32(o.ServerPort),       // int32
+		strconv.FormatBool(o.IsClient),  // bool
+		o.MessageType,                   // string
+		o.ChunkType,                     // string
+		formatUint32(o.SecureChannelID), // uint32
+		formatUint32(o.RequestID),       // uint32
+		formatUint32(o.SequenceNumber),  // uint32
+		o.EndpointURL,                   // string
+		o.SecurityPolicyURI,             // string
+		o.SecurityMode,                  // string
+		strconv.FormatBool(o.Encrypted), // bool
+		formatInt32(o.ServiceID),        // int32
+		o.Service,                       // string
+		formatUint32(o.RequestHandle),   // uint32
+		formatUint32(o.StatusCode),      // uint32
+		o.Error,                         // string
+		join(o.NodeIDs...),              // []string
+		o.ApplicationURI,                // string
+		o.ProductURI,                    // string
+		o.ApplicationName,               // string
+		o.User,                          // string
+		hex.EncodeToString(o.Payload),   // []byte
+		join(o.Endpoints...),            // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (o *OPCUA) Time() int64 {
+	return o.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (o *OPCUA) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	o.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(o)
+}
+
+var opcuaMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_OPCUA.String()),
+		Help: Type_NC_OPCUA.String() + " audit records",
+	},
+	[]string{"MessageType", "Service"},
+)
+
+// Inc increments the metrics for the audit record.
+func (o *OPCUA) Inc() {
+	opcuaMetric.WithLabelValues(o.MessageType, o.Service).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (o *OPCUA) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (o *OPCUA) Src() string {
+	return o.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (o *OPCUA) Dst() string {
+	return o.ServerIP
+}
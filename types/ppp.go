@@ -0,0 +1,119 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsPPP = []string{
+	"Timestamp",
+	"Protocol",     // string
+	"Code",         // string
+	"Identifier",   // int32
+	"Options",      // []string
+	"MRU",          // int32
+	"AuthProtocol", // string
+	"MagicNumber",  // uint32
+	"IPAddress",    // string
+	"PrimaryDNS",   // string
+	"SecondaryDNS", // string
+	"InterfaceID",  // string
+	"User",         // string
+	"Challenge",    // string
+	"Response",     // string
+	"Message",      // string
+	"SrcIP",        // string
+	"DstIP",        // string
+	"SrcPort",      // int32
+	"DstPort",      // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (p *PPP) CSVHeader() []string {
+	return filter(fieldsPPP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (p *PPP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(p.Timestamp),
+		p.Protocol,                  // string
+		p.Code,                      // string
+		formatInt32(p.Identifier),   // int32
+		join(p.Options...),          // []string
+		formatInt32(p.MRU),          // int32
+		p.AuthProtocol,              // string
+		formatUint32(p.MagicNumber), // uint32
+		p.IPAddress,                 // string
+		p.PrimaryDNS,                // string
+		p.SecondaryDNS,              // string
+		p.InterfaceID,               // string
+		p.User,                      // string
+		p.Challenge,                 // string
+		p.Response,                  // string
+		p.Message,                   // string
+		p.SrcIP,                     // string
+		p.DstIP,                     // string
+		formatInt32(p.SrcPort),      // int32
+		formatInt32(p.DstPort),      // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (p *PPP) Time() int64 {
+	return p.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (p *PPP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	p.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(p)
+}
+
+var pppMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_PPP.String()),
+		Help: Type_NC_PPP.String() + " audit records",
+	},
+	[]string{"Protocol", "Code"},
+)
+
+// Inc increments the metrics for the audit record.
+func (p *PPP) Inc() {
+	pppMetric.WithLabelValues(p.Protocol, p.Code).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (p *PPP) SetPacketContext(ctx *PacketContext) {
+	p.SrcIP = ctx.SrcIP
+	p.DstIP = ctx.DstIP
+	p.SrcPort = ctx.SrcPort
+	p.DstPort = ctx.DstPort
+}
+
+// Src returns the source address of the audit record.
+func (p *PPP) Src() string {
+	return p.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (p *PPP) Dst() string {
+	return p.DstIP
+}
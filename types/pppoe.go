@@ -0,0 +1,102 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsPPPoE = []string{
+	"Timestamp",
+	"Version",        // int32
+	"Type",           // int32
+	"Code",           // string
+	"SessionID",      // int32
+	"ServiceName",    // string
+	"ACName",         // string
+	"HostUniq",       // string
+	"ACCookie",       // string
+	"RelaySessionID", // string
+	"MaxPayload",     // int32
+	"VendorSpecific", // []string
+	"Error",          // string
+	"Tags",           // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (p *PPPoE) CSVHeader() []string {
+	return filter(fieldsPPPoE)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (p *PPPoE) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(p.Timestamp),
+		formatInt32(p.Version),    // int32
+		formatInt32(p.Type),       // int32
+		p.Code,                    // string
+		formatInt32(p.SessionID),  // int32
+		p.ServiceName,             // string
+		p.ACName,                  // string
+		p.HostUniq,                // string
+		p.ACCookie,                // string
+		p.RelaySessionID,          // string
+		formatInt32(p.MaxPayload), // int32
+		join(p.VendorSpecific...), // []string
+		p.Error,                   // string
+		join(p.Tags...),           // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (p *PPPoE) Time() int64 {
+	return p.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (p *PPPoE) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	p.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(p)
+}
+
+var pppoeMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_PPPoE.String()),
+		Help: Type_NC_PPPoE.String() + " audit records",
+	},
+	[]string{"Code"},
+)
+
+// Inc increments the metrics for the audit record.
+func (p *PPPoE) Inc() {
+	pppoeMetric.WithLabelValues(p.Code).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (p *PPPoE) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (p *PPPoE) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (p *PPPoE) Dst() string {
+	return ""
+}
@@ -0,0 +1,111 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsProxyTunnel = []string{
+	"Timestamp",
+	"Protocol",    // string
+	"Command",     // string
+	"Flow",        // string
+	"ClientIP",    // string
+	"ClientPort",  // int32
+	"ProxyIP",     // string
+	"ProxyPort",   // int32
+	"TargetHost",  // string
+	"TargetIP",    // string
+	"TargetPort",  // int32
+	"AuthMethod",  // string
+	"User",        // string
+	"Success",     // bool
+	"Status",      // int32
+	"Decoder",     // string
+	"ClientBytes", // int64
+	"ServerBytes", // int64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (t *ProxyTunnel) CSVHeader() []string {
+	return filter(fieldsProxyTunnel)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (t *ProxyTunnel) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(t.Timestamp),
+		t.Protocol,                    // string
+		t.Command,                     // string
+		t.Flow,                        // string
+		t.ClientIP,                    // string
+		formatInt32(t.ClientPort),     // int32
+		t.ProxyIP,                     // string
+		formatInt32(t.ProxyPort),      // int32
+		t.TargetHost,                  // string
+		t.TargetIP,                    // string
+		formatInt32(t.TargetPort),     // int32
+		t.AuthMethod,                  // string
+		t.User,                        // string
+		strconv.FormatBool(t.Success), // bool
+		formatInt32(t.Status),         // int32
+		t.Decoder,                     // string
+		formatInt64(t.ClientBytes),    // int64
+		formatInt64(t.ServerBytes),    // int64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (t *ProxyTunnel) Time() int64 {
+	return t.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (t *ProxyTunnel) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	t.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(t)
+}
+
+var proxyTunnelMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_ProxyTunnel.String()),
+		Help: Type_NC_ProxyTunnel.String() + " audit records",
+	},
+	[]string{"Protocol", "Command", "Success", "Decoder"},
+)
+
+// Inc increments the metrics for the audit record.
+func (t *ProxyTunnel) Inc() {
+	proxyTunnelMetric.WithLabelValues(t.Protocol, t.Command, strconv.FormatBool(t.Success), t.Decoder).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (t *ProxyTunnel) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (t *ProxyTunnel) Src() string {
+	return t.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (t *ProxyTunnel) Dst() string {
+	return t.ProxyIP
+}
@@ -0,0 +1,127 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsRADIUS = []string{
+	"Timestamp",
+	"Code",               // int32
+	"CodeName",           // string
+	"Identifier",         // int32
+	"Length",             // int32
+	"UserName",           // string
+	"NASIPAddress",       // string
+	"NASIdentifier",      // string
+	"CallingStationID",   // string
+	"CalledStationID",    // string
+	"FramedIPAddress",    // string
+	"ReplyMessage",       // string
+	"AcctStatusType",     // string
+	"AcctSessionID",      // string
+	"AcctSessionTime",    // uint32
+	"AcctInputOctets",    // uint64
+	"AcctOutputOctets",   // uint64
+	"AcctTerminateCause", // string
+	"Attributes",         // []string
+	"VendorAttributes",   // []string
+	"SrcIP",              // string
+	"DstIP",              // string
+	"SrcPort",            // int32
+	"DstPort",            // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (r *RADIUS) CSVHeader() []string {
+	return filter(fieldsRADIUS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (r *RADIUS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(r.Timestamp),
+		formatInt32(r.Code),              // int32
+		r.CodeName,                       // string
+		formatInt32(r.Identifier),        // int32
+		formatInt32(r.Length),            // int32
+		r.UserName,                       // string
+		r.NASIPAddress,                   // string
+		r.NASIdentifier,                  // string
+		r.CallingStationID,               // string
+		r.CalledStationID,                // string
+		r.FramedIPAddress,                // string
+		r.ReplyMessage,                   // string
+		r.AcctStatusType,                 // string
+		r.AcctSessionID,                  // string
+		formatUint32(r.AcctSessionTime),  // uint32
+		formatUint64(r.AcctInputOctets),  // uint64
+		formatUint64(r.AcctOutputOctets), // uint64
+		r.AcctTerminateCause,             // string
+		join(r.Attributes...),            // []string
+		join(r.VendorAttributes...),      // []string
+		r.SrcIP,                          // string
+		r.DstIP,                          // string
+		formatInt32(r.SrcPort),           // int32
+		formatInt32(r.DstPort),           // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (r *RADIUS) Time() int64 {
+	return r.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (r *RADIUS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	r.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(r)
+}
+
+var radiusMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_RADIUS.String()),
+		Help: Type_NC_RADIUS.String() + " audit records",
+	},
+	[]string{"CodeName"},
+)
+
+// Inc increments the metrics for the audit record.
+func (r *RADIUS) Inc() {
+	radiusMetric.WithLabelValues(r.CodeName).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (r *RADIUS) SetPacketContext(ctx *PacketContext) {
+	r.SrcIP = ctx.SrcIP
+	r.DstIP = ctx.DstIP
+	r.SrcPort = ctx.SrcPort
+	r.DstPort = ctx.DstPort
+}
+
+// Src returns the source address of the audit record.
+func (r *RADIUS) Src() string {
+	return r.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (r *RADIUS) Dst() string {
+	return r.DstIP
+}
@@ -0,0 +1,110 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsRADIUSSession = []string{
+	"Timestamp",
+	"LastSeen",         // int64
+	"SessionID",        // string
+	"UserName",         // string
+	"NASIPAddress",     // string
+	"NASIdentifier",    // string
+	"CallingStationID", // string
+	"CalledStationID",  // string
+	"FramedIPAddress",  // string
+	"SessionTime",      // uint32
+	"InputOctets",      // uint64
+	"OutputOctets",     // uint64
+	"InterimUpdates",   // int32
+	"Stopped",          // bool
+	"TerminateCause",   // string
+	"ClientIP",         // string
+	"ServerIP",         // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *RADIUSSession) CSVHeader() []string {
+	return filter(fieldsRADIUSSession)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *RADIUSSession) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		formatTimestamp(s.LastSeen),   // int64
+		s.SessionID,                   // string
+		s.UserName,                    // string
+		s.NASIPAddress,                // string
+		s.NASIdentifier,               // string
+		s.CallingStationID,            // string
+		s.CalledStationID,             // string
+		s.FramedIPAddress,             // string
+		formatUint32(s.SessionTime),   // uint32
+		formatUint64(s.InputOctets),   // uint64
+		formatUint64(s.OutputOctets),  // uint64
+		formatInt32(s.InterimUpdates), // int32
+		strconv.FormatBool(s.Stopped), // bool
+		s.TerminateCause,              // string
+		s.ClientIP,                    // string
+		s.ServerIP,                    // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *RADIUSSession) Time() int64 {
+	return s.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *RADIUSSession) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+	s.LastSeen /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var radiusSessionMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_RADIUSSession.String()),
+		Help: Type_NC_RADIUSSession.String() + " audit records",
+	},
+	[]string{"Stopped", "TerminateCause"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *RADIUSSession) Inc() {
+	radiusSessionMetric.WithLabelValues(strconv.FormatBool(s.Stopped), s.TerminateCause).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *RADIUSSession) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (s *RADIUSSession) Src() string {
+	return s.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (s *RADIUSSession) Dst() string {
+	return s.ServerIP
+}
@@ -0,0 +1,152 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsS7Comm = []string{
+	"Timestamp",
+	"ClientIP",            // string
+	"ServerIP",            // string
+	"ClientPort",          // int32
+	"ServerPort",          // int32
+	"IsClient",            // bool
+	"Protocol",            // string
+	"ROSCTR",              // int32
+	"ROSCTRName",          // string
+	"PDUReference",        // int32
+	"ErrorClass",          // int32
+	"ErrorCode",           // int32
+	"FunctionCode",        // int32
+	"Function",            // string
+	"Items",               // []*S7Item
+	"ReturnCodes",         // []int32
+	"BlockType",           // string
+	"BlockNumber",         // int32
+	"Filename",            // string
+	"PIService",           // string
+	"UserdataGroup",       // int32
+	"UserdataSubfunction", // int32
+	"PlusVersion",         // int32
+	"PlusOpcode",          // int32
+	"Control",             // bool
+	"Payload",             // []byte
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *S7Comm) CSVHeader() []string {
+	return filter(fieldsS7Comm)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *S7Comm) CSVRecord() []string {
+	items := make([]string, 0, len(s.Items))
+	for _, v := range s.Items {
+		items = append(items, v.toString())
+	}
+
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		s.ClientIP,                         // string
+		s.ServerIP,                         // string
+		formatInt32(s.ClientPort),          // int32
+		formatInt32(s.ServerPort),          // int32
+		strconv.FormatBool(s.IsClient),     // bool
+		s.Protocol,                         // string
+		formatInt32(s.ROSCTR),              // int32
+		s.ROSCTRName,                       // string
+		formatInt32(s.PDUReference),        // int32
+		formatInt32(s.ErrorClass),          // int32
+		formatInt32(s.ErrorCode),           // int32
+		formatInt32(s.FunctionCode),        // int32
+		s.Function,                         // string
+		strings.Join(items, ""),            // []*S7Item
+		joinInts(s.ReturnCodes),            // []int32
+		s.BlockType,                        // string
+		formatInt32(s.BlockNumber),         // int32
+		s.Filename,                         // string
+		s.PIService,                        // string
+		formatInt32(s.UserdataGroup),       // int32
+		formatInt32(s.UserdataSubfunction), // int32
+		formatInt32(s.PlusVersion),         // int32
+		formatInt32(s.PlusOpcode),          // int32
+		strconv.FormatBool(s.Control),      // bool
+		hex.EncodeToString(s.Payload),      // []byte
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *S7Comm) Time() int64 {
+	return s.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *S7Comm) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var s7CommMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_S7Comm.String()),
+		Help: Type_NC_S7Comm.String() + " audit records",
+	},
+	[]string{"Protocol", "ROSCTRName", "Function"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *S7Comm) Inc() {
+	s7CommMetric.WithLabelValues(s.Protocol, s.ROSCTRName, s.Function).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *S7Comm) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (s *S7Comm) Src() string {
+	return s.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (s *S7Comm) Dst() string {
+	return s.ServerIP
+}
+
+func (i *S7Item) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(i.Area)
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(i.DBNumber))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(i.Address))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(i.Bit))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(i.TransportSize))
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(i.Length))
+	b.WriteString(StructureEnd)
+
+	return b.String()
+}
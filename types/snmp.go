@@ -0,0 +1,152 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsSNMP = []string{
+	"Timestamp",
+	"Version",       // int32
+	"Community",     // string
+	"PDUType",       // string
+	"RequestID",     // int32
+	"ErrorStatus",   // int32
+	"ErrorIndex",    // int32
+	"Variables",     // []*SNMPVariable
+	"Enterprise",    // string
+	"AgentAddr",     // string
+	"GenericTrap",   // int32
+	"SpecificTrap",  // int32
+	"Uptime",        // int64
+	"MsgID",         // int32
+	"MsgFlags",      // int32
+	"SecurityModel", // int32
+	"EngineID",      // string
+	"EngineBoots",   // int32
+	"EngineTime",    // int32
+	"UserName",      // string
+	"AuthParams",    // string
+	"Encrypted",     // bool
+	"SrcIP",
+	"DstIP",
+	"SrcPort",
+	"DstPort",
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *SNMP) CSVHeader() []string {
+	return filter(fieldsSNMP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *SNMP) CSVRecord() []string {
+	vars := make([]string, 0, len(s.Variables))
+	for _, v := range s.Variables {
+		vars = append(vars, v.toString())
+	}
+
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		formatInt32(s.Version),          // int32
+		s.Community,                     // string
+		s.PDUType,                       // string
+		formatInt32(s.RequestID),        // int32
+		formatInt32(s.ErrorStatus),      // int32
+		formatInt32(s.ErrorIndex),       // int32
+		strings.Join(vars, ""),          // []*SNMPVariable
+		s.Enterprise,                    // string
+		s.AgentAddr,                     // string
+		formatInt32(s.GenericTrap),      // int32
+		formatInt32(s.SpecificTrap),     // int32
+		formatInt64(s.Uptime),           // int64
+		formatInt32(s.MsgID),            // int32
+		formatInt32(s.MsgFlags),         // int32
+		formatInt32(s.SecurityModel),    // int32
+		s.EngineID,                      // string
+		formatInt32(s.EngineBoots),      // int32
+		formatInt32(s.EngineTime),       // int32
+		s.UserName,                      // string
+		s.AuthParams,                    // string
+		strconv.FormatBool(s.Encrypted), // bool
+		s.SrcIP,
+		s.DstIP,
+		formatInt32(s.SrcPort),
+		formatInt32(s.DstPort),
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *SNMP) Time() int64 {
+	return s.Timestamp
+}
+
+func (v *SNMPVariable) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(v.OID)
+	b.WriteString(FieldSeparator)
+	b.WriteString(v.Name)
+	b.WriteString(FieldSeparator)
+	b.WriteString(v.Type)
+	b.WriteString(FieldSeparator)
+	b.WriteString(v.Value)
+	b.WriteString(StructureEnd)
+
+	return b.String()
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *SNMP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var snmpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_SNMP.String()),
+		Help: Type_NC_SNMP.String() + " audit records",
+	},
+	[]string{"Version", "PDUType"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *SNMP) Inc() {
+	snmpMetric.WithLabelValues(formatInt32(s.Version), s.PDUType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *SNMP) SetPacketContext(ctx *PacketContext) {
+	s.SrcIP = ctx.SrcIP
+	s.DstIP = ctx.DstIP
+	s.SrcPort = ctx.SrcPort
+	s.DstPort = ctx.DstPort
+}
+
+// Src returns the source address of the audit record.
+func (s *SNMP) Src() string {
+	return s.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (s *SNMP) Dst() string {
+	return s.DstIP
+}
@@ -0,0 +1,121 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsSTP = []string{
+	"Timestamp",
+	"Version",           // string
+	"BPDUType",          // string
+	"Flags",             // int32
+	"TopologyChange",    // bool
+	"TopologyChangeAck", // bool
+	"Proposal",          // bool
+	"Agreement",         // bool
+	"Learning",          // bool
+	"Forwarding",        // bool
+	"PortRole",          // string
+	"RootID",            // string
+	"RootPathCost",      // uint32
+	"BridgeID",          // string
+	"PortID",            // int32
+	"MessageAge",        // int32
+	"MaxAge",            // int32
+	"HelloTime",         // int32
+	"ForwardDelay",      // int32
+	"MSTConfigName",     // string
+	"MSTConfigRevision", // int32
+	"MSTIs",             // int32
+	"VLAN",              // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *STP) CSVHeader() []string {
+	return filter(fieldsSTP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *STP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		s.Version,                               // string
+		s.BPDUType,                              // string
+		formatInt32(s.Flags),                    // int32
+		strconv.FormatBool(s.TopologyChange),    // bool
+		strconv.FormatBool(s.TopologyChangeAck), // bool
+		strconv.FormatBool(s.Proposal),          // bool
+		strconv.FormatBool(s.Agreement),         // bool
+		strconv.FormatBool(s.Learning),          // bool
+		strconv.FormatBool(s.Forwarding),        // bool
+		s.PortRole,                              // string
+		s.RootID,                                // string
+		formatUint32(s.RootPathCost),            // uint32
+		s.BridgeID,                              // string
+		formatInt32(s.PortID),                   // int32
+		formatInt32(s.MessageAge),               // int32
+		formatInt32(s.MaxAge),                   // int32
+		formatInt32(s.HelloTime),                // int32
+		formatInt32(s.ForwardDelay),             // int32
+		s.MSTConfigName,                         // string
+		formatInt32(s.MSTConfigRevision),        // int32
+		formatInt32(s.MSTIs),                    // int32
+		formatInt32(s.VLAN),                     // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *STP) Time() int64 {
+	return s.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *STP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var stpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_STP.String()),
+		Help: Type_NC_STP.String() + " audit records",
+	},
+	[]string{"Version", "BPDUType"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *STP) Inc() {
+	stpMetric.WithLabelValues(s.Version, s.BPDUType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *STP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (s *STP) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (s *STP) Dst() string {
+	return ""
+}
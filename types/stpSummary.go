@@ -0,0 +1,94 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsSTPSummary = []string{
+	"Timestamp",
+	"VLAN",              // int32
+	"Root",              // string
+	"RootBridges",       // []string
+	"RootChanges",       // int32
+	"TopologyChanges",   // int32
+	"Bridges",           // []string
+	"Sources",           // []string
+	"UnexpectedSources", // []string
+	"NumBPDUs",          // int64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *STPSummary) CSVHeader() []string {
+	return filter(fieldsSTPSummary)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *STPSummary) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		formatInt32(s.VLAN),            // int32
+		s.Root,                         // string
+		join(s.RootBridges...),         // []string
+		formatInt32(s.RootChanges),     // int32
+		formatInt32(s.TopologyChanges), // int32
+		join(s.Bridges...),             // []string
+		join(s.Sources...),             // []string
+		join(s.UnexpectedSources...),   // []string
+		formatInt64(s.NumBPDUs),        // int64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *STPSummary) Time() int64 {
+	return s.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *STPSummary) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var stpSummaryMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_STPSummary.String()),
+		Help: Type_NC_STPSummary.String() + " audit records",
+	},
+	[]string{"VLAN", "Root"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *STPSummary) Inc() {
+	stpSummaryMetric.WithLabelValues(formatInt32(s.VLAN), s.Root).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *STPSummary) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (s *STPSummary) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (s *STPSummary) Dst() string {
+	return ""
+}
@@ -0,0 +1,130 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsSyslog = []string{
+	"Timestamp",
+	"SrcIP",            // string
+	"DstIP",            // string
+	"SrcPort",          // int32
+	"DstPort",          // int32
+	"Transport",        // string
+	"Format",           // string
+	"Priority",         // int32
+	"Facility",         // int32
+	"FacilityName",     // string
+	"Severity",         // int32
+	"SeverityName",     // string
+	"Version",          // int32
+	"MessageTimestamp", // string
+	"Hostname",         // string
+	"AppName",          // string
+	"ProcID",           // string
+	"MsgID",            // string
+	"StructuredData",   // []string
+	"Message",          // string
+	"EmbeddedFormat",   // string
+	"Vendor",           // string
+	"Product",          // string
+	"ProductVersion",   // string
+	"EventID",          // string
+	"EventName",        // string
+	"EventSeverity",    // string
+	"Extensions",       // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (s *Syslog) CSVHeader() []string {
+	return filter(fieldsSyslog)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (s *Syslog) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(s.Timestamp),
+		s.SrcIP,                   // string
+		s.DstIP,                   // string
+		formatInt32(s.SrcPort),    // int32
+		formatInt32(s.DstPort),    // int32
+		s.Transport,               // string
+		s.Format,                  // string
+		formatInt32(s.Priority),   // int32
+		formatInt32(s.Facility),   // int32
+		s.FacilityName,            // string
+		formatInt32(s.Severity),   // int32
+		s.SeverityName,            // string
+		formatInt32(s.Version),    // int32
+		s.MessageTimestamp,        // string
+		s.Hostname,                // string
+		s.AppName,                 // string
+		s.ProcID,                  // string
+		s.MsgID,                   // string
+		join(s.StructuredData...), // []string
+		s.Message,                 // string
+		s.EmbeddedFormat,          // string
+		s.Vendor,                  // string
+		s.Product,                 // string
+		s.ProductVersion,          // string
+		s.EventID,                 // string
+		s.EventName,               // string
+		s.EventSeverity,           // string
+		join(s.Extensions...),     // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (s *Syslog) Time() int64 {
+	return s.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (s *Syslog) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	s.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(s)
+}
+
+var syslogMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Syslog.String()),
+		Help: Type_NC_Syslog.String() + " audit records",
+	},
+	[]string{"FacilityName", "SeverityName", "EmbeddedFormat"},
+)
+
+// Inc increments the metrics for the audit record.
+func (s *Syslog) Inc() {
+	syslogMetric.WithLabelValues(s.FacilityName, s.SeverityName, s.EmbeddedFormat).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (s *Syslog) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (s *Syslog) Src() string {
+	return s.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (s *Syslog) Dst() string {
+	return s.DstIP
+}
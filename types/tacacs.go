@@ -0,0 +1,127 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsTACACS = []string{
+	"Timestamp",
+	"ClientIP",       // string
+	"ServerIP",       // string
+	"ClientPort",     // int32
+	"ServerPort",     // int32
+	"IsClient",       // bool
+	"Version",        // int32
+	"Type",           // string
+	"SequenceNumber", // int32
+	"SessionID",      // uint32
+	"Flags",          // int32
+	"Encrypted",      // bool
+	"Decrypted",      // bool
+	"Message",        // string
+	"Action",         // string
+	"AuthenType",     // string
+	"Service",        // string
+	"PrivLevel",      // int32
+	"User",           // string
+	"Port",           // string
+	"RemoteAddress",  // string
+	"Status",         // string
+	"ServerMessage",  // string
+	"Arguments",      // []string
+	"Command",        // string
+	"Accounting",     // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (t *TACACS) CSVHeader() []string {
+	return filter(fieldsTACACS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (t *TACACS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(t.Timestamp),
+		t.ClientIP,                      // string
+		t.ServerIP,                      // string
+		formatInt32(t.ClientPort),       // int32
+		formatInt32(t.ServerPort),       // int32
+		strconv.FormatBool(t.IsClient),  // bool
+		formatInt32(t.Version),          // int32
+		t.Type,                          // string
+		formatInt32(t.SequenceNumber),   // int32
+		formatUint32(t.SessionID),       // uint32
+		formatInt32(t.Flags),            // int32
+		strconv.FormatBool(t.Encrypted), // bool
+		strconv.FormatBool(t.Decrypted), // bool
+		t.Message,                       // string
+		t.Action,                        // string
+		t.AuthenType,                    // string
+		t.Service,                       // string
+		formatInt32(t.PrivLevel),        // int32
+		t.User,                          // string
+		t.Port,                          // string
+		t.RemoteAddress,                 // string
+		t.Status,                        // string
+		t.ServerMessage,                 // string
+		join(t.Arguments...),            // []string
+		t.Command,                       // string
+		t.Accounting,                    // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (t *TACACS) Time() int64 {
+	return t.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (t *TACACS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	t.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(t)
+}
+
+var tacacsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_TACACS.String()),
+		Help: Type_NC_TACACS.String() + " audit records",
+	},
+	[]string{"Type", "Message", "Status"},
+)
+
+// Inc increments the metrics for the audit record.
+func (t *TACACS) Inc() {
+	tacacsMetric.WithLabelValues(t.Type, t.Message, t.Status).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (t *TACACS) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (t *TACACS) Src() string {
+	return t.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (t *TACACS) Dst() string {
+	return t.ServerIP
+}
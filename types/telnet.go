@@ -0,0 +1,147 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsTelnet = []string{
+	"Timestamp",
+	"Duration",        // int64
+	"ClientIP",        // string
+	"ServerIP",        // string
+	"ClientPort",      // int32
+	"ServerPort",      // int32
+	"Options",         // []*TelnetOption
+	"TerminalType",    // string
+	"WindowWidth",     // int32
+	"WindowHeight",    // int32
+	"TerminalSpeed",   // string
+	"DisplayLocation", // string
+	"Environment",     // []string
+	"User",            // string
+	"Transcript",      // []*TelnetLine
+	"ClientBytes",     // int64
+	"ServerBytes",     // int64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (t *Telnet) CSVHeader() []string {
+	return filter(fieldsTelnet)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (t *Telnet) CSVRecord() []string {
+	options := make([]string, 0, len(t.Options))
+	for _, o := range t.Options {
+		options = append(options, o.toString())
+	}
+
+	transcript := make([]string, 0, len(t.Transcript))
+	for _, l := range t.Transcript {
+		transcript = append(transcript, l.toString())
+	}
+
+	return filter([]string{
+		formatTimestamp(t.Timestamp),
+		formatInt64(t.Duration),      // int64
+		t.ClientIP,                   // string
+		t.ServerIP,                   // string
+		formatInt32(t.ClientPort),    // int32
+		formatInt32(t.ServerPort),    // int32
+		strings.Join(options, ""),    // []*TelnetOption
+		t.TerminalType,               // string
+		formatInt32(t.WindowWidth),   // int32
+		formatInt32(t.WindowHeight),  // int32
+		t.TerminalSpeed,              // string
+		t.DisplayLocation,            // string
+		join(t.Environment...),       // []string
+		t.User,                       // string
+		strings.Join(transcript, ""), // []*TelnetLine
+		formatInt64(t.ClientBytes),   // int64
+		formatInt64(t.ServerBytes),   // int64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (t *Telnet) Time() int64 {
+	return t.Timestamp
+}
+
+func (o *TelnetOption) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(strconv.FormatBool(o.IsClient))
+	b.WriteString(FieldSeparator)
+	b.WriteString(o.Command)
+	b.WriteString(FieldSeparator)
+	b.WriteString(formatInt32(o.Code))
+	b.WriteString(FieldSeparator)
+	b.WriteString(o.Name)
+	b.WriteString(StructureEnd)
+
+	return b.String()
+}
+
+func (l *TelnetLine) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(formatTimestamp(l.Timestamp))
+	b.WriteString(FieldSeparator)
+	b.WriteString(strconv.FormatBool(l.IsClient))
+	b.WriteString(FieldSeparator)
+	b.WriteString(l.Data)
+	b.WriteString(StructureEnd)
+
+	return b.String()
+}
+
+// JSON returns the JSON representation of the audit record.
+func (t *Telnet) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	t.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(t)
+}
+
+var telnetMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Telnet.String()),
+		Help: Type_NC_Telnet.String() + " audit records",
+	},
+	[]string{"TerminalType"},
+)
+
+// Inc increments the metrics for the audit record.
+func (t *Telnet) Inc() {
+	telnetMetric.WithLabelValues(t.TerminalType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (t *Telnet) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (t *Telnet) Src() string {
+	return t.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (t *Telnet) Dst() string {
+	return t.ServerIP
+}
@@ -0,0 +1,109 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsTFTP = []string{
+	"Timestamp",
+	"Operation",    // string
+	"Filename",     // string
+	"Mode",         // string
+	"Options",      // []string
+	"BlockSize",    // int32
+	"TransferSize", // int64
+	"Blocks",       // int32
+	"Bytes",        // int64
+	"Complete",     // bool
+	"ErrorCode",    // int32
+	"ErrorMessage", // string
+	"Duration",     // int64
+	"ClientIP",
+	"ServerIP",
+	"ClientPort",
+	"ServerPort",
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (t *TFTP) CSVHeader() []string {
+	return filter(fieldsTFTP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (t *TFTP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(t.Timestamp),
+		t.Operation,                    // string
+		t.Filename,                     // string
+		t.Mode,                         // string
+		join(t.Options...),             // []string
+		formatInt32(t.BlockSize),       // int32
+		formatInt64(t.TransferSize),    // int64
+		formatInt32(t.Blocks),          // int32
+		formatInt64(t.Bytes),           // int64
+		strconv.FormatBool(t.Complete), // bool
+		formatInt32(t.ErrorCode),       // int32
+		t.ErrorMessage,                 // string
+		formatInt64(t.Duration),        // int64
+		t.ClientIP,
+		t.ServerIP,
+		formatInt32(t.ClientPort),
+		formatInt32(t.ServerPort),
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (t *TFTP) Time() int64 {
+	return t.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (t *TFTP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	t.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(t)
+}
+
+var tftpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_TFTP.String()),
+		Help: Type_NC_TFTP.String() + " audit records",
+	},
+	[]string{"Operation", "Complete"},
+)
+
+// Inc increments the metrics for the audit record.
+func (t *TFTP) Inc() {
+	tftpMetric.WithLabelValues(t.Operation, strconv.FormatBool(t.Complete)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (t *TFTP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (t *TFTP) Src() string {
+	return t.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (t *TFTP) Dst() string {
+	return t.ServerIP
+}
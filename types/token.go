@@ -0,0 +1,119 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsToken = []string{
+	"Timestamp",
+	"Type",      // string
+	"Source",    // string
+	"Name",      // string
+	"Value",     // string
+	"Host",      // string
+	"URL",       // string
+	"ClientIP",  // string
+	"ServerIP",  // string
+	"Flow",      // string
+	"JWTHeader", // string
+	"JWTClaims", // string
+	"Algorithm", // string
+	"KeyID",     // string
+	"Issuer",    // string
+	"Subject",   // string
+	"Audience",  // []string
+	"IssuedAt",  // int64
+	"NotBefore", // int64
+	"Expiry",    // int64
+	"AlgNone",   // bool
+	"Expired",   // bool
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (t *Token) CSVHeader() []string {
+	return filter(fieldsToken)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (t *Token) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(t.Timestamp),
+		t.Type,                        // string
+		t.Source,                      // string
+		t.Name,                        // string
+		t.Value,                       // string
+		t.Host,                        // string
+		t.URL,                         // string
+		t.ClientIP,                    // string
+		t.ServerIP,                    // string
+		t.Flow,                        // string
+		t.JWTHeader,                   // string
+		t.JWTClaims,                   // string
+		t.Algorithm,                   // string
+		t.KeyID,                       // string
+		t.Issuer,                      // string
+		t.Subject,                     // string
+		join(t.Audience...),           // []string
+		formatTimestamp(t.IssuedAt),   // int64
+		formatTimestamp(t.NotBefore),  // int64
+		formatTimestamp(t.Expiry),     // int64
+		strconv.FormatBool(t.AlgNone), // bool
+		strconv.FormatBool(t.Expired), // bool
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (t *Token) Time() int64 {
+	return t.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (t *Token) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	t.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(t)
+}
+
+var tokenMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Token.String()),
+		Help: Type_NC_Token.String() + " audit records",
+	},
+	[]string{"Type", "Source", "Algorithm"},
+)
+
+// Inc increments the metrics for the audit record.
+func (t *Token) Inc() {
+	tokenMetric.WithLabelValues(t.Type, t.Source, t.Algorithm).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (t *Token) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (t *Token) Src() string {
+	return t.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (t *Token) Dst() string {
+	return t.ServerIP
+}
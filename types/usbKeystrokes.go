@@ -0,0 +1,93 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsUSBKeystrokes = []string{
+	"Timestamp",
+	"LastSeen",       // int64
+	"BusID",          // int32
+	"DeviceAddress",  // int32
+	"EndpointNumber", // int32
+	"Layout",         // string
+	"Text",           // string
+	"Keys",           // string
+	"NumKeystrokes",  // int64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (u *USBKeystrokes) CSVHeader() []string {
+	return filter(fieldsUSBKeystrokes)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (u *USBKeystrokes) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(u.Timestamp),
+		formatTimestamp(u.LastSeen),   // int64
+		formatInt32(u.BusID),          // int32
+		formatInt32(u.DeviceAddress),  // int32
+		formatInt32(u.EndpointNumber), // int32
+		u.Layout,                      // string
+		u.Text,                        // string
+		u.Keys,                        // string
+		formatInt64(u.NumKeystrokes),  // int64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (u *USBKeystrokes) Time() int64 {
+	return u.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (u *USBKeystrokes) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	u.Timestamp /= int64(time.Millisecond)
+	u.LastSeen /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(u)
+}
+
+var usbKeystrokesMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_USBKeystrokes.String()),
+		Help: Type_NC_USBKeystrokes.String() + " audit records",
+	},
+	[]string{"Layout"},
+)
+
+// Inc increments the metrics for the audit record.
+func (u *USBKeystrokes) Inc() {
+	usbKeystrokesMetric.WithLabelValues(u.Layout).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (u *USBKeystrokes) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (u *USBKeystrokes) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (u *USBKeystrokes) Dst() string {
+	return ""
+}
@@ -0,0 +1,94 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsUSBMouse = []string{
+	"Timestamp",
+	"BusID",          // int32
+	"DeviceAddress",  // int32
+	"EndpointNumber", // int32
+	"Buttons",        // int32
+	"DX",             // int32
+	"DY",             // int32
+	"Wheel",          // int32
+	"X",              // int64
+	"Y",              // int64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (u *USBMouse) CSVHeader() []string {
+	return filter(fieldsUSBMouse)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (u *USBMouse) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(u.Timestamp),
+		formatInt32(u.BusID),          // int32
+		formatInt32(u.DeviceAddress),  // int32
+		formatInt32(u.EndpointNumber), // int32
+		formatInt32(u.Buttons),        // int32
+		formatInt32(u.DX),             // int32
+		formatInt32(u.DY),             // int32
+		formatInt32(u.Wheel),          // int32
+		formatInt64(u.X),              // int64
+		formatInt64(u.Y),              // int64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (u *USBMouse) Time() int64 {
+	return u.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (u *USBMouse) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	u.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(u)
+}
+
+var usbMouseMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_USBMouse.String()),
+		Help: Type_NC_USBMouse.String() + " audit records",
+	},
+	[]string{"Buttons"},
+)
+
+// Inc increments the metrics for the audit record.
+func (u *USBMouse) Inc() {
+	usbMouseMetric.WithLabelValues(formatInt32(u.Buttons)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (u *USBMouse) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (u *USBMouse) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (u *USBMouse) Dst() string {
+	return ""
+}
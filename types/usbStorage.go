@@ -0,0 +1,108 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsUSBStorage = []string{
+	"Timestamp",
+	"BusID",          // int32
+	"DeviceAddress",  // int32
+	"LUN",            // int32
+	"Tag",            // uint32
+	"Opcode",         // int32
+	"Command",        // string
+	"LBA",            // uint64
+	"Blocks",         // uint32
+	"BlockSize",      // uint32
+	"ExpectedLength", // uint32
+	"Length",         // int64
+	"Residue",        // uint32
+	"Status",         // int32
+	"Vendor",         // string
+	"Product",        // string
+	"Revision",       // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (u *USBStorage) CSVHeader() []string {
+	return filter(fieldsUSBStorage)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (u *USBStorage) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(u.Timestamp),
+		formatInt32(u.BusID),           // int32
+		formatInt32(u.DeviceAddress),   // int32
+		formatInt32(u.LUN),             // int32
+		formatUint32(u.Tag),            // uint32
+		formatInt32(u.Opcode),          // int32
+		u.Command,                      // string
+		formatUint64(u.LBA),            // uint64
+		formatUint32(u.Blocks),         // uint32
+		formatUint32(u.BlockSize),      // uint32
+		formatUint32(u.ExpectedLength), // uint32
+		formatInt64(u.Length),          // int64
+		formatUint32(u.Residue),        // uint32
+		formatInt32(u.Status),          // int32
+		u.Vendor,                       // string
+		u.Product,                      // string
+		u.Revision,                     // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (u *USBStorage) Time() int64 {
+	return u.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (u *USBStorage) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	u.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(u)
+}
+
+var usbStorageMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_USBStorage.String()),
+		Help: Type_NC_USBStorage.String() + " audit records",
+	},
+	[]string{"Command", "Status"},
+)
+
+// Inc increments the metrics for the audit record.
+func (u *USBStorage) Inc() {
+	usbStorageMetric.WithLabelValues(u.Command, formatInt32(u.Status)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (u *USBStorage) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (u *USBStorage) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (u *USBStorage) Dst() string {
+	return ""
+}
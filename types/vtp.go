@@ -0,0 +1,96 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsVTP = []string{
+	"Timestamp",
+	"Version",         // int32
+	"Code",            // string
+	"Domain",          // string
+	"ConfigRevision",  // uint32
+	"Followers",       // int32
+	"Sequence",        // int32
+	"UpdaterIP",       // string
+	"UpdateTimestamp", // string
+	"MD5",             // string
+	"VLANs",           // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (v *VTP) CSVHeader() []string {
+	return filter(fieldsVTP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (v *VTP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(v.Timestamp),
+		formatInt32(v.Version),         // int32
+		v.Code,                         // string
+		v.Domain,                       // string
+		formatUint32(v.ConfigRevision), // uint32
+		formatInt32(v.Followers),       // int32
+		formatInt32(v.Sequence),        // int32
+		v.UpdaterIP,                    // string
+		v.UpdateTimestamp,              // string
+		v.MD5,                          // string
+		join(v.VLANs...),               // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (v *VTP) Time() int64 {
+	return v.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (v *VTP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	v.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(v)
+}
+
+var vtpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_VTP.String()),
+		Help: Type_NC_VTP.String() + " audit records",
+	},
+	[]string{"Code", "Domain"},
+)
+
+// Inc increments the metrics for the audit record.
+func (v *VTP) Inc() {
+	vtpMetric.WithLabelValues(v.Code, v.Domain).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (v *VTP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (v *VTP) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (v *VTP) Dst() string {
+	return ""
+}
@@ -0,0 +1,122 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fieldsWebSocketMessage = []string{
+	"Timestamp",
+	"IsClient",    // bool
+	"Opcode",      // int32
+	"OpcodeName",  // string
+	"Fragments",   // int32
+	"Compressed",  // bool
+	"Masked",      // bool
+	"WireSize",    // int64
+	"PayloadSize", // int64
+	"Payload",     // []byte
+	"CloseCode",   // int32
+	"CloseReason", // string
+	"URL",         // string
+	"Host",        // string
+	"Protocol",    // string
+	"ClientIP",    // string
+	"ServerIP",    // string
+	"ClientPort",  // int32
+	"ServerPort",  // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (w *WebSocketMessage) CSVHeader() []string {
+	return filter(fieldsWebSocketMessage)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (w *WebSocketMessage) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(w.Timestamp),
+		strconv.FormatBool(w.IsClient),   // bool
+		formatInt32(w.Opcode),            // int32
+		w.OpcodeName,                     // string
+		formatInt32(w.Fragments),         // int32
+		strconv.FormatBool(w.Compressed), // bool
+		strconv.FormatBool(w.Masked),     // bool
+		formatInt64(w.WireSize),          // int64
+		formatInt64(w.PayloadSize),       // int64
+		hex.EncodeToString(w.Payload),    // []byte
+		formatInt32(w.CloseCode),         // int32
+		w.CloseReason,                    // string
+		w.URL,                            // string
+		w.Host,                           // string
+		w.Protocol,                       // string
+		w.ClientIP,                       // string
+		w.ServerIP,                       // string
+		formatInt32(w.ClientPort),        // int32
+		formatInt32(w.ServerPort),        // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (w *WebSocketMessage) Time() int64 {
+	return w.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (w *WebSocketMessage) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	w.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(w)
+}
+
+var webSocketMessageMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_WebSocketMessage.String()),
+		Help: Type_NC_WebSocketMessage.String() + " audit records",
+	},
+	[]string{"IsClient", "OpcodeName", "Compressed"},
+)
+
+// Inc increments the metrics for the audit record.
+func (w *WebSocketMessage) Inc() {
+	webSocketMessageMetric.WithLabelValues(strconv.FormatBool(w.IsClient), w.OpcodeName, strconv.FormatBool(w.Compressed)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (w *WebSocketMessage) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (w *WebSocketMessage) Src() string {
+	if w.IsClient {
+		return w.ClientIP
+	}
+
+	return w.ServerIP
+}
+
+// Dst returns the destination address of the audit record.
+func (w *WebSocketMessage) Dst() string {
+	if w.IsClient {
+		return w.ServerIP
+	}
+
+	return w.ClientIP
+}